@@ -121,6 +121,13 @@ type InstanceServer interface {
 	GetInstanceUEFIVars(name string) (instanceUEFI *api.InstanceUEFIVars, ETag string, err error)
 	UpdateInstanceUEFIVars(name string, instanceUEFI api.InstanceUEFIVars, ETag string) (err error)
 
+	GetInstanceCheckpointNames(instanceName string) (names []string, err error)
+	GetInstanceCheckpoints(instanceName string) (checkpoints []api.InstanceCheckpoint, err error)
+	GetInstanceCheckpoint(instanceName string, name string) (checkpoint *api.InstanceCheckpoint, err error)
+	CreateInstanceCheckpoint(instanceName string, checkpoint api.InstanceCheckpointsPost) (err error)
+	DeleteInstanceCheckpoint(instanceName string, name string) (err error)
+	ExportInstanceCheckpoint(instanceName string, name string, export api.InstanceCheckpointExportPost) (op Operation, err error)
+
 	ExecInstance(instanceName string, exec api.InstanceExecPost, args *InstanceExecArgs) (op Operation, err error)
 	ConsoleInstance(instanceName string, console api.InstanceConsolePost, args *InstanceConsoleArgs) (op Operation, err error)
 	ConsoleInstanceDynamic(instanceName string, console api.InstanceConsolePost, args *InstanceConsoleArgs) (Operation, func(io.ReadWriteCloser) error, error)
@@ -135,6 +142,8 @@ type InstanceServer interface {
 	GetInstanceFileSFTPConn(instanceName string) (net.Conn, error)
 	GetInstanceFileSFTP(instanceName string) (*sftp.Client, error)
 
+	GetInstanceUSBRedirConn(instanceName string, name string) (net.Conn, error)
+
 	GetInstanceSnapshotNames(instanceName string) (names []string, err error)
 	GetInstanceSnapshots(instanceName string) (snapshots []api.InstanceSnapshot, err error)
 	GetInstanceSnapshot(instanceName string, name string) (snapshot *api.InstanceSnapshot, ETag string, err error)
@@ -157,6 +166,10 @@ type InstanceServer interface {
 	GetInstanceState(name string) (state *api.InstanceState, ETag string, err error)
 	UpdateInstanceState(name string, state api.InstanceStatePut, ETag string) (op Operation, err error)
 
+	GetInstanceReplication(name string) (replication *api.InstanceReplication, err error)
+	SyncInstanceReplica(name string) (op Operation, err error)
+	PromoteInstanceReplica(name string) (err error)
+
 	GetInstanceLogfiles(name string) (logfiles []string, err error)
 	GetInstanceLogfile(name string, filename string) (content io.ReadCloser, err error)
 	DeleteInstanceLogfile(name string, filename string) (err error)
@@ -289,6 +302,8 @@ type InstanceServer interface {
 	GetStoragePools() (pools []api.StoragePool, err error)
 	GetStoragePool(name string) (pool *api.StoragePool, ETag string, err error)
 	GetStoragePoolResources(name string) (resources *api.ResourcesStoragePool, err error)
+	GetStoragePoolHealth(name string) (health *api.StoragePoolHealth, err error)
+	ScrubStoragePool(name string) (err error)
 	CreateStoragePool(pool api.StoragePoolsPost) (err error)
 	UpdateStoragePool(name string, pool api.StoragePoolPut, ETag string) (err error)
 	DeleteStoragePool(name string) (err error)
@@ -321,6 +336,9 @@ type InstanceServer interface {
 	GetStoragePoolVolumesWithFilterAllProjects(pool string, filters []string) (volumes []api.StorageVolume, err error)
 	GetStoragePoolVolume(pool string, volType string, name string) (volume *api.StorageVolume, ETag string, err error)
 	GetStoragePoolVolumeState(pool string, volType string, name string) (state *api.StorageVolumeState, err error)
+	GetStoragePoolVolumeReplication(pool string, volType string, name string) (replication *api.StorageVolumeReplication, err error)
+	SyncStoragePoolVolumeReplica(pool string, volType string, name string) (op Operation, err error)
+	PromoteStoragePoolVolumeReplica(pool string, volType string, name string) (err error)
 	CreateStoragePoolVolume(pool string, volume api.StorageVolumesPost) (op Operation, err error)
 	UpdateStoragePoolVolume(pool string, volType string, name string, volume api.StorageVolumePut, ETag string) (op Operation, err error)
 	RenameStoragePoolVolume(pool string, volType string, name string, volume api.StorageVolumePost) (op Operation, err error)
@@ -337,6 +355,9 @@ type InstanceServer interface {
 	GetStoragePoolVolumeSnapshot(pool string, volumeType string, volumeName string, snapshotName string) (snapshot *api.StorageVolumeSnapshot, ETag string, err error)
 	RenameStoragePoolVolumeSnapshot(pool string, volumeType string, volumeName string, snapshotName string, snapshot api.StorageVolumeSnapshotPost) (op Operation, err error)
 	UpdateStoragePoolVolumeSnapshot(pool string, volumeType string, volumeName string, snapshotName string, volume api.StorageVolumeSnapshotPut, ETag string) (op Operation, err error)
+	GetStoragePoolVolumeSnapshotFile(pool string, volumeType string, volumeName string, snapshotName string, filePath string) (content io.ReadCloser, resp *InstanceFileResponse, err error)
+	RestoreStoragePoolVolumeSnapshotFiles(pool string, volumeType string, volumeName string, snapshotName string, paths []string) (op Operation, err error)
+	DiffStoragePoolVolume(pool string, volumeType string, volumeName string, diff api.StorageVolumeDiffPost) (op Operation, err error)
 
 	// Storage volume backup functions ("custom_volume_backup" API extension)
 	GetStoragePoolVolumeBackupNames(pool string, volName string) (names []string, err error)
@@ -417,6 +417,139 @@ func (r *ProtocolLXD) UpdateInstanceUEFIVars(name string, instanceUEFI api.Insta
 	return nil
 }
 
+// GetInstanceCheckpointNames returns a list of checkpoint names for the instance.
+func (r *ProtocolLXD) GetInstanceCheckpointNames(instanceName string) ([]string, error) {
+	err := r.CheckExtension("instance_checkpoints")
+	if err != nil {
+		return nil, err
+	}
+
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch the raw URL values.
+	urls := []string{}
+	baseURL := path + "/" + url.PathEscape(instanceName) + "/checkpoints"
+	_, err = r.queryStruct(http.MethodGet, baseURL, nil, "", &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse it.
+	return urlsToResourceNames(baseURL, urls...)
+}
+
+// GetInstanceCheckpoints returns a list of checkpoints for the instance.
+func (r *ProtocolLXD) GetInstanceCheckpoints(instanceName string) ([]api.InstanceCheckpoint, error) {
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.CheckExtension("instance_checkpoints")
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch the raw value
+	checkpoints := []api.InstanceCheckpoint{}
+
+	_, err = r.queryStruct(http.MethodGet, path+"/"+url.PathEscape(instanceName)+"/checkpoints?recursion=1", nil, "", &checkpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpoints, nil
+}
+
+// GetInstanceCheckpoint returns a Checkpoint struct for the provided instance and checkpoint names.
+func (r *ProtocolLXD) GetInstanceCheckpoint(instanceName string, name string) (*api.InstanceCheckpoint, error) {
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.CheckExtension("instance_checkpoints")
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch the raw value
+	checkpoint := api.InstanceCheckpoint{}
+	_, err = r.queryStruct(http.MethodGet, path+"/"+url.PathEscape(instanceName)+"/checkpoints/"+url.PathEscape(name), nil, "", &checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &checkpoint, nil
+}
+
+// CreateInstanceCheckpoint requests that LXD starts tracking the changed blocks of the instance disks.
+func (r *ProtocolLXD) CreateInstanceCheckpoint(instanceName string, checkpoint api.InstanceCheckpointsPost) error {
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return err
+	}
+
+	err = r.CheckExtension("instance_checkpoints")
+	if err != nil {
+		return err
+	}
+
+	// Send the request
+	_, _, err = r.query(http.MethodPost, path+"/"+url.PathEscape(instanceName)+"/checkpoints", checkpoint, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteInstanceCheckpoint requests that LXD deletes the instance checkpoint.
+func (r *ProtocolLXD) DeleteInstanceCheckpoint(instanceName string, name string) error {
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return err
+	}
+
+	err = r.CheckExtension("instance_checkpoints")
+	if err != nil {
+		return err
+	}
+
+	// Send the request
+	_, _, err = r.query(http.MethodDelete, path+"/"+url.PathEscape(instanceName)+"/checkpoints/"+url.PathEscape(name), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ExportInstanceCheckpoint requests an NBD export of an instance disk along with the blocks changed since the
+// checkpoint. The NBD protocol is carried over the operation websocket.
+func (r *ProtocolLXD) ExportInstanceCheckpoint(instanceName string, name string, export api.InstanceCheckpointExportPost) (Operation, error) {
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.CheckExtension("instance_checkpoints")
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation(http.MethodPost, path+"/"+url.PathEscape(instanceName)+"/checkpoints/"+url.PathEscape(name)+"/export", export, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
 // GetInstanceFull returns the instance entry for the provided name along with snapshot information.
 func (r *ProtocolLXD) GetInstanceFull(name string) (*api.InstanceFull, string, error) {
 	instance := api.InstanceFull{}
@@ -1627,8 +1760,8 @@ func (r *ProtocolLXD) DeleteInstanceFile(instanceName string, filePath string) e
 	return nil
 }
 
-// rawSFTPConn connects to the apiURL, upgrades to an SFTP raw connection and returns it.
-func (r *ProtocolLXD) rawSFTPConn(apiURL *url.URL) (net.Conn, error) {
+// rawUpgradeConn connects to the apiURL, upgrades to a raw connection of the given protocol and returns it.
+func (r *ProtocolLXD) rawUpgradeConn(apiURL *url.URL, protocol string) (net.Conn, error) {
 	// Get the HTTP transport.
 	httpTransport, err := r.getUnderlyingHTTPTransport()
 	if err != nil {
@@ -1645,7 +1778,7 @@ func (r *ProtocolLXD) rawSFTPConn(apiURL *url.URL) (net.Conn, error) {
 		Host:       apiURL.Host,
 	}
 
-	req.Header["Upgrade"] = []string{"sftp"}
+	req.Header["Upgrade"] = []string{protocol}
 	req.Header["Connection"] = []string{"Upgrade"}
 
 	r.addClientHeaders(req)
@@ -1688,7 +1821,7 @@ func (r *ProtocolLXD) rawSFTPConn(apiURL *url.URL) (net.Conn, error) {
 		}
 	}
 
-	if resp.Header.Get("Upgrade") != "sftp" {
+	if resp.Header.Get("Upgrade") != protocol {
 		return nil, errors.New("Missing or unexpected Upgrade header in response")
 	}
 
@@ -1702,7 +1835,7 @@ func (r *ProtocolLXD) GetInstanceFileSFTPConn(instanceName string) (net.Conn, er
 	apiURL.Path("1.0", "instances", instanceName, "sftp")
 	r.setURLQueryAttributes(&apiURL.URL)
 
-	return r.rawSFTPConn(&apiURL.URL)
+	return r.rawUpgradeConn(&apiURL.URL, "sftp")
 }
 
 // GetInstanceFileSFTP returns an SFTP connection to the instance.
@@ -1728,6 +1861,23 @@ func (r *ProtocolLXD) GetInstanceFileSFTP(instanceName string) (*sftp.Client, er
 	return client, nil
 }
 
+// GetInstanceUSBRedirConn hotplugs a redirected USB device into the running virtual machine and returns the
+// usbredir connection of the device. The device is unplugged when the connection is closed.
+func (r *ProtocolLXD) GetInstanceUSBRedirConn(instanceName string, name string) (net.Conn, error) {
+	err := r.CheckExtension("instance_usb_redirection")
+	if err != nil {
+		return nil, err
+	}
+
+	apiURL := api.NewURL()
+	apiURL.URL = r.httpBaseURL // Preload the URL with the client base URL.
+	apiURL.Path("1.0", "instances", instanceName, "usbredir")
+	apiURL.WithQuery("name", name)
+	r.setURLQueryAttributes(&apiURL.URL)
+
+	return r.rawUpgradeConn(&apiURL.URL, "usbredir")
+}
+
 // GetInstanceSnapshotNames returns a list of snapshot names for the instance.
 func (r *ProtocolLXD) GetInstanceSnapshotNames(instanceName string) ([]string, error) {
 	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
@@ -2244,6 +2394,70 @@ func (r *ProtocolLXD) UpdateInstanceState(name string, state api.InstanceStatePu
 	return op, nil
 }
 
+// GetInstanceReplication returns the replication state of an instance.
+func (r *ProtocolLXD) GetInstanceReplication(name string) (*api.InstanceReplication, error) {
+	err := r.CheckExtension("instance_replication")
+	if err != nil {
+		return nil, err
+	}
+
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch the raw value
+	replication := api.InstanceReplication{}
+	_, err = r.queryStruct(http.MethodGet, path+"/"+url.PathEscape(name)+"/replication", nil, "", &replication)
+	if err != nil {
+		return nil, err
+	}
+
+	return &replication, nil
+}
+
+// SyncInstanceReplica creates or refreshes the replica of an instance.
+func (r *ProtocolLXD) SyncInstanceReplica(name string) (Operation, error) {
+	err := r.CheckExtension("instance_replication")
+	if err != nil {
+		return nil, err
+	}
+
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation(http.MethodPost, path+"/"+url.PathEscape(name)+"/replication", api.InstanceReplicationPost{Action: "sync"}, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// PromoteInstanceReplica detaches a replica from its source instance so it can be used in its place.
+func (r *ProtocolLXD) PromoteInstanceReplica(name string) error {
+	err := r.CheckExtension("instance_replication")
+	if err != nil {
+		return err
+	}
+
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return err
+	}
+
+	// Send the request
+	_, _, err = r.query(http.MethodPost, path+"/"+url.PathEscape(name)+"/replication", api.InstanceReplicationPost{Action: "promote"}, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // GetInstanceLogfiles returns a list of logfiles for the instance.
 func (r *ProtocolLXD) GetInstanceLogfiles(name string) ([]string, error) {
 	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
@@ -137,3 +137,37 @@ func (r *ProtocolLXD) GetStoragePoolResources(name string) (*api.ResourcesStorag
 
 	return &res, nil
 }
+
+// GetStoragePoolHealth runs the health checks of a given storage pool.
+func (r *ProtocolLXD) GetStoragePoolHealth(name string) (*api.StoragePoolHealth, error) {
+	err := r.CheckExtension("storage_pool_health")
+	if err != nil {
+		return nil, err
+	}
+
+	health := api.StoragePoolHealth{}
+
+	// Fetch the raw value
+	_, err = r.queryStruct(http.MethodGet, fmt.Sprintf("/storage-pools/%s/health", url.PathEscape(name)), nil, "", &health)
+	if err != nil {
+		return nil, err
+	}
+
+	return &health, nil
+}
+
+// ScrubStoragePool starts verifying the integrity of the data in a given storage pool.
+func (r *ProtocolLXD) ScrubStoragePool(name string) error {
+	err := r.CheckExtension("storage_pool_health")
+	if err != nil {
+		return err
+	}
+
+	// Send the request
+	_, _, err = r.query(http.MethodPost, fmt.Sprintf("/storage-pools/%s/health", url.PathEscape(name)), api.StoragePoolHealthPost{Action: "scrub"}, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package lxd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -250,6 +251,58 @@ func (r *ProtocolLXD) GetStoragePoolVolumeState(pool string, volType string, nam
 	return &state, nil
 }
 
+// GetStoragePoolVolumeReplication returns the replication state of a custom storage volume.
+func (r *ProtocolLXD) GetStoragePoolVolumeReplication(pool string, volType string, name string) (*api.StorageVolumeReplication, error) {
+	err := r.CheckExtension("storage_volume_replication")
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch the raw value
+	replication := api.StorageVolumeReplication{}
+	path := "/storage-pools/" + url.PathEscape(pool) + "/volumes/" + url.PathEscape(volType) + "/" + url.PathEscape(name) + "/replication"
+	_, err = r.queryStruct(http.MethodGet, path, nil, "", &replication)
+	if err != nil {
+		return nil, err
+	}
+
+	return &replication, nil
+}
+
+// SyncStoragePoolVolumeReplica creates or refreshes the replica of a custom storage volume.
+func (r *ProtocolLXD) SyncStoragePoolVolumeReplica(pool string, volType string, name string) (Operation, error) {
+	err := r.CheckExtension("storage_volume_replication")
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the request
+	path := "/storage-pools/" + url.PathEscape(pool) + "/volumes/" + url.PathEscape(volType) + "/" + url.PathEscape(name) + "/replication"
+	op, _, err := r.queryOperation(http.MethodPost, path, api.StorageVolumeReplicationPost{Action: "sync"}, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// PromoteStoragePoolVolumeReplica detaches a replica from its source volume so it can be used in its place.
+func (r *ProtocolLXD) PromoteStoragePoolVolumeReplica(pool string, volType string, name string) error {
+	err := r.CheckExtension("storage_volume_replication")
+	if err != nil {
+		return err
+	}
+
+	// Send the request
+	path := "/storage-pools/" + url.PathEscape(pool) + "/volumes/" + url.PathEscape(volType) + "/" + url.PathEscape(name) + "/replication"
+	_, _, err = r.query(http.MethodPost, path, api.StorageVolumeReplicationPost{Action: "promote"}, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // CreateStoragePoolVolume defines a new storage volume.
 func (r *ProtocolLXD) CreateStoragePoolVolume(pool string, volume api.StorageVolumesPost) (Operation, error) {
 	err := r.CheckExtension("storage")
@@ -413,6 +466,117 @@ func (r *ProtocolLXD) UpdateStoragePoolVolumeSnapshot(pool string, volumeType st
 	return op, nil
 }
 
+// GetStoragePoolVolumeSnapshotFile retrieves the provided path from a snapshot of a container or custom file system volume.
+func (r *ProtocolLXD) GetStoragePoolVolumeSnapshotFile(pool string, volumeType string, volumeName string, snapshotName string, filePath string) (io.ReadCloser, *InstanceFileResponse, error) {
+	err := r.CheckExtension("storage_volume_snapshot_files")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Prepare the HTTP request
+	path := api.NewURL().Path("storage-pools", pool, "volumes", volumeType, volumeName, "snapshots", snapshotName, "files")
+	requestURL, err := shared.URLEncode(r.httpBaseURL.String()+"/1.0"+path.String(), map[string]string{"path": filePath})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	requestURL, err = r.setQueryAttributes(requestURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Send the request
+	resp, err := r.DoHTTP(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Check the return value for a cleaner error
+	if resp.StatusCode != http.StatusOK {
+		_, _, err := lxdParseResponse(resp)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Parse the headers
+	headers, err := shared.ParseLXDFileHeaders(resp.Header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fileResp := InstanceFileResponse{
+		UID:  headers.UID,
+		GID:  headers.GID,
+		Mode: headers.Mode,
+		Type: headers.Type,
+	}
+
+	if fileResp.Type == "directory" {
+		// Decode the response
+		response := api.Response{}
+		decoder := json.NewDecoder(resp.Body)
+
+		err = decoder.Decode(&response)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Get the file list
+		entries := []string{}
+		err = response.MetadataAsStruct(&entries)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		fileResp.Entries = entries
+
+		return nil, &fileResp, err
+	}
+
+	return resp.Body, &fileResp, err
+}
+
+// DiffStoragePoolVolume starts comparing a storage volume snapshot with either a later snapshot or the current state of the volume.
+// The changed paths are returned in the "diffs" field of the operation metadata.
+func (r *ProtocolLXD) DiffStoragePoolVolume(pool string, volumeType string, volumeName string, diff api.StorageVolumeDiffPost) (Operation, error) {
+	err := r.CheckExtension("storage_volume_diff")
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the request
+	path := api.NewURL().Path("storage-pools", pool, "volumes", volumeType, volumeName, "diff")
+	op, _, err := r.queryOperation(http.MethodPost, path.String(), diff, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// RestoreStoragePoolVolumeSnapshotFiles copies the provided paths from a snapshot of a container or custom file system volume back into the volume.
+func (r *ProtocolLXD) RestoreStoragePoolVolumeSnapshotFiles(pool string, volumeType string, volumeName string, snapshotName string, paths []string) (Operation, error) {
+	err := r.CheckExtension("storage_volume_snapshot_files")
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the request
+	path := api.NewURL().Path("storage-pools", pool, "volumes", volumeType, volumeName, "snapshots", snapshotName, "files")
+	op, _, err := r.queryOperation(http.MethodPost, path.String(), api.StorageVolumeSnapshotFilesPost{Paths: paths}, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
 // MigrateStoragePoolVolume requests that LXD prepares for a storage volume migration.
 func (r *ProtocolLXD) MigrateStoragePoolVolume(pool string, volume api.StorageVolumePost) (Operation, error) {
 	err := r.CheckExtension("storage_api_remote_volume_handling")
@@ -48,6 +48,10 @@ func (c *cmdConfig) command() *cobra.Command {
 	configMetadataCmd := cmdConfigMetadata{global: c.global, config: c}
 	cmd.AddCommand(configMetadataCmd.command())
 
+	// Replication
+	configReplicationCmd := cmdConfigReplication{global: c.global, config: c}
+	cmd.AddCommand(configReplicationCmd.command())
+
 	// Set
 	configSetCmd := cmdConfigSet{global: c.global, config: c}
 	cmd.AddCommand(configSetCmd.command())
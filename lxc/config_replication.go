@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v2"
+
+	lxd "github.com/canonical/lxd/client"
+	cli "github.com/canonical/lxd/shared/cmd"
+)
+
+type cmdConfigReplication struct {
+	global *cmdGlobal
+	config *cmdConfig
+}
+
+func (c *cmdConfigReplication) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("replication")
+	cmd.Short = "Manage instance replication"
+	cmd.Long = cli.FormatSection("Description", cmd.Short+`
+
+Instances are replicated to the pool set in their "replication.target_pool" configuration option.
+They are replicated to a remote server instead if "replication.target_remote" and
+"replication.target_remote_fingerprint" are set.`)
+
+	// Promote
+	configReplicationPromoteCmd := cmdConfigReplicationPromote{global: c.global, config: c.config, configReplication: c}
+	cmd.AddCommand(configReplicationPromoteCmd.command())
+
+	// Show
+	configReplicationShowCmd := cmdConfigReplicationShow{global: c.global, config: c.config, configReplication: c}
+	cmd.AddCommand(configReplicationShowCmd.command())
+
+	// Sync
+	configReplicationSyncCmd := cmdConfigReplicationSync{global: c.global, config: c.config, configReplication: c}
+	cmd.AddCommand(configReplicationSyncCmd.command())
+
+	// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706
+	cmd.Args = cobra.NoArgs
+	cmd.Run = func(cmd *cobra.Command, args []string) { _ = cmd.Usage() }
+	return cmd
+}
+
+// parseArgs returns the client and name of the replicated instance.
+func (c *cmdConfigReplication) parseArgs(instanceArg string) (lxd.InstanceServer, string, error) {
+	resources, err := c.global.ParseServers(instanceArg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resource := resources[0]
+	if resource.name == "" {
+		return nil, "", errors.New("Missing instance name")
+	}
+
+	return resource.server, resource.name, nil
+}
+
+// validArgs completes the instance argument.
+func (c *cmdConfigReplication) validArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return c.global.cmpTopLevelResource("instance", toComplete)
+	}
+
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// Promote.
+type cmdConfigReplicationPromote struct {
+	global            *cmdGlobal
+	config            *cmdConfig
+	configReplication *cmdConfigReplication
+}
+
+func (c *cmdConfigReplicationPromote) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("promote", "[<remote>:]<instance>")
+	cmd.Short = "Promote an instance replica"
+	cmd.Long = cli.FormatSection("Description", cmd.Short+`
+
+The replica is detached from its source instance and is no longer synchronized, so it can be used in place of the source instance.`)
+	cmd.Example = cli.FormatSection("", `lxc config replication promote c1-replica
+    Will promote the replica "c1-replica".`)
+
+	cmd.RunE = c.run
+	cmd.ValidArgsFunction = c.configReplication.validArgs
+
+	return cmd
+}
+
+func (c *cmdConfigReplicationPromote) run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	client, name, err := c.configReplication.parseArgs(args[0])
+	if err != nil {
+		return err
+	}
+
+	err = client.PromoteInstanceReplica(name)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf("Instance %s promoted\n", name)
+	}
+
+	return nil
+}
+
+// Show.
+type cmdConfigReplicationShow struct {
+	global            *cmdGlobal
+	config            *cmdConfig
+	configReplication *cmdConfigReplication
+}
+
+func (c *cmdConfigReplicationShow) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("show", "[<remote>:]<instance>")
+	cmd.Short = "Show instance replication state"
+	cmd.Long = cli.FormatSection("Description", cmd.Short+`
+
+The lag is the number of seconds since the last successful synchronization of the replica.`)
+
+	cmd.RunE = c.run
+	cmd.ValidArgsFunction = c.configReplication.validArgs
+
+	return cmd
+}
+
+func (c *cmdConfigReplicationShow) run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	client, name, err := c.configReplication.parseArgs(args[0])
+	if err != nil {
+		return err
+	}
+
+	replication, err := client.GetInstanceReplication(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&replication)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
+
+// Sync.
+type cmdConfigReplicationSync struct {
+	global            *cmdGlobal
+	config            *cmdConfig
+	configReplication *cmdConfigReplication
+}
+
+func (c *cmdConfigReplicationSync) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("sync", "[<remote>:]<instance>")
+	cmd.Short = "Synchronize an instance replica"
+	cmd.Long = cli.FormatSection("Description", cmd.Short+`
+
+The replica is created if it doesn't exist yet, otherwise it is refreshed from the source instance and its snapshots.
+A replica that is running is never refreshed.`)
+	cmd.Example = cli.FormatSection("", `lxc config set c1 replication.target_pool=backup replication.target_instance=c1-replica
+lxc config replication sync c1
+    Will replicate the instance "c1" to the instance "c1-replica" in the "backup" pool.`)
+
+	cmd.RunE = c.run
+	cmd.ValidArgsFunction = c.configReplication.validArgs
+
+	return cmd
+}
+
+func (c *cmdConfigReplicationSync) run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	client, name, err := c.configReplication.parseArgs(args[0])
+	if err != nil {
+		return err
+	}
+
+	op, err := client.SyncInstanceReplica(name)
+	if err != nil {
+		return err
+	}
+
+	return op.Wait()
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/canonical/lxd/client"
 	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
 	cli "github.com/canonical/lxd/shared/cmd"
 	"github.com/canonical/lxd/shared/ioprogress"
 	"github.com/canonical/lxd/shared/logger"
@@ -48,7 +49,52 @@ type cmdFile struct {
 	flagRecursive bool
 }
 
-func fileGetWrapper(server lxd.InstanceServer, inst string, path string) (io.ReadCloser, *lxd.InstanceFileResponse, error) {
+// fileGetFunc retrieves a file from an instance or from one of its snapshots.
+type fileGetFunc func(path string) (io.ReadCloser, *lxd.InstanceFileResponse, error)
+
+// fileGetter returns the function retrieving the files of an instance, or of its snapshot snapName if set.
+func fileGetter(server lxd.InstanceServer, instName string, snapName string) (fileGetFunc, error) {
+	if snapName == "" {
+		getInstanceFile := func(path string) (io.ReadCloser, *lxd.InstanceFileResponse, error) {
+			return server.GetInstanceFile(instName, path)
+		}
+
+		return getInstanceFile, nil
+	}
+
+	err := server.CheckExtension("storage_volume_snapshot_files")
+	if err != nil {
+		return nil, err
+	}
+
+	snap, _, err := server.GetInstanceSnapshot(instName, snapName)
+	if err != nil {
+		return nil, err
+	}
+
+	_, rootDisk, err := api.GetRootDiskDevice(snap.ExpandedDevices)
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting root disk of snapshot %q: %w", snapName, err)
+	}
+
+	inst, _, err := server.GetInstance(instName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Snapshots of volumes on local pools are only available on the member of the instance.
+	if server.IsClustered() {
+		server = server.UseTarget(inst.Location)
+	}
+
+	getSnapshotFile := func(path string) (io.ReadCloser, *lxd.InstanceFileResponse, error) {
+		return server.GetStoragePoolVolumeSnapshotFile(rootDisk["pool"], inst.Type, instName, snapName, path)
+	}
+
+	return getSnapshotFile, nil
+}
+
+func fileGetWrapper(getFile fileGetFunc, path string) (io.ReadCloser, *lxd.InstanceFileResponse, error) {
 	// Signal handling
 	chSignal := make(chan os.Signal, 1)
 	signal.Notify(chSignal, os.Interrupt)
@@ -60,7 +106,7 @@ func fileGetWrapper(server lxd.InstanceServer, inst string, path string) (io.Rea
 	// Operation handling
 	chDone := make(chan bool)
 	go func() {
-		buf, resp, err = server.GetInstanceFile(inst, path)
+		buf, resp, err = getFile(path)
 		close(chDone)
 	}()
 
@@ -441,19 +487,27 @@ type cmdFilePull struct {
 	global *cmdGlobal
 	file   *cmdFile
 
-	edit bool
+	edit         bool
+	flagSnapshot string
 }
 
 func (c *cmdFilePull) command() *cobra.Command {
 	cmd := &cobra.Command{}
 	cmd.Use = usage("pull", "[<remote>:]<instance>/<path> [[<remote>:]<instance>/<path>...] <target path>")
 	cmd.Short = "Pull files from instances"
-	cmd.Long = cli.FormatSection("Description", cmd.Short)
+	cmd.Long = cli.FormatSection("Description", `Pull files from instances
+
+Files are pulled from a snapshot of the instance when --snapshot is set.
+For virtual machines, the files of a snapshot are those of the configuration volume of the instance, not of its guest file system.`)
 	cmd.Example = cli.FormatSection("", `lxc file pull foo/etc/hosts .
-   To pull /etc/hosts from the instance and write it to the current directory.`)
+   To pull /etc/hosts from the instance and write it to the current directory.
+
+lxc file pull foo/etc/hosts . --snapshot snap0
+   To pull /etc/hosts from the snapshot "snap0" of the instance and write it to the current directory.`)
 
 	cmd.Flags().BoolVarP(&c.file.flagMkdir, "create-dirs", "p", false, "Create any directories necessary")
 	cmd.Flags().BoolVarP(&c.file.flagRecursive, "recursive", "r", false, "Recursively transfer files")
+	cmd.Flags().StringVar(&c.flagSnapshot, "snapshot", "", cli.FormatStringFlagLabel("Pull the files from the given snapshot of the instance"))
 
 	cmd.RunE = c.run
 
@@ -529,7 +583,12 @@ func (c *cmdFilePull) run(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("Invalid source %s", resource.name)
 		}
 
-		buf, resp, err := fileGetWrapper(resource.server, pathSpec[0], pathSpec[1])
+		getFile, err := fileGetter(resource.server, pathSpec[0], c.flagSnapshot)
+		if err != nil {
+			return err
+		}
+
+		buf, resp, err := fileGetWrapper(getFile, pathSpec[1])
 		if err != nil {
 			return err
 		}
@@ -552,7 +611,7 @@ func (c *cmdFilePull) run(cmd *cobra.Command, args []string) error {
 					return err
 				}
 
-				err = c.file.recursivePullFile(resource.server, pathSpec[0], pathSpec[1], root, "")
+				err = c.file.recursivePullFile(getFile, pathSpec[1], root, "")
 				// Capture close error separately; check pull error first so it is
 				// not masked by a close error when both occur.
 				closeErr := root.Close()
@@ -602,7 +661,7 @@ func (c *cmdFilePull) run(cmd *cobra.Command, args []string) error {
 					newPath = filepath.Clean(filepath.Join(filepath.Dir(pathSpec[1]), newPath))
 				}
 
-				buf, resp, err = resource.server.GetInstanceFile(pathSpec[0], newPath)
+				buf, resp, err = getFile(newPath)
 				if err != nil {
 					return err
 				}
@@ -954,8 +1013,8 @@ func (c *cmdFilePush) run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (c *cmdFile) recursivePullFile(d lxd.InstanceServer, inst string, p string, root *os.Root, relDir string) error {
-	buf, resp, err := d.GetInstanceFile(inst, p)
+func (c *cmdFile) recursivePullFile(getFile fileGetFunc, p string, root *os.Root, relDir string) error {
+	buf, resp, err := getFile(p)
 	if err != nil {
 		return err
 	}
@@ -1000,7 +1059,7 @@ func (c *cmdFile) recursivePullFile(d lxd.InstanceServer, inst string, p string,
 		for _, ent := range resp.Entries {
 			nextP := path.Join(p, ent)
 
-			err := c.recursivePullFile(d, inst, nextP, root, relTarget)
+			err := c.recursivePullFile(getFile, nextP, root, relTarget)
 			if err != nil {
 				return err
 			}
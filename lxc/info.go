@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"sort"
 	"strings"
 
@@ -786,6 +787,23 @@ func (c *cmdInfo) instanceInfo(d lxd.InstanceServer, name string, showLog bool)
 		}
 
 		fmt.Printf("\nLog:\n\n%s\n", string(stuff))
+
+		// Operations reported by the security profiles in complain mode.
+		if inst.ExpandedConfig["security.profile.mode"] == "complain" {
+			log, err = d.GetInstanceLogfile(name, "security.log")
+			if err != nil && !api.StatusErrorCheck(err, http.StatusNotFound) {
+				return err
+			}
+
+			if err == nil {
+				stuff, err = io.ReadAll(log)
+				if err != nil {
+					return err
+				}
+
+				fmt.Printf("\nSecurity log:\n\n%s\n", string(stuff))
+			}
+		}
 	}
 
 	return nil
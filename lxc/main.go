@@ -280,6 +280,10 @@ For help with any of those, simply call them with --help.`)
 	stopCmd := cmdStop{global: &globalCmd}
 	app.AddCommand(stopCmd.command())
 
+	// usb sub-command
+	usbCmd := cmdUSB{global: &globalCmd}
+	app.AddCommand(usbCmd.command())
+
 	// version sub-command
 	versionCmd := cmdVersion{global: &globalCmd}
 	app.AddCommand(versionCmd.command())
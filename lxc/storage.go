@@ -33,6 +33,10 @@ func (c *cmdStorage) command() *cobra.Command {
 	cmd.Short = "Manage storage pools and volumes"
 	cmd.Long = cli.FormatSection("Description", cmd.Short)
 
+	// Check
+	storageCheckCmd := cmdStorageCheck{global: c.global, storage: c}
+	cmd.AddCommand(storageCheckCmd.command())
+
 	// Create
 	storageCreateCmd := cmdStorageCreate{global: c.global, storage: c}
 	cmd.AddCommand(storageCreateCmd.command())
@@ -83,6 +87,109 @@ func (c *cmdStorage) command() *cobra.Command {
 	return cmd
 }
 
+// Check.
+type cmdStorageCheck struct {
+	global  *cmdGlobal
+	storage *cmdStorage
+
+	flagFormat string
+	flagScrub  bool
+}
+
+func (c *cmdStorageCheck) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("check", "[<remote>:]<pool>")
+	cmd.Short = "Check the health of a storage pool"
+	cmd.Long = cli.FormatSection("Description", `Check the health of a storage pool
+
+Runs the driver specific health checks of the storage pool.
+With --scrub, a scrub verifying the integrity of the pool data is started in the background instead.`)
+	cmd.Example = cli.FormatSection("", `lxc storage check default
+    Show the health of the "default" storage pool.
+
+lxc storage check default --scrub
+    Start a scrub of the "default" storage pool.`)
+
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", "table", cli.FormatStringFlagLabel("Format (csv|json|table|yaml|compact)"))
+	cmd.Flags().BoolVar(&c.flagScrub, "scrub", false, "Start a scrub of the storage pool")
+	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", cli.FormatStringFlagLabel("Cluster member name"))
+	cmd.RunE = c.run
+
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return c.global.cmpTopLevelResource("storage_pool", toComplete)
+		}
+
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return cmd
+}
+
+func (c *cmdStorageCheck) run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return errors.New("Missing pool name")
+	}
+
+	// Targeting
+	if c.storage.flagTarget != "" {
+		if !resource.server.IsClustered() {
+			return errors.New("To use --target, the destination remote must be a cluster")
+		}
+
+		resource.server = resource.server.UseTarget(c.storage.flagTarget)
+	}
+
+	if c.flagScrub {
+		err = resource.server.ScrubStoragePool(resource.name)
+		if err != nil {
+			return err
+		}
+
+		if !c.global.flagQuiet {
+			fmt.Printf("Scrub of storage pool %s started\n", resource.name)
+		}
+
+		return nil
+	}
+
+	health, err := resource.server.GetStoragePoolHealth(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, check := range health.Checks {
+		data = append(data, []string{check.Name, strings.ToUpper(check.Status), check.Message})
+	}
+
+	if c.flagFormat == cli.TableFormatTable {
+		fmt.Printf("Status: %s\n", strings.ToUpper(health.Status))
+	}
+
+	header := []string{
+		"CHECK",
+		"STATUS",
+		"MESSAGE",
+	}
+
+	return cli.RenderTable(c.flagFormat, header, data, health)
+}
+
 // Create.
 type cmdStorageCreate struct {
 	global  *cmdGlobal
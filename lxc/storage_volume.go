@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -87,6 +88,10 @@ Unless specified through a prefix, all volume operations affect "custom" (user c
 	storageVolumeDeleteCmd := cmdStorageVolumeDelete{global: c.global, storage: c.storage, storageVolume: c}
 	cmd.AddCommand(storageVolumeDeleteCmd.command())
 
+	// Diff
+	storageVolumeDiffCmd := cmdStorageVolumeDiff{global: c.global, storage: c.storage, storageVolume: c}
+	cmd.AddCommand(storageVolumeDiffCmd.command())
+
 	// Detach
 	storageVolumeDetachCmd := cmdStorageVolumeDetach{global: c.global, storage: c.storage, storageVolume: c}
 	cmd.AddCommand(storageVolumeDetachCmd.command())
@@ -127,6 +132,10 @@ Unless specified through a prefix, all volume operations affect "custom" (user c
 	storageVolumeMoveCmd := cmdStorageVolumeMove{global: c.global, storage: c.storage, storageVolume: c, storageVolumeCopy: &storageVolumeCopyCmd, storageVolumeRename: &storageVolumeRenameCmd}
 	cmd.AddCommand(storageVolumeMoveCmd.command())
 
+	// Replication
+	storageVolumeReplicationCmd := cmdStorageVolumeReplication{global: c.global, storage: c.storage, storageVolume: c}
+	cmd.AddCommand(storageVolumeReplicationCmd.command())
+
 	// Set
 	storageVolumeSetCmd := cmdStorageVolumeSet{global: c.global, storage: c.storage, storageVolume: c}
 	cmd.AddCommand(storageVolumeSetCmd.command())
@@ -803,6 +812,121 @@ func (c *cmdStorageVolumeDelete) run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// Diff.
+type cmdStorageVolumeDiff struct {
+	global        *cmdGlobal
+	storage       *cmdStorage
+	storageVolume *cmdStorageVolume
+
+	flagFormat string
+}
+
+func (c *cmdStorageVolumeDiff) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("diff", "[<remote>:]<pool> [<type>/]<volume> <from_snapshot> [<to_snapshot>]")
+	cmd.Short = "Show the changes of a storage volume since a snapshot"
+	cmd.Long = cli.FormatSection("Description", `Show the changes of a storage volume since a snapshot
+
+The paths that were added, modified or deleted are compared against the
+current state of the volume, or against <to_snapshot> when given.`)
+	cmd.Example = cli.FormatSection("", `lxc storage volume diff default data snap0
+	Show the changes of the "data" custom volume since its "snap0" snapshot.
+
+lxc storage volume diff default container/c1 snap0 snap1
+	Show the changes of the container "c1" between its "snap0" and "snap1" snapshots.`)
+
+	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", cli.FormatStringFlagLabel("Cluster member name"))
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", "table", cli.FormatStringFlagLabel("Format (csv|json|table|yaml|compact"))
+
+	cmd.RunE = c.run
+
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return c.global.cmpTopLevelResource("storage_pool", toComplete)
+		}
+
+		if len(args) == 1 {
+			return c.global.cmpStoragePoolVolumes(args[0])
+		}
+
+		if len(args) == 2 || len(args) == 3 {
+			return c.global.cmpStoragePoolVolumeSnapshots(args[0], args[1])
+		}
+
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return cmd
+}
+
+func (c *cmdStorageVolumeDiff) run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 3, 4)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+	if resource.name == "" {
+		return errors.New("Missing pool name")
+	}
+
+	client := resource.server
+
+	// Use the provided target.
+	if c.storage.flagTarget != "" {
+		client = client.UseTarget(c.storage.flagTarget)
+	}
+
+	volName, volType := parseVolume("custom", args[1])
+
+	toSnapshot := ""
+	if len(args) == 4 {
+		toSnapshot = args[3]
+	}
+
+	op, err := client.DiffStoragePoolVolume(resource.name, volType, volName, api.StorageVolumeDiffPost{From: args[2], To: toSnapshot})
+	if err != nil {
+		return err
+	}
+
+	err = op.Wait()
+	if err != nil {
+		return err
+	}
+
+	// Extract the changed paths from the operation metadata.
+	diffsJSON, err := json.Marshal(op.Get().Metadata["diffs"])
+	if err != nil {
+		return err
+	}
+
+	diffs := []api.StorageVolumeDiff{}
+	err = json.Unmarshal(diffsJSON, &diffs)
+	if err != nil {
+		return err
+	}
+
+	// Render the table
+	data := make([][]string, 0, len(diffs))
+	for _, diff := range diffs {
+		data = append(data, []string{diff.Change, diff.Path})
+	}
+
+	header := []string{
+		"CHANGE",
+		"PATH",
+	}
+
+	return cli.RenderTable(c.flagFormat, header, data, diffs)
+}
+
 // Detach.
 type cmdStorageVolumeDetach struct {
 	global        *cmdGlobal
@@ -2565,15 +2689,26 @@ type cmdStorageVolumeRestore struct {
 	global        *cmdGlobal
 	storage       *cmdStorage
 	storageVolume *cmdStorageVolume
+
+	flagPaths []string
 }
 
 func (c *cmdStorageVolumeRestore) command() *cobra.Command {
 	cmd := &cobra.Command{}
-	cmd.Use = usage("restore", "[<remote>:]<pool> <volume> <snapshot>")
+	cmd.Use = usage("restore", "[<remote>:]<pool> [<type>/]<volume> <snapshot>")
 	cmd.Short = "Restore storage volume snapshot"
-	cmd.Long = cli.FormatSection("Description", cmd.Short)
+	cmd.Long = cli.FormatSection("Description", `Restore storage volume snapshot
+
+Only the given paths are copied back into the volume when --path is passed,
+which also works with container volumes.`)
+	cmd.Example = cli.FormatSection("", `lxc storage volume restore default data snap0
+	Restore the "data" custom volume to its "snap0" snapshot.
+
+lxc storage volume restore default container/c1 snap0 --path /etc/hosts --path /var/lib/app
+	Restore "/etc/hosts" and "/var/lib/app" of the container "c1" from its "snap0" snapshot.`)
 
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", cli.FormatStringFlagLabel("Cluster member name"))
+	cmd.Flags().StringArrayVar(&c.flagPaths, "path", nil, cli.FormatStringFlagLabel("Only restore the given path"))
 
 	cmd.RunE = c.run
 
@@ -2621,8 +2756,24 @@ func (c *cmdStorageVolumeRestore) run(cmd *cobra.Command, args []string) error {
 		client = client.UseTarget(c.storage.flagTarget)
 	}
 
+	volName, volType := parseVolume("custom", args[1])
+
+	// Restore the selected paths only.
+	if len(c.flagPaths) > 0 {
+		op, err := client.RestoreStoragePoolVolumeSnapshotFiles(resource.name, volType, volName, args[2], c.flagPaths)
+		if err != nil {
+			return err
+		}
+
+		return op.Wait()
+	}
+
+	if volType != "custom" {
+		return errors.New(`Only "custom" volumes can be restored without --path`)
+	}
+
 	// Check if the requested storage volume actually exists
-	_, _, err = client.GetStoragePoolVolume(resource.name, "custom", args[1])
+	_, _, err = client.GetStoragePoolVolume(resource.name, "custom", volName)
 	if err != nil {
 		return err
 	}
@@ -2631,12 +2782,12 @@ func (c *cmdStorageVolumeRestore) run(cmd *cobra.Command, args []string) error {
 		Restore: args[2],
 	}
 
-	_, etag, err := client.GetStoragePoolVolume(resource.name, "custom", args[1])
+	_, etag, err := client.GetStoragePoolVolume(resource.name, "custom", volName)
 	if err != nil {
 		return err
 	}
 
-	op, err := client.UpdateStoragePoolVolume(resource.name, "custom", args[1], req, etag)
+	op, err := client.UpdateStoragePoolVolume(resource.name, "custom", volName, req, etag)
 	if err != nil {
 		return err
 	}
@@ -2966,3 +3117,215 @@ func (c *cmdStorageVolumeImport) run(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// Replication.
+type cmdStorageVolumeReplication struct {
+	global        *cmdGlobal
+	storage       *cmdStorage
+	storageVolume *cmdStorageVolume
+}
+
+func (c *cmdStorageVolumeReplication) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("replication")
+	cmd.Short = "Manage storage volume replication"
+	cmd.Long = cli.FormatSection("Description", cmd.Short+`
+
+Custom volumes are replicated to the pool set in their "replication.target_pool" configuration option.
+They are replicated to a remote server instead if "replication.target_remote" and
+"replication.target_remote_fingerprint" are set.`)
+
+	// Promote
+	storageVolumeReplicationPromoteCmd := cmdStorageVolumeReplicationPromote{global: c.global, storage: c.storage, storageVolumeReplication: c}
+	cmd.AddCommand(storageVolumeReplicationPromoteCmd.command())
+
+	// Show
+	storageVolumeReplicationShowCmd := cmdStorageVolumeReplicationShow{global: c.global, storage: c.storage, storageVolumeReplication: c}
+	cmd.AddCommand(storageVolumeReplicationShowCmd.command())
+
+	// Sync
+	storageVolumeReplicationSyncCmd := cmdStorageVolumeReplicationSync{global: c.global, storage: c.storage, storageVolumeReplication: c}
+	cmd.AddCommand(storageVolumeReplicationSyncCmd.command())
+
+	// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706
+	cmd.Args = cobra.NoArgs
+	cmd.Run = func(cmd *cobra.Command, args []string) { _ = cmd.Usage() }
+	return cmd
+}
+
+// parseArgs returns the client and pool of the replicated volume.
+func (c *cmdStorageVolumeReplication) parseArgs(poolArg string) (lxd.InstanceServer, string, error) {
+	resources, err := c.global.ParseServers(poolArg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resource := resources[0]
+	if resource.name == "" {
+		return nil, "", errors.New("Missing pool name")
+	}
+
+	client := resource.server
+
+	// Use the provided target.
+	if c.storage.flagTarget != "" {
+		client = client.UseTarget(c.storage.flagTarget)
+	}
+
+	return client, resource.name, nil
+}
+
+// validArgs completes the pool and custom volume arguments.
+func (c *cmdStorageVolumeReplication) validArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return c.global.cmpTopLevelResource("storage_pool", toComplete)
+	}
+
+	if len(args) == 1 {
+		return c.global.cmpStoragePoolVolumes(args[0], "custom")
+	}
+
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// Replication promote.
+type cmdStorageVolumeReplicationPromote struct {
+	global                   *cmdGlobal
+	storage                  *cmdStorage
+	storageVolumeReplication *cmdStorageVolumeReplication
+}
+
+func (c *cmdStorageVolumeReplicationPromote) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("promote", "[<remote>:]<pool> <volume>")
+	cmd.Short = "Promote a storage volume replica"
+	cmd.Long = cli.FormatSection("Description", cmd.Short+`
+
+The replica is detached from its source volume and is no longer synchronized, so it can be used in place of the source volume.`)
+	cmd.Example = cli.FormatSection("", `lxc storage volume replication promote backup data
+    Will promote the replica "data" in the "backup" pool.`)
+
+	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", cli.FormatStringFlagLabel("Cluster member name"))
+	cmd.RunE = c.run
+	cmd.ValidArgsFunction = c.storageVolumeReplication.validArgs
+
+	return cmd
+}
+
+func (c *cmdStorageVolumeReplicationPromote) run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	client, poolName, err := c.storageVolumeReplication.parseArgs(args[0])
+	if err != nil {
+		return err
+	}
+
+	err = client.PromoteStoragePoolVolumeReplica(poolName, "custom", args[1])
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf("Storage volume %s promoted\n", args[1])
+	}
+
+	return nil
+}
+
+// Replication show.
+type cmdStorageVolumeReplicationShow struct {
+	global                   *cmdGlobal
+	storage                  *cmdStorage
+	storageVolumeReplication *cmdStorageVolumeReplication
+}
+
+func (c *cmdStorageVolumeReplicationShow) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("show", "[<remote>:]<pool> <volume>")
+	cmd.Short = "Show storage volume replication state"
+	cmd.Long = cli.FormatSection("Description", cmd.Short+`
+
+The lag is the number of seconds since the last successful synchronization of the replica.`)
+
+	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", cli.FormatStringFlagLabel("Cluster member name"))
+	cmd.RunE = c.run
+	cmd.ValidArgsFunction = c.storageVolumeReplication.validArgs
+
+	return cmd
+}
+
+func (c *cmdStorageVolumeReplicationShow) run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	client, poolName, err := c.storageVolumeReplication.parseArgs(args[0])
+	if err != nil {
+		return err
+	}
+
+	replication, err := client.GetStoragePoolVolumeReplication(poolName, "custom", args[1])
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&replication)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
+
+// Replication sync.
+type cmdStorageVolumeReplicationSync struct {
+	global                   *cmdGlobal
+	storage                  *cmdStorage
+	storageVolumeReplication *cmdStorageVolumeReplication
+}
+
+func (c *cmdStorageVolumeReplicationSync) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("sync", "[<remote>:]<pool> <volume>")
+	cmd.Short = "Synchronize a storage volume replica"
+	cmd.Long = cli.FormatSection("Description", cmd.Short+`
+
+The replica is created if it doesn't exist yet, otherwise it is refreshed from the source volume and its snapshots.`)
+	cmd.Example = cli.FormatSection("", `lxc storage volume set default data replication.target_pool=backup
+lxc storage volume replication sync default data
+    Will replicate the custom volume "data" from the "default" pool to the "backup" pool.`)
+
+	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", cli.FormatStringFlagLabel("Cluster member name"))
+	cmd.RunE = c.run
+	cmd.ValidArgsFunction = c.storageVolumeReplication.validArgs
+
+	return cmd
+}
+
+func (c *cmdStorageVolumeReplicationSync) run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	client, poolName, err := c.storageVolumeReplication.parseArgs(args[0])
+	if err != nil {
+		return err
+	}
+
+	op, err := client.SyncStoragePoolVolumeReplica(poolName, "custom", args[1])
+	if err != nil {
+		return err
+	}
+
+	return op.Wait()
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	cli "github.com/canonical/lxd/shared/cmd"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// usbLocalDeviceRegex matches the vendor and product IDs of a local USB device.
+var usbLocalDeviceRegex = regexp.MustCompile(`^[0-9a-fA-F]{4}:[0-9a-fA-F]{4}$`)
+
+// usbLocalDeviceID validates the <vendorid>:<productid> of a local USB device and returns it in lower case.
+func usbLocalDeviceID(local string) (string, error) {
+	if local == "" {
+		return "", errors.New("A local USB device must be specified with --local")
+	}
+
+	if !usbLocalDeviceRegex.MatchString(local) {
+		return "", fmt.Errorf("Invalid local USB device %q, must be <vendorid>:<productid>", local)
+	}
+
+	return strings.ToLower(local), nil
+}
+
+// usbRedirDefaultName returns the name of the redirected device when none is given.
+func usbRedirDefaultName(deviceID string) string {
+	return "usb-" + strings.ReplaceAll(deviceID, ":", "-")
+}
+
+type cmdUSB struct {
+	global *cmdGlobal
+}
+
+func (c *cmdUSB) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("usb")
+	cmd.Short = "Manage USB devices redirected into instances"
+	cmd.Long = cli.FormatSection("Description", cmd.Short)
+
+	// Attach.
+	usbAttachCmd := cmdUSBAttach{global: c.global, usb: c}
+	cmd.AddCommand(usbAttachCmd.command())
+
+	// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706
+	cmd.Args = cobra.NoArgs
+	cmd.Run = func(cmd *cobra.Command, args []string) { _ = cmd.Usage() }
+	return cmd
+}
+
+// Attach.
+type cmdUSBAttach struct {
+	global *cmdGlobal
+	usb    *cmdUSB
+
+	flagLocal string
+	flagName  string
+}
+
+func (c *cmdUSBAttach) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("attach", "[<remote>:]<instance> --local <vendorid>:<productid>")
+	cmd.Short = "Redirect a local USB device into a virtual machine"
+	cmd.Long = cli.FormatSection("Description", cmd.Short+`
+
+The USB device attached to the machine running the client is forwarded to the
+running virtual machine until the command is interrupted.
+
+This requires the "usbredirect" tool (from the usbredir project) to be installed
+on the local machine.`)
+	cmd.Example = cli.FormatSection("", `lxc usb attach vm1 --local 1050:0407
+   To redirect the local USB device with vendor ID 1050 and product ID 0407 into the vm1 instance.`)
+
+	cmd.Flags().StringVar(&c.flagLocal, "local", "", cli.FormatStringFlagLabel("Vendor and product ID of the local USB device"))
+	cmd.Flags().StringVar(&c.flagName, "name", "", cli.FormatStringFlagLabel("Name of the redirected device in the instance"))
+	cmd.RunE = c.run
+
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return c.global.cmpTopLevelResource("instance", toComplete)
+		}
+
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return cmd
+}
+
+func (c *cmdUSBAttach) run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	deviceID, err := usbLocalDeviceID(c.flagLocal)
+	if err != nil {
+		return err
+	}
+
+	if c.flagName == "" {
+		c.flagName = usbRedirDefaultName(deviceID)
+	}
+
+	usbRedirect, err := exec.LookPath("usbredirect")
+	if err != nil {
+		return errors.New(`The "usbredirect" tool is required to redirect local USB devices`)
+	}
+
+	// Parse remote.
+	remote, instanceName, err := c.global.conf.ParseRemote(args[0])
+	if err != nil {
+		return err
+	}
+
+	d, err := c.global.conf.GetInstanceServer(remote)
+	if err != nil {
+		return err
+	}
+
+	// Create a context that is canceled on signal reception so that the device is detached on exit.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Listen locally for the usbredir connection of the device.
+	lc := net.ListenConfig{}
+	listener, err := lc.Listen(ctx, "tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = listener.Close() }()
+
+	addr, ok := listener.Addr().(*net.TCPAddr)
+	if !ok {
+		return errors.New("Failed getting TCP listen address")
+	}
+
+	// Hotplug the redirected device into the instance.
+	remoteConn, err := d.GetInstanceUSBRedirConn(instanceName, c.flagName)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = remoteConn.Close() }()
+
+	// Start forwarding the local device.
+	redirectCmd := exec.Command(usbRedirect, "--device", deviceID, "--to", "127.0.0.1:"+strconv.Itoa(addr.Port))
+	redirectCmd.Stdout = os.Stdout
+	redirectCmd.Stderr = os.Stderr
+
+	err = redirectCmd.Start()
+	if err != nil {
+		return fmt.Errorf("Failed starting usbredirect: %w", err)
+	}
+
+	redirectDone := make(chan error, 1)
+	go func() {
+		redirectDone <- redirectCmd.Wait()
+	}()
+
+	defer func() { _ = redirectCmd.Process.Kill() }()
+
+	localConnCh := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		localConnCh <- conn
+	}()
+
+	var localConn net.Conn
+	select {
+	case localConn = <-localConnCh:
+	case err = <-redirectDone:
+		if err != nil {
+			return fmt.Errorf("Failed redirecting local USB device %q: %w", deviceID, err)
+		}
+
+		return fmt.Errorf("Failed redirecting local USB device %q", deviceID)
+	case <-ctx.Done():
+		return nil
+	}
+
+	defer func() { _ = localConn.Close() }()
+
+	fmt.Printf("USB device %s redirected to %s as %q, press Ctrl+C to detach it\n", deviceID, instanceName, c.flagName)
+
+	// Mirror the connections until either side is closed.
+	copyDone := make(chan struct{})
+	closeOnce := sync.Once{}
+	mirror := func(dst io.Writer, src io.Reader) {
+		_, err := io.Copy(dst, src)
+		if err != nil {
+			logger.Debugf("USB redirection connection closed: %v", err)
+		}
+
+		closeOnce.Do(func() { close(copyDone) })
+	}
+
+	go mirror(remoteConn, localConn)
+	go mirror(localConn, remoteConn)
+
+	select {
+	case <-copyDone:
+	case <-redirectDone:
+	case <-ctx.Done():
+	}
+
+	return nil
+}
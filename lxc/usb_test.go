@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUSBLocalDeviceID(t *testing.T) {
+	deviceID, err := usbLocalDeviceID("1050:0407")
+	require.NoError(t, err)
+	assert.Equal(t, "1050:0407", deviceID)
+
+	// IDs are normalised to lower case.
+	deviceID, err = usbLocalDeviceID("046D:C52B")
+	require.NoError(t, err)
+	assert.Equal(t, "046d:c52b", deviceID)
+	assert.Equal(t, "usb-046d-c52b", usbRedirDefaultName(deviceID))
+
+	for _, local := range []string{"", "1050", "1050:", "1050:407", "1050:04070", "105g:0407", "1050-0407", "001:002"} {
+		_, err = usbLocalDeviceID(local)
+		assert.Error(t, err, local)
+	}
+}
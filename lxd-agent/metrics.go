@@ -3,7 +3,9 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -66,6 +68,13 @@ func metricsGet(d *Daemon, r *http.Request) response.Response {
 		logger.Warn("Failed getting total processes", logger.Ctx{"err": err})
 	}
 
+	pressureStats, err := getPressureMetrics()
+	if err != nil {
+		logger.Warn("Failed getting pressure metrics", logger.Ctx{"err": err})
+	} else {
+		out.Pressure = pressureStats
+	}
+
 	cpuStats, err := getCPUMetrics()
 	if err != nil {
 		logger.Warn("Failed getting CPU metrics", logger.Ctx{"err": err})
@@ -164,6 +173,29 @@ func getCPUMetrics() (map[string]metrics.CPUMetrics, error) {
 	return out, nil
 }
 
+func getPressureMetrics() (map[string]metrics.PressureMetrics, error) {
+	out := map[string]metrics.PressureMetrics{}
+
+	for _, resource := range []string{"cpu", "memory", "io"} {
+		stats, err := getPressure(resource)
+		if err != nil {
+			// Skip if the kernel doesn't support pressure stall information.
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, nil
+			}
+
+			return nil, fmt.Errorf("Failed getting %q pressure: %w", resource, err)
+		}
+
+		out[resource] = metrics.PressureMetrics{
+			SomeSeconds: float64(stats.Some.Total) / 1000000,
+			FullSeconds: float64(stats.Full.Total) / 1000000,
+		}
+	}
+
+	return out, nil
+}
+
 func getTotalProcesses() (uint64, error) {
 	entries, err := os.ReadDir("/proc")
 	if err != nil {
@@ -14,6 +14,7 @@ import (
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/psi"
 )
 
 var stateCmd = APIEndpoint{
@@ -39,6 +40,7 @@ func renderState() *api.InstanceState {
 		Network:   networkState(),
 		Pid:       1,
 		Processes: processesState(),
+		Pressure:  pressureState(),
 	}
 }
 
@@ -229,3 +231,39 @@ func processesState() int64 {
 
 	return int64(len(pids))
 }
+
+// getPressure returns the system-wide pressure stall information for the given resource (cpu, memory or io).
+func getPressure(resource string) (*psi.Stats, error) {
+	content, err := os.ReadFile("/proc/pressure/" + resource)
+	if err != nil {
+		return nil, err
+	}
+
+	return psi.Parse(string(content))
+}
+
+func pressureState() *api.InstanceStatePressure {
+	getResource := func(resource string) *api.InstanceStatePressureResource {
+		stats, err := getPressure(resource)
+		if err != nil {
+			return nil
+		}
+
+		return &api.InstanceStatePressureResource{
+			Some: api.InstanceStatePressureValues{Avg10: stats.Some.Avg10, Avg60: stats.Some.Avg60, Avg300: stats.Some.Avg300, Total: stats.Some.Total},
+			Full: api.InstanceStatePressureValues{Avg10: stats.Full.Avg10, Avg60: stats.Full.Avg60, Avg300: stats.Full.Avg300, Total: stats.Full.Total},
+		}
+	}
+
+	pressure := api.InstanceStatePressure{
+		CPU:    getResource("cpu"),
+		Memory: getResource("memory"),
+		IO:     getResource("io"),
+	}
+
+	if pressure.CPU == nil && pressure.Memory == nil && pressure.IO == nil {
+		return nil
+	}
+
+	return &pressure
+}
@@ -65,6 +65,9 @@ var api10 = []APIEndpoint{
 	instanceBackupCmd,
 	instanceBackupExportCmd,
 	instanceBackupsCmd,
+	instanceCheckpointCmd,
+	instanceCheckpointExportCmd,
+	instanceCheckpointsCmd,
 	instanceCmd,
 	instanceConsoleCmd,
 	instanceExecCmd,
@@ -77,11 +80,13 @@ var api10 = []APIEndpoint{
 	instanceMetadataTemplatesCmd,
 	instancesCmd,
 	instanceRebuildCmd,
+	instanceReplicationCmd,
 	instanceSFTPCmd,
 	instanceSnapshotCmd,
 	instanceSnapshotsCmd,
 	instanceStateCmd,
 	instanceUEFIVarsCmd,
+	instanceUSBRedirCmd,
 	eventsCmd,
 	imageAliasCmd,
 	imageAliasesCmd,
@@ -120,6 +125,7 @@ var api10 = []APIEndpoint{
 	projectStateCmd,
 	storagePoolCmd,
 	storagePoolResourcesCmd,
+	storagePoolHealthCmd,
 	storagePoolsCmd,
 	storagePoolBucketsCmd,
 	storagePoolBucketCmd,
@@ -134,6 +140,9 @@ var api10 = []APIEndpoint{
 	storagePoolVolumeTypeCustomBackupCmd,
 	storagePoolVolumeTypeCustomBackupExportCmd,
 	storagePoolVolumeTypeStateCmd,
+	storagePoolVolumeTypeReplicationCmd,
+	storagePoolVolumeSnapshotTypeFilesCmd,
+	storagePoolVolumeTypeDiffCmd,
 	warningsCmd,
 	warningCmd,
 	metricsCmd,
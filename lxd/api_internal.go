@@ -69,6 +69,7 @@ var apiInternal = []APIEndpoint{
 	internalPruneTokenCmd,
 	internalOperationWaitCmd,
 	internalSnapshotScheduledTaskCmd,
+	internalStorageUsageTaskCmd,
 }
 
 var internalShutdownCmd = APIEndpoint{
@@ -178,6 +179,12 @@ var internalSnapshotScheduledTaskCmd = APIEndpoint{
 	Post: APIEndpointAction{Handler: internalSnapshotScheduledTask, AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanEdit)},
 }
 
+var internalStorageUsageTaskCmd = APIEndpoint{
+	Path: "testing/storage-usage-task",
+
+	Post: APIEndpointAction{Handler: internalStorageUsageTask, AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanEdit)},
+}
+
 type internalImageOptimizePost struct {
 	Image   api.Image `json:"image"    yaml:"image"`
 	Pool    string    `json:"pool"     yaml:"pool"`
@@ -1215,3 +1222,12 @@ func internalSnapshotScheduledTask(d *Daemon, r *http.Request) response.Response
 
 	return response.EmptySyncResponse
 }
+
+func internalStorageUsageTask(d *Daemon, r *http.Request) response.Response {
+	err := checkStorageUsage(r.Context(), d.State())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
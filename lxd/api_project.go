@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"maps"
 	"net"
 	"net/http"
@@ -19,11 +20,13 @@ import (
 
 	"github.com/canonical/lxd/client"
 	"github.com/canonical/lxd/lxd/auth"
+	"github.com/canonical/lxd/lxd/cgroup"
 	"github.com/canonical/lxd/lxd/cluster"
 	"github.com/canonical/lxd/lxd/config"
 	"github.com/canonical/lxd/lxd/db"
 	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
 	"github.com/canonical/lxd/lxd/db/operationtype"
+	"github.com/canonical/lxd/lxd/instance/instancetype"
 	"github.com/canonical/lxd/lxd/lifecycle"
 	"github.com/canonical/lxd/lxd/network"
 	"github.com/canonical/lxd/lxd/node"
@@ -626,6 +629,17 @@ func projectPut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	reqInfo, err := request.GetRequestor(r.Context())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// On other cluster members, only apply the resource pool limits locally.
+	if reqInfo.IsClusterNotification() {
+		projectResourcePoolsApply(s, project.Name, req.Config)
+		return response.EmptySyncResponse
+	}
+
 	requestor := request.CreateRequestor(r.Context())
 	s.Events.SendLifecycle(project.Name, lifecycle.ProjectUpdated.Event(project.Name, requestor, nil))
 
@@ -866,9 +880,50 @@ func projectChange(ctx context.Context, s *state.State, project *api.Project, re
 		return response.SmartError(err)
 	}
 
+	// Apply the changed resource pool limits on all cluster members.
+	if slices.ContainsFunc(configChanged, func(key string) bool { return strings.HasPrefix(key, "resource_pools.") }) {
+		projectResourcePoolsApply(s, project.Name, req.Config)
+
+		notifier, err := cluster.NewNotifier(s, s.Endpoints.NetworkCert(), s.ServerCert(), cluster.NotifyAlive)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = notifier(func(member db.NodeInfo, client lxd.InstanceServer) error {
+			return client.UpdateProject(project.Name, req, "")
+		})
+		if err != nil {
+			return response.SmartError(fmt.Errorf("Failed notifying other cluster members: %w", err))
+		}
+	}
+
 	return response.EmptySyncResponse
 }
 
+// projectResourcePoolsApply applies the resource pool limits of a project to the pools in use on the local member.
+func projectResourcePoolsApply(s *state.State, projectName string, config map[string]string) {
+	if s.OS.CGInfo.Layout != cgroup.CgroupsUnified {
+		return
+	}
+
+	for _, poolName := range projecthelpers.ResourcePools(config) {
+		cg, err := cgroup.NewPool(projecthelpers.ResourcePool(projectName, poolName), false)
+		if err != nil {
+			// The pool's cgroup only exists while it has running members.
+			if !errors.Is(err, fs.ErrNotExist) {
+				logger.Warn("Failed loading resource pool cgroup", logger.Ctx{"project": projectName, "resourcePool": poolName, "err": err})
+			}
+
+			continue
+		}
+
+		err = cg.SetPoolLimits(config["resource_pools."+poolName+".limits.cpu.allowance"], config["resource_pools."+poolName+".limits.memory"])
+		if err != nil {
+			logger.Warn("Failed applying resource pool limits", logger.Ctx{"project": projectName, "resourcePool": poolName, "err": err})
+		}
+	}
+}
+
 func projectNodeConfigRename(d *Daemon, ctx context.Context, oldName string, newName string) error {
 	var localConfig *node.Config
 
@@ -1640,6 +1695,22 @@ func projectValidateConfig(s *state.State, config map[string]string, defaultNetw
 		//  type: string
 		//  shortdesc: Maximum disk space used by the project
 		"limits.disk": validate.Optional(validate.IsSize),
+		// lxdmeta:generate(entities=project; group=limits; key=limits.disk.bandwidth)
+		// This value is the maximum value for the sum of the individual {config:option}`device-disk-device-conf:limits.read` and {config:option}`device-disk-device-conf:limits.write` configurations (each summed separately) set in byte/s on the disk devices that use a storage volume in the project.
+		// Each device counts with the higher of its base and burst limits, and a direction without a limit doesn't count.
+		// If only this limit is set, all such disk devices must have a read or write limit set in byte/s.
+		// ---
+		//  type: string
+		//  shortdesc: Maximum disk I/O bandwidth used by the project
+		"limits.disk.bandwidth": validate.Optional(validate.IsSize),
+		// lxdmeta:generate(entities=project; group=limits; key=limits.disk.iops)
+		// This value is the maximum value for the sum of the individual {config:option}`device-disk-device-conf:limits.read` and {config:option}`device-disk-device-conf:limits.write` configurations (each summed separately) set in IOPS on the disk devices that use a storage volume in the project.
+		// Each device counts with the higher of its base and burst limits, and a direction without a limit doesn't count.
+		// If only this limit is set, all such disk devices must have a read or write limit set in IOPS.
+		// ---
+		//  type: integer
+		//  shortdesc: Maximum disk IOPS used by the project
+		"limits.disk.iops": validate.Optional(validate.IsUint32),
 		// lxdmeta:generate(entities=project; group=limits; key=limits.networks)
 		//
 		// ---
@@ -1893,6 +1964,26 @@ func projectValidateConfig(s *state.State, config map[string]string, defaultNetw
 		//  defaultdesc: `block`
 		//  shortdesc: When set to `block`, creating instance or volume snapshots is prevented
 		"restricted.snapshots": isEitherAllowOrBlock,
+		// lxdmeta:generate(entities=project; group=limits; key=resource_pools.POOL_NAME.limits.cpu.allowance)
+		// Defines a resource pool inside the project and sets the CPU allowance shared by all its member instances.
+		// Specify either a percentage (`50%`) for a soft limit or a chunk of time (`200ms/100ms`) for a hard limit.
+		//
+		// Instances join the pool through their {config:option}`instance-resource-limits:limits.resource_pool` option.
+		// See {ref}`project-limits-resource-pools` for more information.
+		// ---
+		//  type: string
+		//  shortdesc: CPU allowance shared by the instances of a resource pool
+		"resource_pools.*.limits.cpu.allowance": validate.Optional(instancetype.InstanceConfigKeysContainer["limits.cpu.allowance"]),
+		// lxdmeta:generate(entities=project; group=limits; key=resource_pools.POOL_NAME.limits.memory)
+		// Defines a resource pool inside the project and sets the memory limit shared by all its member instances.
+		// Specify a fixed value in bytes with the various suffixes (see {ref}`instances-limit-units`).
+		//
+		// Instances join the pool through their {config:option}`instance-resource-limits:limits.resource_pool` option.
+		// See {ref}`project-limits-resource-pools` for more information.
+		// ---
+		//  type: string
+		//  shortdesc: Memory limit shared by the instances of a resource pool
+		"resource_pools.*.limits.memory": validate.Optional(validate.IsSize),
 	}
 
 	// Add the storage pool keys.
@@ -1974,6 +2065,19 @@ func projectValidateConfig(s *state.State, config map[string]string, defaultNetw
 			continue
 		}
 
+		// Resource pool keys are of the form "resource_pools.<name>.<key>".
+		poolKey, isPoolKey := strings.CutPrefix(key, "resource_pools.")
+		if isPoolKey {
+			poolName, poolKey, _ := strings.Cut(poolKey, ".")
+
+			err := instancetype.ValidResourcePoolName(poolName)
+			if err != nil {
+				return fmt.Errorf("Invalid project configuration key %q: %w", k, err)
+			}
+
+			key = "resource_pools.*." + poolKey
+		}
+
 		// Then validate.
 		validator, ok := projectConfigKeys[key]
 		if !ok {
@@ -5,7 +5,7 @@ import (
 )
 
 var lxcProfileTpl = template.Must(template.New("lxcProfile").Parse(`#include <tunables/global>
-profile "{{ .name }}" flags=(attach_disconnected,mediate_deleted) {
+profile "{{ .name }}" flags=(attach_disconnected,mediate_deleted{{ if .complain }},complain{{ end }}) {
   ### Base profile
   capability,
   dbus,
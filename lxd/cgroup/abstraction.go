@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/psi"
 )
 
 // CGroup represents the main cgroup abstraction.
@@ -679,7 +680,7 @@ func (cg *CGroup) SetBlkioLimit(dev string, oType string, uType string, limit in
 	case Unavailable:
 		return ErrControllerMissing
 	case V1:
-		return cg.rw.Set(version, "blkio", "blkio.throttle."+oType+"_"+uType+"_device", dev+strconv.FormatInt(limit, 10))
+		return cg.rw.Set(version, "blkio", "blkio.throttle."+oType+"_"+uType+"_device", dev+" "+strconv.FormatInt(limit, 10))
 	case V2:
 		var op string
 		switch oType {
@@ -695,6 +696,84 @@ func (cg *CGroup) SetBlkioLimit(dev string, oType string, uType string, limit in
 	return ErrUnknownVersion
 }
 
+// SetIOMax sets all the I/O throttling limits of a block device at once.
+// A limit of 0 removes the corresponding throttling.
+func (cg *CGroup) SetIOMax(dev string, readBps int64, readIops int64, writeBps int64, writeIops int64) error {
+	version := cgControllers["blkio"]
+	switch version {
+	case Unavailable:
+		return ErrControllerMissing
+	case V1:
+		limits := []struct {
+			oType string
+			uType string
+			limit int64
+		}{
+			{"read", "bps", readBps},
+			{"read", "iops", readIops},
+			{"write", "bps", writeBps},
+			{"write", "iops", writeIops},
+		}
+
+		for _, l := range limits {
+			err := cg.SetBlkioLimit(dev, l.oType, l.uType, l.limit)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case V2:
+		formatLimit := func(limit int64) string {
+			if limit <= 0 {
+				return "max"
+			}
+
+			return strconv.FormatInt(limit, 10)
+		}
+
+		return cg.rw.Set(version, "io", "io.max", dev+" rbps="+formatLimit(readBps)+" wbps="+formatLimit(writeBps)+" riops="+formatLimit(readIops)+" wiops="+formatLimit(writeIops))
+	}
+
+	return ErrUnknownVersion
+}
+
+// SetIOLatency sets the I/O latency target (in microseconds) of a block device.
+// A target of 0 removes it.
+func (cg *CGroup) SetIOLatency(dev string, target int64) error {
+	version := cgControllers["blkio"]
+	switch version {
+	case Unavailable, V1:
+		return ErrControllerMissing
+	case V2:
+		if target <= 0 {
+			return cg.rw.Set(version, "io", "io.latency", dev+" target=max")
+		}
+
+		return cg.rw.Set(version, "io", "io.latency", dev+" target="+strconv.FormatInt(target, 10))
+	}
+
+	return ErrUnknownVersion
+}
+
+// SetIOWeight sets the proportional I/O weight (1-10000) of a block device.
+// A weight of 0 resets it to the default weight.
+func (cg *CGroup) SetIOWeight(dev string, weight int64) error {
+	version := cgControllers["blkio"]
+	switch version {
+	case Unavailable, V1:
+		return ErrControllerMissing
+	case V2:
+		if weight <= 0 {
+			return cg.rw.Set(version, "io", "io.weight", dev+" default")
+		}
+
+		return cg.rw.Set(version, "io", "io.weight", dev+" "+strconv.FormatInt(weight, 10))
+	}
+
+	return ErrUnknownVersion
+}
+
 // SetCPUShare sets the weight of each group in the same hierarchy.
 func (cg *CGroup) SetCPUShare(limit int64) error {
 	version := cgControllers["cpu"]
@@ -899,6 +978,26 @@ func (cg *CGroup) GetOOMKills() (uint64, error) {
 	return 0, errors.New("Failed getting oom_kill")
 }
 
+// GetPressure returns the pressure stall information for the given resource (cpu, memory or io).
+func (cg *CGroup) GetPressure(resource string) (*psi.Stats, error) {
+	controller := resource
+	if resource == "io" {
+		controller = "blkio"
+	}
+
+	version := cgControllers[controller]
+	if version != V2 {
+		return nil, ErrControllerMissing
+	}
+
+	stats, err := cg.rw.Get(version, controller, resource+".pressure")
+	if err != nil {
+		return nil, err
+	}
+
+	return psi.Parse(stats)
+}
+
 // GetIOStats returns disk stats.
 func (cg *CGroup) GetIOStats() (map[string]*IOStats, error) {
 	partitions, err := os.ReadFile("/proc/partitions")
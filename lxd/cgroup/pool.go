@@ -0,0 +1,130 @@
+package cgroup
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/units"
+)
+
+// PoolDir returns the cgroup directory (relative to the cgroup root used by LXC) of a resource pool.
+func PoolDir(name string) string {
+	return "lxd.pool." + name
+}
+
+// poolPath returns the full path of a resource pool's cgroup.
+func poolPath(name string) (string, error) {
+	// LXC creates its cgroups relative to the cgroup of PID 1.
+	controllers, err := os.ReadFile("/proc/1/cgroup")
+	if err != nil {
+		return "", err
+	}
+
+	for line := range strings.SplitSeq(string(controllers), "\n") {
+		cgroupPath, found := strings.CutPrefix(strings.TrimSpace(line), "0::")
+		if !found {
+			continue
+		}
+
+		cgroupPath, _ = strings.CutSuffix(cgroupPath, "/init.scope")
+
+		return filepath.Join(cgPath, cgroupPath, PoolDir(name)), nil
+	}
+
+	return "", errors.New("Failed finding the cgroup2 hierarchy of PID 1")
+}
+
+// NewPool returns a CGroup for the parent cgroup of a resource pool.
+// When create is false and the cgroup doesn't exist yet, an error matching fs.ErrNotExist is returned.
+func NewPool(name string, create bool) (*CGroup, error) {
+	if cgLayout != CgroupsUnified {
+		return nil, errors.New("Resource pools require a pure cgroup2 host")
+	}
+
+	path, err := poolPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if create {
+		err = os.Mkdir(path, 0755)
+		if err != nil && !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("Failed creating resource pool cgroup %q: %w", path, err)
+		}
+
+		// Delegate all the available controllers to the member instances.
+		controllers, err := os.ReadFile(filepath.Join(path, "cgroup.controllers"))
+		if err != nil {
+			return nil, err
+		}
+
+		enable := []string{}
+		for _, controller := range strings.Fields(string(controllers)) {
+			enable = append(enable, "+"+controller)
+		}
+
+		if len(enable) > 0 {
+			err = os.WriteFile(filepath.Join(path, "cgroup.subtree_control"), []byte(strings.Join(enable, " ")), 0600)
+			if err != nil {
+				return nil, fmt.Errorf("Failed enabling controllers for resource pool cgroup %q: %w", path, err)
+			}
+		}
+	} else if !shared.PathExists(path) {
+		return nil, fmt.Errorf("Resource pool cgroup %q: %w", path, fs.ErrNotExist)
+	}
+
+	return New(&fileReadWriter{paths: map[string]string{"unified": path}})
+}
+
+// SetPoolLimits applies the shared CPU allowance and memory limit of a resource pool.
+// Empty values remove the corresponding limit.
+func (cg *CGroup) SetPoolLimits(cpuAllowance string, memory string) error {
+	memoryLimit := int64(-1)
+	if memory != "" {
+		var err error
+
+		memoryLimit, err = units.ParseByteSizeString(memory)
+		if err != nil {
+			return fmt.Errorf("Invalid memory limit %q: %w", memory, err)
+		}
+	}
+
+	err := cg.SetMemoryLimit(memoryLimit)
+	if err != nil {
+		return err
+	}
+
+	cpuShares, cpuCfsQuota, cpuCfsPeriod, err := ParseCPU(cpuAllowance, "")
+	if err != nil {
+		return err
+	}
+
+	err = cg.SetCPUShare(cpuShares)
+	if err != nil {
+		return err
+	}
+
+	return cg.SetCPUCfsLimit(cpuCfsPeriod, cpuCfsQuota)
+}
+
+// DeletePool removes the parent cgroup of a resource pool if it has no member instances left.
+func DeletePool(name string) error {
+	path, err := poolPath(name)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, unix.EBUSY) {
+		return err
+	}
+
+	return nil
+}
@@ -1891,6 +1891,14 @@ func (d *Daemon) init() error {
 			logger.Info("Started seccomp handler", logger.Ctx{"path": shared.VarPath("seccomp.socket")})
 		}
 
+		// Setup the kernel audit log listener for instances in complain mode.
+		if !d.os.RunningInUserNS {
+			err = StartSecurityAuditListener(d.shutdownCtx, d.State())
+			if err != nil {
+				logger.Warn("Failed starting security audit listener", logger.Ctx{"err": err})
+			}
+		}
+
 		// Read the trusted identities
 		updateIdentityCache(d)
 	}
@@ -1966,6 +1974,22 @@ func (d *Daemon) init() error {
 
 		// Remove expired tokens (hourly)
 		d.tasks.Add(autoRemoveExpiredTokensTask(d.State))
+
+		// Adjust automatically managed VM memory balloons (every 30s)
+		d.tasks.Add(instancesMemoryBalloonUpdateTask(d.State))
+
+		// Check storage pool health (hourly)
+		d.tasks.Add(storagePoolsHealthCheckTask(d.State))
+
+		// Scrub storage pools (minutely check of configurable cron expression)
+		d.tasks.Add(autoScrubStoragePoolsTask(d.State))
+
+		// Extend storage pools and check storage volume usage (every 5 minutes)
+		d.tasks.Add(storageUsageCheckTask(d.State))
+
+		// Synchronize custom volume and instance replicas (minutely check of configurable cron expression)
+		d.tasks.Add(autoReplicateCustomVolumesTask(d.State))
+		d.tasks.Add(autoReplicateInstancesTask(d.State))
 	}
 
 	// Load Ubuntu Pro configuration before starting any instances.
@@ -55,6 +55,7 @@ const (
 	TypeUnixHotplug = DeviceType(9)
 	TypeTPM         = DeviceType(10)
 	TypePCI         = DeviceType(11)
+	TypeSerial      = DeviceType(12)
 )
 
 func (t DeviceType) String() string {
@@ -83,6 +84,8 @@ func (t DeviceType) String() string {
 		return "tpm"
 	case TypePCI:
 		return "pci"
+	case TypeSerial:
+		return "serial"
 	}
 
 	return ""
@@ -115,6 +118,8 @@ func NewDeviceType(t string) (DeviceType, error) {
 		return TypeTPM, nil
 	case "pci":
 		return TypePCI, nil
+	case "serial":
+		return TypeSerial, nil
 	default:
 		return -1, fmt.Errorf("Invalid device type %q", t)
 	}
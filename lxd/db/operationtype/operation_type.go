@@ -106,6 +106,13 @@ const (
 	Wait
 	SnapshotsCreateScheduled
 	PruneExpiredOperations
+	CheckpointExport
+	VolumeReplicate
+	VolumeReplicationsScheduled
+	InstanceReplicate
+	InstanceReplicationsScheduled
+	VolumeSnapshotFilesRestore
+	VolumeDiff
 
 	// upperBound is used only to enforce consistency in the package on init.
 	// Make sure it's always the last item in this list.
@@ -279,6 +286,20 @@ func (t Type) Description() string {
 		return "Creating scheduled instance snapshots"
 	case PruneExpiredOperations:
 		return "Pruning expired operations"
+	case CheckpointExport:
+		return "Exporting instance checkpoint"
+	case VolumeReplicate:
+		return "Replicating storage volume"
+	case VolumeReplicationsScheduled:
+		return "Running scheduled storage volume replications"
+	case InstanceReplicate:
+		return "Replicating instance"
+	case InstanceReplicationsScheduled:
+		return "Running scheduled instance replications"
+	case VolumeSnapshotFilesRestore:
+		return "Restoring files from storage volume snapshot"
+	case VolumeDiff:
+		return "Comparing storage volume with snapshot"
 
 	// It should never be possible to reach the default clause.
 	// See the init function.
@@ -295,7 +316,8 @@ func (t Type) EntityType() entity.Type {
 		ImagesSynchronize, RemoveExpiredOIDCSessions, RemoveExpiredTokens, RemoveOrphanedOperations,
 		WarningsPruneResolved, ClusterMemberEvacuate, ClusterMemberRestore, LogsExpire, InstanceTypesUpdate,
 		BackupsExpire, SnapshotsExpire, ClusterJoinToken, CertificateAddToken, RenewServerCertificate,
-		ClusterHeal, ImagesUpdate, VolumeSnapshotsCreateScheduled, SnapshotsCreateScheduled, PruneExpiredOperations:
+		ClusterHeal, ImagesUpdate, VolumeSnapshotsCreateScheduled, SnapshotsCreateScheduled, PruneExpiredOperations,
+		VolumeReplicationsScheduled, InstanceReplicationsScheduled:
 		return entity.TypeServer
 
 	// Project level operations.
@@ -306,18 +328,20 @@ func (t Type) EntityType() entity.Type {
 		return entity.TypeProject
 
 	// Volume operations.
-	case VolumeMigrate, VolumeMove, VolumeSnapshotCreate, CustomVolumeBackupCreate, VolumeCopy, VolumeUpdate, VolumeDelete:
+	case VolumeMigrate, VolumeMove, VolumeSnapshotCreate, CustomVolumeBackupCreate, VolumeCopy, VolumeUpdate, VolumeDelete,
+		VolumeReplicate, VolumeDiff:
 		return entity.TypeStorageVolume
 
 	// Volume snapshot operations
-	case VolumeSnapshotRename, VolumeSnapshotUpdate, VolumeSnapshotDelete, VolumeSnapshotTransfer, VolumeSnapshotCopy:
+	case VolumeSnapshotRename, VolumeSnapshotUpdate, VolumeSnapshotDelete, VolumeSnapshotTransfer, VolumeSnapshotCopy,
+		VolumeSnapshotFilesRestore:
 		return entity.TypeStorageVolumeSnapshot
 
 	// Instance operations.
 	case BackupCreate, ConsoleShow, InstanceFreeze, InstanceUpdate, InstanceUnfreeze,
 		InstanceStart, InstanceStop, InstanceRestart, InstanceRename, InstanceMigrate, InstanceLiveMigrate,
 		InstanceDelete, InstanceRebuild, SnapshotRestore, CommandExec, SnapshotCreate, InstanceCopy,
-		Wait:
+		Wait, CheckpointExport, InstanceReplicate:
 		return entity.TypeInstance
 
 	// Instance backup operations.
@@ -910,6 +910,7 @@ var NodeSpecificStorageConfig = []string{
 	"source.wipe",
 	"source.recover",
 	"volatile.initial_source",
+	"volatile.reflink",
 	"zfs.pool_name",
 	"lvm.thinpool_name",
 	"lvm.vg_name",
@@ -58,6 +58,14 @@ const (
 	StoragePoolUnvailable
 	// UnableToUpdateClusterCertificate represents the unable to update cluster certificate warning.
 	UnableToUpdateClusterCertificate
+	// StoragePoolDegraded represents a storage pool whose health checks report it as degraded.
+	StoragePoolDegraded
+	// StorageVolumeNearlyFull represents a storage volume whose usage is above its warning threshold.
+	StorageVolumeNearlyFull
+	// StoragePoolExtendFailed represents a storage pool that is above its automatic extension threshold but could not be extended.
+	StoragePoolExtendFailed
+	// StoragePoolNearlyFull represents a storage pool whose usage is above its warning threshold.
+	StoragePoolNearlyFull
 )
 
 // TypeNames associates a warning code to its name.
@@ -88,6 +96,10 @@ var TypeNames = map[Type]string{
 	InstanceTypeNotOperational:             "Instance type not operational",
 	StoragePoolUnvailable:                  "Storage pool unavailable",
 	UnableToUpdateClusterCertificate:       "Cannot update cluster certificate",
+	StoragePoolDegraded:                    "Storage pool degraded",
+	StorageVolumeNearlyFull:                "Storage volume nearly full",
+	StoragePoolExtendFailed:                "Storage pool extension failed",
+	StoragePoolNearlyFull:                  "Storage pool nearly full",
 }
 
 // Severity returns the severity of the warning type.
@@ -145,6 +157,14 @@ func (t Type) Severity() Severity {
 		return SeverityHigh
 	case UnableToUpdateClusterCertificate:
 		return SeverityLow
+	case StoragePoolDegraded:
+		return SeverityHigh
+	case StorageVolumeNearlyFull:
+		return SeverityModerate
+	case StoragePoolExtendFailed:
+		return SeverityHigh
+	case StoragePoolNearlyFull:
+		return SeverityModerate
 	}
 
 	return SeverityLow
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/canonical/lxd/shared/units"
+)
+
+// ParseDiskLimits parses the given read, write and max keys of a disk configuration into I/O bytes/iops limits.
+func ParseDiskLimits(dev Device, readKey string, writeKey string, maxKey string) (readBps int64, readIops int64, writeBps int64, writeIops int64, err error) {
+	readSpeed := dev[readKey]
+	writeSpeed := dev[writeKey]
+
+	// Apply max limit.
+	if dev[maxKey] != "" {
+		readSpeed = dev[maxKey]
+		writeSpeed = dev[maxKey]
+	}
+
+	// parseValue parses a single value to either a B/s limit or iops limit.
+	parseValue := func(value string) (bps int64, iops int64, err error) {
+		if value == "" {
+			return bps, iops, nil
+		}
+
+		before, found := strings.CutSuffix(value, "iops")
+		if found {
+			iops, err = strconv.ParseInt(before, 10, 64)
+			if err != nil {
+				return -1, -1, err
+			}
+		} else {
+			bps, err = units.ParseByteSizeString(value)
+			if err != nil {
+				return -1, -1, err
+			}
+		}
+
+		return bps, iops, nil
+	}
+
+	// Process reads.
+	readBps, readIops, err = parseValue(readSpeed)
+	if err != nil {
+		return -1, -1, -1, -1, err
+	}
+
+	// Process writes.
+	writeBps, writeIops, err = parseValue(writeSpeed)
+	if err != nil {
+		return -1, -1, -1, -1, err
+	}
+
+	return readBps, readIops, writeBps, writeIops, nil
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDiskLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		dev       Device
+		readBps   int64
+		readIops  int64
+		writeBps  int64
+		writeIops int64
+		expectErr bool
+	}{
+		{
+			name: "NoLimits",
+			dev:  Device{"type": "disk"},
+		},
+		{
+			name:      "ReadAndWrite",
+			dev:       Device{"limits.read": "10MB", "limits.write": "100iops"},
+			readBps:   10000000,
+			writeIops: 100,
+		},
+		{
+			name:      "MaxOverridesReadAndWrite",
+			dev:       Device{"limits.read": "10MB", "limits.write": "10MB", "limits.max": "200iops"},
+			readIops:  200,
+			writeIops: 200,
+		},
+		{
+			name:      "InvalidIops",
+			dev:       Device{"limits.read": "fooiops"},
+			expectErr: true,
+		},
+		{
+			name:      "InvalidSize",
+			dev:       Device{"limits.write": "foo"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readBps, readIops, writeBps, writeIops, err := ParseDiskLimits(tt.dev, "limits.read", "limits.write", "limits.max")
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.readBps, readBps)
+			assert.Equal(t, tt.readIops, readIops)
+			assert.Equal(t, tt.writeBps, writeBps)
+			assert.Equal(t, tt.writeIops, writeIops)
+		})
+	}
+}
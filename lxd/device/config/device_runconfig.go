@@ -82,6 +82,12 @@ type DiskLimits struct {
 	ReadIOps   int64
 	WriteBytes int64
 	WriteIOps  int64
+
+	ReadBytesBurst  int64
+	ReadIOpsBurst   int64
+	WriteBytesBurst int64
+	WriteIOpsBurst  int64
+	BurstDuration   int64 // Duration of the bursts in seconds.
 }
 
 // RunConfig represents run-time config used for device setup/cleanup.
@@ -97,6 +103,7 @@ type RunConfig struct {
 	USBDevice        []USBDeviceItem  // USB device configuration settings.
 	TPMDevice        []RunConfigItem  // TPM device configuration settings.
 	PCIDevice        []RunConfigItem  // PCI device configuration settings.
+	SerialDevice     []RunConfigItem  // Serial device configuration settings.
 	Revert           revert.Hook      // Revert setup of device on post-setup error.
 }
 
@@ -82,6 +82,8 @@ func newByType(state *state.State, projectName string, conf deviceConfig.Device)
 		dev = &tpm{}
 	case "pci":
 		dev = &pci{}
+	case "serial":
+		dev = &serial{}
 	}
 
 	// Check a valid device type has been found.
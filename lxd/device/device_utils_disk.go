@@ -5,22 +5,28 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/sys/unix"
 
+	"github.com/canonical/lxd/lxd/cgroup"
 	"github.com/canonical/lxd/lxd/idmap"
 	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/project"
 	storageDrivers "github.com/canonical/lxd/lxd/storage/drivers"
 	"github.com/canonical/lxd/lxd/storage/filesystem"
 	"github.com/canonical/lxd/lxd/subprocess"
 	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/osarch"
 	"github.com/canonical/lxd/shared/revert"
 )
@@ -197,6 +203,150 @@ func diskCephfsOptions(clusterName string, userName string, fsName string, fsPat
 	return srcPath, fsOptions, nil
 }
 
+// diskNetworkShare represents an NFS or SMB share used as a disk source.
+type diskNetworkShare struct {
+	scheme string
+	host   string
+	port   string
+	path   string
+}
+
+// diskParseNetworkShare parses a disk source of the form nfs://host[:port]/export or smb://host[:port]/share[/path].
+func diskParseNetworkShare(source string) (*diskNetworkShare, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "nfs" && u.Scheme != "smb" {
+		return nil, fmt.Errorf("Unsupported network share type %q", u.Scheme)
+	}
+
+	// Credentials would end up stored in the instance configuration.
+	if u.User != nil {
+		return nil, errors.New("Credentials cannot be included in the source (use smb.credentials instead)")
+	}
+
+	if u.RawQuery != "" || u.Fragment != "" {
+		return nil, errors.New("Query and fragment aren't supported")
+	}
+
+	if u.Hostname() == "" {
+		return nil, errors.New("Missing server name")
+	}
+
+	if u.Port() != "" {
+		port, err := strconv.ParseUint(u.Port(), 10, 16)
+		if err != nil || port == 0 {
+			return nil, fmt.Errorf("Invalid port %q", u.Port())
+		}
+	}
+
+	sharePath := path.Clean("/" + u.Path)
+	if sharePath == "/" && u.Scheme == "smb" {
+		return nil, errors.New("Missing share name")
+	}
+
+	return &diskNetworkShare{
+		scheme: u.Scheme,
+		host:   u.Hostname(),
+		port:   u.Port(),
+		path:   sharePath,
+	}, nil
+}
+
+// diskSMBCredentials reads a mount.cifs credentials file and returns the matching mount options.
+func diskSMBCredentials(credentialsPath string) ([]string, error) {
+	content, err := os.ReadFile(credentialsPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed reading SMB credentials file %q: %w", credentialsPath, err)
+	}
+
+	var options []string
+	for _, line := range strings.Split(string(content), "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if !found || strings.HasPrefix(key, "#") {
+			continue
+		}
+
+		key = strings.TrimSpace(key)
+		if key != "password" && key != "pass" && strings.Contains(value, ",") {
+			return nil, fmt.Errorf("Invalid value for %q in SMB credentials file", key)
+		}
+
+		switch key {
+		case "username", "user":
+			options = append(options, "username="+value)
+		case "password", "pass":
+			// The kernel treats a doubled comma as a literal comma in the password.
+			options = append(options, "password="+strings.ReplaceAll(value, ",", ",,"))
+		case "domain", "dom":
+			options = append(options, "domain="+value)
+		}
+	}
+
+	if len(options) == 0 {
+		return nil, fmt.Errorf("No credentials found in SMB credentials file %q", credentialsPath)
+	}
+
+	return options, nil
+}
+
+// diskNetworkShareOptions returns the mntSrcPath, filesystem type and fsOptions to use for mounting an NFS or
+// SMB share. The user supplied mount options are used to avoid overriding the protocol version.
+func diskNetworkShareOptions(share *diskNetworkShare, smbCredentials string, mntOptions []string) (string, string, []string, error) {
+	// The kernel doesn't resolve names itself, this is usually done by the mount helpers.
+	addrs, err := net.LookupHost(share.host)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("Failed resolving %q: %w", share.host, err)
+	}
+
+	hasOption := func(prefixes ...string) bool {
+		for _, option := range mntOptions {
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(option, prefix) {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	var fsOptions []string
+	if share.port != "" {
+		fsOptions = append(fsOptions, "port="+share.port)
+	}
+
+	if share.scheme == "nfs" {
+		fsOptions = append(fsOptions, "addr="+addrs[0])
+		if !hasOption("vers=", "nfsvers=") {
+			fsOptions = append(fsOptions, "vers=4.2")
+		}
+
+		host := share.host
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		return host + ":" + share.path, "nfs", fsOptions, nil
+	}
+
+	fsOptions = append(fsOptions, "ip="+addrs[0])
+	if smbCredentials != "" {
+		credentials, err := diskSMBCredentials(smbCredentials)
+		if err != nil {
+			return "", "", nil, err
+		}
+
+		fsOptions = append(fsOptions, credentials...)
+	} else if !hasOption("username=", "user=", "guest") {
+		fsOptions = append(fsOptions, "guest")
+	}
+
+	return "//" + share.host + share.path, "cifs", fsOptions, nil
+}
+
 // diskAddRootUserNSEntry takes a set of idmap entries, and adds host -> userns root uid/gid mappings if needed.
 // Returns the supplied idmap entries with any added root entries.
 func diskAddRootUserNSEntry(idmaps []idmap.IdmapEntry, hostRootID int64) []idmap.IdmapEntry {
@@ -382,3 +532,193 @@ func DiskVMVirtiofsdStop(socketPath string, pidPath string) error {
 
 	return nil
 }
+
+// diskBurstInterval is how often the I/O burst controllers check the I/O usage of a container.
+const diskBurstInterval = time.Second
+
+// diskBurstControllers holds the cancel functions of the running I/O burst controllers, keyed by instance.
+var diskBurstControllers = map[string]context.CancelFunc{}
+
+// diskBurstControllersMu protects diskBurstControllers.
+var diskBurstControllersMu sync.Mutex
+
+// diskBurstBucket tracks the burst credit of a single I/O limit (bytes or operations per second).
+type diskBurstBucket struct {
+	base     int64
+	burst    int64
+	capacity int64
+	credit   int64
+}
+
+// newDiskBurstBucket returns a full bucket, allowing I/O at the burst limit for duration seconds.
+func newDiskBurstBucket(base int64, burst int64, duration int64) *diskBurstBucket {
+	capacity := (burst - base) * duration
+
+	return &diskBurstBucket{base: base, burst: burst, capacity: capacity, credit: capacity}
+}
+
+// update accounts for the I/O used over the elapsed time and returns the limit to apply until the next update.
+// I/O above the base limit uses up the credit and I/O below it regains it, up to the bucket capacity.
+func (b *diskBurstBucket) update(used int64, elapsed time.Duration) int64 {
+	b.credit += int64(float64(b.base)*elapsed.Seconds()) - used
+	b.credit = min(max(b.credit, 0), b.capacity)
+
+	if b.credit > 0 {
+		return b.burst
+	}
+
+	return b.base
+}
+
+// diskBurstUsage returns the increase of an I/O counter, ignoring counter resets.
+func diskBurstUsage(previous uint64, current uint64) int64 {
+	if current < previous {
+		return 0
+	}
+
+	return int64(current - previous)
+}
+
+// diskBurstStart emulates the I/O burst limits of a running container, which cgroups don't support, by
+// switching the io.max limits of each block device between its burst and base limits depending on the
+// burst credit left. The limits must already be set to the burst limits.
+func diskBurstStart(inst instance.Instance, limits map[string]diskBlockLimit, l logger.Logger) error {
+	type burstBlock struct {
+		name    string
+		buckets [4]*diskBurstBucket // Read bytes, read IOPS, written bytes and write IOPS.
+		applied [4]int64
+		stats   cgroup.IOStats
+	}
+
+	blocks := make(map[string]*burstBlock, len(limits))
+	for block, limit := range limits {
+		// The I/O statistics are keyed by device name.
+		devPath, err := os.Readlink("/sys/dev/block/" + block)
+		if err != nil {
+			return fmt.Errorf("Failed resolving block device %q: %w", block, err)
+		}
+
+		blocks[block] = &burstBlock{
+			name: filepath.Base(devPath),
+			buckets: [4]*diskBurstBucket{
+				newDiskBurstBucket(limit.readBps, limit.readBpsBurst, limit.burstDuration),
+				newDiskBurstBucket(limit.readIops, limit.readIopsBurst, limit.burstDuration),
+				newDiskBurstBucket(limit.writeBps, limit.writeBpsBurst, limit.burstDuration),
+				newDiskBurstBucket(limit.writeIops, limit.writeIopsBurst, limit.burstDuration),
+			},
+			applied: [4]int64{limit.readBpsBurst, limit.readIopsBurst, limit.writeBpsBurst, limit.writeIopsBurst},
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	diskBurstControllersMu.Lock()
+	diskBurstControllers[project.Instance(inst.Project().Name, inst.Name())] = cancel
+	diskBurstControllersMu.Unlock()
+
+	getStats := func() (*cgroup.CGroup, map[string]*cgroup.IOStats, error) {
+		cg, err := inst.CGroup()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		stats, err := cg.GetIOStats()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return cg, stats, nil
+	}
+
+	go func() {
+		defer cancel()
+
+		// Only account for the I/O done from now on.
+		_, stats, err := getStats()
+		if err != nil {
+			l.Warn("Failed getting I/O usage for burst limits", logger.Ctx{"err": err})
+		}
+
+		for _, block := range blocks {
+			if stats[block.name] != nil {
+				block.stats = *stats[block.name]
+			}
+		}
+
+		ticker := time.NewTicker(diskBurstInterval)
+		defer ticker.Stop()
+
+		last := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if !inst.IsRunning() {
+				return
+			}
+
+			cg, stats, err := getStats()
+			if err != nil {
+				l.Warn("Failed getting I/O usage for burst limits", logger.Ctx{"err": err})
+				continue
+			}
+
+			now := time.Now()
+			elapsed := now.Sub(last)
+			last = now
+
+			for devNum, block := range blocks {
+				// Devices without any I/O yet are missing from the statistics.
+				current := block.stats
+				if stats[block.name] != nil {
+					current = *stats[block.name]
+				}
+
+				used := [4]int64{
+					diskBurstUsage(block.stats.ReadBytes, current.ReadBytes),
+					diskBurstUsage(block.stats.ReadsCompleted, current.ReadsCompleted),
+					diskBurstUsage(block.stats.WrittenBytes, current.WrittenBytes),
+					diskBurstUsage(block.stats.WritesCompleted, current.WritesCompleted),
+				}
+
+				block.stats = current
+
+				var limits [4]int64
+				for i, bucket := range block.buckets {
+					limits[i] = bucket.update(used[i], elapsed)
+				}
+
+				if limits == block.applied {
+					continue
+				}
+
+				err = cg.SetIOMax(devNum, limits[0], limits[1], limits[2], limits[3])
+				if err != nil {
+					l.Warn("Failed applying I/O burst limits", logger.Ctx{"block": devNum, "err": err})
+					continue
+				}
+
+				block.applied = limits
+			}
+		}
+	}()
+
+	return nil
+}
+
+// diskBurstStop stops the I/O burst controller of the instance, if any.
+func diskBurstStop(inst instance.Instance) {
+	key := project.Instance(inst.Project().Name, inst.Name())
+
+	diskBurstControllersMu.Lock()
+	defer diskBurstControllersMu.Unlock()
+
+	cancel, ok := diskBurstControllers[key]
+	if ok {
+		cancel()
+		delete(diskBurstControllers, key)
+	}
+}
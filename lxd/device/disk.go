@@ -12,6 +12,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/sys/unix"
 
@@ -35,7 +36,6 @@ import (
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
-	"github.com/canonical/lxd/shared/units"
 	"github.com/canonical/lxd/shared/validate"
 )
 
@@ -59,10 +59,22 @@ const DiskIOUring = "io_uring"
 const DiskLoopBacked = "loop"
 
 type diskBlockLimit struct {
-	readBps   int64
-	readIops  int64
-	writeBps  int64
-	writeIops int64
+	readBps        int64
+	readIops       int64
+	writeBps       int64
+	writeIops      int64
+	readBpsBurst   int64
+	readIopsBurst  int64
+	writeBpsBurst  int64
+	writeIopsBurst int64
+	burstDuration  int64 // Burst duration in seconds.
+	latency        int64 // Latency target in microseconds.
+	weight         int64
+}
+
+// hasBurst returns true if any of the burst limits goes above its base limit.
+func (l diskBlockLimit) hasBurst() bool {
+	return l.readBpsBurst > l.readBps || l.readIopsBurst > l.readIops || l.writeBpsBurst > l.writeBps || l.writeIopsBurst > l.writeIops
 }
 
 // diskSourceNotFoundError error used to indicate source not found.
@@ -112,6 +124,16 @@ func (d *disk) sourceIsCeph() bool {
 	return strings.HasPrefix(d.config["source"], "ceph:")
 }
 
+// sourceIsNFS returns true if the disks source config setting is an NFS export.
+func (d *disk) sourceIsNFS() bool {
+	return strings.HasPrefix(d.config["source"], "nfs://")
+}
+
+// sourceIsSMB returns true if the disks source config setting is an SMB share.
+func (d *disk) sourceIsSMB() bool {
+	return strings.HasPrefix(d.config["source"], "smb://")
+}
+
 // CanHotPlug returns whether the device can be managed whilst the instance is running.
 func (d *disk) CanHotPlug() bool {
 	// All disks can be hot-plugged.
@@ -125,7 +147,8 @@ func (d *disk) isRequired(devConfig deviceConfig.Device) bool {
 }
 
 // sourceIsLocalPath returns true if the source supplied should be considered a local path on the host.
-// It returns false if the disk source is empty, a VM cloud-init config drive, or a remote ceph/cephfs path.
+// It returns false if the disk source is empty, a VM cloud-init config drive, a remote ceph/cephfs path or a
+// network share.
 func (d *disk) sourceIsLocalPath(source string) bool {
 	if source == "" {
 		return false
@@ -135,7 +158,7 @@ func (d *disk) sourceIsLocalPath(source string) bool {
 		return false
 	}
 
-	if d.sourceIsCeph() || d.sourceIsCephFs() {
+	if d.sourceIsCeph() || d.sourceIsCephFs() || d.sourceIsNFS() || d.sourceIsSMB() {
 		return false
 	}
 
@@ -237,6 +260,27 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 		//  required: no
 		//  shortdesc: Whether to make the mount read-only
 		"readonly": validate.Optional(validate.IsBool),
+		// lxdmeta:generate(entities=device-disk; group=device-conf; key=overlay)
+		// The source is mounted read-only and a writable `overlayfs` layer is added on top of it.
+		// Changes are stored as configured by {config:option}`device-disk-device-conf:overlay.upper`
+		// and are discarded when the instance stops.
+		// ---
+		//  type: bool
+		//  defaultdesc: `false`
+		//  required: no
+		//  condition: container
+		//  shortdesc: Whether to add an ephemeral writable layer on top of the source
+		"overlay": validate.Optional(validate.IsBool),
+		// lxdmeta:generate(entities=device-disk; group=device-conf; key=overlay.upper)
+		// Possible values are `instance` (the default), which stores the changes in the instance volume,
+		// where they count against its quota, or `tmpfs`, which stores them in memory.
+		// ---
+		//  type: string
+		//  defaultdesc: `instance`
+		//  required: no
+		//  condition: container
+		//  shortdesc: Where to store the writable layer of overlay disks
+		"overlay.upper": validate.Optional(validate.IsOneOf("instance", "tmpfs")),
 		// lxdmeta:generate(entities=device-disk; group=device-conf; key=recursive)
 		//
 		// ---
@@ -305,6 +349,65 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 		//  required: no
 		//  shortdesc: I/O limit in byte/s or IOPS for both read and write
 		"limits.max": validate.IsAny,
+		// lxdmeta:generate(entities=device-disk; group=device-conf; key=limits.read.burst)
+		// Read I/O limit allowed for short bursts, in the same unit as {config:option}`device-disk-device-conf:limits.read` (byte/s or IOPS).
+		// It must be greater than or equal to the base limit.
+		// For containers, the burst is emulated by switching the `io.max` limit between the burst and base limits every second, depending on the I/O usage. Bursts are therefore approximate and aren't emulated on loop devices.
+		// See {ref}`storage-configure-io`.
+		// ---
+		//  type: string
+		//  required: no
+		//  shortdesc: Read I/O burst limit in byte/s or IOPS
+		"limits.read.burst": validate.IsAny,
+		// lxdmeta:generate(entities=device-disk; group=device-conf; key=limits.write.burst)
+		// Write I/O limit allowed for short bursts, in the same unit as {config:option}`device-disk-device-conf:limits.write` (byte/s or IOPS).
+		// It must be greater than or equal to the base limit.
+		// For containers, the burst is emulated by switching the `io.max` limit between the burst and base limits every second, depending on the I/O usage. Bursts are therefore approximate and aren't emulated on loop devices.
+		// See {ref}`storage-configure-io`.
+		// ---
+		//  type: string
+		//  required: no
+		//  shortdesc: Write I/O burst limit in byte/s or IOPS
+		"limits.write.burst": validate.IsAny,
+		// lxdmeta:generate(entities=device-disk; group=device-conf; key=limits.max.burst)
+		// This option is the same as setting both {config:option}`device-disk-device-conf:limits.read.burst` and {config:option}`device-disk-device-conf:limits.write.burst`.
+		// ---
+		//  type: string
+		//  required: no
+		//  shortdesc: I/O burst limit in byte/s or IOPS for both read and write
+		"limits.max.burst": validate.IsAny,
+		// lxdmeta:generate(entities=device-disk; group=device-conf; key=limits.burst.duration)
+		// How long the burst limits can be sustained, in whole seconds (for example, `30s`).
+		// ---
+		//  type: string
+		//  defaultdesc: `1s`
+		//  required: no
+		//  shortdesc: Duration of the I/O bursts
+		"limits.burst.duration": validate.Optional(func(value string) error {
+			_, err := diskParseDuration(value, time.Second)
+			return err
+		}),
+		// lxdmeta:generate(entities=device-disk; group=device-conf; key=limits.latency)
+		// Target I/O latency of the backing block device (for example, `10ms`), enforced through the `cgroup2` `io.latency` controller.
+		// When the target is missed, the I/O of other groups with a higher latency target on that device is throttled.
+		// ---
+		//  type: string
+		//  required: no
+		//  condition: container
+		//  shortdesc: Target I/O latency
+		"limits.latency": validate.Optional(func(value string) error {
+			_, err := diskParseDuration(value, time.Microsecond)
+			return err
+		}),
+		// lxdmeta:generate(entities=device-disk; group=device-conf; key=limits.weight)
+		// Proportional I/O weight (between `1` and `10000`) on the backing block device, enforced through the `cgroup2` `io.weight` controller.
+		// ---
+		//  type: integer
+		//  defaultdesc: `100`
+		//  required: no
+		//  condition: container
+		//  shortdesc: Proportional I/O weight
+		"limits.weight": validate.Optional(validate.IsInRange(1, 10000)),
 		// lxdmeta:generate(entities=device-disk; group=device-conf; key=size)
 		// This option is supported only for the rootfs (`/`).
 		//
@@ -363,6 +466,16 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 		//  required: for Ceph or CephFS sources
 		//  shortdesc: User name of the Ceph cluster
 		"ceph.user_name": validate.IsAny,
+		// lxdmeta:generate(entities=device-disk; group=device-conf; key=smb.credentials)
+		// The file must be in the `mount.cifs` credentials format, with `username=`, `password=` and
+		// optionally `domain=` lines. It is read by LXD when mounting the share, so the password is
+		// never stored in the instance configuration.
+		// If not set, the share is mounted as guest.
+		// ---
+		//  type: string
+		//  required: no
+		//  shortdesc: Absolute path on the host to the credentials file for SMB sources
+		"smb.credentials": validate.Optional(validate.IsAbsFilePath),
 		// lxdmeta:generate(entities=device-disk; group=device-conf; key=boot.priority)
 		// A higher value indicates a higher boot precedence for the disk device.
 		// This is useful for prioritizing boot sources like ISO-backed disks.
@@ -428,6 +541,21 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 		}
 	}
 
+	if d.config["overlay.upper"] != "" && shared.IsFalseOrEmpty(d.config["overlay"]) {
+		return errors.New(`"overlay.upper" can only be used on overlay disk devices`)
+	}
+
+	if shared.IsTrue(d.config["overlay"]) {
+		switch {
+		case instConf.Type() == instancetype.VM:
+			return errors.New("Overlay disks are only supported for containers")
+		case d.config["path"] == "/":
+			return errors.New("The root disk cannot be an overlay disk")
+		case shared.IsTrue(d.config["readonly"]):
+			return errors.New(`Overlay disks cannot be "readonly"`)
+		}
+	}
+
 	if d.config["required"] != "" && d.config["optional"] != "" {
 		return errors.New(`Cannot use both "required" and deprecated "optional" properties at the same time`)
 	}
@@ -468,11 +596,40 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 		return errors.New("Recursive read-only bind-mounts are not currently supported by the kernel")
 	}
 
+	hasBurstLimits := d.config["limits.read.burst"] != "" || d.config["limits.write.burst"] != "" || d.config["limits.max.burst"] != "" || d.config["limits.burst.duration"] != ""
+	if hasBurstLimits {
+		err = diskValidateBurstLimits(d.config)
+		if err != nil {
+			return err
+		}
+	}
+
+	if (d.config["limits.latency"] != "" || d.config["limits.weight"] != "") && instConf.Type() == instancetype.VM {
+		return errors.New(`The "limits.latency" and "limits.weight" options are only supported for containers`)
+	}
+
 	// Check ceph options are only used when ceph or cephfs type source is specified.
 	if !d.sourceIsCeph() && !d.sourceIsCephFs() && (d.config["ceph.cluster_name"] != "" || d.config["ceph.user_name"] != "") {
 		return fmt.Errorf("Invalid options ceph.cluster_name/ceph.user_name for source %q", d.config["source"])
 	}
 
+	// Check SMB options are only used when an SMB source is specified.
+	if !d.sourceIsSMB() && d.config["smb.credentials"] != "" {
+		return fmt.Errorf("Invalid option smb.credentials for source %q", d.config["source"])
+	}
+
+	if d.sourceIsNFS() || d.sourceIsSMB() {
+		_, err := diskParseNetworkShare(d.config["source"])
+		if err != nil {
+			return fmt.Errorf("Invalid source %q: %w", d.config["source"], err)
+		}
+
+		// Network filesystems don't support idmapped mounts.
+		if shared.IsTrue(d.config["shift"]) {
+			return errors.New(`The "shift" property cannot be used with NFS or SMB sources`)
+		}
+	}
+
 	// Check no other devices also have the same path as us. Use LocalDevices for this check so
 	// that we can check before the config is expanded or when a profile is being checked.
 	// Don't take into account the device names, only count active devices that point to the
@@ -732,7 +889,7 @@ func (d *disk) UpdatableFields(oldDevice Type) []string {
 		return []string{}
 	}
 
-	return []string{"limits.max", "limits.read", "limits.write", "size", "size.state"}
+	return []string{"limits.max", "limits.read", "limits.write", "limits.max.burst", "limits.read.burst", "limits.write.burst", "limits.burst.duration", "limits.latency", "limits.weight", "size", "size.state"}
 }
 
 // Register calls mount for the disk volume (which should already be mounted) to reinitialise the reference counter
@@ -843,7 +1000,7 @@ func (d *disk) startContainer() (*deviceConfig.RunConfig, error) {
 	runConf.PostHooks = append(runConf.PostHooks, func() error {
 		runConf := deviceConfig.RunConfig{}
 
-		err := d.generateLimits(&runConf)
+		err := d.generateLimits(&runConf, nil)
 		if err != nil {
 			return err
 		}
@@ -1050,17 +1207,11 @@ func (d *disk) startVM() (*deviceConfig.RunConfig, error) {
 	var diskLimits *deviceConfig.DiskLimits
 	if d.config["limits.read"] != "" || d.config["limits.write"] != "" || d.config["limits.max"] != "" {
 		// Parse the limits into usable values.
-		readBps, readIops, writeBps, writeIops, err := d.parseLimit(d.config)
+		var err error
+		diskLimits, err = d.vmDiskLimits(d.config)
 		if err != nil {
 			return nil, err
 		}
-
-		diskLimits = &deviceConfig.DiskLimits{
-			ReadBytes:  readBps,
-			ReadIOps:   readIops,
-			WriteBytes: writeBps,
-			WriteIOps:  writeIops,
-		}
 	}
 
 	if filters.IsRootDisk(d.config) {
@@ -1224,11 +1375,11 @@ func (d *disk) startVM() (*deviceConfig.RunConfig, error) {
 				}
 			}
 
-			// If the source being added is a directory or cephfs share, then we will use the lxd-agent
-			// directory sharing feature to mount the directory inside the VM, and as such we need to
-			// indicate to the VM the target path to mount to.
+			// If the source being added is a directory, a cephfs share or an NFS/SMB share, then we will use
+			// the lxd-agent directory sharing feature to mount the directory inside the VM, and as such we
+			// need to indicate to the VM the target path to mount to.
 			pathSource, isPath := mount.DevSource.(deviceConfig.DevSourcePath)
-			if (isPath && shared.IsDir(pathSource.Path)) || d.sourceIsCephFs() {
+			if (isPath && shared.IsDir(pathSource.Path)) || d.sourceIsCephFs() || d.sourceIsNFS() || d.sourceIsSMB() {
 				if d.config["path"] == "" {
 					return nil, errors.New(`Missing mount "path" setting`)
 				}
@@ -1418,26 +1569,19 @@ func (d *disk) Update(oldDevices deviceConfig.Devices, isRunning bool) error {
 
 		switch d.inst.Type() {
 		case instancetype.Container:
-			err := d.generateLimits(&runConf)
+			err := d.generateLimits(&runConf, oldDevices)
 			if err != nil {
 				return err
 			}
 
 		case instancetype.VM:
 			// Parse the limits into usable values.
-			readBps, readIops, writeBps, writeIops, err := d.parseLimit(d.config)
+			diskLimits, err := d.vmDiskLimits(d.config)
 			if err != nil {
 				return err
 			}
 
 			// Apply the limits to a minimal mount entry.
-			diskLimits := &deviceConfig.DiskLimits{
-				ReadBytes:  readBps,
-				ReadIOps:   readIops,
-				WriteBytes: writeBps,
-				WriteIOps:  writeIops,
-			}
-
 			runConf.Mounts = []deviceConfig.MountEntryItem{
 				{
 					DevName: d.name,
@@ -1520,17 +1664,28 @@ func (d *disk) applyQuota(remount bool) error {
 }
 
 // generateLimits adds a set of cgroup rules to apply specified limits to the supplied RunConfig.
-func (d *disk) generateLimits(runConf *deviceConfig.RunConfig) error {
-	// Disk throttle limits.
-	hasDiskLimits := false
-	for _, dev := range d.inst.ExpandedDevices().Filter(filters.IsDisk) {
-		if dev["limits.read"] != "" || dev["limits.write"] != "" || dev["limits.max"] != "" {
-			hasDiskLimits = true
-			break
+// When oldDevices is provided (live update), the limits that were set in oldDevices but have since been
+// removed are reset too.
+func (d *disk) generateLimits(runConf *deviceConfig.RunConfig, oldDevices deviceConfig.Devices) error {
+	// Check which kind of limits are (or were) in use.
+	var hasThrottleLimits, hasLatencyLimits, hasWeightLimits bool
+	for _, devices := range []deviceConfig.Devices{d.inst.ExpandedDevices(), oldDevices} {
+		for _, dev := range devices.Filter(filters.IsDisk) {
+			if dev["limits.read"] != "" || dev["limits.write"] != "" || dev["limits.max"] != "" {
+				hasThrottleLimits = true
+			}
+
+			if dev["limits.latency"] != "" {
+				hasLatencyLimits = true
+			}
+
+			if dev["limits.weight"] != "" {
+				hasWeightLimits = true
+			}
 		}
 	}
 
-	if !hasDiskLimits {
+	if !hasThrottleLimits && !hasLatencyLimits && !hasWeightLimits {
 		return nil
 	}
 
@@ -1538,6 +1693,10 @@ func (d *disk) generateLimits(runConf *deviceConfig.RunConfig) error {
 		return errors.New("Cannot apply disk limits as blkio cgroup controller is missing")
 	}
 
+	if (hasLatencyLimits || hasWeightLimits) && d.state.OS.CGInfo.Layout != cgroup.CgroupsUnified {
+		return errors.New(`The "limits.latency" and "limits.weight" disk limits require a pure cgroup2 host`)
+	}
+
 	diskLimits, err := d.getDiskLimits()
 	if err != nil {
 		return err
@@ -1548,36 +1707,48 @@ func (d *disk) generateLimits(runConf *deviceConfig.RunConfig) error {
 		return err
 	}
 
+	burstLimits := map[string]diskBlockLimit{}
 	for block, limit := range diskLimits {
-		if limit.readBps > 0 {
-			err = cg.SetBlkioLimit(block, "read", "bps", limit.readBps)
-			if err != nil {
-				return err
+		if hasThrottleLimits {
+			// Start with the burst limits, the burst controller lowers them to the base limits
+			// once the burst credit is used up. The I/O usage of loop devices isn't accounted
+			// for, so their bursts can't be emulated.
+			if limit.hasBurst() && !strings.HasPrefix(block, "7:") {
+				burstLimits[block] = limit
+				err = cg.SetIOMax(block, limit.readBpsBurst, limit.readIopsBurst, limit.writeBpsBurst, limit.writeIopsBurst)
+			} else {
+				err = cg.SetIOMax(block, limit.readBps, limit.readIops, limit.writeBps, limit.writeIops)
 			}
-		}
 
-		if limit.readIops > 0 {
-			err = cg.SetBlkioLimit(block, "read", "iops", limit.readIops)
 			if err != nil {
 				return err
 			}
 		}
 
-		if limit.writeBps > 0 {
-			err = cg.SetBlkioLimit(block, "write", "bps", limit.writeBps)
+		if hasLatencyLimits {
+			err = cg.SetIOLatency(block, limit.latency)
 			if err != nil {
 				return err
 			}
 		}
 
-		if limit.writeIops > 0 {
-			err = cg.SetBlkioLimit(block, "write", "iops", limit.writeIops)
+		if hasWeightLimits {
+			err = cg.SetIOWeight(block, limit.weight)
 			if err != nil {
 				return err
 			}
 		}
 	}
 
+	// Replace any burst controller started with the previous limits.
+	diskBurstStop(d.inst)
+	if len(burstLimits) > 0 {
+		err = diskBurstStart(d.inst, burstLimits, d.logger)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -1760,6 +1931,38 @@ func (d *disk) createDevice(srcPath string) (func(), string, bool, error) {
 			fsName = "ceph"
 			srcPath = mntSrcPath
 			isFile = false
+		} else if d.sourceIsNFS() || d.sourceIsSMB() {
+			share, err := diskParseNetworkShare(d.config["source"])
+			if err != nil {
+				return nil, "", false, err
+			}
+
+			// Get the mount options.
+			mntSrcPath, fsType, fsOptions, err := diskNetworkShareOptions(share, d.config["smb.credentials"], mntOptions)
+			if err != nil {
+				return nil, "", false, diskSourceNotFoundError{msg: "Failed preparing network share mount", err: err}
+			}
+
+			// Map the ownership of the files on SMB shares to the root user of the container, as the
+			// share doesn't carry IDs meaningful to the instance and can't be shifted.
+			if fsType == "cifs" && d.inst.Type() == instancetype.Container {
+				idmapSet, err := d.inst.(instance.Container).CurrentIdmap()
+				if err != nil {
+					return nil, "", false, err
+				}
+
+				if idmapSet != nil {
+					uid, gid := idmapSet.ShiftIntoNs(0, 0)
+					fsOptions = append(fsOptions, fmt.Sprint("uid=", uid), fmt.Sprint("gid=", gid), "forceuid", "forcegid")
+				}
+			}
+
+			// Join the options with any provided by the user.
+			mntOptions = append(mntOptions, fsOptions...)
+
+			fsName = fsType
+			srcPath = mntSrcPath
+			isFile = false
 		} else if d.sourceIsCeph() {
 			// Get the pool and volume names.
 			fields := strings.SplitN(d.config["source"], ":", 2)
@@ -1814,6 +2017,11 @@ func (d *disk) createDevice(srcPath string) (func(), string, bool, error) {
 		}
 	}
 
+	isOverlay := shared.IsTrue(d.config["overlay"])
+	if isOverlay && isFile {
+		return nil, "", false, errors.New("Overlay disks require a directory source")
+	}
+
 	// Create the devices directory if missing.
 	err := os.Mkdir(d.inst.DevicesPath(), 0711)
 	if err != nil && !errors.Is(err, os.ErrExist) {
@@ -1841,23 +2049,160 @@ func (d *disk) createDevice(srcPath string) (func(), string, bool, error) {
 		}
 	}
 
-	if isReadOnly {
+	// For overlay disks, the source is mounted read-only as the lower layer of the overlay.
+	mntPath := devPath
+	if isOverlay {
+		mntPath = d.overlayLowerPath()
+
+		err = DiskMountClear(mntPath)
+		if err != nil {
+			return nil, "", false, err
+		}
+
+		err = os.Mkdir(mntPath, 0700)
+		if err != nil {
+			return nil, "", false, err
+		}
+
+		revert.Add(func() { _ = DiskMountClear(devPath) })
+	}
+
+	if isReadOnly || isOverlay {
 		mntOptions = append(mntOptions, "ro")
 	}
 
 	// Mount the fs.
-	err = DiskMount(srcPath, devPath, isRecursive, d.config["propagation"], mntOptions, fsName)
+	err = DiskMount(srcPath, mntPath, isRecursive, d.config["propagation"], mntOptions, fsName)
 	if err != nil {
 		return nil, "", false, err
 	}
 
-	revert.Add(func() { _ = DiskMountClear(devPath) })
+	revert.Add(func() { _ = DiskMountClear(mntPath) })
+
+	if isOverlay {
+		revert.Add(func() { _ = d.overlayClear() })
+
+		err = d.overlayMount(mntPath, devPath)
+		if err != nil {
+			return nil, "", false, err
+		}
+	}
 
 	cleanup := revert.Clone().Fail // Clone before calling revert.Success() so we can return the Fail func.
 	revert.Success()
 	return cleanup, devPath, isFile, err
 }
 
+// overlayLowerPath returns the host path where the source of an overlay disk is mounted.
+func (d *disk) overlayLowerPath() string {
+	return d.getDevicePath(d.name, d.config) + ".lower"
+}
+
+// overlayUpperPath returns the host path storing the writable layer of an overlay disk.
+// This is in the instance volume unless overlay.upper is set to tmpfs, in which case a tmpfs is mounted on it.
+func (d *disk) overlayUpperPath() string {
+	if d.config["overlay.upper"] == "tmpfs" {
+		return d.getDevicePath(d.name, d.config) + ".upper"
+	}
+
+	return filepath.Join(d.inst.Path(), "overlay", filesystem.PathNameEncode(d.name))
+}
+
+// overlayMount mounts an overlay with an empty writable layer on top of lowerPath at devPath.
+func (d *disk) overlayMount(lowerPath string, devPath string) error {
+	// Discard any writable layer left behind.
+	err := d.overlayClearUpper()
+	if err != nil {
+		return err
+	}
+
+	upperPath := d.overlayUpperPath()
+	err = os.MkdirAll(upperPath, 0700)
+	if err != nil {
+		return err
+	}
+
+	if d.config["overlay.upper"] == "tmpfs" {
+		err = unix.Mount("tmpfs", upperPath, "tmpfs", 0, "mode=0700")
+		if err != nil {
+			return fmt.Errorf("Failed mounting tmpfs on %q: %w", upperPath, err)
+		}
+	}
+
+	upperDir := filepath.Join(upperPath, "upper")
+	workDir := filepath.Join(upperPath, "work")
+
+	err = os.Mkdir(upperDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	err = os.Mkdir(workDir, 0700)
+	if err != nil {
+		return err
+	}
+
+	// The root of the overlay takes its ownership from the writable layer, let the container root user
+	// write to it.
+	idmapSet, err := d.inst.(instance.Container).CurrentIdmap()
+	if err != nil {
+		return err
+	}
+
+	if idmapSet != nil {
+		uid, gid := idmapSet.ShiftIntoNs(0, 0)
+		err = os.Chown(upperDir, int(uid), int(gid))
+		if err != nil {
+			return err
+		}
+	}
+
+	options := "lowerdir=" + lowerPath + ",upperdir=" + upperDir + ",workdir=" + workDir
+	err = unix.Mount("overlay", devPath, "overlay", 0, options)
+	if err != nil {
+		return fmt.Errorf("Failed mounting overlay on %q: %w", devPath, err)
+	}
+
+	return nil
+}
+
+// overlayClearUpper discards the writable layer of an overlay disk.
+func (d *disk) overlayClearUpper() error {
+	upperPath := d.overlayUpperPath()
+	if !shared.PathExists(upperPath) {
+		return nil
+	}
+
+	if filesystem.IsMountPoint(upperPath) {
+		err := storageDrivers.TryUnmount(upperPath, 0)
+		if err != nil {
+			return fmt.Errorf("Failed unmounting %q: %w", upperPath, err)
+		}
+	}
+
+	err := os.RemoveAll(upperPath)
+	if err != nil {
+		return fmt.Errorf("Failed removing %q: %w", upperPath, err)
+	}
+
+	// Remove the parent directory in the instance volume once the last overlay disk is gone.
+	if d.config["overlay.upper"] != "tmpfs" {
+		_ = os.Remove(filepath.Dir(upperPath))
+	}
+
+	return nil
+}
+
+// overlayClear unmounts the layers of an overlay disk and discards its writable layer.
+func (d *disk) overlayClear() error {
+	err := DiskMountClear(d.overlayLowerPath())
+	if err != nil {
+		return err
+	}
+
+	return d.overlayClearUpper()
+}
+
 // localSourceOpen opens a local disk source path and returns a file handle to it.
 // If d.restrictedParentSourcePath has been set during validation, then the openat2 syscall is used to ensure that
 // the srcPath opened doesn't resolve above the allowed parent source path.
@@ -2140,6 +2485,14 @@ func (d *disk) postStop() error {
 		return err
 	}
 
+	// Discard the layers of overlay disks.
+	if shared.IsTrue(d.config["overlay"]) {
+		err = d.overlayClear()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Check if pool-specific action should be taken to unmount custom volume disks.
 	if d.config["pool"] != "" && d.config["path"] != "/" {
 		isSnapshot := d.config["source.snapshot"] != ""
@@ -2241,6 +2594,34 @@ func (d *disk) getDiskLimits() (map[string]diskBlockLimit, error) {
 			return nil, err
 		}
 
+		readBpsBurst, readIopsBurst, writeBpsBurst, writeIopsBurst, err := d.parseBurstLimit(dev)
+		if err != nil {
+			return nil, err
+		}
+
+		burstDuration := int64(1)
+		if dev["limits.burst.duration"] != "" {
+			burstDuration, err = diskParseDuration(dev["limits.burst.duration"], time.Second)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var latency, weight int64
+		if dev["limits.latency"] != "" {
+			latency, err = diskParseDuration(dev["limits.latency"], time.Microsecond)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if dev["limits.weight"] != "" {
+			weight, err = strconv.ParseInt(dev["limits.weight"], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		// Set the source path
 		source := d.getDevicePath(devName, dev)
 		if dev["source"] == "" {
@@ -2259,7 +2640,7 @@ func (d *disk) getDiskLimits() (map[string]diskBlockLimit, error) {
 		// Get the backing block devices (major:minor)
 		blocks, err := d.getParentBlocks(source)
 		if err != nil {
-			if readBps == 0 && readIops == 0 && writeBps == 0 && writeIops == 0 {
+			if readBps == 0 && readIops == 0 && writeBps == 0 && writeIops == 0 && latency == 0 && weight == 0 {
 				// If the device doesn't exist, there is no limit to clear so ignore the failure
 				continue
 			} else {
@@ -2267,7 +2648,20 @@ func (d *disk) getDiskLimits() (map[string]diskBlockLimit, error) {
 			}
 		}
 
-		device := diskBlockLimit{readBps: readBps, readIops: readIops, writeBps: writeBps, writeIops: writeIops}
+		device := diskBlockLimit{
+			readBps:        readBps,
+			readIops:       readIops,
+			writeBps:       writeBps,
+			writeIops:      writeIops,
+			readBpsBurst:   max(readBps, readBpsBurst),
+			readIopsBurst:  max(readIops, readIopsBurst),
+			writeBpsBurst:  max(writeBps, writeBpsBurst),
+			writeIopsBurst: max(writeIops, writeIopsBurst),
+			burstDuration:  burstDuration,
+			latency:        latency,
+			weight:         weight,
+		}
+
 		for _, block := range blocks {
 			blockStr := ""
 
@@ -2298,26 +2692,47 @@ func (d *disk) getDiskLimits() (map[string]diskBlockLimit, error) {
 	// Average duplicate limits
 	for block, limits := range blockLimits {
 		var readBpsCount, readBpsTotal, readIopsCount, readIopsTotal, writeBpsCount, writeBpsTotal, writeIopsCount, writeIopsTotal int64
+		var readBpsBurstTotal, readIopsBurstTotal, writeBpsBurstTotal, writeIopsBurstTotal, burstDuration int64
+		var weightCount, weightTotal, latency int64
 
 		for _, limit := range limits {
 			if limit.readBps > 0 {
 				readBpsCount++
 				readBpsTotal += limit.readBps
+				readBpsBurstTotal += limit.readBpsBurst
 			}
 
 			if limit.readIops > 0 {
 				readIopsCount++
 				readIopsTotal += limit.readIops
+				readIopsBurstTotal += limit.readIopsBurst
 			}
 
 			if limit.writeBps > 0 {
 				writeBpsCount++
 				writeBpsTotal += limit.writeBps
+				writeBpsBurstTotal += limit.writeBpsBurst
 			}
 
 			if limit.writeIops > 0 {
 				writeIopsCount++
 				writeIopsTotal += limit.writeIops
+				writeIopsBurstTotal += limit.writeIopsBurst
+			}
+
+			// Use the shortest burst duration.
+			if limit.hasBurst() && (burstDuration == 0 || limit.burstDuration < burstDuration) {
+				burstDuration = limit.burstDuration
+			}
+
+			if limit.weight > 0 {
+				weightCount++
+				weightTotal += limit.weight
+			}
+
+			// Use the most demanding latency target.
+			if limit.latency > 0 && (latency == 0 || limit.latency < latency) {
+				latency = limit.latency
 			}
 		}
 
@@ -2325,20 +2740,32 @@ func (d *disk) getDiskLimits() (map[string]diskBlockLimit, error) {
 
 		if readBpsCount > 0 {
 			device.readBps = readBpsTotal / readBpsCount
+			device.readBpsBurst = readBpsBurstTotal / readBpsCount
 		}
 
 		if readIopsCount > 0 {
 			device.readIops = readIopsTotal / readIopsCount
+			device.readIopsBurst = readIopsBurstTotal / readIopsCount
 		}
 
 		if writeBpsCount > 0 {
 			device.writeBps = writeBpsTotal / writeBpsCount
+			device.writeBpsBurst = writeBpsBurstTotal / writeBpsCount
 		}
 
 		if writeIopsCount > 0 {
 			device.writeIops = writeIopsTotal / writeIopsCount
+			device.writeIopsBurst = writeIopsBurstTotal / writeIopsCount
+		}
+
+		device.burstDuration = burstDuration
+
+		if weightCount > 0 {
+			device.weight = weightTotal / weightCount
 		}
 
+		device.latency = latency
+
 		result[block] = device
 	}
 
@@ -2347,50 +2774,111 @@ func (d *disk) getDiskLimits() (map[string]diskBlockLimit, error) {
 
 // parseLimit parses the disk configuration for its I/O limits and returns the I/O bytes/iops limits.
 func (d *disk) parseLimit(dev deviceConfig.Device) (readBps int64, readIops int64, writeBps int64, writeIops int64, err error) {
-	readSpeed := dev["limits.read"]
-	writeSpeed := dev["limits.write"]
+	return deviceConfig.ParseDiskLimits(dev, "limits.read", "limits.write", "limits.max")
+}
+
+// parseBurstLimit parses the disk configuration for its I/O burst limits and returns the I/O bytes/iops burst limits.
+func (d *disk) parseBurstLimit(dev deviceConfig.Device) (readBps int64, readIops int64, writeBps int64, writeIops int64, err error) {
+	return deviceConfig.ParseDiskLimits(dev, "limits.read.burst", "limits.write.burst", "limits.max.burst")
+}
+
+// diskValidateBurstLimits checks that each burst limit of a disk configuration has a matching base limit
+// of the same unit and doesn't go below it.
+func diskValidateBurstLimits(dev deviceConfig.Device) error {
+	readBps, readIops, writeBps, writeIops, err := deviceConfig.ParseDiskLimits(dev, "limits.read", "limits.write", "limits.max")
+	if err != nil {
+		return err
+	}
 
-	// Apply max limit.
-	if dev["limits.max"] != "" {
-		readSpeed = dev["limits.max"]
-		writeSpeed = dev["limits.max"]
+	readBpsBurst, readIopsBurst, writeBpsBurst, writeIopsBurst, err := deviceConfig.ParseDiskLimits(dev, "limits.read.burst", "limits.write.burst", "limits.max.burst")
+	if err != nil {
+		return err
 	}
 
-	// parseValue parses a single value to either a B/s limit or iops limit.
-	parseValue := func(value string) (bps int64, iops int64, err error) {
-		if value == "" {
-			return bps, iops, nil
+	checks := []struct {
+		name  string
+		base  int64
+		burst int64
+	}{
+		{name: "read bytes", base: readBps, burst: readBpsBurst},
+		{name: "read IOPS", base: readIops, burst: readIopsBurst},
+		{name: "write bytes", base: writeBps, burst: writeBpsBurst},
+		{name: "write IOPS", base: writeIops, burst: writeIopsBurst},
+	}
+
+	hasBurst := false
+	for _, check := range checks {
+		if check.burst == 0 {
+			continue
 		}
 
-		before, found := strings.CutSuffix(value, "iops")
-		if found {
-			iops, err = strconv.ParseInt(before, 10, 64)
-			if err != nil {
-				return -1, -1, err
-			}
-		} else {
-			bps, err = units.ParseByteSizeString(value)
-			if err != nil {
-				return -1, -1, err
-			}
+		hasBurst = true
+
+		if check.base == 0 {
+			return fmt.Errorf("Burst %s limit requires a %s limit to be set", check.name, check.name)
 		}
 
-		return bps, iops, nil
+		if check.burst < check.base {
+			return fmt.Errorf("Burst %s limit must be greater than or equal to the %s limit", check.name, check.name)
+		}
+	}
+
+	if dev["limits.burst.duration"] != "" && !hasBurst {
+		return errors.New(`"limits.burst.duration" requires a burst limit to be set`)
+	}
+
+	return nil
+}
+
+// diskParseDuration parses a positive duration and returns it as a whole number of the given unit.
+func diskParseDuration(value string, unit time.Duration) (int64, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return -1, err
+	}
+
+	if duration < unit {
+		return -1, fmt.Errorf("Duration must be at least %s", unit)
+	}
+
+	if duration%unit != 0 {
+		return -1, fmt.Errorf("Duration must be a whole number of %s", unit)
 	}
 
-	// Process reads.
-	readBps, readIops, err = parseValue(readSpeed)
+	return int64(duration / unit), nil
+}
+
+// vmDiskLimits returns the QEMU throttling limits for the disk configuration, including the burst limits.
+func (d *disk) vmDiskLimits(dev deviceConfig.Device) (*deviceConfig.DiskLimits, error) {
+	readBps, readIops, writeBps, writeIops, err := d.parseLimit(dev)
 	if err != nil {
-		return -1, -1, -1, -1, err
+		return nil, err
 	}
 
-	// Process writes.
-	writeBps, writeIops, err = parseValue(writeSpeed)
+	readBpsBurst, readIopsBurst, writeBpsBurst, writeIopsBurst, err := d.parseBurstLimit(dev)
 	if err != nil {
-		return -1, -1, -1, -1, err
+		return nil, err
+	}
+
+	limits := &deviceConfig.DiskLimits{
+		ReadBytes:       readBps,
+		ReadIOps:        readIops,
+		WriteBytes:      writeBps,
+		WriteIOps:       writeIops,
+		ReadBytesBurst:  readBpsBurst,
+		ReadIOpsBurst:   readIopsBurst,
+		WriteBytesBurst: writeBpsBurst,
+		WriteIOpsBurst:  writeIopsBurst,
+	}
+
+	if dev["limits.burst.duration"] != "" {
+		limits.BurstDuration, err = diskParseDuration(dev["limits.burst.duration"], time.Second)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return readBps, readIops, writeBps, writeIops, nil
+	return limits, nil
 }
 
 func (d *disk) getParentBlocks(path string) ([]string, error) {
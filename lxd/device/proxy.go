@@ -53,6 +53,8 @@ type proxyProcInfo struct {
 	securityUID    string
 	securityGID    string
 	proxyProtocol  string
+	balancing      string
+	healthCheck    string
 	inheritFds     []*os.File
 }
 
@@ -72,6 +74,17 @@ func (d *proxy) validateConfig(instConf instance.ConfigReader) error {
 		return err
 	}
 
+	validateConnect := func(input string) error {
+		for _, addr := range network.ProxySplitAddrs(input) {
+			err := validateAddr(addr)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
 	// Supported bind types are: "host" or "instance" (or "guest" or "container", legacy options equivalent to "instance").
 	// If an empty value is supplied the default behavior is to assume "host" bind mode.
 	validateBind := func(input string) error {
@@ -92,11 +105,35 @@ func (d *proxy) validateConfig(instConf instance.ConfigReader) error {
 		"listen": validate.Required(validateAddr),
 		// lxdmeta:generate(entities=device-proxy; group=device-conf; key=connect)
 		// Use the following format to specify the address and port: `<type>:<addr>:<port>[-<port>][,<port>]`
+		//
+		// To distribute the connections between several `tcp` or `udp` targets, specify a comma-separated list of addresses
+		// (for example, `tcp:10.0.0.2:80,tcp:10.0.0.3:80`).
+		// See {config:option}`device-proxy-device-conf:connect.balancing`.
 		// ---
 		//  type: string
 		//  required: yes
 		//  shortdesc: Address and port to connect to
-		"connect": validate.Required(validateAddr),
+		"connect": validate.Required(validateConnect),
+		// lxdmeta:generate(entities=device-proxy; group=device-conf; key=connect.balancing)
+		// Possible values are `round-robin` and `least-connections`.
+		// For `udp`, each new client is assigned to a target for the duration of its session.
+		// ---
+		//  type: string
+		//  defaultdesc: `round-robin`
+		//  required: no
+		//  shortdesc: How to distribute the connections between multiple connect targets
+		"connect.balancing": validate.Optional(validate.IsOneOf("round-robin", "least-connections")),
+		// lxdmeta:generate(entities=device-proxy; group=device-conf; key=connect.health_check.interval)
+		// When multiple `tcp` targets are configured, each target is checked by opening a connection to it at this
+		// interval. Targets that fail the check, or a connection attempt, are considered down and are only tried for
+		// new connections once all the other targets have failed, until a check or a connection succeeds again.
+		// Set to `0` to disable active checks.
+		// ---
+		//  type: integer
+		//  defaultdesc: `10`
+		//  required: no
+		//  shortdesc: Interval in seconds between health checks of the connect targets
+		"connect.health_check.interval": validate.Optional(validate.IsUint32),
 		// lxdmeta:generate(entities=device-proxy; group=device-conf; key=bind)
 		// Possible values are `host` and `instance`.
 		// ---
@@ -161,6 +198,14 @@ func (d *proxy) validateConfig(instConf instance.ConfigReader) error {
 		//  required: no
 		//  shortdesc: Whether to use the HAProxy PROXY protocol
 		"proxy_protocol": validate.Optional(validate.IsBool),
+		// lxdmeta:generate(entities=device-proxy; group=device-conf; key=proxy_protocol.version)
+		// Possible values are `1` for the text header and `2` for the binary header.
+		// ---
+		//  type: integer
+		//  defaultdesc: `1`
+		//  required: no
+		//  shortdesc: Version of the HAProxy PROXY protocol
+		"proxy_protocol.version": validate.Optional(validate.IsOneOf("1", "2")),
 	}
 
 	err := d.config.Validate(rules)
@@ -177,7 +222,8 @@ func (d *proxy) validateConfig(instConf instance.ConfigReader) error {
 		return err
 	}
 
-	connectAddr, err := network.ProxyParseAddr(d.config["connect"])
+	connectAddrs := network.ProxySplitAddrs(d.config["connect"])
+	connectAddr, err := network.ProxyParseAddr(connectAddrs[0])
 	if err != nil {
 		return err
 	}
@@ -192,10 +238,36 @@ func (d *proxy) validateConfig(instConf instance.ConfigReader) error {
 		return errors.New("Mismatch between listen port(s) and connect port(s) count")
 	}
 
-	if shared.IsTrue(d.config["proxy_protocol"]) && (!strings.HasPrefix(d.config["connect"], "tcp") || shared.IsTrue(d.config["nat"])) {
+	if len(connectAddrs) > 1 {
+		if connectAddr.ConnType == "unix" {
+			return errors.New("Multiple connect addresses are only supported for tcp and udp")
+		}
+
+		if shared.IsTrue(d.config["nat"]) {
+			return errors.New("Multiple connect addresses cannot be used in nat mode")
+		}
+
+		// All targets must be interchangeable.
+		for _, addr := range connectAddrs[1:] {
+			targetAddr, err := network.ProxyParseAddr(addr)
+			if err != nil {
+				return err
+			}
+
+			if targetAddr.ConnType != connectAddr.ConnType || len(targetAddr.Ports) != len(connectAddr.Ports) {
+				return errors.New("All connect addresses must use the same protocol and number of ports")
+			}
+		}
+	}
+
+	if shared.IsTrue(d.config["proxy_protocol"]) && (connectAddr.ConnType != "tcp" || shared.IsTrue(d.config["nat"])) {
 		return errors.New("The PROXY header can only be sent to tcp servers in non-nat mode")
 	}
 
+	if d.config["proxy_protocol.version"] != "" && shared.IsFalseOrEmpty(d.config["proxy_protocol"]) {
+		return errors.New(`"proxy_protocol.version" requires "proxy_protocol" to be enabled`)
+	}
+
 	if (!strings.HasPrefix(d.config["listen"], "unix:") || strings.HasPrefix(d.config["listen"], "unix:@")) &&
 		(d.config["uid"] != "" || d.config["gid"] != "" || d.config["mode"] != "") {
 		return errors.New("Only proxy devices for non-abstract unix sockets can carry uid, gid, or mode properties")
@@ -335,6 +407,8 @@ func (d *proxy) Start() (*deviceConfig.RunConfig, error) {
 				proxyValues.securityGID,
 				proxyValues.securityUID,
 				proxyValues.proxyProtocol,
+				proxyValues.balancing,
+				proxyValues.healthCheck,
 			}
 
 			p, err := subprocess.NewProcess(command, forkproxyargs, logPath, logPath)
@@ -640,6 +714,25 @@ func (d *proxy) setupProxyProcInfo() (*proxyProcInfo, error) {
 		listenAddrMode = d.config["mode"]
 	}
 
+	// Pass the version of the PROXY protocol header to send, if any.
+	proxyProtocol := ""
+	if shared.IsTrue(d.config["proxy_protocol"]) {
+		proxyProtocol = d.config["proxy_protocol.version"]
+		if proxyProtocol == "" {
+			proxyProtocol = "1"
+		}
+	}
+
+	balancing := d.config["connect.balancing"]
+	if balancing == "" {
+		balancing = "round-robin"
+	}
+
+	healthCheck := d.config["connect.health_check.interval"]
+	if healthCheck == "" {
+		healthCheck = "10"
+	}
+
 	p := &proxyProcInfo{
 		listenPid:      listenPid,
 		listenPidFd:    listenPidFd,
@@ -652,7 +745,9 @@ func (d *proxy) setupProxyProcInfo() (*proxyProcInfo, error) {
 		listenAddrMode: listenAddrMode,
 		securityGID:    d.config["security.gid"],
 		securityUID:    d.config["security.uid"],
-		proxyProtocol:  d.config["proxy_protocol"],
+		proxyProtocol:  proxyProtocol,
+		balancing:      balancing,
+		healthCheck:    healthCheck,
 		inheritFds:     inheritFd,
 	}
 
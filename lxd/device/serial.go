@@ -0,0 +1,182 @@
+package device
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	deviceConfig "github.com/canonical/lxd/lxd/device/config"
+	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/instance/instancetype"
+	"github.com/canonical/lxd/lxd/storage/filesystem"
+	"github.com/canonical/lxd/shared/osarch"
+	"github.com/canonical/lxd/shared/validate"
+)
+
+// serialPortNameRegex matches the valid names of virtio serial ports.
+var serialPortNameRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+type serial struct {
+	deviceCommon
+}
+
+// validateConfig checks the supplied config for correctness.
+func (d *serial) validateConfig(instConf instance.ConfigReader) error {
+	if !instanceSupported(instConf.Type(), instancetype.VM) {
+		return ErrUnsupportedDevType
+	}
+
+	rules := map[string]func(string) error{
+		// lxdmeta:generate(entities=device-serial; group=device-conf; key=target)
+		// Possible values are `virtio` (a port on the `virtio-serial` bus, available in the guest under `/dev/virtio-ports/`)
+		// and `isa` (an additional legacy serial port, only available on `x86_64`).
+		// ---
+		//  type: string
+		//  defaultdesc: `virtio`
+		//  required: no
+		//  shortdesc: Type of serial port in the VM
+		"target": validate.Optional(validate.IsOneOf("virtio", "isa")),
+
+		// lxdmeta:generate(entities=device-serial; group=device-conf; key=name)
+		// Only for `virtio` ports.
+		// ---
+		//  type: string
+		//  defaultdesc: device name
+		//  required: no
+		//  shortdesc: Name of the port in the VM
+		"name": validate.Optional(func(value string) error {
+			if !serialPortNameRegex.MatchString(value) {
+				return errors.New("Port name can only contain alphanumeric characters, dots, dashes and underscores")
+			}
+
+			return nil
+		}),
+
+		// lxdmeta:generate(entities=device-serial; group=device-conf; key=backend)
+		// Possible values are `unix` (a unix socket that LXD listens on), `file` (the output of the port is appended
+		// to a log file) and `pty` (a pseudo terminal).
+		// The host path of the socket, log file or pseudo terminal is recorded in `volatile.<name>.last_state.serial.path`.
+		// ---
+		//  type: string
+		//  defaultdesc: `unix`
+		//  required: no
+		//  shortdesc: Host side of the serial port
+		"backend": validate.Optional(validate.IsOneOf("unix", "file", "pty")),
+	}
+
+	err := d.config.Validate(rules)
+	if err != nil {
+		return fmt.Errorf("Failed validating config: %w", err)
+	}
+
+	if d.config["name"] != "" && d.config["target"] == "isa" {
+		return errors.New(`The "name" option can only be used with "virtio" serial ports`)
+	}
+
+	return nil
+}
+
+// validateEnvironment checks that the serial port can be added to the instance.
+func (d *serial) validateEnvironment() error {
+	if d.config["target"] == "isa" && d.inst.Architecture() != osarch.ARCH_64BIT_INTEL_X86 {
+		return errors.New("ISA serial ports are only supported on x86_64")
+	}
+
+	return nil
+}
+
+// CanHotPlug returns whether the device can be managed whilst the instance is running.
+// Only virtio serial ports can be hotplugged.
+func (d *serial) CanHotPlug() bool {
+	return d.config["target"] != "isa"
+}
+
+// hostPath returns the host path of the unix socket or log file backing the serial port.
+func (d *serial) hostPath() string {
+	escapedDeviceName := filesystem.PathNameEncode(d.name)
+
+	switch d.config["backend"] {
+	case "file":
+		return filepath.Join(d.inst.LogPath(), "serial."+escapedDeviceName+".log")
+	case "pty":
+		return ""
+	default:
+		return filepath.Join(d.inst.DevicesPath(), "serial."+escapedDeviceName+".sock")
+	}
+}
+
+// Start is run when the device is added to the instance.
+func (d *serial) Start() (*deviceConfig.RunConfig, error) {
+	err := d.validateEnvironment()
+	if err != nil {
+		return nil, fmt.Errorf("Failed validating environment: %w", err)
+	}
+
+	target := d.config["target"]
+	if target == "" {
+		target = "virtio"
+	}
+
+	backend := d.config["backend"]
+	if backend == "" {
+		backend = "unix"
+	}
+
+	portName := d.config["name"]
+	if portName == "" && target == "virtio" {
+		portName = d.name
+	}
+
+	path := d.hostPath()
+
+	// Remove any stale socket left behind.
+	if backend == "unix" {
+		err = os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("Failed removing stale socket %q: %w", path, err)
+		}
+	}
+
+	// The path of a pseudo terminal is only known once it has been allocated by QEMU.
+	err = d.volatileSet(map[string]string{"last_state.serial.path": path})
+	if err != nil {
+		return nil, err
+	}
+
+	runConf := deviceConfig.RunConfig{
+		SerialDevice: []deviceConfig.RunConfigItem{
+			{Key: "devName", Value: d.name},
+			{Key: "target", Value: target},
+			{Key: "portName", Value: portName},
+			{Key: "backend", Value: backend},
+			{Key: "path", Value: path},
+		},
+	}
+
+	return &runConf, nil
+}
+
+// Stop is run when the device is removed from the instance.
+func (d *serial) Stop() (*deviceConfig.RunConfig, error) {
+	runConf := deviceConfig.RunConfig{
+		PostHooks: []func() error{d.postStop},
+	}
+
+	return &runConf, nil
+}
+
+// postStop is run after the device is removed from the instance.
+func (d *serial) postStop() error {
+	if d.config["backend"] == "" || d.config["backend"] == "unix" {
+		path := d.hostPath()
+
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("Failed removing socket %q: %w", path, err)
+		}
+	}
+
+	return d.volatileSet(map[string]string{"last_state.serial.path": ""})
+}
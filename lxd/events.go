@@ -22,7 +22,7 @@ import (
 	"github.com/canonical/lxd/shared/ws"
 )
 
-var eventTypes = []string{api.EventTypeLogging, api.EventTypeOperation, api.EventTypeLifecycle, api.EventTypeOVN}
+var eventTypes = []string{api.EventTypeLogging, api.EventTypeOperation, api.EventTypeLifecycle, api.EventTypeOVN, api.EventTypeSecurity}
 var privilegedEventTypes = []string{api.EventTypeLogging, api.EventTypeOVN}
 
 var eventsCmd = APIEndpoint{
@@ -190,8 +190,8 @@ func eventsSocket(s *state.State, r *http.Request, w http.ResponseWriter) error
 		}
 
 		switch event.Type {
-		case api.EventTypeLifecycle:
-			// Lifecycle events that are not project specific require `can_view_events` on `server`.
+		case api.EventTypeLifecycle, api.EventTypeSecurity:
+			// Lifecycle and security events that are not project specific require `can_view_events` on `server`.
 			if event.Project == "" {
 				return canViewServerEvents
 			}
@@ -0,0 +1,63 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// coalescerMaxEntries is the number of tracked keys above which the expired ones are forgotten.
+const coalescerMaxEntries = 1024
+
+// Coalescer limits repeated events to one per key and time window.
+// This is used for events generated by instance activity, which could otherwise flood the event listeners.
+type Coalescer struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*coalescerEntry
+}
+
+type coalescerEntry struct {
+	sentAt     time.Time
+	suppressed int
+}
+
+// NewCoalescer returns a Coalescer letting through one event per key within the window.
+func NewCoalescer(window time.Duration) *Coalescer {
+	return &Coalescer{
+		window:  window,
+		entries: map[string]*coalescerEntry{},
+	}
+}
+
+// Allow returns whether an event with the key should be sent now.
+// When it should, it also returns how many events with the same key were suppressed since the last one sent.
+func (c *Coalescer) Allow(key string) (bool, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	entry, ok := c.entries[key]
+	if ok && now.Sub(entry.sentAt) < c.window {
+		entry.suppressed++
+		return false, 0
+	}
+
+	if !ok && len(c.entries) >= coalescerMaxEntries {
+		for k, e := range c.entries {
+			if now.Sub(e.sentAt) >= c.window {
+				delete(c.entries, k)
+			}
+		}
+	}
+
+	suppressed := 0
+	if ok {
+		suppressed = entry.suppressed
+	}
+
+	c.entries[key] = &coalescerEntry{sentAt: now}
+
+	return true, suppressed
+}
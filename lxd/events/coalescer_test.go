@@ -0,0 +1,51 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCoalescer(t *testing.T) {
+	c := NewCoalescer(time.Hour)
+
+	allowed, suppressed := c.Allow("c1/keyctl")
+	if !allowed || suppressed != 0 {
+		t.Fatalf("Expected first event to be allowed, got %v (%d suppressed)", allowed, suppressed)
+	}
+
+	for range 3 {
+		allowed, _ = c.Allow("c1/keyctl")
+		if allowed {
+			t.Fatal("Expected repeated event to be suppressed")
+		}
+	}
+
+	// Other keys aren't affected.
+	allowed, _ = c.Allow("c2/keyctl")
+	if !allowed {
+		t.Fatal("Expected event with another key to be allowed")
+	}
+
+	// Once the window expires, the next event reports the suppressed ones.
+	c.entries["c1/keyctl"].sentAt = time.Now().Add(-2 * time.Hour)
+
+	allowed, suppressed = c.Allow("c1/keyctl")
+	if !allowed || suppressed != 3 {
+		t.Fatalf("Expected event to be allowed with 3 suppressed, got %v (%d suppressed)", allowed, suppressed)
+	}
+}
+
+func TestCoalescerForgetsExpiredKeys(t *testing.T) {
+	c := NewCoalescer(time.Minute)
+
+	for i := range coalescerMaxEntries {
+		c.Allow(string(rune('a' + i)))
+		c.entries[string(rune('a'+i))].sentAt = time.Now().Add(-time.Hour)
+	}
+
+	c.Allow("new")
+
+	if len(c.entries) != 1 {
+		t.Fatalf("Expected expired keys to be forgotten, got %d keys", len(c.entries))
+	}
+}
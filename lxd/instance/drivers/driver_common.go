@@ -1571,6 +1571,19 @@ func (d *common) canMigrate(inst instance.Instance) (migrate bool, live bool) {
 		live = shared.IsTrue(config["migration.stateful"])
 	}
 
+	// Check that the volumes can be used by the source and the destination member at the same time.
+	if live {
+		pool, err := d.getStoragePool()
+		if err == nil {
+			err = storagePools.InstanceCheckLiveMigrationPools(d.state, pool, inst)
+		}
+
+		if err != nil {
+			logger.Warn("Instance will not be live-migrated", logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "err": err})
+			live = false
+		}
+	}
+
 	return true, live
 }
 
@@ -783,6 +783,20 @@ func (d *lxc) initLXC(config bool) (*liblxc.Container, error) {
 		}
 	}
 
+	// Nest the container's cgroup under its resource pool.
+	resourcePool := d.expandedConfig["limits.resource_pool"]
+	if resourcePool != "" {
+		err = lxcSetConfigItem(cc, "lxc.cgroup.dir.monitor", "lxc.monitor."+cname)
+		if err != nil {
+			return nil, err
+		}
+
+		err = lxcSetConfigItem(cc, "lxc.cgroup.dir.container", cgroup.PoolDir(project.ResourcePool(d.project.Name, resourcePool))+"/lxc.payload."+cname)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Configure devices cgroup
 	if d.IsPrivileged() && !d.state.OS.RunningInUserNS && d.state.OS.CGInfo.Supports(cgroup.Devices, cg) {
 		if d.state.OS.CGInfo.Layout == cgroup.CgroupsUnified {
@@ -1873,6 +1887,24 @@ func (d *lxc) startCommon() (revert.Hook, string, []func() error, error) {
 		return nil, "", nil, errors.New("The image used by this instance requires a CGroupV1 host system")
 	}
 
+	// Setup the resource pool cgroup shared with the other members.
+	resourcePool := d.expandedConfig["limits.resource_pool"]
+	if resourcePool != "" {
+		if !slices.Contains(project.ResourcePools(d.project.Config), resourcePool) {
+			return nil, "", nil, fmt.Errorf("Resource pool %q isn't defined in project %q", resourcePool, d.project.Name)
+		}
+
+		poolCg, err := cgroup.NewPool(project.ResourcePool(d.project.Name, resourcePool), true)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("Failed setting up resource pool %q: %w", resourcePool, err)
+		}
+
+		err = poolCg.SetPoolLimits(d.project.Config["resource_pools."+resourcePool+".limits.cpu.allowance"], d.project.Config["resource_pools."+resourcePool+".limits.memory"])
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("Failed applying limits of resource pool %q: %w", resourcePool, err)
+		}
+	}
+
 	// Load any required kernel modules
 	kernelModules := d.expandedConfig["linux.kernel_modules"]
 	kernelModulesLoadPolicy := d.expandedConfig["linux.kernel_modules.load"]
@@ -2915,6 +2947,15 @@ func (d *lxc) onStop(args map[string]string) error {
 		// Clean up devices.
 		d.cleanupDevices(false, "")
 
+		// Remove the resource pool cgroup if this was its last running member.
+		resourcePool := d.expandedConfig["limits.resource_pool"]
+		if resourcePool != "" {
+			err := cgroup.DeletePool(project.ResourcePool(d.project.Name, resourcePool))
+			if err != nil {
+				d.logger.Warn("Failed removing resource pool cgroup", logger.Ctx{"resourcePool": resourcePool, "err": err})
+			}
+		}
+
 		// Remove directory ownership (to avoid issue if uidmap is re-used)
 		// Fails on zfs when the dataset is full due to CoW
 		err := os.Chown(d.Path(), 0, 0)
@@ -3341,6 +3382,8 @@ func (d *lxc) renderState(statusCode api.StatusCode, hostInterfaces []net.Interf
 		// Always include PID and processes (lightweight)
 		status.Pid = int64(pid)
 		status.Processes = processesState
+
+		status.Pressure = d.pressureState()
 	}
 
 	// Disk - conditionally fetch (this is the expensive one!)
@@ -6244,6 +6287,43 @@ func (d *lxc) cpuState() api.InstanceStateCPU {
 	return cpu
 }
 
+// pressureState returns the pressure stall information of the instance or nil if not available.
+func (d *lxc) pressureState() *api.InstanceStatePressure {
+	cc, err := d.initLXC(false)
+	if err != nil {
+		return nil
+	}
+
+	cg, err := d.cgroup(cc, true)
+	if err != nil {
+		return nil
+	}
+
+	getPressure := func(resource string) *api.InstanceStatePressureResource {
+		stats, err := cg.GetPressure(resource)
+		if err != nil {
+			return nil
+		}
+
+		return &api.InstanceStatePressureResource{
+			Some: api.InstanceStatePressureValues{Avg10: stats.Some.Avg10, Avg60: stats.Some.Avg60, Avg300: stats.Some.Avg300, Total: stats.Some.Total},
+			Full: api.InstanceStatePressureValues{Avg10: stats.Full.Avg10, Avg60: stats.Full.Avg60, Avg300: stats.Full.Avg300, Total: stats.Full.Total},
+		}
+	}
+
+	pressure := api.InstanceStatePressure{
+		CPU:    getPressure("cpu"),
+		Memory: getPressure("memory"),
+		IO:     getPressure("io"),
+	}
+
+	if pressure.CPU == nil && pressure.Memory == nil && pressure.IO == nil {
+		return nil
+	}
+
+	return &pressure
+}
+
 func (d *lxc) diskState() map[string]api.InstanceStateDisk {
 	disk := map[string]api.InstanceStateDisk{}
 
@@ -7367,6 +7447,23 @@ func (d *lxc) Metrics(hostInterfaces []net.Interface) (*metrics.MetricSet, error
 		}
 	}
 
+	// Get pressure stall information (only available with cgroup2).
+	for _, resource := range []string{"cpu", "memory", "io"} {
+		stats, err := cg.GetPressure(resource)
+		if err != nil {
+			if !errors.Is(err, cgroup.ErrControllerMissing) {
+				d.logger.Warn("Failed getting pressure stats", logger.Ctx{"resource": resource, "err": err})
+			}
+
+			continue
+		}
+
+		out.AddSamples(metrics.PressureStallSecondsTotal,
+			metrics.Sample{Value: float64(stats.Some.Total) / 1000000, Labels: map[string]string{"resource": resource, "kind": "some"}},
+			metrics.Sample{Value: float64(stats.Full.Total) / 1000000, Labels: map[string]string{"resource": resource, "kind": "full"}},
+		)
+	}
+
 	// Get filesystem stats
 	fsStats, err := d.getFSStats()
 	if err != nil {
@@ -97,6 +97,9 @@ const QEMUDefaultMaxBusPorts uint8 = 8
 // qemuSerialChardevName is used to communicate state via qmp between Qemu and LXD.
 const qemuSerialChardevName = "qemu_serial-chardev"
 
+// qemuSerialPortChardevPrefix is the prefix of the character devices backing serial device ports.
+const qemuSerialPortChardevPrefix = "qemu_serialport-chardev_"
+
 // qemuPCIDeviceIDStart is the first PCI slot used for user configurable devices.
 const qemuPCIDeviceIDStart uint8 = 4
 
@@ -122,6 +125,15 @@ const qemuSparseUSBPorts = 8
 // qemuBusModePersistent is the volatile.bus.mode for persistent bus allocation mode.
 const qemuBusModePersistent = "persistent"
 
+// qemuMemoryHotplugSlots is the number of memory slots reserved for hotplugged DIMMs.
+const qemuMemoryHotplugSlots = 32
+
+// qemuMemoryHotplugBlockSize is the granularity of hotplugged memory (matching the guest's memory block size).
+const qemuMemoryHotplugBlockSize = 128 * 1024 * 1024
+
+// qemuMemoryHotplugDeviceIDPrefix used as part of the name given to hotplugged DIMM devices.
+const qemuMemoryHotplugDeviceIDPrefix = "lxd_dimm"
+
 var errQemuAgentOffline = errors.New("LXD VM agent is not currently running")
 
 type monitorHook func(m *qmp.Monitor) error
@@ -629,6 +641,15 @@ func (d *qemu) onStop(target string) error {
 	d.cleanupDevices() // Must be called before unmount.
 	_ = os.Remove(d.pidFilePath())
 	_ = os.Remove(d.monitorPath())
+	d.memoryBalloonClearAdjustments()
+
+	// Remove the root volume left behind by a live storage move now that QEMU has released it.
+	// Must be called before unmount so that the NVRAM can be carried over to the new root volume.
+	err = d.cleanupStorageMoveSource(true)
+	if err != nil {
+		// Don't return an error here as this is retried on next start.
+		d.logger.Error("Failed cleaning up live storage move", logger.Ctx{"err": err})
+	}
 
 	// Stop the storage for the instance.
 	err = d.unmount()
@@ -864,6 +885,13 @@ func (d *qemu) restoreState(monitor *qmp.Monitor) error {
 		// Perform non-shared storage transfer if requested.
 		filesystemConn := d.migrationReceiveStateful[api.SecretNameFilesystem]
 		if filesystemConn != nil {
+			release, err := qemuNBDServerReserve(d.id, "migration")
+			if err != nil {
+				return err
+			}
+
+			defer release()
+
 			nbdConn, err := monitor.NBDServerStart()
 			if err != nil {
 				return fmt.Errorf("Failed starting NBD server: %w", err)
@@ -1196,6 +1224,13 @@ func (d *qemu) start(stateful bool, op *operationlock.InstanceOperation) error {
 
 	revert.Add(func() { _ = d.unmount() })
 
+	// Finish cleaning up a live storage move if the VM stopped while LXD wasn't running.
+	err = d.cleanupStorageMoveSource(true)
+	if err != nil {
+		op.Done(err)
+		return err
+	}
+
 	// Define a set of files to open and pass their file descriptors to QEMU command.
 	fdFiles := make([]*os.File, 0)
 
@@ -1748,7 +1783,8 @@ func (d *qemu) start(stateful bool, op *operationlock.InstanceOperation) error {
 	// - architecture supports hotplug
 	// - no explicit vCPU pinning was specified (cpuInfo.vcpus == nil)
 	// - we have more than one vCPU set
-	if d.architectureSupportsCPUHotplug() && cpuInfo.vcpus == nil && cpuInfo.cores > 1 {
+	// - no guest NUMA nodes are configured (all vCPUs are then added at boot time)
+	if d.architectureSupportsCPUHotplug() && cpuInfo.vcpus == nil && cpuInfo.cores > 1 && !d.hasGuestNumaNodes() {
 		// Setup CPUs and core scheduling for hotpluggable CPU systems.
 		err := d.setCPUs(cpuInfo.cores)
 		if err != nil {
@@ -2250,6 +2286,14 @@ func (d *qemu) deviceStart(dev device.Device, instanceRunning bool) (*deviceConf
 			}
 		}
 
+		// Attach serial port to running instance.
+		if len(runConf.SerialDevice) > 0 {
+			err = d.deviceAttachSerial(runConf.SerialDevice)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		// If running, run post start hooks now (if not, they will be run
 		// once the instance is started).
 		err = d.runHooks(runConf.PostHooks)
@@ -2581,7 +2625,10 @@ func (d *qemu) deviceDetachBlockDevice(deviceName string) error {
 		return err
 	}
 
-	blockDevName := qemuDeviceNameOrID(qemuDeviceNamePrefix, deviceName, "", qemuDeviceNameMaxLength)
+	blockDevName, err := d.blockNodeName(monitor, deviceName)
+	if err != nil {
+		return err
+	}
 
 	err = monitor.RemoveFDFromFDSet(blockDevName)
 	if err != nil {
@@ -2612,6 +2659,11 @@ func (d *qemu) deviceDetachBlockDevice(deviceName string) error {
 		}
 	}
 
+	err = d.checkpointLayerRemove(monitor, deviceName)
+	if err != nil {
+		return fmt.Errorf("Failed removing checkpoint metadata of block device: %w", err)
+	}
+
 	return nil
 }
 
@@ -2742,6 +2794,14 @@ func (d *qemu) deviceStop(dev device.Device, instanceRunning bool, _ string) err
 				return err
 			}
 		}
+
+		// Detach serial port from running instance.
+		if configCopy["type"] == "serial" {
+			err = d.deviceDetachSerial(dev.Name())
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	if runConf != nil {
@@ -3571,11 +3631,14 @@ func (d *qemu) generateQemuConfigFile(cpuInfo *cpuTopology, mountInfo *storagePo
 	// total of 256 devices, but this assumes 32 chassis * 8 function. By using VFs for the internal fixed
 	// devices we avoid consuming a chassis for each one. See also the qemuPCIDeviceIDStart constant.
 	devBus, devAddr, multi := bus.allocate(busFunctionGroupGeneric)
-	balloonOpts := qemuDevOpts{
-		busName:       bus.name,
-		devBus:        devBus,
-		devAddr:       devAddr,
-		multifunction: multi,
+	balloonOpts := qemuBalloonOpts{
+		dev: qemuDevOpts{
+			busName:       bus.name,
+			devBus:        devBus,
+			devAddr:       devAddr,
+			multifunction: multi,
+		},
+		freePageReporting: d.memoryBalloonAuto(),
 	}
 
 	cfg = append(cfg, qemuBalloon(&balloonOpts)...)
@@ -3925,6 +3988,18 @@ func (d *qemu) generateQemuConfigFile(cpuInfo *cpuTopology, mountInfo *storagePo
 				return "", nil, err
 			}
 		}
+
+		// Add serial device.
+		if len(runConf.SerialDevice) > 0 {
+			monHook, err := d.addSerialDeviceConfig(&cfg, runConf.SerialDevice, fdFiles)
+			if err != nil {
+				return "", nil, err
+			}
+
+			if monHook != nil {
+				monHooks = append(monHooks, monHook)
+			}
+		}
 	}
 
 	// Apply any volatile changes that need to be made.
@@ -4070,8 +4145,55 @@ func (d *qemu) addCPUMemoryConfig(cfg *[]cfgSection, cpuInfo *cpuTopology) error
 	nodeMemory := int64(memSizeMB / int64(len(hostNodes)))
 	cpuOpts.memory = nodeMemory
 
+	// Configure the guest NUMA nodes if explicitly defined.
+	if d.hasGuestNumaNodes() {
+		if cpuPinning {
+			return errors.New("Guest NUMA nodes cannot be used with CPU pinning or limits.cpu.nodes")
+		}
+
+		if d.expandedConfig["limits.memory.hotplug_max"] != "" {
+			return errors.New("Guest NUMA nodes cannot be used with limits.memory.hotplug_max")
+		}
+
+		numaNodes, numaMapping, err := d.guestNumaNodes(cpuInfo.cores, memSizeMB)
+		if err != nil {
+			return err
+		}
+
+		// All vCPUs are added at boot time, one socket per guest NUMA node.
+		cpuPinning = true
+		cpuOpts.cpuCount = cpuInfo.cores
+		cpuOpts.cpuSockets = len(numaNodes)
+		cpuOpts.cpuCores = cpuInfo.cores / len(numaNodes)
+		cpuOpts.cpuThreads = 1
+		cpuOpts.cpuNumaMapping = numaMapping
+		cpuOpts.cpuNumaHostNodes = nil
+		cpuOpts.numaNodes = numaNodes
+	}
+
+	// Configure memory hotplug limit.
+	memOpts := qemuMemoryOpts{memSizeMB: memSizeMB}
+
+	maxMemSizeBytes, err := d.memoryHotplugMaxBytes()
+	if err != nil {
+		return err
+	}
+
+	if maxMemSizeBytes > 0 {
+		if maxMemSizeBytes < memSizeBytes {
+			return errors.New("limits.memory.hotplug_max must be greater than or equal to limits.memory")
+		}
+
+		if shared.IsTrue(d.expandedConfig["migration.stateful"]) {
+			return errors.New("limits.memory.hotplug_max cannot be used with migration.stateful")
+		}
+
+		memOpts.maxMemSizeMB = maxMemSizeBytes / 1024 / 1024
+		memOpts.memSlots = qemuMemoryHotplugSlots
+	}
+
 	if cfg != nil {
-		*cfg = append(*cfg, qemuMemory(&qemuMemoryOpts{memSizeMB})...)
+		*cfg = append(*cfg, qemuMemory(&memOpts)...)
 		*cfg = append(*cfg, qemuCPU(&cpuOpts, cpuPinning)...)
 	}
 
@@ -4481,6 +4603,8 @@ func (d *qemu) addDriveConfig(busAllocate busAllocator, bootIndexes map[string]i
 		qemuDev["bootindex"] = bootIndexes[driveConf.DevName]
 	}
 
+	checkpointLayer := d.checkpointsEnabled() && !readonly && media == "disk"
+
 	monHook := func(m *qmp.Monitor) error {
 		reverter := revert.New()
 		defer reverter.Fail()
@@ -4533,9 +4657,30 @@ func (d *qemu) addDriveConfig(busAllocate busAllocator, bootIndexes map[string]i
 			blockDev["filename"] = fmt.Sprintf("/dev/fdset/%d", info.ID)
 		}
 
-		err := m.AddBlockDevice(blockDev, qemuDev)
-		if err != nil {
-			return fmt.Errorf("Failed adding block device for disk device %q: %w", driveConf.DevName, err)
+		// Writable disks are attached through a checkpoint metadata image when checkpoints are enabled.
+		if checkpointLayer {
+			cleanup, err := d.checkpointLayerAdd(m, driveConf.DevName, blockDev)
+			if err != nil {
+				return fmt.Errorf("Failed adding block device for disk device %q: %w", driveConf.DevName, err)
+			}
+
+			reverter.Add(cleanup)
+
+			err = m.AddDevice(qemuDev)
+			if err != nil {
+				return fmt.Errorf("Failed adding device for disk device %q: %w", driveConf.DevName, err)
+			}
+		} else {
+			// Changes made while the disk isn't tracked would be missed by any existing checkpoint.
+			err := d.checkpointMetadataDelete(driveConf.DevName)
+			if err != nil {
+				return err
+			}
+
+			err = m.AddBlockDevice(blockDev, qemuDev)
+			if err != nil {
+				return fmt.Errorf("Failed adding block device for disk device %q: %w", driveConf.DevName, err)
+			}
 		}
 
 		if driveConf.Limits != nil {
@@ -4544,7 +4689,7 @@ func (d *qemu) addDriveConfig(busAllocate busAllocator, bootIndexes map[string]i
 				return errors.New("Failed getting QEMU device id")
 			}
 
-			err = m.SetBlockThrottle(qemuDevID, int(driveConf.Limits.ReadBytes), int(driveConf.Limits.WriteBytes), int(driveConf.Limits.ReadIOps), int(driveConf.Limits.WriteIOps))
+			err := m.SetBlockThrottle(qemuDevID, qemuBlockThrottle(driveConf.Limits))
 			if err != nil {
 				return fmt.Errorf("Failed applying limits for disk device %q: %w", driveConf.DevName, err)
 			}
@@ -6001,6 +6146,9 @@ func (d *qemu) Update(args db.InstanceArgs, userRequested bool) error {
 		liveUpdateKeys := []string{
 			"cluster.evacuate",
 			"limits.memory",
+			"limits.memory.balloon",
+			"limits.memory.balloon.max",
+			"limits.memory.balloon.min",
 			"security.agent.metrics",
 			"boot.mode",
 			"security.devlxd",
@@ -6026,7 +6174,7 @@ func (d *qemu) Update(args db.InstanceArgs, userRequested bool) error {
 			}
 
 			if key == "limits.cpu" {
-				return d.architectureSupportsCPUHotplug()
+				return d.architectureSupportsCPUHotplug() && !d.hasGuestNumaNodes()
 			}
 
 			if slices.Contains(liveUpdateKeys, key) {
@@ -6085,6 +6233,33 @@ func (d *qemu) Update(args db.InstanceArgs, userRequested bool) error {
 						return fmt.Errorf("Failed updating memory limit: %w", err)
 					}
 				}
+			case "limits.memory.balloon", "limits.memory.balloon.max", "limits.memory.balloon.min":
+				if !d.memoryBalloonAuto() {
+					// Restore the balloon to the configured memory limit when leaving automatic mode.
+					if key == "limits.memory.balloon" {
+						memSize := d.expandedConfig["limits.memory"]
+						if memSize == "" {
+							memSize = QEMUDefaultMemSize
+						}
+
+						err = d.updateMemoryLimit(memSize)
+						if err != nil {
+							return fmt.Errorf("Failed updating memory limit: %w", err)
+						}
+					}
+
+					continue
+				}
+
+				_, _, err = d.memoryBalloonBounds()
+				if err != nil {
+					return err
+				}
+
+				err = d.MemoryBalloonUpdate()
+				if err != nil {
+					d.logger.Warn("Failed adjusting memory balloon", logger.Ctx{"err": err})
+				}
 			case "boot.mode":
 				// Defer rebuilding nvram until next start.
 				d.localConfig["volatile.apply_nvram"] = "true"
@@ -6238,13 +6413,157 @@ func (d *qemu) Update(args db.InstanceArgs, userRequested bool) error {
 	return nil
 }
 
-// updateMemoryLimit live updates the VM's memory limit by reszing the balloon device.
+// memoryHotplugMaxBytes returns the maximum memory size the VM can be grown to while running.
+// Returns 0 if memory hotplug isn't enabled.
+func (d *qemu) memoryHotplugMaxBytes() (int64, error) {
+	maxSize := d.expandedConfig["limits.memory.hotplug_max"]
+	if maxSize == "" {
+		return 0, nil
+	}
+
+	if !slices.Contains([]int{osarch.ARCH_64BIT_INTEL_X86, osarch.ARCH_64BIT_ARMV8_LITTLE_ENDIAN}, d.architecture) {
+		return 0, errors.New("Memory hotplug is not supported on this architecture")
+	}
+
+	maxSizeBytes, err := parseMemoryStr(maxSize)
+	if err != nil {
+		return 0, fmt.Errorf("limits.memory.hotplug_max invalid: %w", err)
+	}
+
+	return maxSizeBytes, nil
+}
+
+// updateMemoryHotplug adds or removes DIMM devices so that the plugged memory matches the difference between
+// the requested size and the boot time memory size. Memory is added in qemuMemoryHotplugBlockSize increments
+// and removed one DIMM at a time, failing if the guest doesn't release one. Returns the total plugged memory in bytes.
+func (d *qemu) updateMemoryHotplug(monitor *qmp.Monitor, baseSizeBytes int64, newSizeBytes int64, maxSizeBytes int64) (int64, error) {
+	memDevices, err := monitor.QueryMemoryDevices()
+	if err != nil {
+		return -1, fmt.Errorf("Failed querying memory devices: %w", err)
+	}
+
+	var pluggedBytes int64
+	dimms := []qmp.MemoryDeviceInfo{}
+	usedIDs := map[string]bool{}
+	for _, memDevice := range memDevices {
+		if !strings.HasPrefix(memDevice.Data.ID, qemuMemoryHotplugDeviceIDPrefix) {
+			continue
+		}
+
+		dimms = append(dimms, memDevice.Data)
+		usedIDs[memDevice.Data.ID] = true
+		pluggedBytes += memDevice.Data.Size
+	}
+
+	targetBytes := max(newSizeBytes-baseSizeBytes, 0)
+
+	if targetBytes > pluggedBytes {
+		if len(dimms) >= qemuMemoryHotplugSlots {
+			return -1, errors.New("Cannot hotplug more memory, no free memory slots available")
+		}
+
+		// Round up to the hotplug block size (the balloon will take care of the remainder) but don't
+		// exceed the reserved hotplug range.
+		sizeBytes := ((targetBytes - pluggedBytes + qemuMemoryHotplugBlockSize - 1) / qemuMemoryHotplugBlockSize) * qemuMemoryHotplugBlockSize
+		availableBytes := ((maxSizeBytes - baseSizeBytes - pluggedBytes) / qemuMemoryHotplugBlockSize) * qemuMemoryHotplugBlockSize
+		sizeBytes = min(sizeBytes, availableBytes)
+		if sizeBytes <= 0 {
+			return -1, fmt.Errorf("Cannot increase memory size beyond limits.memory.hotplug_max (%dMiB)", maxSizeBytes/1024/1024)
+		}
+
+		var devID string
+		for i := range qemuMemoryHotplugSlots {
+			devID = fmt.Sprintf("%s%d", qemuMemoryHotplugDeviceIDPrefix, i)
+			if !usedIDs[devID] {
+				break
+			}
+		}
+
+		memdevID := devID + "-mem"
+		memdev := map[string]any{
+			"qom-type": "memory-backend-memfd",
+			"id":       memdevID,
+			"size":     sizeBytes,
+			"share":    true,
+		}
+
+		if shared.IsTrue(d.expandedConfig["limits.memory.hugepages"]) {
+			hugetlb, err := util.HugepagesPath()
+			if err != nil {
+				return -1, err
+			}
+
+			memdev["qom-type"] = "memory-backend-file"
+			memdev["mem-path"] = hugetlb
+			memdev["prealloc"] = true
+			memdev["discard-data"] = true
+		}
+
+		revert := revert.New()
+		defer revert.Fail()
+
+		err = monitor.AddObject(memdev)
+		if err != nil {
+			return -1, fmt.Errorf("Failed adding memory backend: %w", err)
+		}
+
+		revert.Add(func() { _ = monitor.RemoveObject(memdevID) })
+
+		err = monitor.AddDevice(map[string]any{
+			"driver": "pc-dimm",
+			"id":     devID,
+			"memdev": memdevID,
+		})
+		if err != nil {
+			return -1, fmt.Errorf("Failed adding memory device: %w", err)
+		}
+
+		revert.Success()
+
+		return pluggedBytes + sizeBytes, nil
+	}
+
+	// Remove the most recently plugged DIMMs first, for as long as they fit in the reduction.
+	for i := len(dimms) - 1; i >= 0 && pluggedBytes-dimms[i].Size >= targetBytes; i-- {
+		dimm := dimms[i]
+
+		// The guest needs to offline the memory before the DIMM is released, which it may refuse to do.
+		// Only once QEMU reports the DIMM as deleted can its memory backend be removed.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = monitor.RemoveDeviceWait(ctx, dimm.ID)
+		cancel()
+		if err != nil {
+			return -1, fmt.Errorf("Guest didn't release hotplugged memory device %q: %w", dimm.ID, err)
+		}
+
+		// The memdev reported by QEMU is the QOM path of the backend, remove it using its object ID.
+		memdevID := dimm.ID + "-mem"
+		err = monitor.RemoveObject(memdevID)
+		if err != nil {
+			return -1, fmt.Errorf("Failed removing memory backend %q: %w", memdevID, err)
+		}
+
+		pluggedBytes -= dimm.Size
+	}
+
+	return pluggedBytes, nil
+}
+
+// updateMemoryLimit live updates the VM's memory limit by hotplugging memory (when enabled) and
+// resizing the balloon device.
 func (d *qemu) updateMemoryLimit(newLimit string) error {
 	if newLimit == "" {
 		return nil
 	}
 
-	if shared.IsTrue(d.expandedConfig["limits.memory.hugepages"]) {
+	hugepages := shared.IsTrue(d.expandedConfig["limits.memory.hugepages"])
+
+	maxSizeBytes, err := d.memoryHotplugMaxBytes()
+	if err != nil {
+		return err
+	}
+
+	if hugepages && maxSizeBytes == 0 {
 		return errors.New("Cannot live update memory limit when using huge pages")
 	}
 
@@ -6267,8 +6586,30 @@ func (d *qemu) updateMemoryLimit(newLimit string) error {
 		return err
 	}
 
+	// Add or remove hotplugged memory, the total guest memory then includes the plugged memory.
+	if maxSizeBytes > 0 {
+		if newSizeBytes > maxSizeBytes {
+			return fmt.Errorf("Cannot increase memory size beyond limits.memory.hotplug_max when VM is running (Maximum size %dMiB, new size %dMiB)", maxSizeBytes/1024/1024, newSizeMB)
+		}
+
+		pluggedBytes, err := d.updateMemoryHotplug(monitor, baseSizeBytes, newSizeBytes, maxSizeBytes)
+		if err != nil {
+			return err
+		}
+
+		baseSizeBytes += pluggedBytes
+	}
+
 	baseSizeMB := baseSizeBytes / 1024 / 1024
 
+	if hugepages {
+		if baseSizeMB != newSizeMB {
+			return fmt.Errorf("Cannot live update memory limit when using huge pages unless it matches the hotplugged memory size (Current size %dMiB, new size %dMiB)", baseSizeMB, newSizeMB)
+		}
+
+		return nil
+	}
+
 	curSizeBytes, err := monitor.GetMemoryBalloonSizeBytes()
 	if err != nil {
 		return err
@@ -6413,6 +6754,10 @@ func (d *qemu) delete(force bool) error {
 		return err
 	}
 
+	if !d.IsSnapshot() {
+		d.memoryBalloonClearAdjustments()
+	}
+
 	// Attempt to initialize storage interface for the instance.
 	pool, err := d.getStoragePool()
 	if err != nil && !response.IsNotFoundError(err) {
@@ -6425,6 +6770,12 @@ func (d *qemu) delete(force bool) error {
 				return err
 			}
 		} else {
+			// Remove any root volume left behind by a live storage move.
+			err = d.cleanupStorageMoveSource(false)
+			if err != nil {
+				return err
+			}
+
 			// Remove all snapshots.
 			err := d.deleteSnapshots(func(snapInst instance.Instance) error {
 				return snapInst.(*qemu).delete(true) // Internal delete function that does not lock.
@@ -7042,7 +7393,25 @@ func (d *qemu) migrateSendLive(pool storagePools.Pool, clusterMoveSourceName str
 		return err
 	}
 
-	rootDiskName := "lxd_root"                  // Name of source disk device to sync from
+	// Checkpoint exports rely on the disk block nodes which the migration rearranges.
+	release, err := qemuNBDServerReserve(d.id, "migration")
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
+	rootDevName, _, err := d.getRootDiskDevice()
+	if err != nil {
+		return err
+	}
+
+	// Name of source disk device to sync from (the root disk may have been moved to another pool while running).
+	rootDiskName, err := d.blockNodeName(monitor, rootDevName)
+	if err != nil {
+		return err
+	}
+
 	nbdTargetDiskName := "lxd_root_nbd"         // Name of NBD disk device added to local VM to sync to.
 	rootSnapshotDiskName := "lxd_root_snapshot" // Name of snapshot disk device to use.
 
@@ -8516,7 +8885,7 @@ func (d *qemu) DeviceEventHandler(runConf *deviceConfig.RunConfig) error {
 		devID := qemuDeviceIDPrefix + filesystem.PathNameEncode(mount.DevName)
 
 		// Apply the limits.
-		err = m.SetBlockThrottle(devID, int(mount.Limits.ReadBytes), int(mount.Limits.WriteBytes), int(mount.Limits.ReadIOps), int(mount.Limits.WriteIOps))
+		err = m.SetBlockThrottle(devID, qemuBlockThrottle(mount.Limits))
 		if err != nil {
 			return fmt.Errorf("Failed applying limits for disk device %q: %w", mount.DevName, err)
 		}
@@ -8525,6 +8894,21 @@ func (d *qemu) DeviceEventHandler(runConf *deviceConfig.RunConfig) error {
 	return nil
 }
 
+// qemuBlockThrottle converts disk limits into QEMU block throttling limits.
+func qemuBlockThrottle(limits *deviceConfig.DiskLimits) qmp.BlockThrottle {
+	return qmp.BlockThrottle{
+		BytesRead:     limits.ReadBytes,
+		BytesWrite:    limits.WriteBytes,
+		IOPsRead:      limits.ReadIOps,
+		IOPsWrite:     limits.WriteIOps,
+		BytesReadMax:  limits.ReadBytesBurst,
+		BytesWriteMax: limits.WriteBytesBurst,
+		IOPsReadMax:   limits.ReadIOpsBurst,
+		IOPsWriteMax:  limits.WriteIOpsBurst,
+		MaxLength:     limits.BurstDuration,
+	}
+}
+
 // reservedVsockID returns true if the given vsockID equals 0, 1 or 2.
 // Those are reserved and we cannot use them.
 func (d *qemu) reservedVsockID(vsockID uint32) bool {
@@ -8791,6 +9175,161 @@ type cpuTopology struct {
 	nodes   map[uint64][]uint64
 }
 
+// hasGuestNumaNodes returns whether the guest NUMA topology is explicitly configured through limits.numa.nodes.*.
+func (d *qemu) hasGuestNumaNodes() bool {
+	for key := range d.expandedConfig {
+		if strings.HasPrefix(key, "limits.numa.nodes.") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// guestNumaNodes parses the limits.numa.nodes.<id>.* configuration into the guest NUMA nodes and the mapping
+// of the vCPUs to those nodes. Each guest NUMA node is exposed as its own CPU socket.
+func (d *qemu) guestNumaNodes(cpuCount int, memSizeMB int64) ([]qemuNumaNode, []qemuNumaEntry, error) {
+	nodeConfigs := map[int]map[string]string{}
+	for key, value := range d.expandedConfig {
+		nodeKey, found := strings.CutPrefix(key, "limits.numa.nodes.")
+		if !found {
+			continue
+		}
+
+		nodeIDStr, field, _ := strings.Cut(nodeKey, ".")
+		nodeID, err := strconv.Atoi(nodeIDStr)
+		if err != nil || strconv.Itoa(nodeID) != nodeIDStr {
+			return nil, nil, fmt.Errorf("Invalid guest NUMA node ID %q", nodeIDStr)
+		}
+
+		if nodeConfigs[nodeID] == nil {
+			nodeConfigs[nodeID] = map[string]string{}
+		}
+
+		nodeConfigs[nodeID][field] = value
+	}
+
+	if len(nodeConfigs) == 0 {
+		return nil, nil, nil
+	}
+
+	if d.architecture != osarch.ARCH_64BIT_INTEL_X86 {
+		return nil, nil, errors.New("Guest NUMA nodes are only supported on x86_64")
+	}
+
+	nodes := make([]qemuNumaNode, 0, len(nodeConfigs))
+	vcpuNodes := make([]int, cpuCount)
+	for i := range vcpuNodes {
+		vcpuNodes[i] = -1
+	}
+
+	coresPerNode := -1
+	var totalMemSizeMB int64
+	for nodeID := range len(nodeConfigs) {
+		nodeConfig, ok := nodeConfigs[nodeID]
+		if !ok {
+			return nil, nil, fmt.Errorf("Guest NUMA node IDs must be contiguous and start at 0 (missing node %d)", nodeID)
+		}
+
+		// Assign the vCPUs to the node.
+		if nodeConfig["cpus"] == "" {
+			return nil, nil, fmt.Errorf("Guest NUMA node %d has no vCPUs defined", nodeID)
+		}
+
+		vcpus, err := resources.ParseCpuset(nodeConfig["cpus"])
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid vCPUs for guest NUMA node %d: %w", nodeID, err)
+		}
+
+		if coresPerNode != -1 && len(vcpus) != coresPerNode {
+			return nil, nil, errors.New("All guest NUMA nodes must have the same number of vCPUs")
+		}
+
+		coresPerNode = len(vcpus)
+
+		for _, vcpu := range vcpus {
+			if vcpu >= int64(cpuCount) {
+				return nil, nil, fmt.Errorf("Guest NUMA node %d uses vCPU %d which is beyond limits.cpu", nodeID, vcpu)
+			}
+
+			if vcpuNodes[vcpu] != -1 {
+				return nil, nil, fmt.Errorf("vCPU %d is assigned to more than one guest NUMA node", vcpu)
+			}
+
+			vcpuNodes[vcpu] = nodeID
+		}
+
+		// Size the memory of the node.
+		if nodeConfig["memory"] == "" {
+			return nil, nil, fmt.Errorf("Guest NUMA node %d has no memory defined", nodeID)
+		}
+
+		nodeMemSizeBytes, err := units.ParseByteSizeString(nodeConfig["memory"])
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid memory for guest NUMA node %d: %w", nodeID, err)
+		}
+
+		node := qemuNumaNode{
+			memory:   nodeMemSizeBytes / 1024 / 1024,
+			hostNode: -1,
+		}
+
+		totalMemSizeMB += node.memory
+
+		if nodeConfig["host_node"] != "" {
+			node.hostNode, err = strconv.ParseInt(nodeConfig["host_node"], 10, 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Invalid host NUMA node for guest NUMA node %d: %w", nodeID, err)
+			}
+		}
+
+		if nodeConfig["distances"] != "" {
+			for i, distanceStr := range strings.Split(nodeConfig["distances"], ",") {
+				distance, err := strconv.ParseUint(strings.TrimSpace(distanceStr), 10, 8)
+				if err != nil {
+					return nil, nil, fmt.Errorf("Invalid distances for guest NUMA node %d: %w", nodeID, err)
+				}
+
+				if (i == nodeID) != (distance == 10) {
+					return nil, nil, fmt.Errorf("Invalid distances for guest NUMA node %d: only the distance to itself can (and must) be 10", nodeID)
+				}
+
+				node.distances = append(node.distances, distance)
+			}
+
+			if len(node.distances) != len(nodeConfigs) {
+				return nil, nil, fmt.Errorf("Guest NUMA node %d must define the distances to all %d nodes", nodeID, len(nodeConfigs))
+			}
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	if totalMemSizeMB != memSizeMB {
+		return nil, nil, fmt.Errorf("The memory of the guest NUMA nodes (%dMiB) must add up to limits.memory (%dMiB)", totalMemSizeMB, memSizeMB)
+	}
+
+	// Map each vCPU to its node's socket.
+	mapping := make([]qemuNumaEntry, 0, cpuCount)
+	nodeCores := make([]uint64, len(nodes))
+	for vcpu, nodeID := range vcpuNodes {
+		if nodeID == -1 {
+			return nil, nil, fmt.Errorf("vCPU %d isn't assigned to any guest NUMA node", vcpu)
+		}
+
+		mapping = append(mapping, qemuNumaEntry{
+			node:   uint64(nodeID),
+			socket: uint64(nodeID),
+			core:   nodeCores[nodeID],
+			thread: 0,
+		})
+
+		nodeCores[nodeID]++
+	}
+
+	return nodes, mapping, nil
+}
+
 // cpuTopology takes the CPU limit and computes the QEMU CPU topology.
 func (d *qemu) cpuTopology(limit string) (*cpuTopology, error) {
 	topology := &cpuTopology{}
@@ -9274,24 +9813,48 @@ func (d *qemu) Metrics(_ []net.Interface) (*metrics.MetricSet, error) {
 		return nil, ErrInstanceIsStopped
 	}
 
+	var metricSet *metrics.MetricSet
+
 	if d.agentMetricsEnabled() {
-		metrics, err := d.getAgentMetrics()
+		var err error
+		metricSet, err = d.getAgentMetrics()
 		if err != nil {
 			if !errors.Is(err, errQemuAgentOffline) {
 				d.logger.Warn("Could not get VM metrics from agent", logger.Ctx{"err": err})
 			}
 
-			// Fallback data if agent is not reachable.
-			return d.getQemuMetrics()
+			metricSet = nil
 		}
+	}
+
+	// Connect to the monitor, only needed for the fallback data and the memory balloon metrics.
+	monitor, monitorErr := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
 
-		return metrics, nil
+	if metricSet == nil {
+		if monitorErr != nil {
+			return nil, monitorErr
+		}
+
+		// Fallback data if agent is not reachable.
+		var err error
+		metricSet, err = d.getQemuMetrics(monitor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if monitorErr != nil {
+		d.logger.Warn("Failed connecting to monitor for memory balloon metrics", logger.Ctx{"err": monitorErr})
+		return metricSet, nil
 	}
 
-	return d.getQemuMetrics()
+	d.addMemoryBalloonMetrics(monitor, metricSet)
+
+	return metricSet, nil
 }
 
-func (d *qemu) getAgentMetrics() (*metrics.MetricSet, error) {
+// getAgentMetricsAPI fetches the raw metrics from the lxd-agent.
+func (d *qemu) getAgentMetricsAPI() (*metrics.Metrics, error) {
 	client, err := d.getAgentClient()
 	if err != nil {
 		return nil, err
@@ -9317,8 +9880,17 @@ func (d *qemu) getAgentMetrics() (*metrics.MetricSet, error) {
 		return nil, err
 	}
 
+	return &m, nil
+}
+
+func (d *qemu) getAgentMetrics() (*metrics.MetricSet, error) {
+	m, err := d.getAgentMetricsAPI()
+	if err != nil {
+		return nil, err
+	}
+
 	// The running state is hard-coded here as if we've made it to this point, the VM is running.
-	metricSet, err := metrics.MetricSetFromAPI(&m, map[string]string{"project": d.project.Name, "name": d.name, "type": instancetype.VM.String(), "state": instance.PowerStateRunning})
+	metricSet, err := metrics.MetricSetFromAPI(m, map[string]string{"project": d.project.Name, "name": d.name, "type": instancetype.VM.String(), "state": instance.PowerStateRunning})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,183 @@
+package drivers
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/canonical/lxd/lxd/instance/drivers/qmp"
+	"github.com/canonical/lxd/lxd/instance/instancetype"
+	"github.com/canonical/lxd/lxd/metrics"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// qemuBalloonLowWaterPercent is the percentage of available guest memory below which the guest is considered
+// to be under memory pressure and the balloon is deflated.
+const qemuBalloonLowWaterPercent = 10
+
+// qemuBalloonHighWaterPercent is the percentage of available guest memory above which the guest is considered
+// to be idle and the balloon is inflated.
+const qemuBalloonHighWaterPercent = 40
+
+// qemuBalloonHeadroomPercent is the percentage of the guest memory that is kept available when resizing.
+const qemuBalloonHeadroomPercent = 25
+
+// qemuBalloonStepPercent is the maximum percentage of the balloon upper bound reclaimed from a guest at once.
+const qemuBalloonStepPercent = 10
+
+// qemuBalloonAdjustments records the automatic balloon adjustments made per instance ID.
+var qemuBalloonAdjustments = map[int]map[string]uint64{}
+var qemuBalloonAdjustmentsMu sync.Mutex
+
+// qemuBalloonTarget returns the balloon size that should be applied to a guest given its current size, the
+// memory the guest reports as available and the configured bounds.
+func qemuBalloonTarget(curBytes int64, availBytes int64, minBytes int64, maxBytes int64) int64 {
+	usedBytes := max(curBytes-availBytes, 0)
+	wantedBytes := usedBytes * 100 / (100 - qemuBalloonHeadroomPercent)
+
+	targetBytes := curBytes
+	if availBytes < curBytes*qemuBalloonLowWaterPercent/100 {
+		// Guest is under pressure, give it back enough memory to restore the headroom.
+		targetBytes = max(wantedBytes, curBytes+(maxBytes*qemuBalloonStepPercent/100))
+	} else if availBytes > curBytes*qemuBalloonHighWaterPercent/100 {
+		// Guest is mostly idle, reclaim memory gradually so that a burst doesn't hit a small guest.
+		targetBytes = max(wantedBytes, curBytes-(maxBytes*qemuBalloonStepPercent/100))
+	}
+
+	return min(max(targetBytes, minBytes), maxBytes)
+}
+
+// memoryBalloonAuto returns whether the balloon size is managed automatically.
+func (d *qemu) memoryBalloonAuto() bool {
+	return d.expandedConfig["limits.memory.balloon"] == instancetype.MemoryBalloonModeAuto
+}
+
+// memoryBalloonBounds returns the lower and upper bounds of the automatically managed balloon.
+// The upper bound defaults to limits.memory and the lower bound to half of the upper bound.
+func (d *qemu) memoryBalloonBounds() (int64, int64, error) {
+	memSize := d.expandedConfig["limits.memory"]
+	if memSize == "" {
+		memSize = QEMUDefaultMemSize
+	}
+
+	memSizeBytes, err := parseMemoryStr(memSize)
+	if err != nil {
+		return -1, -1, fmt.Errorf("limits.memory invalid: %w", err)
+	}
+
+	maxBytes := memSizeBytes
+	if d.expandedConfig["limits.memory.balloon.max"] != "" {
+		maxBytes, err = parseMemoryStr(d.expandedConfig["limits.memory.balloon.max"])
+		if err != nil {
+			return -1, -1, fmt.Errorf("limits.memory.balloon.max invalid: %w", err)
+		}
+
+		maxBytes = min(maxBytes, memSizeBytes)
+	}
+
+	minBytes := maxBytes / 2
+	if d.expandedConfig["limits.memory.balloon.min"] != "" {
+		minBytes, err = parseMemoryStr(d.expandedConfig["limits.memory.balloon.min"])
+		if err != nil {
+			return -1, -1, fmt.Errorf("limits.memory.balloon.min invalid: %w", err)
+		}
+	}
+
+	if minBytes > maxBytes {
+		return -1, -1, errors.New("limits.memory.balloon.min must be lower than or equal to limits.memory.balloon.max")
+	}
+
+	return minBytes, maxBytes, nil
+}
+
+// MemoryBalloonUpdate resizes the memory balloon based on the memory pressure reported by the lxd-agent.
+// Does nothing unless limits.memory.balloon is set to auto.
+func (d *qemu) MemoryBalloonUpdate() error {
+	if !d.memoryBalloonAuto() || !d.IsRunning() {
+		return nil
+	}
+
+	minBytes, maxBytes, err := d.memoryBalloonBounds()
+	if err != nil {
+		return err
+	}
+
+	m, err := d.getAgentMetricsAPI()
+	if err != nil {
+		return fmt.Errorf("Failed getting memory metrics from agent: %w", err)
+	}
+
+	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
+	if err != nil {
+		return err
+	}
+
+	curBytes, err := monitor.GetMemoryBalloonSizeBytes()
+	if err != nil {
+		return err
+	}
+
+	targetBytes := qemuBalloonTarget(curBytes, int64(m.Memory.MemAvailableBytes), minBytes, maxBytes)
+
+	// Ignore changes of less than 1% to avoid constantly resizing the balloon.
+	diffBytes := targetBytes - curBytes
+	if max(diffBytes, -diffBytes) <= maxBytes/100 {
+		return nil
+	}
+
+	direction := "grow"
+	if diffBytes < 0 {
+		direction = "shrink"
+	}
+
+	d.logger.Debug("Adjusting memory balloon", logger.Ctx{"direction": direction, "current": curBytes, "target": targetBytes, "available": m.Memory.MemAvailableBytes})
+
+	err = monitor.SetMemoryBalloonSizeBytes(targetBytes)
+	if err != nil {
+		return fmt.Errorf("Failed setting memory balloon size: %w", err)
+	}
+
+	qemuBalloonAdjustmentsMu.Lock()
+	defer qemuBalloonAdjustmentsMu.Unlock()
+
+	if qemuBalloonAdjustments[d.id] == nil {
+		qemuBalloonAdjustments[d.id] = map[string]uint64{}
+	}
+
+	qemuBalloonAdjustments[d.id][direction]++
+
+	return nil
+}
+
+// memoryBalloonClearAdjustments forgets the automatic balloon adjustments recorded for the instance.
+func (d *qemu) memoryBalloonClearAdjustments() {
+	qemuBalloonAdjustmentsMu.Lock()
+	defer qemuBalloonAdjustmentsMu.Unlock()
+
+	delete(qemuBalloonAdjustments, d.id)
+}
+
+// addMemoryBalloonMetrics adds the current balloon size and the automatic balloon adjustments to the metric set.
+func (d *qemu) addMemoryBalloonMetrics(monitor *qmp.Monitor, metricSet *metrics.MetricSet) {
+	curBytes, err := monitor.GetMemoryBalloonSizeBytes()
+	if err != nil {
+		d.logger.Warn("Failed getting memory balloon size", logger.Ctx{"err": err})
+		return
+	}
+
+	metricSet.AddSamples(metrics.MemoryBalloonSizeBytes, metrics.Sample{Value: float64(curBytes)})
+
+	if !d.memoryBalloonAuto() {
+		return
+	}
+
+	qemuBalloonAdjustmentsMu.Lock()
+	defer qemuBalloonAdjustmentsMu.Unlock()
+
+	for _, direction := range []string{"grow", "shrink"} {
+		metricSet.AddSamples(metrics.MemoryBalloonAdjustmentsTotal, metrics.Sample{
+			Labels: map[string]string{"direction": direction},
+			Value:  float64(qemuBalloonAdjustments[d.id][direction]),
+		})
+	}
+}
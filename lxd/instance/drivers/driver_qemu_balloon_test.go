@@ -0,0 +1,72 @@
+package drivers
+
+import "testing"
+
+func TestQemuBalloonTarget(t *testing.T) {
+	tests := []struct {
+		name       string
+		curBytes   int64
+		availBytes int64
+		minBytes   int64
+		maxBytes   int64
+		want       int64
+	}{
+		{
+			name:       "Steady",
+			curBytes:   1000,
+			availBytes: 200,
+			minBytes:   500,
+			maxBytes:   1000,
+			want:       1000,
+		},
+		{
+			name:       "PressureRestoresHeadroom",
+			curBytes:   600,
+			availBytes: 50,
+			minBytes:   500,
+			maxBytes:   1000,
+			want:       733,
+		},
+		{
+			name:       "PressureCappedAtMax",
+			curBytes:   800,
+			availBytes: 40,
+			minBytes:   500,
+			maxBytes:   1000,
+			want:       1000,
+		},
+		{
+			name:       "IdleReclaimsOneStep",
+			curBytes:   1000,
+			availBytes: 600,
+			minBytes:   500,
+			maxBytes:   1000,
+			want:       900,
+		},
+		{
+			name:       "IdleReclaimStopsAtMin",
+			curBytes:   600,
+			availBytes: 500,
+			minBytes:   550,
+			maxBytes:   1000,
+			want:       550,
+		},
+		{
+			name:       "AboveMaxIsClamped",
+			curBytes:   1200,
+			availBytes: 200,
+			minBytes:   500,
+			maxBytes:   1000,
+			want:       1000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := qemuBalloonTarget(tt.curBytes, tt.availBytes, tt.minBytes, tt.maxBytes)
+			if got != tt.want {
+				t.Errorf("qemuBalloonTarget(%d, %d, %d, %d) = %d, want %d", tt.curBytes, tt.availBytes, tt.minBytes, tt.maxBytes, got, tt.want)
+			}
+		})
+	}
+}
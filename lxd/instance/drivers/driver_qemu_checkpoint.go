@@ -0,0 +1,615 @@
+package drivers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/canonical/lxd/lxd/instance/drivers/qmp"
+	"github.com/canonical/lxd/lxd/storage/filesystem"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/revert"
+)
+
+// qemuCheckpointBitmapPrefix is the prefix of the dirty bitmaps backing instance checkpoints.
+const qemuCheckpointBitmapPrefix = "lxd_checkpoint_"
+
+// qemuCheckpointDataNamePrefix is the prefix of the block node of a disk image used as the data file of the
+// checkpoint metadata image. Like qemuDeviceMirrorNamePrefix, it doesn't start with qemuDeviceNamePrefix.
+const qemuCheckpointDataNamePrefix = "lxdcpdata_"
+
+// qemuCheckpointMetadataNamePrefix is the prefix of the block node of the checkpoint metadata image of a disk.
+const qemuCheckpointMetadataNamePrefix = "lxdcpmeta_"
+
+// qemuCheckpointFleecingNodeName is the name of the block node holding the point in time view of an exported disk.
+const qemuCheckpointFleecingNodeName = "lxd_checkpoint_fleecing"
+
+// qemuNBDServerUsers records what each VM uses its NBD server for, keyed by instance ID.
+// QEMU only runs a single NBD server per process so checkpoint exports and migrations can't overlap.
+var qemuNBDServerUsers = map[int]string{}
+var qemuNBDServerUsersMu sync.Mutex
+
+// qemuNBDServerReserve reserves the NBD server of the VM for the specified use until the returned function is called.
+func qemuNBDServerReserve(instanceID int, use string) (func(), error) {
+	qemuNBDServerUsersMu.Lock()
+	defer qemuNBDServerUsersMu.Unlock()
+
+	current, found := qemuNBDServerUsers[instanceID]
+	if found {
+		return nil, api.StatusErrorf(http.StatusConflict, "The NBD server of the instance is in use by a %s", current)
+	}
+
+	qemuNBDServerUsers[instanceID] = use
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			qemuNBDServerUsersMu.Lock()
+			delete(qemuNBDServerUsers, instanceID)
+			qemuNBDServerUsersMu.Unlock()
+		})
+	}
+
+	return release, nil
+}
+
+// qemuNBDExportConn is an NBD connection which tears down the export and the NBD server when closed.
+type qemuNBDExportConn struct {
+	net.Conn
+
+	closeOnce sync.Once
+	cleanup   func()
+}
+
+// Close closes the NBD connection and removes the export.
+func (c *qemuNBDExportConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(c.cleanup)
+
+	return err
+}
+
+// checkpointsEnabled returns whether the writable disks of the VM are attached with a checkpoint metadata image.
+func (d *qemu) checkpointsEnabled() bool {
+	return shared.IsTrue(d.expandedConfig["checkpoints.enabled"])
+}
+
+// checkpointMetadataPath returns the path of the checkpoint metadata image of a disk.
+func (d *qemu) checkpointMetadataPath(devName string) string {
+	return filepath.Join(d.Path(), "checkpoints", filesystem.PathNameEncode(devName)+".qcow2")
+}
+
+// checkpointLayerAdd adds the block nodes of a disk with checkpoints enabled.
+// The disk image described by blockDev is used as the raw data file of a qcow2 metadata image which is stored in the
+// instance directory and holds the persistent dirty bitmaps of the disk. The guest data stays in the disk image.
+// The qcow2 node takes over the node name of blockDev so that the disk device can be added on top of it.
+// A metadata image which can't be used with the disk anymore (e.g. after the disk was grown) is recreated, which
+// discards the checkpoints of the disk. The returned hook removes the added block nodes.
+func (d *qemu) checkpointLayerAdd(m *qmp.Monitor, devName string, blockDev map[string]any) (revert.Hook, error) {
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	nodeName, ok := blockDev["node-name"].(string)
+	if !ok {
+		return nil, errors.New("Device node name must be a string")
+	}
+
+	dataNodeName := qemuDeviceNameOrID(qemuCheckpointDataNamePrefix, devName, "", qemuDeviceNameMaxLength)
+	metadataNodeName := qemuDeviceNameOrID(qemuCheckpointMetadataNamePrefix, devName, "", qemuDeviceNameMaxLength)
+
+	blockDev["node-name"] = dataNodeName
+
+	err := m.AddBlockDevice(blockDev, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed adding disk image: %w", err)
+	}
+
+	reverter.Add(func() { _ = m.RemoveBlockDevice(dataNodeName) })
+
+	metadataPath := d.checkpointMetadataPath(devName)
+
+	err = os.MkdirAll(filepath.Dir(metadataPath), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(metadataPath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("Failed opening checkpoint metadata image %q: %w", metadataPath, err)
+	}
+
+	defer func() { _ = f.Close() }()
+
+	metadataInfo, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := m.SendFileWithFDSet(metadataNodeName, f, false)
+	if err != nil {
+		return nil, fmt.Errorf("Failed sending file descriptor of %q: %w", metadataPath, err)
+	}
+
+	reverter.Add(func() { _ = m.RemoveFDFromFDSet(metadataNodeName) })
+
+	err = m.AddBlockDevice(map[string]any{
+		"driver":    "file",
+		"node-name": metadataNodeName,
+		"filename":  fmt.Sprintf("/dev/fdset/%d", info.ID),
+		"read-only": false,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed adding checkpoint metadata image: %w", err)
+	}
+
+	reverter.Add(func() { _ = m.RemoveBlockDevice(metadataNodeName) })
+
+	nodes, err := m.QueryNamedBlockNodes()
+	if err != nil {
+		return nil, fmt.Errorf("Failed querying block nodes: %w", err)
+	}
+
+	size := nodes[dataNodeName].Image.VirtualSize
+
+	createMetadata := func() error {
+		// The data file is kept as a valid raw image, which requires preallocated qcow2 metadata.
+		return m.BlockDevCreate(metadataNodeName, map[string]any{
+			"driver":        "qcow2",
+			"file":          metadataNodeName,
+			"data-file":     dataNodeName,
+			"data-file-raw": true,
+			"preallocation": "metadata",
+			"size":          size,
+		})
+	}
+
+	layerBlockDev := map[string]any{
+		"driver":    "qcow2",
+		"node-name": nodeName,
+		"file":      metadataNodeName,
+		"data-file": dataNodeName,
+		"cache":     blockDev["cache"],
+		"discard":   "unmap",
+		"read-only": false,
+	}
+
+	addLayer := func() error {
+		err := m.AddBlockDevice(layerBlockDev, nil)
+		if err != nil {
+			return err
+		}
+
+		nodes, err := m.QueryNamedBlockNodes()
+		if err != nil {
+			_ = m.RemoveBlockDevice(nodeName)
+			return fmt.Errorf("Failed querying block nodes: %w", err)
+		}
+
+		if nodes[nodeName].Image.VirtualSize != size {
+			_ = m.RemoveBlockDevice(nodeName)
+			return fmt.Errorf("Checkpoint metadata image size %d doesn't match disk size %d", nodes[nodeName].Image.VirtualSize, size)
+		}
+
+		return nil
+	}
+
+	if metadataInfo.Size() == 0 {
+		err = createMetadata()
+		if err != nil {
+			return nil, fmt.Errorf("Failed creating checkpoint metadata image: %w", err)
+		}
+
+		err = addLayer()
+	} else {
+		err = addLayer()
+		if err != nil {
+			d.logger.Warn("Recreating unusable checkpoint metadata image, discarding the checkpoints of the disk", logger.Ctx{"device": devName, "err": err})
+
+			err = createMetadata()
+			if err != nil {
+				return nil, fmt.Errorf("Failed creating checkpoint metadata image: %w", err)
+			}
+
+			err = addLayer()
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed adding checkpoint metadata layer: %w", err)
+	}
+
+	reverter.Add(func() { _ = m.RemoveBlockDevice(nodeName) })
+
+	// Bitmaps which weren't stored when the image was last closed (e.g. the VM crashed or the image was copied while
+	// in use) no longer describe the changes since their checkpoint.
+	nodes, err = m.QueryNamedBlockNodes()
+	if err != nil {
+		return nil, fmt.Errorf("Failed querying block nodes: %w", err)
+	}
+
+	for _, bitmap := range nodes[nodeName].DirtyBitmaps {
+		if !bitmap.Inconsistent {
+			continue
+		}
+
+		d.logger.Warn("Removing inconsistent checkpoint", logger.Ctx{"device": devName, "bitmap": bitmap.Name})
+
+		err = m.BlockDirtyBitmapRemove(nodeName, bitmap.Name)
+		if err != nil {
+			return nil, fmt.Errorf("Failed removing inconsistent dirty bitmap %q: %w", bitmap.Name, err)
+		}
+	}
+
+	cleanup := reverter.Clone().Fail
+	reverter.Success()
+
+	return cleanup, nil
+}
+
+// checkpointLayerRemove removes the checkpoint block nodes left behind by a disk whose qcow2 node was removed, along
+// with its checkpoint metadata image as changes to the disk are no longer tracked.
+func (d *qemu) checkpointLayerRemove(m *qmp.Monitor, devName string) error {
+	dataNodeName := qemuDeviceNameOrID(qemuCheckpointDataNamePrefix, devName, "", qemuDeviceNameMaxLength)
+	metadataNodeName := qemuDeviceNameOrID(qemuCheckpointMetadataNamePrefix, devName, "", qemuDeviceNameMaxLength)
+
+	for _, nodeName := range []string{dataNodeName, metadataNodeName} {
+		err := m.RemoveBlockDevice(nodeName)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := m.RemoveFDFromFDSet(metadataNodeName)
+	if err != nil {
+		return err
+	}
+
+	return d.checkpointMetadataDelete(devName)
+}
+
+// checkpointMetadataDelete deletes the checkpoint metadata image of a disk, if any.
+func (d *qemu) checkpointMetadataDelete(devName string) error {
+	err := os.Remove(d.checkpointMetadataPath(devName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("Failed deleting checkpoint metadata image of disk %q: %w", devName, err)
+	}
+
+	return nil
+}
+
+// checkpointDiskNodes returns the writable block nodes of the instance disks, keyed by device name.
+func (d *qemu) checkpointDiskNodes(monitor *qmp.Monitor) (map[string]qmp.BlockNode, error) {
+	nodes, err := monitor.QueryNamedBlockNodes()
+	if err != nil {
+		return nil, fmt.Errorf("Failed querying block nodes: %w", err)
+	}
+
+	diskNodes := make(map[string]qmp.BlockNode)
+	for devName, dev := range d.expandedDevices {
+		if dev["type"] != "disk" {
+			continue
+		}
+
+		nodeName, _ := qemuBlockNodeNames(nodes, devName)
+
+		node, ok := nodes[nodeName]
+		if !ok || node.ReadOnly {
+			continue
+		}
+
+		diskNodes[devName] = node
+	}
+
+	return diskNodes, nil
+}
+
+// checkpointMonitor returns a monitor connection for checkpoint operations on a running VM.
+func (d *qemu) checkpointMonitor() (*qmp.Monitor, error) {
+	if !d.IsRunning() {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Checkpoints require the instance to be running")
+	}
+
+	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
+	if err != nil {
+		return nil, err
+	}
+
+	return monitor, nil
+}
+
+// Checkpoints returns the checkpoints of the running VM.
+// Checkpoints are backed by persistent dirty bitmaps stored in the checkpoint metadata images of the disks.
+func (d *qemu) Checkpoints() ([]api.InstanceCheckpoint, error) {
+	monitor, err := d.checkpointMonitor()
+	if err != nil {
+		return nil, err
+	}
+
+	diskNodes, err := d.checkpointDiskNodes(monitor)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints := map[string]*api.InstanceCheckpoint{}
+	for devName, node := range diskNodes {
+		for _, bitmap := range node.DirtyBitmaps {
+			name, ok := strings.CutPrefix(bitmap.Name, qemuCheckpointBitmapPrefix)
+			if !ok {
+				continue
+			}
+
+			if checkpoints[name] == nil {
+				checkpoints[name] = &api.InstanceCheckpoint{
+					Name:  name,
+					Disks: map[string]api.InstanceCheckpointDisk{},
+				}
+			}
+
+			checkpoints[name].Disks[devName] = api.InstanceCheckpointDisk{
+				DirtyBytes:  bitmap.Count,
+				Granularity: bitmap.Granularity,
+			}
+		}
+	}
+
+	result := make([]api.InstanceCheckpoint, 0, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		result = append(result, *checkpoint)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result, nil
+}
+
+// Checkpoint returns a single checkpoint of the running VM.
+func (d *qemu) Checkpoint(name string) (*api.InstanceCheckpoint, error) {
+	checkpoints, err := d.Checkpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, checkpoint := range checkpoints {
+		if checkpoint.Name == name {
+			return &checkpoint, nil
+		}
+	}
+
+	return nil, api.StatusErrorf(http.StatusNotFound, "Checkpoint %q not found", name)
+}
+
+// CheckpointCreate starts tracking the blocks changed on the specified disks (or all writable disks if none are
+// specified) from now on. The dirty bitmaps are added atomically so that all the disks share the same point in time.
+// The bitmaps are persistent and are kept across restarts of the VM.
+func (d *qemu) CheckpointCreate(req api.InstanceCheckpointsPost) error {
+	if !d.checkpointsEnabled() {
+		return api.StatusErrorf(http.StatusBadRequest, `Checkpoints require "checkpoints.enabled" to be set`)
+	}
+
+	monitor, err := d.checkpointMonitor()
+	if err != nil {
+		return err
+	}
+
+	diskNodes, err := d.checkpointDiskNodes(monitor)
+	if err != nil {
+		return err
+	}
+
+	bitmapName := qemuCheckpointBitmapPrefix + req.Name
+
+	disks := req.Disks
+	if len(disks) == 0 {
+		for devName := range diskNodes {
+			disks = append(disks, devName)
+		}
+	}
+
+	if len(disks) == 0 {
+		return api.StatusErrorf(http.StatusBadRequest, "Instance has no writable disks to track")
+	}
+
+	nodeNames := make([]string, 0, len(disks))
+	for _, devName := range disks {
+		node, ok := diskNodes[devName]
+		if !ok {
+			return api.StatusErrorf(http.StatusBadRequest, "Disk %q isn't a writable disk of the running instance", devName)
+		}
+
+		// The disks only get their checkpoint metadata image when attached with checkpoints enabled.
+		if node.Driver != "qcow2" {
+			return api.StatusErrorf(http.StatusBadRequest, "Disk %q was attached before checkpoints were enabled, restart the instance first", devName)
+		}
+
+		if slices.ContainsFunc(node.DirtyBitmaps, func(bitmap qmp.DirtyBitmap) bool { return bitmap.Name == bitmapName }) {
+			return api.StatusErrorf(http.StatusConflict, "Checkpoint %q already exists", req.Name)
+		}
+
+		nodeNames = append(nodeNames, node.NodeName)
+	}
+
+	err = monitor.BlockDirtyBitmapAdd(nodeNames, bitmapName, true)
+	if err != nil {
+		return fmt.Errorf("Failed adding dirty bitmaps: %w", err)
+	}
+
+	d.logger.Debug("Created checkpoint", logger.Ctx{"checkpoint": req.Name, "disks": disks})
+
+	return nil
+}
+
+// CheckpointDelete stops tracking changes for the checkpoint and releases its dirty bitmaps.
+func (d *qemu) CheckpointDelete(name string) error {
+	monitor, err := d.checkpointMonitor()
+	if err != nil {
+		return err
+	}
+
+	diskNodes, err := d.checkpointDiskNodes(monitor)
+	if err != nil {
+		return err
+	}
+
+	bitmapName := qemuCheckpointBitmapPrefix + name
+
+	found := false
+	for devName, node := range diskNodes {
+		if !slices.ContainsFunc(node.DirtyBitmaps, func(bitmap qmp.DirtyBitmap) bool { return bitmap.Name == bitmapName }) {
+			continue
+		}
+
+		found = true
+
+		err = monitor.BlockDirtyBitmapRemove(node.NodeName, bitmapName)
+		if err != nil {
+			return fmt.Errorf("Failed removing dirty bitmap from disk %q: %w", devName, err)
+		}
+	}
+
+	if !found {
+		return api.StatusErrorf(http.StatusNotFound, "Checkpoint %q not found", name)
+	}
+
+	d.logger.Debug("Deleted checkpoint", logger.Ctx{"checkpoint": name})
+
+	return nil
+}
+
+// CheckpointExport exports a disk read-only over NBD along with the checkpoint dirty bitmap.
+// The export is a point in time view of the disk, kept consistent while the guest writes to it by copying the
+// overwritten blocks to a temporary fleecing image in the instance directory. The dirty bitmap is frozen at the same
+// point in time. The export is named after the disk device and the changed blocks are exposed to NBD clients through
+// the "qemu:dirty-bitmap:lxd_checkpoint_<name>" metadata context. Closing the returned connection ends the export.
+func (d *qemu) CheckpointExport(name string, diskName string) (io.ReadWriteCloser, error) {
+	monitor, err := d.checkpointMonitor()
+	if err != nil {
+		return nil, err
+	}
+
+	diskNodes, err := d.checkpointDiskNodes(monitor)
+	if err != nil {
+		return nil, err
+	}
+
+	node, ok := diskNodes[diskName]
+	if !ok {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Disk %q isn't a writable disk of the running instance", diskName)
+	}
+
+	bitmapName := qemuCheckpointBitmapPrefix + name
+	bitmapIndex := slices.IndexFunc(node.DirtyBitmaps, func(bitmap qmp.DirtyBitmap) bool { return bitmap.Name == bitmapName })
+	if bitmapIndex < 0 {
+		return nil, api.StatusErrorf(http.StatusNotFound, "Checkpoint %q doesn't track disk %q", name, diskName)
+	}
+
+	release, err := qemuNBDServerReserve(d.id, "checkpoint export")
+	if err != nil {
+		return nil, err
+	}
+
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	reverter.Add(release)
+
+	// Create the fleecing image in the instance directory, same as the migration snapshot.
+	fleecingFile := filepath.Join(d.Path(), "checkpoint_fleecing.qcow2")
+
+	err = os.Remove(fleecingFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	// Always remove the fleecing file so that if qemu-img fails the partially written file is removed.
+	defer func() { _ = os.Remove(fleecingFile) }()
+
+	_, err = shared.RunCommand(d.state.ShutdownCtx, "qemu-img", "create", "-f", "qcow2", fleecingFile, strconv.FormatInt(node.Image.VirtualSize, 10))
+	if err != nil {
+		return nil, fmt.Errorf("Failed creating checkpoint fleecing image %q: %w", fleecingFile, err)
+	}
+
+	f, err := os.OpenFile(fleecingFile, unix.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Failed opening checkpoint fleecing image %q: %w", fleecingFile, err)
+	}
+
+	defer func() { _ = f.Close() }()
+
+	// The fleecing image is only referenced by QEMU from now on.
+	err = os.Remove(fleecingFile)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := monitor.SendFileWithFDSet(qemuCheckpointFleecingNodeName, f, false)
+	if err != nil {
+		return nil, fmt.Errorf("Failed sending file descriptor of %q: %w", fleecingFile, err)
+	}
+
+	reverter.Add(func() { _ = monitor.RemoveFDFromFDSet(qemuCheckpointFleecingNodeName) })
+
+	// Unchanged blocks are read from the disk through the backing node.
+	err = monitor.AddBlockDevice(map[string]any{
+		"driver":    "qcow2",
+		"node-name": qemuCheckpointFleecingNodeName,
+		"read-only": false,
+		"backing":   node.NodeName,
+		"file": map[string]any{
+			"driver":   "file",
+			"filename": fmt.Sprintf("/dev/fdset/%d", info.ID),
+		},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed adding checkpoint fleecing block device: %w", err)
+	}
+
+	reverter.Add(func() { _ = monitor.RemoveBlockDevice(qemuCheckpointFleecingNodeName) })
+
+	err = monitor.BlockDevBackupFleecing(node.NodeName, qemuCheckpointFleecingNodeName, bitmapName, node.DirtyBitmaps[bitmapIndex].Granularity)
+	if err != nil {
+		return nil, fmt.Errorf("Failed starting checkpoint fleecing of disk %q: %w", diskName, err)
+	}
+
+	reverter.Add(func() {
+		_ = monitor.BlockJobCancel(qemuCheckpointFleecingNodeName)
+		_ = monitor.BlockJobWait(qemuCheckpointFleecingNodeName)
+	})
+
+	nbdConn, err := monitor.NBDServerStart()
+	if err != nil {
+		return nil, fmt.Errorf("Failed starting NBD server: %w", err)
+	}
+
+	reverter.Add(func() {
+		_ = nbdConn.Close()
+		_ = monitor.NBDServerStop()
+	})
+
+	err = monitor.NBDBlockExportAddBitmap(diskName, qemuCheckpointFleecingNodeName, bitmapName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed adding disk %q to NBD server: %w", diskName, err)
+	}
+
+	reverter.Add(func() { _ = monitor.BlockExportDel(diskName) })
+
+	cleanup := reverter.Clone().Fail
+	reverter.Success()
+
+	d.logger.Debug("Started checkpoint export", logger.Ctx{"checkpoint": name, "disk": diskName})
+
+	return &qemuNBDExportConn{Conn: nbdConn, cleanup: cleanup}, nil
+}
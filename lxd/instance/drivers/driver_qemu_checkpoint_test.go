@@ -0,0 +1,46 @@
+package drivers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+func TestQemuNBDServerReserve(t *testing.T) {
+	release, err := qemuNBDServerReserve(1, "checkpoint export")
+	if err != nil {
+		t.Fatalf("Unexpected error reserving NBD server: %v", err)
+	}
+
+	// A second user of the same instance conflicts.
+	_, err = qemuNBDServerReserve(1, "migration")
+	if !api.StatusErrorCheck(err, http.StatusConflict) {
+		t.Fatalf("Expected conflict reserving NBD server in use, got: %v", err)
+	}
+
+	// Other instances are independent.
+	releaseOther, err := qemuNBDServerReserve(2, "migration")
+	if err != nil {
+		t.Fatalf("Unexpected error reserving NBD server of another instance: %v", err)
+	}
+
+	releaseOther()
+
+	// Releasing twice must not release a later reservation.
+	release()
+
+	releaseAgain, err := qemuNBDServerReserve(1, "migration")
+	if err != nil {
+		t.Fatalf("Unexpected error reserving released NBD server: %v", err)
+	}
+
+	release()
+
+	_, err = qemuNBDServerReserve(1, "checkpoint export")
+	if !api.StatusErrorCheck(err, http.StatusConflict) {
+		t.Fatalf("Expected reservation to survive a stale release, got: %v", err)
+	}
+
+	releaseAgain()
+}
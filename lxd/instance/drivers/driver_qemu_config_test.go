@@ -98,15 +98,27 @@ func TestQemuConfigTemplates(t *testing.T) {
 			opts     qemuMemoryOpts
 			expected string
 		}{{
-			qemuMemoryOpts{4096},
+			qemuMemoryOpts{4096, 0, 0},
 			`# Memory
 			[memory]
 			size = "4096M"`,
 		}, {
-			qemuMemoryOpts{8192},
+			qemuMemoryOpts{8192, 0, 0},
 			`# Memory
 			[memory]
 			size = "8192M"`,
+		}, {
+			qemuMemoryOpts{2048, 16384, 32},
+			`# Memory
+			[memory]
+			size = "2048M"
+			slots = "32"
+			maxmem = "16384M"`,
+		}, {
+			qemuMemoryOpts{4096, 4096, 32},
+			`# Memory
+			[memory]
+			size = "4096M"`,
 		}}
 		for _, tc := range testCases {
 			runTest(tc.expected, qemuMemory(&tc.opts))
@@ -223,10 +235,10 @@ func TestQemuConfigTemplates(t *testing.T) {
 
 	t.Run("qemu_balloon", func(t *testing.T) {
 		testCases := []struct {
-			opts     qemuDevOpts
+			opts     qemuBalloonOpts
 			expected string
 		}{{
-			qemuDevOpts{"pcie", "qemu_pcie0", "00.0", true},
+			qemuBalloonOpts{qemuDevOpts{"pcie", "qemu_pcie0", "00.0", true}, false},
 			`# Balloon driver
 			[device "qemu_balloon"]
 			driver = "virtio-balloon-pci"
@@ -235,11 +247,21 @@ func TestQemuConfigTemplates(t *testing.T) {
 			multifunction = "on"
 			`,
 		}, {
-			qemuDevOpts{"ccw", "devBus", "busAddr", false},
+			qemuBalloonOpts{qemuDevOpts{"ccw", "devBus", "busAddr", false}, false},
 			`# Balloon driver
 			[device "qemu_balloon"]
 			driver = "virtio-balloon-ccw"
 			`,
+		}, {
+			qemuBalloonOpts{qemuDevOpts{"pcie", "qemu_pcie0", "00.0", true}, true},
+			`# Balloon driver
+			[device "qemu_balloon"]
+			driver = "virtio-balloon-pci"
+			bus = "qemu_pcie0"
+			addr = "00.0"
+			multifunction = "on"
+			free-page-reporting = "on"
+			`,
 		}}
 		for _, tc := range testCases {
 			runTest(tc.expected, qemuBalloon(&tc.opts))
@@ -671,6 +693,93 @@ func TestQemuConfigTemplates(t *testing.T) {
 			sockets = "1"
 			cores = "4"
 			threads = "1"`,
+		}, {
+			qemuCPUOpts{
+				architecture: "x86_64",
+				cpuCount:     4,
+				cpuSockets:   2,
+				cpuCores:     2,
+				cpuThreads:   1,
+				cpuNumaMapping: []qemuNumaEntry{
+					{node: 0, socket: 0, core: 0, thread: 0},
+					{node: 0, socket: 0, core: 1, thread: 0},
+					{node: 1, socket: 1, core: 0, thread: 0},
+					{node: 1, socket: 1, core: 1, thread: 0},
+				},
+				numaNodes: []qemuNumaNode{
+					{memory: 1024, hostNode: -1, distances: []uint64{10, 21}},
+					{memory: 3072, hostNode: 1, distances: []uint64{21, 10}},
+				},
+				qemuMemObjectFormat: "indexed",
+			},
+			`# CPU
+			[smp-opts]
+			cpus = "4"
+			sockets = "2"
+			cores = "2"
+			threads = "1"
+
+			[object "mem0"]
+			qom-type = "memory-backend-memfd"
+			size = "1024M"
+			share = "on"
+
+			[numa]
+			type = "node"
+			nodeid = "0"
+			memdev = "mem0"
+
+			[object "mem1"]
+			qom-type = "memory-backend-memfd"
+			size = "3072M"
+			share = "on"
+			policy = "bind"
+			host-nodes.0 = "1"
+
+			[numa]
+			type = "node"
+			nodeid = "1"
+			memdev = "mem1"
+
+			[numa]
+			type = "dist"
+			src = "0"
+			dst = "1"
+			val = "21"
+
+			[numa]
+			type = "dist"
+			src = "1"
+			dst = "0"
+			val = "21"
+
+			[numa]
+			type = "cpu"
+			node-id = "0"
+			socket-id = "0"
+			core-id = "0"
+			thread-id = "0"
+
+			[numa]
+			type = "cpu"
+			node-id = "0"
+			socket-id = "0"
+			core-id = "1"
+			thread-id = "0"
+
+			[numa]
+			type = "cpu"
+			node-id = "1"
+			socket-id = "1"
+			core-id = "0"
+			thread-id = "0"
+
+			[numa]
+			type = "cpu"
+			node-id = "1"
+			socket-id = "1"
+			core-id = "1"
+			thread-id = "0"`,
 		}}
 		for _, tc := range testCases {
 			runTest(tc.expected, qemuCPU(&tc.opts, true))
@@ -1081,6 +1190,68 @@ func TestQemuConfigTemplates(t *testing.T) {
 		}
 	})
 
+	t.Run("qemu_serial_port", func(t *testing.T) {
+		testCases := []struct {
+			opts     qemuSerialPortOpts
+			expected string
+		}{{
+			qemuSerialPortOpts{
+				devName:  "mgmt",
+				target:   "virtio",
+				portName: "com.example.mgmt",
+				backend:  "unix",
+				fd:       5,
+			},
+			`# Serial port ("mgmt" device)
+			[chardev "qemu_serialport-chardev_mgmt"]
+			backend = "socket"
+			fd = "5"
+			server = "on"
+			wait = "off"
+
+			[device "dev-lxd_mgmt"]
+			driver = "virtserialport"
+			bus = "dev-qemu_serial.0"
+			name = "com.example.mgmt"
+			chardev = "qemu_serialport-chardev_mgmt"`,
+		}, {
+			qemuSerialPortOpts{
+				devName: "com2",
+				target:  "isa",
+				backend: "file",
+				path:    "/var/log/lxd/vm1/serial.com2.log",
+			},
+			`# Serial port ("com2" device)
+			[chardev "qemu_serialport-chardev_com2"]
+			backend = "file"
+			path = "/var/log/lxd/vm1/serial.com2.log"
+			append = "on"
+
+			[device "dev-lxd_com2"]
+			driver = "isa-serial"
+			chardev = "qemu_serialport-chardev_com2"`,
+		}, {
+			qemuSerialPortOpts{
+				devName:  "console2",
+				target:   "virtio",
+				portName: "console2",
+				backend:  "pty",
+			},
+			`# Serial port ("console2" device)
+			[chardev "qemu_serialport-chardev_console2"]
+			backend = "pty"
+
+			[device "dev-lxd_console2"]
+			driver = "virtserialport"
+			bus = "dev-qemu_serial.0"
+			name = "console2"
+			chardev = "qemu_serialport-chardev_console2"`,
+		}}
+		for _, tc := range testCases {
+			runTest(tc.expected, qemuSerialPort(&tc.opts))
+		}
+	})
+
 	t.Run("qemu_raw_cfg_override", func(t *testing.T) {
 		cfg := []cfgSection{{
 			name: "global",
@@ -16,13 +16,7 @@ import (
 	"github.com/canonical/lxd/shared/units"
 )
 
-func (d *qemu) getQemuMetrics() (*metrics.MetricSet, error) {
-	// Connect to the monitor.
-	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
-	if err != nil {
-		return nil, err
-	}
-
+func (d *qemu) getQemuMetrics(monitor *qmp.Monitor) (*metrics.MetricSet, error) {
 	out := metrics.Metrics{}
 
 	cpuStats, err := d.getQemuCPUMetrics(monitor)
@@ -0,0 +1,241 @@
+package drivers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/canonical/lxd/lxd/instance/drivers/qmp"
+	storagePools "github.com/canonical/lxd/lxd/storage"
+	"github.com/canonical/lxd/lxd/storage/filesystem"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/revert"
+)
+
+// qemuDeviceMirrorNamePrefix is the prefix of the block node backing a disk once it has been mirrored to a new volume.
+// Block nodes can't be renamed so disks alternate between this prefix and qemuDeviceNamePrefix on each mirror.
+// The prefix doesn't start with qemuDeviceNamePrefix so that it can't clash with the node of another disk.
+const qemuDeviceMirrorNamePrefix = "lxdmirror_"
+
+// qemuBlockNodeNames returns the name of the block node currently backing the disk device and the name of the node to
+// mirror it to.
+func qemuBlockNodeNames(nodes map[string]qmp.BlockNode, deviceName string) (string, string) {
+	baseName := qemuDeviceNameOrID(qemuDeviceNamePrefix, deviceName, "", qemuDeviceNameMaxLength)
+	mirrorName := qemuDeviceNameOrID(qemuDeviceMirrorNamePrefix, deviceName, "", qemuDeviceNameMaxLength)
+
+	_, found := nodes[mirrorName]
+	if found {
+		return mirrorName, baseName
+	}
+
+	return baseName, mirrorName
+}
+
+// blockNodeName returns the name of the block node currently backing the disk device.
+func (d *qemu) blockNodeName(monitor *qmp.Monitor, deviceName string) (string, error) {
+	nodes, err := monitor.QueryNamedBlockNodes()
+	if err != nil {
+		return "", fmt.Errorf("Failed querying block nodes: %w", err)
+	}
+
+	nodeName, _ := qemuBlockNodeNames(nodes, deviceName)
+
+	return nodeName, nil
+}
+
+// MirrorDisk copies a disk of the running VM to the block device or file at targetPath and switches the VM over to
+// it without interrupting the guest. The progress function is called with the number of bytes copied so far and the
+// total number of bytes to copy. On failure the VM keeps using its original disk.
+func (d *qemu) MirrorDisk(deviceName string, targetPath string, progress func(current int64, total int64)) error {
+	if !d.IsRunning() {
+		return api.StatusErrorf(http.StatusBadRequest, "Instance must be running to mirror its disks")
+	}
+
+	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
+	if err != nil {
+		return err
+	}
+
+	nodes, err := monitor.QueryNamedBlockNodes()
+	if err != nil {
+		return fmt.Errorf("Failed querying block nodes: %w", err)
+	}
+
+	srcNodeName, targetNodeName := qemuBlockNodeNames(nodes, deviceName)
+
+	srcNode, ok := nodes[srcNodeName]
+	if !ok {
+		return fmt.Errorf("Disk device %q isn't attached to the instance", deviceName)
+	}
+
+	if srcNode.ReadOnly {
+		return fmt.Errorf("Read-only disk device %q can't be mirrored", deviceName)
+	}
+
+	targetInfo, err := os.Stat(targetPath)
+	if err != nil {
+		return fmt.Errorf("Invalid target path %q: %w", targetPath, err)
+	}
+
+	// Keep the cache mode of the source disk unless the target doesn't support direct I/O.
+	// Same as when starting the VM, avoid direct I/O for image files on ZFS or BTRFS.
+	directCache := srcNode.Cache.Direct
+	driver := "host_device"
+	if !shared.IsBlockdev(targetInfo.Mode()) {
+		driver = "file"
+
+		fsType, err := filesystem.Detect(targetPath)
+		if err != nil {
+			return fmt.Errorf("Failed detecting filesystem type of %q: %w", targetPath, err)
+		}
+
+		if fsType == "zfs" || fsType == "btrfs" {
+			directCache = false
+		}
+	}
+
+	aioMode := "threads"
+	permissions := unix.O_RDWR
+	if directCache {
+		aioMode = "native"
+		permissions |= unix.O_DIRECT
+	}
+
+	f, err := os.OpenFile(targetPath, permissions, 0)
+	if err != nil {
+		return fmt.Errorf("Failed opening target of disk device %q: %w", deviceName, err)
+	}
+
+	defer func() { _ = f.Close() }()
+
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	info, err := monitor.SendFileWithFDSet(targetNodeName, f, false)
+	if err != nil {
+		return fmt.Errorf("Failed sending file descriptor of %q for disk device %q: %w", targetPath, deviceName, err)
+	}
+
+	reverter.Add(func() { _ = monitor.RemoveFDFromFDSet(targetNodeName) })
+
+	blockDev := map[string]any{
+		"aio": aioMode,
+		"cache": map[string]any{
+			"direct":   directCache,
+			"no-flush": srcNode.Cache.NoFlush,
+		},
+		"discard":   "unmap",
+		"driver":    driver,
+		"filename":  fmt.Sprintf("/dev/fdset/%d", info.ID),
+		"locking":   "off",
+		"node-name": targetNodeName,
+		"read-only": false,
+	}
+
+	err = monitor.AddBlockDevice(blockDev, nil)
+	if err != nil {
+		return fmt.Errorf("Failed adding target block device for disk device %q: %w", deviceName, err)
+	}
+
+	reverter.Add(func() { _ = monitor.RemoveBlockDevice(targetNodeName) })
+
+	// Cancelling the job leaves the disk on its original node, wait for it to go away so the target can be removed.
+	cancelJob := func() {
+		_ = monitor.BlockJobCancel(srcNodeName)
+		_ = monitor.BlockJobWait(srcNodeName)
+	}
+
+	d.logger.Info("Mirroring disk", logger.Ctx{"device": deviceName, "target": targetPath})
+
+	err = monitor.BlockDevMirrorFull(srcNodeName, targetNodeName, progress)
+	if err != nil {
+		cancelJob()
+		return fmt.Errorf("Failed mirroring disk device %q: %w", deviceName, err)
+	}
+
+	err = monitor.BlockJobComplete(srcNodeName)
+	if err != nil {
+		cancelJob()
+		return fmt.Errorf("Failed completing mirror of disk device %q: %w", deviceName, err)
+	}
+
+	err = monitor.BlockJobWait(srcNodeName)
+	if err != nil {
+		return fmt.Errorf("Failed waiting for mirror of disk device %q: %w", deviceName, err)
+	}
+
+	// The job doesn't report whether the switch over succeeded once it has concluded.
+	// The original node can only be removed once the disk no longer uses it so treat a failure as the switch over
+	// not having happened.
+	err = monitor.RemoveBlockDevice(srcNodeName)
+	if err != nil {
+		return fmt.Errorf("Failed switching disk device %q to %q: %w", deviceName, targetPath, err)
+	}
+
+	reverter.Success()
+
+	err = monitor.RemoveFDFromFDSet(srcNodeName)
+	if err != nil {
+		d.logger.Warn("Failed removing file descriptor of mirrored disk", logger.Ctx{"device": deviceName, "err": err})
+	}
+
+	// The mirror target is attached without a checkpoint metadata image so the checkpoints of the disk are lost.
+	if srcNode.Driver == "qcow2" {
+		err = d.checkpointLayerRemove(monitor, deviceName)
+		if err != nil {
+			d.logger.Warn("Failed removing checkpoint metadata of mirrored disk", logger.Ctx{"device": deviceName, "err": err})
+		}
+	}
+
+	d.logger.Info("Mirrored disk", logger.Ctx{"device": deviceName, "target": targetPath})
+
+	return nil
+}
+
+// cleanupStorageMoveSource removes the root volume left on the source pool after the root disk of the VM was moved
+// to another pool while running. If keepNVRAM is true, the NVRAM is carried over to the (mounted) new root volume
+// first as QEMU kept using the copy on the source volume.
+func (d *qemu) cleanupStorageMoveSource(keepNVRAM bool) error {
+	srcPoolName := d.localConfig["volatile.storage_move.source_pool"]
+	if srcPoolName == "" {
+		return nil
+	}
+
+	srcPool, err := storagePools.LoadByName(d.state, srcPoolName)
+	if err != nil {
+		return fmt.Errorf("Failed loading storage pool %q: %w", srcPoolName, err)
+	}
+
+	var preDelete func(mountPath string) error
+	if keepNVRAM {
+		preDelete = func(mountPath string) error {
+			// The qemu.nvram symlink points at the firmware specific vars file next to it.
+			srcNVRAMPath := filepath.Join(mountPath, "qemu.nvram")
+			vmFirmwareName, err := os.Readlink(srcNVRAMPath)
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return nil
+				}
+
+				return err
+			}
+
+			return shared.FileCopy(filepath.Join(mountPath, vmFirmwareName), filepath.Join(d.Path(), vmFirmwareName))
+		}
+	}
+
+	err = srcPool.DeleteInstanceLiveMoveSource(d, preDelete, nil)
+	if err != nil {
+		return fmt.Errorf("Failed removing root volume from storage pool %q: %w", srcPoolName, err)
+	}
+
+	d.logger.Info("Removed root volume left over from live storage move", logger.Ctx{"pool": srcPoolName})
+
+	return d.VolatileSet(map[string]string{"volatile.storage_move.source_pool": ""})
+}
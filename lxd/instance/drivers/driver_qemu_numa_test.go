@@ -0,0 +1,231 @@
+package drivers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/canonical/lxd/shared/osarch"
+)
+
+func TestQemuGuestNumaNodes(t *testing.T) {
+	tests := []struct {
+		name         string
+		architecture int
+		config       map[string]string
+		cpuCount     int
+		memSizeMB    int64
+		wantNodes    []qemuNumaNode
+		wantMapping  []qemuNumaEntry
+		wantErr      bool
+	}{
+		{
+			name:      "NotConfigured",
+			config:    map[string]string{},
+			cpuCount:  2,
+			memSizeMB: 1024,
+		},
+		{
+			name: "SingleNode",
+			config: map[string]string{
+				"limits.numa.nodes.0.cpus":   "0-1",
+				"limits.numa.nodes.0.memory": "1GiB",
+			},
+			cpuCount:  2,
+			memSizeMB: 1024,
+			wantNodes: []qemuNumaNode{
+				{memory: 1024, hostNode: -1},
+			},
+			wantMapping: []qemuNumaEntry{
+				{node: 0, socket: 0, core: 0},
+				{node: 0, socket: 0, core: 1},
+			},
+		},
+		{
+			name: "EvenSplit",
+			config: map[string]string{
+				"limits.numa.nodes.0.cpus":   "0-1",
+				"limits.numa.nodes.0.memory": "1GiB",
+				"limits.numa.nodes.1.cpus":   "2-3",
+				"limits.numa.nodes.1.memory": "1GiB",
+			},
+			cpuCount:  4,
+			memSizeMB: 2048,
+			wantNodes: []qemuNumaNode{
+				{memory: 1024, hostNode: -1},
+				{memory: 1024, hostNode: -1},
+			},
+			wantMapping: []qemuNumaEntry{
+				{node: 0, socket: 0, core: 0},
+				{node: 0, socket: 0, core: 1},
+				{node: 1, socket: 1, core: 0},
+				{node: 1, socket: 1, core: 1},
+			},
+		},
+		{
+			name: "InterleavedCPUs",
+			config: map[string]string{
+				"limits.numa.nodes.0.cpus":   "0,2",
+				"limits.numa.nodes.0.memory": "512MiB",
+				"limits.numa.nodes.1.cpus":   "1,3",
+				"limits.numa.nodes.1.memory": "512MiB",
+			},
+			cpuCount:  4,
+			memSizeMB: 1024,
+			wantNodes: []qemuNumaNode{
+				{memory: 512, hostNode: -1},
+				{memory: 512, hostNode: -1},
+			},
+			wantMapping: []qemuNumaEntry{
+				{node: 0, socket: 0, core: 0},
+				{node: 1, socket: 1, core: 0},
+				{node: 0, socket: 0, core: 1},
+				{node: 1, socket: 1, core: 1},
+			},
+		},
+		{
+			name: "UnevenMemorySplit",
+			config: map[string]string{
+				"limits.numa.nodes.0.cpus":      "0",
+				"limits.numa.nodes.0.memory":    "1536MiB",
+				"limits.numa.nodes.0.host_node": "1",
+				"limits.numa.nodes.1.cpus":      "1",
+				"limits.numa.nodes.1.memory":    "512MiB",
+				"limits.numa.nodes.0.distances": "10,20",
+				"limits.numa.nodes.1.distances": "20,10",
+			},
+			cpuCount:  2,
+			memSizeMB: 2048,
+			wantNodes: []qemuNumaNode{
+				{memory: 1536, hostNode: 1, distances: []uint64{10, 20}},
+				{memory: 512, hostNode: -1, distances: []uint64{20, 10}},
+			},
+			wantMapping: []qemuNumaEntry{
+				{node: 0, socket: 0, core: 0},
+				{node: 1, socket: 1, core: 0},
+			},
+		},
+		{
+			name: "UnevenCPUSplit",
+			config: map[string]string{
+				"limits.numa.nodes.0.cpus":   "0-2",
+				"limits.numa.nodes.0.memory": "1GiB",
+				"limits.numa.nodes.1.cpus":   "3",
+				"limits.numa.nodes.1.memory": "1GiB",
+			},
+			cpuCount:  4,
+			memSizeMB: 2048,
+			wantErr:   true,
+		},
+		{
+			name: "MemoryMismatch",
+			config: map[string]string{
+				"limits.numa.nodes.0.cpus":   "0",
+				"limits.numa.nodes.0.memory": "1GiB",
+				"limits.numa.nodes.1.cpus":   "1",
+				"limits.numa.nodes.1.memory": "512MiB",
+			},
+			cpuCount:  2,
+			memSizeMB: 2048,
+			wantErr:   true,
+		},
+		{
+			name: "UnassignedCPU",
+			config: map[string]string{
+				"limits.numa.nodes.0.cpus":   "0-1",
+				"limits.numa.nodes.0.memory": "1GiB",
+			},
+			cpuCount:  4,
+			memSizeMB: 1024,
+			wantErr:   true,
+		},
+		{
+			name: "CPUBeyondLimit",
+			config: map[string]string{
+				"limits.numa.nodes.0.cpus":   "0-3",
+				"limits.numa.nodes.0.memory": "1GiB",
+			},
+			cpuCount:  2,
+			memSizeMB: 1024,
+			wantErr:   true,
+		},
+		{
+			name: "CPUInTwoNodes",
+			config: map[string]string{
+				"limits.numa.nodes.0.cpus":   "0-1",
+				"limits.numa.nodes.0.memory": "512MiB",
+				"limits.numa.nodes.1.cpus":   "1-2",
+				"limits.numa.nodes.1.memory": "512MiB",
+			},
+			cpuCount:  3,
+			memSizeMB: 1024,
+			wantErr:   true,
+		},
+		{
+			name: "NonContiguousNodes",
+			config: map[string]string{
+				"limits.numa.nodes.0.cpus":   "0",
+				"limits.numa.nodes.0.memory": "512MiB",
+				"limits.numa.nodes.2.cpus":   "1",
+				"limits.numa.nodes.2.memory": "512MiB",
+			},
+			cpuCount:  2,
+			memSizeMB: 1024,
+			wantErr:   true,
+		},
+		{
+			name: "NonCanonicalNodeID",
+			config: map[string]string{
+				"limits.numa.nodes.0.cpus":    "0",
+				"limits.numa.nodes.0.memory":  "512MiB",
+				"limits.numa.nodes.1.cpus":    "1",
+				"limits.numa.nodes.01.memory": "512MiB",
+			},
+			cpuCount:  2,
+			memSizeMB: 1024,
+			wantErr:   true,
+		},
+		{
+			name:         "UnsupportedArchitecture",
+			architecture: osarch.ARCH_64BIT_ARMV8_LITTLE_ENDIAN,
+			config: map[string]string{
+				"limits.numa.nodes.0.cpus":   "0",
+				"limits.numa.nodes.0.memory": "1GiB",
+			},
+			cpuCount:  1,
+			memSizeMB: 1024,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			architecture := tt.architecture
+			if architecture == 0 {
+				architecture = osarch.ARCH_64BIT_INTEL_X86
+			}
+
+			d := &qemu{common: common{architecture: architecture, expandedConfig: tt.config}}
+
+			nodes, mapping, err := d.guestNumaNodes(tt.cpuCount, tt.memSizeMB)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(nodes, tt.wantNodes) {
+				t.Errorf("Expected nodes %+v, got %+v", tt.wantNodes, nodes)
+			}
+
+			if !reflect.DeepEqual(mapping, tt.wantMapping) {
+				t.Errorf("Expected mapping %+v, got %+v", tt.wantMapping, mapping)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package drivers
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	deviceConfig "github.com/canonical/lxd/lxd/device/config"
+	"github.com/canonical/lxd/lxd/instance/drivers/qmp"
+	"github.com/canonical/lxd/shared/revert"
+)
+
+// qemuSerialPortConfig parses the run config of a serial device.
+func qemuSerialPortConfig(serialConfig []deviceConfig.RunConfigItem) qemuSerialPortOpts {
+	opts := qemuSerialPortOpts{}
+
+	for _, item := range serialConfig {
+		switch item.Key {
+		case "devName":
+			opts.devName = item.Value
+		case "target":
+			opts.target = item.Value
+		case "portName":
+			opts.portName = item.Value
+		case "backend":
+			opts.backend = item.Value
+		case "path":
+			opts.path = item.Value
+		}
+	}
+
+	return opts
+}
+
+// qemuSerialListen creates the listening unix socket of a serial port and returns its file.
+// The socket is handed over to QEMU which then accepts the connections itself.
+func qemuSerialListen(path string) (*os.File, error) {
+	// Trickery to handle paths > 108 chars.
+	socketFileDir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = socketFileDir.Close() }()
+
+	socketFile := fmt.Sprintf("/proc/self/fd/%d/%s", socketFileDir.Fd(), filepath.Base(path))
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketFile, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("Failed creating unix listener for serial port: %w", err)
+	}
+
+	// Only close our copy of the listener, the socket remains in use by QEMU.
+	listener.SetUnlinkOnClose(false)
+	defer func() { _ = listener.Close() }()
+
+	return listener.File()
+}
+
+// addSerialDeviceConfig adds the qemu config required for adding a serial port.
+func (d *qemu) addSerialDeviceConfig(cfg *[]cfgSection, serialConfig []deviceConfig.RunConfigItem, fdFiles *[]*os.File) (monitorHook, error) {
+	opts := qemuSerialPortConfig(serialConfig)
+
+	if opts.backend == "unix" {
+		socketFile, err := qemuSerialListen(opts.path)
+		if err != nil {
+			return nil, err
+		}
+
+		opts.fd = d.addFileDescriptor(fdFiles, socketFile)
+	}
+
+	*cfg = append(*cfg, qemuSerialPort(&opts)...)
+
+	if opts.backend != "pty" {
+		return nil, nil
+	}
+
+	// The pseudo terminal is only allocated once QEMU has started.
+	monHook := func(m *qmp.Monitor) error {
+		return d.serialRecordPTY(m, opts.devName)
+	}
+
+	return monHook, nil
+}
+
+// serialRecordPTY records the path of the pseudo terminal allocated by QEMU for a serial device.
+func (d *qemu) serialRecordPTY(m *qmp.Monitor, devName string) error {
+	chardevs, err := m.QueryCharDevices()
+	if err != nil {
+		return err
+	}
+
+	chardevID := qemuDeviceNameOrID(qemuSerialPortChardevPrefix, devName, "", qemuDeviceIDMaxLength)
+
+	chardev, ok := chardevs[chardevID]
+	if !ok {
+		return fmt.Errorf("Failed finding character device of serial device %q", devName)
+	}
+
+	ptyPath, found := strings.CutPrefix(chardev.Filename, "pty:")
+	if !found {
+		return fmt.Errorf("Unexpected pseudo terminal %q for serial device %q", chardev.Filename, devName)
+	}
+
+	return d.VolatileSet(map[string]string{"volatile." + devName + ".last_state.serial.path": ptyPath})
+}
+
+// deviceAttachSerial hotplugs a serial port into the running VM.
+func (d *qemu) deviceAttachSerial(serialConfig []deviceConfig.RunConfigItem) error {
+	opts := qemuSerialPortConfig(serialConfig)
+
+	if opts.target == "isa" {
+		return fmt.Errorf("ISA serial port of device %q cannot be hotplugged", opts.devName)
+	}
+
+	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
+	if err != nil {
+		return err
+	}
+
+	chardevID := qemuDeviceNameOrID(qemuSerialPortChardevPrefix, opts.devName, "", qemuDeviceIDMaxLength)
+	deviceID := qemuDeviceNameOrID(qemuDeviceIDPrefix, opts.devName, "", qemuDeviceIDMaxLength)
+
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	var backend map[string]any
+	switch opts.backend {
+	case "file":
+		backend = map[string]any{
+			"type": "file",
+			"data": map[string]any{
+				"out":    opts.path,
+				"append": true,
+			},
+		}
+
+	case "pty":
+		backend = map[string]any{
+			"type": "pty",
+			"data": map[string]any{},
+		}
+
+	default:
+		socketFile, err := qemuSerialListen(opts.path)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = socketFile.Close() }()
+
+		err = monitor.SendFile(chardevID, socketFile)
+		if err != nil {
+			return fmt.Errorf("Failed sending serial port file descriptor: %w", err)
+		}
+
+		reverter.Add(func() { _ = monitor.CloseFile(chardevID) })
+
+		backend = map[string]any{
+			"type": "socket",
+			"data": map[string]any{
+				"addr": map[string]any{
+					"type": "fd",
+					"data": map[string]any{
+						"str": chardevID,
+					},
+				},
+				"server": true,
+				"wait":   false,
+			},
+		}
+	}
+
+	err = monitor.AddCharDevice(map[string]any{
+		"id":      chardevID,
+		"backend": backend,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed adding the serial port character device: %w", err)
+	}
+
+	reverter.Add(func() { _ = monitor.RemoveCharDevice(chardevID) })
+
+	err = monitor.AddDevice(map[string]any{
+		"id":      deviceID,
+		"driver":  "virtserialport",
+		"bus":     "dev-qemu_serial.0",
+		"name":    opts.portName,
+		"chardev": chardevID,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed adding the serial port device: %w", err)
+	}
+
+	if opts.backend == "pty" {
+		err = d.serialRecordPTY(monitor, opts.devName)
+		if err != nil {
+			return err
+		}
+	}
+
+	reverter.Success()
+
+	return nil
+}
+
+// deviceDetachSerial removes a serial port from the running VM.
+func (d *qemu) deviceDetachSerial(devName string) error {
+	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
+	if err != nil {
+		return err
+	}
+
+	deviceID := qemuDeviceNameOrID(qemuDeviceIDPrefix, devName, "", qemuDeviceIDMaxLength)
+	chardevID := qemuDeviceNameOrID(qemuSerialPortChardevPrefix, devName, "", qemuDeviceIDMaxLength)
+
+	err = monitor.RemoveDevice(deviceID)
+	if err != nil {
+		return fmt.Errorf("Failed removing serial port device: %w", err)
+	}
+
+	err = monitor.RemoveCharDevice(chardevID)
+	if err != nil {
+		return fmt.Errorf("Failed removing serial port character device: %w", err)
+	}
+
+	return nil
+}
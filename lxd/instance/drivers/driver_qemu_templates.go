@@ -142,14 +142,24 @@ func qemuBase(opts *qemuBaseOpts) []cfgSection {
 }
 
 type qemuMemoryOpts struct {
-	memSizeMB int64
+	memSizeMB    int64
+	maxMemSizeMB int64
+	memSlots     int
 }
 
 func qemuMemory(opts *qemuMemoryOpts) []cfgSection {
+	entries := []cfgEntry{{key: "size", value: fmt.Sprintf("%dM", opts.memSizeMB)}}
+
+	// Reserve address space and slots for memory hotplug.
+	if opts.memSlots > 0 && opts.maxMemSizeMB > opts.memSizeMB {
+		entries = append(entries, cfgEntry{key: "slots", value: strconv.Itoa(opts.memSlots)})
+		entries = append(entries, cfgEntry{key: "maxmem", value: fmt.Sprintf("%dM", opts.maxMemSizeMB)})
+	}
+
 	return []cfgSection{{
 		name:    "memory",
 		comment: "Memory",
-		entries: []cfgEntry{{key: "size", value: fmt.Sprintf("%dM", opts.memSizeMB)}},
+		entries: entries,
 	}}
 }
 
@@ -304,17 +314,29 @@ func qemuSCSI(opts *qemuDevOpts) []cfgSection {
 	}}
 }
 
-func qemuBalloon(opts *qemuDevOpts) []cfgSection {
+type qemuBalloonOpts struct {
+	dev               qemuDevOpts
+	freePageReporting bool
+}
+
+func qemuBalloon(opts *qemuBalloonOpts) []cfgSection {
 	entriesOpts := qemuDevEntriesOpts{
-		dev:     *opts,
+		dev:     opts.dev,
 		pciName: "virtio-balloon-pci",
 		ccwName: "virtio-balloon-ccw",
 	}
 
+	entries := qemuDeviceEntries(&entriesOpts)
+
+	// Let the guest report its free pages so that they can be returned to the host.
+	if opts.freePageReporting {
+		entries = append(entries, cfgEntry{key: "free-page-reporting", value: "on"})
+	}
+
 	return []cfgSection{{
 		name:    `device "qemu_balloon"`,
 		comment: "Balloon driver",
-		entries: qemuDeviceEntries(&entriesOpts),
+		entries: entries,
 	}}
 }
 
@@ -450,6 +472,13 @@ type qemuNumaEntry struct {
 	thread uint64
 }
 
+// qemuNumaNode describes an explicitly configured guest NUMA node.
+type qemuNumaNode struct {
+	memory    int64    // Memory size in MiB.
+	hostNode  int64    // Host NUMA node the memory is bound to (-1 to not bind the memory).
+	distances []uint64 // Distances to all guest NUMA nodes (empty to use the defaults).
+}
+
 type qemuCPUOpts struct {
 	architecture        string
 	cpuCount            int
@@ -460,12 +489,13 @@ type qemuCPUOpts struct {
 	cpuNumaNodes        []uint64
 	cpuNumaMapping      []qemuNumaEntry
 	cpuNumaHostNodes    []uint64
+	numaNodes           []qemuNumaNode
 	hugepages           string
 	memory              int64
 	qemuMemObjectFormat string
 }
 
-func qemuCPUNumaHostNode(opts *qemuCPUOpts, index int) []cfgSection {
+func qemuCPUNumaHostNode(opts *qemuCPUOpts, index int, memory int64) []cfgSection {
 	entries := []cfgEntry{}
 
 	if opts.hugepages != "" {
@@ -479,7 +509,7 @@ func qemuCPUNumaHostNode(opts *qemuCPUOpts, index int) []cfgSection {
 		entries = append(entries, cfgEntry{key: "qom-type", value: "memory-backend-memfd"})
 	}
 
-	entries = append(entries, cfgEntry{key: "size", value: fmt.Sprintf("%dM", opts.memory)})
+	entries = append(entries, cfgEntry{key: "size", value: fmt.Sprintf("%dM", memory)})
 
 	return []cfgSection{{
 		name:    fmt.Sprintf("object \"mem%d\"", index),
@@ -540,16 +570,18 @@ func qemuCPU(opts *qemuCPUOpts, pinning bool) []cfgSection {
 
 	share := cfgEntry{key: "share", value: "on"}
 
-	if len(opts.cpuNumaHostNodes) == 0 {
+	if len(opts.numaNodes) > 0 {
+		sections = append(sections, qemuNumaNodes(opts)...)
+	} else if len(opts.cpuNumaHostNodes) == 0 {
 		// add one mem and one numa sections with index 0
-		numaHostNode := qemuCPUNumaHostNode(opts, 0)
+		numaHostNode := qemuCPUNumaHostNode(opts, 0, opts.memory)
 		// unconditionally append "share = "on" to the [object "mem0"] section
 		numaHostNode[0].entries = append(numaHostNode[0].entries, share)
 		return append(sections, numaHostNode...)
 	}
 
 	for index, element := range opts.cpuNumaHostNodes {
-		numaHostNode := qemuCPUNumaHostNode(opts, index)
+		numaHostNode := qemuCPUNumaHostNode(opts, index, opts.memory)
 
 		extraMemEntries := []cfgEntry{{key: "policy", value: "bind"}}
 
@@ -581,6 +613,47 @@ func qemuCPU(opts *qemuCPUOpts, pinning bool) []cfgSection {
 	return sections
 }
 
+// qemuNumaNodes generates the memory backends, NUMA nodes and distances of an explicitly configured guest
+// NUMA topology.
+func qemuNumaNodes(opts *qemuCPUOpts) []cfgSection {
+	sections := []cfgSection{}
+
+	for index, node := range opts.numaNodes {
+		numaNode := qemuCPUNumaHostNode(opts, index, node.memory)
+
+		// Always share the memory as it may be needed by vhost-user devices (such as virtiofsd).
+		extraMemEntries := []cfgEntry{{key: "share", value: "on"}}
+		if node.hostNode >= 0 {
+			extraMemEntries = append(extraMemEntries, cfgEntry{key: "policy", value: "bind"})
+			extraMemEntries = append(extraMemEntries, cfgEntry{key: "host-nodes.0", value: strconv.FormatInt(node.hostNode, 10)})
+		}
+
+		numaNode[0].entries = append(numaNode[0].entries, extraMemEntries...)
+		sections = append(sections, numaNode...)
+	}
+
+	for src, node := range opts.numaNodes {
+		for dst, distance := range node.distances {
+			// The local distance is fixed.
+			if src == dst {
+				continue
+			}
+
+			sections = append(sections, cfgSection{
+				name: "numa",
+				entries: []cfgEntry{
+					{key: "type", value: "dist"},
+					{key: "src", value: strconv.Itoa(src)},
+					{key: "dst", value: strconv.Itoa(dst)},
+					{key: "val", value: strconv.FormatUint(distance, 10)},
+				},
+			})
+		}
+	}
+
+	return sections
+}
+
 type qemuControlSocketOpts struct {
 	path string
 }
@@ -915,6 +988,67 @@ func qemuTPM(opts *qemuTPMOpts) []cfgSection {
 	}}
 }
 
+type qemuSerialPortOpts struct {
+	devName  string
+	target   string
+	portName string
+	backend  string
+	path     string // Path of the log file for the "file" backend.
+	fd       int    // File descriptor of the listening socket for the "unix" backend.
+}
+
+func qemuSerialPort(opts *qemuSerialPortOpts) []cfgSection {
+	chardev := qemuDeviceNameOrID(qemuSerialPortChardevPrefix, opts.devName, "", qemuDeviceIDMaxLength)
+	device := qemuDeviceNameOrID(qemuDeviceIDPrefix, opts.devName, "", qemuDeviceIDMaxLength)
+
+	var chardevEntries []cfgEntry
+	switch opts.backend {
+	case "file":
+		chardevEntries = []cfgEntry{
+			{key: "backend", value: "file"},
+			{key: "path", value: opts.path},
+			{key: "append", value: "on"},
+		}
+
+	case "pty":
+		chardevEntries = []cfgEntry{
+			{key: "backend", value: "pty"},
+		}
+
+	default:
+		chardevEntries = []cfgEntry{
+			{key: "backend", value: "socket"},
+			{key: "fd", value: strconv.Itoa(opts.fd)},
+			{key: "server", value: "on"},
+			{key: "wait", value: "off"},
+		}
+	}
+
+	var deviceEntries []cfgEntry
+	if opts.target == "isa" {
+		deviceEntries = []cfgEntry{
+			{key: "driver", value: "isa-serial"},
+			{key: "chardev", value: chardev},
+		}
+	} else {
+		deviceEntries = []cfgEntry{
+			{key: "driver", value: "virtserialport"},
+			{key: "bus", value: "dev-qemu_serial.0"},
+			{key: "name", value: opts.portName},
+			{key: "chardev", value: chardev},
+		}
+	}
+
+	return []cfgSection{{
+		name:    `chardev "` + chardev + `"`,
+		comment: `Serial port ("` + opts.devName + `" device)`,
+		entries: chardevEntries,
+	}, {
+		name:    `device "` + device + `"`,
+		entries: deviceEntries,
+	}}
+}
+
 type qemuVmgenIDOpts struct {
 	guid string
 }
@@ -0,0 +1,159 @@
+package drivers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/canonical/lxd/lxd/instance/drivers/qmp"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/osarch"
+	"github.com/canonical/lxd/shared/revert"
+)
+
+// qemuUSBRedirPrefix is the prefix of the devices used to redirect USB devices from a remote client.
+const qemuUSBRedirPrefix = "usbredir-"
+
+// usbRedirConn is the connection to a redirected USB device.
+// Closing it unplugs the device from the VM.
+type usbRedirConn struct {
+	net.Conn
+
+	closeOnce sync.Once
+	unplug    func()
+}
+
+// Close closes the connection and unplugs the redirected USB device.
+func (c *usbRedirConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(c.unplug)
+
+	return err
+}
+
+// USBRedirConn hotplugs a usb-redir device named after the given name into the running VM and returns a
+// connection to its usbredir channel. The device is unplugged once the connection is closed.
+func (d *qemu) USBRedirConn(name string) (net.Conn, error) {
+	if !d.IsRunning() {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Instance must be running to redirect USB devices")
+	}
+
+	if d.architecture == osarch.ARCH_64BIT_S390_BIG_ENDIAN {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "USB redirection isn't supported on this architecture")
+	}
+
+	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
+	if err != nil {
+		return nil, err
+	}
+
+	deviceID := qemuDeviceNameOrID(qemuDeviceIDPrefix, qemuUSBRedirPrefix+name, "", qemuDeviceIDMaxLength)
+	chardevID := qemuDeviceNameOrID("qemu_usbredir-chardev_", name, "", qemuDeviceIDMaxLength)
+
+	// Use a socket pair so that QEMU and LXD each get one end of the usbredir channel.
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Failed creating socket pair: %w", err)
+	}
+
+	qemuFile := os.NewFile(uintptr(fds[0]), "usbredir-qemu")
+	defer func() { _ = qemuFile.Close() }()
+
+	lxdFile := os.NewFile(uintptr(fds[1]), "usbredir-lxd")
+	defer func() { _ = lxdFile.Close() }()
+
+	conn, err := net.FileConn(lxdFile)
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting usbredir connection: %w", err)
+	}
+
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	reverter.Add(func() { _ = conn.Close() })
+
+	err = monitor.SendFile(chardevID, qemuFile)
+	if err != nil {
+		return nil, fmt.Errorf("Failed sending usbredir file descriptor: %w", err)
+	}
+
+	reverter.Add(func() { _ = monitor.CloseFile(chardevID) })
+
+	err = monitor.AddCharDevice(map[string]any{
+		"id": chardevID,
+		"backend": map[string]any{
+			"type": "socket",
+			"data": map[string]any{
+				"addr": map[string]any{
+					"type": "fd",
+					"data": map[string]any{
+						"str": chardevID,
+					},
+				},
+				"server": false,
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed adding the usbredir character device: %w", err)
+	}
+
+	reverter.Add(func() { _ = monitor.RemoveCharDevice(chardevID) })
+
+	err = monitor.AddDevice(map[string]any{
+		"id":      deviceID,
+		"driver":  "usb-redir",
+		"bus":     "qemu_usb.0",
+		"chardev": chardevID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed adding the usb-redir device: %w", err)
+	}
+
+	d.logger.Info("Redirected USB device attached", logger.Ctx{"device": name})
+
+	reverter.Success()
+
+	return &usbRedirConn{
+		Conn: conn,
+		unplug: func() {
+			err := d.usbRedirUnplug(deviceID, chardevID)
+			if err != nil {
+				d.logger.Warn("Failed unplugging redirected USB device", logger.Ctx{"device": name, "err": err})
+				return
+			}
+
+			d.logger.Info("Redirected USB device detached", logger.Ctx{"device": name})
+		},
+	}, nil
+}
+
+// usbRedirUnplug removes a usb-redir device and its character device from the VM.
+func (d *qemu) usbRedirUnplug(deviceID string, chardevID string) error {
+	// Nothing to unplug if the VM has been stopped in the mean time.
+	if !d.IsRunning() {
+		return nil
+	}
+
+	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
+	if err != nil {
+		return err
+	}
+
+	err = monitor.RemoveDevice(deviceID)
+	if err != nil {
+		return fmt.Errorf("Failed removing the usb-redir device: %w", err)
+	}
+
+	err = monitor.RemoveCharDevice(chardevID)
+	if err != nil {
+		return fmt.Errorf("Failed removing the usbredir character device: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package drivers
+
+import (
+	"io"
+	"net"
+	"testing"
+)
+
+func TestUSBRedirConnClose(t *testing.T) {
+	lxdConn, qemuConn := net.Pipe()
+	defer func() { _ = qemuConn.Close() }()
+
+	unplugged := 0
+	conn := &usbRedirConn{Conn: lxdConn, unplug: func() { unplugged++ }}
+
+	go func() { _, _ = conn.Write([]byte("usbredir")) }()
+
+	buf := make([]byte, 8)
+	_, err := io.ReadFull(qemuConn, buf)
+	if err != nil || string(buf) != "usbredir" {
+		t.Fatalf("Failed reading from usbredir connection: %q, %v", buf, err)
+	}
+
+	// Closing the connection unplugs the device only once.
+	_ = conn.Close()
+	_ = conn.Close()
+
+	if unplugged != 1 {
+		t.Fatalf("Expected the device to be unplugged once, got %d", unplugged)
+	}
+
+	_, err = qemuConn.Read(buf)
+	if err == nil {
+		t.Fatal("Expected the usbredir connection to be closed")
+	}
+}
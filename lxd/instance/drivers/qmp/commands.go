@@ -462,6 +462,70 @@ func (m *Monitor) SetMemoryBalloonSizeBytes(sizeBytes int64) error {
 	return m.run("balloon", args, nil)
 }
 
+// MemoryDeviceInfo contains information about a memory device.
+type MemoryDeviceInfo struct {
+	ID           string `json:"id"`
+	Memdev       string `json:"memdev"`
+	Size         int64  `json:"size"`
+	Slot         int    `json:"slot"`
+	Node         int    `json:"node"`
+	Hotplugged   bool   `json:"hotplugged"`
+	Hotpluggable bool   `json:"hotpluggable"`
+}
+
+// MemoryDevice contains information about a memory device (such as a DIMM).
+type MemoryDevice struct {
+	Type string           `json:"type"`
+	Data MemoryDeviceInfo `json:"data"`
+}
+
+// QueryMemoryDevices returns a list of memory devices.
+func (m *Monitor) QueryMemoryDevices() ([]MemoryDevice, error) {
+	// Prepare the response.
+	var resp struct {
+		Return []MemoryDevice `json:"return"`
+	}
+
+	err := m.run("query-memory-devices", nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Return, nil
+}
+
+// AddObject adds a new object.
+func (m *Monitor) AddObject(object map[string]any) error {
+	if object != nil {
+		err := m.run("object-add", object, nil)
+		if err != nil {
+			return fmt.Errorf("Failed adding object: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RemoveObject removes an object.
+func (m *Monitor) RemoveObject(objectID string) error {
+	if objectID != "" {
+		objectID := map[string]string{
+			"id": objectID,
+		}
+
+		err := m.run("object-del", objectID, nil)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				return nil
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
 // AddBlockDevice adds a block device.
 func (m *Monitor) AddBlockDevice(blockDev map[string]any, device map[string]any) error {
 	revert := revert.New()
@@ -548,6 +612,32 @@ func (m *Monitor) RemoveCharDevice(deviceID string) error {
 	return nil
 }
 
+// CharDevice represents a character device.
+type CharDevice struct {
+	Label        string `json:"label"`
+	Filename     string `json:"filename"`
+	FrontendOpen bool   `json:"frontend-open"`
+}
+
+// QueryCharDevices returns the character devices indexed by their label.
+func (m *Monitor) QueryCharDevices() (map[string]CharDevice, error) {
+	var resp struct {
+		Return []CharDevice `json:"return"`
+	}
+
+	err := m.run("query-chardev", nil, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("Failed querying character devices: %w", err)
+	}
+
+	devices := make(map[string]CharDevice, len(resp.Return))
+	for _, device := range resp.Return {
+		devices[device.Label] = device
+	}
+
+	return devices, nil
+}
+
 // AddDevice adds a new device.
 func (m *Monitor) AddDevice(device map[string]any) error {
 	if device != nil {
@@ -580,6 +670,49 @@ func (m *Monitor) RemoveDevice(deviceID string) error {
 	return nil
 }
 
+// RemoveDeviceWait removes a device and waits for QEMU to confirm its removal with a DEVICE_DELETED event.
+// This is needed for devices that the guest has to release first, such as DIMMs, as the removal is then
+// asynchronous and may be refused. Returns an error if the device isn't removed before the context is done.
+func (m *Monitor) RemoveDeviceWait(ctx context.Context, deviceID string) error {
+	ch := make(chan struct{})
+
+	// Register the waiter before requesting the removal so that the event can't be missed.
+	m.deviceDeletedWaitersMu.Lock()
+	if m.deviceDeletedWaiters == nil {
+		m.deviceDeletedWaiters = map[string]chan struct{}{}
+	}
+
+	m.deviceDeletedWaiters[deviceID] = ch
+	m.deviceDeletedWaitersMu.Unlock()
+
+	defer func() {
+		m.deviceDeletedWaitersMu.Lock()
+		if m.deviceDeletedWaiters[deviceID] == ch {
+			delete(m.deviceDeletedWaiters, deviceID)
+		}
+
+		m.deviceDeletedWaitersMu.Unlock()
+	}()
+
+	err := m.run("device_del", map[string]string{"id": deviceID}, nil)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil
+		}
+
+		return err
+	}
+
+	select {
+	case <-ch:
+		return nil
+	case <-m.chDisconnect:
+		return ErrMonitorDisconnect
+	case <-ctx.Done():
+		return fmt.Errorf("Timed out waiting for device %q to be removed: %w", deviceID, ctx.Err())
+	}
+}
+
 // AddNIC adds a NIC device.
 func (m *Monitor) AddNIC(netDev map[string]any, device map[string]any) error {
 	revert := revert.New()
@@ -841,6 +974,268 @@ func (m *Monitor) NBDBlockExportAdd(deviceNodeName string) error {
 	return nil
 }
 
+// NBDBlockExportAddBitmap exports a read-only device via the NBD server along with a dirty bitmap.
+// The bitmap is made available to NBD clients as the "qemu:dirty-bitmap:<bitmapName>" metadata context.
+func (m *Monitor) NBDBlockExportAddBitmap(exportName string, deviceNodeName string, bitmapName string) error {
+	var args struct {
+		ID       string   `json:"id"`
+		Type     string   `json:"type"`
+		NodeName string   `json:"node-name"`
+		Name     string   `json:"name"`
+		Writable bool     `json:"writable"`
+		Bitmaps  []string `json:"bitmaps"`
+	}
+
+	args.ID = exportName
+	args.Type = "nbd"
+	args.NodeName = deviceNodeName
+	args.Name = exportName
+	args.Writable = false
+	args.Bitmaps = []string{bitmapName}
+
+	err := m.run("block-export-add", args, nil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// BlockExportDel removes a block export.
+func (m *Monitor) BlockExportDel(exportName string) error {
+	var args struct {
+		ID string `json:"id"`
+	}
+
+	args.ID = exportName
+
+	err := m.run("block-export-del", args, nil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DirtyBitmap represents a dirty bitmap attached to a block node.
+type DirtyBitmap struct {
+	Name         string `json:"name"`
+	Recording    bool   `json:"recording"`
+	Busy         bool   `json:"busy"`
+	Count        int64  `json:"count"`
+	Granularity  int64  `json:"granularity"`
+	Persistent   bool   `json:"persistent"`
+	Inconsistent bool   `json:"inconsistent"`
+}
+
+// BlockNodeCache represents the cache mode of a block node.
+type BlockNodeCache struct {
+	Writeback bool `json:"writeback"`
+	Direct    bool `json:"direct"`
+	NoFlush   bool `json:"no-flush"`
+}
+
+// BlockNodeImage represents the image information of a block node.
+type BlockNodeImage struct {
+	VirtualSize int64 `json:"virtual-size"`
+}
+
+// BlockNode represents a named block node.
+type BlockNode struct {
+	NodeName     string         `json:"node-name"`
+	Driver       string         `json:"drv"`
+	ReadOnly     bool           `json:"ro"`
+	Cache        BlockNodeCache `json:"cache"`
+	Image        BlockNodeImage `json:"image"`
+	DirtyBitmaps []DirtyBitmap  `json:"dirty-bitmaps"`
+}
+
+// QueryNamedBlockNodes returns the named block nodes, keyed by node name.
+func (m *Monitor) QueryNamedBlockNodes() (map[string]BlockNode, error) {
+	var args struct {
+		Flat bool `json:"flat"`
+	}
+
+	args.Flat = true
+
+	var resp struct {
+		Return []BlockNode `json:"return"`
+	}
+
+	err := m.run("query-named-block-nodes", args, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]BlockNode, len(resp.Return))
+	for _, node := range resp.Return {
+		nodes[node.NodeName] = node
+	}
+
+	return nodes, nil
+}
+
+// BlockDirtyBitmapAdd atomically adds a dirty bitmap with the same name to each of the specified block nodes.
+// Persistent bitmaps are stored in the image of the block nodes when they are closed, which requires qcow2 nodes.
+func (m *Monitor) BlockDirtyBitmapAdd(deviceNodeNames []string, bitmapName string, persistent bool) error {
+	type bitmapAction struct {
+		Type string `json:"type"`
+		Data struct {
+			Node       string `json:"node"`
+			Name       string `json:"name"`
+			Persistent bool   `json:"persistent"`
+		} `json:"data"`
+	}
+
+	var args struct {
+		Actions []bitmapAction `json:"actions"`
+	}
+
+	for _, deviceNodeName := range deviceNodeNames {
+		action := bitmapAction{Type: "block-dirty-bitmap-add"}
+		action.Data.Node = deviceNodeName
+		action.Data.Name = bitmapName
+		action.Data.Persistent = persistent
+
+		args.Actions = append(args.Actions, action)
+	}
+
+	err := m.run("transaction", args, nil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// BlockDirtyBitmapRemove removes a dirty bitmap from a block node.
+func (m *Monitor) BlockDirtyBitmapRemove(deviceNodeName string, bitmapName string) error {
+	var args struct {
+		Node string `json:"node"`
+		Name string `json:"name"`
+	}
+
+	args.Node = deviceNodeName
+	args.Name = bitmapName
+
+	err := m.run("block-dirty-bitmap-remove", args, nil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// BlockDevBackupFleecing starts a copy-before-write job from the device to the fleecing node, which must use the
+// device as its backing node. Reading from the fleecing node then returns the content the device had at the time of
+// the call. The dirty bitmap of the device is frozen into a disabled bitmap of the same name on the fleecing node as
+// part of the same transaction, so that it matches the fleecing point in time.
+func (m *Monitor) BlockDevBackupFleecing(deviceNodeName string, fleecingNodeName string, bitmapName string, granularity int64) error {
+	type transactionAction struct {
+		Type string         `json:"type"`
+		Data map[string]any `json:"data"`
+	}
+
+	var args struct {
+		Actions []transactionAction `json:"actions"`
+	}
+
+	args.Actions = []transactionAction{
+		{
+			Type: "block-dirty-bitmap-add",
+			Data: map[string]any{
+				"node":        fleecingNodeName,
+				"name":        bitmapName,
+				"granularity": granularity,
+				"disabled":    true,
+			},
+		},
+		{
+			Type: "block-dirty-bitmap-merge",
+			Data: map[string]any{
+				"node":    fleecingNodeName,
+				"target":  bitmapName,
+				"bitmaps": []map[string]string{{"node": deviceNodeName, "name": bitmapName}},
+			},
+		},
+		{
+			Type: "blockdev-backup",
+			Data: map[string]any{
+				"job-id": fleecingNodeName,
+				"device": deviceNodeName,
+				"target": fleecingNodeName,
+				"sync":   "none",
+			},
+		},
+	}
+
+	err := m.run("transaction", args, nil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// BlockDevCreate formats an image on existing block nodes and waits for the creation job to conclude.
+func (m *Monitor) BlockDevCreate(jobID string, options map[string]any) error {
+	var args struct {
+		JobID   string         `json:"job-id"`
+		Options map[string]any `json:"options"`
+	}
+
+	args.JobID = jobID
+	args.Options = options
+
+	err := m.run("blockdev-create", args, nil)
+	if err != nil {
+		return err
+	}
+
+	// Creation jobs are never dismissed automatically.
+	defer func() {
+		_ = m.run("job-dismiss", map[string]string{"id": jobID}, nil)
+	}()
+
+	for {
+		var resp struct {
+			Return []struct {
+				ID     string `json:"id"`
+				Status string `json:"status"`
+				Error  string `json:"error"`
+			} `json:"return"`
+		}
+
+		err := m.run("query-jobs", nil, &resp)
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for _, job := range resp.Return {
+			if job.ID != jobID {
+				continue
+			}
+
+			if job.Status == "concluded" {
+				if job.Error != "" {
+					return fmt.Errorf("Failed creating image: %s", job.Error)
+				}
+
+				return nil
+			}
+
+			found = true
+		}
+
+		if !found {
+			return errors.New("Specified job not found")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
 // BlockDevSnapshot creates a snapshot of a device using the specified snapshot device.
 func (m *Monitor) BlockDevSnapshot(deviceNodeName string, snapshotNodeName string) error {
 	var args struct {
@@ -860,14 +1255,17 @@ func (m *Monitor) BlockDevSnapshot(deviceNodeName string, snapshotNodeName strin
 }
 
 // blockJobWaitReady waits until the specified jobID is ready, errored or missing.
+// If a progress function is provided, it is called with the job offset and length on every poll.
 // Returns nil if the job is ready, otherwise an error.
-func (m *Monitor) blockJobWaitReady(jobID string) error {
+func (m *Monitor) blockJobWaitReady(jobID string, progress func(current int64, total int64)) error {
 	for {
 		var resp struct {
 			Return []struct {
 				Device string `json:"device"`
 				Ready  bool   `json:"ready"`
 				Error  string `json:"error"`
+				Offset int64  `json:"offset"`
+				Len    int64  `json:"len"`
 			} `json:"return"`
 		}
 
@@ -886,6 +1284,10 @@ func (m *Monitor) blockJobWaitReady(jobID string) error {
 				return fmt.Errorf("Failed block job: %s", job.Error)
 			}
 
+			if progress != nil {
+				progress(job.Offset, job.Len)
+			}
+
 			if job.Ready {
 				return nil
 			}
@@ -916,7 +1318,7 @@ func (m *Monitor) BlockCommit(deviceNodeName string) error {
 		return err
 	}
 
-	err = m.blockJobWaitReady(args.JobID)
+	err = m.blockJobWaitReady(args.JobID, nil)
 	if err != nil {
 		return err
 	}
@@ -956,7 +1358,7 @@ func (m *Monitor) BlockDevMirror(deviceNodeName string, targetNodeName string) e
 		return err
 	}
 
-	err = m.blockJobWaitReady(args.JobID)
+	err = m.blockJobWaitReady(args.JobID, nil)
 	if err != nil {
 		return err
 	}
@@ -964,6 +1366,70 @@ func (m *Monitor) BlockDevMirror(deviceNodeName string, targetNodeName string) e
 	return nil
 }
 
+// BlockDevMirrorFull copies the whole content of the device to the target device.
+// It returns once both devices are in sync, after which the job keeps mirroring new writes until it is
+// completed (switching the device over to the target) or cancelled.
+func (m *Monitor) BlockDevMirrorFull(deviceNodeName string, targetNodeName string, progress func(current int64, total int64)) error {
+	var args struct {
+		Device   string `json:"device"`
+		Target   string `json:"target"`
+		Sync     string `json:"sync"`
+		JobID    string `json:"job-id"`
+		CopyMode string `json:"copy-mode"`
+	}
+
+	args.Device = deviceNodeName
+	args.Target = targetNodeName
+	args.JobID = deviceNodeName
+	args.Sync = "full"
+
+	// Copy the existing data in the background while writing new data to both devices.
+	// This ensures that the devices converge even with a busy guest.
+	args.CopyMode = "write-blocking"
+
+	err := m.run("blockdev-mirror", args, nil)
+	if err != nil {
+		return err
+	}
+
+	err = m.blockJobWaitReady(args.JobID, progress)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// BlockJobWait waits until the specified block job has concluded and is no longer listed.
+func (m *Monitor) BlockJobWait(jobID string) error {
+	for {
+		var resp struct {
+			Return []struct {
+				Device string `json:"device"`
+			} `json:"return"`
+		}
+
+		err := m.run("query-block-jobs", nil, &resp)
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for _, job := range resp.Return {
+			if job.Device == jobID {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil
+		}
+
+		time.Sleep(1 * time.Second)
+	}
+}
+
 // BlockJobCancel cancels an ongoing block job.
 func (m *Monitor) BlockJobCancel(deviceNodeName string) error {
 	var args struct {
@@ -1012,24 +1478,75 @@ func (m *Monitor) Eject(id string) error {
 	return nil
 }
 
+// BlockThrottle represents the I/O limits of a disk.
+// Zero values disable the corresponding limit.
+type BlockThrottle struct {
+	BytesRead  int64
+	BytesWrite int64
+	IOPsRead   int64
+	IOPsWrite  int64
+
+	// Burst limits and the maximum length of a burst in seconds.
+	BytesReadMax  int64
+	BytesWriteMax int64
+	IOPsReadMax   int64
+	IOPsWriteMax  int64
+	MaxLength     int64
+}
+
 // SetBlockThrottle applies an I/O limit on a disk.
-func (m *Monitor) SetBlockThrottle(id string, bytesRead int, bytesWrite int, iopsRead int, iopsWrite int) error {
+// The disk is placed in a throttle group named after its ID.
+func (m *Monitor) SetBlockThrottle(id string, limits BlockThrottle) error {
 	var args struct {
-		ID string `json:"id"`
-
-		Bytes      int `json:"bps"`
-		BytesRead  int `json:"bps_rd"`
-		BytesWrite int `json:"bps_wr"`
-		IOPs       int `json:"iops"`
-		IOPsRead   int `json:"iops_rd"`
-		IOPsWrite  int `json:"iops_wr"`
+		ID    string `json:"id"`
+		Group string `json:"group"`
+
+		Bytes      int64 `json:"bps"`
+		BytesRead  int64 `json:"bps_rd"`
+		BytesWrite int64 `json:"bps_wr"`
+		IOPs       int64 `json:"iops"`
+		IOPsRead   int64 `json:"iops_rd"`
+		IOPsWrite  int64 `json:"iops_wr"`
+
+		BytesReadMax        int64 `json:"bps_rd_max,omitempty"`
+		BytesWriteMax       int64 `json:"bps_wr_max,omitempty"`
+		IOPsReadMax         int64 `json:"iops_rd_max,omitempty"`
+		IOPsWriteMax        int64 `json:"iops_wr_max,omitempty"`
+		BytesReadMaxLength  int64 `json:"bps_rd_max_length,omitempty"`
+		BytesWriteMaxLength int64 `json:"bps_wr_max_length,omitempty"`
+		IOPsReadMaxLength   int64 `json:"iops_rd_max_length,omitempty"`
+		IOPsWriteMaxLength  int64 `json:"iops_wr_max_length,omitempty"`
 	}
 
 	args.ID = id
-	args.BytesRead = bytesRead
-	args.BytesWrite = bytesWrite
-	args.IOPsRead = iopsRead
-	args.IOPsWrite = iopsWrite
+	args.Group = id
+	args.BytesRead = limits.BytesRead
+	args.BytesWrite = limits.BytesWrite
+	args.IOPsRead = limits.IOPsRead
+	args.IOPsWrite = limits.IOPsWrite
+	args.BytesReadMax = limits.BytesReadMax
+	args.BytesWriteMax = limits.BytesWriteMax
+	args.IOPsReadMax = limits.IOPsReadMax
+	args.IOPsWriteMax = limits.IOPsWriteMax
+
+	// QEMU only accepts a burst length for the limits that have a burst set.
+	if limits.MaxLength > 0 {
+		if args.BytesReadMax > 0 {
+			args.BytesReadMaxLength = limits.MaxLength
+		}
+
+		if args.BytesWriteMax > 0 {
+			args.BytesWriteMaxLength = limits.MaxLength
+		}
+
+		if args.IOPsReadMax > 0 {
+			args.IOPsReadMaxLength = limits.MaxLength
+		}
+
+		if args.IOPsWriteMax > 0 {
+			args.IOPsWriteMaxLength = limits.MaxLength
+		}
+	}
 
 	err := m.run("block_set_io_throttle", args, nil)
 	if err != nil {
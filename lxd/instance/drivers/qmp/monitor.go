@@ -28,6 +28,9 @@ var EventVMShutdown = "SHUTDOWN"
 // EventVMShutdownReasonDisconnect is used as the reason when the shutdown event is triggered by a QMP disconnect.
 var EventVMShutdownReasonDisconnect = "disconnect"
 
+// EventDeviceDeleted is the event sent when a device has been released by the guest and removed.
+var EventDeviceDeleted = "DEVICE_DELETED"
+
 // Monitor represents a QMP monitor.
 type Monitor struct {
 	path string
@@ -40,6 +43,9 @@ type Monitor struct {
 	eventHandler      func(name string, data map[string]any)
 	serialCharDev     string
 	onDisconnectEvent bool
+
+	deviceDeletedWaiters   map[string]chan struct{}
+	deviceDeletedWaitersMu sync.Mutex
 }
 
 // start handles the background goroutines for event handling and monitoring the ringbuffer.
@@ -103,6 +109,12 @@ func (m *Monitor) start() error {
 			case <-m.chDisconnect:
 				return
 			case e, more := <-chEvents:
+				// Wake up anyone waiting for the removal of this device.
+				if e.Event == EventDeviceDeleted {
+					deviceID, _ := e.Data["device"].(string)
+					m.notifyDeviceDeleted(deviceID)
+				}
+
 				// Deliver non-empty events to the event handler.
 				if m.eventHandler != nil && e.Event != "" {
 					if e.Event == EventVMShutdown {
@@ -321,6 +333,18 @@ func (m *Monitor) Wait() (chan struct{}, error) {
 	return m.chDisconnect, nil
 }
 
+// notifyDeviceDeleted wakes up the waiter registered for the device ID, if any.
+func (m *Monitor) notifyDeviceDeleted(deviceID string) {
+	m.deviceDeletedWaitersMu.Lock()
+	defer m.deviceDeletedWaitersMu.Unlock()
+
+	ch, ok := m.deviceDeletedWaiters[deviceID]
+	if ok {
+		close(ch)
+		delete(m.deviceDeletedWaiters, deviceID)
+	}
+}
+
 // SetOnDisconnectEvent enables or disables the on disconnect event.
 func (m *Monitor) SetOnDisconnectEvent(enable bool) {
 	m.onDisconnectEvent = enable
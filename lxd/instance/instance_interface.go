@@ -202,6 +202,20 @@ type VM interface {
 	// UEFI vars handling.
 	UEFIVars() (*api.InstanceUEFIVars, error)
 	UEFIVarsUpdate(newUEFIVarsSet api.InstanceUEFIVars) error
+
+	MemoryBalloonUpdate() error
+
+	// Changed block tracking.
+	Checkpoints() ([]api.InstanceCheckpoint, error)
+	Checkpoint(name string) (*api.InstanceCheckpoint, error)
+	CheckpointCreate(req api.InstanceCheckpointsPost) error
+	CheckpointDelete(name string) error
+	CheckpointExport(name string, diskName string) (io.ReadWriteCloser, error)
+
+	MirrorDisk(deviceName string, targetPath string, progress func(current int64, total int64)) error
+
+	// USB redirection.
+	USBRedirConn(name string) (net.Conn, error)
 }
 
 // CriuMigrationArgs arguments for CRIU migration.
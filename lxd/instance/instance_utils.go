@@ -65,6 +65,10 @@ func ValidConfig(sysOS *sys.OS, config map[string]string, expanded bool, instanc
 			return errors.New("Image keys can only be set on instances")
 		}
 
+		if instanceType == instancetype.Any && !expanded && strings.HasPrefix(k, "replication.") {
+			return errors.New("Replication keys can only be set on instances")
+		}
+
 		err := validConfigKey(sysOS, k, v, instanceType)
 		if err != nil {
 			return err
@@ -38,6 +38,12 @@ const (
 	BootModeBIOS             = "bios"
 )
 
+// Memory balloon configuration values.
+const (
+	MemoryBalloonModeManual = "manual"
+	MemoryBalloonModeAuto   = "auto"
+)
+
 // ConfigKeyPrefixesAny indicates valid prefixes for configuration options.
 var ConfigKeyPrefixesAny = []string{"environment.", "user.", "image.", "cloud-init.ssh-keys."}
 
@@ -85,6 +91,22 @@ func ValidSnapName(snapshotName string) error {
 	return nil
 }
 
+// ValidResourcePoolName checks a project resource pool name is 1-63 characters long and only contains alphanumeric,
+// hyphen and underscore characters.
+func ValidResourcePoolName(name string) error {
+	if len(name) < 1 || len(name) > 63 {
+		return errors.New("Resource pool name must be 1-63 characters long")
+	}
+
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') && r != '-' && r != '_' {
+			return fmt.Errorf("Resource pool name contains invalid character %q", r)
+		}
+	}
+
+	return nil
+}
+
 // HugePageSizeKeys is a list of known hugepage size configuration keys.
 var HugePageSizeKeys = [...]string{"limits.hugepages.64KB", "limits.hugepages.1MB", "limits.hugepages.2MB", "limits.hugepages.1GB"}
 
@@ -319,6 +341,50 @@ var InstanceConfigKeysAny = map[string]func(value string) error{
 	//  shortdesc: Raw idmap configuration
 	"raw.idmap": validate.IsAny,
 
+	// lxdmeta:generate(entities=instance; group=replication; key=replication.target_pool)
+	// Setting this option enables the replication of the instance to a replica whose root disk is in the given storage pool.
+	// See {ref}`instances-replication`.
+	// ---
+	//  type: string
+	//  liveupdate: yes
+	//  shortdesc: Storage pool to replicate the instance to
+	"replication.target_pool": validate.IsAny,
+
+	// lxdmeta:generate(entities=instance; group=replication; key=replication.target_instance)
+	// The replica must have a different name than the instance if it is created in the same project on this server or cluster.
+	// ---
+	//  type: string
+	//  defaultdesc: same as the instance name
+	//  liveupdate: yes
+	//  shortdesc: Name of the replica instance
+	"replication.target_instance": validate.Optional(func(value string) error { return ValidName(value, false) }),
+
+	// lxdmeta:generate(entities=instance; group=replication; key=replication.target_remote)
+	// Set this option to the HTTPS address of a remote LXD server or cluster (for example, `https://10.0.0.2:8443`) to create the replica there instead of on this server or cluster.
+	// The remote must trust the certificate of this server or cluster, and its own certificate must match {config:option}`instance-replication:replication.target_remote_fingerprint`.
+	// ---
+	//  type: string
+	//  liveupdate: yes
+	//  shortdesc: Remote server to replicate the instance to
+	"replication.target_remote": validate.Optional(validate.IsHTTPSURL),
+
+	// lxdmeta:generate(entities=instance; group=replication; key=replication.target_remote_fingerprint)
+	// This option is required if {config:option}`instance-replication:replication.target_remote` is set.
+	// ---
+	//  type: string
+	//  liveupdate: yes
+	//  shortdesc: SHA-256 fingerprint of the remote server certificate
+	"replication.target_remote_fingerprint": validate.Optional(validate.IsCertificateFingerprint),
+
+	// lxdmeta:generate(entities=instance; group=replication; key=replication.schedule)
+	// Specify either a cron expression (`<minute> <hour> <dom> <month> <dow>`), a comma-separated list of schedule aliases (`@hourly`, `@daily`, `@midnight`, `@weekly`, `@monthly`, `@annually`, `@yearly`), or leave empty to only synchronize the replica on demand.
+	// ---
+	//  type: string
+	//  defaultdesc: empty
+	//  liveupdate: yes
+	//  shortdesc: Schedule for synchronizing the replica
+	"replication.schedule": validate.Optional(validate.IsCron([]string{"@hourly", "@daily", "@midnight", "@weekly", "@monthly", "@annually", "@yearly"})),
+
 	// lxdmeta:generate(entities=instance; group=security; key=security.devlxd)
 	// See {ref}`dev-lxd` for more information.
 	// ---
@@ -472,6 +538,25 @@ var InstanceConfigKeysAny = map[string]func(value string) error{
 	"volatile.last_state.power": validate.IsAny,
 	"volatile.last_state.ready": validate.IsBool,
 	"volatile.apply_quota":      validate.IsAny,
+
+	// lxdmeta:generate(entities=instance; group=volatile; key=volatile.replication.source)
+	// This option is set on replica instances to the name of the instance they are synchronized from.
+	// If the source is on a remote server, it is prefixed with the certificate fingerprint of that server (`<fingerprint>:<instance>`).
+	// ---
+	//  type: string
+	//  shortdesc: Source of the replica instance
+	"volatile.replication.source": validate.IsAny,
+
+	// lxdmeta:generate(entities=instance; group=volatile; key=volatile.replication.last_sync)
+	//
+	// ---
+	//  type: string
+	//  shortdesc: Time of the last successful replica synchronization
+	"volatile.replication.last_sync": validate.Optional(func(value string) error {
+		_, err := time.Parse(time.RFC3339, value)
+		return err
+	}),
+
 	// lxdmeta:generate(entities=instance; group=volatile; key=volatile.uuid)
 	// The instance UUID is globally unique across all servers and projects.
 	// ---
@@ -637,6 +722,17 @@ var InstanceConfigKeysContainer = map[string]func(value string) error{
 	//  condition: container
 	//  shortdesc: Maximum number of processes that can run in the instance
 	"limits.processes": validate.Optional(validate.IsInt64),
+	// lxdmeta:generate(entities=instance; group=resource-limits; key=limits.resource_pool)
+	// Name of a resource pool defined in the instance's project (see {ref}`project-limits-resource-pools`).
+	// The instance's cgroup is then nested under the pool's cgroup, so that it shares the pool's CPU and memory limits with the other members.
+	//
+	// Requires a pure `cgroup2` host.
+	// ---
+	//  type: string
+	//  liveupdate: no
+	//  condition: container
+	//  shortdesc: Resource pool that the instance is a member of
+	"limits.resource_pool": validate.Optional(ValidResourcePoolName),
 
 	// lxdmeta:generate(entities=instance; group=miscellaneous; key=linux.kernel_modules)
 	// Specify the kernel modules as a comma-separated list.
@@ -796,6 +892,20 @@ var InstanceConfigKeysContainer = map[string]func(value string) error{
 	//  shortdesc: Whether to run the instance in privileged mode
 	"security.privileged": validate.Optional(validate.IsBool),
 
+	// lxdmeta:generate(entities=instance; group=security; key=security.profile.mode)
+	// Possible values are `enforce` and `complain`.
+	// In `complain` mode, the seccomp policy logs the system calls it would deny and the AppArmor profile is loaded in complain mode.
+	// The operations that would have been denied are written to the `security.log` instance log file and reported through `security` events.
+	// This mode is meant to help tighten the profiles of an instance and must not be used in production.
+	// See {ref}`security-profile-mode` for more information.
+	// ---
+	//  type: string
+	//  defaultdesc: `enforce`
+	//  liveupdate: no
+	//  condition: container
+	//  shortdesc: Whether the security profiles are enforced
+	"security.profile.mode": validate.Optional(validate.IsOneOf("enforce", "complain")),
+
 	// lxdmeta:generate(entities=instance; group=security; key=security.protection.shift)
 	// Set this option to `true` to prevent the instance's file system from being UID/GID shifted on startup.
 	// ---
@@ -867,6 +977,26 @@ var InstanceConfigKeysContainer = map[string]func(value string) error{
 	//  shortdesc: Whether to allow BPF programs
 	"security.syscalls.intercept.bpf.devices": validate.Optional(validate.IsBool),
 
+	// lxdmeta:generate(entities=instance; group=security; key=security.syscalls.intercept.kexec_load)
+	// Calls are allowed or denied according to the seccomp policy of the instance and each of them is reported through an `instance-syscall-intercepted` lifecycle event.
+	// ---
+	//  type: bool
+	//  defaultdesc: `false`
+	//  liveupdate: no
+	//  condition: container
+	//  shortdesc: Whether to audit the `kexec_load` system call
+	"security.syscalls.intercept.kexec_load": validate.Optional(validate.IsBool),
+
+	// lxdmeta:generate(entities=instance; group=security; key=security.syscalls.intercept.keyctl)
+	// Calls are allowed or denied according to the seccomp policy of the instance and each of them is reported through an `instance-syscall-intercepted` lifecycle event.
+	// ---
+	//  type: bool
+	//  defaultdesc: `false`
+	//  liveupdate: no
+	//  condition: container
+	//  shortdesc: Whether to audit the `keyctl` system call
+	"security.syscalls.intercept.keyctl": validate.Optional(validate.IsBool),
+
 	// lxdmeta:generate(entities=instance; group=security; key=security.syscalls.intercept.mknod)
 	// These system calls allow creation of a limited subset of char/block devices.
 	// ---
@@ -915,6 +1045,26 @@ var InstanceConfigKeysContainer = map[string]func(value string) error{
 	//  shortdesc: Whether to use idmapped mounts for syscall interception
 	"security.syscalls.intercept.mount.shift": validate.Optional(validate.IsBool),
 
+	// lxdmeta:generate(entities=instance; group=security; key=security.syscalls.intercept.open_by_handle_at)
+	// Calls are allowed or denied according to the seccomp policy of the instance and each of them is reported through an `instance-syscall-intercepted` lifecycle event.
+	// ---
+	//  type: bool
+	//  defaultdesc: `false`
+	//  liveupdate: no
+	//  condition: container
+	//  shortdesc: Whether to audit the `open_by_handle_at` system call
+	"security.syscalls.intercept.open_by_handle_at": validate.Optional(validate.IsBool),
+
+	// lxdmeta:generate(entities=instance; group=security; key=security.syscalls.intercept.perf_event_open)
+	// Calls are allowed or denied according to the seccomp policy of the instance and each of them is reported through an `instance-syscall-intercepted` lifecycle event.
+	// ---
+	//  type: bool
+	//  defaultdesc: `false`
+	//  liveupdate: no
+	//  condition: container
+	//  shortdesc: Whether to audit the `perf_event_open` system call
+	"security.syscalls.intercept.perf_event_open": validate.Optional(validate.IsBool),
+
 	// lxdmeta:generate(entities=instance; group=security; key=security.syscalls.intercept.sched_setscheduler)
 	// This system call allows increasing process priority.
 	// ---
@@ -1060,6 +1210,78 @@ var InstanceConfigKeysVM = map[string]func(value string) error{
 	//  shortdesc: Whether to back the instance using huge pages
 	"limits.memory.hugepages": validate.Optional(validate.IsBool),
 
+	// lxdmeta:generate(entities=instance; group=resource-limits; key=limits.memory.hotplug_max)
+	// Maximum amount of memory that the VM can be grown to while running, as a fixed value in bytes.
+	// Various suffixes are supported, percentages are not.
+	// When set, memory hotplug slots are reserved at start and live updates of
+	// {config:option}`instance-resource-limits:limits.memory` add or remove memory devices instead of
+	// only resizing the balloon.
+	//
+	// See {ref}`instance-options-limits-memory-vm` for more information.
+	// ---
+	//  type: string
+	//  liveupdate: no
+	//  condition: virtual machine
+	//  shortdesc: Maximum memory size the VM can be grown to while running
+	"limits.memory.hotplug_max": func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		if strings.HasSuffix(value, "%") {
+			return errors.New("Value cannot be a percentage")
+		}
+
+		return validate.IsSize(value)
+	},
+
+	// lxdmeta:generate(entities=instance; group=resource-limits; key=limits.memory.balloon)
+	// Possible values are `manual` (the balloon is only resized when {config:option}`instance-resource-limits:limits.memory` changes)
+	// and `auto` (the balloon is resized based on the memory pressure reported by the `lxd-agent`).
+	// When set to `auto` at start, free page reporting is also enabled so that memory freed by the guest is returned to the host.
+	//
+	// See {ref}`instance-options-limits-memory-vm` for more information.
+	// ---
+	//  type: string
+	//  defaultdesc: `manual`
+	//  liveupdate: yes
+	//  condition: virtual machine
+	//  shortdesc: Whether the memory balloon follows the guest memory pressure
+	"limits.memory.balloon": validate.Optional(validate.IsOneOf(MemoryBalloonModeManual, MemoryBalloonModeAuto)),
+
+	// lxdmeta:generate(entities=instance; group=resource-limits; key=limits.memory.balloon.max)
+	// Upper bound of the memory left to the guest when {config:option}`instance-resource-limits:limits.memory.balloon` is set to `auto`.
+	// It can't exceed {config:option}`instance-resource-limits:limits.memory`.
+	// ---
+	//  type: string
+	//  defaultdesc: value of `limits.memory`
+	//  liveupdate: yes
+	//  condition: virtual machine
+	//  shortdesc: Maximum memory left to the guest by the automatic balloon
+	"limits.memory.balloon.max": func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		return InstanceConfigKeysAny["limits.memory"](value)
+	},
+
+	// lxdmeta:generate(entities=instance; group=resource-limits; key=limits.memory.balloon.min)
+	// Lower bound of the memory left to the guest when {config:option}`instance-resource-limits:limits.memory.balloon` is set to `auto`.
+	// ---
+	//  type: string
+	//  defaultdesc: half of `limits.memory.balloon.max`
+	//  liveupdate: yes
+	//  condition: virtual machine
+	//  shortdesc: Minimum memory left to the guest by the automatic balloon
+	"limits.memory.balloon.min": func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		return InstanceConfigKeysAny["limits.memory"](value)
+	},
+
 	// lxdmeta:generate(entities=instance; group=resource-limits; key=limits.cpu.pin_strategy)
 	// Specify the strategy for VM CPU auto pinning.
 	// Possible values: `none` (disables CPU auto pinning) and `auto` (enables CPU auto pinning).
@@ -1192,6 +1414,17 @@ var InstanceConfigKeysVM = map[string]func(value string) error{
 	//  shortdesc: Whether to use the name and MTU of the default network interfaces
 	"agent.nic_config": validate.Optional(validate.IsBool),
 
+	// lxdmeta:generate(entities=instance; group=miscellaneous; key=checkpoints.enabled)
+	// When set to true, the writable disks of the virtual machine are attached with a checkpoint metadata image that stores their dirty bitmaps, which allows creating checkpoints that are kept across restarts.
+	// See {ref}`instances-backup-checkpoints` for more information.
+	// ---
+	//  type: bool
+	//  defaultdesc: `false`
+	//  liveupdate: no
+	//  condition: virtual machine
+	//  shortdesc: Whether to track changed blocks of the disks for checkpoints
+	"checkpoints.enabled": validate.Optional(validate.IsBool),
+
 	// lxdmeta:generate(entities=instance; group=volatile; key=volatile.apply_nvram)
 	//
 	// ---
@@ -1206,6 +1439,14 @@ var InstanceConfigKeysVM = map[string]func(value string) error{
 	//  shortdesc: Device bus allocation mode
 	"volatile.bus.mode": validate.Optional(validate.IsOneOf("persistent")),
 
+	// lxdmeta:generate(entities=instance; group=volatile; key=volatile.storage_move.source_pool)
+	// Set when the root disk of the running VM was moved to another storage pool.
+	// The volume left on the source pool is removed once the VM stops.
+	// ---
+	//  type: string
+	//  shortdesc: Storage pool holding the root volume the VM was moved from while running
+	"volatile.storage_move.source_pool": validate.IsAny,
+
 	// lxdmeta:generate(entities=instance; group=volatile; key=volatile.vsock_id)
 	//
 	// ---
@@ -1263,6 +1504,60 @@ func ConfigKeyChecker(key string, instanceType Type) (func(value string) error,
 		return validate.Optional(validate.IsUserSSHKey), nil
 	}
 
+	if (instanceType == Any || instanceType == VM) && strings.HasPrefix(key, "limits.numa.nodes.") {
+		nodeID, nodeKey, found := strings.Cut(strings.TrimPrefix(key, "limits.numa.nodes."), ".")
+
+		// Only accept the canonical form of node IDs (no leading zeros or sign), so that each node has a single key.
+		id, err := strconv.ParseUint(nodeID, 10, 8)
+		if found && err == nil && strconv.FormatUint(id, 10) == nodeID {
+			switch nodeKey {
+			// lxdmeta:generate(entities=instance; group=resource-limits; key=limits.numa.nodes.<id>.cpus)
+			// A comma-separated list of vCPU IDs or ranges (for example, `0-3`) that belong to the guest NUMA node.
+			// The vCPU IDs range from `0` to the value of {config:option}`instance-resource-limits:limits.cpu` minus one.
+			//
+			// See {ref}`instance-options-limits-numa-vm` for more information.
+			// ---
+			//  type: string
+			//  liveupdate: no
+			//  condition: virtual machine
+			//  shortdesc: vCPUs of the guest NUMA node
+			case "cpus":
+				return validate.Optional(validate.IsValidCPUSet), nil
+
+			// lxdmeta:generate(entities=instance; group=resource-limits; key=limits.numa.nodes.<id>.memory)
+			// The memory sizes of all guest NUMA nodes must add up to {config:option}`instance-resource-limits:limits.memory`.
+			// ---
+			//  type: string
+			//  liveupdate: no
+			//  condition: virtual machine
+			//  shortdesc: Memory size of the guest NUMA node
+			case "memory":
+				return validate.Optional(validate.IsSize), nil
+
+			// lxdmeta:generate(entities=instance; group=resource-limits; key=limits.numa.nodes.<id>.host_node)
+			// If set, the memory of the guest NUMA node is allocated from (and bound to) that host NUMA node.
+			// ---
+			//  type: integer
+			//  liveupdate: no
+			//  condition: virtual machine
+			//  shortdesc: Host NUMA node backing the memory of the guest NUMA node
+			case "host_node":
+				return validate.Optional(validate.IsUint32), nil
+
+			// lxdmeta:generate(entities=instance; group=resource-limits; key=limits.numa.nodes.<id>.distances)
+			// A comma-separated list of distances from this guest NUMA node to every guest NUMA node, in node ID order
+			// (for example, `10,20`). The distance to the node itself must be `10`.
+			// ---
+			//  type: string
+			//  liveupdate: no
+			//  condition: virtual machine
+			//  shortdesc: Distances to the other guest NUMA nodes
+			case "distances":
+				return validate.Optional(validate.IsListOf(validate.IsInRange(10, 255))), nil
+			}
+		}
+	}
+
 	if strings.HasPrefix(key, ConfigVolatilePrefix) {
 		// lxdmeta:generate(entities=instance; group=volatile; key=volatile.<name>.last_state.hwaddr)
 		// The original MAC that was used when moving a physical device into an instance.
@@ -1397,6 +1692,15 @@ func ConfigKeyChecker(key string, instanceType Type) (func(value string) error,
 			return validate.IsAny, nil
 		}
 
+		// lxdmeta:generate(entities=instance; group=volatile; key=volatile.<name>.last_state.serial.path)
+		// Host path of the unix socket, log file or pseudo terminal of a `serial` device.
+		// ---
+		//  type: string
+		//  shortdesc: Host path of the serial port
+		if strings.HasSuffix(key, ".last_state.serial.path") {
+			return validate.IsAny, nil
+		}
+
 		if strings.HasSuffix(key, ".uuid") {
 			return validate.IsAny, nil
 		}
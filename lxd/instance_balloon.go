@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/instance/instancetype"
+	"github.com/canonical/lxd/lxd/state"
+	"github.com/canonical/lxd/lxd/task"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// instancesMemoryBalloonUpdate adjusts the memory balloon of the local running VMs with limits.memory.balloon set to auto.
+func instancesMemoryBalloonUpdate(ctx context.Context, s *state.State) {
+	instances, err := instance.LoadNodeAll(s, instancetype.VM)
+	if err != nil {
+		logger.Warn("Failed loading instances for memory balloon update", logger.Ctx{"err": err})
+		return
+	}
+
+	for _, inst := range instances {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if inst.ExpandedConfig()["limits.memory.balloon"] != instancetype.MemoryBalloonModeAuto || !inst.IsRunning() {
+			continue
+		}
+
+		vm, ok := inst.(instance.VM)
+		if !ok {
+			continue
+		}
+
+		err = vm.MemoryBalloonUpdate()
+		if err != nil {
+			logger.Debug("Failed updating memory balloon", logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "err": err})
+		}
+	}
+}
+
+func instancesMemoryBalloonUpdateTask(stateFunc func() *state.State) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		s := stateFunc()
+
+		instancesMemoryBalloonUpdate(ctx, s)
+	}
+
+	return f, task.Every(30*time.Second, task.SkipFirst)
+}
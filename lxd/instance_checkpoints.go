@@ -0,0 +1,575 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+
+	"github.com/canonical/lxd/lxd/cluster"
+	"github.com/canonical/lxd/lxd/db/operationtype"
+	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/instance/instancetype"
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/state"
+	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/validate"
+	"github.com/canonical/lxd/shared/version"
+	"github.com/canonical/lxd/shared/ws"
+)
+
+// instanceCheckpointVM loads the local VM targeted by a checkpoint request.
+// Returns a non-nil response if the request was forwarded to another member or failed.
+func instanceCheckpointVM(s *state.State, r *http.Request) (instance.VM, response.Response) {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	projectName := request.ProjectParam(r)
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	if shared.IsSnapshot(name) {
+		return nil, response.BadRequest(errors.New("Invalid instance name"))
+	}
+
+	// Handle requests targeted to an instance on a different node.
+	resp, err := forwardedResponseIfInstanceIsRemote(r.Context(), s, projectName, name, instanceType)
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	if resp != nil {
+		return nil, resp
+	}
+
+	inst, err := instance.LoadByProjectAndName(s, projectName, name)
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	vm, ok := inst.(instance.VM)
+	if !ok || inst.Type() != instancetype.VM {
+		return nil, response.BadRequest(errors.New("Checkpoints are supported for VM type instances only"))
+	}
+
+	return vm, nil
+}
+
+// swagger:operation GET /1.0/instances/{name}/checkpoints instances instance_checkpoints_get
+//
+//	Get the checkpoints
+//
+//	Returns a list of instance checkpoints (URLs).
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of endpoints
+//	          items:
+//	            type: string
+//	          example: |-
+//	            [
+//	              "/1.0/instances/foo/checkpoints/checkpoint0",
+//	              "/1.0/instances/foo/checkpoints/checkpoint1"
+//	            ]
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation GET /1.0/instances/{name}/checkpoints?recursion=1 instances instance_checkpoints_get_recursion1
+//
+//	Get the checkpoints
+//
+//	Returns a list of instance checkpoints (structs).
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of instance checkpoints
+//	          items:
+//	            $ref: "#/definitions/InstanceCheckpoint"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instanceCheckpointsGet(d *Daemon, r *http.Request) response.Response {
+	vm, resp := instanceCheckpointVM(d.State(), r)
+	if resp != nil {
+		return resp
+	}
+
+	recursion, _ := util.IsRecursionRequest(r)
+
+	checkpoints, err := vm.Checkpoints()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if recursion == 0 {
+		urls := make([]string, 0, len(checkpoints))
+		for _, checkpoint := range checkpoints {
+			urls = append(urls, api.NewURL().Path(version.APIVersion, "instances", vm.Name(), "checkpoints", checkpoint.Name).String())
+		}
+
+		return response.SyncResponse(true, urls)
+	}
+
+	return response.SyncResponse(true, checkpoints)
+}
+
+// swagger:operation POST /1.0/instances/{name}/checkpoints instances instance_checkpoints_post
+//
+//	Create a checkpoint
+//
+//	Starts tracking the blocks changed on the disks of a running VM from this point in time.
+//	The VM must have been started with `checkpoints.enabled` set.
+//	Checkpoints are stored in persistent dirty bitmaps and kept across VM restarts until deleted.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: body
+//	    name: checkpoint
+//	    description: Checkpoint request
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/InstanceCheckpointsPost"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instanceCheckpointsPost(d *Daemon, r *http.Request) response.Response {
+	vm, resp := instanceCheckpointVM(d.State(), r)
+	if resp != nil {
+		return resp
+	}
+
+	req := api.InstanceCheckpointsPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = validate.IsDeviceName(req.Name)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = vm.CheckpointCreate(req)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// swagger:operation GET /1.0/instances/{name}/checkpoints/{checkpoint} instances instance_checkpoint_get
+//
+//	Get the checkpoint
+//
+//	Gets a specific instance checkpoint.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: Instance checkpoint
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/InstanceCheckpoint"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instanceCheckpointGet(d *Daemon, r *http.Request) response.Response {
+	vm, resp := instanceCheckpointVM(d.State(), r)
+	if resp != nil {
+		return resp
+	}
+
+	checkpointName, err := url.PathUnescape(mux.Vars(r)["checkpointName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	checkpoint, err := vm.Checkpoint(checkpointName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, checkpoint)
+}
+
+// swagger:operation DELETE /1.0/instances/{name}/checkpoints/{checkpoint} instances instance_checkpoint_delete
+//
+//	Delete a checkpoint
+//
+//	Stops tracking changes for the checkpoint.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instanceCheckpointDelete(d *Daemon, r *http.Request) response.Response {
+	vm, resp := instanceCheckpointVM(d.State(), r)
+	if resp != nil {
+		return resp
+	}
+
+	checkpointName, err := url.PathUnescape(mux.Vars(r)["checkpointName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = vm.CheckpointDelete(checkpointName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+type checkpointExportWs struct {
+	// NBD connection to the export
+	nbdConn io.ReadWriteCloser
+
+	// secret used to connect the websocket
+	secret string
+
+	// channel to wait until the websocket is connected
+	connected chan *websocket.Conn
+}
+
+// Metadata returns a map of metadata.
+func (s *checkpointExportWs) Metadata() map[string]any {
+	return map[string]any{"fds": map[string]string{"0": s.secret}}
+}
+
+// Connect connects to the websocket.
+func (s *checkpointExportWs) Connect(op *operations.Operation, r *http.Request, w http.ResponseWriter) error {
+	err := op.CheckRequestor(r)
+	if err != nil {
+		return err
+	}
+
+	secret := r.FormValue("secret")
+	if secret == "" {
+		return errors.New("missing secret")
+	}
+
+	// If the user provided a bad secret, return 403, not 404, since this operation actually exists.
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(s.secret)) != 1 {
+		return os.ErrPermission
+	}
+
+	conn, err := ws.Upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case s.connected <- conn:
+	default:
+		_ = conn.Close()
+		return errors.New("Export websocket is already connected")
+	}
+
+	return nil
+}
+
+// Do waits for the websocket to connect and mirrors it with the NBD connection until either side disconnects.
+func (s *checkpointExportWs) Do(ctx context.Context, _ *operations.Operation) error {
+	defer func() { _ = s.nbdConn.Close() }()
+
+	var conn *websocket.Conn
+	select {
+	case conn = <-s.connected:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	defer logger.Debug("Checkpoint export websocket finished")
+
+	readDone, writeDone := ws.Mirror(conn, s.nbdConn)
+
+	select {
+	case <-readDone:
+	case <-writeDone:
+	case <-ctx.Done():
+	}
+
+	// Closing both ends terminates the remaining mirror.
+	_ = s.nbdConn.Close()
+	_ = conn.Close()
+
+	<-readDone
+	<-writeDone
+
+	return nil
+}
+
+// swagger:operation POST /1.0/instances/{name}/checkpoints/{checkpoint}/export instances instance_checkpoint_export_post
+//
+//	Export a checkpoint
+//
+//	Exports a disk of the running VM read-only over NBD along with the blocks changed since the checkpoint.
+//
+//	The returned operation metadata will contain a single websocket carrying the NBD protocol. The export
+//	is named after the disk device and the changed blocks are exposed through the
+//	"qemu:dirty-bitmap:lxd_checkpoint_<checkpoint>" metadata context.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: body
+//	    name: export
+//	    description: Checkpoint export request
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/InstanceCheckpointExportPost"
+//	responses:
+//	  "202":
+//	    $ref: "#/responses/Operation"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instanceCheckpointExportPost(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	projectName := request.ProjectParam(r)
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	checkpointName, err := url.PathUnescape(mux.Vars(r)["checkpointName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if shared.IsSnapshot(name) {
+		return response.BadRequest(errors.New("Invalid instance name"))
+	}
+
+	req := api.InstanceCheckpointExportPost{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Forward the request if the instance is remote.
+	client, err := cluster.ConnectIfInstanceIsRemote(r.Context(), s, projectName, name, instanceType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if client != nil {
+		url := api.NewURL().Path(version.APIVersion, "instances", name, "checkpoints", checkpointName, "export").Project(projectName)
+		resp, _, err := client.RawQuery(http.MethodPost, url.String(), req, "")
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		opAPI, err := resp.MetadataAsOperation()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return operations.ForwardedOperationResponse(opAPI)
+	}
+
+	if req.Disk == "" {
+		return response.BadRequest(errors.New("No disk specified"))
+	}
+
+	inst, err := instance.LoadByProjectAndName(s, projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	vm, ok := inst.(instance.VM)
+	if !ok || inst.Type() != instancetype.VM {
+		return response.BadRequest(errors.New("Checkpoints are supported for VM type instances only"))
+	}
+
+	exportWs := &checkpointExportWs{}
+	exportWs.connected = make(chan *websocket.Conn, 1)
+	exportWs.secret, err = shared.RandomCryptoString()
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	exportWs.nbdConn, err = vm.CheckpointExport(checkpointName, req.Disk)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	instanceURL := api.NewURL().Path(version.APIVersion, "instances", inst.Name()).Project(projectName)
+	args := operations.OperationArgs{
+		ProjectName: projectName,
+		EntityURL:   instanceURL,
+		Type:        operationtype.CheckpointExport,
+		Class:       operations.OperationClassWebsocket,
+		Metadata:    exportWs.Metadata(),
+		RunHook:     exportWs.Do,
+		ConnectHook: exportWs.Connect,
+		Resources: map[entity.Type][]api.URL{
+			entity.TypeInstance: {*instanceURL},
+		},
+	}
+
+	op, err := operations.ScheduleUserOperationFromRequest(s, r, args)
+	if err != nil {
+		_ = exportWs.nbdConn.Close()
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
@@ -598,6 +598,8 @@ func validLogFileName(fname string) bool {
 	 */
 	return fname == "lxc.conf" ||
 		fname == "qemu.conf" ||
+		fname == securityAuditLogFile ||
+		fname == securityAuditLogFile+".old" ||
 		slices.Contains(instanceProtectedLogFiles, fname)
 }
 
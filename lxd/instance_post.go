@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -19,6 +20,7 @@ import (
 	"github.com/canonical/lxd/lxd/db"
 	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
 	"github.com/canonical/lxd/lxd/db/operationtype"
+	deviceConfig "github.com/canonical/lxd/lxd/device/config"
 	"github.com/canonical/lxd/lxd/instance"
 	"github.com/canonical/lxd/lxd/instance/instancetype"
 	"github.com/canonical/lxd/lxd/operations"
@@ -30,6 +32,8 @@ import (
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/revert"
 	"github.com/canonical/lxd/shared/version"
 )
 
@@ -576,6 +580,9 @@ func instancePostMigration(ctx context.Context, s *state.State, inst instance.In
 		req.Name = sourceName
 	}
 
+	liveStorageMove := inst.Type() == instancetype.VM && inst.IsRunning() && req.Live && req.Pool != "" && targetMemberInfo == nil &&
+		req.Name == sourceName && req.Project == sourceProject && req.Config == nil && req.Devices == nil && req.Profiles == nil
+
 	// Copy config from instance to avoid modifying it.
 	localConfig := make(map[string]string)
 	maps.Copy(localConfig, inst.LocalConfig())
@@ -692,6 +699,34 @@ func instancePostMigration(ctx context.Context, s *state.State, inst instance.In
 		return migrateInstance(ctx, s, inst, targetMemberInfo.Name, targetGroupName, req, &targetArgs, op)
 	}
 
+	// Running VMs that only change storage pool can have their root disk mirrored without being stopped.
+	// Snapshots can't be mirrored so instances that have some go through the stateful stop below instead.
+	// The operation metadata records which of the two happens.
+	if liveStorageMove {
+		snapshots, err := inst.Snapshots()
+		if err != nil {
+			return err
+		}
+
+		if len(snapshots) == 0 {
+			err = op.ExtendMetadata(map[string]any{"storage_move": "live"})
+			if err != nil {
+				return err
+			}
+
+			return instancePostLiveStorageMove(ctx, s, inst, localDevices, rootDevKey, op)
+		}
+
+		if shared.IsFalseOrEmpty(inst.ExpandedConfig()["migration.stateful"]) {
+			return api.StatusErrorf(http.StatusBadRequest, "Running virtual machines with snapshots can only be moved to another storage pool with migration.stateful enabled, stop the instance first")
+		}
+
+		err = op.ExtendMetadata(map[string]any{"storage_move": "stateful"})
+		if err != nil {
+			return err
+		}
+	}
+
 	statefulStart := false
 	if inst.IsRunning() {
 		if !req.Live {
@@ -761,6 +796,115 @@ func instancePostMigration(ctx context.Context, s *state.State, inst instance.In
 	return nil
 }
 
+// instancePostLiveStorageMove moves the root disk of a running VM to the pool of its new root disk device.
+// The disk is copied and mirrored while the VM runs, the volume left on the source pool is removed once it stops.
+func instancePostLiveStorageMove(ctx context.Context, s *state.State, inst instance.Instance, localDevices deviceConfig.Devices, rootDevKey string, op *operations.Operation) error {
+	vm, ok := inst.(instance.VM)
+	if !ok {
+		return errors.New("Instance is not a virtual machine")
+	}
+
+	srcPool, err := storagePools.LoadByInstance(s, inst)
+	if err != nil {
+		return err
+	}
+
+	targetPool, err := storagePools.LoadByName(s, localDevices[rootDevKey]["pool"])
+	if err != nil {
+		return err
+	}
+
+	if srcPool.Name() == targetPool.Name() {
+		return api.StatusErrorf(http.StatusBadRequest, "Instance is already on storage pool %q", targetPool.Name())
+	}
+
+	if inst.LocalConfig()["volatile.storage_move.source_pool"] != "" {
+		return api.StatusErrorf(http.StatusBadRequest, "Instance must be restarted to complete its previous storage move first")
+	}
+
+	startTime := time.Now()
+	progress := func(current int64, total int64) {
+		if total <= 0 {
+			return
+		}
+
+		var speed int64
+		elapsed := int64(time.Since(startTime).Seconds())
+		if elapsed > 0 {
+			speed = current / elapsed
+		}
+
+		_ = op.UpdateProgress("mirror_disk", "Mirroring root disk", current*100/total, current, speed)
+	}
+
+	// Do not store initial.* device config keys in database.
+	localDevices.CutInitialConfig()
+
+	updateDevices := func(devices deviceConfig.Devices) error {
+		return s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+			dbDevices, err := dbCluster.APIToDevices(devices.CloneNative())
+			if err != nil {
+				return err
+			}
+
+			return dbCluster.UpdateInstanceDevices(ctx, tx.Tx(), int64(inst.ID()), dbDevices)
+		})
+	}
+
+	err = targetPool.MoveInstanceLive(inst, func(diskPath string) error {
+		reverter := revert.New()
+		defer reverter.Fail()
+
+		// Record the new root disk pool before switching over so that the new volume is unmounted if the VM
+		// stops in the meantime.
+		err := updateDevices(localDevices)
+		if err != nil {
+			return fmt.Errorf("Failed updating instance root disk pool: %w", err)
+		}
+
+		reverter.Add(func() {
+			oldDevices := inst.LocalDevices().Clone()
+			oldDevices.CutInitialConfig()
+
+			err := updateDevices(oldDevices)
+			if err != nil {
+				logger.Error("Failed restoring instance root disk pool", logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "err": err})
+			}
+		})
+
+		err = vm.MirrorDisk(rootDevKey, diskPath, progress)
+		if err != nil {
+			return err
+		}
+
+		reverter.Success()
+
+		// The VM now uses the new volume so don't fail from here on, that would remove the volume.
+		err = inst.VolatileSet(map[string]string{"volatile.storage_move.source_pool": srcPool.Name()})
+		if err != nil {
+			logger.Warn("Failed recording storage move source pool", logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "pool": srcPool.Name(), "err": err})
+		}
+
+		return nil
+	}, op)
+	if err != nil {
+		return err
+	}
+
+	// Reload the instance to pick up its new devices and record them in its backup file.
+	inst, err = instance.LoadByProjectAndName(s, inst.Project().Name, inst.Name())
+	if err != nil {
+		return err
+	}
+
+	err = inst.UpdateBackupFile()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // Migrate an instance to another cluster node (supports both local and remote storage).
 // Source and target members must be online.
 func instancePostClusteringMigrate(s *state.State, srcPool storagePools.Pool, srcInst instance.Instance, req api.InstancePost, targetArgs *db.InstanceArgs, srcMember db.NodeInfo, newMember db.NodeInfo, targetGroupName string) (func(ctx context.Context, op *operations.Operation) error, error) {
@@ -780,6 +924,14 @@ func instancePostClusteringMigrate(s *state.State, srcPool storagePools.Pool, sr
 	stateful := req.Live
 	allowInconsistent := req.AllowInconsistent
 
+	// Check that the volumes can be used by the source and the destination member at the same time.
+	if stateful && srcInst.IsRunning() && srcInst.Type() == instancetype.VM {
+		err := storagePools.InstanceCheckLiveMigrationPools(s, srcPool, srcInst)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Check we can convert the instance to the volume types needed.
 	volType, err := storagePools.InstanceTypeToVolumeType(srcInst.Type())
 	if err != nil {
@@ -0,0 +1,610 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/lxd/lxd/auth"
+	"github.com/canonical/lxd/lxd/db"
+	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/db/operationtype"
+	deviceConfig "github.com/canonical/lxd/lxd/device/config"
+	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/instance/instancetype"
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/state"
+	storagePools "github.com/canonical/lxd/lxd/storage"
+	"github.com/canonical/lxd/lxd/task"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/osarch"
+	"github.com/canonical/lxd/shared/version"
+)
+
+var instanceReplicationCmd = APIEndpoint{
+	Name:        "instanceReplication",
+	Path:        "instances/{name}/replication",
+	MetricsType: entity.TypeInstance,
+
+	Get:  APIEndpointAction{Handler: instanceReplicationGet, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanView, "name")},
+	Post: APIEndpointAction{Handler: instanceReplicationPost, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanEdit, "name")},
+}
+
+// instanceReplicationRunning tracks the IDs of the instances whose replica is being synchronized.
+var instanceReplicationRunning = sync.Map{}
+
+// instanceReplicationLoad loads the instance of a replication request, or returns a response if the request must
+// be forwarded to the member that holds the instance.
+func instanceReplicationLoad(s *state.State, r *http.Request) (instance.Instance, response.Response) {
+	projectName := request.ProjectParam(r)
+
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	if shared.IsSnapshot(name) {
+		return nil, response.BadRequest(errors.New("Invalid instance name"))
+	}
+
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	// Handle requests targeted to an instance on a different member.
+	resp, err := forwardedResponseIfInstanceIsRemote(r.Context(), s, projectName, name, instanceType)
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	if resp != nil {
+		return nil, resp
+	}
+
+	inst, err := instance.LoadByProjectAndName(s, projectName, name)
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	return inst, nil
+}
+
+// swagger:operation GET /1.0/instances/{name}/replication instances instance_replication_get
+//
+//	Get the instance replication state
+//
+//	Gets the role of the instance in a replication, the instance at the other end and the replication lag.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: Instance replication state
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/InstanceReplication"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instanceReplicationGet(d *Daemon, r *http.Request) response.Response {
+	inst, resp := instanceReplicationLoad(d.State(), r)
+	if resp != nil {
+		return resp
+	}
+
+	return response.SyncResponse(true, instanceReplicationState(inst.Name(), inst.LocalConfig()))
+}
+
+// swagger:operation POST /1.0/instances/{name}/replication instances instance_replication_post
+//
+//	Run an instance replication action
+//
+//	Runs an action against the replication of an instance.
+//	The `sync` action of a source instance creates or refreshes its replica in the background.
+//	The `promote` action of a replica detaches it from its source so it can be used in place of the source instance.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: body
+//	    name: action
+//	    description: Action to run
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/InstanceReplicationPost"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "202":
+//	    $ref: "#/responses/Operation"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instanceReplicationPost(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	inst, resp := instanceReplicationLoad(s, r)
+	if resp != nil {
+		return resp
+	}
+
+	req := api.InstanceReplicationPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	replication := instanceReplicationState(inst.Name(), inst.LocalConfig())
+
+	switch req.Action {
+	case "sync":
+		if replication.Role != api.StorageVolumeReplicationRoleSource {
+			return response.BadRequest(fmt.Errorf("Instance %q isn't a replication source", inst.Name()))
+		}
+
+		run := func(ctx context.Context, op *operations.Operation) error {
+			return instanceReplicate(s, inst, op)
+		}
+
+		instanceURL := api.NewURL().Path(version.APIVersion, "instances", inst.Name()).Project(inst.Project().Name)
+
+		args := operations.OperationArgs{
+			ProjectName: inst.Project().Name,
+			Type:        operationtype.InstanceReplicate,
+			Class:       operations.OperationClassTask,
+			RunHook:     run,
+			EntityURL:   instanceURL,
+			Resources: map[entity.Type][]api.URL{
+				entity.TypeInstance: {*instanceURL},
+			},
+		}
+
+		op, err := operations.ScheduleUserOperationFromRequest(s, r, args)
+		if err != nil {
+			return response.InternalError(err)
+		}
+
+		return operations.OperationResponse(op)
+
+	case "promote":
+		if replication.Role != api.StorageVolumeReplicationRoleReplica {
+			return response.BadRequest(fmt.Errorf("Instance %q isn't a replica", inst.Name()))
+		}
+
+		// Removing the source marker stops further synchronizations from overwriting the instance.
+		err = inst.VolatileSet(map[string]string{
+			"volatile.replication.source":    "",
+			"volatile.replication.last_sync": "",
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	return response.BadRequest(fmt.Errorf("Unknown action %q", req.Action))
+}
+
+// instanceReplicaName returns the name of the replica of an instance.
+func instanceReplicaName(instName string, config map[string]string) string {
+	if config["replication.target_instance"] != "" {
+		return config["replication.target_instance"]
+	}
+
+	return instName
+}
+
+// instanceReplicationState returns the replication state of an instance from its local config.
+func instanceReplicationState(instName string, config map[string]string) *api.InstanceReplication {
+	replication := &api.InstanceReplication{Lag: -1}
+
+	if config["replication.target_pool"] != "" {
+		replication.Role = api.StorageVolumeReplicationRoleSource
+		replication.Remote = config["replication.target_remote"]
+		replication.Pool = config["replication.target_pool"]
+		replication.Instance = instanceReplicaName(instName, config)
+		replication.Schedule = config["replication.schedule"]
+	} else if config["volatile.replication.source"] != "" {
+		replication.Role = api.StorageVolumeReplicationRoleReplica
+		replication.Remote, replication.Instance = replicationSourceParse(config["volatile.replication.source"])
+	} else {
+		return replication
+	}
+
+	replication.LastSync, replication.Lag = replicationLastSync(config)
+
+	return replication
+}
+
+// instanceReplicaConfig returns the local config of the replica of an instance with the given local config.
+// The volatile keys of an existing replica are kept, so that refreshing it doesn't change its identity. The replica
+// doesn't replicate any further, records where it comes from and never starts on its own.
+func instanceReplicaConfig(sourceConfig map[string]string, replicaConfig map[string]string, source string) map[string]string {
+	config := make(map[string]string, len(sourceConfig))
+	maps.Copy(config, sourceConfig)
+
+	if replicaConfig == nil {
+		api.InstanceRemoteCopyConfigKeyPolicy.Apply(config, nil)
+	} else {
+		maps.DeleteFunc(config, func(key string, _ string) bool {
+			return strings.HasPrefix(key, instancetype.ConfigVolatilePrefix)
+		})
+
+		for key, value := range replicaConfig {
+			if strings.HasPrefix(key, instancetype.ConfigVolatilePrefix) {
+				config[key] = value
+			}
+		}
+	}
+
+	maps.DeleteFunc(config, func(key string, _ string) bool {
+		return replicationIsConfigKey(key)
+	})
+
+	config["volatile.replication.source"] = source
+	config["boot.autostart"] = "false"
+
+	return config
+}
+
+// instanceReplicaDevices returns the local devices of the replica of an instance, with a root disk in poolName.
+func instanceReplicaDevices(inst instance.Instance, poolName string) (deviceConfig.Devices, error) {
+	rootDiskName, rootDisk, err := api.GetRootDiskDevice(inst.ExpandedDevices().CloneNative())
+	if err != nil {
+		return nil, err
+	}
+
+	devices := inst.LocalDevices().Clone()
+	rootDisk["pool"] = poolName
+	devices[rootDiskName] = rootDisk
+
+	return devices, nil
+}
+
+// instanceReplicate creates or refreshes the replica of an instance, including its snapshots.
+// An existing instance is only refreshed if it is a stopped replica of the source instance, so promoted replicas
+// are never overwritten.
+func instanceReplicate(s *state.State, inst instance.Instance, op *operations.Operation) error {
+	config := inst.LocalConfig()
+
+	targetPoolName := config["replication.target_pool"]
+	if targetPoolName == "" {
+		return api.StatusErrorf(http.StatusBadRequest, "Instance %q isn't a replication source", inst.Name())
+	}
+
+	remote := config["replication.target_remote"] != ""
+	targetName := instanceReplicaName(inst.Name(), config)
+	if !remote && targetName == inst.Name() {
+		return api.StatusErrorf(http.StatusBadRequest, "Instance %q cannot be replicated onto itself, set %q", inst.Name(), "replication.target_instance")
+	}
+
+	_, loaded := instanceReplicationRunning.LoadOrStore(inst.ID(), struct{}{})
+	if loaded {
+		return api.StatusErrorf(http.StatusConflict, "Replication of instance %q is already running", inst.Name())
+	}
+
+	defer instanceReplicationRunning.Delete(inst.ID())
+
+	source := replicationSource(s, remote, inst.Name())
+	lastSync := time.Now().UTC().Format(time.RFC3339)
+
+	var err error
+	if remote {
+		err = instanceReplicateRemote(s, inst, targetPoolName, targetName, source, lastSync, op)
+	} else {
+		err = instanceReplicateLocal(s, inst, targetPoolName, targetName, source, lastSync, op)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Record the synchronization time on the source instance so the lag can be reported.
+	err = inst.VolatileSet(map[string]string{"volatile.replication.last_sync": lastSync})
+	if err != nil {
+		return fmt.Errorf("Failed recording replication time of instance %q: %w", inst.Name(), err)
+	}
+
+	return nil
+}
+
+// instanceReplicateLocal creates or refreshes the replica of an instance on this cluster member.
+func instanceReplicateLocal(s *state.State, inst instance.Instance, targetPoolName string, targetName string, source string, lastSync string, op *operations.Operation) error {
+	_, err := storagePools.LoadByName(s, targetPoolName)
+	if err != nil {
+		return fmt.Errorf("Failed loading replication target pool %q: %w", targetPoolName, err)
+	}
+
+	replica, err := instance.LoadByProjectAndName(s, inst.Project().Name, targetName)
+	if err != nil && !response.IsNotFoundError(err) {
+		return err
+	}
+
+	var replicaConfig map[string]string
+	if replica != nil {
+		if replica.LocalConfig()["volatile.replication.source"] != source {
+			return api.StatusErrorf(http.StatusConflict, "Instance %q isn't a replica of instance %q", targetName, inst.Name())
+		}
+
+		if replica.IsRunning() {
+			return api.StatusErrorf(http.StatusConflict, "Replica %q of instance %q is running", targetName, inst.Name())
+		}
+
+		replicaConfig = replica.LocalConfig()
+	}
+
+	devices, err := instanceReplicaDevices(inst, targetPoolName)
+	if err != nil {
+		return err
+	}
+
+	args := db.InstanceArgs{
+		Project:      inst.Project().Name,
+		Architecture: inst.Architecture(),
+		Config:       instanceReplicaConfig(inst.LocalConfig(), replicaConfig, source),
+		Type:         inst.Type(),
+		Description:  inst.Description(),
+		Devices:      devices,
+		Ephemeral:    inst.IsEphemeral(),
+		Name:         targetName,
+		Profiles:     inst.Profiles(),
+	}
+
+	replica, err = instanceCreateAsCopy(s, instanceCreateAsCopyOpts{
+		sourceInstance: inst,
+		targetInstance: args,
+		refresh:        replica != nil,
+	}, op)
+	if err != nil {
+		return fmt.Errorf("Failed synchronizing replica %q: %w", targetName, err)
+	}
+
+	// Record the synchronization time on the replica so the lag can also be reported from its side.
+	err = replica.VolatileSet(map[string]string{"volatile.replication.last_sync": lastSync})
+	if err != nil {
+		return fmt.Errorf("Failed recording replication time of instance %q: %w", targetName, err)
+	}
+
+	return nil
+}
+
+// instanceReplicateRemote creates or refreshes the replica of an instance on a remote server by pushing the
+// instance with the migration protocol.
+func instanceReplicateRemote(s *state.State, inst instance.Instance, targetPoolName string, targetName string, source string, lastSync string, op *operations.Operation) error {
+	address := inst.LocalConfig()["replication.target_remote"]
+
+	client, certPEM, err := replicationRemoteConnect(context.TODO(), s, inst.LocalConfig())
+	if err != nil {
+		return err
+	}
+
+	client = client.UseProject(inst.Project().Name)
+
+	replica, _, err := client.GetInstance(targetName)
+	if err != nil && !api.StatusErrorCheck(err, http.StatusNotFound) {
+		return fmt.Errorf("Failed getting replica %q on remote %q: %w", targetName, address, err)
+	}
+
+	var replicaConfig map[string]string
+	if replica != nil {
+		if replica.Config["volatile.replication.source"] != source {
+			return api.StatusErrorf(http.StatusConflict, "Instance %q on remote %q isn't a replica of instance %q", targetName, address, inst.Name())
+		}
+
+		if replica.StatusCode != api.Stopped {
+			return api.StatusErrorf(http.StatusConflict, "Replica %q of instance %q on remote %q is running", targetName, inst.Name(), address)
+		}
+
+		replicaConfig = replica.Config
+	}
+
+	devices, err := instanceReplicaDevices(inst, targetPoolName)
+	if err != nil {
+		return err
+	}
+
+	architecture, err := osarch.ArchitectureName(inst.Architecture())
+	if err != nil {
+		return err
+	}
+
+	profileNames := make([]string, 0, len(inst.Profiles()))
+	for _, profile := range inst.Profiles() {
+		profileNames = append(profileNames, profile.Name)
+	}
+
+	req := api.InstancesPost{
+		Name: targetName,
+		Type: api.InstanceType(inst.Type().String()),
+		InstancePut: api.InstancePut{
+			Architecture: architecture,
+			Config:       instanceReplicaConfig(inst.LocalConfig(), replicaConfig, source),
+			Devices:      devices.CloneNative(),
+			Ephemeral:    inst.IsEphemeral(),
+			Profiles:     profileNames,
+			Description:  inst.Description(),
+		},
+		Source: api.InstanceSource{
+			Type:      api.SourceTypeMigration,
+			Mode:      "push",
+			BaseImage: inst.LocalConfig()["volatile.base_image"],
+			Refresh:   replica != nil,
+		},
+	}
+
+	targetOp, err := client.CreateInstance(req)
+	if err != nil {
+		return fmt.Errorf("Failed creating replica %q on remote %q: %w", targetName, address, err)
+	}
+
+	opURL, secrets := replicationPushTarget(address, targetOp)
+	ws, err := newMigrationSource(inst, false, false, false, "", &api.InstancePostTarget{Certificate: certPEM, Operation: opURL, Websockets: secrets})
+	if err != nil {
+		_ = targetOp.Cancel()
+		return err
+	}
+
+	err = ws.Do(s, op)
+	if err != nil {
+		_ = targetOp.Cancel()
+		return fmt.Errorf("Failed synchronizing replica %q on remote %q: %w", targetName, address, err)
+	}
+
+	err = targetOp.Wait()
+	if err != nil {
+		return fmt.Errorf("Failed synchronizing replica %q on remote %q: %w", targetName, address, err)
+	}
+
+	// Record the synchronization time on the replica so the lag can also be reported from its side.
+	replica, etag, err := client.GetInstance(targetName)
+	if err != nil {
+		return err
+	}
+
+	put := replica.Writable()
+	put.Config["volatile.replication.last_sync"] = lastSync
+
+	updateOp, err := client.UpdateInstance(targetName, put, etag)
+	if err != nil {
+		return fmt.Errorf("Failed recording replication time of instance %q on remote %q: %w", targetName, address, err)
+	}
+
+	return updateOp.Wait()
+}
+
+func autoReplicateInstancesTask(stateFunc func() *state.State) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		s := stateFunc()
+
+		var instances []instance.Instance
+
+		// Instances are replicated by the member that holds them.
+		filter := dbCluster.InstanceFilter{Node: &s.ServerName}
+
+		err := s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+			return tx.InstanceList(ctx, func(dbInst db.InstanceArgs, p api.Project) error {
+				if dbInst.Config["replication.target_pool"] == "" {
+					return nil
+				}
+
+				schedule := dbInst.Config["replication.schedule"]
+				if schedule == "" || !snapshotIsScheduledNow(schedule, int64(dbInst.ID)) {
+					return nil
+				}
+
+				inst, err := instance.Load(s, dbInst, p)
+				if err != nil {
+					return fmt.Errorf("Failed loading instance %q (project %q) for replication task: %w", dbInst.Name, dbInst.Project, err)
+				}
+
+				instances = append(instances, inst)
+
+				return nil
+			}, filter)
+		})
+		if err != nil {
+			logger.Error("Failed getting instance replication schedule info", logger.Ctx{"err": err})
+			return
+		}
+
+		if len(instances) == 0 {
+			return
+		}
+
+		opRun := func(ctx context.Context, op *operations.Operation) error {
+			for _, inst := range instances {
+				err := ctx.Err()
+				if err != nil {
+					return err // Stop if context is cancelled.
+				}
+
+				// A failed replication doesn't prevent the other instances from being replicated.
+				err = instanceReplicate(s, inst, op)
+				if err != nil {
+					logger.Error("Failed scheduled instance replication", logger.Ctx{"instance": inst.Name(), "project": inst.Project().Name, "err": err})
+				}
+			}
+
+			return nil
+		}
+
+		args := operations.OperationArgs{
+			Type:    operationtype.InstanceReplicationsScheduled,
+			Class:   operations.OperationClassTask,
+			RunHook: opRun,
+		}
+
+		logger.Info("Replicating scheduled instances")
+		op, err := operations.ScheduleServerOperation(s, args)
+		if err != nil {
+			logger.Error("Failed creating scheduled instance replication operation", logger.Ctx{"err": err})
+			return
+		}
+
+		err = op.Wait(ctx)
+		if err != nil {
+			logger.Error("Failed scheduled instance replications", logger.Ctx{"err": err})
+			return
+		}
+
+		logger.Info("Done replicating scheduled instances")
+	}
+
+	return f, task.Every(time.Minute, task.SkipFirst)
+}
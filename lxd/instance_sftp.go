@@ -67,9 +67,10 @@ func instanceSFTPHandler(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	resp := &sftpServeResponse{
+	resp := &instanceConnServeResponse{
 		projectName: projectName,
 		instName:    instName,
+		protocol:    "sftp",
 	}
 
 	// Forward the request if the instance is remote.
@@ -98,18 +99,20 @@ func instanceSFTPHandler(d *Daemon, r *http.Request) response.Response {
 	return resp
 }
 
-type sftpServeResponse struct {
+// instanceConnServeResponse upgrades the request to the given protocol and proxies it to an instance connection.
+type instanceConnServeResponse struct {
 	projectName string
 	instName    string
+	protocol    string
 	instConn    net.Conn
 }
 
-func (r *sftpServeResponse) String() string {
-	return "sftp handler"
+func (r *instanceConnServeResponse) String() string {
+	return r.protocol + " handler"
 }
 
 // Render renders the server response.
-func (r *sftpServeResponse) Render(w http.ResponseWriter, req *http.Request) error {
+func (r *instanceConnServeResponse) Render(w http.ResponseWriter, req *http.Request) error {
 	defer func() { _ = r.instConn.Close() }()
 
 	hijacker, ok := w.(http.Hijacker)
@@ -133,9 +136,9 @@ func (r *sftpServeResponse) Render(w http.ResponseWriter, req *http.Request) err
 		}
 	}
 
-	err = response.Upgrade(remoteConn, "sftp")
+	err = response.Upgrade(remoteConn, r.protocol)
 	if err != nil {
-		return api.StatusErrorf(http.StatusInternalServerError, "Failed upgrading SFTP connection: %w", err)
+		return api.StatusErrorf(http.StatusInternalServerError, "Failed upgrading %s connection: %w", r.protocol, err)
 	}
 
 	ctx, cancel := context.WithCancel(req.Context())
@@ -144,6 +147,7 @@ func (r *sftpServeResponse) Render(w http.ResponseWriter, req *http.Request) err
 		"instance": r.instName,
 		"local":    remoteConn.LocalAddr(),
 		"remote":   remoteConn.RemoteAddr(),
+		"protocol": r.protocol,
 		"err":      err,
 	})
 
@@ -152,7 +156,7 @@ func (r *sftpServeResponse) Render(w http.ResponseWriter, req *http.Request) err
 		_, err := io.Copy(remoteConn, r.instConn)
 		if err != nil {
 			if ctx.Err() == nil {
-				l.Warn("Failed copying SFTP instance connection to remote connection", logger.Ctx{"err": err})
+				l.Warn("Failed copying instance connection to remote connection", logger.Ctx{"err": err})
 			}
 		}
 		cancel()               // Cancel context first so when remoteConn is closed it doesn't cause a warning.
@@ -162,7 +166,7 @@ func (r *sftpServeResponse) Render(w http.ResponseWriter, req *http.Request) err
 	_, err = io.Copy(r.instConn, remoteConn)
 	if err != nil {
 		if ctx.Err() == nil {
-			l.Warn("Failed copying SFTP remote connection to instance connection", logger.Ctx{"err": err})
+			l.Warn("Failed copying remote connection to instance connection", logger.Ctx{"err": err})
 		}
 	}
 	cancel() // Cancel context first so when instConn is closed it doesn't cause a warning.
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceConnServeResponse(t *testing.T) {
+	instConn, lxdConn := net.Pipe()
+	rendered := make(chan error, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := &instanceConnServeResponse{
+			projectName: "default",
+			instName:    "v1",
+			protocol:    "usbredir",
+			instConn:    lxdConn,
+		}
+
+		rendered <- resp.Render(w, r)
+	}))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	defer func() { _ = conn.Close() }()
+
+	_, err = conn.Write([]byte("GET /1.0/instances/v1/usbredir HTTP/1.1\r\nHost: lxd\r\nUpgrade: usbredir\r\nConnection: Upgrade\r\n\r\n"))
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "usbredir", resp.Header.Get("Upgrade"))
+
+	// Data is proxied both ways.
+	go func() { _, _ = conn.Write([]byte("ping")) }()
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(instConn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+
+	go func() { _, _ = instConn.Write([]byte("pong")) }()
+
+	_, err = io.ReadFull(reader, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "pong", string(buf))
+
+	// Closing the client connection closes the instance connection.
+	_ = conn.Close()
+	require.NoError(t, <-rendered)
+
+	_, err = instConn.Read(buf)
+	assert.Error(t, err)
+}
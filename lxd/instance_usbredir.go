@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/lxd/lxd/cluster"
+	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/instance/instancetype"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/validate"
+)
+
+// swagger:operation GET /1.0/instances/{name}/usbredir instances instance_usbredir
+//
+//	Redirect a USB device into the instance
+//
+//	Hotplugs a redirected USB device into the virtual machine and upgrades the request to its usbredir connection.
+//	The device is unplugged from the virtual machine when the connection is closed.
+//
+//	---
+//	produces:
+//	  - application/json
+//	  - application/octet-stream
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: query
+//	    name: name
+//	    description: Name of the redirected USB device
+//	    type: string
+//	    example: yubikey
+//	responses:
+//	  "101":
+//	    description: Switching protocols to usbredir
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instanceUSBRedirHandler(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	projectName := request.ProjectParam(r)
+	instName, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if shared.IsSnapshot(instName) {
+		return response.BadRequest(errors.New("Invalid instance name"))
+	}
+
+	if r.Header.Get("Upgrade") != "usbredir" {
+		return response.SmartError(api.StatusErrorf(http.StatusBadRequest, "Missing or invalid upgrade header"))
+	}
+
+	name := request.QueryParam(r, "name")
+	err = validate.IsDeviceName(name)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid USB device name: %w", err))
+	}
+
+	// Redirect to correct server if needed.
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	resp := &instanceConnServeResponse{
+		projectName: projectName,
+		instName:    instName,
+		protocol:    "usbredir",
+	}
+
+	// Forward the request if the instance is remote.
+	client, err := cluster.ConnectIfInstanceIsRemote(r.Context(), s, projectName, instName, instanceType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if client != nil {
+		resp.instConn, err = client.GetInstanceUSBRedirConn(instName, name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return resp
+	}
+
+	inst, err := instance.LoadByProjectAndName(s, projectName, instName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	vm, ok := inst.(instance.VM)
+	if !ok || inst.Type() != instancetype.VM {
+		return response.BadRequest(errors.New("USB redirection is supported for VM type instances only"))
+	}
+
+	resp.instConn, err = vm.USBRedirConn(name)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed redirecting USB device: %w", err))
+	}
+
+	return resp
+}
@@ -57,6 +57,32 @@ var instanceUEFIVarsCmd = APIEndpoint{
 	Put: APIEndpointAction{Handler: instanceUEFIVarsPut, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanEdit, "name")},
 }
 
+var instanceCheckpointsCmd = APIEndpoint{
+	Name:        "instanceCheckpoints",
+	Path:        "instances/{name}/checkpoints",
+	MetricsType: entity.TypeInstance,
+
+	Get:  APIEndpointAction{Handler: instanceCheckpointsGet, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanView, "name")},
+	Post: APIEndpointAction{Handler: instanceCheckpointsPost, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanManageBackups, "name")},
+}
+
+var instanceCheckpointCmd = APIEndpoint{
+	Name:        "instanceCheckpoint",
+	Path:        "instances/{name}/checkpoints/{checkpointName}",
+	MetricsType: entity.TypeInstance,
+
+	Get:    APIEndpointAction{Handler: instanceCheckpointGet, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanView, "name")},
+	Delete: APIEndpointAction{Handler: instanceCheckpointDelete, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanManageBackups, "name")},
+}
+
+var instanceCheckpointExportCmd = APIEndpoint{
+	Name:        "instanceCheckpointExport",
+	Path:        "instances/{name}/checkpoints/{checkpointName}/export",
+	MetricsType: entity.TypeInstance,
+
+	Post: APIEndpointAction{Handler: instanceCheckpointExportPost, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanManageBackups, "name")},
+}
+
 var instanceRebuildCmd = APIEndpoint{
 	Name:        "instanceRebuild",
 	Path:        "instances/{name}/rebuild",
@@ -82,6 +108,14 @@ var instanceSFTPCmd = APIEndpoint{
 	Get: APIEndpointAction{Handler: instanceSFTPHandler, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanConnectSFTP, "name")},
 }
 
+var instanceUSBRedirCmd = APIEndpoint{
+	Name:        "instanceUSBRedir",
+	Path:        "instances/{name}/usbredir",
+	MetricsType: entity.TypeInstance,
+
+	Get: APIEndpointAction{Handler: instanceUSBRedirHandler, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanEdit, "name")},
+}
+
 var instanceFileCmd = APIEndpoint{
 	Name:        "instanceFile",
 	Path:        "instances/{name}/files",
@@ -18,26 +18,27 @@ type InstanceAction string
 
 // All supported lifecycle events for instances.
 const (
-	InstanceCreated          = InstanceAction(api.EventLifecycleInstanceCreated)
-	InstanceStarted          = InstanceAction(api.EventLifecycleInstanceStarted)
-	InstanceStopped          = InstanceAction(api.EventLifecycleInstanceStopped)
-	InstanceShutdown         = InstanceAction(api.EventLifecycleInstanceShutdown)
-	InstanceRestarted        = InstanceAction(api.EventLifecycleInstanceRestarted)
-	InstancePaused           = InstanceAction(api.EventLifecycleInstancePaused)
-	InstanceReady            = InstanceAction(api.EventLifecycleInstanceReady)
-	InstanceResumed          = InstanceAction(api.EventLifecycleInstanceResumed)
-	InstanceRestored         = InstanceAction(api.EventLifecycleInstanceRestored)
-	InstanceDeleted          = InstanceAction(api.EventLifecycleInstanceDeleted)
-	InstanceRenamed          = InstanceAction(api.EventLifecycleInstanceRenamed)
-	InstanceUpdated          = InstanceAction(api.EventLifecycleInstanceUpdated)
-	InstanceMigrated         = InstanceAction(api.EventLifecycleInstanceMigrated)
-	InstanceExec             = InstanceAction(api.EventLifecycleInstanceExec)
-	InstanceConsole          = InstanceAction(api.EventLifecycleInstanceConsole)
-	InstanceConsoleRetrieved = InstanceAction(api.EventLifecycleInstanceConsoleRetrieved)
-	InstanceConsoleReset     = InstanceAction(api.EventLifecycleInstanceConsoleReset)
-	InstanceFileRetrieved    = InstanceAction(api.EventLifecycleInstanceFileRetrieved)
-	InstanceFilePushed       = InstanceAction(api.EventLifecycleInstanceFilePushed)
-	InstanceFileDeleted      = InstanceAction(api.EventLifecycleInstanceFileDeleted)
+	InstanceCreated            = InstanceAction(api.EventLifecycleInstanceCreated)
+	InstanceStarted            = InstanceAction(api.EventLifecycleInstanceStarted)
+	InstanceStopped            = InstanceAction(api.EventLifecycleInstanceStopped)
+	InstanceShutdown           = InstanceAction(api.EventLifecycleInstanceShutdown)
+	InstanceRestarted          = InstanceAction(api.EventLifecycleInstanceRestarted)
+	InstancePaused             = InstanceAction(api.EventLifecycleInstancePaused)
+	InstanceReady              = InstanceAction(api.EventLifecycleInstanceReady)
+	InstanceResumed            = InstanceAction(api.EventLifecycleInstanceResumed)
+	InstanceRestored           = InstanceAction(api.EventLifecycleInstanceRestored)
+	InstanceDeleted            = InstanceAction(api.EventLifecycleInstanceDeleted)
+	InstanceRenamed            = InstanceAction(api.EventLifecycleInstanceRenamed)
+	InstanceUpdated            = InstanceAction(api.EventLifecycleInstanceUpdated)
+	InstanceMigrated           = InstanceAction(api.EventLifecycleInstanceMigrated)
+	InstanceExec               = InstanceAction(api.EventLifecycleInstanceExec)
+	InstanceConsole            = InstanceAction(api.EventLifecycleInstanceConsole)
+	InstanceConsoleRetrieved   = InstanceAction(api.EventLifecycleInstanceConsoleRetrieved)
+	InstanceConsoleReset       = InstanceAction(api.EventLifecycleInstanceConsoleReset)
+	InstanceFileRetrieved      = InstanceAction(api.EventLifecycleInstanceFileRetrieved)
+	InstanceFilePushed         = InstanceAction(api.EventLifecycleInstanceFilePushed)
+	InstanceFileDeleted        = InstanceAction(api.EventLifecycleInstanceFileDeleted)
+	InstanceSyscallIntercepted = InstanceAction(api.EventLifecycleInstanceSyscallIntercepted)
 )
 
 // Event creates the lifecycle event for an action on an instance.
@@ -10,9 +10,10 @@ type StoragePoolAction string
 
 // All supported lifecycle events for storage pools.
 const (
-	StoragePoolCreated = StoragePoolAction(api.EventLifecycleStoragePoolCreated)
-	StoragePoolDeleted = StoragePoolAction(api.EventLifecycleStoragePoolDeleted)
-	StoragePoolUpdated = StoragePoolAction(api.EventLifecycleStoragePoolUpdated)
+	StoragePoolCreated    = StoragePoolAction(api.EventLifecycleStoragePoolCreated)
+	StoragePoolDeleted    = StoragePoolAction(api.EventLifecycleStoragePoolDeleted)
+	StoragePoolNearlyFull = StoragePoolAction(api.EventLifecycleStoragePoolNearlyFull)
+	StoragePoolUpdated    = StoragePoolAction(api.EventLifecycleStoragePoolUpdated)
 )
 
 // Event creates the lifecycle event for an action on an storage pool.
@@ -17,11 +17,12 @@ type StorageVolumeAction string
 
 // All supported lifecycle events for storage volumes.
 const (
-	StorageVolumeCreated  = StorageVolumeAction(api.EventLifecycleStorageVolumeCreated)
-	StorageVolumeDeleted  = StorageVolumeAction(api.EventLifecycleStorageVolumeDeleted)
-	StorageVolumeUpdated  = StorageVolumeAction(api.EventLifecycleStorageVolumeUpdated)
-	StorageVolumeRenamed  = StorageVolumeAction(api.EventLifecycleStorageVolumeRenamed)
-	StorageVolumeRestored = StorageVolumeAction(api.EventLifecycleStorageVolumeRestored)
+	StorageVolumeCreated    = StorageVolumeAction(api.EventLifecycleStorageVolumeCreated)
+	StorageVolumeDeleted    = StorageVolumeAction(api.EventLifecycleStorageVolumeDeleted)
+	StorageVolumeUpdated    = StorageVolumeAction(api.EventLifecycleStorageVolumeUpdated)
+	StorageVolumeRenamed    = StorageVolumeAction(api.EventLifecycleStorageVolumeRenamed)
+	StorageVolumeRestored   = StorageVolumeAction(api.EventLifecycleStorageVolumeRestored)
+	StorageVolumeNearlyFull = StorageVolumeAction(api.EventLifecycleStorageVolumeNearlyFull)
 )
 
 // Event creates the lifecycle event for an action on a storage volume.
@@ -341,6 +341,18 @@ func (c *Client) HandleEvent(event api.Event) {
 		line.WriteString(lifecycleEvent.Action)
 
 		entry.Line = line.String()
+	case api.EventTypeSecurity:
+		securityEvent := api.EventSecurity{}
+
+		err := json.Unmarshal(event.Metadata, &securityEvent)
+		if err != nil {
+			return
+		}
+
+		entry.labels["name"] = securityEvent.Name
+		entry.labels["project"] = securityEvent.Project
+
+		entry.Line = `mechanism="` + securityEvent.Mechanism + `" operation="` + securityEvent.Operation + `" source="` + securityEvent.Source + `" ` + securityEvent.Message
 	case api.EventTypeLogging, api.EventTypeOVN:
 		logEvent := api.EventLogging{}
 
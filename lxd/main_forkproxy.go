@@ -241,15 +241,19 @@ void forkproxy(void)
 import "C"
 
 import (
+	"cmp"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
@@ -275,17 +279,22 @@ var udpSessions = map[string]*udpSession{}
 var udpSessionsLock sync.Mutex
 
 type udpSession struct {
-	client    net.Addr
-	target    net.Conn
-	timer     *time.Timer
-	timerLock sync.Mutex
+	client        net.Addr
+	target        net.Conn
+	connectTarget *proxyTarget
+	timer         *time.Timer
+	timerLock     sync.Mutex
 }
 
+// UDP listen address and connect targets used to assign new UDP sessions.
+var udpListenAddr *deviceConfig.ProxyAddress
+var udpTargets *proxyTargets
+
 // Command setup network connection proxying.
 func (c *cmdForkproxy) Command() *cobra.Command {
 	// Main subcommand
 	cmd := &cobra.Command{}
-	cmd.Use = "forkproxy <listen PID> <listen PidFd> <listen address> <connect PID> <connect PidFd> <connect address> <log path> <pid path> <listen gid> <listen uid> <listen mode> <security gid> <security uid>"
+	cmd.Use = "forkproxy <listen PID> <listen PidFd> <listen address> <connect PID> <connect PidFd> <connect addresses> <listen gid> <listen uid> <listen mode> <security gid> <security uid> <proxy protocol version> <balancing> <health check interval>"
 	cmd.Short = "Setup network connection proxying"
 	cmd.Long = `Description:
   Setup network connection proxying
@@ -294,7 +303,7 @@ func (c *cmdForkproxy) Command() *cobra.Command {
   container, connecting one side to the host and the other to the
   container.
 `
-	cmd.Args = cobra.ExactArgs(12)
+	cmd.Args = cobra.ExactArgs(14)
 	cmd.RunE = c.Run
 	cmd.Hidden = true
 
@@ -311,19 +320,7 @@ func rearmUDPFd(epFd C.int, connFd C.int) {
 	}
 }
 
-func listenerInstance(epFd C.int, lAddr *deviceConfig.ProxyAddress, cAddr *deviceConfig.ProxyAddress, connFd C.int, lStruct *lStruct, proxy bool) error {
-	// Single or multiple port -> single port
-	connectAddr := cAddr.Address
-	if cAddr.ConnType != "unix" {
-		connectPort := cAddr.Ports[0]
-		if lAddr.ConnType != "unix" && cAddr.ConnType != "unix" && len(cAddr.Ports) > 1 {
-			// multiple port -> multiple port
-			connectPort = cAddr.Ports[(*lStruct).lAddrIndex]
-		}
-
-		connectAddr = net.JoinHostPort(cAddr.Address, strconv.FormatUint(connectPort, 10))
-	}
-
+func listenerInstance(epFd C.int, lAddr *deviceConfig.ProxyAddress, targets *proxyTargets, connFd C.int, lStruct *lStruct, proxyProtocol string) error {
 	if lAddr.ConnType == "udp" {
 		// This only handles udp <-> udp. The C constructor will have verified this before
 		go func() {
@@ -334,7 +331,8 @@ func listenerInstance(epFd C.int, lAddr *deviceConfig.ProxyAddress, cAddr *devic
 				return
 			}
 
-			dstConn, err := net.Dial(cAddr.ConnType, connectAddr)
+			// The UDP sessions are assigned to the targets as clients send their first datagram.
+			dstConn, err := net.Dial(targets.targets[0].addr.ConnType, targets.targets[0].address(lAddr, (*lStruct).lAddrIndex))
 			if err != nil {
 				fmt.Printf("Warning: Failed connecting to target: %v\n", err)
 				rearmUDPFd(epFd, connFd)
@@ -356,47 +354,232 @@ func listenerInstance(epFd C.int, lAddr *deviceConfig.ProxyAddress, cAddr *devic
 		return err
 	}
 
-	dstConn, err := net.Dial(cAddr.ConnType, connectAddr)
+	dstConn, target, err := targets.dial(lAddr, (*lStruct).lAddrIndex)
 	if err != nil {
 		_ = srcConn.Close()
 		fmt.Printf("Warning: Failed connecting to target: %v\n", err)
 		return err
 	}
 
-	if proxy && cAddr.ConnType == "tcp" {
-		if lAddr.ConnType == "unix" {
-			_, _ = dstConn.Write([]byte("PROXY UNKNOWN\r\n"))
+	if proxyProtocol != "" && target.addr.ConnType == "tcp" {
+		header, err := proxyProtocolHeader(proxyProtocol, lAddr, srcConn)
+		if err != nil {
+			_ = srcConn.Close()
+			_ = dstConn.Close()
+			target.release()
+			return err
+		}
+
+		_, _ = dstConn.Write(header)
+	}
+
+	go func() {
+		if target.addr.ConnType == "unix" && lAddr.ConnType == "unix" {
+			// Handle OOB if both src and dst are using unix sockets
+			unixRelay(srcConn, dstConn)
 		} else {
-			cHost, cPort, err := net.SplitHostPort(srcConn.RemoteAddr().String())
-			if err != nil {
-				return err
+			genericRelay(srcConn, dstConn)
+		}
+
+		target.release()
+	}()
+
+	return nil
+}
+
+// proxyProtocolHeader returns the HAProxy PROXY protocol header describing the client connection.
+func proxyProtocolHeader(version string, lAddr *deviceConfig.ProxyAddress, srcConn net.Conn) ([]byte, error) {
+	if version == "2" {
+		header := []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+		// Use the LOCAL command when the client has no address.
+		if lAddr.ConnType == "unix" {
+			return append(header, 0x20, 0x00, 0x00, 0x00), nil
+		}
+
+		src, srcOk := srcConn.RemoteAddr().(*net.TCPAddr)
+		dst, dstOk := srcConn.LocalAddr().(*net.TCPAddr)
+		if !srcOk || !dstOk {
+			return nil, errors.New("Unsupported client connection type for the PROXY header")
+		}
+
+		// TCP over IPv4 unless one of the addresses is IPv6.
+		family := byte(0x11)
+		srcIP := src.IP.To4()
+		dstIP := dst.IP.To4()
+		if srcIP == nil || dstIP == nil {
+			family = 0x21
+			srcIP = src.IP.To16()
+			dstIP = dst.IP.To16()
+		}
+
+		header = append(header, 0x21, family)
+		header = binary.BigEndian.AppendUint16(header, uint16(len(srcIP)+len(dstIP)+4))
+		header = append(header, srcIP...)
+		header = append(header, dstIP...)
+		header = binary.BigEndian.AppendUint16(header, uint16(src.Port))
+		header = binary.BigEndian.AppendUint16(header, uint16(dst.Port))
+
+		return header, nil
+	}
+
+	if lAddr.ConnType == "unix" {
+		return []byte("PROXY UNKNOWN\r\n"), nil
+	}
+
+	cHost, cPort, err := net.SplitHostPort(srcConn.RemoteAddr().String())
+	if err != nil {
+		return nil, err
+	}
+
+	dHost, dPort, err := net.SplitHostPort(srcConn.LocalAddr().String())
+	if err != nil {
+		return nil, err
+	}
+
+	proto := srcConn.LocalAddr().Network()
+	proto = strings.ToUpper(proto)
+	if strings.Contains(cHost, ":") {
+		proto = proto + "6"
+	} else {
+		proto = proto + "4"
+	}
+
+	return []byte("PROXY " + proto + " " + cHost + " " + dHost + " " + cPort + " " + dPort + "\r\n"), nil
+}
+
+// proxyTarget is one of the addresses the proxy connects to.
+type proxyTarget struct {
+	addr        *deviceConfig.ProxyAddress
+	connections atomic.Int64
+	down        atomic.Bool
+}
+
+// address returns the address to connect to for the listen address at lAddrIndex.
+func (t *proxyTarget) address(lAddr *deviceConfig.ProxyAddress, lAddrIndex int) string {
+	if t.addr.ConnType == "unix" {
+		return t.addr.Address
+	}
+
+	// Single or multiple port -> single port
+	connectPort := t.addr.Ports[0]
+	if lAddr.ConnType != "unix" && len(t.addr.Ports) > 1 {
+		// multiple port -> multiple port
+		connectPort = t.addr.Ports[lAddrIndex]
+	}
+
+	return net.JoinHostPort(t.addr.Address, strconv.FormatUint(connectPort, 10))
+}
+
+// release records that a connection to the target was closed.
+func (t *proxyTarget) release() {
+	t.connections.Add(-1)
+}
+
+// proxyTargets distributes the connections between the connect addresses.
+type proxyTargets struct {
+	targets   []*proxyTarget
+	balancing string
+	next      atomic.Uint64
+}
+
+// order returns the targets in the order they should be tried for a new connection.
+// Targets that are down are only tried once all the others have failed.
+func (p *proxyTargets) order() []*proxyTarget {
+	type candidate struct {
+		target      *proxyTarget
+		down        bool
+		connections int64
+	}
+
+	// Rotate the starting target so that targets with equal weight are used in turn.
+	start := int((p.next.Add(1) - 1) % uint64(len(p.targets)))
+	candidates := make([]candidate, 0, len(p.targets))
+	for i := range p.targets {
+		t := p.targets[(start+i)%len(p.targets)]
+		candidates = append(candidates, candidate{target: t, down: t.down.Load(), connections: t.connections.Load()})
+	}
+
+	slices.SortStableFunc(candidates, func(a candidate, b candidate) int {
+		if a.down != b.down {
+			if a.down {
+				return 1
 			}
 
-			dHost, dPort, err := net.SplitHostPort(srcConn.LocalAddr().String())
-			if err != nil {
-				return err
+			return -1
+		}
+
+		if p.balancing == "least-connections" {
+			return cmp.Compare(a.connections, b.connections)
+		}
+
+		return 0
+	})
+
+	ordered := make([]*proxyTarget, 0, len(candidates))
+	for _, c := range candidates {
+		ordered = append(ordered, c.target)
+	}
+
+	return ordered
+}
+
+// dial connects to a target for the listen address at lAddrIndex, falling back to the next targets on failure.
+// The target must be released once the returned connection is closed.
+func (p *proxyTargets) dial(lAddr *deviceConfig.ProxyAddress, lAddrIndex int) (net.Conn, *proxyTarget, error) {
+	var err error
+	for _, t := range p.order() {
+		var conn net.Conn
+		conn, err = net.Dial(t.addr.ConnType, t.address(lAddr, lAddrIndex))
+		if err != nil {
+			if len(p.targets) > 1 && !t.down.Swap(true) {
+				fmt.Printf("Warning: Target %q is down: %v\n", t.address(lAddr, lAddrIndex), err)
 			}
 
-			proto := srcConn.LocalAddr().Network()
-			proto = strings.ToUpper(proto)
-			if strings.Contains(cHost, ":") {
-				proto = proto + "6"
-			} else {
-				proto = proto + "4"
+			continue
+		}
+
+		t.down.Store(false)
+		t.connections.Add(1)
+		return conn, t, nil
+	}
+
+	return nil, nil, err
+}
+
+// healthCheck regularly checks whether the targets accept connections.
+func (p *proxyTargets) healthCheck(lAddr *deviceConfig.ProxyAddress, interval time.Duration) {
+	for {
+		time.Sleep(interval)
+
+		for _, t := range p.targets {
+			conn, err := net.DialTimeout(t.addr.ConnType, t.address(lAddr, 0), interval)
+			if err != nil {
+				if !t.down.Swap(true) {
+					fmt.Printf("Warning: Target %q failed health check: %v\n", t.address(lAddr, 0), err)
+				}
+
+				continue
 			}
 
-			_, _ = dstConn.Write([]byte("PROXY " + proto + " " + cHost + " " + dHost + " " + cPort + " " + dPort + "\r\n"))
+			_ = conn.Close()
+
+			if t.down.Swap(false) {
+				fmt.Printf("Status: Target %q passed health check\n", t.address(lAddr, 0))
+			}
 		}
 	}
+}
 
-	if cAddr.ConnType == "unix" && lAddr.ConnType == "unix" {
-		// Handle OOB if both src and dst are using unix sockets
-		go unixRelay(srcConn, dstConn)
-	} else {
-		go genericRelay(srcConn, dstConn)
+// dialUDPSession connects a new UDP session received on the listener to a target.
+func dialUDPSession(listener net.Addr) (net.Conn, *proxyTarget, error) {
+	lAddrIndex := 0
+	udpAddr, ok := listener.(*net.UDPAddr)
+	if ok {
+		lAddrIndex = max(slices.Index(udpListenAddr.Ports, uint64(udpAddr.Port)), 0)
 	}
 
-	return nil
+	return udpTargets.dial(udpListenAddr, lAddrIndex)
 }
 
 type lStruct struct {
@@ -413,7 +596,7 @@ func (c *cmdForkproxy) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Quick checks.
-	if len(args) != 12 {
+	if len(args) != 14 {
 		_ = cmd.Help()
 
 		if len(args) == 0 {
@@ -434,12 +617,18 @@ func (c *cmdForkproxy) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	connectAddr := args[5]
-	cAddr, err := network.ProxyParseAddr(connectAddr)
-	if err != nil {
-		return err
+	targets := &proxyTargets{balancing: args[12]}
+	for _, connectAddr := range network.ProxySplitAddrs(args[5]) {
+		addr, err := network.ProxyParseAddr(connectAddr)
+		if err != nil {
+			return err
+		}
+
+		targets.targets = append(targets.targets, &proxyTarget{addr: addr})
 	}
 
+	cAddr := targets.targets[0].addr
+
 	if (lAddr.ConnType == "udp" || lAddr.ConnType == "tcp") && cAddr.ConnType == "udp" || cAddr.ConnType == "tcp" {
 		err := errors.New("Invalid port range")
 		if len(lAddr.Ports) > 1 && len(cAddr.Ports) > 1 && (len(cAddr.Ports) != len(lAddr.Ports)) {
@@ -667,6 +856,21 @@ func (c *cmdForkproxy) Run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if isUDPListener {
+		udpListenAddr = lAddr
+		udpTargets = targets
+	}
+
+	healthCheckInterval, err := strconv.ParseUint(args[13], 10, 32)
+	if err != nil {
+		return err
+	}
+
+	// UDP targets can't be checked by connecting to them.
+	if cAddr.ConnType == "tcp" && len(targets.targets) > 1 && healthCheckInterval > 0 {
+		go targets.healthCheck(lAddr, time.Duration(healthCheckInterval)*time.Second)
+	}
+
 	// This line is used by LXD to check forkproxy has started OK.
 	fmt.Println("Status: Started")
 
@@ -686,7 +890,7 @@ func (c *cmdForkproxy) Run(cmd *cobra.Command, args []string) error {
 				continue
 			}
 
-			err := listenerInstance(epFd, lAddr, cAddr, curFd, srcConn, args[11] == "true")
+			err := listenerInstance(epFd, lAddr, targets, curFd, srcConn, args[11])
 			if err != nil {
 				fmt.Printf("Warning: Failed preparing new listener instance: %v\n", err)
 			}
@@ -720,14 +924,15 @@ func proxyCopy(dst net.Conn, src net.Conn) error {
 				udpSessionsLock.Unlock()
 
 				if !ok {
-					dc, err := net.Dial(dst.RemoteAddr().Network(), dst.RemoteAddr().String())
+					dc, connectTarget, err := dialUDPSession(src.LocalAddr())
 					if err != nil {
 						return err
 					}
 
 					us = &udpSession{
-						client: addr,
-						target: dc,
+						client:        addr,
+						target:        dc,
+						connectTarget: connectTarget,
 					}
 
 					udpSessionsLock.Lock()
@@ -737,6 +942,7 @@ func proxyCopy(dst net.Conn, src net.Conn) error {
 					go func() { _ = proxyCopy(src, dc) }()
 					us.timer = time.AfterFunc(30*time.Minute, func() {
 						_ = us.target.Close()
+						us.connectTarget.release()
 
 						udpSessionsLock.Lock()
 						delete(udpSessions, addr.String())
@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"net"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -190,3 +191,81 @@ func TestParseAddr(t *testing.T) {
 		require.Equal(t, tt.expected, addr)
 	}
 }
+
+// addrConn is a connection with fixed addresses.
+type addrConn struct {
+	net.Conn
+	local  net.Addr
+	remote net.Addr
+}
+
+func (c addrConn) LocalAddr() net.Addr  { return c.local }
+func (c addrConn) RemoteAddr() net.Addr { return c.remote }
+
+func TestProxyProtocolHeader(t *testing.T) {
+	tcpListen := &deviceConfig.ProxyAddress{ConnType: "tcp"}
+	unixListen := &deviceConfig.ProxyAddress{ConnType: "unix"}
+
+	conn4 := addrConn{
+		local:  &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 80},
+		remote: &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 50000},
+	}
+
+	conn6 := addrConn{
+		local:  &net.TCPAddr{IP: net.ParseIP("fd00::1"), Port: 443},
+		remote: &net.TCPAddr{IP: net.ParseIP("2001:db8::10"), Port: 50000},
+	}
+
+	signature := "\r\n\r\n\x00\r\nQUIT\n"
+
+	tests := []struct {
+		name     string
+		version  string
+		lAddr    *deviceConfig.ProxyAddress
+		conn     net.Conn
+		expected []byte
+	}{
+		{"v1 IPv4", "1", tcpListen, conn4, []byte("PROXY TCP4 192.0.2.10 10.0.0.1 50000 80\r\n")},
+		{"v1 IPv6", "1", tcpListen, conn6, []byte("PROXY TCP6 2001:db8::10 fd00::1 50000 443\r\n")},
+		{"v1 unix", "1", unixListen, conn4, []byte("PROXY UNKNOWN\r\n")},
+		{
+			"v2 IPv4", "2", tcpListen, conn4,
+			append([]byte(signature), 0x21, 0x11, 0x00, 0x0c, 192, 0, 2, 10, 10, 0, 0, 1, 0xc3, 0x50, 0x00, 0x50),
+		},
+		{
+			"v2 IPv6", "2", tcpListen, conn6,
+			append(append(append([]byte(signature), 0x21, 0x21, 0x00, 0x24), append(net.ParseIP("2001:db8::10").To16(), net.ParseIP("fd00::1").To16()...)...), 0xc3, 0x50, 0x01, 0xbb),
+		},
+		{"v2 unix", "2", unixListen, conn4, append([]byte(signature), 0x20, 0x00, 0x00, 0x00)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, err := proxyProtocolHeader(tt.version, tt.lAddr, tt.conn)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, header)
+		})
+	}
+}
+
+func TestProxyTargetsOrder(t *testing.T) {
+	a := &proxyTarget{}
+	b := &proxyTarget{}
+	c := &proxyTarget{}
+
+	// Round-robin rotates through the targets.
+	targets := &proxyTargets{targets: []*proxyTarget{a, b, c}, balancing: "round-robin"}
+	require.Equal(t, []*proxyTarget{a, b, c}, targets.order())
+	require.Equal(t, []*proxyTarget{b, c, a}, targets.order())
+
+	// Targets that are down are tried last.
+	b.down.Store(true)
+	require.Equal(t, []*proxyTarget{c, a, b}, targets.order())
+	b.down.Store(false)
+
+	// Least-connections prefers the least busy targets.
+	a.connections.Store(2)
+	b.connections.Store(1)
+	targets = &proxyTargets{targets: []*proxyTarget{a, b, c}, balancing: "least-connections"}
+	require.Equal(t, []*proxyTarget{c, b, a}, targets.order())
+}
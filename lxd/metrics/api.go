@@ -9,6 +9,7 @@ type Metrics struct {
 	Memory         MemoryMetrics                `json:"memory" yaml:"memory"`
 	Network        map[string]NetworkMetrics    `json:"network" yaml:"network"`
 	ProcessesTotal uint64                       `json:"procs_total" yaml:"procs_total"`
+	Pressure       map[string]PressureMetrics   `json:"pressure" yaml:"pressure"`
 }
 
 // CPUMetrics represents CPU metrics for an instance.
@@ -64,6 +65,12 @@ type MemoryMetrics struct {
 	OOMKills            uint64 `json:"memory_oom_kills" yaml:"memory_oom_kills"`
 }
 
+// PressureMetrics represents the pressure stall information of a resource for an instance.
+type PressureMetrics struct {
+	SomeSeconds float64 `json:"pressure_some_seconds" yaml:"pressure_some_seconds"`
+	FullSeconds float64 `json:"pressure_full_seconds" yaml:"pressure_full_seconds"`
+}
+
 // NetworkMetrics represents network metrics for an instance.
 type NetworkMetrics struct {
 	ReceiveBytes    uint64 `json:"network_receive_bytes" yaml:"network_receive_bytes"`
@@ -262,6 +262,14 @@ func MetricSetFromAPI(metrics *Metrics, labels map[string]string) (*MetricSet, e
 		set.AddSamples(NetworkTransmitPacketsTotal, Sample{Value: float64(stats.TransmitPackets), Labels: labels})
 	}
 
+	// Pressure stats
+	for resource, stats := range metrics.Pressure {
+		set.AddSamples(PressureStallSecondsTotal,
+			Sample{Value: stats.SomeSeconds, Labels: map[string]string{"resource": resource, "kind": "some"}},
+			Sample{Value: stats.FullSeconds, Labels: map[string]string{"resource": resource, "kind": "full"}},
+		)
+	}
+
 	// Procs stats
 	set.AddSamples(ProcsTotal, Sample{Value: float64(metrics.ProcessesTotal)})
 
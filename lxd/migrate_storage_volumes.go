@@ -112,7 +112,7 @@ func (s *migrationSourceWs) DoStorage(state *state.State, projectName string, po
 	// to false here. The migration source/sender doesn't need to care whether
 	// or not it's doing a refresh as the migration sink/receiver will know
 	// this, and adjust the migration types accordingly.
-	poolMigrationTypes = pool.MigrationTypes(storageDrivers.ContentType(customVol.ContentType), false, !s.volumeOnly)
+	poolMigrationTypes = storagePools.CustomVolumeMigrationTypes(pool, storageDrivers.ContentType(customVol.ContentType), customVol.Config, false, !s.volumeOnly)
 	if len(poolMigrationTypes) == 0 {
 		return errors.New("No source migration types available")
 	}
@@ -1428,6 +1428,24 @@ func BridgeNetfilterEnabled(ipVersion uint) error {
 	return nil
 }
 
+// ProxySplitAddrs splits a comma separated list of proxy addresses.
+// As commas also separate the ports of an address, a new address only starts at a comma followed by a protocol
+// type.
+func ProxySplitAddrs(data string) []string {
+	var addrs []string
+	for _, field := range strings.Split(data, ",") {
+		connType, _, _ := strings.Cut(field, ":")
+		if len(addrs) == 0 || slices.Contains([]string{"tcp", "udp", "unix"}, connType) {
+			addrs = append(addrs, field)
+			continue
+		}
+
+		addrs[len(addrs)-1] += "," + field
+	}
+
+	return addrs
+}
+
 // ProxyParseAddr validates a proxy address and parses it into its constituent parts.
 func ProxyParseAddr(data string) (*deviceConfig.ProxyAddress, error) {
 	// Split into <protocol> and <address>.
@@ -253,3 +253,20 @@ func Example_complementRangesIP4() {
 	// Range2: 10.1.1.1-10.1.1.9, 10.1.1.101-10.1.1.199, 10.1.1.231-10.1.1.255
 	// Range3: 10.1.1.1-10.1.1.9, 10.1.1.26-10.1.1.255
 }
+
+func TestProxySplitAddrs(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected []string
+	}{
+		{"tcp:127.0.0.1:80", []string{"tcp:127.0.0.1:80"}},
+		{"tcp:127.0.0.1:80,443", []string{"tcp:127.0.0.1:80,443"}},
+		{"tcp:10.0.0.2:80,tcp:10.0.0.3:80", []string{"tcp:10.0.0.2:80", "tcp:10.0.0.3:80"}},
+		{"udp:[fd00::2]:53-54,80,udp:[fd00::3]:53-54,80", []string{"udp:[fd00::2]:53-54,80", "udp:[fd00::3]:53-54,80"}},
+		{"unix:/run/a.sock", []string{"unix:/run/a.sock"}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, ProxySplitAddrs(tt.data))
+	}
+}
@@ -122,6 +122,11 @@ func (c *Config) StorageImagesVolume(projectName string) string {
 	return c.daemonStorageVolume(projectName, config.DaemonStorageTypeImages)
 }
 
+// StorageEncryptionKeyFile returns the path to the key-encryption key used to seal storage volume keys.
+func (c *Config) StorageEncryptionKeyFile() string {
+	return c.m.GetString("storage.encryption_key_file")
+}
+
 // SyslogSocket returns true if the syslog socket is enabled, otherwise false.
 func (c *Config) SyslogSocket() bool {
 	return c.m.GetBool("core.syslog_socket")
@@ -261,6 +266,16 @@ var ConfigSchema = config.Schema{
 		//  shortdesc: Volume to use to store the image tarballs
 		"storage.images_volume": {},
 
+		// lxdmeta:generate(entities=server; group=miscellaneous; key=storage.encryption_key_file)
+		// When set, the keys of newly encrypted storage volumes are sealed with a key derived from the content of this file
+		// before being stored in the database.
+		// Volumes with sealed keys can only be opened by servers that use a file with the same content.
+		// ---
+		//  type: string
+		//  scope: local
+		//  shortdesc: Path to the key-encryption key for storage volume keys
+		"storage.encryption_key_file": {Validator: validate.Optional(validate.IsAbsFilePath)},
+
 		// lxdmeta:generate(entities=server; group=miscellaneous; key=storage.project.{name}.backups_volume)
 		// Specify the volume using the syntax `POOL/VOLUME`.
 		// ---
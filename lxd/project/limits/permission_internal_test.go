@@ -165,3 +165,38 @@ func TestProjectLowLevelRestrictions(t *testing.T) {
 		})
 	require.NoError(t, err)
 }
+
+func TestGetDiskIOTotal(t *testing.T) {
+	info := &projectInfo{
+		Project: api.Project{Name: "p1", ProjectPut: api.ProjectPut{Config: map[string]string{"limits.disk.iops": "1000"}}},
+		Instances: []api.Instance{
+			{Name: "v1", Project: "p1", InstancePut: api.InstancePut{Devices: map[string]map[string]string{
+				"root": {"type": "disk", "pool": "default", "path": "/", "limits.max": "100iops", "limits.max.burst": "300iops"},
+			}}},
+			{Name: "v2", Project: "p1", InstancePut: api.InstancePut{Devices: map[string]map[string]string{
+				"root": {"type": "disk", "pool": "default", "path": "/", "limits.read": "200iops"},
+				"data": {"type": "disk", "pool": "default", "source": "vol1", "path": "/data", "limits.write": "50iops", "limits.write.burst": "150iops"},
+				"host": {"type": "disk", "source": "/srv", "path": "/srv"},
+			}}},
+		},
+	}
+
+	// Reads: max(100, 300) + 200. Writes: max(100, 300) + max(50, 150).
+	total, err := getDiskIOTotal(info, "limits.disk.iops", false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), total)
+
+	// A disk backed by a storage volume without any I/O limit is refused.
+	info.Instances[1].Devices["data"] = map[string]string{"type": "disk", "pool": "default", "source": "vol1", "path": "/data"}
+	_, err = getDiskIOTotal(info, "limits.disk.iops", false)
+	require.ErrorContains(t, err, "has no read or write I/O limit set")
+
+	total, err = getDiskIOTotal(info, "limits.disk.iops", true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), total)
+
+	// Limits in byte/s require a project bandwidth limit.
+	info.Instances[1].Devices["data"]["limits.read"] = "10MB"
+	_, err = getDiskIOTotal(info, "limits.disk.iops", false)
+	require.ErrorContains(t, err, "must have its I/O limits set in IOPS")
+}
@@ -722,6 +722,14 @@ func checkInstanceRestrictions(proj api.Project, instances []api.Instance, profi
 						if !allowed {
 							return fmt.Errorf("Disk source path %q not allowed", device["source"])
 						}
+
+						// The SMB credentials file is read from the host.
+						if device["smb.credentials"] != "" {
+							allowed, _ := project.CheckRestrictedDevicesDiskPaths(proj.Config, device["smb.credentials"])
+							if !allowed {
+								return fmt.Errorf("Disk SMB credentials path %q not allowed", device["smb.credentials"])
+							}
+						}
 					}
 				}
 
@@ -862,6 +870,8 @@ func checkInstanceRestrictions(proj api.Project, instances []api.Instance, profi
 var allInstanceAggregateLimits = []string{
 	"limits.cpu",
 	"limits.disk",
+	"limits.disk.bandwidth",
+	"limits.disk.iops",
 	"limits.memory",
 	"limits.processes",
 }
@@ -897,9 +907,13 @@ var allRestrictions = map[string]string{
 var allowableIntercept = []string{
 	"security.syscalls.intercept.bpf",
 	"security.syscalls.intercept.bpf.devices",
+	"security.syscalls.intercept.kexec_load",
+	"security.syscalls.intercept.keyctl",
 	"security.syscalls.intercept.mknod",
 	"security.syscalls.intercept.mount",
 	"security.syscalls.intercept.mount.fuse",
+	"security.syscalls.intercept.open_by_handle_at",
+	"security.syscalls.intercept.perf_event_open",
 	"security.syscalls.intercept.setxattr",
 	"security.syscalls.intercept.sysinfo",
 }
@@ -914,7 +928,7 @@ func isContainerLowLevelOptionForbidden(key string) bool {
 		return true
 	}
 
-	if slices.Contains([]string{"boot.host_shutdown_timeout", "linux.kernel_modules", "linux.kernel_modules.load", "raw.apparmor", "raw.idmap", "raw.lxc", "raw.seccomp", "security.devlxd.images", "security.idmap.base", "security.idmap.size"}, key) {
+	if slices.Contains([]string{"boot.host_shutdown_timeout", "linux.kernel_modules", "linux.kernel_modules.load", "raw.apparmor", "raw.idmap", "raw.lxc", "raw.seccomp", "security.devlxd.images", "security.idmap.base", "security.idmap.size", "security.profile.mode"}, key) {
 		return true
 	}
 
@@ -1140,6 +1154,28 @@ func AllowProjectUpdate(ctx context.Context, globalConfig *clusterConfig.Config,
 		return fmt.Errorf("Conflict detected when updating project %q: %w", projectName, err)
 	}
 
+	// Check that the resource pools being removed aren't used by any instance.
+	resourcePools := project.ResourcePools(config)
+	removedResourcePools := []string{}
+	for _, key := range changed {
+		poolKey, found := strings.CutPrefix(key, "resource_pools.")
+		if !found {
+			continue
+		}
+
+		poolName, _, _ := strings.Cut(poolKey, ".")
+		if !slices.Contains(resourcePools, poolName) && !slices.Contains(removedResourcePools, poolName) {
+			removedResourcePools = append(removedResourcePools, poolName)
+		}
+	}
+
+	if len(removedResourcePools) > 0 {
+		err := validateResourcePoolsUnused(globalConfig, info, removedResourcePools)
+		if err != nil {
+			return fmt.Errorf("Cannot remove resource pool in project %q: %w", projectName, err)
+		}
+	}
+
 	// Handle the changed project limits not yet checked.
 	for _, key := range changed {
 		switch key {
@@ -1169,6 +1205,27 @@ func AllowProjectUpdate(ctx context.Context, globalConfig *clusterConfig.Config,
 	return nil
 }
 
+// validateResourcePoolsUnused checks that none of the project's instances is a member of the given resource pools.
+func validateResourcePoolsUnused(globalConfig *clusterConfig.Config, info *projectInfo, resourcePools []string) error {
+	var globalConfigDump map[string]string
+	if globalConfig != nil {
+		globalConfigDump = globalConfig.Dump()
+	}
+
+	instances, err := expandInstancesConfigAndDevices(globalConfigDump, info.Instances, info.Profiles)
+	if err != nil {
+		return err
+	}
+
+	for _, inst := range instances {
+		if slices.Contains(resourcePools, inst.Config["limits.resource_pool"]) {
+			return fmt.Errorf("Resource pool %q is used by instance %q", inst.Config["limits.resource_pool"], inst.Name)
+		}
+	}
+
+	return nil
+}
+
 // Check that limits.instances, i.e. the total limit of containers/virtual machines allocated
 // to the user is equal to or above the current count.
 func validateTotalInstanceCountLimit(instances []api.Instance, value string) error {
@@ -1393,6 +1450,19 @@ func getTotalsAcrossProjectEntities(info *projectInfo, keys []string, skipUnset
 		}
 	}
 
+	for _, key := range keys {
+		if key != "limits.disk.iops" && key != "limits.disk.bandwidth" {
+			continue
+		}
+
+		total, err := getDiskIOTotal(info, key, skipUnset)
+		if err != nil {
+			return nil, err
+		}
+
+		totals[key] = total
+	}
+
 	for _, instance := range info.Instances {
 		limits, err := getInstanceLimits(instance, keys, skipUnset, info.StoragePoolDrivers)
 		if err != nil {
@@ -1413,6 +1483,11 @@ func getInstanceLimits(instance api.Instance, keys []string, skipUnset bool, sto
 	limits := map[string]int64{}
 
 	for _, key := range keys {
+		// Disk I/O limits are summed per direction across all instances by getDiskIOTotal.
+		if key == "limits.disk.iops" || key == "limits.disk.bandwidth" {
+			continue
+		}
+
 		var limit int64
 		keyName := key
 
@@ -1514,6 +1589,67 @@ func getInstanceLimits(instance api.Instance, keys []string, skipUnset bool, sto
 	return limits, nil
 }
 
+// getDiskIOTotal returns the sum of the read or write I/O limits, whichever is higher, of all disk devices
+// backed by a storage volume across the project instances.
+// Each device counts with the higher of its base and burst limit in each direction, and a direction without a
+// limit doesn't count. Each device limit counts against the project limit matching its unit, "limits.disk.iops"
+// for IOPS and "limits.disk.bandwidth" for byte/s. A device limit in a unit that isn't limited by the project,
+// or a device without any I/O limit, is an error unless skipUnset is true.
+func getDiskIOTotal(info *projectInfo, key string, skipUnset bool) (int64, error) {
+	var readTotal int64
+	var writeTotal int64
+
+	for _, instance := range info.Instances {
+		for devName, dev := range instance.Devices {
+			if dev["type"] != "disk" || dev["pool"] == "" {
+				continue
+			}
+
+			readBps, readIops, writeBps, writeIops, err := deviceconfig.ParseDiskLimits(dev, "limits.read", "limits.write", "limits.max")
+			if err != nil {
+				return -1, fmt.Errorf("Failed parsing I/O limits of disk device %q for instance %q in project %q: %w", devName, instance.Name, instance.Project, err)
+			}
+
+			// A device can exceed its base limits up to its burst limits for a while.
+			readBurstBps, readBurstIops, writeBurstBps, writeBurstIops, err := deviceconfig.ParseDiskLimits(dev, "limits.read.burst", "limits.write.burst", "limits.max.burst")
+			if err != nil {
+				return -1, fmt.Errorf("Failed parsing I/O burst limits of disk device %q for instance %q in project %q: %w", devName, instance.Name, instance.Project, err)
+			}
+
+			readBps = max(readBps, readBurstBps)
+			readIops = max(readIops, readBurstIops)
+			writeBps = max(writeBps, writeBurstBps)
+			writeIops = max(writeIops, writeBurstIops)
+
+			if readBps+readIops+writeBps+writeIops == 0 {
+				if skipUnset {
+					continue
+				}
+
+				return -1, fmt.Errorf("Disk device %q of instance %q in project %q has no read or write I/O limit set either directly or via a profile", devName, instance.Name, instance.Project)
+			}
+
+			if key == "limits.disk.iops" {
+				readTotal += readIops
+				writeTotal += writeIops
+
+				if (readBps > 0 || writeBps > 0) && info.Project.Config["limits.disk.bandwidth"] == "" && !skipUnset {
+					return -1, fmt.Errorf("Disk device %q of instance %q in project %q must have its I/O limits set in IOPS", devName, instance.Name, instance.Project)
+				}
+			} else {
+				readTotal += readBps
+				writeTotal += writeBps
+
+				if (readIops > 0 || writeIops > 0) && info.Project.Config["limits.disk.iops"] == "" && !skipUnset {
+					return -1, fmt.Errorf("Disk device %q of instance %q in project %q must have its I/O limits set in byte/s", devName, instance.Name, instance.Project)
+				}
+			}
+		}
+	}
+
+	return max(readTotal, writeTotal), nil
+}
+
 var aggregateLimitConfigValueParsers = map[string]func(string) (int64, error){
 	"limits.memory": func(value string) (int64, error) {
 		if strings.HasSuffix(value, "%") {
@@ -1545,6 +1681,12 @@ var aggregateLimitConfigValueParsers = map[string]func(string) (int64, error){
 	"limits.disk": func(value string) (int64, error) {
 		return units.ParseByteSizeString(value)
 	},
+	"limits.disk.bandwidth": func(value string) (int64, error) {
+		return units.ParseByteSizeString(value)
+	},
+	"limits.disk.iops": func(value string) (int64, error) {
+		return strconv.ParseInt(value, 10, 64)
+	},
 }
 
 // Return true if particular restriction in project is violated.
@@ -8,7 +8,9 @@ import (
 
 	"github.com/canonical/lxd/lxd/db"
 	"github.com/canonical/lxd/lxd/instance/instancetype"
+	"github.com/canonical/lxd/lxd/project"
 	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/units"
 )
 
 // GetCurrentAllocations returns the current resource utilization for a given project.
@@ -48,6 +50,8 @@ func GetCurrentAllocations(ctx context.Context, globalConfig map[string]string,
 
 	result["cpu"] = raw["limits.cpu"]
 	result["disk"] = raw["limits.disk"]
+	result["disk.bandwidth"] = raw["limits.disk.bandwidth"]
+	result["disk.iops"] = raw["limits.disk.iops"]
 	result["memory"] = raw["limits.memory"]
 	result["networks"] = raw["limits.networks"]
 	result["processes"] = raw["limits.processes"]
@@ -59,6 +63,47 @@ func GetCurrentAllocations(ctx context.Context, globalConfig map[string]string,
 		}
 	}
 
+	// Add the resource pool allocations.
+	for _, poolName := range project.ResourcePools(info.Project.Config) {
+		memoryLimit := int64(-1)
+		value := info.Project.Config["resource_pools."+poolName+".limits.memory"]
+		if value != "" {
+			memoryLimit, err = units.ParseByteSizeString(value)
+			if err != nil {
+				return nil, fmt.Errorf("Failed parsing memory limit of resource pool %q: %w", poolName, err)
+			}
+		}
+
+		members := int64(0)
+		memoryUsage := int64(0)
+		for _, inst := range info.Instances {
+			if inst.Config["limits.resource_pool"] != poolName {
+				continue
+			}
+
+			members++
+
+			// Like the project limits, this accounts for the configured limits and members without a memory
+			// limit don't count towards the usage.
+			instLimits, err := getInstanceLimits(inst, []string{"limits.memory"}, true, info.StoragePoolDrivers)
+			if err != nil {
+				return nil, err
+			}
+
+			memoryUsage += instLimits["limits.memory"]
+		}
+
+		result["instances.resource_pool."+poolName] = api.ProjectStateResource{
+			Limit: -1,
+			Usage: members,
+		}
+
+		result["memory.resource_pool."+poolName] = api.ProjectStateResource{
+			Limit: memoryLimit,
+			Usage: memoryUsage,
+		}
+	}
+
 	// Get the instance count values.
 	count, limit, err := getTotalInstanceCountLimit(info)
 	if err != nil {
@@ -114,6 +114,33 @@ func StorageVolumeParts(projectStorageVolumeName string) (projectName string, st
 	return projectName, storageVolumeName
 }
 
+// ResourcePool adds the "<project>_" prefix to the resource pool name. Even if the project name is "default".
+func ResourcePool(projectName string, resourcePoolName string) string {
+	return projectName + separator + resourcePoolName
+}
+
+// ResourcePools returns the sorted names of the resource pools defined in the project configuration.
+// A resource pool is defined by setting any of its "resource_pools.<name>.*" configuration keys.
+func ResourcePools(config map[string]string) []string {
+	names := []string{}
+
+	for key := range config {
+		poolKey, found := strings.CutPrefix(key, "resource_pools.")
+		if !found {
+			continue
+		}
+
+		name, _, _ := strings.Cut(poolKey, ".")
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
 // StorageVolumeProject returns the project name to use to for the volume based on the requested project.
 // For image volume types the default project is always returned.
 // For custom volume type, if the project specified has the "features.storage.volumes" flag enabled then the
@@ -45,3 +45,18 @@ func ExampleStorageVolume() {
 	// Output: default_test
 	// project_name_test1
 }
+
+func ExampleResourcePools() {
+	pools := project.ResourcePools(map[string]string{
+		"limits.memory":                           "10GiB",
+		"resource_pools.web.limits.memory":        "2GiB",
+		"resource_pools.web.limits.cpu.allowance": "50ms/100ms",
+		"resource_pools.batch.limits.memory":      "4GiB",
+	})
+
+	fmt.Println(pools)
+	fmt.Println(project.ResourcePool("foo", "web"))
+
+	// Output: [batch web]
+	// foo_web
+}
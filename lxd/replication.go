@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"strings"
+	"time"
+
+	lxd "github.com/canonical/lxd/client"
+	"github.com/canonical/lxd/lxd/state"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/validate"
+	"github.com/canonical/lxd/shared/version"
+)
+
+// replicationSource returns the value of the volatile.replication.source key of the replicas of name.
+// Replicas on a remote server record the certificate fingerprint of this server or cluster as well, so that
+// volumes or instances with the same name on other servers are never mistaken for their source.
+func replicationSource(s *state.State, remote bool, name string) string {
+	if !remote {
+		return name
+	}
+
+	return s.Endpoints.NetworkCert().Fingerprint() + ":" + name
+}
+
+// replicationSourceParse splits a volatile.replication.source value into the certificate fingerprint of the source
+// server (empty if it is this server or cluster) and the name of the source.
+func replicationSourceParse(source string) (string, string) {
+	fingerprint, name, found := strings.Cut(source, ":")
+	if !found || validate.IsCertificateFingerprint(fingerprint) != nil {
+		return "", source
+	}
+
+	return fingerprint, name
+}
+
+// replicationLastSync returns the time of the last synchronization recorded in config and the number of seconds
+// since then, or -1 if the replica was never synchronized.
+func replicationLastSync(config map[string]string) (time.Time, int64) {
+	lastSync, err := time.Parse(time.RFC3339, config["volatile.replication.last_sync"])
+	if err != nil {
+		return time.Time{}, -1
+	}
+
+	return lastSync, int64(time.Since(lastSync).Seconds())
+}
+
+// replicationIsConfigKey returns whether key is a replication key that must not be copied to a replica.
+func replicationIsConfigKey(key string) bool {
+	return strings.HasPrefix(key, "replication.") || strings.HasPrefix(key, "volatile.replication.")
+}
+
+// replicationRemoteConnect connects to the remote server set in the replication.target_remote option of config.
+// The certificate of the remote must match replication.target_remote_fingerprint, and this server authenticates
+// with its network certificate, which is the cluster certificate when clustered.
+// It returns the client and the PEM encoded certificate of the remote.
+func replicationRemoteConnect(ctx context.Context, s *state.State, config map[string]string) (lxd.InstanceServer, string, error) {
+	address := config["replication.target_remote"]
+	fingerprint := config["replication.target_remote_fingerprint"]
+	if fingerprint == "" {
+		return nil, "", api.StatusErrorf(http.StatusBadRequest, "The %q option is required to replicate to remote %q", "replication.target_remote_fingerprint", address)
+	}
+
+	cert, err := shared.GetRemoteCertificate(ctx, address, version.UserAgent)
+	if err != nil {
+		return nil, "", api.StatusErrorf(http.StatusServiceUnavailable, "Failed getting certificate of remote %q: %w", address, err)
+	}
+
+	if shared.CertFingerprint(cert) != fingerprint {
+		return nil, "", api.StatusErrorf(http.StatusForbidden, "Certificate fingerprint of remote %q doesn't match %q", address, fingerprint)
+	}
+
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	networkCert := s.Endpoints.NetworkCert()
+
+	args := &lxd.ConnectionArgs{
+		TLSServerCert: certPEM,
+		TLSClientCert: string(networkCert.PublicKey()),
+		TLSClientKey:  string(networkCert.PrivateKey()),
+		UserAgent:     version.UserAgent,
+		Proxy:         s.Proxy,
+	}
+
+	client, err := lxd.ConnectLXD(address, args)
+	if err != nil {
+		return nil, "", api.StatusErrorf(http.StatusServiceUnavailable, "Failed connecting to remote %q: %w", address, err)
+	}
+
+	return client, certPEM, nil
+}
+
+// replicationPushTarget returns the URL of a migration sink operation created on the remote server at address
+// and the secrets of its websockets, for a migration source to push to.
+func replicationPushTarget(address string, op lxd.Operation) (string, map[string]string) {
+	opAPI := op.Get()
+
+	secrets := map[string]string{}
+	for k, v := range opAPI.Metadata {
+		value, ok := v.(string)
+		if ok {
+			secrets[k] = value
+		}
+	}
+
+	return strings.TrimSuffix(address, "/") + "/" + version.APIVersion + "/operations/" + opAPI.ID, secrets
+}
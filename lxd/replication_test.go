@@ -0,0 +1,186 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+var testReplicationFingerprint = strings.Repeat("0123456789abcdef", 4)
+
+func TestReplicationSourceParse(t *testing.T) {
+	tests := []struct {
+		source      string
+		fingerprint string
+		name        string
+	}{
+		{source: "default/data", name: "default/data"},
+		{source: "c1", name: "c1"},
+		{source: testReplicationFingerprint + ":default/data", fingerprint: testReplicationFingerprint, name: "default/data"},
+		{source: testReplicationFingerprint + ":c1", fingerprint: testReplicationFingerprint, name: "c1"},
+		// Only a certificate fingerprint is taken as the source server.
+		{source: "remote:c1", name: "remote:c1"},
+		{source: strings.ToUpper(testReplicationFingerprint) + ":c1", name: strings.ToUpper(testReplicationFingerprint) + ":c1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			fingerprint, name := replicationSourceParse(test.source)
+			assert.Equal(t, test.fingerprint, fingerprint)
+			assert.Equal(t, test.name, name)
+		})
+	}
+}
+
+func TestStorageVolumeReplicationState(t *testing.T) {
+	lastSync := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name   string
+		config map[string]string
+		want   api.StorageVolumeReplication
+	}{
+		{
+			name:   "Not replicated",
+			config: map[string]string{"size": "1GiB"},
+			want:   api.StorageVolumeReplication{Lag: -1},
+		},
+		{
+			name:   "Source never synchronized",
+			config: map[string]string{"replication.target_pool": "backup", "replication.schedule": "@daily"},
+			want:   api.StorageVolumeReplication{Role: api.StorageVolumeReplicationRoleSource, Pool: "backup", Volume: "data", Schedule: "@daily", Lag: -1},
+		},
+		{
+			name: "Source with another replica name",
+			config: map[string]string{
+				"replication.target_pool":        "backup",
+				"replication.target_volume":      "data-replica",
+				"volatile.replication.last_sync": lastSync.Format(time.RFC3339),
+			},
+			want: api.StorageVolumeReplication{Role: api.StorageVolumeReplicationRoleSource, Pool: "backup", Volume: "data-replica", LastSync: lastSync, Lag: 3600},
+		},
+		{
+			name: "Source on a remote",
+			config: map[string]string{
+				"replication.target_pool":   "backup",
+				"replication.target_remote": "https://192.0.2.1:8443",
+			},
+			want: api.StorageVolumeReplication{Role: api.StorageVolumeReplicationRoleSource, Remote: "https://192.0.2.1:8443", Pool: "backup", Volume: "data", Lag: -1},
+		},
+		{
+			name: "Local replica",
+			config: map[string]string{
+				"volatile.replication.source":    "default/data",
+				"volatile.replication.last_sync": lastSync.Format(time.RFC3339),
+			},
+			want: api.StorageVolumeReplication{Role: api.StorageVolumeReplicationRoleReplica, Pool: "default", Volume: "data", LastSync: lastSync, Lag: 3600},
+		},
+		{
+			name:   "Remote replica",
+			config: map[string]string{"volatile.replication.source": testReplicationFingerprint + ":default/data"},
+			want:   api.StorageVolumeReplication{Role: api.StorageVolumeReplicationRoleReplica, Remote: testReplicationFingerprint, Pool: "default", Volume: "data", Lag: -1},
+		},
+		{
+			name:   "Invalid last synchronization",
+			config: map[string]string{"volatile.replication.source": "default/data", "volatile.replication.last_sync": "yesterday"},
+			want:   api.StorageVolumeReplication{Role: api.StorageVolumeReplicationRoleReplica, Pool: "default", Volume: "data", Lag: -1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replication := storageVolumeReplicationState("data", test.config)
+
+			// Allow for the test crossing a second boundary.
+			assert.InDelta(t, test.want.Lag, replication.Lag, 1)
+			replication.Lag = test.want.Lag
+
+			assert.Equal(t, test.want, *replication)
+		})
+	}
+}
+
+func TestStorageVolumeReplicaConfig(t *testing.T) {
+	config := map[string]string{
+		"size":                           "1GiB",
+		"snapshots.expiry":               "1d",
+		"replication.target_pool":        "backup",
+		"replication.target_volume":      "data-replica",
+		"replication.target_remote":      "https://192.0.2.1:8443",
+		"replication.schedule":           "@daily",
+		"volatile.replication.source":    "other/data",
+		"volatile.replication.last_sync": "2026-01-01T00:00:00Z",
+	}
+
+	replicaConfig := storageVolumeReplicaConfig(config, "default/data")
+
+	assert.Equal(t, map[string]string{
+		"size":                        "1GiB",
+		"snapshots.expiry":            "1d",
+		"volatile.replication.source": "default/data",
+	}, replicaConfig)
+
+	// The config of the source volume is left untouched.
+	assert.Equal(t, "backup", config["replication.target_pool"])
+}
+
+func TestInstanceReplicationState(t *testing.T) {
+	replication := instanceReplicationState("c1", map[string]string{
+		"replication.target_pool":     "backup",
+		"replication.target_instance": "c1-replica",
+		"replication.schedule":        "@hourly",
+	})
+	assert.Equal(t, api.InstanceReplication{Role: api.StorageVolumeReplicationRoleSource, Pool: "backup", Instance: "c1-replica", Schedule: "@hourly", Lag: -1}, *replication)
+
+	replication = instanceReplicationState("c1", map[string]string{"volatile.replication.source": testReplicationFingerprint + ":c1"})
+	assert.Equal(t, api.InstanceReplication{Role: api.StorageVolumeReplicationRoleReplica, Remote: testReplicationFingerprint, Instance: "c1", Lag: -1}, *replication)
+
+	replication = instanceReplicationState("c1", map[string]string{"limits.cpu": "2"})
+	assert.Equal(t, api.InstanceReplication{Lag: -1}, *replication)
+}
+
+func TestInstanceReplicaConfig(t *testing.T) {
+	sourceConfig := map[string]string{
+		"limits.cpu":                     "2",
+		"boot.autostart":                 "true",
+		"replication.target_pool":        "backup",
+		"replication.target_instance":    "c1-replica",
+		"replication.schedule":           "@hourly",
+		"volatile.base_image":            "abcd",
+		"volatile.uuid":                  "source-uuid",
+		"volatile.eth0.hwaddr":           "10:66:6a:00:00:01",
+		"volatile.replication.last_sync": "2026-01-01T00:00:00Z",
+	}
+
+	// A new replica keeps the base image, but gets its own identity.
+	assert.Equal(t, map[string]string{
+		"limits.cpu":                  "2",
+		"boot.autostart":              "false",
+		"volatile.base_image":         "abcd",
+		"volatile.replication.source": "c1",
+	}, instanceReplicaConfig(sourceConfig, nil, "c1"))
+
+	// A refreshed replica keeps its own volatile keys.
+	replicaConfig := map[string]string{
+		"limits.cpu":                     "1",
+		"volatile.uuid":                  "replica-uuid",
+		"volatile.eth0.hwaddr":           "10:66:6a:00:00:02",
+		"volatile.replication.source":    "c1",
+		"volatile.replication.last_sync": "2026-01-01T00:00:00Z",
+	}
+
+	assert.Equal(t, map[string]string{
+		"limits.cpu":                  "2",
+		"boot.autostart":              "false",
+		"volatile.uuid":               "replica-uuid",
+		"volatile.eth0.hwaddr":        "10:66:6a:00:00:02",
+		"volatile.replication.source": "c1",
+	}, instanceReplicaConfig(sourceConfig, replicaConfig, "c1"))
+
+	// The config of the source instance is left untouched.
+	assert.Equal(t, "source-uuid", sourceConfig["volatile.uuid"])
+}
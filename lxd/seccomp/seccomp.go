@@ -106,6 +106,10 @@ struct lxd_seccomp_data_arch {
 	int nr_sched_setscheduler;
 	int nr_sysinfo;
 	int nr_finit_module;
+	int nr_keyctl;
+	int nr_perf_event_open;
+	int nr_open_by_handle_at;
+	int nr_kexec_load;
 };
 
 #define LXD_SECCOMP_NOTIFY_MKNOD    0
@@ -116,69 +120,73 @@ struct lxd_seccomp_data_arch {
 #define LXD_SECCOMP_NOTIFY_SCHED_SETSCHEDULER 5
 #define LXD_SECCOMP_NOTIFY_SYSINFO 6
 #define LXD_SECCOMP_NOTIFY_FINIT_MODULE 7
+#define LXD_SECCOMP_NOTIFY_KEYCTL 8
+#define LXD_SECCOMP_NOTIFY_PERF_EVENT_OPEN 9
+#define LXD_SECCOMP_NOTIFY_OPEN_BY_HANDLE_AT 10
+#define LXD_SECCOMP_NOTIFY_KEXEC_LOAD 11
 
 // ordered by likelihood of usage...
 static const struct lxd_seccomp_data_arch seccomp_notify_syscall_table[] = {
-	{ -1, LXD_SECCOMP_NOTIFY_MKNOD, LXD_SECCOMP_NOTIFY_MKNODAT, LXD_SECCOMP_NOTIFY_SETXATTR, LXD_SECCOMP_NOTIFY_MOUNT, LXD_SECCOMP_NOTIFY_BPF, LXD_SECCOMP_NOTIFY_SCHED_SETSCHEDULER, LXD_SECCOMP_NOTIFY_SYSINFO, LXD_SECCOMP_NOTIFY_FINIT_MODULE },
+	{ -1, LXD_SECCOMP_NOTIFY_MKNOD, LXD_SECCOMP_NOTIFY_MKNODAT, LXD_SECCOMP_NOTIFY_SETXATTR, LXD_SECCOMP_NOTIFY_MOUNT, LXD_SECCOMP_NOTIFY_BPF, LXD_SECCOMP_NOTIFY_SCHED_SETSCHEDULER, LXD_SECCOMP_NOTIFY_SYSINFO, LXD_SECCOMP_NOTIFY_FINIT_MODULE, LXD_SECCOMP_NOTIFY_KEYCTL, LXD_SECCOMP_NOTIFY_PERF_EVENT_OPEN, LXD_SECCOMP_NOTIFY_OPEN_BY_HANDLE_AT, LXD_SECCOMP_NOTIFY_KEXEC_LOAD },
 #ifdef AUDIT_ARCH_X86_64
-	{ AUDIT_ARCH_X86_64,       133, 259, 188, 165, 321, 144, 99, 313 },
+	{ AUDIT_ARCH_X86_64,       133, 259, 188, 165, 321, 144, 99, 313, 250, 298, 304, 246 },
 #endif
 #ifdef AUDIT_ARCH_I386
-	{ AUDIT_ARCH_I386,         14, 297, 226,  21, 357, 156, 116, 350 },
+	{ AUDIT_ARCH_I386,         14, 297, 226,  21, 357, 156, 116, 350, 288, 336, 342, 283 },
 #endif
 #ifdef AUDIT_ARCH_AARCH64
-	{ AUDIT_ARCH_AARCH64,      -1,  33,   5,  21, 280, 156, 179, 273 },
+	{ AUDIT_ARCH_AARCH64,      -1,  33,   5,  21, 280, 156, 179, 273, 219, 241, 265, 104 },
 #endif
 #ifdef AUDIT_ARCH_ARM
-	{ AUDIT_ARCH_ARM,          14, 324, 226,  21, 386, 156, 116, 379 },
+	{ AUDIT_ARCH_ARM,          14, 324, 226,  21, 386, 156, 116, 379, 311, 364, 371, 347 },
 #endif
 #ifdef AUDIT_ARCH_ARMEB
-	{ AUDIT_ARCH_ARMEB,        14, 324, 226,  21, 386, 156, 116, 379 },
+	{ AUDIT_ARCH_ARMEB,        14, 324, 226,  21, 386, 156, 116, 379, 311, 364, 371, 347 },
 #endif
 #ifdef AUDIT_ARCH_S390
-	{ AUDIT_ARCH_S390,         14, 290, 224,  21, 386, 156, 116, 344 },
+	{ AUDIT_ARCH_S390,         14, 290, 224,  21, 386, 156, 116, 344, 280, 331, 336, 277 },
 #endif
 #ifdef AUDIT_ARCH_S390X
-	{ AUDIT_ARCH_S390X,        14, 290, 224,  21, 351, 156, 116, 344 },
+	{ AUDIT_ARCH_S390X,        14, 290, 224,  21, 351, 156, 116, 344, 280, 331, 336, 277 },
 #endif
 #ifdef AUDIT_ARCH_PPC
-	{ AUDIT_ARCH_PPC,          14, 288, 209,  21, 361, 156, 116, 353 },
+	{ AUDIT_ARCH_PPC,          14, 288, 209,  21, 361, 156, 116, 353, 271, 319, 346, 268 },
 #endif
 #ifdef AUDIT_ARCH_PPC64
-	{ AUDIT_ARCH_PPC64,        14, 288, 209,  21, 361, 156, 116, 353 },
+	{ AUDIT_ARCH_PPC64,        14, 288, 209,  21, 361, 156, 116, 353, 271, 319, 346, 268 },
 #endif
 #ifdef AUDIT_ARCH_PPC64LE
-	{ AUDIT_ARCH_PPC64LE,      14, 288, 209,  21, 361, 156, 116, 353 },
+	{ AUDIT_ARCH_PPC64LE,      14, 288, 209,  21, 361, 156, 116, 353, 271, 319, 346, 268 },
 #endif
 #ifdef AUDIT_ARCH_RISCV64
-	{ AUDIT_ARCH_RISCV64,      -1,  33,   5,  40, 280, -1, 179, 273 },
+	{ AUDIT_ARCH_RISCV64,      -1,  33,   5,  40, 280, -1, 179, 273, 219, 241, 265, 104 },
 #endif
 #ifdef AUDIT_ARCH_SPARC
-	{ AUDIT_ARCH_SPARC,        14, 286, 169, 167, 349, 243, 214, 342 },
+	{ AUDIT_ARCH_SPARC,        14, 286, 169, 167, 349, 243, 214, 342, 283, 327, 333, 306 },
 #endif
 #ifdef AUDIT_ARCH_SPARC64
-	{ AUDIT_ARCH_SPARC64,      14, 286, 169, 167, 349, 243, 214, 342 },
+	{ AUDIT_ARCH_SPARC64,      14, 286, 169, 167, 349, 243, 214, 342, 283, 327, 333, 306 },
 #endif
 #ifdef AUDIT_ARCH_MIPS
-	{ AUDIT_ARCH_MIPS,         14, 290, 224,  21,  -1, 141, 4116, 4348 },
+	{ AUDIT_ARCH_MIPS,         14, 290, 224,  21,  -1, 141, 4116, 4348, 4282, 4333, 4340, 4311 },
 #endif
 #ifdef AUDIT_ARCH_MIPSEL
-	{ AUDIT_ARCH_MIPSEL,       14, 290, 224,  21,  -1, 141, 4116, 4348 },
+	{ AUDIT_ARCH_MIPSEL,       14, 290, 224,  21,  -1, 141, 4116, 4348, 4282, 4333, 4340, 4311 },
 #endif
 #ifdef AUDIT_ARCH_MIPS64
-	{ AUDIT_ARCH_MIPS64,      131, 249, 180, 160,  -1, 141, 5097, 5307 },
+	{ AUDIT_ARCH_MIPS64,      131, 249, 180, 160,  -1, 141, 5097, 5307, 5241, 5292, 5299, 5270 },
 #endif
 #ifdef AUDIT_ARCH_MIPS64N32
-	{ AUDIT_ARCH_MIPS64N32,   131, 253, 180, 160,  -1, 141, 4116, 6312 },
+	{ AUDIT_ARCH_MIPS64N32,   131, 253, 180, 160,  -1, 141, 4116, 6312, 6245, 6296, 6304, 6274 },
 #endif
 #ifdef AUDIT_ARCH_MIPSEL64
-	{ AUDIT_ARCH_MIPSEL64,    131, 249, 180, 160,  -1, 141, 5097, 5307 },
+	{ AUDIT_ARCH_MIPSEL64,    131, 249, 180, 160,  -1, 141, 5097, 5307, 5241, 5292, 5299, 5270 },
 #endif
 #ifdef AUDIT_ARCH_MIPSEL64N32
-	{ AUDIT_ARCH_MIPSEL64N32, 131, 253, 180, 160,  -1, 141, 4116, 6312 },
+	{ AUDIT_ARCH_MIPSEL64N32, 131, 253, 180, 160,  -1, 141, 4116, 6312, 6245, 6296, 6304, 6274 },
 #endif
 #ifdef AUDIT_ARCH_LOONGARCH64
-	{ AUDIT_ARCH_LOONGARCH64, -1,  33,   5,  40, 280, 119, 179 },
+	{ AUDIT_ARCH_LOONGARCH64, -1,  33,   5,  40, 280, 119, 179, 273, 219, 241, 265, 104 },
 #endif
 };
 
@@ -222,6 +230,18 @@ static int seccomp_notify_get_syscall(struct seccomp_notif *req,
 		if (entry->nr_finit_module == req->data.nr)
 			return LXD_SECCOMP_NOTIFY_FINIT_MODULE;
 
+		if (entry->nr_keyctl == req->data.nr)
+			return LXD_SECCOMP_NOTIFY_KEYCTL;
+
+		if (entry->nr_perf_event_open == req->data.nr)
+			return LXD_SECCOMP_NOTIFY_PERF_EVENT_OPEN;
+
+		if (entry->nr_open_by_handle_at == req->data.nr)
+			return LXD_SECCOMP_NOTIFY_OPEN_BY_HANDLE_AT;
+
+		if (entry->nr_kexec_load == req->data.nr)
+			return LXD_SECCOMP_NOTIFY_KEXEC_LOAD;
+
 		break;
 	}
 
@@ -496,9 +516,12 @@ import (
 
 	"github.com/canonical/lxd/lxd/cgroup"
 	deviceConfig "github.com/canonical/lxd/lxd/device/config"
+	"github.com/canonical/lxd/lxd/events"
 	"github.com/canonical/lxd/lxd/idmap"
 	_ "github.com/canonical/lxd/lxd/include" // Used by cgo
+	"github.com/canonical/lxd/lxd/lifecycle"
 	"github.com/canonical/lxd/lxd/linux"
+	"github.com/canonical/lxd/lxd/operations"
 	"github.com/canonical/lxd/lxd/project"
 	"github.com/canonical/lxd/lxd/state"
 	"github.com/canonical/lxd/lxd/subprocess"
@@ -519,6 +542,10 @@ const lxdSeccompNotifyBpf = C.LXD_SECCOMP_NOTIFY_BPF
 const lxdSeccompNotifySchedSetscheduler = C.LXD_SECCOMP_NOTIFY_SCHED_SETSCHEDULER
 const lxdSeccompNotifySysinfo = C.LXD_SECCOMP_NOTIFY_SYSINFO
 const lxdSeccompNotifyFinitModule = C.LXD_SECCOMP_NOTIFY_FINIT_MODULE
+const lxdSeccompNotifyKeyctl = C.LXD_SECCOMP_NOTIFY_KEYCTL
+const lxdSeccompNotifyPerfEventOpen = C.LXD_SECCOMP_NOTIFY_PERF_EVENT_OPEN
+const lxdSeccompNotifyOpenByHandleAt = C.LXD_SECCOMP_NOTIFY_OPEN_BY_HANDLE_AT
+const lxdSeccompNotifyKexecLoad = C.LXD_SECCOMP_NOTIFY_KEXEC_LOAD
 
 const seccompHeader = `2
 `
@@ -556,6 +583,18 @@ const seccompNotifySysinfo = `sysinfo notify
 const seccompNotifyModule = `finit_module notify
 `
 
+const seccompNotifyKeyctl = `keyctl notify
+`
+
+const seccompNotifyPerfEventOpen = `perf_event_open notify
+`
+
+const seccompNotifyOpenByHandleAt = `open_by_handle_at notify
+`
+
+const seccompNotifyKexecLoad = `kexec_load notify
+`
+
 const seccompBlockNewMountAPI = `fsopen errno 38
 fsconfig errno 38
 fsinfo errno 38
@@ -658,6 +697,7 @@ type Instance interface {
 	DiskIdmap() (*idmap.IdmapSet, error)
 	IdmappedStorage(path string, fstype string) idmap.IdmapStorageType
 	InsertSeccompUnixDevice(prefix string, m deviceConfig.Device, pid int) error
+	Operation() *operations.Operation
 }
 
 var (
@@ -692,20 +732,13 @@ func InstanceNeedsPolicy(c Instance) bool {
 	}
 
 	// Check for boolean keys that default to false
-	keys = []string{
-		"security.syscalls.deny_compat",
-		"security.syscalls.intercept.mknod",
-		"security.syscalls.intercept.sched_setscheduler",
-		"security.syscalls.intercept.setxattr",
-		"security.syscalls.intercept.sysinfo",
-		"security.syscalls.intercept.mount",
-		"security.syscalls.intercept.bpf",
+	if shared.IsTrue(config["security.syscalls.deny_compat"]) {
+		return true
 	}
 
-	for _, k := range keys {
-		if shared.IsTrue(config[k]) {
-			return true
-		}
+	// Check for syscall interception
+	if len(enabledSyscallInterceptors(config)) > 0 {
+		return true
 	}
 
 	// Check for boolean keys that default to true
@@ -729,35 +762,11 @@ func InstanceNeedsIntercept(s *state.State, c Instance) (bool, error) {
 		return false, nil
 	}
 
-	config := c.ExpandedConfig()
-
-	var keys = map[string]func(state *state.State) error{
-		"security.syscalls.intercept.mknod":              lxcSupportSeccompNotify,
-		"security.syscalls.intercept.sched_setscheduler": lxcSupportSeccompNotify,
-		"security.syscalls.intercept.setxattr":           lxcSupportSeccompNotify,
-		"security.syscalls.intercept.sysinfo":            lxcSupportSeccompNotify,
-		"security.syscalls.intercept.mount":              lxcSupportSeccompNotifyContinue,
-		"security.syscalls.intercept.bpf":                lxcSupportSeccompNotifyAddfd,
-	}
-
 	needed := false
-	for key, check := range keys {
-		if shared.IsFalseOrEmpty(config[key]) {
-			continue
-		}
-
-		err := check(s)
-		if err != nil {
-			return needed, err
-		}
-
-		needed = true
-	}
-
-	if config["linux.kernel_modules.load"] == "ondemand" {
-		err := lxcSupportSeccompNotifyContinue(s)
+	for _, interceptor := range enabledSyscallInterceptors(c.ExpandedConfig()) {
+		err := interceptor.supported(s)
 		if err != nil {
-			return needed, err
+			return needed, fmt.Errorf("Syscall interception %q: %w", interceptor.name, err)
 		}
 
 		needed = true
@@ -789,64 +798,50 @@ func seccompGetPolicyContent(s *state.State, c Instance) (string, error) {
 		return raw, nil
 	}
 
+	// Syscall interception
+	ok, err := InstanceNeedsIntercept(s, c)
+	if err != nil {
+		return "", err
+	}
+
+	var interceptors []syscallInterceptor
+	if ok {
+		interceptors = enabledSyscallInterceptors(config)
+	}
+
+	// In complain mode, syscalls that would be denied are only logged.
+	// This doesn't apply to the entries protecting syscall interception.
+	denyPolicy := func(policy string) string { return policy }
+	allowlistHeader := "allowlist\n[all]\n"
+	if config["security.profile.mode"] == "complain" {
+		denyPolicy = seccompComplainPolicy
+		allowlistHeader = "allowlist log\n[all]\n"
+	}
+
 	// Policy header
 	policy := seccompHeader
 	allowlist := config["security.syscalls.allow"]
 
 	if allowlist != "" {
-		policy += "allowlist\n[all]\n"
+		policy += allowlistHeader
 		policy += allowlist
 	} else {
 		policy += "denylist\n[all]\n"
 
 		defaultFlag, ok := config["security.syscalls.deny_default"]
 		if !ok || shared.IsTrue(defaultFlag) {
-			policy += defaultSeccompPolicy
+			// Intercepted syscalls must reach the notifier rather than be denied outright.
+			policy += denyPolicy(filterSyscallPolicy(defaultSeccompPolicy, interceptedSyscalls(interceptors)))
 		}
 	}
 
-	// Syscall interception
-	ok, err := InstanceNeedsIntercept(s, c)
-	if err != nil {
-		return "", err
-	}
-
-	if ok {
+	if len(interceptors) > 0 {
 		// Prevent the container from overriding our syscall
 		// supervision.
 		policy += seccompNotifyDisallow
 
-		if shared.IsTrue(config["security.syscalls.intercept.mknod"]) {
-			policy += seccompNotifyMknod
-		}
-
-		if shared.IsTrue(config["security.syscalls.intercept.sched_setscheduler"]) {
-			policy += seccompNotifySchedSetscheduler
-		}
-
-		if shared.IsTrue(config["security.syscalls.intercept.setxattr"]) {
-			policy += seccompNotifySetxattr
-		}
-
-		if shared.IsTrue(config["security.syscalls.intercept.sysinfo"]) {
-			policy += seccompNotifySysinfo
-		}
-
-		if config["linux.kernel_modules.load"] == "ondemand" {
-			policy += seccompNotifyModule
-		}
-
-		if shared.IsTrue(config["security.syscalls.intercept.mount"]) {
-			policy += seccompNotifyMount
-			// We block the new mount api for now to simplify mount
-			// syscall interception. Since it keeps state over
-			// multiple syscalls we'd need more invasive changes to
-			// make this work.
-			policy += seccompBlockNewMountAPI
-		}
-
-		if shared.IsTrue(config["security.syscalls.intercept.bpf"]) {
-			policy += seccompNotifyBpf
+		for _, interceptor := range interceptors {
+			policy += interceptor.policy
 		}
 	}
 
@@ -862,17 +857,60 @@ func seccompGetPolicyContent(s *state.State, c Instance) (string, error) {
 			return "", err
 		}
 
-		policy += fmt.Sprintf(compatBlockingPolicy, arch)
+		policy += denyPolicy(fmt.Sprintf(compatBlockingPolicy, arch))
 	}
 
 	denylist := config["security.syscalls.deny"]
 	if denylist != "" {
-		policy += denylist
+		policy += denyPolicy(denylist)
 	}
 
 	return policy, nil
 }
 
+// seccompComplainPolicy turns the deny entries of a policy into log entries.
+// The matching syscalls are then allowed but reported to the kernel audit log.
+func seccompComplainPolicy(policy string) string {
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(policy, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "[") {
+			b.WriteString(line)
+			continue
+		}
+
+		// Same rule as the one LXC generates for reject_force_umount (MNT_FORCE).
+		if fields[0] == "reject_force_umount" {
+			b.WriteString("umount2 log [1,1,SCMP_CMP_MASKED_EQ,1]\n")
+			continue
+		}
+
+		args := fields[1:]
+		if len(args) > 1 && args[0] == "errno" {
+			args = args[2:]
+		} else if len(args) > 0 && (args[0] == "kill" || args[0] == "trap") {
+			args = args[1:]
+		} else if len(args) > 0 && !strings.HasPrefix(args[0], "[") && !strings.HasPrefix(args[0], "#") {
+			// Keep entries which don't deny the syscall.
+			b.WriteString(line)
+			continue
+		}
+
+		entry := []string{fields[0], "log"}
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "#") {
+				break
+			}
+
+			entry = append(entry, arg)
+		}
+
+		b.WriteString(strings.Join(entry, " ") + "\n")
+	}
+
+	return b.String()
+}
+
 // CreateProfile creates a seccomp profile.
 func CreateProfile(s *state.State, c Instance) error {
 	/* Unlike apparmor, there is no way to "cache" profiles, and profiles
@@ -2563,27 +2601,62 @@ func (s *Server) HandleBpfSyscall(c Instance, siov *Iovec) int {
 	return 0
 }
 
+// auditedSyscallEventWindow is the minimum delay between two events reporting the same audited syscall of an instance.
+const auditedSyscallEventWindow = 10 * time.Second
+
+// auditedSyscallEvents coalesces the events of audited syscalls per instance and syscall.
+var auditedSyscallEvents = events.NewCoalescer(auditedSyscallEventWindow)
+
+// HandleAuditedSyscall handles syscalls which are only intercepted to be reported.
+// An instance-syscall-intercepted lifecycle event is sent for the call, unless the same syscall of the instance was
+// already reported within auditedSyscallEventWindow, before the syscall is either handed back to the kernel (when
+// allow is true) or denied with ENOSYS, matching the default seccomp policy.
+func (s *Server) HandleAuditedSyscall(c Instance, siov *Iovec, syscall string, allow bool) int {
+	ctx := logger.Ctx{"container": c.Name(),
+		"project":               c.Project().Name,
+		"syscall":               syscall,
+		"syscall_number":        siov.req.data.nr,
+		"audit_architecture":    siov.req.data.arch,
+		"seccomp_notify_id":     siov.req.id,
+		"seccomp_notify_flags":  siov.req.flags,
+		"seccomp_notify_pid":    siov.req.pid,
+		"seccomp_notify_fd":     siov.notifyFd,
+		"seccomp_notify_mem_fd": siov.memFd,
+	}
+
+	defer logger.Debug("Handling audited syscall", ctx)
+
+	action := "deny"
+	if allow {
+		action = "allow"
+	}
+
+	send, suppressed := auditedSyscallEvents.Allow(c.Project().Name + "/" + c.Name() + "/" + syscall)
+	if send {
+		s.s.Events.SendLifecycle(c.Project().Name, lifecycle.InstanceSyscallIntercepted.Event(c, map[string]any{
+			"syscall":    syscall,
+			"pid":        int(siov.req.pid),
+			"action":     action,
+			"suppressed": suppressed,
+		}))
+	}
+
+	if !allow {
+		return int(-C.ENOSYS)
+	}
+
+	ctx["syscall_continue"] = "true"
+	C.seccomp_notify_update_response(siov.resp, 0, C.uint32_t(seccompUserNotifFlagContinue))
+	return 0
+}
+
 func (s *Server) handleSyscall(c Instance, siov *Iovec) int {
-	switch int(C.seccomp_notify_get_syscall(siov.req, siov.resp)) {
-	case lxdSeccompNotifyMknod:
-		return s.HandleMknodSyscall(c, siov)
-	case lxdSeccompNotifyMknodat:
-		return s.HandleMknodatSyscall(c, siov)
-	case lxdSeccompNotifySetxattr:
-		return s.HandleSetxattrSyscall(c, siov)
-	case lxdSeccompNotifyMount:
-		return s.HandleMountSyscall(c, siov)
-	case lxdSeccompNotifyBpf:
-		return s.HandleBpfSyscall(c, siov)
-	case lxdSeccompNotifySchedSetscheduler:
-		return s.HandleSchedSetschedulerSyscall(c, siov)
-	case lxdSeccompNotifySysinfo:
-		return s.HandleSysinfoSyscall(c, siov)
-	case lxdSeccompNotifyFinitModule:
-		return s.HandleFinitModuleSyscall(c, siov)
-	}
-
-	return int(-C.EINVAL)
+	handler := syscallHandlerFor(int(C.seccomp_notify_get_syscall(siov.req, siov.resp)))
+	if handler == nil {
+		return int(-C.EINVAL)
+	}
+
+	return handler(s, c, siov)
 }
 
 const seccompUserNotifFlagContinue uint32 = 0x00000001
@@ -0,0 +1,258 @@
+//go:build linux && cgo
+
+package seccomp
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/canonical/lxd/lxd/state"
+	"github.com/canonical/lxd/shared"
+)
+
+// syscallHandler handles an intercepted syscall and returns the errno to report to the caller.
+type syscallHandler func(s *Server, c Instance, siov *Iovec) int
+
+// syscallInterceptor describes a syscall interception that can be enabled on an instance.
+type syscallInterceptor struct {
+	// Name of the interception, enabled through the security.syscalls.intercept.<name> configuration key.
+	name string
+
+	// Seccomp policy entries notifying LXD of the syscalls.
+	policy string
+
+	// Optional function overriding whether the interception is enabled.
+	enabled func(config map[string]string) bool
+
+	// Function checking whether the host supports the interception.
+	supported func(s *state.State) error
+
+	// Handlers of the intercepted syscalls, indexed by lxdSeccompNotify value.
+	handlers map[int]syscallHandler
+}
+
+// isEnabled returns whether the interception is enabled by the instance configuration.
+func (i syscallInterceptor) isEnabled(config map[string]string) bool {
+	if i.enabled != nil {
+		return i.enabled(config)
+	}
+
+	return shared.IsTrue(config["security.syscalls.intercept."+i.name])
+}
+
+// syscalls returns the names of the syscalls notified to LXD by the policy.
+func (i syscallInterceptor) syscalls() []string {
+	var syscalls []string
+	for _, line := range strings.Split(i.policy, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[1] != "notify" || slices.Contains(syscalls, fields[0]) {
+			continue
+		}
+
+		syscalls = append(syscalls, fields[0])
+	}
+
+	return syscalls
+}
+
+// policyListsSyscall returns whether the seccomp policy entries list the syscall.
+// If unconditional is true, entries restricted to some syscall arguments are ignored.
+func policyListsSyscall(policy string, syscall string, unconditional bool) bool {
+	for _, line := range strings.Split(policy, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != syscall {
+			continue
+		}
+
+		if unconditional && slices.ContainsFunc(fields[1:], func(field string) bool { return strings.HasPrefix(field, "[") }) {
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// syscallAllowedByPolicy returns whether the seccomp policy of the instance allows the syscall when not intercepted.
+func syscallAllowedByPolicy(config map[string]string, syscall string) bool {
+	// In complain mode, syscalls that would be denied are only logged.
+	if config["security.profile.mode"] == "complain" {
+		return true
+	}
+
+	allowlist := config["security.syscalls.allow"]
+	if allowlist != "" {
+		return policyListsSyscall(allowlist, syscall, true)
+	}
+
+	defaultFlag, ok := config["security.syscalls.deny_default"]
+	if (!ok || shared.IsTrue(defaultFlag)) && policyListsSyscall(defaultSeccompPolicy, syscall, false) {
+		return false
+	}
+
+	return !policyListsSyscall(config["security.syscalls.deny"], syscall, false)
+}
+
+// auditedSyscallHandler returns a handler which reports calls to the syscall and then allows or denies them the same
+// way as the seccomp policy of the instance would without the interception.
+func auditedSyscallHandler(syscall string) syscallHandler {
+	return func(s *Server, c Instance, siov *Iovec) int {
+		return s.HandleAuditedSyscall(c, siov, syscall, syscallAllowedByPolicy(c.ExpandedConfig(), syscall))
+	}
+}
+
+// syscallInterceptors is the registry of supported syscall interceptions.
+// The seccomp policy entries are generated in this order.
+var syscallInterceptors = []syscallInterceptor{
+	{
+		name:      "mknod",
+		policy:    seccompNotifyMknod,
+		supported: lxcSupportSeccompNotify,
+		handlers: map[int]syscallHandler{
+			lxdSeccompNotifyMknod:   (*Server).HandleMknodSyscall,
+			lxdSeccompNotifyMknodat: (*Server).HandleMknodatSyscall,
+		},
+	},
+	{
+		name:      "sched_setscheduler",
+		policy:    seccompNotifySchedSetscheduler,
+		supported: lxcSupportSeccompNotify,
+		handlers: map[int]syscallHandler{
+			lxdSeccompNotifySchedSetscheduler: (*Server).HandleSchedSetschedulerSyscall,
+		},
+	},
+	{
+		name:      "setxattr",
+		policy:    seccompNotifySetxattr,
+		supported: lxcSupportSeccompNotify,
+		handlers: map[int]syscallHandler{
+			lxdSeccompNotifySetxattr: (*Server).HandleSetxattrSyscall,
+		},
+	},
+	{
+		name:      "sysinfo",
+		policy:    seccompNotifySysinfo,
+		supported: lxcSupportSeccompNotify,
+		handlers: map[int]syscallHandler{
+			lxdSeccompNotifySysinfo: (*Server).HandleSysinfoSyscall,
+		},
+	},
+	{
+		// Module loading is controlled by linux.kernel_modules.load rather than an intercept key.
+		name:   "finit_module",
+		policy: seccompNotifyModule,
+		enabled: func(config map[string]string) bool {
+			return config["linux.kernel_modules.load"] == "ondemand"
+		},
+		supported: lxcSupportSeccompNotifyContinue,
+		handlers: map[int]syscallHandler{
+			lxdSeccompNotifyFinitModule: (*Server).HandleFinitModuleSyscall,
+		},
+	},
+	{
+		name: "mount",
+		// We block the new mount api for now to simplify mount
+		// syscall interception. Since it keeps state over
+		// multiple syscalls we'd need more invasive changes to
+		// make this work.
+		policy:    seccompNotifyMount + seccompBlockNewMountAPI,
+		supported: lxcSupportSeccompNotifyContinue,
+		handlers: map[int]syscallHandler{
+			lxdSeccompNotifyMount: (*Server).HandleMountSyscall,
+		},
+	},
+	{
+		name:      "bpf",
+		policy:    seccompNotifyBpf,
+		supported: lxcSupportSeccompNotifyAddfd,
+		handlers: map[int]syscallHandler{
+			lxdSeccompNotifyBpf: (*Server).HandleBpfSyscall,
+		},
+	},
+	{
+		name:      "keyctl",
+		policy:    seccompNotifyKeyctl,
+		supported: lxcSupportSeccompNotifyContinue,
+		handlers: map[int]syscallHandler{
+			lxdSeccompNotifyKeyctl: auditedSyscallHandler("keyctl"),
+		},
+	},
+	{
+		name:      "perf_event_open",
+		policy:    seccompNotifyPerfEventOpen,
+		supported: lxcSupportSeccompNotifyContinue,
+		handlers: map[int]syscallHandler{
+			lxdSeccompNotifyPerfEventOpen: auditedSyscallHandler("perf_event_open"),
+		},
+	},
+	{
+		name:      "open_by_handle_at",
+		policy:    seccompNotifyOpenByHandleAt,
+		supported: lxcSupportSeccompNotifyContinue,
+		handlers: map[int]syscallHandler{
+			lxdSeccompNotifyOpenByHandleAt: auditedSyscallHandler("open_by_handle_at"),
+		},
+	},
+	{
+		name:      "kexec_load",
+		policy:    seccompNotifyKexecLoad,
+		supported: lxcSupportSeccompNotifyContinue,
+		handlers: map[int]syscallHandler{
+			lxdSeccompNotifyKexecLoad: auditedSyscallHandler("kexec_load"),
+		},
+	},
+}
+
+// enabledSyscallInterceptors returns the syscall interceptions enabled by the instance configuration.
+func enabledSyscallInterceptors(config map[string]string) []syscallInterceptor {
+	var interceptors []syscallInterceptor
+	for _, interceptor := range syscallInterceptors {
+		if interceptor.isEnabled(config) {
+			interceptors = append(interceptors, interceptor)
+		}
+	}
+
+	return interceptors
+}
+
+// interceptedSyscalls returns the names of the syscalls notified to LXD by the interceptions.
+func interceptedSyscalls(interceptors []syscallInterceptor) []string {
+	var syscalls []string
+	for _, interceptor := range interceptors {
+		syscalls = append(syscalls, interceptor.syscalls()...)
+	}
+
+	return syscalls
+}
+
+// filterSyscallPolicy removes the policy entries for the given syscalls.
+func filterSyscallPolicy(policy string, syscalls []string) string {
+	if len(syscalls) == 0 {
+		return policy
+	}
+
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(policy, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && slices.Contains(syscalls, fields[0]) {
+			continue
+		}
+
+		b.WriteString(line)
+	}
+
+	return b.String()
+}
+
+// syscallHandlerFor returns the handler of the syscall identified by its lxdSeccompNotify value.
+func syscallHandlerFor(syscall int) syscallHandler {
+	for _, interceptor := range syscallInterceptors {
+		handler, ok := interceptor.handlers[syscall]
+		if ok {
+			return handler
+		}
+	}
+
+	return nil
+}
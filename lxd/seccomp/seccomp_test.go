@@ -10,6 +10,7 @@ import (
 	"github.com/canonical/lxd/lxd/cgroup"
 	deviceConfig "github.com/canonical/lxd/lxd/device/config"
 	"github.com/canonical/lxd/lxd/idmap"
+	"github.com/canonical/lxd/lxd/operations"
 	"github.com/canonical/lxd/shared/api"
 )
 
@@ -35,6 +36,8 @@ func (m *mockInstance) InsertSeccompUnixDevice(prefix string, mDev deviceConfig.
 	return nil
 }
 
+func (m *mockInstance) Operation() *operations.Operation { return nil }
+
 func TestInstanceNeedsPolicy(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -96,6 +99,22 @@ func TestInstanceNeedsPolicy(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name: "security.syscalls.deny_default false but keyctl intercept",
+			config: map[string]string{
+				"security.syscalls.deny_default":     "false",
+				"security.syscalls.intercept.keyctl": "true",
+			},
+			expected: true,
+		},
+		{
+			name: "security.syscalls.deny_default false but kernel modules loaded on demand",
+			config: map[string]string{
+				"security.syscalls.deny_default": "false",
+				"linux.kernel_modules.load":      "ondemand",
+			},
+			expected: true,
+		},
 	}
 
 	for _, tc := range tests {
@@ -108,6 +127,133 @@ func TestInstanceNeedsPolicy(t *testing.T) {
 	}
 }
 
+func TestFilterSyscallPolicy(t *testing.T) {
+	interceptors := enabledSyscallInterceptors(map[string]string{
+		"security.syscalls.intercept.mount":      "true",
+		"security.syscalls.intercept.kexec_load": "true",
+	})
+
+	syscalls := interceptedSyscalls(interceptors)
+	if len(syscalls) != 2 || syscalls[0] != "mount" || syscalls[1] != "kexec_load" {
+		t.Fatalf("Unexpected intercepted syscalls %v", syscalls)
+	}
+
+	policy := filterSyscallPolicy(defaultSeccompPolicy, syscalls)
+	expected := `reject_force_umount  # comment this to allow umount -f;  not recommended
+[all]
+open_by_handle_at errno 38
+init_module errno 38
+delete_module errno 38
+`
+
+	if policy != expected {
+		t.Errorf("Expected policy %q, got %q", expected, policy)
+	}
+
+	if filterSyscallPolicy(defaultSeccompPolicy, nil) != defaultSeccompPolicy {
+		t.Error("Expected policy to be unchanged without intercepted syscalls")
+	}
+}
+
+func TestSyscallAllowedByPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]string
+		syscall  string
+		expected bool
+	}{
+		{
+			name:     "default policy denies kexec_load",
+			config:   map[string]string{},
+			syscall:  "kexec_load",
+			expected: false,
+		},
+		{
+			name:     "default policy allows keyctl",
+			config:   map[string]string{},
+			syscall:  "keyctl",
+			expected: true,
+		},
+		{
+			name: "deny_default false allows open_by_handle_at",
+			config: map[string]string{
+				"security.syscalls.deny_default": "false",
+			},
+			syscall:  "open_by_handle_at",
+			expected: true,
+		},
+		{
+			name: "deny list denies keyctl",
+			config: map[string]string{
+				"security.syscalls.deny": "keyctl errno 38",
+			},
+			syscall:  "keyctl",
+			expected: false,
+		},
+		{
+			name: "allow list allows listed syscall",
+			config: map[string]string{
+				"security.syscalls.allow": "read\nkexec_load\n",
+			},
+			syscall:  "kexec_load",
+			expected: true,
+		},
+		{
+			name: "allow list denies unlisted syscall",
+			config: map[string]string{
+				"security.syscalls.allow": "read\nperf_event_open [0,1,SCMP_CMP_EQ]\n",
+			},
+			syscall:  "perf_event_open",
+			expected: false,
+		},
+		{
+			name: "complain mode allows denied syscall",
+			config: map[string]string{
+				"security.profile.mode": "complain",
+			},
+			syscall:  "kexec_load",
+			expected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			allowed := syscallAllowedByPolicy(tc.config, tc.syscall)
+			if allowed != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, allowed)
+			}
+		})
+	}
+}
+
+func TestSeccompComplainPolicy(t *testing.T) {
+	policy := `reject_force_umount  # comment this to allow umount -f;  not recommended
+[all]
+kexec_load errno 38
+mknod notify [1,8192,SCMP_CMP_MASKED_EQ,61440]
+[x86_64]
+compat_sys_ioctl errno 38
+keyctl
+ptrace kill [0,16,SCMP_CMP_EQ]
+open_by_handle_at`
+
+	expected := `umount2 log [1,1,SCMP_CMP_MASKED_EQ,1]
+[all]
+kexec_load log
+mknod notify [1,8192,SCMP_CMP_MASKED_EQ,61440]
+[x86_64]
+compat_sys_ioctl log
+keyctl log
+ptrace log [0,16,SCMP_CMP_EQ]
+open_by_handle_at log
+`
+
+	result := seccompComplainPolicy(policy)
+	if result != expected {
+		t.Errorf("Expected policy %q, got %q", expected, result)
+	}
+}
+
 func TestTaskIDs(t *testing.T) {
 	pid := os.Getpid()
 	uid, gid, _, _, err := TaskIDs(pid)
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/canonical/lxd/lxd/apparmor"
+	"github.com/canonical/lxd/lxd/events"
+	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/instance/instancetype"
+	"github.com/canonical/lxd/lxd/state"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/version"
+)
+
+// Kernel audit record types which report operations allowed by a security profile in complain mode.
+const (
+	auditTypeSeccomp         = 1326 // AUDIT_SECCOMP
+	auditTypeAVC             = 1400 // AUDIT_AVC
+	auditTypeAppArmorAllowed = 1502 // AUDIT_APPARMOR_ALLOWED
+)
+
+// auditNetlinkGroupReadLog is the netlink multicast group receiving a copy of the kernel audit records.
+// This works whether or not an audit daemon is running (AUDIT_NLGRP_READLOG).
+const auditNetlinkGroupReadLog = 1
+
+// seccompRetLog is the code of syscalls that were allowed by a seccomp log action (SECCOMP_RET_LOG).
+const seccompRetLog = "0x7ffc0000"
+
+// securityAuditLogFile is the instance log file recording the operations reported in complain mode.
+const securityAuditLogFile = "security.log"
+
+// securityAuditLogMaxSize is the size in bytes above which the security audit log is rotated.
+// Only the previous log is kept, as security.log.old.
+const securityAuditLogMaxSize = 1024 * 1024
+
+// securityAuditEventWindow is the minimum delay between two security events reporting the same operation of an
+// instance. All operations are still recorded in the security audit log.
+const securityAuditEventWindow = 10 * time.Second
+
+// securityAuditProfileRefresh is the minimum delay between reloads of the instances in complain mode.
+const securityAuditProfileRefresh = 5 * time.Second
+
+// StartSecurityAuditListener listens to the kernel audit log for the operations which the security profiles of
+// instances in complain mode would have denied.
+// These are recorded in the security.log file of the instance and sent as security events.
+func StartSecurityAuditListener(ctx context.Context, s *state.State) error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_AUDIT)
+	if err != nil {
+		return fmt.Errorf("Failed creating audit netlink socket: %w", err)
+	}
+
+	err = unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: auditNetlinkGroupReadLog})
+	if err != nil {
+		_ = unix.Close(fd)
+		return fmt.Errorf("Failed subscribing to the kernel audit log: %w", err)
+	}
+
+	// Wake up regularly to notice the context being cancelled.
+	err = unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &unix.Timeval{Sec: 1})
+	if err != nil {
+		_ = unix.Close(fd)
+		return fmt.Errorf("Failed setting audit netlink socket timeout: %w", err)
+	}
+
+	go func() {
+		defer func() { _ = unix.Close(fd) }()
+
+		auditor := newSecurityAuditor(s)
+		buf := make([]byte, 16384)
+
+		for ctx.Err() == nil {
+			n, _, err := unix.Recvfrom(fd, buf, 0)
+			if err != nil {
+				// ENOBUFS means that records were dropped as they came in faster than they were handled.
+				if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) || errors.Is(err, unix.ENOBUFS) {
+					continue
+				}
+
+				logger.Warn("Failed reading from the kernel audit log", logger.Ctx{"err": err})
+				return
+			}
+
+			msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+			if err != nil {
+				continue
+			}
+
+			for _, msg := range msgs {
+				auditor.handle(int(msg.Header.Type), strings.TrimRight(string(msg.Data), "\x00\n"))
+			}
+		}
+	}()
+
+	return nil
+}
+
+// securityAuditor matches kernel audit records to the instances in complain mode.
+type securityAuditor struct {
+	s *state.State
+
+	// Containers in complain mode, keyed by their AppArmor profile name.
+	profiles   map[string]instance.Instance
+	profilesAt time.Time
+
+	// Function reporting the operations of the instances in complain mode.
+	report func(inst instance.Instance, mechanism string, operation string, record string)
+
+	// Security events sent per instance and operation.
+	events *events.Coalescer
+}
+
+// newSecurityAuditor returns a securityAuditor reporting the operations to the instance logs and as events.
+func newSecurityAuditor(s *state.State) *securityAuditor {
+	a := &securityAuditor{
+		s:      s,
+		events: events.NewCoalescer(securityAuditEventWindow),
+	}
+
+	a.report = a.record
+
+	return a
+}
+
+// handle reports the audit record if it comes from an instance in complain mode.
+func (a *securityAuditor) handle(recordType int, record string) {
+	if recordType != auditTypeSeccomp && recordType != auditTypeAVC && recordType != auditTypeAppArmorAllowed {
+		return
+	}
+
+	// Most records come from processes outside of any instance in complain mode, skip them before any parsing.
+	a.refreshProfiles()
+	if len(a.profiles) == 0 {
+		return
+	}
+
+	var inst instance.Instance
+	var mechanism, operation string
+
+	// Skip the "audit(<time>:<serial>): " prefix.
+	_, record, _ = strings.Cut(record, "): ")
+	fields := parseAuditRecord(record)
+
+	switch recordType {
+	case auditTypeSeccomp:
+		if fields["code"] != seccompRetLog {
+			return
+		}
+
+		pid, err := strconv.Atoi(fields["pid"])
+		if err != nil {
+			return
+		}
+
+		inst = a.pidInstance(pid)
+		mechanism = "seccomp"
+		operation = "syscall " + fields["syscall"]
+	default:
+		if fields["apparmor"] != "ALLOWED" {
+			return
+		}
+
+		// Nested profiles and hats are reported as children of the container profile.
+		profile, _, _ := strings.Cut(fields["profile"], "//")
+
+		inst = a.profiles[profile]
+		mechanism = "apparmor"
+		operation = fields["operation"]
+	}
+
+	if inst == nil {
+		return
+	}
+
+	a.report(inst, mechanism, operation, record)
+}
+
+// record appends the operation to the security audit log of the instance and sends it as a security event.
+// Repeated operations are only sent as an event once per securityAuditEventWindow.
+func (a *securityAuditor) record(inst instance.Instance, mechanism string, operation string, record string) {
+	logPath := filepath.Join(inst.LogPath(), securityAuditLogFile)
+	err := appendSecurityAuditLog(logPath, time.Now().UTC().Format(time.RFC3339)+" "+mechanism+": "+record+"\n")
+	if err != nil {
+		logger.Warn("Failed writing security audit log", logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "err": err})
+	}
+
+	send, suppressed := a.events.Allow(inst.Project().Name + "/" + inst.Name() + "/" + mechanism + "/" + operation)
+	if !send {
+		return
+	}
+
+	err = a.s.Events.Send(inst.Project().Name, api.EventTypeSecurity, api.EventSecurity{
+		Mechanism:  mechanism,
+		Operation:  operation,
+		Message:    record,
+		Suppressed: suppressed,
+		Source:     api.NewURL().Path(version.APIVersion, "instances", inst.Name()).Project(inst.Project().Name).String(),
+		Name:       inst.Name(),
+		Project:    inst.Project().Name,
+	})
+	if err != nil {
+		logger.Warn("Failed sending security event", logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "err": err})
+	}
+}
+
+// refreshProfiles reloads the containers in complain mode if they haven't been loaded recently.
+func (a *securityAuditor) refreshProfiles() {
+	if time.Since(a.profilesAt) < securityAuditProfileRefresh {
+		return
+	}
+
+	a.profilesAt = time.Now()
+
+	instances, err := instance.LoadNodeAll(a.s, instancetype.Container)
+	if err != nil {
+		logger.Warn("Failed loading instances for security audit", logger.Ctx{"err": err})
+		return
+	}
+
+	a.profiles = map[string]instance.Instance{}
+	for _, inst := range instances {
+		if inst.ExpandedConfig()["security.profile.mode"] == "complain" {
+			a.profiles[apparmor.InstanceProfileName(inst)] = inst
+		}
+	}
+}
+
+// pidInstance returns the container in complain mode running the process.
+// The process is matched on its PID namespace, processes which can't be matched are silently ignored as the audit
+// log reports processes from the whole system, many of them short lived.
+func (a *securityAuditor) pidInstance(pid int) instance.Instance {
+	pidNamespace, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", pid))
+	if err != nil {
+		return nil
+	}
+
+	for _, inst := range a.profiles {
+		initPID := inst.InitPID()
+		if initPID <= 0 {
+			continue
+		}
+
+		initPIDNamespace, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", initPID))
+		if err == nil && initPIDNamespace == pidNamespace {
+			return inst
+		}
+	}
+
+	return nil
+}
+
+// appendSecurityAuditLog appends the entry to the security audit log file.
+// The log is rotated once it would grow beyond securityAuditLogMaxSize, replacing any previously rotated log.
+func appendSecurityAuditLog(path string, entry string) error {
+	fi, err := os.Stat(path)
+	if err == nil && fi.Size()+int64(len(entry)) > securityAuditLogMaxSize {
+		err = os.Rename(path, path+".old")
+		if err != nil {
+			return fmt.Errorf("Failed rotating security audit log: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteString(entry)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// parseAuditRecord parses the key=value fields of a kernel audit record.
+// Quoted values have their quotes removed.
+func parseAuditRecord(record string) map[string]string {
+	fields := map[string]string{}
+
+	for {
+		record = strings.TrimLeft(record, " ")
+
+		key, rest, found := strings.Cut(record, "=")
+		if !found {
+			return fields
+		}
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, " ")
+		}
+
+		fields[key] = value
+		record = rest
+	}
+}
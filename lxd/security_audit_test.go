@@ -0,0 +1,151 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/lxd/lxd/instance"
+)
+
+func TestParseAuditRecord(t *testing.T) {
+	tests := []struct {
+		name     string
+		record   string
+		expected map[string]string
+	}{
+		{
+			"AppArmor",
+			`apparmor="ALLOWED" operation="mount" class="mount" info="failed flags match" profile="lxd-c1_</var/lib/lxd>" name="/mnt/" pid=1234 comm="mount" flags="rw"`,
+			map[string]string{
+				"apparmor":  "ALLOWED",
+				"operation": "mount",
+				"class":     "mount",
+				"info":      "failed flags match",
+				"profile":   "lxd-c1_</var/lib/lxd>",
+				"name":      "/mnt/",
+				"pid":       "1234",
+				"comm":      "mount",
+				"flags":     "rw",
+			},
+		},
+		{
+			"Seccomp",
+			`auid=4294967295 uid=1000000 gid=1000000 ses=4294967295 pid=4321 comm="keyctl" exe="/usr/bin/keyctl" sig=0 arch=c000003e syscall=250 compat=0 ip=0x7f2d3c71a88d code=0x7ffc0000`,
+			map[string]string{
+				"auid":    "4294967295",
+				"uid":     "1000000",
+				"gid":     "1000000",
+				"ses":     "4294967295",
+				"pid":     "4321",
+				"comm":    "keyctl",
+				"exe":     "/usr/bin/keyctl",
+				"sig":     "0",
+				"arch":    "c000003e",
+				"syscall": "250",
+				"compat":  "0",
+				"ip":      "0x7f2d3c71a88d",
+				"code":    "0x7ffc0000",
+			},
+		},
+		{
+			"Empty",
+			"",
+			map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseAuditRecord(tt.record))
+		})
+	}
+}
+
+// auditTestInstance is an instance in complain mode, only implementing what the auditor uses to match records.
+type auditTestInstance struct {
+	instance.Instance
+}
+
+type auditTestReport struct {
+	mechanism string
+	operation string
+}
+
+// newTestSecurityAuditor returns an auditor with the given containers in complain mode, keyed by AppArmor profile,
+// and the list its reports are appended to.
+func newTestSecurityAuditor(profiles map[string]instance.Instance) (*securityAuditor, *[]auditTestReport) {
+	reports := []auditTestReport{}
+
+	auditor := &securityAuditor{profiles: profiles, profilesAt: time.Now()}
+	auditor.report = func(_ instance.Instance, mechanism string, operation string, _ string) {
+		reports = append(reports, auditTestReport{mechanism: mechanism, operation: operation})
+	}
+
+	return auditor, &reports
+}
+
+func TestSecurityAuditorHandleWithoutComplainMode(t *testing.T) {
+	// No instance in complain mode, records must be skipped without loading or reporting anything.
+	auditor, reports := newTestSecurityAuditor(nil)
+
+	auditor.handle(auditTypeSeccomp, `audit(1700000000.123:42): pid=1 comm="init" syscall=250 code=0x7ffc0000`)
+	auditor.handle(auditTypeAVC, `audit(1700000000.123:43): apparmor="ALLOWED" operation="mount" profile="lxd-c1_</var/lib/lxd>" pid=1`)
+
+	assert.Empty(t, *reports)
+}
+
+func TestSecurityAuditorHandle(t *testing.T) {
+	auditor, reports := newTestSecurityAuditor(map[string]instance.Instance{
+		"lxd-c1_</var/lib/lxd>": &auditTestInstance{},
+	})
+
+	// Operations allowed by the profile of the container in complain mode are reported, including from child profiles.
+	auditor.handle(auditTypeAVC, `audit(1700000000.123:43): apparmor="ALLOWED" operation="mount" profile="lxd-c1_</var/lib/lxd>" pid=1`)
+	auditor.handle(auditTypeAppArmorAllowed, `audit(1700000000.123:44): apparmor="ALLOWED" operation="open" profile="lxd-c1_</var/lib/lxd>//nested" pid=1`)
+
+	// Operations from other profiles, denials and other record types aren't.
+	auditor.handle(auditTypeAVC, `audit(1700000000.123:45): apparmor="ALLOWED" operation="mount" profile="lxd-c2_</var/lib/lxd>" pid=1`)
+	auditor.handle(auditTypeAVC, `audit(1700000000.123:46): apparmor="DENIED" operation="mount" profile="lxd-c1_</var/lib/lxd>" pid=1`)
+	auditor.handle(1300, `audit(1700000000.123:47): arch=c000003e syscall=165 success=yes`)
+
+	// Seccomp records which don't come from a log action aren't either.
+	auditor.handle(auditTypeSeccomp, `audit(1700000000.123:48): pid=1 comm="init" syscall=250 code=0x80000000`)
+
+	assert.Equal(t, []auditTestReport{
+		{mechanism: "apparmor", operation: "mount"},
+		{mechanism: "apparmor", operation: "open"},
+	}, *reports)
+}
+
+func TestAppendSecurityAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), securityAuditLogFile)
+
+	err := appendSecurityAuditLog(path, "first\n")
+	require.NoError(t, err)
+
+	// Fill the log up to its maximum size.
+	err = appendSecurityAuditLog(path, strings.Repeat("x", securityAuditLogMaxSize-len("first\n")))
+	require.NoError(t, err)
+
+	_, err = os.Stat(path + ".old")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	// The next entry rotates the log.
+	err = appendSecurityAuditLog(path, "last\n")
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "last\n", string(content))
+
+	fi, err := os.Stat(path + ".old")
+	require.NoError(t, err)
+	assert.Equal(t, int64(securityAuditLogMaxSize), fi.Size())
+}
@@ -379,6 +379,49 @@ func (b *lxdBackend) GetResources() (*api.ResourcesStoragePool, error) {
 	return b.driver.GetResources()
 }
 
+// CheckHealth runs the driver specific health checks of the pool.
+func (b *lxdBackend) CheckHealth() (*api.StoragePoolHealth, error) {
+	l := b.logger.AddContext(nil)
+	l.Debug("CheckHealth started")
+	defer l.Debug("CheckHealth finished")
+
+	err := b.isStatusReady()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.driver.CheckHealth()
+}
+
+// Scrub starts verifying the integrity of the data in the pool.
+func (b *lxdBackend) Scrub() error {
+	l := b.logger.AddContext(nil)
+	l.Debug("Scrub started")
+	defer l.Debug("Scrub finished")
+
+	err := b.isStatusReady()
+	if err != nil {
+		return err
+	}
+
+	return b.driver.Scrub()
+}
+
+// AutoExtend grows the pool if its usage is above the configured threshold.
+// Returns true if the pool was extended.
+func (b *lxdBackend) AutoExtend() (bool, error) {
+	l := b.logger.AddContext(nil)
+	l.Debug("AutoExtend started")
+	defer l.Debug("AutoExtend finished")
+
+	err := b.isStatusReady()
+	if err != nil {
+		return false, err
+	}
+
+	return b.driver.AutoExtend()
+}
+
 // IsUsed returns whether the storage pool is used by any volumes or profiles (excluding image volumes).
 func (b *lxdBackend) IsUsed() (bool, error) {
 	usedBy, err := UsedBy(context.TODO(), b.state, b, true, true, cluster.StoragePoolVolumeTypeNameImage)
@@ -474,12 +517,13 @@ func (b *lxdBackend) Delete(clientType request.ClientType, op *operations.Operat
 	}
 
 	if clientType != request.ClientTypeNormal && b.driver.Info().Remote {
-		if b.driver.Info().MountedRoot {
-			_, err := b.driver.Unmount()
-			if err != nil {
-				return err
-			}
-		} else {
+		// Release the pool on this member, e.g. leave the lock space of a shared volume group.
+		_, err := b.driver.Unmount()
+		if err != nil {
+			return err
+		}
+
+		if !b.driver.Info().MountedRoot {
 			// Remote storage may have leftover entries caused by
 			// volumes that were moved or delete while a particular system was offline.
 			err := os.RemoveAll(path)
@@ -3043,6 +3087,157 @@ func (b *lxdBackend) DeleteInstance(inst instance.Instance, op *operations.Opera
 	return nil
 }
 
+// MoveInstanceLive copies the root volume of a running VM from its current pool to this pool and then calls mirror
+// with the path of the new root disk so that the VM can switch over to it without stopping.
+// The source volume is left in place as the VM keeps its config filesystem open until it stops, after which it must
+// be removed with DeleteInstanceLiveMoveSource on the source pool.
+func (b *lxdBackend) MoveInstanceLive(inst instance.Instance, mirror func(diskPath string) error, op *operations.Operation) error {
+	l := b.logger.AddContext(logger.Ctx{"project": inst.Project().Name, "instance": inst.Name()})
+	l.Debug("MoveInstanceLive started")
+	defer l.Debug("MoveInstanceLive finished")
+
+	err := b.isStatusReady()
+	if err != nil {
+		return err
+	}
+
+	if inst.Type() != instancetype.VM || inst.IsSnapshot() || !inst.IsRunning() {
+		return errors.New("Only running virtual machines can be moved live")
+	}
+
+	srcPool, err := LoadByInstance(b.state, inst)
+	if err != nil {
+		return err
+	}
+
+	srcPoolBackend, ok := srcPool.(*lxdBackend)
+	if !ok {
+		return errors.New("Source pool is not a lxdBackend")
+	}
+
+	if srcPool.Name() == b.Name() {
+		return fmt.Errorf("Instance is already on storage pool %q", b.Name())
+	}
+
+	volStorageName := project.Instance(inst.Project().Name, inst.Name())
+	srcMountPath := drivers.GetVolumeMountPath(srcPool.Name(), drivers.VolumeTypeVM, volStorageName)
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// The copy is inconsistent as the guest keeps writing to its disk, the mirror catches up with those writes.
+	err = b.CreateInstanceFromCopy(inst, inst, false, true, op)
+	if err != nil {
+		return err
+	}
+
+	// Point the instance symlink back at the source volume as the VM keeps using it.
+	revert.Add(func() {
+		_ = b.DeleteInstance(inst, op)
+		_ = srcPoolBackend.ensureInstanceSymlink(inst.Type(), inst.Project().Name, inst.Name(), srcMountPath)
+	})
+
+	// Keep the new volume mounted for the lifetime of the VM like the volume it replaces.
+	mountInfo, err := b.MountInstance(inst, op)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() { _ = b.UnmountInstance(inst, op) })
+
+	diskSource, ok := mountInfo.DevSource.(config.DevSourcePath)
+	if !ok || diskSource.Path == "" {
+		return fmt.Errorf("Storage pool %q doesn't provide a local disk path for the instance", b.Name())
+	}
+
+	err = mirror(diskSource.Path)
+	if err != nil {
+		return err
+	}
+
+	revert.Success()
+	return nil
+}
+
+// DeleteInstanceLiveMoveSource removes the root volume left on this pool by MoveInstanceLive once the VM has stopped.
+// The volume is mounted while preDelete is called with its mount path so that any state can be carried over.
+// Unlike DeleteInstance, the instance symlink is left pointing at the volume on the new pool.
+func (b *lxdBackend) DeleteInstanceLiveMoveSource(inst instance.Instance, preDelete func(mountPath string) error, op *operations.Operation) error {
+	l := b.logger.AddContext(logger.Ctx{"project": inst.Project().Name, "instance": inst.Name()})
+	l.Debug("DeleteInstanceLiveMoveSource started")
+	defer l.Debug("DeleteInstanceLiveMoveSource finished")
+
+	volType, err := InstanceTypeToVolumeType(inst.Type())
+	if err != nil {
+		return err
+	}
+
+	dbVol, err := VolumeDBGet(b, inst.Project().Name, inst.Name(), volType)
+	if err != nil {
+		if response.IsNotFoundError(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	volStorageName := project.Instance(inst.Project().Name, inst.Name())
+	vol := b.GetVolume(volType, InstanceContentType(inst), volStorageName, dbVol.Config)
+
+	volExists, err := b.driver.HasVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	if volExists {
+		err = b.driver.MountVolume(vol, op)
+		if err != nil {
+			return err
+		}
+
+		if preDelete != nil {
+			err = preDelete(vol.MountPath())
+			if err != nil {
+				_, _ = b.driver.UnmountVolume(vol, false, op)
+				return err
+			}
+		}
+
+		// Drop our own reference as well as the one taken when the VM was started.
+		// Give up if other references don't go away, rather than waiting forever.
+		waitDuration := time.Second * 30
+		waitUntil := time.Now().Add(waitDuration)
+		for {
+			_, err = b.driver.UnmountVolume(vol, false, op)
+			if !errors.Is(err, drivers.ErrInUse) {
+				break
+			}
+
+			if time.Now().After(waitUntil) {
+				return fmt.Errorf("Failed unmounting storage volume after %v: %w", waitDuration, err)
+			}
+
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		if err != nil {
+			return fmt.Errorf("Failed unmounting storage volume: %w", err)
+		}
+
+		err = b.driver.DeleteVolume(vol, op)
+		if err != nil {
+			return fmt.Errorf("Error deleting storage volume: %w", err)
+		}
+	}
+
+	err = VolumeDBDelete(b, inst.Project().Name, inst.Name(), vol.Type())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // instanceVolumeConfigPolicy stores immutable config keys for instance root volumes.
 var instanceVolumeConfigPolicy = api.ConfigKeyPolicy{
 	Immutable: []string{
@@ -3050,6 +3245,8 @@ var instanceVolumeConfigPolicy = api.ConfigKeyPolicy{
 		"size",
 		"size.state",
 		"block.filesystem",
+		"block.encryption",
+		"volatile.encryption.key",
 	},
 }
 
@@ -3057,7 +3254,9 @@ var instanceVolumeConfigPolicy = api.ConfigKeyPolicy{
 var customVolumeConfigPolicy = api.ConfigKeyPolicy{
 	Immutable: []string{
 		"block.filesystem",
+		"block.encryption",
 		"volatile.uuid",
+		"volatile.encryption.key",
 	},
 }
 
@@ -4442,6 +4641,11 @@ func (b *lxdBackend) EnsureImage(fingerprint string, op *operations.Operation, p
 func (b *lxdBackend) shouldUseOptimizedImage(fingerprint string, contentType drivers.ContentType, volConfig map[string]string) (bool, error) {
 	canOptimizeImage := b.driver.Info().OptimizedImages
 
+	// Encrypted volumes are never created from optimized images as each volume has its own key.
+	if volConfig["block.encryption"] != "" || b.db.Config["volume.block.encryption"] != "" {
+		return false, nil
+	}
+
 	// If the volume config is empty, the default pool configuration is used, making the driver's support
 	// for optimized images the determining factor. However, an optimized image cannot be utilized if the
 	// driver lacks support for it.
@@ -5159,6 +5363,15 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(projectName string, srcProjectNa
 		volStorageName := project.StorageVolume(projectName, volName)
 		vol := b.GetNewVolume(drivers.VolumeTypeCustom, contentType, volStorageName, config)
 
+		// Same-pool copies of encrypted volumes are clones of the source data, including its encryption header,
+		// so they deliberately share the key of the source volume rather than getting their own.
+		// Re-encrypting the copy would require copying the decrypted data, defeating cheap driver clones.
+		// Copies to another pool get their own key, see CustomVolumeMigrationTypes.
+		if srcVol.IsEncrypted() {
+			vol.Config()["block.encryption"] = srcVol.Config()["block.encryption"]
+			vol.Config()["volatile.encryption.key"] = srcVol.Config()["volatile.encryption.key"]
+		}
+
 		// Validate config and create database entry for new storage volume.
 		err = VolumeDBCreate(b, projectName, volName, desc, vol.Type(), false, vol.Config(), time.Now().UTC(), time.Time{}, vol.ContentType(), false, true)
 		if err != nil {
@@ -5216,7 +5429,7 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(projectName string, srcProjectNa
 	l.Debug("CreateCustomVolumeFromCopy cross-pool mode detected")
 
 	// Negotiate the migration type to use.
-	offeredTypes := srcPool.MigrationTypes(contentType, false, snapshots)
+	offeredTypes := CustomVolumeMigrationTypes(srcPool, contentType, srcVol.Config(), false, snapshots)
 	offerHeader := migration.TypesToHeader(offeredTypes...)
 	migrationTypes, err := migration.MatchTypes(offerHeader, FallbackMigrationType(contentType), b.MigrationTypes(contentType, false, snapshots))
 	if err != nil {
@@ -6811,6 +7024,193 @@ func (b *lxdBackend) RestoreCustomVolume(projectName, volName string, snapshotNa
 	return nil
 }
 
+// customVolumeSnapshot returns the driver volume of a custom volume snapshot.
+func (b *lxdBackend) customVolumeSnapshot(projectName string, volName string) (drivers.Volume, error) {
+	if !shared.IsSnapshot(volName) {
+		return drivers.Volume{}, errors.New("Volume must be a snapshot")
+	}
+
+	volume, err := VolumeDBGet(b, projectName, volName, drivers.VolumeTypeCustom)
+	if err != nil {
+		return drivers.Volume{}, err
+	}
+
+	// Get the volume name on storage.
+	volStorageName := project.StorageVolume(projectName, volName)
+	vol := b.GetVolume(drivers.VolumeTypeCustom, drivers.ContentType(volume.ContentType), volStorageName, volume.Config)
+
+	// Set the parent volume's UUID.
+	if b.driver.Info().PopulateParentVolumeUUID {
+		parentUUID, err := b.getParentVolumeUUID(vol, projectName)
+		if err != nil {
+			return drivers.Volume{}, err
+		}
+
+		vol.SetParentUUID(parentUUID)
+	}
+
+	return vol, nil
+}
+
+// MountCustomVolumeSnapshot mounts a custom volume snapshot. It is mounted as read only so that the
+// snapshot cannot be modified.
+func (b *lxdBackend) MountCustomVolumeSnapshot(projectName string, volName string, op *operations.Operation) (*MountInfo, error) {
+	l := b.logger.AddContext(logger.Ctx{"project": projectName, "volName": volName})
+	l.Debug("MountCustomVolumeSnapshot started")
+	defer l.Debug("MountCustomVolumeSnapshot finished")
+
+	err := b.isStatusReady()
+	if err != nil {
+		return nil, err
+	}
+
+	vol, err := b.customVolumeSnapshot(projectName, volName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.driver.MountVolumeSnapshot(vol, op)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MountInfo{}, nil
+}
+
+// UnmountCustomVolumeSnapshot unmounts a custom volume snapshot.
+func (b *lxdBackend) UnmountCustomVolumeSnapshot(projectName string, volName string, op *operations.Operation) (bool, error) {
+	l := b.logger.AddContext(logger.Ctx{"project": projectName, "volName": volName})
+	l.Debug("UnmountCustomVolumeSnapshot started")
+	defer l.Debug("UnmountCustomVolumeSnapshot finished")
+
+	vol, err := b.customVolumeSnapshot(projectName, volName)
+	if err != nil {
+		return false, err
+	}
+
+	return b.driver.UnmountVolumeSnapshot(vol, op)
+}
+
+// DiffVolume returns the paths that were added, modified or deleted in a custom or container file system volume
+// between one of its snapshots and either a later snapshot or the volume itself if toSnapshot is empty.
+// The comparison stops once ctx is done.
+func (b *lxdBackend) DiffVolume(ctx context.Context, projectName string, volType drivers.VolumeType, volName string, fromSnapshot string, toSnapshot string, op *operations.Operation) ([]api.StorageVolumeDiff, error) {
+	l := b.logger.AddContext(logger.Ctx{"project": projectName, "volName": volName, "volType": volType, "fromSnapshot": fromSnapshot, "toSnapshot": toSnapshot})
+	l.Debug("DiffVolume started")
+	defer l.Debug("DiffVolume finished")
+
+	err := b.isStatusReady()
+	if err != nil {
+		return nil, err
+	}
+
+	if volType != drivers.VolumeTypeCustom && volType != drivers.VolumeTypeContainer {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Only custom and container volumes can be compared")
+	}
+
+	getVolume := func(name string) (drivers.Volume, error) {
+		dbVol, err := VolumeDBGet(b, projectName, name, volType)
+		if err != nil {
+			return drivers.Volume{}, err
+		}
+
+		if dbVol.ContentType != cluster.StoragePoolVolumeContentTypeNameFS {
+			return drivers.Volume{}, api.StatusErrorf(http.StatusBadRequest, "Only file system volumes can be compared")
+		}
+
+		volStorageName := project.StorageVolume(projectName, name)
+		if volType == drivers.VolumeTypeContainer {
+			volStorageName = project.Instance(projectName, name)
+		}
+
+		vol := b.GetVolume(volType, drivers.ContentTypeFS, volStorageName, dbVol.Config)
+
+		// Set the parent volume's UUID.
+		if b.driver.Info().PopulateParentVolumeUUID {
+			parentUUID, err := b.getParentVolumeUUID(vol, projectName)
+			if err != nil {
+				return drivers.Volume{}, err
+			}
+
+			vol.SetParentUUID(parentUUID)
+		}
+
+		return vol, nil
+	}
+
+	vol, err := getVolume(volName)
+	if err != nil {
+		return nil, err
+	}
+
+	fromVol, err := getVolume(drivers.GetSnapshotVolumeName(volName, fromSnapshot))
+	if err != nil {
+		return nil, err
+	}
+
+	// The volume is also mounted when comparing two snapshots as some drivers need it to list their changes.
+	err = b.driver.MountVolume(vol, op)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _, _ = b.driver.UnmountVolume(vol, false, op) }()
+
+	err = b.driver.MountVolumeSnapshot(fromVol, op)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _, _ = b.driver.UnmountVolumeSnapshot(fromVol, op) }()
+
+	toVol := vol
+	if toSnapshot != "" {
+		toVol, err = getVolume(drivers.GetSnapshotVolumeName(volName, toSnapshot))
+		if err != nil {
+			return nil, err
+		}
+
+		err = b.driver.MountVolumeSnapshot(toVol, op)
+		if err != nil {
+			return nil, err
+		}
+
+		defer func() { _, _ = b.driver.UnmountVolumeSnapshot(toVol, op) }()
+	}
+
+	diffs, err := b.driver.DiffVolume(ctx, fromVol, toVol, op)
+	if err != nil {
+		return nil, fmt.Errorf("Failed comparing volume %q with snapshot %q: %w", volName, fromSnapshot, err)
+	}
+
+	// Only report the files of the root file system of containers, relative to it.
+	if volType == drivers.VolumeTypeContainer {
+		rootfsDiffs := make([]api.StorageVolumeDiff, 0, len(diffs))
+		for _, diff := range diffs {
+			if diff.Path == "/rootfs" {
+				diff.Path = "/"
+			} else {
+				path, ok := strings.CutPrefix(diff.Path, "/rootfs/")
+				if !ok {
+					continue
+				}
+
+				diff.Path = "/" + path
+			}
+
+			rootfsDiffs = append(rootfsDiffs, diff)
+		}
+
+		diffs = rootfsDiffs
+	}
+
+	slices.SortStableFunc(diffs, func(x api.StorageVolumeDiff, y api.StorageVolumeDiff) int {
+		return strings.Compare(x.Path, y.Path)
+	})
+
+	return diffs, nil
+}
+
 func (b *lxdBackend) createStorageStructure(path string) error {
 	for _, volType := range b.driver.Info().VolumeTypes {
 		for _, name := range drivers.BaseDirectories[volType].Paths {
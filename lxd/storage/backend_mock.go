@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"io"
 	"net/url"
 	"os"
@@ -89,6 +90,21 @@ func (b *mockBackend) GetResources() (*api.ResourcesStoragePool, error) {
 	return nil, nil
 }
 
+// CheckHealth ...
+func (b *mockBackend) CheckHealth() (*api.StoragePoolHealth, error) {
+	return nil, nil
+}
+
+// Scrub ...
+func (b *mockBackend) Scrub() error {
+	return nil
+}
+
+// AutoExtend ...
+func (b *mockBackend) AutoExtend() (bool, error) {
+	return false, nil
+}
+
 // IsUsed ...
 func (b *mockBackend) IsUsed() (bool, error) {
 	return false, nil
@@ -169,6 +185,16 @@ func (b *mockBackend) DeleteInstance(inst instance.Instance, op *operations.Oper
 	return nil
 }
 
+// MoveInstanceLive ...
+func (b *mockBackend) MoveInstanceLive(inst instance.Instance, mirror func(diskPath string) error, op *operations.Operation) error {
+	return nil
+}
+
+// DeleteInstanceLiveMoveSource ...
+func (b *mockBackend) DeleteInstanceLiveMoveSource(inst instance.Instance, preDelete func(mountPath string) error, op *operations.Operation) error {
+	return nil
+}
+
 // UpdateInstance ...
 func (b *mockBackend) UpdateInstance(inst instance.Instance, newDesc string, newConfig map[string]string, op *operations.Operation) error {
 	return nil
@@ -424,6 +450,21 @@ func (b *mockBackend) RestoreCustomVolume(projectName string, volName string, sn
 	return nil
 }
 
+// MountCustomVolumeSnapshot ...
+func (b *mockBackend) MountCustomVolumeSnapshot(projectName string, volName string, op *operations.Operation) (*MountInfo, error) {
+	return nil, nil
+}
+
+// UnmountCustomVolumeSnapshot ...
+func (b *mockBackend) UnmountCustomVolumeSnapshot(projectName string, volName string, op *operations.Operation) (bool, error) {
+	return true, nil
+}
+
+// DiffVolume ...
+func (b *mockBackend) DiffVolume(ctx context.Context, projectName string, volType drivers.VolumeType, volName string, fromSnapshot string, toSnapshot string, op *operations.Operation) ([]api.StorageVolumeDiff, error) {
+	return nil, nil
+}
+
 // BackupCustomVolume ...
 func (b *mockBackend) BackupCustomVolume(projectName string, volName string, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, op *operations.Operation) error {
 	return nil
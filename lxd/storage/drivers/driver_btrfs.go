@@ -351,12 +351,13 @@ func (d *btrfs) Validate(config map[string]string) error {
 		//  shortdesc: Mount options for block devices
 		//  scope: global
 		"btrfs.mount_options": validate.IsAny,
+		"scrub.schedule":      validateScrubSchedule,
 	}
 
 	// Append common local pool rules.
 	maps.Insert(rules, maps.All(d.commonRules.LocalPoolRules()))
 
-	return d.validatePool(config, rules, nil)
+	return d.validatePool(config, rules, d.commonVolumeRules())
 }
 
 // Update applies any driver changes required from a configuration change.
@@ -520,6 +521,45 @@ func (d *btrfs) GetResources() (*api.ResourcesStoragePool, error) {
 	return genericVFSGetResources(d)
 }
 
+// CheckHealth runs health checks against the storage pool filesystem.
+func (d *btrfs) CheckHealth() (*api.StoragePoolHealth, error) {
+	poolMountPath := GetPoolMountPath(d.name)
+	checks := []api.StoragePoolHealthCheck{}
+
+	// Check the device error counters.
+	out, err := shared.RunCommand(d.state.ShutdownCtx, "btrfs", "device", "stats", poolMountPath)
+	if err != nil {
+		checks = append(checks, healthCheckFailed("device-errors", err))
+	} else {
+		counters := btrfsDeviceStatsErrors(out)
+		if len(counters) > 0 {
+			checks = append(checks, healthCheck("device-errors", false, strings.Join(counters, ", ")))
+		} else {
+			checks = append(checks, healthCheck("device-errors", true, "No device errors"))
+		}
+	}
+
+	// Check the outcome of the last scrub.
+	out, err = shared.RunCommand(d.state.ShutdownCtx, "btrfs", "scrub", "status", poolMountPath)
+	if err != nil {
+		checks = append(checks, healthCheckFailed("scrub", err))
+	} else {
+		checks = append(checks, btrfsScrubHealthCheck(out))
+	}
+
+	return poolHealth(checks), nil
+}
+
+// Scrub starts a scrub of the storage pool filesystem in the background.
+func (d *btrfs) Scrub() error {
+	_, err := shared.RunCommand(d.state.ShutdownCtx, "btrfs", "scrub", "start", GetPoolMountPath(d.name))
+	if err != nil {
+		return fmt.Errorf("Failed starting scrub of pool %q: %w", d.name, err)
+	}
+
+	return nil
+}
+
 // MigrationTypes returns the type of transfer methods to be used when doing migrations between pools in preference order.
 func (d *btrfs) MigrationTypes(contentType ContentType, refresh bool, copySnapshots bool) []migration.Type {
 	var rsyncFeatures []string
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"slices"
@@ -31,6 +32,7 @@ import (
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
 	"github.com/canonical/lxd/shared/units"
+	"github.com/canonical/lxd/shared/validate"
 )
 
 // CreateVolume creates an empty volume and can optionally fill it by executing the supplied filler function.
@@ -86,6 +88,26 @@ func (d *btrfs) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Op
 				return fmt.Errorf("Failed setting nodatacow on %q: %w", volPath, err)
 			}
 		}
+
+		if luksIsBlockFile(vol) {
+			sizeBytes, err := units.ParseByteSizeString(vol.ConfigSize())
+			if err != nil {
+				return err
+			}
+
+			err = d.luksCreateBlockFile(vol, sizeBytes)
+			if err != nil {
+				return err
+			}
+
+			// We expect the filler to copy the VM image into the decrypted device.
+			rootBlockPath, err = d.luksOpenBlockFile(vol)
+			if err != nil {
+				return err
+			}
+
+			defer func() { _ = luksCloseBlockFile(vol) }()
+		}
 	}
 
 	err = d.runFiller(vol, rootBlockPath, filler, false)
@@ -112,7 +134,12 @@ func (d *btrfs) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Op
 		// In that situation ensureVolumeBlockFile returns ErrCannotBeShrunk, but we ignore it as this just
 		// means the filler run above has needed to increase the volume size beyond the default block
 		// volume size.
-		_, err = ensureVolumeBlockFile(vol, rootBlockPath, sizeBytes, false)
+		if luksIsBlockFile(vol) {
+			_, err = d.luksResizeBlockFile(vol, sizeBytes, false)
+		} else {
+			_, err = ensureVolumeBlockFile(vol, rootBlockPath, sizeBytes, false)
+		}
+
 		if err != nil && !errors.Is(err, ErrCannotBeShrunk) {
 			return err
 		}
@@ -860,6 +887,14 @@ func (d *btrfs) DeleteVolume(vol Volume, op *operations.Operation) error {
 		return nil
 	}
 
+	// Close the decrypted device as it keeps the block file in use.
+	if luksIsBlockFile(vol) {
+		err = luksCloseBlockFile(vol)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Delete the volume (and any subvolumes).
 	err = d.deleteSubvolume(volPath, true)
 	if err != nil {
@@ -881,9 +916,67 @@ func (d *btrfs) HasVolume(vol Volume) (bool, error) {
 	return genericVFSHasVolume(vol)
 }
 
+// FillVolumeConfig populate volume with default config.
+func (d *btrfs) FillVolumeConfig(vol Volume) error {
+	var excludedKeys []string
+
+	// Only block files can be encrypted.
+	if vol.contentType != ContentTypeBlock {
+		excludedKeys = []string{"block.encryption"}
+	}
+
+	err := d.fillVolumeConfig(&vol, excludedKeys...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// commonVolumeRules returns validation rules which are common for pool and volume.
+func (d *btrfs) commonVolumeRules() map[string]func(value string) error {
+	return map[string]func(value string) error{
+		// lxdmeta:generate(entities=storage-btrfs; group=volume-conf; key=block.encryption)
+		// The only supported value is `luks2`.
+		// The volume is encrypted with a random key held by LXD, see {ref}`storage-volume-encryption`.
+		// ---
+		//  type: string
+		//  condition: block volume
+		//  defaultdesc: same as `volume.block.encryption`
+		//  shortdesc: Encryption of the storage volume
+		//  scope: global
+		"block.encryption": validate.Optional(validate.IsOneOf("luks2")),
+	}
+}
+
 // ValidateVolume validates the supplied volume config.
 func (d *btrfs) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
-	return d.validateVolume(vol, nil, removeUnknownKeys)
+	commonRules := d.commonVolumeRules()
+
+	// lxdmeta:generate(entities=storage-btrfs; group=volume-conf; key=volatile.encryption.key)
+	// The key is sealed when `storage.encryption_key_file` is set on the server.
+	// It is never returned by the API.
+	// ---
+	//  type: string
+	//  condition: encrypted volume
+	//  shortdesc: Key of the encrypted storage volume
+	//  scope: global
+	commonRules["volatile.encryption.key"] = validate.IsAny
+
+	err := d.validateVolume(vol, commonRules, removeUnknownKeys)
+	if err != nil {
+		return err
+	}
+
+	if vol.config["volatile.encryption.key"] != "" && !vol.IsEncrypted() {
+		return errors.New("volatile.encryption.key can only be set on encrypted volumes")
+	}
+
+	if vol.IsEncrypted() && vol.contentType != ContentTypeBlock {
+		return errors.New("block.encryption is only supported for block volumes")
+	}
+
+	return nil
 }
 
 // UpdateVolume applies config changes to the volume.
@@ -940,7 +1033,13 @@ func (d *btrfs) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool,
 		// ErrNotSupported so that the caller can take the appropriate action. In the case of optimized
 		// image volumes, this will cause the image volume to be deleted and regenerated with the new size.
 		// In other cases this is probably a bug and the operation should fail anyway.
-		resized, err := ensureVolumeBlockFile(vol, rootBlockPath, sizeBytes, allowUnsafeResize, VolumeTypeImage)
+		var resized bool
+		if luksIsBlockFile(vol) {
+			resized, err = d.luksResizeBlockFile(vol, sizeBytes, allowUnsafeResize, VolumeTypeImage)
+		} else {
+			resized, err = ensureVolumeBlockFile(vol, rootBlockPath, sizeBytes, allowUnsafeResize, VolumeTypeImage)
+		}
+
 		if err != nil {
 			return err
 		}
@@ -949,6 +1048,16 @@ func (d *btrfs) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool,
 		// unsafe resize mode as it is expected the caller will do all necessary post resize actions
 		// themselves).
 		if vol.IsVMBlock() && resized && !allowUnsafeResize {
+			// The GPT of encrypted volumes is only reachable through their decrypted device.
+			if luksIsBlockFile(vol) && !shared.PathExists(rootBlockPath) {
+				_, err = d.luksOpenBlockFile(vol)
+				if err != nil {
+					return err
+				}
+
+				defer func() { _ = luksCloseBlockFile(vol) }()
+			}
+
 			err = d.moveGPTAltHeader(rootBlockPath)
 			if err != nil {
 				return err
@@ -1064,7 +1173,12 @@ func (d *btrfs) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool,
 }
 
 // GetVolumeDiskPath returns the location and file format of a disk volume.
+// For encrypted volumes this is the decrypted device which only exists while the volume is mounted.
 func (d *btrfs) GetVolumeDiskPath(vol Volume) (string, error) {
+	if luksIsBlockFile(vol) {
+		return luksMapperPath(luksBlockFileMapperName(vol)), nil
+	}
+
 	return genericVFSGetVolumeDiskPath(vol)
 }
 
@@ -1091,12 +1205,21 @@ func (d *btrfs) MountVolume(vol Volume, op *operations.Operation) error {
 		}
 	}
 
+	// Open the decrypted device of encrypted volumes.
+	if luksIsBlockFile(vol) {
+		_, err = d.luksOpenBlockFile(vol)
+		if err != nil {
+			return err
+		}
+	}
+
 	vol.MountRefCountIncrement() // From here on it is up to caller to call UnmountVolume() when done.
 	return nil
 }
 
 // UnmountVolume simulates unmounting a volume.
 // As driver doesn't have volumes to unmount it returns false indicating the volume was already unmounted.
+// The decrypted device of encrypted volumes is closed unless keepBlockDev is set.
 func (d *btrfs) UnmountVolume(vol Volume, keepBlockDev bool, op *operations.Operation) (bool, error) {
 	unlock, err := vol.MountLock()
 	if err != nil {
@@ -1111,11 +1234,26 @@ func (d *btrfs) UnmountVolume(vol Volume, keepBlockDev bool, op *operations.Oper
 		return false, ErrInUse
 	}
 
+	if luksIsBlockFile(vol) && !keepBlockDev {
+		err = luksCloseBlockFile(vol)
+		if err != nil {
+			return false, err
+		}
+	}
+
 	return false, nil
 }
 
 // RenameVolume renames a volume and its snapshots.
 func (d *btrfs) RenameVolume(vol Volume, newVolName string, op *operations.Operation) error {
+	// The decrypted device is named after the volume so close it before renaming.
+	if luksIsBlockFile(vol) {
+		err := luksCloseBlockFile(vol)
+		if err != nil {
+			return err
+		}
+	}
+
 	return genericVFSRenameVolume(d, vol, newVolName, op)
 }
 
@@ -1726,6 +1864,14 @@ func (d *btrfs) CreateVolumeSnapshot(snapVol Volume, op *operations.Operation) e
 func (d *btrfs) DeleteVolumeSnapshot(snapVol Volume, op *operations.Operation) error {
 	snapPath := snapVol.MountPath()
 
+	// Close the decrypted device as it keeps the block file in use.
+	if luksIsBlockFile(snapVol) {
+		err := luksCloseBlockFile(snapVol)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Delete the snapshot.
 	err := d.deleteSubvolume(snapPath, true)
 	if err != nil {
@@ -1767,6 +1913,15 @@ func (d *btrfs) MountVolumeSnapshot(snapVol Volume, op *operations.Operation) er
 		return err
 	}
 
+	// Open the decrypted device of encrypted snapshots (read-only).
+	if luksIsBlockFile(snapVol) {
+		_, err = d.luksOpenBlockFile(snapVol)
+		if err != nil {
+			_, _ = forceUnmount(snapPath)
+			return err
+		}
+	}
+
 	snapVol.MountRefCountIncrement() // From here on it is up to caller to call UnmountVolumeSnapshot() when done.
 	return nil
 }
@@ -1786,6 +1941,13 @@ func (d *btrfs) UnmountVolumeSnapshot(snapVol Volume, op *operations.Operation)
 		return false, ErrInUse
 	}
 
+	if luksIsBlockFile(snapVol) {
+		err = luksCloseBlockFile(snapVol)
+		if err != nil {
+			return false, err
+		}
+	}
+
 	snapPath := snapVol.MountPath()
 	return forceUnmount(snapPath)
 }
@@ -1848,6 +2010,14 @@ func (d *btrfs) RestoreVolume(vol Volume, snapVol Volume, op *operations.Operati
 
 	target := vol.MountPath()
 
+	// The decrypted device uses the block file being replaced so close it first.
+	if luksIsBlockFile(vol) {
+		err = luksCloseBlockFile(vol)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Create a backup so we can revert.
 	backupSubvolume := target + tmpVolSuffix
 	err = os.Rename(target, backupSubvolume)
@@ -1887,6 +2057,28 @@ func (d *btrfs) RestoreVolume(vol Volume, snapVol Volume, op *operations.Operati
 	return d.deleteSubvolume(backupSubvolume, true)
 }
 
+// DiffVolume returns the paths that changed between a snapshot and a later snapshot or the volume itself.
+// Only the content of the files written since the snapshot, according to `btrfs subvolume find-new`, is compared.
+func (d *btrfs) DiffVolume(ctx context.Context, snapVol Volume, vol Volume, op *operations.Operation) ([]api.StorageVolumeDiff, error) {
+	// Get the generation of the snapshot, passing a generation that is too high for any file to be reported.
+	out, err := shared.RunCommand(ctx, "btrfs", "subvolume", "find-new", snapVol.MountPath(), strconv.FormatUint(math.MaxUint64, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	generation, err := btrfsFindNewMarker(out)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err = shared.RunCommand(ctx, "btrfs", "subvolume", "find-new", vol.MountPath(), strconv.FormatUint(generation, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	return genericVFSDiffVolume(ctx, snapVol, vol, btrfsFindNewPaths(out))
+}
+
 // RenameVolumeSnapshot renames a volume snapshot.
 func (d *btrfs) RenameVolumeSnapshot(snapVol Volume, newSnapshotName string, op *operations.Operation) error {
 	return genericVFSRenameVolumeSnapshot(d, snapVol, newSnapshotName, op)
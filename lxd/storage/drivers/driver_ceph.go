@@ -324,6 +324,7 @@ func (d *ceph) Validate(config map[string]string) error {
 		//  shortdesc: Name of the Ceph cluster in which to create new storage pools
 		//  scope: global
 		"ceph.cluster_name": validate.IsAny,
+		"scrub.schedule":    validateScrubSchedule,
 		// lxdmeta:generate(entities=storage-ceph; group=pool-conf; key=ceph.osd.pg_num)
 		//
 		// ---
@@ -530,6 +531,33 @@ func (d *ceph) GetResources() (*api.ResourcesStoragePool, error) {
 	return &res, nil
 }
 
+// CheckHealth runs health checks against the Ceph cluster backing the storage pool.
+func (d *ceph) CheckHealth() (*api.StoragePoolHealth, error) {
+	checks := []api.StoragePoolHealthCheck{
+		cephHealthCheck(d.state.ShutdownCtx, d.config["ceph.cluster_name"], d.config["ceph.user.name"]),
+	}
+
+	return poolHealth(checks), nil
+}
+
+// Scrub requests a deep scrub of the placement groups of the OSD pool.
+func (d *ceph) Scrub() error {
+	_, err := shared.RunCommand(
+		d.state.ShutdownCtx,
+		"ceph",
+		"--name", "client."+d.config["ceph.user.name"],
+		"--cluster", d.config["ceph.cluster_name"],
+		"osd",
+		"pool",
+		"deep-scrub",
+		d.config["ceph.osd.pool_name"])
+	if err != nil {
+		return fmt.Errorf("Failed requesting deep scrub of OSD pool %q: %w", d.config["ceph.osd.pool_name"], err)
+	}
+
+	return nil
+}
+
 // MigrationTypes returns the type of transfer methods to be used when doing migrations between pools in preference order.
 func (d *ceph) MigrationTypes(contentType ContentType, refresh bool, copySnapshots bool) []migration.Type {
 	var rsyncFeatures []string
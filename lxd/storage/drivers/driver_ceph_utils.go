@@ -136,6 +136,11 @@ func (d *ceph) rbdCreateVolume(vol Volume, size string) error {
 		cmd = append(cmd, "--data-pool", d.config["ceph.osd.data_pool_name"])
 	}
 
+	// The image of encrypted volumes also holds the encryption header.
+	if vol.IsEncrypted() {
+		sizeBytes += luksHeaderSize
+	}
+
 	// Ceph allows writing only to images of size in multiples of 512B
 	sizeBytes = d.roundUpTo512(sizeBytes)
 
@@ -172,7 +177,18 @@ func (d *ceph) rbdDeleteVolume(vol Volume) error {
 // rbdMapVolume maps a given RBD storage volume.
 // This will ensure that the RBD storage volume is accessible as a block device
 // in the /dev directory and is therefore necessary in order to mount it.
+// For encrypted volumes the decrypted device is opened too and its path returned instead.
 func (d *ceph) rbdMapVolume(vol Volume) (string, error) {
+	devPath, err := d.rbdMapRawVolume(vol)
+	if err != nil {
+		return "", err
+	}
+
+	return d.decryptedDevPath(vol, devPath)
+}
+
+// rbdMapRawVolume maps a given RBD storage volume without opening the decrypted device of encrypted volumes.
+func (d *ceph) rbdMapRawVolume(vol Volume) (string, error) {
 	rbdName := d.getRBDVolumeName(vol, "", false, false)
 	devPath, err := shared.RunCommand(
 		context.TODO(),
@@ -203,6 +219,14 @@ func (d *ceph) rbdUnmapVolume(vol Volume, unmapUntilEINVAL bool) error {
 	busyCount := 0
 	rbdVol := d.getRBDVolumeName(vol, "", false, false)
 
+	// Close the decrypted device first as it keeps the RBD device busy.
+	if vol.IsEncrypted() {
+		err := luksClose(d.luksMapperName(rbdVol))
+		if err != nil {
+			return err
+		}
+	}
+
 	ourDeactivate := false
 
 again:
@@ -258,6 +282,13 @@ again:
 // rbdUnmapVolumeSnapshot unmaps a given RBD snapshot.
 // This is a precondition in order to delete an RBD snapshot can.
 func (d *ceph) rbdUnmapVolumeSnapshot(vol Volume, snapshotName string, unmapUntilEINVAL bool) error {
+	if vol.IsEncrypted() {
+		err := luksClose(d.luksMapperName(d.getRBDVolumeName(vol, snapshotName, false, false)))
+		if err != nil {
+			return err
+		}
+	}
+
 again:
 	_, err := shared.RunCommand(
 		context.TODO(),
@@ -1114,11 +1145,13 @@ func (d *ceph) getRBDMappedDevPath(vol Volume, mapIfMissing bool) (bool, string,
 		if vol.IsSnapshot() {
 			// Volume is a snapshot, check device's snapshot name matches the volume's snapshot name.
 			if len(rbdNameParts) == 2 && rbdNameParts[1] == devSnapName {
-				return false, fmt.Sprintf("/dev/rbd%d", idx), nil // We found a match.
+				devPath, err := d.decryptedDevPath(vol, fmt.Sprintf("/dev/rbd%d", idx)) // We found a match.
+				return false, devPath, err
 			}
 		} else if slices.Contains([]string{"-", ""}, devSnapName) {
 			// Volume is not a snapshot and neither is this device.
-			return false, fmt.Sprintf("/dev/rbd%d", idx), nil // We found a match.
+			devPath, err := d.decryptedDevPath(vol, fmt.Sprintf("/dev/rbd%d", idx)) // We found a match.
+			return false, devPath, err
 		}
 
 		continue
@@ -1137,6 +1170,52 @@ func (d *ceph) getRBDMappedDevPath(vol Volume, mapIfMissing bool) (bool, string,
 	return false, "", fmt.Errorf("Volume %q not mapped to an RBD device", vol.Name())
 }
 
+// luksMapperName returns the device mapper name of the decrypted device of an encrypted RBD image.
+func (d *ceph) luksMapperName(rbdName string) string {
+	return luksMapperName(d.name, strings.ReplaceAll(rbdName, "@", "-"))
+}
+
+// formatEncryptedVolume initializes the encryption header of a new RBD volume.
+func (d *ceph) formatEncryptedVolume(vol Volume) error {
+	key, err := d.luksVolumeKey(vol)
+	if err != nil {
+		return err
+	}
+
+	devPath, err := d.rbdMapRawVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = d.rbdUnmapVolume(vol, true) }()
+
+	return luksFormat(devPath, key)
+}
+
+// decryptedDevPath returns the path of the device holding the data of a mapped RBD volume.
+// For encrypted volumes this is the decrypted device, which is opened if needed.
+// Snapshots are mapped read-only so their decrypted device is too.
+func (d *ceph) decryptedDevPath(vol Volume, devPath string) (string, error) {
+	if !vol.IsEncrypted() {
+		return devPath, nil
+	}
+
+	key, err := d.luksVolumeKey(vol)
+	if err != nil {
+		return "", err
+	}
+
+	rbdName := d.getRBDVolumeName(vol, "", false, false)
+
+	mapperPath, err := luksOpen(devPath, d.luksMapperName(rbdName), key, vol.IsSnapshot())
+	if err != nil {
+		return "", err
+	}
+
+	d.logger.Debug("Opened encrypted RBD volume", logger.Ctx{"volName": rbdName, "dev": mapperPath})
+	return mapperPath, nil
+}
+
 // generateUUID regenerates the XFS/btrfs UUID as needed.
 func (d *ceph) generateUUID(fsType string, devPath string) error {
 	if !renegerateFilesystemUUIDNeeded(fsType) {
@@ -1305,7 +1384,13 @@ func (d *ceph) receiveVolume(volumeName string, conn io.ReadWriteCloser, writeWr
 }
 
 // resizeVolume resizes an RBD volume. This function does not resize any filesystem inside the RBD volume.
+// The size of encrypted volumes is the size of their decrypted device.
 func (d *ceph) resizeVolume(vol Volume, sizeBytes int64, allowShrink bool) error {
+	// The image of encrypted volumes also holds the encryption header.
+	if vol.IsEncrypted() {
+		sizeBytes += luksHeaderSize
+	}
+
 	args := []string{
 		"resize",
 	}
@@ -1324,8 +1409,21 @@ func (d *ceph) resizeVolume(vol Volume, sizeBytes int64, allowShrink bool) error
 
 	// Resize the block device.
 	_, err := shared.RunCommandRetry(context.TODO(), noKillRetryOpts, "rbd", args...)
+	if err != nil {
+		return err
+	}
 
-	return err
+	// Resize the decrypted device if opened.
+	if vol.IsEncrypted() {
+		key, err := d.luksVolumeKey(vol)
+		if err != nil {
+			return err
+		}
+
+		return luksResize(d.luksMapperName(d.getRBDVolumeName(vol, "", false, false)), key)
+	}
+
+	return nil
 }
 
 // findLastCommonSnapshotIndex finds the last common snapshot from the list of targetSnapshots based on its name.
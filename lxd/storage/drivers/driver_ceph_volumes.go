@@ -149,6 +149,13 @@ func (d *ceph) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Ope
 
 	revert.Add(func() { _ = d.DeleteVolume(vol, op) })
 
+	if vol.IsEncrypted() {
+		err = d.formatEncryptedVolume(vol)
+		if err != nil {
+			return err
+		}
+	}
+
 	devPath, err := d.rbdMapVolume(vol)
 	if err != nil {
 		return err
@@ -1186,6 +1193,15 @@ func (d *ceph) commonVolumeRules() map[string]func(value string) error {
 		//  shortdesc: Mount options for block-backed file system volumes
 		//  scope: global
 		"block.mount_options": validate.IsAny,
+		// lxdmeta:generate(entities=storage-ceph,storage-lvm; group=volume-conf; key=block.encryption)
+		// The only supported value is `luks2`.
+		// The volume is encrypted with a random key held by LXD, see {ref}`storage-volume-encryption`.
+		// ---
+		//  type: string
+		//  defaultdesc: same as `volume.block.encryption`
+		//  shortdesc: Encryption of the storage volume
+		//  scope: global
+		"block.encryption": validate.Optional(validate.IsOneOf("luks2")),
 	}
 }
 
@@ -1202,7 +1218,26 @@ func (d *ceph) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
 		delete(commonRules, "block.mount_options")
 	}
 
-	return d.validateVolume(vol, commonRules, removeUnknownKeys)
+	// lxdmeta:generate(entities=storage-ceph,storage-lvm; group=volume-conf; key=volatile.encryption.key)
+	// The key is sealed when `storage.encryption_key_file` is set on the server.
+	// It is never returned by the API.
+	// ---
+	//  type: string
+	//  condition: encrypted volume
+	//  shortdesc: Key of the encrypted storage volume
+	//  scope: global
+	commonRules["volatile.encryption.key"] = validate.IsAny
+
+	err := d.validateVolume(vol, commonRules, removeUnknownKeys)
+	if err != nil {
+		return err
+	}
+
+	if vol.config["volatile.encryption.key"] != "" && !vol.IsEncrypted() {
+		return errors.New("volatile.encryption.key can only be set on encrypted volumes")
+	}
+
+	return nil
 }
 
 // UpdateVolume applies config changes to the volume.
@@ -2022,7 +2057,7 @@ func (d *ceph) MountVolumeSnapshot(snapVol Volume, op *operations.Operation) err
 
 		// Clone snapshot.
 		cloneName := fmt.Sprintf("%s_%s_start_clone", parentName, snapshotOnlyName)
-		cloneVol := NewVolume(d, d.name, VolumeType("snapshots"), ContentTypeFS, cloneName, nil, nil)
+		cloneVol := NewVolume(d, d.name, VolumeType("snapshots"), ContentTypeFS, cloneName, snapVol.config, snapVol.poolConfig)
 
 		err = d.rbdCreateClone(parentVol, prefixedSnapOnlyName, cloneVol)
 		if err != nil {
@@ -2115,7 +2150,7 @@ func (d *ceph) UnmountVolumeSnapshot(snapVol Volume, op *operations.Operation) (
 
 		parentName, snapshotOnlyName, _ := api.GetParentAndSnapshotName(snapVol.name)
 		cloneName := fmt.Sprintf("%s_%s_start_clone", parentName, snapshotOnlyName)
-		cloneVol := NewVolume(d, d.name, VolumeType("snapshots"), ContentTypeFS, cloneName, nil, nil)
+		cloneVol := NewVolume(d, d.name, VolumeType("snapshots"), ContentTypeFS, cloneName, snapVol.config, snapVol.poolConfig)
 
 		err = d.rbdUnmapVolume(cloneVol, true)
 		if err != nil {
@@ -564,6 +564,15 @@ func (d *cephfs) GetResources() (*api.ResourcesStoragePool, error) {
 	return genericVFSGetResources(d)
 }
 
+// CheckHealth runs health checks against the Ceph cluster backing the storage pool.
+func (d *cephfs) CheckHealth() (*api.StoragePoolHealth, error) {
+	checks := []api.StoragePoolHealthCheck{
+		cephHealthCheck(d.state.ShutdownCtx, d.config["cephfs.cluster_name"], d.config["cephfs.user.name"]),
+	}
+
+	return poolHealth(checks), nil
+}
+
 // MigrationTypes returns the supported migration types and options supported by the driver.
 func (d *cephfs) MigrationTypes(contentType ContentType, refresh bool, copySnapshots bool) []migration.Type {
 	var rsyncFeatures []string
@@ -136,6 +136,11 @@ func (d *common) fillVolumeConfig(vol *Volume, excludedKeys ...string) error {
 			continue
 		}
 
+		// size.inodes is only relevant for volumes that support inode quotas.
+		if !vol.supportsInodeQuota() && volKey == "size.inodes" {
+			continue
+		}
+
 		if vol.config[volKey] == "" {
 			vol.config[volKey] = d.config[k]
 		}
@@ -264,6 +269,22 @@ func (d *common) Config() map[string]string {
 	return confCopy
 }
 
+// CheckHealth runs the driver specific health checks of the storage pool.
+func (d *common) CheckHealth() (*api.StoragePoolHealth, error) {
+	return nil, ErrNotSupported
+}
+
+// Scrub starts verifying the integrity of the data in the storage pool.
+func (d *common) Scrub() error {
+	return ErrNotSupported
+}
+
+// AutoExtend grows the storage pool if its usage is above the configured threshold.
+// Returns true if the storage pool was extended.
+func (d *common) AutoExtend() (bool, error) {
+	return false, ErrNotSupported
+}
+
 // ApplyPatch looks for a suitable patch and runs it.
 func (d *common) ApplyPatch(name string) error {
 	if d.patches == nil {
@@ -524,6 +545,12 @@ func (d *common) RestoreVolume(vol Volume, snapVol Volume, op *operations.Operat
 	return ErrNotSupported
 }
 
+// DiffVolume returns the paths that changed between a snapshot and a later snapshot or the volume itself by
+// comparing their files.
+func (d *common) DiffVolume(ctx context.Context, snapVol Volume, vol Volume, op *operations.Operation) ([]api.StorageVolumeDiff, error) {
+	return genericVFSDiffVolume(ctx, snapVol, vol, nil)
+}
+
 // RenameVolumeSnapshot renames a snapshot.
 func (d *common) RenameVolumeSnapshot(snapVol Volume, newSnapshotName string, op *operations.Operation) error {
 	return ErrNotSupported
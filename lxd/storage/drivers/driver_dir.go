@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"golang.org/x/sys/unix"
@@ -14,6 +16,7 @@ import (
 	"github.com/canonical/lxd/lxd/storage/filesystem"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/validate"
 )
 
 type dir struct {
@@ -41,7 +44,7 @@ func (d *dir) Info() Info {
 		Version:                      "1",
 		DefaultBlockSize:             d.defaultBlockVolumeSize(),
 		DefaultVMBlockFilesystemSize: d.defaultVMBlockFilesystemSize(),
-		OptimizedImages:              false,
+		OptimizedImages:              d.usesReflink(),
 		PreservesInodes:              false,
 		Remote:                       d.isRemote(),
 		VolumeTypes:                  []VolumeType{VolumeTypeCustom, VolumeTypeImage, VolumeTypeContainer, VolumeTypeVM},
@@ -122,6 +125,9 @@ func (d *dir) Create() error {
 		}
 	}
 
+	// Record whether the underlying filesystem supports copy-on-write clones.
+	d.config["volatile.reflink"] = strconv.FormatBool(reflinkSupported(sourcePath))
+
 	return nil
 }
 
@@ -144,12 +150,32 @@ func (d *dir) Delete(op *operations.Operation) error {
 
 // Validate checks that all provide keys are supported and that no conflicting or missing configuration is present.
 func (d *dir) Validate(config map[string]string) error {
-	// Use common local pool rules.
-	return d.validatePool(config, d.commonRules.LocalPoolRules(), nil)
+	rules := map[string]func(value string) error{
+		// lxdmeta:generate(entities=storage-dir; group=pool-conf; key=volatile.reflink)
+		// This is detected when the storage pool is created and cannot be changed afterwards.
+		// Existing storage pools are never re-detected.
+		// If enabled, snapshots and copies of volumes use copy-on-write clones instead of full copies.
+		// ---
+		//  type: bool
+		//  shortdesc: Whether the file system supports reflinks
+		//  scope: local
+		"volatile.reflink": validate.Optional(validate.IsBool),
+	}
+
+	// Append common local pool rules.
+	maps.Insert(rules, maps.All(d.commonRules.LocalPoolRules()))
+
+	return d.validatePool(config, rules, d.commonVolumeRules())
 }
 
 // Update applies any driver changes required from a configuration change.
 func (d *dir) Update(changedConfig map[string]string) error {
+	// Reflink support is only detected when the pool is created.
+	_, ok := changedConfig["volatile.reflink"]
+	if ok {
+		return errors.New("volatile.reflink cannot be modified")
+	}
+
 	return nil
 }
 
@@ -1,10 +1,18 @@
 package drivers
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"strconv"
 
+	"golang.org/x/sys/unix"
+
+	"github.com/canonical/lxd/lxd/operations"
 	"github.com/canonical/lxd/lxd/storage/quota"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
 	"github.com/canonical/lxd/shared/units"
@@ -53,6 +61,17 @@ func (d *dir) setupInitialQuota(vol Volume) (revert.Hook, error) {
 		return nil, err
 	}
 
+	// Set the inode quota on the project.
+	inodes, err := d.parseInodes(vol.config["size.inodes"])
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.setInodeQuota(volPath, volID, inodes)
+	if err != nil {
+		return nil, err
+	}
+
 	revert.Success()
 	return revertFunc, nil
 }
@@ -126,13 +145,211 @@ func (d *dir) setQuota(path string, volID int64, sizeBytes int64) error {
 			return fmt.Errorf("Failed setting project: %w", err)
 		}
 
-		// Unset the quota on the current project.
+		// Unset the quotas on the current project.
 		err = quota.SetProjectQuota(path, currentProjectID, 0)
 		if err != nil {
 			return err
 		}
+
+		err = quota.SetProjectInodeQuota(path, currentProjectID, 0)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Set the project quota size.
 	return quota.SetProjectQuota(path, projectID, sizeBytes)
 }
+
+// setInodeQuota sets the project inode quota on the path. The volID generates a quota project ID.
+// The project must already have been setup on the path using setQuota.
+func (d *dir) setInodeQuota(path string, volID int64, inodes int64) error {
+	if volID == volIDQuotaSkip {
+		// Disabled on purpose, just ignore.
+		return nil
+	}
+
+	if volID == 0 {
+		return errors.New("Missing volume ID")
+	}
+
+	ok, err := quota.Supported(path)
+	if err != nil || !ok {
+		if inodes > 0 {
+			// Skipping quota as underlying filesystem doesn't support project quotas.
+			d.logger.Warn("The backing filesystem does not support quotas, skipping set inode quota", logger.Ctx{"path": path, "inodes": inodes, "volID": volID})
+		}
+
+		return nil
+	}
+
+	return quota.SetProjectInodeQuota(path, d.quotaProjectID(volID), inodes)
+}
+
+// parseInodes parses the value of the size.inodes setting. An empty value means no limit.
+func (d *dir) parseInodes(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	inodes, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return -1, fmt.Errorf("Invalid inode count %q: %w", value, err)
+	}
+
+	return inodes, nil
+}
+
+// usesReflink returns whether the pool's filesystem supports copy-on-write clones.
+func (d *dir) usesReflink() bool {
+	return shared.IsTrue(d.config["volatile.reflink"])
+}
+
+// reflinkSupported checks whether the filesystem at path supports copy-on-write clones.
+func reflinkSupported(path string) bool {
+	srcFile, err := os.CreateTemp(path, ".lxd-reflink-")
+	if err != nil {
+		return false
+	}
+
+	defer func() { _ = os.Remove(srcFile.Name()) }()
+	defer func() { _ = srcFile.Close() }()
+
+	_, err = srcFile.WriteString("reflink")
+	if err != nil {
+		return false
+	}
+
+	targetFile, err := os.CreateTemp(path, ".lxd-reflink-")
+	if err != nil {
+		return false
+	}
+
+	defer func() { _ = os.Remove(targetFile.Name()) }()
+	defer func() { _ = targetFile.Close() }()
+
+	return unix.IoctlFileClone(int(targetFile.Fd()), int(srcFile.Fd())) == nil
+}
+
+// reflinkCopy copies srcPath to targetPath using copy-on-write clones.
+// If both are directories, the content of srcPath is copied into targetPath.
+func reflinkCopy(srcPath string, targetPath string) error {
+	_, err := shared.RunCommand(context.TODO(), "cp", "--archive", "--reflink=always", "--no-target-directory", srcPath, targetPath)
+	if err != nil {
+		return fmt.Errorf("Failed cloning %q to %q: %w", srcPath, targetPath, err)
+	}
+
+	return nil
+}
+
+// copyVolumeReflink copies a volume and its snapshots using copy-on-write clones.
+func (d *dir) copyVolumeReflink(vol VolumeCopy, srcVol VolumeCopy, srcSnapshots []string, op *operations.Operation) error {
+	if vol.contentType != srcVol.contentType {
+		return errors.New("Content type of source and target must be the same")
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	err := d.CreateVolume(vol.Volume, nil, op)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() { _ = d.DeleteVolume(vol.Volume, op) })
+
+	// Clone the snapshots directly into place.
+	for _, snapName := range srcSnapshots {
+		var snapVol Volume
+		found := false
+		for _, snapshot := range vol.Snapshots {
+			_, snapshotName, _ := api.GetParentAndSnapshotName(snapshot.name)
+			if snapshotName == snapName {
+				snapVol = snapshot
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("Snapshot %q missing in volume's list", snapName)
+		}
+
+		srcSnapVol, err := srcVol.NewSnapshot(snapName)
+		if err != nil {
+			return err
+		}
+
+		err = snapVol.EnsureMountPath()
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { _ = d.DeleteVolumeSnapshot(snapVol, op) })
+
+		d.Logger().Debug("Cloning snapshot", logger.Ctx{"sourcePath": srcSnapVol.MountPath(), "targetPath": snapVol.MountPath()})
+		err = reflinkCopy(srcSnapVol.MountPath(), snapVol.MountPath())
+		if err != nil {
+			return err
+		}
+	}
+
+	// Clone the main volume.
+	err = srcVol.MountTask(func(srcMountPath string, op *operations.Operation) error {
+		d.Logger().Debug("Cloning volume", logger.Ctx{"sourcePath": srcMountPath, "targetPath": vol.MountPath()})
+		return reflinkCopy(srcMountPath, vol.MountPath())
+	}, op)
+	if err != nil {
+		return err
+	}
+
+	// Grow the cloned block file to the requested size if needed.
+	if IsContentBlock(vol.contentType) {
+		rootBlockPath, err := d.GetVolumeDiskPath(vol.Volume)
+		if err != nil {
+			return err
+		}
+
+		sizeBytes, err := units.ParseByteSizeString(vol.ConfigSize())
+		if err != nil {
+			return err
+		}
+
+		var resized bool
+		if luksIsBlockFile(vol.Volume) {
+			resized, err = d.luksResizeBlockFile(vol.Volume, sizeBytes, false)
+		} else {
+			resized, err = ensureVolumeBlockFile(vol.Volume, rootBlockPath, sizeBytes, false)
+		}
+
+		if err != nil && !errors.Is(err, ErrCannotBeShrunk) {
+			return err
+		}
+
+		if resized && vol.IsVMBlock() {
+			// The GPT of encrypted volumes is only reachable through their decrypted device.
+			if luksIsBlockFile(vol.Volume) {
+				_, err = d.luksOpenBlockFile(vol.Volume)
+				if err != nil {
+					return err
+				}
+
+				defer func() { _ = luksCloseBlockFile(vol.Volume) }()
+			}
+
+			err = d.moveGPTAltHeader(rootBlockPath)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Run EnsureMountPath after copying to ensure the directory has the correct permissions set.
+	err = vol.EnsureMountPath()
+	if err != nil {
+		return err
+	}
+
+	revert.Success()
+	return nil
+}
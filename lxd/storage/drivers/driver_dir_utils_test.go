@@ -0,0 +1,55 @@
+package drivers
+
+import (
+	"os"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func Test_dir_reflinkSupportedTmpfs(t *testing.T) {
+	path, err := os.MkdirTemp("/dev/shm", "lxd-reflink-test-")
+	if err != nil {
+		t.Skipf("Failed creating directory in /dev/shm: %v", err)
+	}
+
+	defer func() { _ = os.RemoveAll(path) }()
+
+	var st unix.Statfs_t
+	err = unix.Statfs(path, &st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if st.Type != unix.TMPFS_MAGIC {
+		t.Skip("/dev/shm is not a tmpfs")
+	}
+
+	if reflinkSupported(path) {
+		t.Fatal("Expected reflinks to be unsupported on tmpfs")
+	}
+
+	// The probe files are removed.
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("Expected no leftover files, found %d", len(entries))
+	}
+}
+
+func Test_dir_UpdateReflink(t *testing.T) {
+	d := &dir{}
+
+	err := d.Update(map[string]string{"volatile.reflink": "true"})
+	if err == nil {
+		t.Fatal("Expected changing volatile.reflink to fail")
+	}
+
+	err = d.Update(map[string]string{"rsync.bwlimit": "10MiB"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
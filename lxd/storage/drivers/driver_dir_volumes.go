@@ -20,6 +20,7 @@ import (
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
 	"github.com/canonical/lxd/shared/units"
+	"github.com/canonical/lxd/shared/validate"
 )
 
 // CreateVolume creates an empty volume and can optionally fill it by executing the supplied
@@ -44,7 +45,25 @@ func (d *dir) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Oper
 
 	// Get path to disk volume if volume is block or iso.
 	rootBlockPath := ""
-	if IsContentBlock(vol.contentType) {
+	if luksIsBlockFile(vol) {
+		sizeBytes, err := units.ParseByteSizeString(vol.ConfigSize())
+		if err != nil {
+			return err
+		}
+
+		err = d.luksCreateBlockFile(vol, sizeBytes)
+		if err != nil {
+			return err
+		}
+
+		// We expect the filler to copy the VM image into the decrypted device.
+		rootBlockPath, err = d.luksOpenBlockFile(vol)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = luksCloseBlockFile(vol) }()
+	} else if IsContentBlock(vol.contentType) {
 		// We expect the filler to copy the VM image into this path.
 		rootBlockPath, err = d.GetVolumeDiskPath(vol)
 		if err != nil {
@@ -80,7 +99,12 @@ func (d *dir) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Oper
 
 		// Ignore ErrCannotBeShrunk when setting size this just means the filler run above has needed to
 		// increase the volume size beyond the default block volume size.
-		_, err = ensureVolumeBlockFile(vol, rootBlockPath, sizeBytes, false)
+		if luksIsBlockFile(vol) {
+			_, err = d.luksResizeBlockFile(vol, sizeBytes, false)
+		} else {
+			_, err = ensureVolumeBlockFile(vol, rootBlockPath, sizeBytes, false)
+		}
+
 		if err != nil && !errors.Is(err, ErrCannotBeShrunk) {
 			return err
 		}
@@ -154,6 +178,11 @@ func (d *dir) CreateVolumeFromCopy(vol VolumeCopy, srcVol VolumeCopy, allowIncon
 		}
 	}
 
+	// Use copy-on-write clones if supported by the filesystem.
+	if d.usesReflink() {
+		return d.copyVolumeReflink(vol, srcVol, srcSnapshots, op)
+	}
+
 	// Run the generic copy.
 	_, err := genericVFSCopyVolume(d, d.setupInitialQuota, vol, srcVol, srcSnapshots, false, allowInconsistent, op)
 	return err
@@ -185,6 +214,14 @@ func (d *dir) DeleteVolume(vol Volume, op *operations.Operation) error {
 
 	volPath := vol.MountPath()
 
+	// Close the decrypted device as it keeps the block file in use.
+	if luksIsBlockFile(vol) {
+		err = luksCloseBlockFile(vol)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Remove the volume from the storage device.
 	err = forceRemoveAll(volPath)
 	if err != nil {
@@ -220,7 +257,14 @@ func (d *dir) HasVolume(vol Volume) (bool, error) {
 
 // FillVolumeConfig populate volume with default config.
 func (d *dir) FillVolumeConfig(vol Volume) error {
-	err := d.fillVolumeConfig(&vol)
+	var excludedKeys []string
+
+	// Only block files can be encrypted.
+	if vol.contentType != ContentTypeBlock {
+		excludedKeys = []string{"block.encryption"}
+	}
+
+	err := d.fillVolumeConfig(&vol, excludedKeys...)
 	if err != nil {
 		return err
 	}
@@ -228,13 +272,64 @@ func (d *dir) FillVolumeConfig(vol Volume) error {
 	return nil
 }
 
+// commonVolumeRules returns validation rules which are common for pool and volume.
+func (d *dir) commonVolumeRules() map[string]func(value string) error {
+	return map[string]func(value string) error{
+		// lxdmeta:generate(entities=storage-dir; group=volume-conf; key=block.encryption)
+		// The only supported value is `luks2`.
+		// The volume is encrypted with a random key held by LXD, see {ref}`storage-volume-encryption`.
+		// ---
+		//  type: string
+		//  condition: block volume
+		//  defaultdesc: same as `volume.block.encryption`
+		//  shortdesc: Encryption of the storage volume
+		//  scope: global
+		"block.encryption": validate.Optional(validate.IsOneOf("luks2")),
+		// lxdmeta:generate(entities=storage-dir; group=volume-conf; key=size.inodes)
+		// This option limits the number of files and directories that can be created on the volume.
+		// It requires the file system that backs the storage pool to support project quotas.
+		// See {ref}`storage-dir-quotas`.
+		// ---
+		//  type: string
+		//  condition: container or custom filesystem volume
+		//  defaultdesc: same as `volume.size.inodes`
+		//  shortdesc: Maximum number of inodes of the storage volume
+		//  scope: global
+		"size.inodes": validate.Optional(validate.IsUint32),
+	}
+}
+
 // ValidateVolume validates the supplied volume config. Optionally removes invalid keys from the volume's config.
 func (d *dir) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
-	err := d.validateVolume(vol, nil, removeUnknownKeys)
+	commonRules := d.commonVolumeRules()
+
+	// lxdmeta:generate(entities=storage-dir; group=volume-conf; key=volatile.encryption.key)
+	// The key is sealed when `storage.encryption_key_file` is set on the server.
+	// It is never returned by the API.
+	// ---
+	//  type: string
+	//  condition: encrypted volume
+	//  shortdesc: Key of the encrypted storage volume
+	//  scope: global
+	commonRules["volatile.encryption.key"] = validate.IsAny
+
+	err := d.validateVolume(vol, commonRules, removeUnknownKeys)
 	if err != nil {
 		return err
 	}
 
+	if vol.config["volatile.encryption.key"] != "" && !vol.IsEncrypted() {
+		return errors.New("volatile.encryption.key can only be set on encrypted volumes")
+	}
+
+	if vol.IsEncrypted() && vol.contentType != ContentTypeBlock {
+		return errors.New("block.encryption is only supported for block volumes")
+	}
+
+	if vol.config["size.inodes"] != "" && !vol.supportsInodeQuota() {
+		return fmt.Errorf("Volume %q property is not valid for volume type", "size.inodes")
+	}
+
 	return nil
 }
 
@@ -248,6 +343,24 @@ func (d *dir) UpdateVolume(vol Volume, changedConfig map[string]string) error {
 		}
 	}
 
+	newInodes, inodesChanged := changedConfig["size.inodes"]
+	if inodesChanged {
+		inodes, err := d.parseInodes(newInodes)
+		if err != nil {
+			return err
+		}
+
+		volID, err := d.getVolID(vol.volType, vol.name)
+		if err != nil {
+			return err
+		}
+
+		err = d.setInodeQuota(vol.MountPath(), volID, inodes)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -302,7 +415,13 @@ func (d *dir) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op
 			return err
 		}
 
-		resized, err := ensureVolumeBlockFile(vol, rootBlockPath, sizeBytes, allowUnsafeResize)
+		var resized bool
+		if luksIsBlockFile(vol) {
+			resized, err = d.luksResizeBlockFile(vol, sizeBytes, allowUnsafeResize)
+		} else {
+			resized, err = ensureVolumeBlockFile(vol, rootBlockPath, sizeBytes, allowUnsafeResize)
+		}
+
 		if err != nil {
 			return err
 		}
@@ -311,6 +430,16 @@ func (d *dir) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op
 		// unsafe resize mode as it is expected the caller will do all necessary post resize actions
 		// themselves).
 		if vol.IsVMBlock() && resized && !allowUnsafeResize {
+			// The GPT of encrypted volumes is only reachable through their decrypted device.
+			if luksIsBlockFile(vol) && !shared.PathExists(rootBlockPath) {
+				_, err = d.luksOpenBlockFile(vol)
+				if err != nil {
+					return err
+				}
+
+				defer func() { _ = luksCloseBlockFile(vol) }()
+			}
+
 			err = d.moveGPTAltHeader(rootBlockPath)
 			if err != nil {
 				return err
@@ -347,7 +476,12 @@ func (d *dir) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op
 }
 
 // GetVolumeDiskPath returns the location of a disk volume.
+// For encrypted volumes this is the decrypted device which only exists while the volume is mounted.
 func (d *dir) GetVolumeDiskPath(vol Volume) (string, error) {
+	if luksIsBlockFile(vol) {
+		return luksMapperPath(luksBlockFileMapperName(vol)), nil
+	}
+
 	return genericVFSGetVolumeDiskPath(vol)
 }
 
@@ -374,12 +508,21 @@ func (d *dir) MountVolume(vol Volume, op *operations.Operation) error {
 		}
 	}
 
+	// Open the decrypted device of encrypted volumes.
+	if luksIsBlockFile(vol) {
+		_, err = d.luksOpenBlockFile(vol)
+		if err != nil {
+			return err
+		}
+	}
+
 	vol.MountRefCountIncrement() // From here on it is up to caller to call UnmountVolume() when done.
 	return nil
 }
 
 // UnmountVolume simulates unmounting a volume.
 // As driver doesn't have volumes to unmount it returns false indicating the volume was already unmounted.
+// The decrypted device of encrypted volumes is closed unless keepBlockDev is set.
 func (d *dir) UnmountVolume(vol Volume, keepBlockDev bool, op *operations.Operation) (bool, error) {
 	unlock, err := vol.MountLock()
 	if err != nil {
@@ -394,11 +537,26 @@ func (d *dir) UnmountVolume(vol Volume, keepBlockDev bool, op *operations.Operat
 		return false, ErrInUse
 	}
 
+	if luksIsBlockFile(vol) && !keepBlockDev {
+		err = luksCloseBlockFile(vol)
+		if err != nil {
+			return false, err
+		}
+	}
+
 	return false, nil
 }
 
 // RenameVolume renames a volume and its snapshots.
 func (d *dir) RenameVolume(vol Volume, newVolName string, op *operations.Operation) error {
+	// The decrypted device is named after the volume so close it before renaming.
+	if luksIsBlockFile(vol) {
+		err := luksCloseBlockFile(vol)
+		if err != nil {
+			return err
+		}
+	}
+
 	return genericVFSRenameVolume(d, vol, newVolName, op)
 }
 
@@ -429,6 +587,20 @@ func (d *dir) CreateVolumeSnapshot(snapVol Volume, op *operations.Operation) err
 	snapPath := snapVol.MountPath()
 	revert.Add(func() { _ = os.RemoveAll(snapPath) })
 
+	// Clone the volume into the snapshot directory if supported by the filesystem.
+	if d.usesReflink() {
+		srcPath := GetVolumeMountPath(d.name, snapVol.volType, parentName)
+		d.Logger().Debug("Cloning volume", logger.Ctx{"sourcePath": srcPath, "targetPath": snapPath})
+
+		err = reflinkCopy(srcPath, snapPath)
+		if err != nil {
+			return err
+		}
+
+		revert.Success()
+		return nil
+	}
+
 	if snapVol.contentType != ContentTypeBlock || snapVol.volType != VolumeTypeCustom {
 		var rsyncArgs []string
 
@@ -448,13 +620,14 @@ func (d *dir) CreateVolumeSnapshot(snapVol Volume, op *operations.Operation) err
 	}
 
 	if snapVol.IsVMBlock() || (snapVol.contentType == ContentTypeBlock && snapVol.volType == VolumeTypeCustom) {
+		// Copy the block files themselves so that the snapshots of encrypted volumes stay encrypted.
 		parentVol := NewVolume(d, d.name, snapVol.volType, snapVol.contentType, parentName, nil, d.config)
-		srcDevPath, err := d.GetVolumeDiskPath(parentVol)
+		srcDevPath, err := genericVFSGetVolumeDiskPath(parentVol)
 		if err != nil {
 			return err
 		}
 
-		targetDevPath, err := d.GetVolumeDiskPath(snapVol)
+		targetDevPath, err := genericVFSGetVolumeDiskPath(snapVol)
 		if err != nil {
 			return err
 		}
@@ -481,6 +654,14 @@ func (d *dir) CreateVolumeSnapshot(snapVol Volume, op *operations.Operation) err
 func (d *dir) DeleteVolumeSnapshot(snapVol Volume, op *operations.Operation) error {
 	snapPath := snapVol.MountPath()
 
+	// Close the decrypted device as it keeps the block file in use.
+	if luksIsBlockFile(snapVol) {
+		err := luksCloseBlockFile(snapVol)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Remove the snapshot from the storage device.
 	err := forceRemoveAll(snapPath)
 	if err != nil && !os.IsNotExist(err) {
@@ -523,6 +704,15 @@ func (d *dir) MountVolumeSnapshot(snapVol Volume, op *operations.Operation) erro
 		return err
 	}
 
+	// Open the decrypted device of encrypted snapshots (read-only).
+	if luksIsBlockFile(snapVol) {
+		_, err = d.luksOpenBlockFile(snapVol)
+		if err != nil {
+			_, _ = forceUnmount(snapPath)
+			return err
+		}
+	}
+
 	snapVol.MountRefCountIncrement() // From here on it is up to caller to call UnmountVolumeSnapshot() when done.
 	return nil
 }
@@ -546,6 +736,13 @@ func (d *dir) UnmountVolumeSnapshot(snapVol Volume, op *operations.Operation) (b
 			return false, ErrInUse
 		}
 
+		if luksIsBlockFile(snapVol) {
+			err = luksCloseBlockFile(snapVol)
+			if err != nil {
+				return false, err
+			}
+		}
+
 		snapPath := snapVol.MountPath()
 		return forceUnmount(snapPath)
 	}
@@ -590,18 +787,30 @@ func (d *dir) RestoreVolume(vol Volume, snapVol Volume, op *operations.Operation
 
 	// Restore block volume.
 	if vol.IsVMBlock() || (vol.contentType == ContentTypeBlock && vol.volType == VolumeTypeCustom) {
-		srcDevPath, err := d.GetVolumeDiskPath(snapVol)
+		// Restore the block file itself, closing the decrypted device of encrypted volumes first.
+		if luksIsBlockFile(vol) {
+			err = luksCloseBlockFile(vol)
+			if err != nil {
+				return err
+			}
+		}
+
+		srcDevPath, err := genericVFSGetVolumeDiskPath(snapVol)
 		if err != nil {
 			return err
 		}
 
-		targetDevPath, err := d.GetVolumeDiskPath(vol)
+		targetDevPath, err := genericVFSGetVolumeDiskPath(vol)
 		if err != nil {
 			return err
 		}
 
 		d.Logger().Debug("Restoring block volume", logger.Ctx{"srcDevPath": srcDevPath, "targetPath": targetDevPath})
 
+		if d.usesReflink() {
+			return reflinkCopy(srcDevPath, targetDevPath)
+		}
+
 		err = ensureSparseFile(targetDevPath, 0)
 		if err != nil {
 			return err
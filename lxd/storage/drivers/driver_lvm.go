@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/lxd/storage/connectors"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
@@ -28,8 +29,22 @@ const lvmVgPoolMarker = "lxd_pool" // Indicator tag used to mark volume groups a
 var lvmLoaded bool
 var lvmVersion string
 
+// lvmClusterSupportedConnectors represents a list of storage connectors that can be used to reach the shared LUN
+// of a clustered volume group.
+var lvmClusterSupportedConnectors = []string{
+	connectors.TypeISCSI,
+	connectors.TypeNVME,
+}
+
 type lvm struct {
 	common
+
+	// Indicates whether the volume group is shared between the cluster members (lvmcluster driver).
+	clustered bool
+
+	// Holds the low level connector used to reach the shared LUN.
+	// Use lvm.connector() to retrieve the initialized connector.
+	storageConnector connectors.Connector
 }
 
 func (d *lvm) load() error {
@@ -42,6 +57,23 @@ func (d *lvm) load() error {
 		"storage_prefix_bucket_names_with_project":           nil,
 	}
 
+	if d.clustered {
+		// Shared volume groups are locked through lvmlockd.
+		_, err := exec.LookPath("lvmlockctl")
+		if err != nil {
+			return fmt.Errorf("Required tool %q is missing", "lvmlockctl")
+		}
+
+		// Load the kernel modules of the configured connector, ignoring errors.
+		// Support for the connector is checked during pool validation.
+		if d.config["lvm.connector"] != "" {
+			connector, err := d.connector()
+			if err == nil {
+				_ = connector.LoadModules()
+			}
+		}
+	}
+
 	// Done if previously loaded.
 	if lvmLoaded {
 		return nil
@@ -84,10 +116,20 @@ func (d *lvm) load() error {
 	return nil
 }
 
+// isRemote returns true if the volume group is shared between the cluster members.
+func (d *lvm) isRemote() bool {
+	return d.clustered
+}
+
 // Info returns info about the driver and its environment.
 func (d *lvm) Info() Info {
+	name := "lvm"
+	if d.clustered {
+		name = "lvmcluster"
+	}
+
 	return Info{
-		Name:                         "lvm",
+		Name:                         name,
 		Version:                      lvmVersion,
 		DefaultBlockSize:             d.defaultBlockVolumeSize(),
 		DefaultVMBlockFilesystemSize: d.defaultVMBlockFilesystemSize(),
@@ -112,6 +154,24 @@ func (d *lvm) FillConfig() error {
 		d.config["lvm.thinpool_name"] = lvmThinpoolDefaultName
 	}
 
+	// Shared volume groups are never backed by a loop file.
+	// If no source is specified, the existing shared volume group named after lvm.vg_name is used.
+	if d.clustered {
+		if d.config["lvm.vg_name"] == "" {
+			if d.config["source"] != "" && !filepath.IsAbs(d.config["source"]) {
+				d.config["lvm.vg_name"] = d.config["source"]
+			} else {
+				d.config["lvm.vg_name"] = d.name
+			}
+		}
+
+		if d.config["source"] == "" {
+			d.config["source"] = d.config["lvm.vg_name"]
+		}
+
+		return nil
+	}
+
 	defaultSource := loopFilePath(d.name)
 
 	if d.config["source"] == "" || d.config["source"] == defaultSource {
@@ -162,6 +222,23 @@ func (d *lvm) ValidateSource() error {
 		return errors.New("No name for volume group detected")
 	}
 
+	if d.clustered {
+		if d.config["lvm.connector"] != "" && (d.config["lvm.connector.target_qn"] == "" || d.config["lvm.connector.target"] == "") {
+			return errors.New("The lvm.connector.target_qn and lvm.connector.target keys are required when using a connector")
+		}
+
+		if d.config["size"] != "" {
+			return errors.New("Cannot specify size for a shared volume group")
+		}
+
+		// The physical device might only appear once connected to the target, so it is checked during creation.
+		if !filepath.IsAbs(d.config["source"]) && d.config["source"] != d.config["lvm.vg_name"] {
+			return errors.New("Invalid combination of source and lvm.vg_name properties")
+		}
+
+		return nil
+	}
+
 	defaultSource := loopFilePath(d.name)
 
 	if d.config["source"] == "" || d.config["source"] == defaultSource {
@@ -208,6 +285,16 @@ func (d *lvm) Create() error {
 	revert := revert.New()
 	defer revert.Fail()
 
+	// Connect to the target holding the shared LUN so that its device appears.
+	if d.clustered {
+		err = d.connectTarget()
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { _ = d.disconnectTarget() })
+	}
+
 	var usingLoopFile bool
 
 	if d.config["source"] == "" || d.config["source"] == defaultSource {
@@ -264,6 +351,13 @@ func (d *lvm) Create() error {
 		// We are using an existing physical device.
 		srcPath := shared.HostPath(d.config["source"])
 
+		if d.clustered {
+			err = d.waitBlockDevice(srcPath)
+			if err != nil {
+				return err
+			}
+		}
+
 		d.config["source"] = d.config["lvm.vg_name"]
 
 		// Wipe if requested.
@@ -305,6 +399,24 @@ func (d *lvm) Create() error {
 		if !vgExists {
 			return fmt.Errorf("The requested volume group %q does not exist", d.config["lvm.vg_name"])
 		}
+
+		if d.clustered {
+			lockType, err := d.volumeGroupLockType(d.config["lvm.vg_name"])
+			if err != nil {
+				return err
+			}
+
+			if lockType == "" || lockType == "none" {
+				return fmt.Errorf("The requested volume group %q is not a shared volume group", d.config["lvm.vg_name"])
+			}
+
+			err = d.lockStart()
+			if err != nil {
+				return err
+			}
+
+			revert.Add(func() { _ = d.lockStop() })
+		}
 	} else {
 		return errors.New("Invalid source property")
 	}
@@ -372,7 +484,14 @@ func (d *lvm) Create() error {
 		}
 
 		// Create volume group.
-		_, err := shared.RunCommandRetry(context.TODO(), noKillRetryOpts, "vgcreate", d.config["lvm.vg_name"], pvName)
+		// Shared volume groups are created with the lock type configured in lvmlockd, which also starts
+		// the lock space on this member.
+		args := []string{d.config["lvm.vg_name"], pvName}
+		if d.clustered {
+			args = append([]string{"--shared"}, args...)
+		}
+
+		_, err := shared.RunCommandRetry(context.TODO(), noKillRetryOpts, "vgcreate", args...)
 		if err != nil {
 			return err
 		}
@@ -428,6 +547,31 @@ func (d *lvm) Delete(op *operations.Operation) error {
 	var err error
 	var loopDevPath string
 
+	// The other cluster members still hold the lock space of a shared volume group at this point, which
+	// prevents its removal. So only the marker tag is removed, leaving the empty volume group in place.
+	if d.clustered {
+		vgExists, vgTags, err := d.volumeGroupExists(d.config["lvm.vg_name"])
+		if err != nil {
+			return err
+		}
+
+		if vgExists && slices.Contains(vgTags, lvmVgPoolMarker) {
+			_, err = shared.RunCommandRetry(context.TODO(), noKillRetryOpts, "vgchange", "--deltag", lvmVgPoolMarker, d.config["lvm.vg_name"])
+			if err != nil {
+				return fmt.Errorf("Failed removing marker tag on volume group for the lvm storage pool: %w", err)
+			}
+
+			d.logger.Debug("LXD marker tag removed from volume group", logger.Ctx{"vg_name": d.config["lvm.vg_name"]})
+		}
+
+		_, err = d.Unmount()
+		if err != nil {
+			return err
+		}
+
+		return wipeDirectory(GetPoolMountPath(d.name))
+	}
+
 	// Open the loop file if needed.
 	if filepath.IsAbs(d.config["source"]) && !shared.IsBlockdevPath(d.config["source"]) {
 		loopDevPath, err = d.openLoopFile(d.config["source"])
@@ -571,6 +715,23 @@ func (d *lvm) Validate(config map[string]string) error {
 		//  shortdesc: The size of the thin pool metadata volume
 		//  scope: global
 		"lvm.thinpool_metadata_size": validate.Optional(validate.IsSize),
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.thinpool_autoextend_threshold)
+		// When the data or metadata usage of the thin pool reaches this percentage, LXD extends the thin pool using free space of the volume group.
+		// Leave empty to disable automatic extension (the default).
+		// See {ref}`storage-lvm-thinpool-autoextend`.
+		// ---
+		//  type: integer
+		//  shortdesc: Thin pool usage in percent that triggers an automatic extension
+		//  scope: global
+		"lvm.thinpool_autoextend_threshold": validate.Optional(validate.IsInRange(1, 99)),
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.thinpool_autoextend_percent)
+		//
+		// ---
+		//  type: integer
+		//  defaultdesc: `20`
+		//  shortdesc: Percentage of its current size by which the thin pool is extended
+		//  scope: global
+		"lvm.thinpool_autoextend_percent": validate.Optional(validate.IsInRange(1, 1000)),
 		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.use_thinpool)
 		//
 		// ---
@@ -589,6 +750,40 @@ func (d *lvm) Validate(config map[string]string) error {
 		"lvm.vg.force_reuse": validate.Optional(validate.IsBool),
 	}
 
+	// Shared volume groups can't use a thin pool as its volumes can only be active on a single member.
+	if d.clustered {
+		for _, key := range []string{"lvm.thinpool_name", "lvm.thinpool_metadata_size", "lvm.thinpool_autoextend_threshold", "lvm.thinpool_autoextend_percent", "lvm.use_thinpool"} {
+			delete(rules, key)
+		}
+
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.connector)
+		// The connector used to reach the shared LUN backing the volume group.
+		// Supported values are `iscsi` and `nvme`.
+		// If empty, the LUN must already be available on all cluster members.
+		// ---
+		//  type: string
+		//  shortdesc: How the shared LUN is reached
+		//  condition: `lvmcluster` driver
+		//  scope: global
+		rules["lvm.connector"] = validate.Optional(validate.IsOneOf(lvmClusterSupportedConnectors...))
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.connector.target)
+		// A comma-separated list of target addresses to connect to.
+		// ---
+		//  type: string
+		//  shortdesc: List of target addresses
+		//  condition: `lvmcluster` driver
+		//  scope: global
+		rules["lvm.connector.target"] = validate.Optional(validate.IsListOf(validate.IsNetworkAddress))
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.connector.target_qn)
+		// The iSCSI qualified name (IQN) or NVMe qualified name (NQN) of the target exporting the shared LUN.
+		// ---
+		//  type: string
+		//  shortdesc: Qualified name of the target
+		//  condition: `lvmcluster` driver
+		//  scope: global
+		rules["lvm.connector.target_qn"] = validate.IsAny
+	}
+
 	// Append common local pool rules.
 	maps.Insert(rules, maps.All(d.commonRules.LocalPoolRules()))
 
@@ -605,6 +800,24 @@ func (d *lvm) Validate(config map[string]string) error {
 		if config["lvm.thinpool_metadata_size"] != "" {
 			return errors.New("The key lvm.use_thinpool cannot be set to false when lvm.thinpool_metadata_size is set")
 		}
+
+		if config["lvm.thinpool_autoextend_threshold"] != "" {
+			return errors.New("The key lvm.use_thinpool cannot be set to false when lvm.thinpool_autoextend_threshold is set")
+		}
+	}
+
+	// Check if the connector is supported on this member. Validate gets executed on every cluster member
+	// when receiving the cluster notification to finally create the pool.
+	if config["lvm.connector"] != "" {
+		connector, err := connectors.NewConnector(config["lvm.connector"], "")
+		if err != nil {
+			return fmt.Errorf("Connector %q is not supported: %w", config["lvm.connector"], err)
+		}
+
+		err = connector.LoadModules()
+		if err != nil {
+			return fmt.Errorf("Connector %q is not supported due to missing kernel modules: %w", config["lvm.connector"], err)
+		}
 	}
 
 	return nil
@@ -622,6 +835,15 @@ func (d *lvm) Update(changedConfig map[string]string) error {
 		return errors.New("lvm.thinpool_metadata_size cannot be changed")
 	}
 
+	if d.clustered {
+		for _, key := range []string{"lvm.vg_name", "lvm.connector", "lvm.connector.target_qn"} {
+			_, changed = changedConfig[key]
+			if changed {
+				return fmt.Errorf("%s cannot be changed", key)
+			}
+		}
+	}
+
 	_, changed = changedConfig["volume.lvm.stripes"]
 	if changed && d.usesThinpool() {
 		return errors.New("volume.lvm.stripes cannot be changed when using thin pool")
@@ -729,25 +951,44 @@ func (d *lvm) Mount() (bool, error) {
 		}
 
 		revert.Add(func() { _ = loopDeviceAutoDetach(loopDevPath) })
+	} else if d.clustered && !vgExists {
+		// Connect to the target holding the shared LUN.
+		err := d.connectTarget()
+		if err != nil {
+			return false, err
+		}
 
-		// Wait for volume group to be detected if wasn't detected before.
-		if !vgExists {
-			waitUntil := time.Now().Add(waitDuration)
-			for {
-				vgExists, _, _ = d.volumeGroupExists(d.config["lvm.vg_name"])
-				if vgExists {
-					break
-				}
+		revert.Add(func() { _ = d.disconnectTarget() })
 
-				if time.Now().After(waitUntil) {
-					return false, fmt.Errorf("Volume group %q not found", d.config["lvm.vg_name"])
-				}
+		// The device of the LUN appears asynchronously after connecting.
+		waitDuration = time.Second * 30
+	} else if !vgExists {
+		return false, fmt.Errorf("Volume group %s not found", d.config["lvm.vg_name"])
+	}
 
-				time.Sleep(1 * time.Second)
+	// Wait for volume group to be detected if wasn't detected before.
+	if !vgExists {
+		waitUntil := time.Now().Add(waitDuration)
+		for {
+			vgExists, _, _ = d.volumeGroupExists(d.config["lvm.vg_name"])
+			if vgExists {
+				break
+			}
+
+			if time.Now().After(waitUntil) {
+				return false, fmt.Errorf("Volume group %q not found", d.config["lvm.vg_name"])
 			}
+
+			time.Sleep(1 * time.Second)
+		}
+	}
+
+	// Join the lock space of the shared volume group so that its logical volumes can be activated.
+	if d.clustered {
+		err := d.lockStart()
+		if err != nil {
+			return false, err
 		}
-	} else if !vgExists {
-		return false, fmt.Errorf("Volume group %s not found", d.config["lvm.vg_name"])
 	}
 
 	// Ensure thinpool exists if needed for storage pool.
@@ -771,10 +1012,35 @@ func (d *lvm) Mount() (bool, error) {
 	return ourMount, nil
 }
 
-// Unmount unmounts the storage pool (this does nothing).
-// LVM doesn't currently support unmounting, please see https://github.com/canonical/lxd/issues/9278
+// Unmount unmounts the storage pool.
+// This does nothing for local volume groups as LVM doesn't currently support unmounting,
+// please see https://github.com/canonical/lxd/issues/9278
+// For shared volume groups, the lock space is left and the target is disconnected.
 func (d *lvm) Unmount() (bool, error) {
-	return false, nil
+	if !d.clustered {
+		return false, nil
+	}
+
+	vgExists, _, err := d.volumeGroupExists(d.config["lvm.vg_name"])
+	if err != nil {
+		return false, err
+	}
+
+	if !vgExists {
+		return false, nil
+	}
+
+	err = d.lockStop()
+	if err != nil {
+		return false, err
+	}
+
+	err = d.disconnectTarget()
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
 
 // GetResources returns utilisation and space info about the pool.
@@ -831,6 +1097,84 @@ func (d *lvm) GetResources() (*api.ResourcesStoragePool, error) {
 	return &res, nil
 }
 
+// CheckHealth runs health checks against the volume group and thin pool of the storage pool.
+func (d *lvm) CheckHealth() (*api.StoragePoolHealth, error) {
+	checks := []api.StoragePoolHealthCheck{}
+
+	// Check whether the volume group is missing physical volumes.
+	out, err := shared.RunCommand(d.state.ShutdownCtx, "vgs", "--noheadings", "-o", "vg_attr", d.config["lvm.vg_name"])
+	if err != nil {
+		checks = append(checks, healthCheckFailed("volume-group", err))
+	} else {
+		attr := strings.TrimSpace(out)
+		if len(attr) > 3 && attr[3] == 'p' {
+			checks = append(checks, healthCheck("volume-group", false, "Volume group is missing physical volumes"))
+		} else {
+			checks = append(checks, healthCheck("volume-group", true, "All physical volumes present"))
+		}
+	}
+
+	// Check the data and metadata usage of the thin pool.
+	if d.usesThinpool() {
+		out, err := shared.RunCommand(d.state.ShutdownCtx, "lvs", "--noheadings", "--separator", ",", "-o", "data_percent,metadata_percent", d.config["lvm.vg_name"]+"/"+d.thinpoolName())
+		if err != nil {
+			checks = append(checks, healthCheckFailed("thinpool-usage", err))
+		} else {
+			checks = append(checks, lvmThinpoolHealthCheck(out))
+		}
+	}
+
+	return poolHealth(checks), nil
+}
+
+// AutoExtend extends the thin pool from the free space of the volume group if its data or metadata usage is above
+// the lvm.thinpool_autoextend_threshold.
+func (d *lvm) AutoExtend() (bool, error) {
+	if !d.usesThinpool() || d.config["lvm.thinpool_autoextend_threshold"] == "" {
+		return false, nil
+	}
+
+	threshold, err := strconv.ParseFloat(d.config["lvm.thinpool_autoextend_threshold"], 64)
+	if err != nil {
+		return false, fmt.Errorf("Invalid lvm.thinpool_autoextend_threshold: %w", err)
+	}
+
+	extendPercent := int64(20)
+	if d.config["lvm.thinpool_autoextend_percent"] != "" {
+		extendPercent, err = strconv.ParseInt(d.config["lvm.thinpool_autoextend_percent"], 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("Invalid lvm.thinpool_autoextend_percent: %w", err)
+		}
+	}
+
+	thinPoolVolPath := d.config["lvm.vg_name"] + "/" + d.thinpoolName()
+
+	out, err := shared.RunCommand(d.state.ShutdownCtx, "lvs", "--noheadings", "--separator", ",", "--units", "b", "--nosuffix", "-o", "data_percent,metadata_percent,lv_metadata_size", thinPoolVolPath)
+	if err != nil {
+		return false, fmt.Errorf("Failed getting usage of thin pool %q: %w", thinPoolVolPath, err)
+	}
+
+	extendArgs, err := lvmThinpoolAutoExtendArgs(out, threshold, extendPercent)
+	if err != nil {
+		return false, fmt.Errorf("Failed checking usage of thin pool %q: %w", thinPoolVolPath, err)
+	}
+
+	if len(extendArgs) == 0 {
+		return false, nil
+	}
+
+	for _, args := range extendArgs {
+		_, err = shared.RunCommand(d.state.ShutdownCtx, "lvextend", append(args, thinPoolVolPath)...)
+		if err != nil {
+			return false, fmt.Errorf("Failed extending thin pool %q: %w", thinPoolVolPath, err)
+		}
+	}
+
+	d.logger.Info("Extended thin pool", logger.Ctx{"thinpool": thinPoolVolPath, "usage": strings.TrimSpace(out)})
+
+	return true, nil
+}
+
 // roundVolumeBlockSizeBytes returns sizeBytes rounded up to the next multiple
 // of the volume group extent size.
 func (d *lvm) roundVolumeBlockSizeBytes(vol Volume, sizeBytes int64) int64 {
@@ -16,6 +16,7 @@ import (
 	"github.com/canonical/lxd/lxd/operations"
 	"github.com/canonical/lxd/lxd/refcount"
 	"github.com/canonical/lxd/lxd/storage/block"
+	"github.com/canonical/lxd/lxd/storage/connectors"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
@@ -41,6 +42,11 @@ const lvmThinpoolDefaultName = "LXDThinPool"
 
 // usesThinpool indicates whether the config specifies to use a thin pool or not.
 func (d *lvm) usesThinpool() bool {
+	// Shared volume groups never use a thinpool.
+	if d.clustered {
+		return false
+	}
+
 	// Default is to use a thinpool.
 	return shared.IsTrueOrEmpty(d.config["lvm.use_thinpool"])
 }
@@ -54,6 +60,38 @@ func (d *lvm) thinpoolName() string {
 	return lvmThinpoolDefaultName
 }
 
+// lvmThinpoolAutoExtendArgs returns the arguments of the `lvextend` commands that grow the data and metadata of a
+// thin pool by extendPercent of their size when their usage is at or above thresholdPercent.
+// The usage is the output of `lvs -o data_percent,metadata_percent,lv_metadata_size --units b --nosuffix`.
+func lvmThinpoolAutoExtendArgs(usage string, thresholdPercent float64, extendPercent int64) ([][]string, error) {
+	dataPerc, metaPerc, err := lvmThinpoolUsage(usage)
+	if err != nil {
+		return nil, err
+	}
+
+	var extendArgs [][]string
+
+	if dataPerc >= thresholdPercent {
+		extendArgs = append(extendArgs, []string{"--extents", fmt.Sprintf("+%d%%LV", extendPercent)})
+	}
+
+	if metaPerc >= thresholdPercent {
+		parts := shared.SplitNTrimSpace(usage, ",", -1, true)
+		if len(parts) < 3 {
+			return nil, errors.New("Unexpected output from lvs command")
+		}
+
+		metaSizeBytes, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Failed parsing thin pool metadata size (%q): %w", parts[2], err)
+		}
+
+		extendArgs = append(extendArgs, []string{"--poolmetadatasize", fmt.Sprintf("+%db", metaSizeBytes*extendPercent/100)})
+	}
+
+	return extendArgs, nil
+}
+
 // openLoopFile opens a loop device and returns the device path.
 func (d *lvm) openLoopFile(source string) (string, error) {
 	if source == "" {
@@ -126,6 +164,111 @@ func (d *lvm) volumeGroupExists(vgName string) (bool, []string, error) {
 	return true, tags, nil
 }
 
+// volumeGroupLockType returns the lock type of an LVM Volume Group, for example "sanlock" or "dlm" for shared
+// volume groups.
+func (d *lvm) volumeGroupLockType(vgName string) (string, error) {
+	output, err := shared.RunCommand(context.TODO(), "vgs", "--noheadings", "-o", "vg_lock_type", vgName)
+	if err != nil {
+		return "", fmt.Errorf("Error getting lock type of LVM volume group %q: %w", vgName, err)
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+// lockStart starts the lock space of the shared volume group on this member.
+func (d *lvm) lockStart() error {
+	_, err := shared.RunCommandRetry(context.TODO(), noKillRetryOpts, "vgchange", "--lock-start", d.config["lvm.vg_name"])
+	if err != nil {
+		return fmt.Errorf("Failed starting lock space of volume group %q: %w", d.config["lvm.vg_name"], err)
+	}
+
+	d.logger.Debug("Volume group lock space started", logger.Ctx{"vg_name": d.config["lvm.vg_name"]})
+
+	return nil
+}
+
+// lockStop stops the lock space of the shared volume group on this member.
+func (d *lvm) lockStop() error {
+	_, err := shared.RunCommandRetry(context.TODO(), noKillRetryOpts, "vgchange", "--lock-stop", d.config["lvm.vg_name"])
+	if err != nil {
+		return fmt.Errorf("Failed stopping lock space of volume group %q: %w", d.config["lvm.vg_name"], err)
+	}
+
+	d.logger.Debug("Volume group lock space stopped", logger.Ctx{"vg_name": d.config["lvm.vg_name"]})
+
+	return nil
+}
+
+// connector retrieves an initialized storage connector based on the configured lvm.connector.
+// The connector is cached in the driver struct.
+func (d *lvm) connector() (connectors.Connector, error) {
+	if d.storageConnector == nil {
+		connector, err := connectors.NewConnector(d.config["lvm.connector"], d.state.OS.ServerUUID)
+		if err != nil {
+			return nil, err
+		}
+
+		d.storageConnector = connector
+	}
+
+	return d.storageConnector, nil
+}
+
+// connectTarget connects to the target holding the shared LUN if a connector is configured.
+func (d *lvm) connectTarget() error {
+	if d.config["lvm.connector"] == "" {
+		return nil
+	}
+
+	connector, err := d.connector()
+	if err != nil {
+		return err
+	}
+
+	targetAddrs := shared.SplitNTrimSpace(d.config["lvm.connector.target"], ",", -1, true)
+
+	// The returned reverter only cancels the remaining connection attempts, which are left to complete.
+	_, err = connector.Connect(d.state.ShutdownCtx, d.config["lvm.connector.target_qn"], targetAddrs...)
+	if err != nil {
+		return fmt.Errorf("Failed connecting to target %q: %w", d.config["lvm.connector.target_qn"], err)
+	}
+
+	return nil
+}
+
+// disconnectTarget disconnects from the target holding the shared LUN if a connector is configured.
+func (d *lvm) disconnectTarget() error {
+	if d.config["lvm.connector"] == "" {
+		return nil
+	}
+
+	connector, err := d.connector()
+	if err != nil {
+		return err
+	}
+
+	err = connector.Disconnect(d.config["lvm.connector.target_qn"])
+	if err != nil {
+		return fmt.Errorf("Failed disconnecting from target %q: %w", d.config["lvm.connector.target_qn"], err)
+	}
+
+	return nil
+}
+
+// waitBlockDevice waits for the block device of the shared LUN to appear after connecting to its target.
+func (d *lvm) waitBlockDevice(devPath string) error {
+	waitUntil := time.Now().Add(30 * time.Second)
+	for !shared.IsBlockdevPath(devPath) {
+		if time.Now().After(waitUntil) {
+			return fmt.Errorf("Block device %q not found", devPath)
+		}
+
+		time.Sleep(1 * time.Second)
+	}
+
+	return nil
+}
+
 // volumeGroupExtentSize gets the volume group's physical extent size in bytes.
 func (d *lvm) volumeGroupExtentSize(vgName string) (int64, error) {
 	output, err := shared.RunCommand(context.TODO(), "vgs", "--noheadings", "--nosuffix", "--units", "b", "-o", "vg_extent_size", vgName)
@@ -326,6 +469,11 @@ func (d *lvm) createLogicalVolume(vgName, thinPoolName string, vol Volume, makeT
 		return err
 	}
 
+	// Make room for the encryption header so that the decrypted device has the requested size.
+	if vol.IsEncrypted() {
+		lvSizeBytes += luksHeaderSize
+	}
+
 	lvFullName := d.lvmFullVolumeName(vol.volType, vol.contentType, vol.name)
 
 	args := []string{
@@ -371,14 +519,45 @@ func (d *lvm) createLogicalVolume(vgName, thinPoolName string, vol Volume, makeT
 
 	volDevPath := d.lvmDevPath(vgName, vol.volType, vol.contentType, vol.name)
 
+	if vol.IsEncrypted() {
+		err = d.formatEncryptedVolume(vol)
+		if err != nil {
+			return err
+		}
+	}
+
 	if vol.contentType == ContentTypeFS {
-		_, err = makeFSType(volDevPath, vol.ConfigBlockFilesystem(), nil)
+		fsDevPath := volDevPath
+
+		if vol.IsEncrypted() {
+			err = d.openEncryptedVolume(vol)
+			if err != nil {
+				return err
+			}
+
+			fsDevPath = d.volumeDevPath(vol)
+		}
+
+		_, err = makeFSType(fsDevPath, vol.ConfigBlockFilesystem(), nil)
+
+		if vol.IsEncrypted() {
+			closeErr := luksClose(d.luksMapperName(vol))
+			if err == nil {
+				err = closeErr
+			}
+		}
+
 		if err != nil {
 			return fmt.Errorf("Error making filesystem on LVM logical volume: %w", err)
 		}
 	} else if !d.usesThinpool() {
-		// Make sure we get an empty LV.
-		err := block.ClearBlock(volDevPath, 0)
+		// Make sure we get an empty LV, leaving the encryption header in place.
+		var offset int64
+		if vol.IsEncrypted() {
+			offset = luksHeaderSize
+		}
+
+		err := block.ClearBlock(volDevPath, offset)
 		if err != nil {
 			return fmt.Errorf("Error clearing LVM logical volume: %w", err)
 		}
@@ -398,6 +577,15 @@ func (d *lvm) createLogicalVolume(vgName, thinPoolName string, vol Volume, makeT
 		}
 	}
 
+	// The logical volume is active on this member after creation, which would prevent the other members of a
+	// shared volume group from activating it.
+	if d.clustered {
+		_, err := shared.RunCommand(context.TODO(), "lvchange", "--activate", "n", volDevPath)
+		if err != nil {
+			return fmt.Errorf("Failed deactivating LVM logical volume %q: %w", volDevPath, err)
+		}
+	}
+
 	d.logger.Debug("Logical volume created", logger.Ctx{"vg_name": vgName, "lv_name": lvFullName, "size": strconv.FormatInt(lvSizeBytes, 10) + "b", "fs": vol.ConfigBlockFilesystem()})
 	return nil
 }
@@ -800,7 +988,59 @@ func (d *lvm) activationRefCountDecrement(vol Volume) uint {
 	return refcount.Decrement(d.activationRefCountName(vol), 1)
 }
 
+// luksMapperName returns the device mapper name of the decrypted device of an encrypted volume.
+func (d *lvm) luksMapperName(vol Volume) string {
+	return luksMapperName(d.name, d.lvmFullVolumeName(vol.volType, vol.contentType, vol.name))
+}
+
+// volumeDevPath returns the path of the block device holding the volume's data.
+// For encrypted volumes this is the decrypted device which only exists while the volume is activated.
+func (d *lvm) volumeDevPath(vol Volume) string {
+	if vol.IsEncrypted() {
+		return luksMapperPath(d.luksMapperName(vol))
+	}
+
+	return d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name)
+}
+
+// formatEncryptedVolume initializes the encryption header of a new logical volume.
+func (d *lvm) formatEncryptedVolume(vol Volume) error {
+	key, err := d.luksVolumeKey(vol)
+	if err != nil {
+		return err
+	}
+
+	return luksFormat(d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name), key)
+}
+
+// openEncryptedVolume opens the decrypted device of an encrypted logical volume if not already opened.
+func (d *lvm) openEncryptedVolume(vol Volume) error {
+	key, err := d.luksVolumeKey(vol)
+	if err != nil {
+		return err
+	}
+
+	_, err = luksOpen(d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name), d.luksMapperName(vol), key, false)
+	if err != nil {
+		return err
+	}
+
+	d.logger.Debug("Opened encrypted logical volume", logger.Ctx{"volName": vol.Name(), "dev": d.volumeDevPath(vol)})
+	return nil
+}
+
+// resizeEncryptedVolume grows the decrypted device of an encrypted logical volume to the size of the volume.
+func (d *lvm) resizeEncryptedVolume(vol Volume) error {
+	key, err := d.luksVolumeKey(vol)
+	if err != nil {
+		return err
+	}
+
+	return luksResize(d.luksMapperName(vol), key)
+}
+
 // activateVolume activates an LVM logical volume if not already present. Returns true if activated, false if not.
+// The decrypted device of encrypted volumes is opened too.
 func (d *lvm) activateVolume(vol Volume) (bool, error) {
 	var volDevPath string
 
@@ -813,17 +1053,37 @@ func (d *lvm) activateVolume(vol Volume) (bool, error) {
 	}
 
 	if !shared.PathExists(volDevPath) {
-		_, err := shared.RunCommand(context.TODO(), "lvchange", "--activate", "y", "--ignoreactivationskip", volDevPath)
+		// Logical volumes of shared volume groups are activated exclusively on this member.
+		activate := "y"
+		if d.clustered {
+			activate = "ey"
+		}
+
+		_, err := shared.RunCommand(context.TODO(), "lvchange", "--activate", activate, "--ignoreactivationskip", volDevPath)
 		if err != nil {
 			return false, fmt.Errorf("Failed activating LVM logical volume %q: %w", volDevPath, err)
 		}
 
 		d.logger.Debug("Activated logical volume", logger.Ctx{"volName": vol.Name(), "dev": volDevPath})
 
+		if vol.IsEncrypted() {
+			err = d.openEncryptedVolume(vol)
+			if err != nil {
+				return false, err
+			}
+		}
+
 		d.activationRefCountIncrement(vol)
 		return true, nil
 	}
 
+	if vol.IsEncrypted() {
+		err := d.openEncryptedVolume(vol)
+		if err != nil {
+			return false, err
+		}
+	}
+
 	d.activationRefCountIncrement(vol)
 	return false, nil
 }
@@ -840,6 +1100,14 @@ func (d *lvm) deactivateVolume(vol Volume) (bool, error) {
 		return false, nil
 	}
 
+	// Close the decrypted device first as it keeps the logical volume busy.
+	if vol.IsEncrypted() {
+		err := luksClose(d.luksMapperName(vol))
+		if err != nil {
+			return false, err
+		}
+	}
+
 	var volDevPath string
 
 	if d.usesThinpool() {
@@ -2,6 +2,10 @@ package drivers
 
 import (
 	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Example_lvm_parseLogicalVolumeName() {
@@ -66,3 +70,50 @@ func Example_lvm_parseLogicalVolumeName() {
 	// custom_proj_testvol--with--hyphens.block: Unrecognised
 	// custom_proj_testvol--with--hyphens.block-snap1--with--hyphens.block: snap1-with-hyphens.block
 }
+
+func Test_lvmThinpoolAutoExtendArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		usage string
+		want  [][]string
+	}{
+		{
+			name:  "Below threshold",
+			usage: "  45.00,12.50,4194304\n",
+		},
+		{
+			name:  "Data above threshold",
+			usage: "  85.10,12.50,4194304\n",
+			want:  [][]string{{"--extents", "+20%LV"}},
+		},
+		{
+			name:  "Metadata at threshold",
+			usage: "45.00,80.00,4194304",
+			want:  [][]string{{"--poolmetadatasize", "+838860b"}},
+		},
+		{
+			name:  "Data and metadata above threshold",
+			usage: "99.00,95.00,4194304",
+			want:  [][]string{{"--extents", "+20%LV"}, {"--poolmetadatasize", "+838860b"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			extendArgs, err := lvmThinpoolAutoExtendArgs(test.usage, 80, 20)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, extendArgs)
+		})
+	}
+
+	_, err := lvmThinpoolAutoExtendArgs("", 80, 20)
+	assert.Error(t, err)
+
+	// The metadata size is only needed to extend the metadata.
+	_, err = lvmThinpoolAutoExtendArgs("95.00,95.00", 80, 20)
+	assert.Error(t, err)
+
+	extendArgs, err := lvmThinpoolAutoExtendArgs("95.00,10.00", 80, 50)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"--extents", "+50%LV"}}, extendArgs)
+}
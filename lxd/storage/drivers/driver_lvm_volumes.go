@@ -212,6 +212,13 @@ func (d *lvm) DeleteVolume(vol Volume, op *operations.Operation) error {
 			}
 		}
 
+		if vol.IsEncrypted() {
+			err = luksClose(d.luksMapperName(vol))
+			if err != nil {
+				return err
+			}
+		}
+
 		err = d.removeLogicalVolume(d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name))
 		if err != nil {
 			return fmt.Errorf("Error removing LVM logical volume: %w", err)
@@ -312,6 +319,7 @@ func (d *lvm) commonVolumeRules() map[string]func(value string) error {
 	return map[string]func(value string) error{
 		"block.mount_options": validate.IsAny,
 		"block.filesystem":    validate.Optional(validate.IsOneOf(blockBackedAllowedFilesystems...)),
+		"block.encryption":    validate.Optional(validate.IsOneOf("luks2")),
 		// lxdmeta:generate(entities=storage-lvm; group=volume-conf; key=lvm.stripes)
 		//
 		// ---
@@ -344,11 +352,17 @@ func (d *lvm) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
 		delete(commonRules, "block.mount_options")
 	}
 
+	commonRules["volatile.encryption.key"] = validate.IsAny
+
 	err := d.validateVolume(vol, commonRules, removeUnknownKeys)
 	if err != nil {
 		return err
 	}
 
+	if vol.config["volatile.encryption.key"] != "" && !vol.IsEncrypted() {
+		return errors.New("volatile.encryption.key can only be set on encrypted volumes")
+	}
+
 	if d.usesThinpool() && vol.config["lvm.stripes"] != "" {
 		return errors.New("lvm.stripes cannot be used with thin pool volumes")
 	}
@@ -430,6 +444,11 @@ func (d *lvm) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op
 		return err
 	}
 
+	// The logical volume of encrypted volumes also holds the encryption header.
+	if vol.IsEncrypted() {
+		sizeBytes += luksHeaderSize
+	}
+
 	// Read actual size of current volume.
 	volDevPath := d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name)
 	oldSizeBytes, err := d.logicalVolumeSize(volDevPath)
@@ -489,7 +508,12 @@ func (d *lvm) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op
 			// so that we can have more control over when we trigger unsafe filesystem resize mode,
 			// otherwise by passing -f to lvresize (required for other reasons) this would then pass
 			// -f onto resize2fs as well.
-			err = shrinkFileSystem(fsType, volDevPath, vol, sizeBytes, allowUnsafeResize)
+			fsSizeBytes := sizeBytes
+			if vol.IsEncrypted() {
+				fsSizeBytes -= luksHeaderSize
+			}
+
+			err = shrinkFileSystem(fsType, d.volumeDevPath(vol), vol, fsSizeBytes, allowUnsafeResize)
 			if err != nil {
 				_, _ = d.deactivateVolume(vol)
 				return err
@@ -527,8 +551,16 @@ func (d *lvm) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op
 				}()
 			}
 
+			// Grow the decrypted device if it was already opened before the resize.
+			if vol.IsEncrypted() {
+				err = d.resizeEncryptedVolume(vol)
+				if err != nil {
+					return err
+				}
+			}
+
 			// Grow the filesystem to fill block device.
-			err = growFileSystem(fsType, volDevPath, vol)
+			err = growFileSystem(fsType, d.volumeDevPath(vol), vol)
 			if err != nil {
 				return err
 			}
@@ -553,6 +585,14 @@ func (d *lvm) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op
 			return err
 		}
 
+		// Resize the decrypted device if opened, as is the case when called from a volume filler.
+		if vol.IsEncrypted() {
+			err = d.resizeEncryptedVolume(vol)
+			if err != nil {
+				return err
+			}
+		}
+
 		// The new blocks in a grown volume will need clearing if using a thick pool.
 		needsClearing := !d.usesThinpool() && (oldSizeBytes < sizeBytes)
 
@@ -589,7 +629,7 @@ func (d *lvm) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op
 		// expected the caller will do all necessary post resize actions themselves).
 		// Do this after the new blocks have been cleared.
 		if needsGPTHeaderMove {
-			err = d.moveGPTAltHeader(volDevPath)
+			err = d.moveGPTAltHeader(d.volumeDevPath(vol))
 			if err != nil {
 				return err
 			}
@@ -602,8 +642,7 @@ func (d *lvm) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op
 // GetVolumeDiskPath returns the location of a disk volume.
 func (d *lvm) GetVolumeDiskPath(vol Volume) (string, error) {
 	if vol.IsVMBlock() || (vol.volType == VolumeTypeCustom && IsContentBlock(vol.contentType)) {
-		volDevPath := d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name)
-		return volDevPath, nil
+		return d.volumeDevPath(vol), nil
 	}
 
 	return "", ErrNotSupported
@@ -756,6 +795,7 @@ func (d *lvm) mountCommon(vol Volume, op *operations.Operation) error {
 		// we take another snapshot of the snapshot, regenerate the temporary snapshot's UUID and then
 		// mount that.
 		regenerateFSUUID := renegerateFilesystemUUIDNeeded(vol.ConfigBlockFilesystem())
+		regenerateTmpVolFSUUID := false
 		if isSnapshot && regenerateFSUUID {
 			// Instantiate a new volume to be the temporary writable snapshot.
 			tmpVolName := vol.name + tmpVolSuffix
@@ -786,11 +826,8 @@ func (d *lvm) mountCommon(vol Volume, op *operations.Operation) error {
 					mountOptions += ",nouuid"
 				}
 			} else {
-				d.logger.Debug("Regenerating filesystem UUID", logger.Ctx{"dev": volDevPath, "fs": tmpVolFsType})
-				err = regenerateFilesystemUUID(mountVol.ConfigBlockFilesystem(), volDevPath)
-				if err != nil {
-					return err
-				}
+				// The filesystem UUID is regenerated once the volume is activated.
+				regenerateTmpVolFSUUID = true
 			}
 		}
 
@@ -800,6 +837,17 @@ func (d *lvm) mountCommon(vol Volume, op *operations.Operation) error {
 			return err
 		}
 
+		// Encrypted volumes are mounted through their decrypted device.
+		volDevPath = d.volumeDevPath(mountVol)
+
+		if regenerateTmpVolFSUUID {
+			d.logger.Debug("Regenerating filesystem UUID", logger.Ctx{"dev": volDevPath, "fs": mountVol.ConfigBlockFilesystem()})
+			err = regenerateFilesystemUUID(mountVol.ConfigBlockFilesystem(), volDevPath)
+			if err != nil {
+				return err
+			}
+		}
+
 		// Finally attempt to mount the volume that needs mounting.
 		err = TryMount(context.TODO(), volDevPath, mountPath, mountVol.ConfigBlockFilesystem(), mountFlags, mountOptions)
 		if err != nil {
@@ -886,6 +934,20 @@ func (d *lvm) unmountCommon(vol Volume, keepBlockDev bool, op *operations.Operat
 			}
 
 			if exists {
+				// Unmount first as the temporary snapshot is the mounted device.
+				err = TryUnmount(mountPath, 0)
+				if err != nil {
+					return false, fmt.Errorf("Failed unmounting LVM logical volume: %w", err)
+				}
+
+				if vol.IsEncrypted() {
+					tmpVol := NewVolume(d, d.name, vol.volType, vol.contentType, tmpVolName, vol.config, vol.poolConfig)
+					err = luksClose(d.luksMapperName(tmpVol))
+					if err != nil {
+						return true, err
+					}
+				}
+
 				err = d.removeLogicalVolume(tmpVolDevPath)
 				if err != nil {
 					return true, fmt.Errorf("Failed removing temporary LVM snapshot volume %q: %w", tmpVolDevPath, err)
@@ -893,9 +955,11 @@ func (d *lvm) unmountCommon(vol Volume, keepBlockDev bool, op *operations.Operat
 			}
 		}
 
-		err = TryUnmount(mountPath, 0)
-		if err != nil {
-			return false, fmt.Errorf("Failed unmounting LVM logical volume: %w", err)
+		if filesystem.IsMountPoint(mountPath) {
+			err = TryUnmount(mountPath, 0)
+			if err != nil {
+				return false, fmt.Errorf("Failed unmounting LVM logical volume: %w", err)
+			}
 		}
 
 		d.logger.Debug("Unmounted logical volume", logger.Ctx{"volName": vol.name, "path": mountPath, "keepBlockDev": keepBlockDev})
@@ -972,6 +1036,14 @@ func (d *lvm) RenameVolume(vol Volume, newVolName string, op *operations.Operati
 			}
 		}
 
+		// The decrypted device is named after the logical volume so close it before renaming.
+		if vol.IsEncrypted() {
+			err = luksClose(d.luksMapperName(vol))
+			if err != nil {
+				return err
+			}
+		}
+
 		// Rename actual volume.
 		newVolDevPath := d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, newVolName)
 		err = d.renameLogicalVolume(volDevPath, newVolDevPath)
@@ -1085,6 +1157,13 @@ func (d *lvm) DeleteVolumeSnapshot(snapVol Volume, op *operations.Operation) err
 			}
 		}
 
+		if snapVol.IsEncrypted() {
+			err = luksClose(d.luksMapperName(snapVol))
+			if err != nil {
+				return err
+			}
+		}
+
 		err = d.removeLogicalVolume(d.lvmDevPath(d.config["lvm.vg_name"], snapVol.volType, snapVol.contentType, snapVol.name))
 		if err != nil {
 			return fmt.Errorf("Error removing LVM logical volume: %w", err)
@@ -1190,6 +1269,14 @@ func (d *lvm) RestoreVolume(vol Volume, snapVol Volume, op *operations.Operation
 			return nil, fmt.Errorf("Error unmounting LVM logical volume: %w", err)
 		}
 
+		// The decrypted device is named after the logical volume so close it before renaming.
+		if restoreVol.IsEncrypted() {
+			err = luksClose(d.luksMapperName(restoreVol))
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		originalVolDevPath := d.lvmDevPath(d.config["lvm.vg_name"], restoreVol.volType, restoreVol.contentType, restoreVol.name)
 		tmpVolName := restoreVol.name + tmpVolSuffix
 		tmpVolDevPath := d.lvmDevPath(d.config["lvm.vg_name"], restoreVol.volType, restoreVol.contentType, tmpVolName)
@@ -0,0 +1,235 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/lxd/storage/filesystem"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/validate"
+)
+
+// nfs stores volumes on an NFS export mounted on every cluster member.
+// The volumes use the same layout as the dir driver: directories for filesystem volumes and raw files for
+// block volumes. So the volume handling is inherited from it and only the pool handling differs.
+type nfs struct {
+	dir
+}
+
+// load is used to run one-time action per-driver rather than per-pool.
+func (d *nfs) load() error {
+	// Register the patches.
+	d.patches = map[string]func() error{
+		"storage_lvm_skipactivation":                         nil,
+		"storage_missing_snapshot_records":                   nil,
+		"storage_delete_old_snapshot_records":                nil,
+		"storage_zfs_drop_block_volume_filesystem_extension": nil,
+		"storage_prefix_bucket_names_with_project":           nil,
+	}
+
+	return nil
+}
+
+// isRemote returns true indicating this driver uses remote storage.
+func (d *nfs) isRemote() bool {
+	return true
+}
+
+// Info returns info about the driver and its environment.
+func (d *nfs) Info() Info {
+	return Info{
+		Name:                         "nfs",
+		Version:                      nfsVersion,
+		DefaultBlockSize:             d.defaultBlockVolumeSize(),
+		DefaultVMBlockFilesystemSize: d.defaultVMBlockFilesystemSize(),
+		OptimizedImages:              false,
+		PreservesInodes:              false,
+		Remote:                       d.isRemote(),
+		VolumeTypes:                  []VolumeType{VolumeTypeCustom, VolumeTypeImage, VolumeTypeContainer, VolumeTypeVM},
+		VolumeMultiNode:              false,
+		BlockBacking:                 false,
+		RunningCopyFreeze:            true,
+		DirectIO:                     true,
+		IOUring:                      false,
+		MountedRoot:                  true,
+		Buckets:                      false,
+		PopulateParentVolumeUUID:     false,
+	}
+}
+
+// FillConfig populates the storage pool's configuration file with the default values.
+func (d *nfs) FillConfig() error {
+	return nil
+}
+
+// SourceIdentifier returns the NFS export in the form used by mount.
+func (d *nfs) SourceIdentifier() (string, error) {
+	if d.config["nfs.host"] == "" || d.config["nfs.path"] == "" {
+		return "", errors.New("Cannot derive identifier from empty host or path")
+	}
+
+	return nfsMountSource(d.config["nfs.host"], d.config["nfs.path"]), nil
+}
+
+// ValidateSource checks whether the required config keys are set to access the remote source.
+func (d *nfs) ValidateSource() error {
+	if d.config["nfs.host"] == "" {
+		return errors.New("Missing required host")
+	}
+
+	if !path.IsAbs(d.config["nfs.path"]) {
+		return errors.New("Missing required absolute export path")
+	}
+
+	return nil
+}
+
+// Create is called during pool creation and is effectively using an empty driver struct.
+// WARNING: The Create() function cannot rely on any of the struct attributes being set.
+func (d *nfs) Create() error {
+	// Create a temporary mountpoint.
+	mountPath, err := os.MkdirTemp("", "lxd_nfs_")
+	if err != nil {
+		return fmt.Errorf("Failed creating temporary directory: %w", err)
+	}
+
+	defer func() { _ = os.RemoveAll(mountPath) }()
+
+	err = os.Chmod(mountPath, 0700)
+	if err != nil {
+		return fmt.Errorf("Failed chmoding %q: %w", mountPath, err)
+	}
+
+	// Mount the export to check that it is reachable and unused.
+	err = d.mountExport(mountPath)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _, _ = forceUnmount(mountPath) }()
+
+	isEmpty, err := shared.PathIsEmpty(mountPath)
+	if err != nil {
+		return err
+	}
+
+	if !isEmpty {
+		return errors.New("Only empty NFS exports can be used as a LXD storage pool")
+	}
+
+	return nil
+}
+
+// Delete removes the storage pool from the storage device.
+func (d *nfs) Delete(op *operations.Operation) error {
+	_, err := d.Mount()
+	if err != nil {
+		return err
+	}
+
+	// On delete, wipe everything in the export.
+	err = wipeDirectory(GetPoolMountPath(d.name))
+	if err != nil {
+		return err
+	}
+
+	// Make sure the existing pool is unmounted.
+	_, err = d.Unmount()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Validate checks that all provide keys are supported and that no conflicting or missing configuration is present.
+func (d *nfs) Validate(config map[string]string) error {
+	rules := map[string]func(value string) error{
+		// lxdmeta:generate(entities=storage-nfs; group=pool-conf; key=nfs.host)
+		// This option specifies the host name or IP address of the NFS server.
+		// ---
+		//  type: string
+		//  shortdesc: Host name or IP address of the NFS server
+		//  scope: global
+		"nfs.host": validate.IsAny,
+		// lxdmeta:generate(entities=storage-nfs; group=pool-conf; key=nfs.path)
+		// This option specifies the absolute path of the export on the NFS server.
+		// The export must exist and be empty when the storage pool is created.
+		// ---
+		//  type: string
+		//  shortdesc: Path of the NFS export
+		//  scope: global
+		"nfs.path": validate.Optional(validate.IsAbsFilePath),
+		// lxdmeta:generate(entities=storage-nfs; group=pool-conf; key=nfs.mount_options)
+		// This option specifies additional comma-separated NFS mount options, for example `nconnect=4`.
+		// Changes take effect the next time the storage pool is mounted.
+		// ---
+		//  type: string
+		//  shortdesc: Additional NFS mount options
+		//  scope: global
+		"nfs.mount_options": validate.IsAny,
+	}
+
+	return d.validatePool(config, rules, nil)
+}
+
+// ValidateVolume validates the supplied volume config. Optionally removes invalid keys from the volume's config.
+// Inode quotas rely on project quotas of the local file system and so aren't available on NFS exports.
+func (d *nfs) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
+	return d.validateVolume(vol, nil, removeUnknownKeys)
+}
+
+// Update applies any driver changes required from a configuration change.
+func (d *nfs) Update(changedConfig map[string]string) error {
+	for _, key := range []string{"nfs.host", "nfs.path"} {
+		_, changed := changedConfig[key]
+		if changed {
+			return fmt.Errorf("%s cannot be changed", key)
+		}
+	}
+
+	return nil
+}
+
+// Mount mounts the storage pool.
+func (d *nfs) Mount() (bool, error) {
+	path := GetPoolMountPath(d.name)
+
+	// Check if already mounted.
+	if filesystem.IsMountPoint(path) {
+		return false, nil
+	}
+
+	err := d.mountExport(path)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Unmount unmounts the storage pool.
+func (d *nfs) Unmount() (bool, error) {
+	return forceUnmount(GetPoolMountPath(d.name))
+}
+
+// GetResources returns the pool resource usage information.
+func (d *nfs) GetResources() (*api.ResourcesStoragePool, error) {
+	return genericVFSGetResources(d)
+}
+
+// mountExport mounts the NFS export of the pool on the given path.
+func (d *nfs) mountExport(path string) error {
+	addr, err := nfsServerAddress(context.TODO(), d.config["nfs.host"])
+	if err != nil {
+		return err
+	}
+
+	source := nfsMountSource(d.config["nfs.host"], d.config["nfs.path"])
+	return TryMount(context.TODO(), source, path, "nfs4", 0, nfsMountOptions(addr, d.config["nfs.mount_options"]))
+}
@@ -0,0 +1,46 @@
+package drivers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// nfsVersion is the NFS protocol version used to mount the exports.
+// NFS 4.2 is required for user extended attributes, sparse files and server-side copies.
+const nfsVersion = "4.2"
+
+// nfsServerAddress resolves the host of an NFS server to the address passed to the kernel.
+func nfsServerAddress(ctx context.Context, host string) (string, error) {
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("Failed resolving NFS server %q: %w", host, err)
+	}
+
+	if len(addrs) == 0 {
+		return "", fmt.Errorf("No address found for NFS server %q", host)
+	}
+
+	return addrs[0], nil
+}
+
+// nfsMountSource returns the source of an NFS mount for an export.
+func nfsMountSource(host string, exportPath string) string {
+	// IPv6 addresses must be enclosed in brackets to separate them from the path.
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	return host + ":" + exportPath
+}
+
+// nfsMountOptions returns the options of an NFS mount for a server address, followed by the additional options.
+func nfsMountOptions(addr string, extraOptions string) string {
+	options := []string{"vers=" + nfsVersion, "addr=" + addr}
+	if extraOptions != "" {
+		options = append(options, extraOptions)
+	}
+
+	return strings.Join(options, ",")
+}
@@ -0,0 +1,18 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNFSMountSource(t *testing.T) {
+	assert.Equal(t, "nfs.example.com:/srv/lxd", nfsMountSource("nfs.example.com", "/srv/lxd"))
+	assert.Equal(t, "10.0.0.1:/srv/lxd", nfsMountSource("10.0.0.1", "/srv/lxd"))
+	assert.Equal(t, "[fd00::1]:/srv/lxd", nfsMountSource("fd00::1", "/srv/lxd"))
+}
+
+func TestNFSMountOptions(t *testing.T) {
+	assert.Equal(t, "vers=4.2,addr=10.0.0.1", nfsMountOptions("10.0.0.1", ""))
+	assert.Equal(t, "vers=4.2,addr=fd00::1,nconnect=4,hard", nfsMountOptions("fd00::1", "nconnect=4,hard"))
+}
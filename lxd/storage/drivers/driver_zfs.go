@@ -476,6 +476,15 @@ func (d *zfs) Validate(config map[string]string) error {
 		//  shortdesc: Name of the zpool
 		//  scope: local
 		"zfs.pool_name": validate.IsAny,
+		// lxdmeta:generate(entities=storage-btrfs,storage-ceph,storage-zfs; group=pool-conf; key=scrub.schedule)
+		// Specify either a cron expression (`<minute> <hour> <dom> <month> <dow>`), a comma-separated list of schedule aliases (`@hourly`, `@daily`, `@midnight`, `@weekly`, `@monthly`, `@annually`, `@yearly`), or leave empty to disable scheduled scrubs (the default).
+		// See {ref}`storage-pool-health`.
+		// ---
+		//  type: string
+		//  defaultdesc: empty
+		//  shortdesc: Schedule for automatic scrubs of the pool
+		//  scope: global
+		"scrub.schedule": validateScrubSchedule,
 		// lxdmeta:generate(entities=storage-zfs; group=pool-conf; key=zfs.clone_copy)
 		// Set this option to `true` or `false` to enable or disable using ZFS lightweight clones rather
 		// than full dataset copies.
@@ -684,6 +693,51 @@ func (d *zfs) GetResources() (*api.ResourcesStoragePool, error) {
 	return &res, nil
 }
 
+// CheckHealth runs health checks against the zpool backing the storage pool.
+func (d *zfs) CheckHealth() (*api.StoragePoolHealth, error) {
+	poolName, _, _ := strings.Cut(d.config["zfs.pool_name"], "/")
+	checks := []api.StoragePoolHealthCheck{}
+
+	// Check the state of the zpool and its devices.
+	out, err := shared.RunCommand(d.state.ShutdownCtx, "zpool", "list", "-H", "-o", "health", poolName)
+	if err != nil {
+		checks = append(checks, healthCheckFailed("pool-state", err))
+	} else {
+		state := strings.TrimSpace(out)
+		checks = append(checks, healthCheck("pool-state", state == "ONLINE", state))
+	}
+
+	// Check how full the zpool is as ZFS slows down and eventually fails writes when it runs out of space.
+	out, err = shared.RunCommand(d.state.ShutdownCtx, "zpool", "list", "-H", "-o", "capacity", poolName)
+	if err != nil {
+		checks = append(checks, healthCheckFailed("capacity", err))
+	} else {
+		checks = append(checks, zfsCapacityHealthCheck(out))
+	}
+
+	// Check the outcome of the last scrub and for known data errors.
+	out, err = shared.RunCommand(d.state.ShutdownCtx, "zpool", "status", poolName)
+	if err != nil {
+		checks = append(checks, healthCheckFailed("scrub", err))
+	} else {
+		checks = append(checks, zfsScrubHealthCheck(out)...)
+	}
+
+	return poolHealth(checks), nil
+}
+
+// Scrub starts a scrub of the zpool backing the storage pool.
+func (d *zfs) Scrub() error {
+	poolName, _, _ := strings.Cut(d.config["zfs.pool_name"], "/")
+
+	_, err := shared.RunCommand(d.state.ShutdownCtx, "zpool", "scrub", poolName)
+	if err != nil {
+		return fmt.Errorf("Failed starting scrub of zpool %q: %w", poolName, err)
+	}
+
+	return nil
+}
+
 // MigrationTypes returns the type of transfer methods to be used when doing
 // migrations between pools in preference order.
 func (d *zfs) MigrationTypes(contentType ContentType, refresh bool, copySnapshots bool) []migration.Type {
@@ -14,9 +14,12 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/canonical/lxd/lxd/migration"
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/lxd/storage/quota"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/ioprogress"
+	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/units"
 )
 
@@ -35,6 +38,9 @@ const (
 
 	// zfsMaxVolBlocksize is a maximum value for volblocksize property.
 	zfsMaxVolBlocksize = 128 * 1024
+
+	// zfsInodeQuotaProjectID is the project ID assigned to the files of a dataset with an inode limit.
+	zfsInodeQuotaProjectID = 1
 )
 
 func (d *zfs) dataset(vol Volume, deleted bool) string {
@@ -283,6 +289,86 @@ func (d *zfs) setDatasetProperties(dataset string, options ...string) error {
 	return nil
 }
 
+// setInodeQuotaFromConfig applies the inode limit from the volume's "size.inodes" setting if set.
+func (d *zfs) setInodeQuotaFromConfig(vol Volume) error {
+	inodes := vol.config["size.inodes"]
+	if inodes == "" {
+		return nil
+	}
+
+	return d.setInodeQuota(vol, inodes)
+}
+
+// projectQuotaEnabled returns whether the "project_quota" feature is enabled on the ZFS pool.
+func (d *zfs) projectQuotaEnabled() (bool, error) {
+	poolName, _, _ := strings.Cut(d.config["zfs.pool_name"], "/")
+
+	out, err := shared.RunCommand(context.TODO(), "zpool", "get", "-H", "-o", "value", "feature@project_quota", poolName)
+	if err != nil {
+		return false, err
+	}
+
+	value := strings.TrimSpace(out)
+
+	return value == "enabled" || value == "active", nil
+}
+
+// setInodeQuota limits the number of inodes of a filesystem dataset using a ZFS project object quota.
+// All files of the dataset are assigned to the same project so the limit applies to the whole dataset.
+// An empty or zero value removes the limit.
+func (d *zfs) setInodeQuota(vol Volume, inodes string) error {
+	dataset := d.dataset(vol, false)
+
+	enabled, err := d.projectQuotaEnabled()
+	if err != nil {
+		return err
+	}
+
+	if inodes == "" || inodes == "0" {
+		// No limit can have been set without the feature.
+		if !enabled {
+			return nil
+		}
+
+		return d.setDatasetProperties(dataset, fmt.Sprintf("projectobjquota@%d=none", zfsInodeQuotaProjectID))
+	}
+
+	if !enabled {
+		poolName, _, _ := strings.Cut(d.config["zfs.pool_name"], "/")
+		return fmt.Errorf("Limiting the number of inodes requires the %q feature to be enabled on ZFS pool %q", "project_quota", poolName)
+	}
+
+	err = d.setInodeQuotaProject(vol)
+	if err != nil {
+		return err
+	}
+
+	return d.setDatasetProperties(dataset, fmt.Sprintf("projectobjquota@%d=%s", zfsInodeQuotaProjectID, inodes))
+}
+
+// setInodeQuotaProject assigns all files of a filesystem dataset to the project used for inode limits.
+// Directories are flagged so that new files inherit the project. The files are only walked if the root
+// directory isn't assigned yet, which is the case for volumes cloned from an image volume created empty.
+func (d *zfs) setInodeQuotaProject(vol Volume) error {
+	err := vol.MountTask(func(mountPath string, _ *operations.Operation) error {
+		projectID, err := quota.GetProject(mountPath)
+		if err != nil {
+			return err
+		}
+
+		if projectID == zfsInodeQuotaProjectID {
+			return nil
+		}
+
+		return quota.SetProject(mountPath, zfsInodeQuotaProjectID)
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("Failed setting project on dataset %q: %w", d.dataset(vol, false), err)
+	}
+
+	return nil
+}
+
 func (d *zfs) setBlocksizeFromConfig(vol Volume) error {
 	size := vol.ExpandedConfig("zfs.blocksize")
 	if size == "" {
@@ -564,6 +650,70 @@ func (d *zfs) delegateDataset(vol Volume, pid int) error {
 	return nil
 }
 
+// luksMapperName returns the device mapper name of the decrypted device of the encrypted zvol of a dataset.
+func (d *zfs) luksMapperName(dataset string) string {
+	return luksMapperName(d.name, strings.ReplaceAll(dataset, "@", "-"))
+}
+
+// formatEncryptedVolume initializes the encryption header of a new zvol.
+func (d *zfs) formatEncryptedVolume(vol Volume) error {
+	key, err := d.luksVolumeKey(vol)
+	if err != nil {
+		return err
+	}
+
+	dataset := d.dataset(vol, false)
+
+	// Block volumes are created with volmode=none so make their device appear for formatting.
+	current, err := d.getDatasetProperty(dataset, "volmode")
+	if err != nil {
+		return err
+	}
+
+	if current != "dev" {
+		err = d.setDatasetProperties(dataset, "volmode=dev")
+		if err != nil {
+			return err
+		}
+
+		defer func() { _, _ = d.deactivateVolume(vol) }()
+	}
+
+	devPath, err := d.getDatasetDiskPath(dataset)
+	if err != nil {
+		return err
+	}
+
+	return luksFormat(devPath, key)
+}
+
+// openEncryptedDataset opens the decrypted device of the encrypted zvol of a dataset if not already opened.
+// Returns the path of the decrypted device.
+func (d *zfs) openEncryptedDataset(vol Volume, dataset string, devPath string, readOnly bool) (string, error) {
+	key, err := d.luksVolumeKey(vol)
+	if err != nil {
+		return "", err
+	}
+
+	mapperPath, err := luksOpen(devPath, d.luksMapperName(dataset), key, readOnly)
+	if err != nil {
+		return "", err
+	}
+
+	d.logger.Debug("Opened encrypted ZFS volume", logger.Ctx{"volName": vol.name, "dev": dataset, "path": mapperPath})
+	return mapperPath, nil
+}
+
+// resizeEncryptedVolume resizes the decrypted device of an encrypted volume to the size of its zvol.
+func (d *zfs) resizeEncryptedVolume(vol Volume) error {
+	key, err := d.luksVolumeKey(vol)
+	if err != nil {
+		return err
+	}
+
+	return luksResize(d.luksMapperName(d.dataset(vol, false)), key)
+}
+
 // ZFSSupportsDelegation returns true if the ZFS version on the system supports user namespace delegation.
 func ZFSSupportsDelegation() bool {
 	return zfsDelegate
@@ -170,6 +170,29 @@ func (d *zfs) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Oper
 		if err != nil {
 			return err
 		}
+
+		// Apply the inode limit.
+		err = d.setInodeQuotaFromConfig(vol)
+		if err != nil {
+			return err
+		}
+
+		// Assign image volumes to the inode limit project while they are still empty, so that the files
+		// unpacked into them inherit it and instances cloned from them don't need to walk their root
+		// file system when an inode limit is set.
+		if vol.volType == VolumeTypeImage && vol.config["size.inodes"] == "" {
+			enabled, err := d.projectQuotaEnabled()
+			if err != nil {
+				return err
+			}
+
+			if enabled {
+				err = d.setInodeQuotaProject(vol)
+				if err != nil {
+					return err
+				}
+			}
+		}
 	} else {
 		var opts []string
 
@@ -219,6 +242,11 @@ func (d *zfs) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Oper
 			return err
 		}
 
+		// The zvol of encrypted volumes also holds the encryption header.
+		if vol.IsEncrypted() {
+			sizeBytes += luksHeaderSize
+		}
+
 		sizeBytes = d.roundVolumeBlockSizeBytes(vol, sizeBytes)
 
 		// Create the volume dataset.
@@ -227,6 +255,13 @@ func (d *zfs) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Oper
 			return err
 		}
 
+		if vol.IsEncrypted() {
+			err = d.formatEncryptedVolume(vol)
+			if err != nil {
+				return err
+			}
+		}
+
 		if vol.contentType == ContentTypeFS {
 			activated, volPath, err := d.activateVolume(vol)
 			if err != nil {
@@ -896,6 +931,14 @@ func (d *zfs) CreateVolumeFromCopy(vol VolumeCopy, srcVol VolumeCopy, allowIncon
 		return err
 	}
 
+	// Apply the inode limit.
+	if vol.contentType == ContentTypeFS && !d.isBlockBacked(vol.Volume) {
+		err = d.setInodeQuotaFromConfig(vol.Volume)
+		if err != nil {
+			return err
+		}
+	}
+
 	// All done.
 	revert.Success()
 	return nil
@@ -1200,6 +1243,12 @@ func (d *zfs) createVolumeFromMigrationOptimized(vol Volume, conn io.ReadWriteCl
 			if err != nil {
 				return err
 			}
+
+			// Apply the inode limit.
+			err = d.setInodeQuotaFromConfig(vol)
+			if err != nil {
+				return err
+			}
 		}
 
 		if d.isBlockBacked(vol) && renegerateFilesystemUUIDNeeded(vol.ConfigBlockFilesystem()) {
@@ -1509,6 +1558,14 @@ func (d *zfs) deleteVolume(vol Volume, op *operations.Operation) error {
 	}
 
 	if exists {
+		// Close the decrypted device as it keeps the zvol busy.
+		if vol.IsEncrypted() {
+			err = luksClose(d.luksMapperName(dataset))
+			if err != nil {
+				return err
+			}
+		}
+
 		// Handle clones.
 		clones, err := d.getClones(dataset)
 		if err != nil {
@@ -1583,6 +1640,27 @@ func (d *zfs) commonVolumeRules() map[string]func(value string) error {
 		//  shortdesc: Mount options for block-backed file system volumes
 		//  scope: global
 		"block.mount_options": validate.IsAny,
+		// lxdmeta:generate(entities=storage-zfs; group=volume-conf; key=block.encryption)
+		// The only supported value is `luks2`.
+		// The volume is encrypted with a random key held by LXD, see {ref}`storage-volume-encryption`.
+		// ---
+		//  type: string
+		//  condition: block volume or block-based volume with content type `filesystem` (`zfs.block_mode` enabled)
+		//  defaultdesc: same as `volume.block.encryption`
+		//  shortdesc: Encryption of the storage volume
+		//  scope: global
+		"block.encryption": validate.Optional(validate.IsOneOf("luks2")),
+		// lxdmeta:generate(entities=storage-zfs; group=volume-conf; key=size.inodes)
+		// This option limits the number of files and directories that can be created on the volume.
+		// The limit is applied using a ZFS project object quota (`projectobjquota`), which requires
+		// the `project_quota` feature to be enabled on the ZFS pool.
+		// ---
+		//  type: string
+		//  condition: container or custom filesystem volume (`zfs.block_mode` disabled)
+		//  defaultdesc: same as `volume.size.inodes`
+		//  shortdesc: Maximum number of inodes of the storage volume
+		//  scope: global
+		"size.inodes": validate.Optional(validate.IsUint32),
 		// lxdmeta:generate(entities=storage-zfs; group=volume-conf; key=zfs.block_mode)
 		// `zfs.block_mode` can be set only for custom storage volumes.
 		// To enable ZFS block mode for all storage volumes in the pool, including instance volumes,
@@ -1661,7 +1739,35 @@ func (d *zfs) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
 		delete(commonRules, "block.mount_options")
 	}
 
-	return d.validateVolume(vol, commonRules, removeUnknownKeys)
+	// lxdmeta:generate(entities=storage-zfs; group=volume-conf; key=volatile.encryption.key)
+	// The key is sealed when `storage.encryption_key_file` is set on the server.
+	// It is never returned by the API.
+	// ---
+	//  type: string
+	//  condition: encrypted volume
+	//  shortdesc: Key of the encrypted storage volume
+	//  scope: global
+	commonRules["volatile.encryption.key"] = validate.IsAny
+
+	err := d.validateVolume(vol, commonRules, removeUnknownKeys)
+	if err != nil {
+		return err
+	}
+
+	if vol.config["volatile.encryption.key"] != "" && !vol.IsEncrypted() {
+		return errors.New("volatile.encryption.key can only be set on encrypted volumes")
+	}
+
+	// Only zvols can be encrypted.
+	if vol.IsEncrypted() && vol.contentType == ContentTypeFS && !d.isBlockBacked(vol) {
+		return errors.New("block.encryption requires zfs.block_mode for filesystem volumes")
+	}
+
+	if vol.config["size.inodes"] != "" && !vol.supportsInodeQuota() {
+		return fmt.Errorf("Volume %q property is not valid for volume type", "size.inodes")
+	}
+
+	return nil
 }
 
 // UpdateVolume applies config changes to the volume.
@@ -1686,6 +1792,13 @@ func (d *zfs) UpdateVolume(vol Volume, changedConfig map[string]string) error {
 				return err
 			}
 		}
+
+		if k == "size.inodes" {
+			err := d.setInodeQuota(vol, v)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	defer func() {
@@ -1763,6 +1876,11 @@ func (d *zfs) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op
 			return nil
 		}
 
+		// The zvol of encrypted volumes also holds the encryption header.
+		if vol.IsEncrypted() {
+			sizeBytes += luksHeaderSize
+		}
+
 		sizeBytes = d.roundVolumeBlockSizeBytes(vol, sizeBytes)
 
 		oldSizeBytesStr, err := d.getDatasetProperty(dataset, "volsize")
@@ -1805,9 +1923,14 @@ func (d *zfs) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op
 					return ErrInUse // We don't allow online shrinking of filesystem block volumes.
 				}
 
+				fsSizeBytes := sizeBytes
+				if vol.IsEncrypted() {
+					fsSizeBytes -= luksHeaderSize
+				}
+
 				// Shrink filesystem first.
 				// Pass allowUnsafeResize to allow disabling of filesystem resize safety checks.
-				err = shrinkFileSystem(fsType, volDevPath, vol, sizeBytes, allowUnsafeResize)
+				err = shrinkFileSystem(fsType, volDevPath, vol, fsSizeBytes, allowUnsafeResize)
 				if err != nil {
 					return err
 				}
@@ -1819,6 +1942,13 @@ func (d *zfs) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op
 				if err != nil {
 					return err
 				}
+
+				if vol.IsEncrypted() {
+					err = d.resizeEncryptedVolume(vol)
+					if err != nil {
+						return err
+					}
+				}
 			} else if sizeBytes > oldVolSizeBytes {
 				// Grow block device first.
 				err = d.setDatasetProperties(dataset, fmt.Sprintf("volsize=%d", sizeBytes))
@@ -1826,6 +1956,13 @@ func (d *zfs) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op
 					return err
 				}
 
+				if vol.IsEncrypted() {
+					err = d.resizeEncryptedVolume(vol)
+					if err != nil {
+						return err
+					}
+				}
+
 				// Grow the filesystem to fill block device.
 				err = growFileSystem(fsType, volDevPath, vol)
 				if err != nil {
@@ -1858,6 +1995,14 @@ func (d *zfs) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op
 			if err != nil {
 				return err
 			}
+
+			// Resize the decrypted device if opened, as is the case when called from a volume filler.
+			if vol.IsEncrypted() {
+				err = d.resizeEncryptedVolume(vol)
+				if err != nil {
+					return err
+				}
+			}
 		}
 
 		// Move the VM GPT alt header to end of disk if needed (not needed in unsafe resize mode as
@@ -2021,14 +2166,24 @@ func (d *zfs) getVolumeDiskPathFromDataset(dataset string) (string, error) {
 }
 
 // GetVolumeDiskPath returns the location of a root disk block device.
+// For encrypted volumes this is the decrypted device which only exists while the volume is activated.
 func (d *zfs) GetVolumeDiskPath(vol Volume) (string, error) {
+	if vol.IsEncrypted() {
+		return luksMapperPath(d.luksMapperName(d.dataset(vol, false))), nil
+	}
+
+	return d.getDatasetDiskPath(d.dataset(vol, false))
+}
+
+// getDatasetDiskPath returns the location of the zvol of a dataset.
+func (d *zfs) getDatasetDiskPath(dataset string) (string, error) {
 	// Wait up to 30 seconds for the device to appear.
 	// Don't use d.state.ShutdownCtx here as this is used during instance stop during LXD shutdown after it is
 	// canceled.
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	return d.tryGetVolumeDiskPathFromDataset(ctx, d.dataset(vol, false))
+	return d.tryGetVolumeDiskPathFromDataset(ctx, dataset)
 }
 
 // ListVolumes returns a list of LXD volumes in storage pool.
@@ -2151,6 +2306,7 @@ func (d *zfs) ListVolumes() ([]Volume, error) {
 }
 
 // activateVolume activates a ZFS volume if not already active. Returns whether it was activated along with the path to the zvol or an error.
+// The decrypted device of encrypted volumes is opened too and its path returned instead.
 func (d *zfs) activateVolume(vol Volume) (bool, string, error) {
 	if !IsContentBlock(vol.contentType) && !vol.IsBlockBacked() {
 		return false, "", nil // Nothing to do for non-block or non-block backed volumes.
@@ -2177,11 +2333,18 @@ func (d *zfs) activateVolume(vol Volume) (bool, string, error) {
 		d.logger.Debug("Activated ZFS volume", logger.Ctx{"volName": vol.Name(), "dev": dataset})
 	}
 
-	volumeDiskPath, err := d.GetVolumeDiskPath(vol)
+	volumeDiskPath, err := d.getDatasetDiskPath(dataset)
 	if err != nil {
 		return false, "", fmt.Errorf("Failed getting volume disk path: %v", err)
 	}
 
+	if vol.IsEncrypted() {
+		volumeDiskPath, err = d.openEncryptedDataset(vol, dataset, volumeDiskPath, false)
+		if err != nil {
+			return false, "", err
+		}
+	}
+
 	return activated, volumeDiskPath, nil
 }
 
@@ -2203,7 +2366,15 @@ func (d *zfs) deactivateVolume(vol Volume) (bool, error) {
 		return false, nil
 	}
 
-	devPath, err := d.GetVolumeDiskPath(vol)
+	// Close the decrypted device first as it keeps the zvol busy.
+	if vol.IsEncrypted() {
+		err = luksClose(d.luksMapperName(dataset))
+		if err != nil {
+			return false, err
+		}
+	}
+
+	devPath, err := d.getDatasetDiskPath(dataset)
 	if err != nil {
 		return false, fmt.Errorf("Failed locating zvol for deactivation: %w", err)
 	}
@@ -2438,6 +2609,14 @@ func (d *zfs) RenameVolume(vol Volume, newVolName string, op *operations.Operati
 		_ = genericVFSRenameVolume(d, newVol, vol.name, op)
 	})
 
+	// The decrypted device is named after the dataset so close it before renaming.
+	if vol.IsEncrypted() {
+		err = luksClose(d.luksMapperName(d.dataset(vol, false)))
+		if err != nil {
+			return err
+		}
+	}
+
 	// Rename the ZFS datasets.
 	_, err = shared.RunCommand(context.TODO(), "zfs", "rename", d.dataset(vol, false), d.dataset(newVol, false))
 	if err != nil {
@@ -3135,6 +3314,21 @@ func (d *zfs) mountVolumeSnapshot(snapVol Volume, snapshotDataset string, mountP
 			d.logger.Debug("Activated ZFS snapshot volume", logger.Ctx{"dev": snapshotDataset})
 		}
 
+		// Snapshot zvols are read-only so their decrypted device is too.
+		if snapVol.contentType == ContentTypeBlock && snapVol.IsEncrypted() {
+			devPath, err := d.getDatasetDiskPath(snapshotDataset)
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = d.openEncryptedDataset(snapVol, snapshotDataset, devPath, true)
+			if err != nil {
+				return nil, err
+			}
+
+			revert.Add(func() { _ = luksClose(d.luksMapperName(snapshotDataset)) })
+		}
+
 		if snapVol.contentType != ContentTypeBlock && d.isBlockBacked(snapVol) && !filesystem.IsMountPoint(mountPath) {
 			err = snapVol.EnsureMountPath()
 			if err != nil {
@@ -3184,6 +3378,16 @@ func (d *zfs) mountVolumeSnapshot(snapVol Volume, snapshotDataset string, mountP
 				return nil, err
 			}
 
+			// Only the temporary clone is writable, the snapshot zvol itself is read-only.
+			if snapVol.IsEncrypted() {
+				volPath, err = d.openEncryptedDataset(snapVol, dataset, volPath, !regenerateFSUUID)
+				if err != nil {
+					return nil, err
+				}
+
+				revert.Add(func() { _ = luksClose(d.luksMapperName(dataset)) })
+			}
+
 			tmpVolFsType := mountVol.ConfigBlockFilesystem()
 			mountOptions = addNoRecoveryMountOption(mountOptions, tmpVolFsType)
 
@@ -3287,6 +3491,13 @@ func (d *zfs) UnmountVolumeSnapshot(snapVol Volume, op *operations.Operation) (b
 			}
 
 			if exists {
+				if snapVol.IsEncrypted() {
+					err = luksClose(d.luksMapperName(dataset))
+					if err != nil {
+						return true, err
+					}
+				}
+
 				err = d.deleteDatasetRecursive(dataset)
 				if err != nil {
 					return true, err
@@ -3309,6 +3520,14 @@ func (d *zfs) UnmountVolumeSnapshot(snapVol Volume, op *operations.Operation) (b
 				return false, ErrInUse
 			}
 
+			// Close the decrypted device first as it keeps the snapshot zvol busy.
+			if snapVol.IsEncrypted() {
+				err := luksClose(d.luksMapperName(snapshotDataset))
+				if err != nil {
+					return false, err
+				}
+			}
+
 			err := d.setDatasetProperties(parentDataset, "snapdev=hidden")
 			if err != nil {
 				return false, err
@@ -3367,6 +3586,25 @@ func (d *zfs) RestoreVolume(vol Volume, snapVol Volume, op *operations.Operation
 	return d.restoreVolume(vol, snapVol, false, op)
 }
 
+// DiffVolume returns the paths that changed between a snapshot and a later snapshot or the volume itself using
+// `zfs diff`.
+func (d *zfs) DiffVolume(ctx context.Context, snapVol Volume, vol Volume, op *operations.Operation) ([]api.StorageVolumeDiff, error) {
+	// The file system of block backed volumes is unknown to ZFS.
+	if d.isBlockBacked(snapVol) {
+		return genericVFSDiffVolume(ctx, snapVol, vol, nil)
+	}
+
+	out, err := shared.RunCommand(ctx, "zfs", "diff", "-H", d.dataset(snapVol, false), d.dataset(vol, false))
+	if err != nil {
+		return nil, err
+	}
+
+	// The paths are reported below the mount path of the volume, even when comparing two snapshots.
+	parentName, _, _ := api.GetParentAndSnapshotName(snapVol.name)
+
+	return zfsDiffParse(out, GetVolumeMountPath(d.name, snapVol.volType, parentName)), nil
+}
+
 func (d *zfs) restoreVolume(vol Volume, snapVol Volume, migration bool, op *operations.Operation) error {
 	// Get the list of snapshots.
 	entries, err := d.getDatasets(d.dataset(vol, false), "snapshot")
@@ -1155,6 +1155,27 @@ func genericVFSCopyVolume(d Driver, initVolume func(vol Volume) (revert.Hook, er
 	return cleanup, nil
 }
 
+// genericVFSDiffVolume compares the files of a mounted snapshot with those of a later mounted snapshot or of the
+// mounted volume itself. The content of files with a different modification time is compared, restricted to the
+// paths in candidates if it isn't nil.
+func genericVFSDiffVolume(ctx context.Context, snapVol Volume, vol Volume, candidates map[string]struct{}) ([]api.StorageVolumeDiff, error) {
+	from, err := os.OpenRoot(snapVol.MountPath())
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = from.Close() }()
+
+	to, err := os.OpenRoot(vol.MountPath())
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = to.Close() }()
+
+	return diffDirectories(ctx, from, to, candidates)
+}
+
 // genericVFSListVolumes returns a list of LXD volumes in storage pool.
 func genericVFSListVolumes(d Driver) ([]Volume, error) {
 	var vols []Volume
@@ -1,6 +1,7 @@
 package drivers
 
 import (
+	"context"
 	"io"
 	"net/url"
 
@@ -48,6 +49,9 @@ type Driver interface {
 	// Unmount unmounts a storage pool if needed, returns true if unmounted, false if was not mounted.
 	Unmount() (bool, error)
 	GetResources() (*api.ResourcesStoragePool, error)
+	CheckHealth() (*api.StoragePoolHealth, error)
+	Scrub() error
+	AutoExtend() (bool, error)
 	Validate(config map[string]string) error
 	ValidateSource() error
 	Update(changedConfig map[string]string) error
@@ -105,6 +109,10 @@ type Driver interface {
 	CheckVolumeSnapshots(vol Volume, snapVols []Volume, op *operations.Operation) error
 	RestoreVolume(vol Volume, snapVol Volume, op *operations.Operation) error
 
+	// DiffVolume returns the paths that changed between a mounted snapshot and either a later mounted snapshot
+	// or the mounted volume itself. The comparison stops once ctx is done.
+	DiffVolume(ctx context.Context, snapVol Volume, vol Volume, op *operations.Operation) ([]api.StorageVolumeDiff, error)
+
 	// Migration.
 	MigrationTypes(contentType ContentType, refresh bool, copySnapshots bool) []migration.Type
 	MigrateVolume(vol VolumeCopy, conn io.ReadWriteCloser, volSrcArgs *migration.VolumeSourceArgs, op *operations.Operation) error
@@ -12,6 +12,8 @@ var drivers = map[string]func() driver{
 	"cephobject": func() driver { return &cephobject{} },
 	"dir":        func() driver { return &dir{} },
 	"lvm":        func() driver { return &lvm{} },
+	"lvmcluster": func() driver { return &lvm{clustered: true} },
+	"nfs":        func() driver { return &nfs{} },
 	"powerflex":  func() driver { return &powerflex{} },
 	"pure":       func() driver { return &pure{} },
 	"alletra":    func() driver { return &alletra{} },
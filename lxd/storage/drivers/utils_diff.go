@@ -0,0 +1,341 @@
+package drivers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+// btrfsFindNewMarkerRegex matches the current generation of a subvolume reported by `btrfs subvolume find-new`.
+var btrfsFindNewMarkerRegex = regexp.MustCompile(`transid marker was (\d+)`)
+
+// diffDirectories returns the paths that were added, modified or deleted in the to root compared to the from root.
+// Files whose size or metadata changed are always modified. Otherwise the content of files whose modification time
+// changed is compared, restricted to the paths in candidates if it isn't nil. The comparison stops with the error of
+// ctx once it is done.
+func diffDirectories(ctx context.Context, from *os.Root, to *os.Root, candidates map[string]struct{}) ([]api.StorageVolumeDiff, error) {
+	diffs := []api.StorageVolumeDiff{}
+
+	err := diffDirectory(ctx, from, to, ".", candidates, &diffs)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffs, nil
+}
+
+// diffDirectory compares the entries of a directory that exists in both roots.
+func diffDirectory(ctx context.Context, from *os.Root, to *os.Root, path string, candidates map[string]struct{}, diffs *[]api.StorageVolumeDiff) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	fromInfos, err := diffReadDir(from, path)
+	if err != nil {
+		return err
+	}
+
+	toInfos, err := diffReadDir(to, path)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(fromInfos)+len(toInfos))
+	for name := range fromInfos {
+		names = append(names, name)
+	}
+
+	for name := range toInfos {
+		_, ok := fromInfos[name]
+		if !ok {
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		entryPath := filepath.Join(path, name)
+		fromInfo, inFrom := fromInfos[name]
+		toInfo, inTo := toInfos[name]
+
+		if !inFrom {
+			err = diffTree(ctx, to, entryPath, toInfo, api.StorageVolumeDiffChangeAdded, diffs)
+		} else if !inTo {
+			err = diffTree(ctx, from, entryPath, fromInfo, api.StorageVolumeDiffChangeDeleted, diffs)
+		} else if fromInfo.Mode().Type() != toInfo.Mode().Type() {
+			err = diffTree(ctx, from, entryPath, fromInfo, api.StorageVolumeDiffChangeDeleted, diffs)
+			if err == nil {
+				err = diffTree(ctx, to, entryPath, toInfo, api.StorageVolumeDiffChangeAdded, diffs)
+			}
+		} else {
+			var changed bool
+			changed, err = diffEntryChanged(from, to, entryPath, fromInfo, toInfo, candidates)
+			if err == nil && changed {
+				*diffs = append(*diffs, api.StorageVolumeDiff{Path: "/" + entryPath, Change: api.StorageVolumeDiffChangeModified})
+			}
+
+			if err == nil && fromInfo.IsDir() {
+				err = diffDirectory(ctx, from, to, entryPath, candidates, diffs)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diffTree records a path and everything below it as added or deleted.
+func diffTree(ctx context.Context, root *os.Root, path string, info fs.FileInfo, change string, diffs *[]api.StorageVolumeDiff) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	*diffs = append(*diffs, api.StorageVolumeDiff{Path: "/" + path, Change: change})
+
+	if !info.IsDir() {
+		return nil
+	}
+
+	infos, err := diffReadDir(root, path)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(infos))
+	for name := range infos {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		err = diffTree(ctx, root, filepath.Join(path, name), infos[name], change, diffs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diffReadDir returns the information of the entries of a directory, skipping the ones that vanished.
+func diffReadDir(root *os.Root, path string) (map[string]fs.FileInfo, error) {
+	dir, err := root.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := dir.ReadDir(-1)
+	_ = dir.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make(map[string]fs.FileInfo, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		infos[entry.Name()] = info
+	}
+
+	return infos, nil
+}
+
+// diffEntryChanged reports whether an entry of the same type in both roots was modified.
+func diffEntryChanged(from *os.Root, to *os.Root, path string, fromInfo fs.FileInfo, toInfo fs.FileInfo, candidates map[string]struct{}) (bool, error) {
+	if fromInfo.Mode() != toInfo.Mode() {
+		return true, nil
+	}
+
+	fromStat, fromOK := fromInfo.Sys().(*syscall.Stat_t)
+	toStat, toOK := toInfo.Sys().(*syscall.Stat_t)
+	if fromOK && toOK && (fromStat.Uid != toStat.Uid || fromStat.Gid != toStat.Gid || fromStat.Rdev != toStat.Rdev) {
+		return true, nil
+	}
+
+	switch {
+	case fromInfo.Mode()&fs.ModeSymlink != 0:
+		fromTarget, err := from.Readlink(path)
+		if err != nil {
+			return false, err
+		}
+
+		toTarget, err := to.Readlink(path)
+		if err != nil {
+			return false, err
+		}
+
+		return fromTarget != toTarget, nil
+	case fromInfo.Mode().IsRegular():
+		if fromInfo.Size() != toInfo.Size() {
+			return true, nil
+		}
+
+		if fromInfo.ModTime().Equal(toInfo.ModTime()) {
+			return false, nil
+		}
+
+		if candidates != nil {
+			_, ok := candidates[path]
+			if !ok {
+				return false, nil
+			}
+		}
+
+		fromHash, err := diffFileHash(from, path)
+		if err != nil {
+			return false, err
+		}
+
+		toHash, err := diffFileHash(to, path)
+		if err != nil {
+			return false, err
+		}
+
+		return !bytes.Equal(fromHash, toHash), nil
+	}
+
+	return false, nil
+}
+
+// diffFileHash returns the SHA256 hash of the content of a file.
+func diffFileHash(root *os.Root, path string) ([]byte, error) {
+	file, err := root.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = file.Close() }()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return nil, fmt.Errorf("Failed hashing %q: %w", path, err)
+	}
+
+	return hash.Sum(nil), nil
+}
+
+// zfsDiffParse returns the changed paths from the output of `zfs diff -H`, relative to the mount path of the dataset.
+// Renamed paths are reported as deleted from their old path and added to their new one.
+func zfsDiffParse(output string, mountPath string) []api.StorageVolumeDiff {
+	changes := map[string]string{
+		"+": api.StorageVolumeDiffChangeAdded,
+		"-": api.StorageVolumeDiffChangeDeleted,
+		"M": api.StorageVolumeDiffChangeModified,
+	}
+
+	diffs := []api.StorageVolumeDiff{}
+	for line := range strings.SplitSeq(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+
+		if fields[0] == "R" && len(fields) >= 3 {
+			diffs = append(diffs,
+				api.StorageVolumeDiff{Path: zfsDiffPath(fields[1], mountPath), Change: api.StorageVolumeDiffChangeDeleted},
+				api.StorageVolumeDiff{Path: zfsDiffPath(fields[2], mountPath), Change: api.StorageVolumeDiffChangeAdded},
+			)
+
+			continue
+		}
+
+		change, ok := changes[fields[0]]
+		if ok {
+			diffs = append(diffs, api.StorageVolumeDiff{Path: zfsDiffPath(fields[1], mountPath), Change: change})
+		}
+	}
+
+	return diffs
+}
+
+// zfsDiffPath returns a path reported by `zfs diff` relative to the mount path of the dataset.
+// ZFS escapes spaces and special characters as a backslash followed by their octal value.
+func zfsDiffPath(path string, mountPath string) string {
+	var unescaped strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+5 <= len(path) {
+			value, err := strconv.ParseUint(path[i+1:i+5], 8, 8)
+			if err == nil {
+				unescaped.WriteByte(byte(value))
+				i += 4
+				continue
+			}
+		}
+
+		unescaped.WriteByte(path[i])
+	}
+
+	path = unescaped.String()
+	if path == mountPath {
+		return "/"
+	}
+
+	relPath, ok := strings.CutPrefix(path, mountPath+"/")
+	if ok {
+		return "/" + relPath
+	}
+
+	return path
+}
+
+// btrfsFindNewMarker returns the current generation of a subvolume from the output of `btrfs subvolume find-new`.
+func btrfsFindNewMarker(output string) (uint64, error) {
+	match := btrfsFindNewMarkerRegex.FindStringSubmatch(output)
+	if match == nil {
+		return 0, errors.New("Failed finding the generation of the subvolume")
+	}
+
+	return strconv.ParseUint(match[1], 10, 64)
+}
+
+// btrfsFindNewPaths returns the paths of the files whose content was written since a generation from the output of
+// `btrfs subvolume find-new`, relative to the subvolume.
+func btrfsFindNewPaths(output string) map[string]struct{} {
+	paths := map[string]struct{}{}
+	for line := range strings.SplitSeq(output, "\n") {
+		if !strings.HasPrefix(line, "inode ") {
+			continue
+		}
+
+		// The path follows the flags of the extent and may contain spaces.
+		_, flagsAndPath, ok := strings.Cut(line, " flags ")
+		if !ok {
+			continue
+		}
+
+		_, path, ok := strings.Cut(flagsAndPath, " ")
+		if ok && path != "" {
+			paths[path] = struct{}{}
+		}
+	}
+
+	return paths
+}
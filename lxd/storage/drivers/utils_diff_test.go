@@ -0,0 +1,108 @@
+package drivers
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+func TestDiffDirectories(t *testing.T) {
+	fromDir := t.TempDir()
+	toDir := t.TempDir()
+
+	for _, dir := range []string{fromDir, toDir} {
+		require.NoError(t, os.MkdirAll(dir+"/etc/app", 0755))
+		require.NoError(t, os.WriteFile(dir+"/etc/hosts", []byte("127.0.0.1 localhost"), 0644))
+		require.NoError(t, os.WriteFile(dir+"/etc/touched", []byte("same"), 0644))
+		require.NoError(t, os.WriteFile(dir+"/etc/app/config", []byte("a=1"), 0644))
+	}
+
+	require.NoError(t, os.WriteFile(fromDir+"/etc/old", []byte("old"), 0644))
+	require.NoError(t, os.Symlink("hosts", fromDir+"/etc/link"))
+	require.NoError(t, os.WriteFile(fromDir+"/etc/type", []byte("file"), 0644))
+
+	// Same size and content but a different modification time.
+	require.NoError(t, os.Chtimes(toDir+"/etc/touched", time.Now(), time.Now().Add(time.Hour)))
+
+	// Same size but a different content.
+	require.NoError(t, os.WriteFile(toDir+"/etc/app/config", []byte("a=2"), 0644))
+	require.NoError(t, os.Chtimes(toDir+"/etc/app/config", time.Now(), time.Now().Add(time.Hour)))
+
+	require.NoError(t, os.Chmod(toDir+"/etc/hosts", 0600))
+	require.NoError(t, os.MkdirAll(toDir+"/srv/data", 0755))
+	require.NoError(t, os.WriteFile(toDir+"/srv/data/file", []byte("new"), 0644))
+	require.NoError(t, os.Symlink("app", toDir+"/etc/link"))
+	require.NoError(t, os.MkdirAll(toDir+"/etc/type", 0755))
+
+	from, err := os.OpenRoot(fromDir)
+	require.NoError(t, err)
+	defer func() { _ = from.Close() }()
+
+	to, err := os.OpenRoot(toDir)
+	require.NoError(t, err)
+	defer func() { _ = to.Close() }()
+
+	diffs, err := diffDirectories(context.Background(), from, to, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []api.StorageVolumeDiff{
+		{Path: "/etc/app/config", Change: api.StorageVolumeDiffChangeModified},
+		{Path: "/etc/hosts", Change: api.StorageVolumeDiffChangeModified},
+		{Path: "/etc/link", Change: api.StorageVolumeDiffChangeModified},
+		{Path: "/etc/old", Change: api.StorageVolumeDiffChangeDeleted},
+		{Path: "/etc/type", Change: api.StorageVolumeDiffChangeDeleted},
+		{Path: "/etc/type", Change: api.StorageVolumeDiffChangeAdded},
+		{Path: "/srv", Change: api.StorageVolumeDiffChangeAdded},
+		{Path: "/srv/data", Change: api.StorageVolumeDiffChangeAdded},
+		{Path: "/srv/data/file", Change: api.StorageVolumeDiffChangeAdded},
+	}, diffs)
+
+	// Only the candidates have their content compared.
+	diffs, err = diffDirectories(context.Background(), from, to, map[string]struct{}{})
+	require.NoError(t, err)
+	assert.NotContains(t, diffs, api.StorageVolumeDiff{Path: "/etc/app/config", Change: api.StorageVolumeDiffChangeModified})
+
+	// The comparison stops once the context is canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = diffDirectories(ctx, from, to, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestZFSDiffParse(t *testing.T) {
+	output := "M\t/var/lib/lxd/storage-pools/default/custom/default_data/\n" +
+		"+\t/var/lib/lxd/storage-pools/default/custom/default_data/new\\0040file\n" +
+		"-\t/var/lib/lxd/storage-pools/default/custom/default_data/old\n" +
+		"R\t/var/lib/lxd/storage-pools/default/custom/default_data/a\t/var/lib/lxd/storage-pools/default/custom/default_data/b\n"
+
+	assert.Equal(t, []api.StorageVolumeDiff{
+		{Path: "/", Change: api.StorageVolumeDiffChangeModified},
+		{Path: "/new file", Change: api.StorageVolumeDiffChangeAdded},
+		{Path: "/old", Change: api.StorageVolumeDiffChangeDeleted},
+		{Path: "/a", Change: api.StorageVolumeDiffChangeDeleted},
+		{Path: "/b", Change: api.StorageVolumeDiffChangeAdded},
+	}, zfsDiffParse(output, "/var/lib/lxd/storage-pools/default/custom/default_data"))
+
+	assert.Equal(t, "/", zfsDiffPath("/mnt/data", "/mnt/data"))
+}
+
+func TestBtrfsFindNew(t *testing.T) {
+	output := `inode 257 file offset 0 len 4096 disk start 13631488 offset 0 gen 9 flags NONE etc/hosts
+inode 258 file offset 0 len 12 disk start 0 offset 0 gen 10 flags INLINE srv/my file
+transid marker was 11
+`
+
+	marker, err := btrfsFindNewMarker(output)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(11), marker)
+
+	assert.Equal(t, map[string]struct{}{"etc/hosts": {}, "srv/my file": {}}, btrfsFindNewPaths(output))
+
+	_, err = btrfsFindNewMarker("")
+	assert.Error(t, err)
+}
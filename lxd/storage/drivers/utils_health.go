@@ -0,0 +1,184 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/validate"
+)
+
+// poolUsageDegradedPercent is the usage of a pool or thin pool above which it is reported as degraded.
+const poolUsageDegradedPercent = 90
+
+// validateScrubSchedule validates the `scrub.schedule` pool option of drivers that support scrubs.
+var validateScrubSchedule = validate.Optional(validate.IsCron([]string{"@hourly", "@daily", "@midnight", "@weekly", "@monthly", "@annually", "@yearly"}))
+
+// zfsScanErrorsRegex matches the number of errors reported by a completed ZFS scrub.
+var zfsScanErrorsRegex = regexp.MustCompile(`with (\d+) errors`)
+
+// poolHealth returns the health of a pool from the results of its checks.
+// The pool is degraded if any check is degraded and unknown if any check could not be completed.
+func poolHealth(checks []api.StoragePoolHealthCheck) *api.StoragePoolHealth {
+	status := api.StoragePoolHealthStatusHealthy
+	for _, check := range checks {
+		if check.Status == api.StoragePoolHealthStatusDegraded {
+			status = api.StoragePoolHealthStatusDegraded
+			break
+		}
+
+		if check.Status == api.StoragePoolHealthStatusUnknown {
+			status = api.StoragePoolHealthStatusUnknown
+		}
+	}
+
+	return &api.StoragePoolHealth{Status: status, Checks: checks}
+}
+
+// healthCheck returns the result of a check which is healthy if ok is true and degraded otherwise.
+func healthCheck(name string, ok bool, message string) api.StoragePoolHealthCheck {
+	status := api.StoragePoolHealthStatusHealthy
+	if !ok {
+		status = api.StoragePoolHealthStatusDegraded
+	}
+
+	return api.StoragePoolHealthCheck{Name: name, Status: status, Message: message}
+}
+
+// healthCheckFailed returns the result of a check that could not be completed.
+func healthCheckFailed(name string, err error) api.StoragePoolHealthCheck {
+	return api.StoragePoolHealthCheck{Name: name, Status: api.StoragePoolHealthStatusUnknown, Message: err.Error()}
+}
+
+// zfsScrubHealthCheck returns the scrub and data error checks from the output of `zpool status`.
+func zfsScrubHealthCheck(status string) []api.StoragePoolHealthCheck {
+	scan := "No scrub has been run"
+	scanOK := true
+	dataErrors := ""
+
+	for line := range strings.SplitSeq(status, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "scan":
+			if value == "none requested" {
+				continue
+			}
+
+			scan = value
+
+			match := zfsScanErrorsRegex.FindStringSubmatch(value)
+			if match != nil {
+				count, err := strconv.Atoi(match[1])
+				scanOK = err == nil && count == 0
+			}
+
+		case "errors":
+			dataErrors = value
+		}
+	}
+
+	checks := []api.StoragePoolHealthCheck{healthCheck("scrub", scanOK, scan)}
+	if dataErrors != "" {
+		checks = append(checks, healthCheck("data-errors", dataErrors == "No known data errors", dataErrors))
+	}
+
+	return checks
+}
+
+// btrfsDeviceStatsErrors returns the non-zero error counters from the output of `btrfs device stats`.
+func btrfsDeviceStatsErrors(stats string) []string {
+	var counters []string
+	for line := range strings.SplitSeq(stats, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[1] == "0" {
+			continue
+		}
+
+		counters = append(counters, fields[0]+"="+fields[1])
+	}
+
+	return counters
+}
+
+// btrfsScrubHealthCheck returns the scrub check from the output of `btrfs scrub status`.
+func btrfsScrubHealthCheck(status string) api.StoragePoolHealthCheck {
+	for line := range strings.SplitSeq(status, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found || key != "Error summary" {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+
+		return healthCheck("scrub", value == "no errors found", value)
+	}
+
+	return healthCheck("scrub", true, "No scrub has been run")
+}
+
+// lvmThinpoolUsage returns the data and metadata usage percentages of a thin pool from the output of `lvs`.
+func lvmThinpoolUsage(usage string) (dataPerc float64, metaPerc float64, err error) {
+	parts := shared.SplitNTrimSpace(usage, ",", -1, true)
+	if len(parts) < 2 {
+		return -1, -1, errors.New("Unexpected output from lvs command")
+	}
+
+	dataPerc, err = strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return -1, -1, fmt.Errorf("Failed parsing thin pool data usage (%q): %w", parts[0], err)
+	}
+
+	metaPerc, err = strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return -1, -1, fmt.Errorf("Failed parsing thin pool metadata usage (%q): %w", parts[1], err)
+	}
+
+	return dataPerc, metaPerc, nil
+}
+
+// lvmThinpoolHealthCheck returns the thin pool usage check from the data and metadata percentages reported by `lvs`.
+func lvmThinpoolHealthCheck(usage string) api.StoragePoolHealthCheck {
+	dataPerc, metaPerc, err := lvmThinpoolUsage(usage)
+	if err != nil {
+		return healthCheckFailed("thinpool-usage", err)
+	}
+
+	message := fmt.Sprintf("Data %.2f%%, metadata %.2f%%", dataPerc, metaPerc)
+
+	return healthCheck("thinpool-usage", dataPerc < poolUsageDegradedPercent && metaPerc < poolUsageDegradedPercent, message)
+}
+
+// zfsCapacityHealthCheck returns the capacity check from the output of `zpool list -o capacity`.
+func zfsCapacityHealthCheck(capacity string) api.StoragePoolHealthCheck {
+	value := strings.TrimSpace(capacity)
+
+	perc, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return healthCheckFailed("capacity", fmt.Errorf("Failed parsing zpool capacity (%q): %w", value, err))
+	}
+
+	return healthCheck("capacity", perc < poolUsageDegradedPercent, value+" used")
+}
+
+// cephHealthCheck returns the health of the Ceph cluster as reported by `ceph health`.
+func cephHealthCheck(ctx context.Context, clusterName string, userName string) api.StoragePoolHealthCheck {
+	out, err := shared.RunCommand(ctx, "ceph", "--name", "client."+userName, "--cluster", clusterName, "health")
+	if err != nil {
+		return healthCheckFailed("cluster-health", fmt.Errorf("Failed getting Ceph cluster health: %w", err))
+	}
+
+	health := strings.TrimSpace(out)
+
+	return healthCheck("cluster-health", strings.HasPrefix(health, "HEALTH_OK"), health)
+}
@@ -0,0 +1,129 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+func TestPoolHealth(t *testing.T) {
+	healthy := api.StoragePoolHealthCheck{Name: "a", Status: api.StoragePoolHealthStatusHealthy}
+	degraded := api.StoragePoolHealthCheck{Name: "b", Status: api.StoragePoolHealthStatusDegraded}
+	unknown := api.StoragePoolHealthCheck{Name: "c", Status: api.StoragePoolHealthStatusUnknown}
+
+	assert.Equal(t, api.StoragePoolHealthStatusHealthy, poolHealth([]api.StoragePoolHealthCheck{healthy}).Status)
+	assert.Equal(t, api.StoragePoolHealthStatusUnknown, poolHealth([]api.StoragePoolHealthCheck{healthy, unknown}).Status)
+	assert.Equal(t, api.StoragePoolHealthStatusDegraded, poolHealth([]api.StoragePoolHealthCheck{unknown, degraded, healthy}).Status)
+}
+
+func TestZFSScrubHealthCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		expected []api.StoragePoolHealthCheck
+	}{
+		{
+			name: "Never scrubbed",
+			status: `  pool: default
+ state: ONLINE
+  scan: none requested
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	default     ONLINE       0     0     0
+	  sda       ONLINE       0     0     0
+
+errors: No known data errors
+`,
+			expected: []api.StoragePoolHealthCheck{
+				{Name: "scrub", Status: api.StoragePoolHealthStatusHealthy, Message: "No scrub has been run"},
+				{Name: "data-errors", Status: api.StoragePoolHealthStatusHealthy, Message: "No known data errors"},
+			},
+		},
+		{
+			name: "Scrub with errors",
+			status: `  pool: default
+ state: ONLINE
+  scan: scrub repaired 0B in 00:00:01 with 2 errors on Sun Oct 18 12:00:00 2026
+config:
+
+errors: 2 data errors, use '-v' for a list
+`,
+			expected: []api.StoragePoolHealthCheck{
+				{Name: "scrub", Status: api.StoragePoolHealthStatusDegraded, Message: "scrub repaired 0B in 00:00:01 with 2 errors on Sun Oct 18 12:00:00 2026"},
+				{Name: "data-errors", Status: api.StoragePoolHealthStatusDegraded, Message: "2 data errors, use '-v' for a list"},
+			},
+		},
+		{
+			name: "Scrub without errors",
+			status: `  scan: scrub repaired 0B in 00:00:01 with 0 errors on Sun Oct 18 12:00:00 2026
+errors: No known data errors
+`,
+			expected: []api.StoragePoolHealthCheck{
+				{Name: "scrub", Status: api.StoragePoolHealthStatusHealthy, Message: "scrub repaired 0B in 00:00:01 with 0 errors on Sun Oct 18 12:00:00 2026"},
+				{Name: "data-errors", Status: api.StoragePoolHealthStatusHealthy, Message: "No known data errors"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, zfsScrubHealthCheck(tt.status))
+		})
+	}
+}
+
+func TestBtrfsHealthChecks(t *testing.T) {
+	stats := `[/dev/sda].write_io_errs    0
+[/dev/sda].read_io_errs     3
+[/dev/sda].flush_io_errs    0
+[/dev/sda].corruption_errs  1
+[/dev/sda].generation_errs  0
+`
+
+	assert.Equal(t, []string{"[/dev/sda].read_io_errs=3", "[/dev/sda].corruption_errs=1"}, btrfsDeviceStatsErrors(stats))
+	assert.Empty(t, btrfsDeviceStatsErrors("[/dev/sda].write_io_errs    0\n"))
+
+	scrubOK := `UUID:             8a4c0c6e-5d6b-4c43-9f7f-3b5c1c3c1f0a
+Scrub started:    Sun Oct 18 12:00:00 2026
+Status:           finished
+Duration:         0:00:01
+Total to scrub:   1.00GiB
+Rate:             1.00GiB/s
+Error summary:    no errors found
+`
+	assert.Equal(t, api.StoragePoolHealthStatusHealthy, btrfsScrubHealthCheck(scrubOK).Status)
+
+	scrubErrors := `Status:           finished
+Error summary:    csum=2
+  Corrected:      0
+  Uncorrectable:  2
+  Unverified:     0
+`
+	check := btrfsScrubHealthCheck(scrubErrors)
+	assert.Equal(t, api.StoragePoolHealthStatusDegraded, check.Status)
+	assert.Equal(t, "csum=2", check.Message)
+
+	assert.Equal(t, api.StoragePoolHealthStatusHealthy, btrfsScrubHealthCheck("UUID: 8a4c0c6e\n\tno stats available\n").Status)
+}
+
+func TestLVMThinpoolHealthCheck(t *testing.T) {
+	check := lvmThinpoolHealthCheck("  45.00,12.50\n")
+	assert.Equal(t, api.StoragePoolHealthStatusHealthy, check.Status)
+	assert.Equal(t, "Data 45.00%, metadata 12.50%", check.Message)
+
+	assert.Equal(t, api.StoragePoolHealthStatusDegraded, lvmThinpoolHealthCheck("45.00,95.10").Status)
+	assert.Equal(t, api.StoragePoolHealthStatusDegraded, lvmThinpoolHealthCheck("90.00,1.00").Status)
+	assert.Equal(t, api.StoragePoolHealthStatusUnknown, lvmThinpoolHealthCheck("").Status)
+}
+
+func TestZFSCapacityHealthCheck(t *testing.T) {
+	check := zfsCapacityHealthCheck("45%\n")
+	assert.Equal(t, api.StoragePoolHealthStatusHealthy, check.Status)
+	assert.Equal(t, "45% used", check.Message)
+
+	assert.Equal(t, api.StoragePoolHealthStatusDegraded, zfsCapacityHealthCheck("93%").Status)
+	assert.Equal(t, api.StoragePoolHealthStatusUnknown, zfsCapacityHealthCheck("-").Status)
+}
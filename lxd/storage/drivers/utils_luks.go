@@ -0,0 +1,342 @@
+package drivers
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// luksHeaderSize is the space reserved at the start of encrypted volumes for the LUKS2 header.
+// It is added on top of the volume size so that the decrypted device has the requested size.
+const luksHeaderSize = 16 * 1024 * 1024
+
+// luksKeySize is the size in bytes of the randomly generated volume keys.
+const luksKeySize = 64
+
+// luksSealedPrefix is the prefix of volume keys sealed with the key-encryption key.
+const luksSealedPrefix = "sealed:"
+
+// luksMapperPrefix is the prefix of the device mapper devices of opened encrypted volumes.
+const luksMapperPrefix = "lxd-luks-"
+
+// luksReadKEK reads the key-encryption key file and derives the key used to seal volume keys.
+func luksReadKEK(kekPath string) ([]byte, error) {
+	content, err := os.ReadFile(kekPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed reading key-encryption key: %w", err)
+	}
+
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, fmt.Errorf("Key-encryption key file %q is empty", kekPath)
+	}
+
+	kek := sha256.Sum256(content)
+
+	return kek[:], nil
+}
+
+// luksCipher returns the AEAD cipher used to seal volume keys with the key-encryption key.
+func luksCipher(kekPath string) (cipher.AEAD, error) {
+	kek, err := luksReadKEK(kekPath)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// NewEncryptionKey generates a random volume key and returns it in the form stored in the volume config.
+// If kekPath is set, the key is sealed with the key-encryption key read from it.
+func NewEncryptionKey(kekPath string) (string, error) {
+	key := make([]byte, luksKeySize)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", fmt.Errorf("Failed generating volume key: %w", err)
+	}
+
+	if kekPath == "" {
+		return base64.StdEncoding.EncodeToString(key), nil
+	}
+
+	aead, err := luksCipher(kekPath)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", fmt.Errorf("Failed generating nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, key, nil)
+
+	return luksSealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// luksUnsealKey returns the volume key from its representation in the volume config.
+func luksUnsealKey(value string, kekPath string) ([]byte, error) {
+	if value == "" {
+		return nil, errors.New("Encrypted volume is missing its key")
+	}
+
+	encodedSealed, isSealed := strings.CutPrefix(value, luksSealedPrefix)
+	if !isSealed {
+		return base64.StdEncoding.DecodeString(value)
+	}
+
+	if kekPath == "" {
+		return nil, errors.New("Volume key is sealed but no key-encryption key is configured")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encodedSealed)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := luksCipher(kekPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("Invalid sealed volume key")
+	}
+
+	key, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed unsealing volume key: %w", err)
+	}
+
+	return key, nil
+}
+
+// luksVolumeKey returns the key of an encrypted volume.
+func (d *common) luksVolumeKey(vol Volume) ([]byte, error) {
+	kekPath := ""
+	if d.state != nil && d.state.LocalConfig != nil {
+		kekPath = d.state.LocalConfig.StorageEncryptionKeyFile()
+	}
+
+	return luksUnsealKey(vol.config["volatile.encryption.key"], kekPath)
+}
+
+// luksMapperName returns the device mapper name for the encrypted device devName on the pool.
+func luksMapperName(poolName string, devName string) string {
+	return luksMapperPrefix + poolName + "-" + strings.ReplaceAll(devName, "/", "-")
+}
+
+// luksMapperPath returns the path of the decrypted device for the given mapper name.
+func luksMapperPath(mapperName string) string {
+	return "/dev/mapper/" + mapperName
+}
+
+// luksFormat initializes a LUKS2 header on the device.
+func luksFormat(devPath string, key []byte) error {
+	// Use 512 bytes encryption sectors as cryptsetup may otherwise pick 4096 bytes ones, which the decrypted
+	// device would expose as its logical block size while VM images expect 512 bytes sectors.
+	err := shared.RunCommandWithFds(context.TODO(), bytes.NewReader(key), nil, "cryptsetup", "luksFormat", "--batch-mode", "--type", "luks2", "--sector-size", "512", "--offset", strconv.Itoa(luksHeaderSize/512), "--key-file", "-", devPath)
+	if err != nil {
+		return fmt.Errorf("Failed formatting encrypted device %q: %w", devPath, err)
+	}
+
+	return nil
+}
+
+// luksOpen opens the encrypted device if not already opened and returns the path of the decrypted device.
+// Read-only devices, such as snapshots exposed by some drivers, must be opened with readOnly set.
+func luksOpen(devPath string, mapperName string, key []byte, readOnly bool) (string, error) {
+	mapperPath := luksMapperPath(mapperName)
+	if shared.PathExists(mapperPath) {
+		return mapperPath, nil
+	}
+
+	args := []string{"open", "--type", "luks2", "--key-file", "-"}
+	if readOnly {
+		args = append(args, "--readonly")
+	}
+
+	args = append(args, devPath, mapperName)
+
+	err := shared.RunCommandWithFds(context.TODO(), bytes.NewReader(key), nil, "cryptsetup", args...)
+	if err != nil {
+		return "", fmt.Errorf("Failed opening encrypted device %q: %w", devPath, err)
+	}
+
+	return mapperPath, nil
+}
+
+// luksClose closes the decrypted device if opened.
+func luksClose(mapperName string) error {
+	if !shared.PathExists(luksMapperPath(mapperName)) {
+		return nil
+	}
+
+	// Keep trying to close a few times in case the device is still being flushed.
+	var err error
+	for i := range 20 {
+		_, err = shared.RunCommand(context.TODO(), "cryptsetup", "close", mapperName)
+		if err == nil {
+			return nil
+		}
+
+		logger.Debug("Failed closing encrypted device", logger.Ctx{"name": mapperName, "attempt": i, "err": err})
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	return fmt.Errorf("Failed closing encrypted device %q: %w", mapperName, err)
+}
+
+// luksResize resizes the decrypted device to the size of its underlying device if opened.
+func luksResize(mapperName string, key []byte) error {
+	if !shared.PathExists(luksMapperPath(mapperName)) {
+		return nil
+	}
+
+	err := shared.RunCommandWithFds(context.TODO(), bytes.NewReader(key), nil, "cryptsetup", "resize", "--key-file", "-", mapperName)
+	if err != nil {
+		return fmt.Errorf("Failed resizing encrypted device %q: %w", mapperName, err)
+	}
+
+	return nil
+}
+
+// luksBlockFileMapperName returns the device mapper name of the decrypted device of an encrypted volume block file.
+func luksBlockFileMapperName(vol Volume) string {
+	return luksMapperName(vol.pool, string(vol.volType)+"-"+vol.name)
+}
+
+// luksIsBlockFile returns true if the volume is an encrypted volume stored in a block file.
+func luksIsBlockFile(vol Volume) bool {
+	return vol.contentType == ContentTypeBlock && vol.IsEncrypted()
+}
+
+// luksCreateBlockFile creates the block file of a new encrypted volume and initializes its encryption header.
+// The file holds sizeBytes of data on top of the header.
+func (d *common) luksCreateBlockFile(vol Volume, sizeBytes int64) error {
+	key, err := d.luksVolumeKey(vol)
+	if err != nil {
+		return err
+	}
+
+	rootBlockPath, err := genericVFSGetVolumeDiskPath(vol)
+	if err != nil {
+		return err
+	}
+
+	_, err = ensureVolumeBlockFile(vol, rootBlockPath, sizeBytes+luksHeaderSize, false)
+	if err != nil {
+		return err
+	}
+
+	return luksFormat(rootBlockPath, key)
+}
+
+// luksOpenBlockFile opens the decrypted device of an encrypted volume block file on top of a loop device, if not
+// already opened. Returns the path of the decrypted device.
+func (d *common) luksOpenBlockFile(vol Volume) (string, error) {
+	mapperName := luksBlockFileMapperName(vol)
+	if shared.PathExists(luksMapperPath(mapperName)) {
+		return luksMapperPath(mapperName), nil
+	}
+
+	key, err := d.luksVolumeKey(vol)
+	if err != nil {
+		return "", err
+	}
+
+	rootBlockPath, err := genericVFSGetVolumeDiskPath(vol)
+	if err != nil {
+		return "", err
+	}
+
+	loopDevPath, err := loopDeviceSetup(rootBlockPath)
+	if err != nil {
+		return "", fmt.Errorf("Failed setting up loop device for %q: %w", rootBlockPath, err)
+	}
+
+	// Have the loop device detached once the decrypted device on top of it is closed.
+	defer func() { _ = loopDeviceAutoDetach(loopDevPath) }()
+
+	mapperPath, err := luksOpen(loopDevPath, mapperName, key, vol.IsSnapshot())
+	if err != nil {
+		return "", err
+	}
+
+	d.logger.Debug("Opened encrypted volume block file", logger.Ctx{"volName": vol.name, "dev": loopDevPath, "path": mapperPath})
+	return mapperPath, nil
+}
+
+// luksCloseBlockFile closes the decrypted device of an encrypted volume block file if opened.
+// The loop device underneath is detached automatically.
+func luksCloseBlockFile(vol Volume) error {
+	return luksClose(luksBlockFileMapperName(vol))
+}
+
+// luksResizeBlockFile grows the block file of an encrypted volume so that it holds sizeBytes of data on top of the
+// encryption header, and resizes its decrypted device if opened. Returns true if the file was resized.
+// Encrypted block files are never shrunk as their decrypted device may still be using the space.
+func (d *common) luksResizeBlockFile(vol Volume, sizeBytes int64, allowUnsafeResize bool, unsupportedResizeTypes ...VolumeType) (bool, error) {
+	rootBlockPath, err := genericVFSGetVolumeDiskPath(vol)
+	if err != nil {
+		return false, err
+	}
+
+	fi, err := os.Stat(rootBlockPath)
+	if err != nil {
+		return false, err
+	}
+
+	if d.roundVolumeBlockSizeBytes(vol, sizeBytes+luksHeaderSize) < fi.Size() {
+		return false, fmt.Errorf("Encrypted block volumes cannot be shrunk: %w", ErrCannotBeShrunk)
+	}
+
+	resized, err := ensureVolumeBlockFile(vol, rootBlockPath, sizeBytes+luksHeaderSize, allowUnsafeResize, unsupportedResizeTypes...)
+	if err != nil || !resized {
+		return resized, err
+	}
+
+	mapperName := luksBlockFileMapperName(vol)
+	if !shared.PathExists(luksMapperPath(mapperName)) {
+		return true, nil
+	}
+
+	// Make the loop device under the decrypted device pick up the new size of the file.
+	// The loop device already attached to the file is returned as overlapping devices aren't set up.
+	loopDevPath, err := loopDeviceSetup(rootBlockPath)
+	if err != nil {
+		return true, fmt.Errorf("Failed finding loop device of %q: %w", rootBlockPath, err)
+	}
+
+	defer func() { _ = loopDeviceAutoDetach(loopDevPath) }()
+
+	err = loopDeviceSetCapacity(loopDevPath)
+	if err != nil {
+		return true, fmt.Errorf("Failed resizing loop device %q: %w", loopDevPath, err)
+	}
+
+	key, err := d.luksVolumeKey(vol)
+	if err != nil {
+		return true, err
+	}
+
+	return true, luksResize(mapperName, key)
+}
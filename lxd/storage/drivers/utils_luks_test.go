@@ -0,0 +1,71 @@
+package drivers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test NewEncryptionKey and luksUnsealKey.
+func TestEncryptionKey(t *testing.T) {
+	dir := t.TempDir()
+
+	kekPath := filepath.Join(dir, "kek")
+	err := os.WriteFile(kekPath, []byte("key-encryption key"), 0600)
+	require.NoError(t, err)
+
+	otherKEKPath := filepath.Join(dir, "other-kek")
+	err = os.WriteFile(otherKEKPath, []byte("another key-encryption key"), 0600)
+	require.NoError(t, err)
+
+	emptyKEKPath := filepath.Join(dir, "empty-kek")
+	err = os.WriteFile(emptyKEKPath, []byte("\n"), 0600)
+	require.NoError(t, err)
+
+	// Unsealed keys.
+	value, err := NewEncryptionKey("")
+	require.NoError(t, err)
+	assert.False(t, strings.HasPrefix(value, luksSealedPrefix))
+
+	key, err := luksUnsealKey(value, "")
+	require.NoError(t, err)
+	assert.Len(t, key, luksKeySize)
+
+	// Keys are random.
+	otherValue, err := NewEncryptionKey("")
+	require.NoError(t, err)
+	assert.NotEqual(t, value, otherValue)
+
+	// Sealed keys.
+	value, err = NewEncryptionKey(kekPath)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(value, luksSealedPrefix))
+
+	key, err = luksUnsealKey(value, kekPath)
+	require.NoError(t, err)
+	assert.Len(t, key, luksKeySize)
+
+	_, err = luksUnsealKey(value, "")
+	assert.Error(t, err)
+
+	_, err = luksUnsealKey(value, otherKEKPath)
+	assert.Error(t, err)
+
+	// Invalid key-encryption keys.
+	_, err = NewEncryptionKey(emptyKEKPath)
+	assert.Error(t, err)
+
+	_, err = NewEncryptionKey(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+
+	// Missing and invalid keys.
+	_, err = luksUnsealKey("", "")
+	assert.Error(t, err)
+
+	_, err = luksUnsealKey(luksSealedPrefix+"AAAA", kekPath)
+	assert.Error(t, err)
+}
@@ -401,6 +401,11 @@ func (v Volume) IsCustomBlock() bool {
 	return (v.volType == VolumeTypeCustom && v.contentType == ContentTypeBlock)
 }
 
+// supportsInodeQuota returns true if an inode quota (size.inodes) can be applied to the volume.
+func (v Volume) supportsInodeQuota() bool {
+	return (v.volType == VolumeTypeContainer || v.volType == VolumeTypeCustom) && v.contentType == ContentTypeFS && !v.IsBlockBacked()
+}
+
 // NewVMBlockFilesystemVolume returns a copy of the volume with the content type set to ContentTypeFS and the
 // config "size" property set to "size.state" or DefaultVMBlockFilesystemSize if not set.
 func (v Volume) NewVMBlockFilesystemVolume() Volume {
@@ -479,6 +484,12 @@ func (v Volume) ConfigBlockMountOptions() string {
 	return defaultFilesystemMountOptions
 }
 
+// IsEncrypted returns true if the volume's block device is encrypted.
+// Only the volume's own config is checked as the encryption of existing volumes cannot change.
+func (v Volume) IsEncrypted() bool {
+	return v.config["block.encryption"] != ""
+}
+
 // ConfigSize returns the size to use when creating new a volume. Returns config value "size" if defined in volume
 // or pool's volume config, otherwise for block volumes and block-backed volumes the defaultBlockSize. For other
 // volumes an empty string is returned if no size is defined.
@@ -0,0 +1,145 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// CopyFromRoot copies the file, symlink or directory tree at path in the src root to the same path in the dst
+// root, replacing what is there and creating its missing parent directories. Paths are resolved within the
+// roots, so symlinks cannot make the copy read or write outside of them. The ownership of the copied files is
+// passed through shift if it isn't nil. Device nodes, sockets and named pipes are skipped.
+func CopyFromRoot(src *os.Root, dst *os.Root, path string, shift func(uid int64, gid int64) (int64, int64)) error {
+	// Make the path relative to the roots.
+	path = strings.TrimPrefix(filepath.Clean("/"+path), "/")
+	if path == "" {
+		path = "."
+	}
+
+	parent := filepath.Dir(path)
+	if parent != "." {
+		err := dst.MkdirAll(parent, 0755)
+		if err != nil {
+			return fmt.Errorf("Failed creating parent directory %q: %w", parent, err)
+		}
+	}
+
+	return copyFromRoot(src, dst, path, shift)
+}
+
+func copyFromRoot(src *os.Root, dst *os.Root, path string, shift func(uid int64, gid int64) (int64, int64)) error {
+	info, err := src.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	mode := info.Mode()
+	if mode&(fs.ModeDevice|fs.ModeCharDevice|fs.ModeSocket|fs.ModeNamedPipe) != 0 {
+		return nil
+	}
+
+	uid, gid := int64(-1), int64(-1)
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if ok {
+		uid, gid = int64(stat.Uid), int64(stat.Gid)
+		if shift != nil {
+			uid, gid = shift(uid, gid)
+		}
+	}
+
+	// Replace what is at the destination unless both are directories, in which case they are merged.
+	dstInfo, err := dst.Lstat(path)
+	if err == nil && (!dstInfo.IsDir() || !info.IsDir()) {
+		err = dst.RemoveAll(path)
+		if err != nil {
+			return fmt.Errorf("Failed removing %q: %w", path, err)
+		}
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	switch {
+	case mode&fs.ModeSymlink != 0:
+		target, err := src.Readlink(path)
+		if err != nil {
+			return err
+		}
+
+		err = dst.Symlink(target, path)
+		if err != nil {
+			return fmt.Errorf("Failed creating symlink %q: %w", path, err)
+		}
+
+		// Symlinks don't have their own permissions and changing their times would follow them.
+		return dst.Lchown(path, int(uid), int(gid))
+	case mode.IsDir():
+		err = dst.Mkdir(path, mode.Perm())
+		if err != nil && !errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("Failed creating directory %q: %w", path, err)
+		}
+
+		dir, err := src.Open(path)
+		if err != nil {
+			return err
+		}
+
+		names, err := dir.Readdirnames(-1)
+		_ = dir.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			err = copyFromRoot(src, dst, filepath.Join(path, name), shift)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		err = copyFileFromRoot(src, dst, path, mode.Perm())
+		if err != nil {
+			return err
+		}
+	}
+
+	// Set the ownership first as changing it clears the setuid and setgid bits.
+	err = dst.Lchown(path, int(uid), int(gid))
+	if err != nil {
+		return err
+	}
+
+	err = dst.Chmod(path, mode&(fs.ModePerm|fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky))
+	if err != nil {
+		return err
+	}
+
+	return dst.Chtimes(path, info.ModTime(), info.ModTime())
+}
+
+func copyFileFromRoot(src *os.Root, dst *os.Root, path string, perm fs.FileMode) error {
+	in, err := src.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = in.Close() }()
+
+	out, err := dst.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return fmt.Errorf("Failed creating file %q: %w", path, err)
+	}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		_ = out.Close()
+		return fmt.Errorf("Failed copying file %q: %w", path, err)
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,85 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openRoots(t *testing.T) (*os.Root, *os.Root) {
+	t.Helper()
+
+	src, err := os.OpenRoot(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = src.Close() })
+
+	dst, err := os.OpenRoot(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = dst.Close() })
+
+	return src, dst
+}
+
+func TestCopyFromRoot(t *testing.T) {
+	src, dst := openRoots(t)
+
+	require.NoError(t, src.MkdirAll("etc/app", 0755))
+	require.NoError(t, src.WriteFile("etc/app/config", []byte("restored"), 0600))
+	require.NoError(t, src.Symlink("config", "etc/app/link"))
+	require.NoError(t, src.WriteFile("etc/other", []byte("other"), 0644))
+
+	// Existing files are replaced and unrelated ones are kept.
+	require.NoError(t, dst.MkdirAll("etc/app", 0755))
+	require.NoError(t, dst.WriteFile("etc/app/config", []byte("current"), 0644))
+	require.NoError(t, dst.WriteFile("etc/app/new", []byte("new"), 0644))
+
+	require.NoError(t, CopyFromRoot(src, dst, "/etc/app", nil))
+
+	content, err := dst.ReadFile("etc/app/config")
+	require.NoError(t, err)
+	assert.Equal(t, "restored", string(content))
+
+	info, err := dst.Stat("etc/app/config")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	target, err := dst.Readlink("etc/app/link")
+	require.NoError(t, err)
+	assert.Equal(t, "config", target)
+
+	_, err = dst.Stat("etc/app/new")
+	assert.NoError(t, err)
+
+	_, err = dst.Stat("etc/other")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// Missing parent directories are created.
+	require.NoError(t, CopyFromRoot(src, dst, "etc/other", nil))
+	content, err = dst.ReadFile("etc/other")
+	require.NoError(t, err)
+	assert.Equal(t, "other", string(content))
+}
+
+func TestCopyFromRootEscape(t *testing.T) {
+	src, dst := openRoots(t)
+	outside := t.TempDir()
+
+	// A symlink in the source pointing outside of it is copied as is and never followed.
+	require.NoError(t, src.Symlink(outside, "escape"))
+	require.NoError(t, CopyFromRoot(src, dst, "escape", nil))
+	target, err := dst.Readlink("escape")
+	require.NoError(t, err)
+	assert.Equal(t, outside, target)
+
+	// Restoring below a symlink of the destination pointing outside of it fails.
+	require.NoError(t, src.MkdirAll("data", 0755))
+	require.NoError(t, src.WriteFile("data/file", []byte("data"), 0644))
+	require.NoError(t, dst.Symlink(outside, "data"))
+	assert.Error(t, CopyFromRoot(src, dst, "data/file", nil))
+
+	_, err = os.Stat(filepath.Join(outside, "file"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
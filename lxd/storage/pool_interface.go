@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"io"
 	"net/url"
 	"os"
@@ -53,6 +54,9 @@ type Pool interface {
 	ToAPI() api.StoragePool
 
 	GetResources() (*api.ResourcesStoragePool, error)
+	CheckHealth() (*api.StoragePoolHealth, error)
+	Scrub() error
+	AutoExtend() (bool, error)
 	IsUsed() (bool, error)
 	Delete(clientType request.ClientType, op *operations.Operation) error
 	Update(clientType request.ClientType, newDesc string, newConfig map[string]string, op *operations.Operation) error
@@ -74,6 +78,8 @@ type Pool interface {
 	CreateInstanceFromConversion(inst instance.Instance, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) error
 	RenameInstance(inst instance.Instance, newName string, op *operations.Operation) error
 	DeleteInstance(inst instance.Instance, op *operations.Operation) error
+	MoveInstanceLive(inst instance.Instance, mirror func(diskPath string) error, op *operations.Operation) error
+	DeleteInstanceLiveMoveSource(inst instance.Instance, preDelete func(mountPath string) error, op *operations.Operation) error
 	UpdateInstance(inst instance.Instance, newDesc string, newConfig map[string]string, op *operations.Operation) error
 	UpdateInstanceBackupFile(inst instance.Instance, snapshots bool, volBackupConf *backupConfig.Config, version uint32, op *operations.Operation) error
 	GenerateInstanceBackupConfig(inst instance.Instance, snapshots bool, volBackupConf *backupConfig.Config, op *operations.Operation) (*backupConfig.Config, error)
@@ -137,6 +143,11 @@ type Pool interface {
 	DeleteCustomVolumeSnapshot(projectName string, volName string, op *operations.Operation) error
 	UpdateCustomVolumeSnapshot(projectName string, volName string, newDesc string, newConfig map[string]string, newExpiryDate time.Time, op *operations.Operation) error
 	RestoreCustomVolume(projectName string, volName string, snapshotName string, op *operations.Operation) error
+	MountCustomVolumeSnapshot(projectName string, volName string, op *operations.Operation) (*MountInfo, error)
+	UnmountCustomVolumeSnapshot(projectName string, volName string, op *operations.Operation) (bool, error)
+
+	// Volume diffs.
+	DiffVolume(ctx context.Context, projectName string, volType drivers.VolumeType, volName string, fromSnapshot string, toSnapshot string, op *operations.Operation) ([]api.StorageVolumeDiff, error)
 
 	// Custom volume migration.
 	MigrationTypes(contentType drivers.ContentType, refresh bool, copySnapshots bool) []migration.Type
@@ -99,6 +99,30 @@ int quota_set(char *dev_path, uint32_t id, uint64_t hard_bytes) {
 	return 0;
 }
 
+int quota_set_inodes(char *dev_path, uint32_t id, uint64_t hard_inodes) {
+	struct if_dqblk quota;
+	fs_disk_quota_t xfsquota;
+
+	if (quotactl(QCMD(Q_GETQUOTA, PRJQUOTA), dev_path, id, (caddr_t)&quota) < 0) {
+		return -1;
+	}
+
+	quota.dqb_ihardlimit = hard_inodes;
+	if (quotactl(QCMD(Q_SETQUOTA, PRJQUOTA), dev_path, id, (caddr_t)&quota) < 0) {
+		xfsquota.d_version = FS_DQUOT_VERSION;
+		xfsquota.d_id = id;
+		xfsquota.d_flags = FS_PROJ_QUOTA;
+		xfsquota.d_fieldmask = FS_DQ_IHARD;
+		xfsquota.d_ino_hardlimit = hard_inodes;
+
+		if (quotactl(QCMD(Q_XSETQLIM, PRJQUOTA), dev_path, id, (caddr_t)&xfsquota) < 0) {
+			return -1;
+		}
+	}
+
+	return 0;
+}
+
 int quota_set_path(char *path, uint32_t id, bool inherit) {
 	struct fsxattr attr;
 	int fd;
@@ -286,6 +310,12 @@ func DeleteProject(path string, id uint32) error {
 		return err
 	}
 
+	// Unset the inode quota on the project.
+	err = SetProjectInodeQuota(path, id, 0)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -327,3 +357,22 @@ func SetProjectQuota(path string, id uint32, bytes int64) error {
 
 	return nil
 }
+
+// SetProjectInodeQuota sets the inode quota on the project ID.
+func SetProjectInodeQuota(path string, id uint32, inodes int64) error {
+	// Get the backing device.
+	devPath, err := devForPath(path)
+	if err != nil {
+		return err
+	}
+
+	// Call quotactl through CGo.
+	cDevPath := C.CString(devPath)
+	defer C.free(unsafe.Pointer(cDevPath))
+
+	if C.quota_set_inodes(cDevPath, C.uint32_t(id), C.uint64_t(inodes)) != 0 {
+		return fmt.Errorf(`Failed setting project inode quota for ID "%d" on %q`, id, devPath)
+	}
+
+	return nil
+}
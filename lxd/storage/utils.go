@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"slices"
@@ -197,6 +198,43 @@ func VolumeDBGet(pool Pool, projectName string, volumeName string, volumeType dr
 	return dbVolume, nil
 }
 
+// volumeConfigSecrets are the volume config keys holding secrets which are only used by LXD.
+var volumeConfigSecrets = []string{"volatile.encryption.key"}
+
+// VolumeConfigWithoutSecrets returns a copy of the volume config without the secrets, to be returned by the API.
+func VolumeConfigWithoutSecrets(config map[string]string) map[string]string {
+	if config == nil {
+		return nil
+	}
+
+	result := make(map[string]string, len(config))
+	for k, v := range config {
+		if !slices.Contains(volumeConfigSecrets, k) {
+			result[k] = v
+		}
+	}
+
+	return result
+}
+
+// VolumeConfigKeepSecrets sets the secrets of the current volume config into the updated volume config received
+// through the API, as the API never returns them. Returns an error if the update tries to change a secret.
+func VolumeConfigKeepSecrets(newConfig map[string]string, curConfig map[string]string) error {
+	for _, key := range volumeConfigSecrets {
+		value, ok := newConfig[key]
+		if ok && value != curConfig[key] {
+			return api.StatusErrorf(http.StatusBadRequest, "Storage volume %q property cannot be changed", key)
+		}
+
+		value, ok = curConfig[key]
+		if ok {
+			newConfig[key] = value
+		}
+	}
+
+	return nil
+}
+
 // VolumeDBCreate creates a volume in the database.
 // If volumeConfig is supplied, it is modified with any driver level default config options (if not set).
 // If removeUnknownKeys is true, any unknown config keys are removed from volumeConfig rather than failing.
@@ -250,6 +288,19 @@ func VolumeDBCreate(pool Pool, projectName string, volumeName string, volumeDesc
 		return err
 	}
 
+	// Generate the key of new encrypted volumes.
+	if vol.IsEncrypted() && vol.Config()["volatile.encryption.key"] == "" {
+		kekPath := ""
+		if p.state.LocalConfig != nil {
+			kekPath = p.state.LocalConfig.StorageEncryptionKeyFile()
+		}
+
+		vol.Config()["volatile.encryption.key"], err = drivers.NewEncryptionKey(kekPath)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Validate config.
 	err = pool.Driver().ValidateVolume(vol, removeUnknownKeys)
 	if err != nil {
@@ -409,7 +460,7 @@ func poolAndVolumeCommonRules(vol *drivers.Volume) map[string]func(string) error
 		//  shortdesc: Size of the storage pool (for loop-based pools)
 		//  scope: local
 
-		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs; group=volume-conf; key=size)
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs; group=volume-conf; key=size)
 		//
 		// ---
 		//  type: string
@@ -425,7 +476,7 @@ func poolAndVolumeCommonRules(vol *drivers.Volume) map[string]func(string) error
 		//  shortdesc: Quota of the storage bucket
 		//  scope: local
 		"size": validate.Optional(validate.IsSize),
-		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=snapshots.expiry)
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=snapshots.expiry)
 		// Specify an expression like `1M 2H 3d 4w 5m 6y`.
 		// ---
 		//  type: string
@@ -438,7 +489,7 @@ func poolAndVolumeCommonRules(vol *drivers.Volume) map[string]func(string) error
 			_, err := shared.GetExpiry(time.Time{}, value)
 			return err
 		},
-		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=snapshots.schedule)
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=snapshots.schedule)
 		// Specify either a cron expression (`<minute> <hour> <dom> <month> <dow>`), a comma-separated list of schedule aliases (`@hourly`, `@daily`, `@midnight`, `@weekly`, `@monthly`, `@annually`, `@yearly`), or leave empty to disable automatic snapshots (the default).
 		// ---
 		//  type: string
@@ -447,7 +498,7 @@ func poolAndVolumeCommonRules(vol *drivers.Volume) map[string]func(string) error
 		//  shortdesc: Schedule for automatic volume snapshots
 		//  scope: global
 		"snapshots.schedule": validate.Optional(validate.IsCron([]string{"@hourly", "@daily", "@midnight", "@weekly", "@monthly", "@annually", "@yearly"})),
-		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=snapshots.pattern)
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=snapshots.pattern)
 		// You can specify a naming template that is used for scheduled snapshots and unnamed snapshots.
 		//
 		// {{snapshot_pattern_detail}}
@@ -458,11 +509,21 @@ func poolAndVolumeCommonRules(vol *drivers.Volume) map[string]func(string) error
 		//  shortdesc: Template for the snapshot name
 		//  scope: global
 		"snapshots.pattern": validate.IsAny,
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=warning_threshold)
+		// When the usage of a volume reaches this percentage of its size, LXD raises a warning.
+		// Volumes without a size limit are compared to their usage plus the free space of the storage pool.
+		// Leave empty to disable the warning (the default).
+		// ---
+		//  type: integer
+		//  defaultdesc: same as `volume.warning_threshold`
+		//  shortdesc: Volume usage in percent that raises a warning
+		//  scope: global
+		"warning_threshold": validate.Optional(validate.IsInRange(1, 100)),
 	}
 
 	// security.shifted and security.unmapped are only relevant for custom filesystem volumes.
 	if vol == nil || (vol.Type() == drivers.VolumeTypeCustom && vol.ContentType() == drivers.ContentTypeFS) {
-		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=security.shifted)
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=security.shifted)
 		// Enabling this option allows attaching the volume to multiple isolated instances.
 		// ---
 		//  type: bool
@@ -471,7 +532,7 @@ func poolAndVolumeCommonRules(vol *drivers.Volume) map[string]func(string) error
 		//  shortdesc: Enable ID shifting overlay
 		//  scope: global
 		rules["security.shifted"] = validate.Optional(validate.IsBool)
-		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=security.unmapped)
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=security.unmapped)
 		//
 		// ---
 		//  type: bool
@@ -484,7 +545,7 @@ func poolAndVolumeCommonRules(vol *drivers.Volume) map[string]func(string) error
 
 	// security.shared guards virtual-machine and custom block volumes.
 	if vol == nil || ((vol.Type() == drivers.VolumeTypeCustom || vol.Type() == drivers.VolumeTypeVM) && vol.ContentType() == drivers.ContentTypeBlock) {
-		// lxdmeta:generate(entities=storage-btrfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=security.shared)
+		// lxdmeta:generate(entities=storage-btrfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=security.shared)
 		// Enabling this option allows sharing the volume across multiple instances despite the possibility of data loss.
 		//
 		// ---
@@ -498,7 +559,7 @@ func poolAndVolumeCommonRules(vol *drivers.Volume) map[string]func(string) error
 
 	// Those keys are only valid for volumes.
 	if vol != nil {
-		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=volatile.uuid)
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=volatile.uuid)
 		//
 		// ---
 		//  type: string
@@ -507,7 +568,7 @@ func poolAndVolumeCommonRules(vol *drivers.Volume) map[string]func(string) error
 		//  scope: global
 		rules["volatile.uuid"] = validate.Optional(validate.IsUUID)
 
-		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=volatile.devlxd.owner)
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=volatile.devlxd.owner)
 		//
 		// ---
 		//  type: string
@@ -517,6 +578,73 @@ func poolAndVolumeCommonRules(vol *drivers.Volume) map[string]func(string) error
 		rules["volatile.devlxd.owner"] = validate.Optional(validate.IsUUID)
 	}
 
+	// Replication is only supported for custom volumes.
+	if vol != nil && vol.Type() == drivers.VolumeTypeCustom {
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=replication.target_pool)
+		// Setting this option enables the replication of the volume to a replica in the given storage pool.
+		// See {ref}`storage-volume-replication`.
+		// ---
+		//  type: string
+		//  condition: custom volume
+		//  shortdesc: Storage pool to replicate the volume to
+		//  scope: global
+		rules["replication.target_pool"] = validate.IsAny
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=replication.target_volume)
+		//
+		// ---
+		//  type: string
+		//  condition: custom volume
+		//  defaultdesc: same as the volume name
+		//  shortdesc: Name of the replica volume
+		//  scope: global
+		rules["replication.target_volume"] = validate.IsAny
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=replication.target_remote)
+		// Set this option to the HTTPS address of a remote LXD server or cluster (for example, `https://10.0.0.2:8443`) to create the replica there instead of on this server or cluster.
+		// The remote must trust the certificate of this server or cluster, and its own certificate must match `replication.target_remote_fingerprint`.
+		// ---
+		//  type: string
+		//  condition: custom volume
+		//  shortdesc: Remote server to replicate the volume to
+		//  scope: global
+		rules["replication.target_remote"] = validate.Optional(validate.IsHTTPSURL)
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=replication.target_remote_fingerprint)
+		// This option is required if `replication.target_remote` is set.
+		// ---
+		//  type: string
+		//  condition: custom volume
+		//  shortdesc: SHA-256 fingerprint of the remote server certificate
+		//  scope: global
+		rules["replication.target_remote_fingerprint"] = validate.Optional(validate.IsCertificateFingerprint)
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=replication.schedule)
+		// Specify either a cron expression (`<minute> <hour> <dom> <month> <dow>`), a comma-separated list of schedule aliases (`@hourly`, `@daily`, `@midnight`, `@weekly`, `@monthly`, `@annually`, `@yearly`), or leave empty to only synchronize the replica on demand (the default).
+		// ---
+		//  type: string
+		//  condition: custom volume
+		//  shortdesc: Schedule for synchronizing the replica
+		//  scope: global
+		rules["replication.schedule"] = validate.Optional(validate.IsCron([]string{"@hourly", "@daily", "@midnight", "@weekly", "@monthly", "@annually", "@yearly"}))
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=volatile.replication.source)
+		// This option is set on replica volumes to the `<pool>/<volume>` source they are synchronized from.
+		// If the source is on a remote server, it is prefixed with the certificate fingerprint of that server (`<fingerprint>:<pool>/<volume>`).
+		// ---
+		//  type: string
+		//  condition: custom volume
+		//  shortdesc: Source of the replica volume
+		//  scope: global
+		rules["volatile.replication.source"] = validate.IsAny
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=volatile.replication.last_sync)
+		//
+		// ---
+		//  type: string
+		//  condition: custom volume
+		//  shortdesc: Time of the last successful replica synchronization
+		//  scope: global
+		rules["volatile.replication.last_sync"] = validate.Optional(func(value string) error {
+			_, err := time.Parse(time.RFC3339, value)
+			return err
+		})
+	}
+
 	return rules
 }
 
@@ -532,7 +660,7 @@ func validatePoolCommonRules() map[string]func(string) error {
 		//  shortdesc: Whether to wipe the block device before creating the pool
 		//  scope: local
 		"source.wipe": validate.Optional(validate.IsBool),
-		// lxdmeta:generate(entities=storage-dir,storage-lvm,storage-btrfs,storage-zfs,storage-ceph,storage-cephfs; group=pool-conf; key=source.recover)
+		// lxdmeta:generate(entities=storage-dir,storage-nfs,storage-lvm,storage-btrfs,storage-zfs,storage-ceph,storage-cephfs; group=pool-conf; key=source.recover)
 		// Set this option to true to recover an existing source which was previously created by LXD.
 		// ---
 		//  type: bool
@@ -541,7 +669,7 @@ func validatePoolCommonRules() map[string]func(string) error {
 		//  scope: local
 		"source.recover":          validate.Optional(validate.IsBool),
 		"volatile.initial_source": validate.IsAny,
-		// lxdmeta:generate(entities=storage-dir,storage-lvm,storage-powerflex,storage-pure,storage-alletra; group=pool-conf; key=rsync.bwlimit)
+		// lxdmeta:generate(entities=storage-dir,storage-nfs,storage-lvm,storage-powerflex,storage-pure,storage-alletra; group=pool-conf; key=rsync.bwlimit)
 		// When `rsync` must be used to transfer storage entities, this option specifies the upper limit
 		// to be placed on the socket I/O.
 		// ---
@@ -550,7 +678,7 @@ func validatePoolCommonRules() map[string]func(string) error {
 		//  shortdesc: Upper limit on the socket I/O for `rsync`
 		//  scope: global
 		"rsync.bwlimit": validate.Optional(validate.IsSize),
-		// lxdmeta:generate(entities=storage-dir,storage-lvm,storage-powerflex,storage-pure,storage-alletra; group=pool-conf; key=rsync.compression)
+		// lxdmeta:generate(entities=storage-dir,storage-nfs,storage-lvm,storage-powerflex,storage-pure,storage-alletra; group=pool-conf; key=rsync.compression)
 		//
 		// ---
 		//  type: bool
@@ -558,6 +686,15 @@ func validatePoolCommonRules() map[string]func(string) error {
 		//  shortdesc: Whether to use compression while migrating storage pools
 		//  scope: global
 		"rsync.compression": validate.Optional(validate.IsBool),
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=pool-conf; key=warning_threshold)
+		// When the usage of the storage pool reaches this percentage of its space, LXD raises a warning.
+		// Leave empty to disable the warning (the default).
+		// See {ref}`storage-usage-warnings`.
+		// ---
+		//  type: integer
+		//  shortdesc: Pool usage in percent that raises a warning
+		//  scope: global
+		"warning_threshold": validate.Optional(validate.IsInRange(1, 100)),
 	}
 
 	// Add to pool config rules (prefixed with volume.*) which are common for pool and volume.
@@ -608,14 +745,14 @@ func validateLocalPoolCommonRules() map[string]func(string) error {
 func validateVolumeCommonRules(vol drivers.Volume) map[string]func(string) error {
 	rules := poolAndVolumeCommonRules(&vol)
 
-	// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=volatile.idmap.last)
+	// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=volatile.idmap.last)
 	//
 	// ---
 	//   type: string
 	//   shortdesc: JSON-serialized UID/GID map that has been applied to the volume
 	//   condition: filesystem
 
-	// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=volatile.idmap.next)
+	// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-nfs,storage-lvm,storage-zfs,storage-powerflex,storage-pure,storage-alletra; group=volume-conf; key=volatile.idmap.next)
 	//
 	// ---
 	//   type: string
@@ -779,7 +916,10 @@ func ImageUnpack(s *state.State, projectName string, imageFile string, vol drive
 			cmd = append(cmd, "-W")
 
 			// Our block devices are clean, so skip zeroes.
-			cmd = append(cmd, "-n", "--target-is-zero")
+			// Encrypted devices don't read back as zeroes so those must be written.
+			if !vol.IsEncrypted() {
+				cmd = append(cmd, "-n", "--target-is-zero")
+			}
 		}
 
 		cmd = append(cmd, imgPath, dstPath)
@@ -1179,6 +1319,22 @@ func FallbackMigrationType(contentType drivers.ContentType) migration.MigrationF
 	return migration.MigrationFSType_RSYNC
 }
 
+// CustomVolumeMigrationTypes returns the migration types the pool offers when sending a custom volume.
+// Encrypted volumes only offer the generic migration type. Their data is then read from the decrypted device and
+// encrypted again on the target with the key of the target volume, as custom volumes don't take the source key along.
+func CustomVolumeMigrationTypes(pool Pool, contentType drivers.ContentType, volConfig map[string]string, refresh bool, copySnapshots bool) []migration.Type {
+	types := pool.MigrationTypes(contentType, refresh, copySnapshots)
+	if volConfig["block.encryption"] == "" {
+		return types
+	}
+
+	fallbackType := FallbackMigrationType(contentType)
+
+	return slices.DeleteFunc(types, func(t migration.Type) bool {
+		return t.FSType != fallbackType
+	})
+}
+
 // RenderSnapshotUsage can be used as an optional argument to Instance.Render() to return snapshot usage.
 // As this is a relatively expensive operation it is provided as an optional feature rather than on by default.
 func RenderSnapshotUsage(s *state.State, snapInst instance.Instance) func(response any) error {
@@ -1265,6 +1421,34 @@ func InstanceDiskBlockSize(pool Pool, inst instance.Instance, op *operations.Ope
 	return blockDiskSize, nil
 }
 
+// InstanceCheckLiveMigrationPools checks that the root disk and custom volumes of the instance are on storage
+// pools that can be used from two cluster members at once during a live migration.
+// Logical volumes of lvmcluster pools are activated exclusively on one cluster member, so they can't be used by
+// the source and the destination member at the same time.
+func InstanceCheckLiveMigrationPools(s *state.State, rootPool Pool, inst instance.Instance) error {
+	poolNames := []string{rootPool.Name()}
+	for _, dev := range inst.ExpandedDevices().Filter(filters.IsCustomVolumeDisk).Sorted() {
+		poolNames = append(poolNames, dev.Config["pool"])
+	}
+
+	for _, poolName := range poolNames {
+		pool := rootPool
+		if poolName != rootPool.Name() {
+			var err error
+			pool, err = LoadByName(s, poolName)
+			if err != nil {
+				return fmt.Errorf("Failed loading storage pool %q: %w", poolName, err)
+			}
+		}
+
+		if pool.Driver().Info().Name == "lvmcluster" {
+			return api.StatusErrorf(http.StatusBadRequest, "Live migration isn't supported for virtual machines using volumes of the %q storage pool (%q driver), stop the instance first", poolName, "lvmcluster")
+		}
+	}
+
+	return nil
+}
+
 // ComparableSnapshot is used when comparing snapshots on different pools to see whether they differ.
 type ComparableSnapshot struct {
 	// Name of the snapshot (without the parent name).
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/lxd/lxd/auth"
+	"github.com/canonical/lxd/lxd/db"
+	"github.com/canonical/lxd/lxd/db/warningtype"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/state"
+	storagePools "github.com/canonical/lxd/lxd/storage"
+	storageDrivers "github.com/canonical/lxd/lxd/storage/drivers"
+	"github.com/canonical/lxd/lxd/task"
+	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/lxd/warnings"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+var storagePoolHealthCmd = APIEndpoint{
+	Path:        "storage-pools/{poolName}/health",
+	MetricsType: entity.TypeStoragePool,
+
+	Get:  APIEndpointAction{Handler: storagePoolHealthGet, AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanViewResources)},
+	Post: APIEndpointAction{Handler: storagePoolHealthPost, AccessHandler: allowPermission(entity.TypeStoragePool, auth.EntitlementCanEdit, "poolName")},
+}
+
+// swagger:operation GET /1.0/storage-pools/{poolName}/health storage storage_pool_health_get
+//
+//	Get the storage pool health
+//
+//	Runs the driver specific health checks of the storage pool on the cluster member.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: target
+//	    description: Cluster member name
+//	    type: string
+//	    example: lxd01
+//	responses:
+//	  "200":
+//	    description: Storage pool health
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/StoragePoolHealth"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+//	  "501":
+//	    $ref: "#/responses/NotImplemented"
+func storagePoolHealthGet(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	// If a target was specified, forward the request to the relevant node.
+	target := request.QueryParam(r, "target")
+	resp := forwardedResponseToNode(r.Context(), s, target)
+	if resp != nil {
+		return resp
+	}
+
+	poolName, err := url.PathUnescape(mux.Vars(r)["poolName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	pool, err := storagePools.LoadByName(s, poolName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	health, err := pool.CheckHealth()
+	if err != nil {
+		if errors.Is(err, storageDrivers.ErrNotSupported) {
+			return response.NotImplemented(fmt.Errorf("Storage pool driver %q does not support health checks", pool.Driver().Info().Name))
+		}
+
+		return response.SmartError(err)
+	}
+
+	storagePoolHealthUpdateWarning(s, pool, health)
+
+	return response.SyncResponse(true, health)
+}
+
+// swagger:operation POST /1.0/storage-pools/{poolName}/health storage storage_pool_health_post
+//
+//	Run a storage pool health action
+//
+//	Runs an action against the storage pool on the cluster member.
+//	The only supported action is `scrub`, which starts verifying the integrity of the pool data in the background.
+//	Its progress and outcome are reported by the storage pool health.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: target
+//	    description: Cluster member name
+//	    type: string
+//	    example: lxd01
+//	  - in: body
+//	    name: action
+//	    description: Action to run
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/StoragePoolHealthPost"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+//	  "501":
+//	    $ref: "#/responses/NotImplemented"
+func storagePoolHealthPost(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	// If a target was specified, forward the request to the relevant node.
+	target := request.QueryParam(r, "target")
+	resp := forwardedResponseToNode(r.Context(), s, target)
+	if resp != nil {
+		return resp
+	}
+
+	poolName, err := url.PathUnescape(mux.Vars(r)["poolName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := api.StoragePoolHealthPost{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Action != "scrub" {
+		return response.BadRequest(fmt.Errorf("Unknown action %q", req.Action))
+	}
+
+	pool, err := storagePools.LoadByName(s, poolName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = pool.Scrub()
+	if err != nil {
+		if errors.Is(err, storageDrivers.ErrNotSupported) {
+			return response.NotImplemented(fmt.Errorf("Storage pool driver %q does not support scrubbing", pool.Driver().Info().Name))
+		}
+
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// storagePoolHealthUpdateWarning raises a warning on the local member if the pool is degraded and resolves it once
+// the pool is healthy again.
+func storagePoolHealthUpdateWarning(s *state.State, pool storagePools.Pool, health *api.StoragePoolHealth) {
+	switch health.Status {
+	case api.StoragePoolHealthStatusDegraded:
+		var failures []string
+		for _, check := range health.Checks {
+			if check.Status == api.StoragePoolHealthStatusDegraded {
+				failures = append(failures, check.Name+": "+check.Message)
+			}
+		}
+
+		err := s.DB.Cluster.Transaction(s.ShutdownCtx, func(ctx context.Context, tx *db.ClusterTx) error {
+			return tx.UpsertWarningLocalNode(ctx, "", entity.TypeStoragePool, int(pool.ID()), warningtype.StoragePoolDegraded, strings.Join(failures, "; "))
+		})
+		if err != nil {
+			logger.Warn("Failed raising storage pool degraded warning", logger.Ctx{"pool": pool.Name(), "err": err})
+		}
+
+	case api.StoragePoolHealthStatusHealthy:
+		err := warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(s.DB.Cluster, "", warningtype.StoragePoolDegraded, entity.TypeStoragePool, int(pool.ID()))
+		if err != nil {
+			logger.Warn("Failed resolving storage pool degraded warning", logger.Ctx{"pool": pool.Name(), "err": err})
+		}
+	}
+}
+
+// storagePoolsLoadCreated loads the storage pools which are created.
+func storagePoolsLoadCreated(ctx context.Context, s *state.State) ([]storagePools.Pool, error) {
+	var poolNames []string
+
+	err := s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+
+		poolNames, err = tx.GetCreatedStoragePoolNames(ctx)
+
+		return err
+	})
+	if err != nil && !response.IsNotFoundError(err) {
+		return nil, fmt.Errorf("Failed loading storage pools: %w", err)
+	}
+
+	pools := make([]storagePools.Pool, 0, len(poolNames))
+	for _, poolName := range poolNames {
+		pool, err := storagePools.LoadByName(s, poolName)
+		if err != nil {
+			return nil, fmt.Errorf("Failed loading storage pool %q: %w", poolName, err)
+		}
+
+		pools = append(pools, pool)
+	}
+
+	return pools, nil
+}
+
+func storagePoolsHealthCheckTask(stateFunc func() *state.State) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		s := stateFunc()
+
+		pools, err := storagePoolsLoadCreated(ctx, s)
+		if err != nil {
+			logger.Error("Failed loading storage pools for health checks", logger.Ctx{"err": err})
+			return
+		}
+
+		for _, pool := range pools {
+			if pool.LocalStatus() == api.StoragePoolStatusUnvailable {
+				continue
+			}
+
+			health, err := pool.CheckHealth()
+			if err != nil {
+				if !errors.Is(err, storageDrivers.ErrNotSupported) {
+					logger.Warn("Failed checking storage pool health", logger.Ctx{"pool": pool.Name(), "err": err})
+				}
+
+				continue
+			}
+
+			storagePoolHealthUpdateWarning(s, pool, health)
+		}
+	}
+
+	return f, task.Hourly(task.SkipFirst)
+}
+
+func autoScrubStoragePoolsTask(stateFunc func() *state.State) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		s := stateFunc()
+
+		pools, err := storagePoolsLoadCreated(ctx, s)
+		if err != nil {
+			logger.Error("Failed loading storage pools for scheduled scrubs", logger.Ctx{"err": err})
+			return
+		}
+
+		var onlineMemberIDs []int64
+		for _, pool := range pools {
+			schedule := pool.Driver().Config()["scrub.schedule"]
+			if schedule == "" || !snapshotIsScheduledNow(schedule, pool.ID()) {
+				continue
+			}
+
+			if pool.LocalStatus() == api.StoragePoolStatusUnvailable {
+				continue
+			}
+
+			// Remote pools are shared by all members, so a stable random online member is chosen to scrub them.
+			if pool.Driver().Info().Remote && s.ServerClustered {
+				if onlineMemberIDs == nil {
+					err := s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+						members, err := tx.GetNodes(ctx)
+						if err != nil {
+							return fmt.Errorf("Failed getting cluster members: %w", err)
+						}
+
+						for _, member := range members {
+							if !member.IsOffline(s.GlobalConfig.OfflineThreshold()) {
+								onlineMemberIDs = append(onlineMemberIDs, member.ID)
+							}
+						}
+
+						return nil
+					})
+					if err != nil {
+						logger.Error("Failed scheduling storage pool scrub", logger.Ctx{"pool": pool.Name(), "err": err})
+						continue
+					}
+				}
+
+				selectedMemberID, err := util.GetStableRandomInt64FromList(pool.ID(), onlineMemberIDs)
+				if err != nil {
+					logger.Error("Failed scheduling storage pool scrub", logger.Ctx{"pool": pool.Name(), "err": err})
+					continue
+				}
+
+				if selectedMemberID != s.DB.Cluster.GetNodeID() {
+					continue
+				}
+			}
+
+			logger.Info("Starting scheduled storage pool scrub", logger.Ctx{"pool": pool.Name()})
+			err := pool.Scrub()
+			if err != nil {
+				logger.Error("Failed starting scheduled storage pool scrub", logger.Ctx{"pool": pool.Name(), "err": err})
+			}
+		}
+	}
+
+	return f, task.Every(time.Minute, task.SkipFirst)
+}
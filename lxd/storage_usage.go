@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db"
+	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/db/warningtype"
+	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/lifecycle"
+	"github.com/canonical/lxd/lxd/state"
+	storagePools "github.com/canonical/lxd/lxd/storage"
+	storageDrivers "github.com/canonical/lxd/lxd/storage/drivers"
+	"github.com/canonical/lxd/lxd/task"
+	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/lxd/warnings"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/units"
+)
+
+// storagePoolAutoExtend extends the pool if it is above its automatic extension threshold and raises a warning on
+// the local member if it could not be extended.
+func storagePoolAutoExtend(s *state.State, pool storagePools.Pool) {
+	extended, err := pool.AutoExtend()
+	if err != nil {
+		if errors.Is(err, storageDrivers.ErrNotSupported) {
+			return
+		}
+
+		logger.Warn("Failed extending storage pool", logger.Ctx{"pool": pool.Name(), "err": err})
+
+		message := err.Error()
+		err = s.DB.Cluster.Transaction(s.ShutdownCtx, func(ctx context.Context, tx *db.ClusterTx) error {
+			return tx.UpsertWarningLocalNode(ctx, "", entity.TypeStoragePool, int(pool.ID()), warningtype.StoragePoolExtendFailed, message)
+		})
+		if err != nil {
+			logger.Warn("Failed raising storage pool extension warning", logger.Ctx{"pool": pool.Name(), "err": err})
+		}
+
+		return
+	}
+
+	if extended {
+		logger.Info("Extended storage pool", logger.Ctx{"pool": pool.Name()})
+	}
+
+	err = warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(s.DB.Cluster, "", warningtype.StoragePoolExtendFailed, entity.TypeStoragePool, int(pool.ID()))
+	if err != nil {
+		logger.Warn("Failed resolving storage pool extension warning", logger.Ctx{"pool": pool.Name(), "err": err})
+	}
+}
+
+// storageVolumeUsage returns the usage of a custom or instance volume.
+func storageVolumeUsage(s *state.State, pool storagePools.Pool, v db.StorageVolumeArgs) (*storagePools.VolumeUsage, error) {
+	if v.Type == dbCluster.StoragePoolVolumeTypeCustom {
+		return pool.GetCustomVolumeUsage(v.ProjectName, v.Name)
+	}
+
+	inst, err := instance.LoadByProjectAndName(s, v.ProjectName, v.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return pool.GetInstanceUsage(inst)
+}
+
+// storageVolumeWarningThreshold returns the usage percentage above which a warning is raised for a volume with
+// volConfig in a pool with poolConfig, or -1 if the volume usage isn't monitored.
+func storageVolumeWarningThreshold(volConfig map[string]string, poolConfig map[string]string) (int64, error) {
+	threshold := volConfig["warning_threshold"]
+	if threshold == "" {
+		threshold = poolConfig["volume.warning_threshold"]
+	}
+
+	if threshold == "" {
+		return -1, nil
+	}
+
+	return strconv.ParseInt(threshold, 10, 64)
+}
+
+// storageUsageWarningMessage returns the usage message of an entity using used bytes of total bytes, or an empty
+// string if the usage is below thresholdPercent or total is unknown.
+func storageUsageWarningMessage(entityDesc string, used int64, total int64, thresholdPercent int64) string {
+	if total <= 0 {
+		return ""
+	}
+
+	usedPercent := used * 100 / total
+	if usedPercent < thresholdPercent {
+		return ""
+	}
+
+	return fmt.Sprintf("%s is %d%% full (%s of %s)", entityDesc, usedPercent, units.GetByteSizeStringIEC(used, 2), units.GetByteSizeStringIEC(total, 2))
+}
+
+// storageVolumeUsageWarning returns the warning message for a volume whose usage is at or above thresholdPercent of
+// its size, or an empty string if the usage is below the threshold. Volumes without a size limit are only limited by
+// the space of the pool, so their usage is compared to their used space plus poolFree, the free space of the pool.
+// No warning is returned for them if poolFree is negative, i.e. the free space of the pool is unknown.
+func storageVolumeUsageWarning(v db.StorageVolumeArgs, usage storagePools.VolumeUsage, poolFree int64, thresholdPercent int64) string {
+	total := usage.Total
+	if total <= 0 {
+		if poolFree < 0 {
+			return ""
+		}
+
+		total = usage.Used + poolFree
+	}
+
+	return storageUsageWarningMessage(fmt.Sprintf("Volume %q of type %q", v.Name, v.TypeName), usage.Used, total, thresholdPercent)
+}
+
+// storagePoolUsageWarning returns the warning message for a pool whose usage is at or above thresholdPercent of its
+// space, or an empty string if the usage is below the threshold.
+func storagePoolUsageWarning(poolName string, space api.ResourcesStoragePoolSpace, thresholdPercent int64) string {
+	return storageUsageWarningMessage(fmt.Sprintf("Storage pool %q", poolName), int64(space.Used), int64(space.Total), thresholdPercent)
+}
+
+// storageUsageRaiseWarning raises a warning of typeCode for an entity and returns whether its usage has just crossed
+// the threshold, meaning that there was no unresolved warning of that type for the entity yet.
+func storageUsageRaiseWarning(s *state.State, memberName string, projectName string, entityType entity.Type, entityID int, typeCode warningtype.Type, message string) (bool, error) {
+	crossed := false
+
+	err := s.DB.Cluster.Transaction(s.ShutdownCtx, func(ctx context.Context, tx *db.ClusterTx) error {
+		clusterEntityType := dbCluster.EntityType(entityType)
+		existing, err := dbCluster.GetWarnings(ctx, tx.Tx(), dbCluster.WarningFilter{
+			TypeCode:   &typeCode,
+			Node:       &memberName,
+			Project:    &projectName,
+			EntityType: &clusterEntityType,
+			EntityID:   &entityID,
+		})
+		if err != nil {
+			return err
+		}
+
+		crossed = true
+		for _, w := range existing {
+			if w.Status != warningtype.StatusResolved {
+				crossed = false
+			}
+		}
+
+		return tx.UpsertWarning(ctx, memberName, projectName, entityType, entityID, typeCode, message)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return crossed, nil
+}
+
+// storagePoolWarningThreshold returns the usage percentage above which a warning is raised for a pool with
+// poolConfig, or -1 if the pool usage isn't monitored.
+func storagePoolWarningThreshold(poolConfig map[string]string) (int64, error) {
+	threshold := poolConfig["warning_threshold"]
+	if threshold == "" {
+		return -1, nil
+	}
+
+	return strconv.ParseInt(threshold, 10, 64)
+}
+
+// storagePoolCheckUsage raises a warning and emits a lifecycle event if the usage of the pool crosses its
+// warning_threshold and resolves the warning once the usage is below the threshold again. The warning is raised on
+// memberName, which is empty for remote pools of a cluster.
+func storagePoolCheckUsage(s *state.State, pool storagePools.Pool, space *api.ResourcesStoragePoolSpace, memberName string) {
+	thresholdPercent, err := storagePoolWarningThreshold(pool.Driver().Config())
+	if err != nil {
+		logger.Warn("Invalid storage pool warning threshold", logger.Ctx{"pool": pool.Name(), "err": err})
+		return
+	}
+
+	if thresholdPercent < 0 || space == nil {
+		return
+	}
+
+	message := storagePoolUsageWarning(pool.Name(), *space, thresholdPercent)
+	if message != "" {
+		logger.Warn("Storage pool nearly full", logger.Ctx{"pool": pool.Name(), "usage": message})
+
+		crossed, err := storageUsageRaiseWarning(s, memberName, "", entity.TypeStoragePool, int(pool.ID()), warningtype.StoragePoolNearlyFull, message)
+		if err != nil {
+			logger.Warn("Failed raising storage pool usage warning", logger.Ctx{"pool": pool.Name(), "err": err})
+			return
+		}
+
+		if crossed {
+			ctx := map[string]any{"threshold": thresholdPercent, "used": space.Used, "total": space.Total}
+			if memberName != "" && s.ServerClustered {
+				ctx["target"] = memberName
+			}
+
+			s.Events.SendLifecycle("", lifecycle.StoragePoolNearlyFull.Event(pool.Name(), nil, ctx))
+		}
+
+		return
+	}
+
+	err = warnings.ResolveWarningsByNodeAndProjectAndTypeAndEntity(s.DB.Cluster, memberName, "", warningtype.StoragePoolNearlyFull, entity.TypeStoragePool, int(pool.ID()))
+	if err != nil {
+		logger.Warn("Failed resolving storage pool usage warning", logger.Ctx{"pool": pool.Name(), "err": err})
+	}
+}
+
+// storageVolumeCheckUsage raises a warning and emits a lifecycle event if the usage of the volume crosses its
+// warning_threshold and resolves the warning once the usage is below the threshold again. The warning is raised on
+// memberName, which is empty for remote volumes of a cluster so that the warning is resolved by whichever member
+// checks the volume next. The usage of volumes without a size limit is compared to the space of the pool.
+func storageVolumeCheckUsage(s *state.State, pool storagePools.Pool, poolSpace *api.ResourcesStoragePoolSpace, v db.StorageVolumeArgs, memberName string) {
+	thresholdPercent, err := storageVolumeWarningThreshold(v.Config, pool.Driver().Config())
+	if err != nil {
+		logger.Warn("Invalid storage volume warning threshold", logger.Ctx{"volName": v.Name, "project": v.ProjectName, "pool": v.PoolName, "err": err})
+		return
+	}
+
+	if thresholdPercent < 0 {
+		return
+	}
+
+	usage, err := storageVolumeUsage(s, pool, v)
+	if err != nil {
+		if !errors.Is(err, storageDrivers.ErrNotSupported) {
+			logger.Warn("Failed getting storage volume usage", logger.Ctx{"volName": v.Name, "project": v.ProjectName, "pool": v.PoolName, "err": err})
+		}
+
+		return
+	}
+
+	poolFree := int64(-1)
+	if poolSpace != nil && poolSpace.Total >= poolSpace.Used {
+		poolFree = int64(poolSpace.Total - poolSpace.Used)
+	}
+
+	message := storageVolumeUsageWarning(v, *usage, poolFree, thresholdPercent)
+	if message != "" {
+		logger.Warn("Storage volume nearly full", logger.Ctx{"volName": v.Name, "project": v.ProjectName, "pool": v.PoolName, "usage": message})
+
+		crossed, err := storageUsageRaiseWarning(s, memberName, v.ProjectName, entity.TypeStorageVolume, int(v.ID), warningtype.StorageVolumeNearlyFull, message)
+		if err != nil {
+			logger.Warn("Failed raising storage volume usage warning", logger.Ctx{"volName": v.Name, "project": v.ProjectName, "pool": v.PoolName, "err": err})
+			return
+		}
+
+		if crossed {
+			vol := pool.GetVolume(storagePools.VolumeDBTypeToType(v.Type), storageDrivers.ContentType(v.ContentType), v.Name, v.Config)
+			ctx := map[string]any{"type": v.TypeName, "threshold": thresholdPercent, "used": usage.Used}
+			if usage.Total > 0 {
+				ctx["total"] = usage.Total
+			}
+
+			s.Events.SendLifecycle(v.ProjectName, lifecycle.StorageVolumeNearlyFull.Event(vol, v.TypeName, v.ProjectName, nil, ctx))
+		}
+
+		return
+	}
+
+	err = warnings.ResolveWarningsByNodeAndProjectAndTypeAndEntity(s.DB.Cluster, memberName, v.ProjectName, warningtype.StorageVolumeNearlyFull, entity.TypeStorageVolume, int(v.ID))
+	if err != nil {
+		logger.Warn("Failed resolving storage volume usage warning", logger.Ctx{"volName": v.Name, "project": v.ProjectName, "pool": v.PoolName, "err": err})
+	}
+}
+
+// checkStorageUsage extends the storage pools of the local member that are above their automatic extension
+// threshold and checks the usage of the pools and their volumes against their warning thresholds.
+func checkStorageUsage(ctx context.Context, s *state.State) error {
+	allPools, err := storagePoolsLoadCreated(ctx, s)
+	if err != nil {
+		return fmt.Errorf("Failed loading storage pools: %w", err)
+	}
+
+	pools := make(map[string]storagePools.Pool, len(allPools))
+	for _, pool := range allPools {
+		if pool.LocalStatus() == api.StoragePoolStatusUnvailable {
+			continue
+		}
+
+		pools[pool.Name()] = pool
+
+		// Extend the pools first so the volume usage reflects the space they have available.
+		storagePoolAutoExtend(s, pool)
+	}
+
+	var volumes []db.StorageVolumeArgs
+	var onlineMemberIDs []int64
+
+	err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		for _, volType := range []dbCluster.StoragePoolVolumeType{dbCluster.StoragePoolVolumeTypeCustom, dbCluster.StoragePoolVolumeTypeContainer, dbCluster.StoragePoolVolumeTypeVM} {
+			typeVolumes, err := tx.GetStoragePoolVolumesWithType(ctx, volType, true)
+			if err != nil {
+				return fmt.Errorf("Failed getting volumes: %w", err)
+			}
+
+			// The volume type isn't part of the returned volumes as they were filtered by it.
+			for _, v := range typeVolumes {
+				v.Type = volType
+				v.TypeName = volType.String()
+				volumes = append(volumes, v)
+			}
+		}
+
+		if !s.ServerClustered {
+			return nil
+		}
+
+		members, err := tx.GetNodes(ctx)
+		if err != nil {
+			return fmt.Errorf("Failed getting cluster members: %w", err)
+		}
+
+		for _, member := range members {
+			if !member.IsOffline(s.GlobalConfig.OfflineThreshold()) {
+				onlineMemberIDs = append(onlineMemberIDs, member.ID)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	localMemberID := s.DB.Cluster.GetNodeID()
+
+	// checkMember returns the member name on which the warnings of an entity are raised and whether the entity
+	// is checked by the local member. Remote entities are shared by all members, so a stable random online member
+	// is chosen to check them. Their warnings aren't tied to a member as the chosen member changes with the
+	// online members.
+	checkMember := func(entityID int64, remote bool) (string, bool) {
+		if !remote || !s.ServerClustered {
+			return s.ServerName, true
+		}
+
+		selectedMemberID, err := util.GetStableRandomInt64FromList(entityID, onlineMemberIDs)
+		if err != nil || selectedMemberID != localMemberID {
+			return "", false
+		}
+
+		return "", true
+	}
+
+	// The space of the pools is only retrieved when needed, and at most once per check.
+	poolSpaces := make(map[string]*api.ResourcesStoragePoolSpace, len(pools))
+	poolSpace := func(pool storagePools.Pool) *api.ResourcesStoragePoolSpace {
+		space, ok := poolSpaces[pool.Name()]
+		if ok {
+			return space
+		}
+
+		res, err := pool.GetResources()
+		if err != nil {
+			if !errors.Is(err, storageDrivers.ErrNotSupported) {
+				logger.Warn("Failed getting storage pool resources", logger.Ctx{"pool": pool.Name(), "err": err})
+			}
+		} else {
+			space = &res.Space
+		}
+
+		poolSpaces[pool.Name()] = space
+
+		return space
+	}
+
+	for _, pool := range pools {
+		memberName, ok := checkMember(pool.ID(), pool.Driver().Info().Remote)
+		if !ok || pool.Driver().Config()["warning_threshold"] == "" {
+			continue
+		}
+
+		storagePoolCheckUsage(s, pool, poolSpace(pool), memberName)
+	}
+
+	for _, v := range volumes {
+		pool, ok := pools[v.PoolName]
+		if !ok {
+			continue
+		}
+
+		memberName, ok := checkMember(v.ID, v.NodeID < 0)
+		if !ok {
+			continue
+		}
+
+		// Only retrieve the space of the pool for monitored volumes.
+		var space *api.ResourcesStoragePoolSpace
+		if v.Config["warning_threshold"] != "" || pool.Driver().Config()["volume.warning_threshold"] != "" {
+			space = poolSpace(pool)
+		}
+
+		storageVolumeCheckUsage(s, pool, space, v, memberName)
+	}
+
+	return nil
+}
+
+func storageUsageCheckTask(stateFunc func() *state.State) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		err := checkStorageUsage(ctx, stateFunc())
+		if err != nil {
+			logger.Error("Failed checking storage usage", logger.Ctx{"err": err})
+		}
+	}
+
+	return f, task.Every(5*time.Minute, task.SkipFirst)
+}
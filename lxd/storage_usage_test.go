@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/lxd/lxd/db"
+	storagePools "github.com/canonical/lxd/lxd/storage"
+	"github.com/canonical/lxd/shared/api"
+)
+
+func TestStorageVolumeWarningThreshold(t *testing.T) {
+	// Volumes aren't monitored by default.
+	threshold, err := storageVolumeWarningThreshold(map[string]string{}, map[string]string{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), threshold)
+
+	// The pool sets the default threshold of its volumes.
+	threshold, err = storageVolumeWarningThreshold(map[string]string{}, map[string]string{"volume.warning_threshold": "80"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(80), threshold)
+
+	// The volume threshold takes precedence.
+	threshold, err = storageVolumeWarningThreshold(map[string]string{"warning_threshold": "95"}, map[string]string{"volume.warning_threshold": "80"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(95), threshold)
+
+	_, err = storageVolumeWarningThreshold(map[string]string{"warning_threshold": "full"}, map[string]string{})
+	assert.Error(t, err)
+}
+
+func TestStorageVolumeUsageWarning(t *testing.T) {
+	v := db.StorageVolumeArgs{Name: "data", TypeName: "custom"}
+
+	tests := []struct {
+		name     string
+		usage    storagePools.VolumeUsage
+		poolFree int64
+		want     string
+	}{
+		{
+			name:  "Below threshold",
+			usage: storagePools.VolumeUsage{Used: 700 * 1024 * 1024, Total: 1024 * 1024 * 1024},
+		},
+		{
+			name:  "At threshold",
+			usage: storagePools.VolumeUsage{Used: 800 * 1024 * 1024, Total: 1000 * 1024 * 1024},
+			want:  `Volume "data" of type "custom" is 80% full (800.00MiB of 1000.00MiB)`,
+		},
+		{
+			name:  "Above threshold",
+			usage: storagePools.VolumeUsage{Used: 1000 * 1024 * 1024, Total: 1024 * 1024 * 1024},
+			want:  `Volume "data" of type "custom" is 97% full (1000.00MiB of 1.00GiB)`,
+		},
+		{
+			name:     "No size limit and unknown pool space",
+			usage:    storagePools.VolumeUsage{Used: 1024 * 1024 * 1024, Total: -1},
+			poolFree: -1,
+		},
+		{
+			name:     "No size limit with free pool space",
+			usage:    storagePools.VolumeUsage{Used: 512 * 1024 * 1024, Total: -1},
+			poolFree: 1024 * 1024 * 1024,
+		},
+		{
+			name:     "No size limit with little free pool space",
+			usage:    storagePools.VolumeUsage{Used: 900 * 1024 * 1024, Total: -1},
+			poolFree: 100 * 1024 * 1024,
+			want:     `Volume "data" of type "custom" is 90% full (900.00MiB of 1000.00MiB)`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, storageVolumeUsageWarning(v, test.usage, test.poolFree, 80))
+		})
+	}
+}
+
+func TestStoragePoolWarningThreshold(t *testing.T) {
+	// Pools aren't monitored by default, regardless of the threshold of their volumes.
+	threshold, err := storagePoolWarningThreshold(map[string]string{"volume.warning_threshold": "80"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), threshold)
+
+	threshold, err = storagePoolWarningThreshold(map[string]string{"warning_threshold": "90"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(90), threshold)
+}
+
+func TestStoragePoolUsageWarning(t *testing.T) {
+	assert.Empty(t, storagePoolUsageWarning("local", api.ResourcesStoragePoolSpace{Used: 70, Total: 100}, 80))
+	assert.Empty(t, storagePoolUsageWarning("local", api.ResourcesStoragePoolSpace{Used: 0, Total: 0}, 80))
+	assert.Equal(t, `Storage pool "local" is 92% full (950.00MiB of 1.00GiB)`, storagePoolUsageWarning("local", api.ResourcesStoragePoolSpace{Used: 950 * 1024 * 1024, Total: 1024 * 1024 * 1024}, 80))
+}
@@ -28,7 +28,9 @@ import (
 	"github.com/canonical/lxd/lxd/db"
 	"github.com/canonical/lxd/lxd/db/cluster"
 	"github.com/canonical/lxd/lxd/db/operationtype"
+	deviceConfig "github.com/canonical/lxd/lxd/device/config"
 	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/instance/instancetype"
 	"github.com/canonical/lxd/lxd/operations"
 	"github.com/canonical/lxd/lxd/project"
 	"github.com/canonical/lxd/lxd/project/limits"
@@ -778,6 +780,11 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// Secrets are never returned, nor used for filtering.
+	for _, vol := range dbVolumes {
+		vol.Config = storagePools.VolumeConfigWithoutSecrets(vol.Config)
+	}
+
 	// Pre-fill UsedBy if using filtering.
 	if clauses != nil && len(clauses.Clauses) > 0 {
 		for i, vol := range dbVolumes {
@@ -1673,6 +1680,12 @@ func storagePoolVolumePost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// A block volume attached to a single running VM can be moved to another pool without stopping the VM.
+	liveMove := req.Pool != "" && req.Pool != details.pool.Name() && req.Name == details.volumeName && effectiveProjectName == targetProjectName && dbVolume.ContentType == cluster.StoragePoolVolumeContentTypeNameBlock
+
+	var liveInst instance.Instance
+	var liveDevName string
+
 	// Check if a running instance is using it.
 	err = storagePools.VolumeUsedByInstanceDevices(s, details.pool.Name(), effectiveProjectName, &dbVolume.StorageVolume, true, func(dbInst db.InstanceArgs, project api.Project, usedByDevices []string) error {
 		inst, err := instance.Load(s, dbInst, project)
@@ -1680,16 +1693,31 @@ func storagePoolVolumePost(d *Daemon, r *http.Request) response.Response {
 			return err
 		}
 
-		if inst.IsRunning() {
-			return errors.New("Volume is still in use by running instances")
+		if !inst.IsRunning() {
+			return nil
 		}
 
-		return nil
+		// Only local devices are handled as updating a profile would hot-plug the disk again.
+		if liveMove && liveInst == nil && inst.Type() == instancetype.VM && len(usedByDevices) == 1 {
+			_, isLocalDevice := inst.LocalDevices()[usedByDevices[0]]
+			if isLocalDevice {
+				liveInst = inst
+				liveDevName = usedByDevices[0]
+
+				return nil
+			}
+		}
+
+		return errors.New("Volume is still in use by running instances")
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	if liveInst != nil {
+		return storagePoolVolumeTypePostMoveLive(s, r, details.pool.Name(), effectiveProjectName, &dbVolume.StorageVolume, req, liveInst, liveDevName)
+	}
+
 	// Detect a rename request.
 	if (req.Pool == "" || req.Pool == details.pool.Name()) && (effectiveProjectName == targetProjectName) {
 		return storagePoolVolumeTypePostRename(s, r, details.pool.Name(), effectiveProjectName, &dbVolume.StorageVolume, req)
@@ -2039,6 +2067,162 @@ func storagePoolVolumeTypePostMove(s *state.State, r *http.Request, poolName str
 	return operations.OperationResponse(op)
 }
 
+// storagePoolVolumeTypePostMoveLive moves a block volume attached to a running VM to another pool.
+// The volume is copied and then mirrored by the VM which switches over to the new volume without being stopped.
+func storagePoolVolumeTypePostMoveLive(s *state.State, r *http.Request, poolName string, projectName string, vol *api.StorageVolume, req api.StorageVolumePost, inst instance.Instance, devName string) response.Response {
+	vm, ok := inst.(instance.VM)
+	if !ok {
+		return response.BadRequest(errors.New("Volume is still in use by running instances"))
+	}
+
+	newVol := *vol
+	newVol.Name = req.Name
+
+	pool, err := storagePools.LoadByName(s, poolName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	newPool, err := storagePools.LoadByName(s, req.Pool)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	run := func(ctx context.Context, op *operations.Operation) error {
+		revert := revert.New()
+		defer revert.Fail()
+
+		// Point the running VM at the new volume without going through an instance update as that would
+		// hot-plug the disk again.
+		updateDevices := func(devices deviceConfig.Devices) error {
+			// Do not store initial.* device config keys in database.
+			devices.CutInitialConfig()
+
+			return s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+				dbDevices, err := cluster.APIToDevices(devices.CloneNative())
+				if err != nil {
+					return err
+				}
+
+				return cluster.UpdateInstanceDevices(ctx, tx.Tx(), int64(inst.ID()), dbDevices)
+			})
+		}
+
+		newDevices := inst.LocalDevices().Clone()
+		newDevices[devName]["pool"] = newPool.Name()
+		newDevices[devName]["source"] = newVol.Name
+
+		err := updateDevices(newDevices)
+		if err != nil {
+			return fmt.Errorf("Failed updating instance disk device: %w", err)
+		}
+
+		revert.Add(func() {
+			err := updateDevices(inst.LocalDevices().Clone())
+			if err != nil {
+				logger.Error("Failed restoring instance disk device", logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "device": devName, "err": err})
+			}
+		})
+
+		// Update devices using the volume in other instances and profiles.
+		cleanup, err := storagePoolVolumeUpdateUsers(ctx, s, projectName, pool.Name(), vol, newPool.Name(), &newVol)
+		if err != nil {
+			return err
+		}
+
+		revert.Add(cleanup)
+
+		// The copy is inconsistent as the guest keeps writing to the volume, the mirror catches up with those writes.
+		err = newPool.CreateCustomVolumeFromCopy(projectName, projectName, newVol.Name, "", nil, pool.Name(), vol.Name, true, op)
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { _ = newPool.DeleteCustomVolume(projectName, newVol.Name, op) })
+
+		// Keep the new volume mounted for as long as the VM uses it, like the disk device does on start.
+		_, err = newPool.MountCustomVolume(projectName, newVol.Name, op)
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { _, _ = newPool.UnmountCustomVolume(projectName, newVol.Name, op) })
+
+		dbNewVol, err := storagePools.VolumeDBGet(newPool, projectName, newVol.Name, storageDrivers.VolumeTypeCustom)
+		if err != nil {
+			return err
+		}
+
+		diskVol := newPool.GetVolume(storageDrivers.VolumeTypeCustom, storageDrivers.ContentTypeBlock, project.StorageVolume(projectName, newVol.Name), dbNewVol.Config)
+		diskPath, err := newPool.Driver().GetVolumeDiskPath(diskVol)
+		if err != nil {
+			return fmt.Errorf("Failed getting disk path: %w", err)
+		}
+
+		startTime := time.Now()
+		err = vm.MirrorDisk(devName, diskPath, func(current int64, total int64) {
+			if total <= 0 {
+				return
+			}
+
+			var speed int64
+			elapsed := int64(time.Since(startTime).Seconds())
+			if elapsed > 0 {
+				speed = current / elapsed
+			}
+
+			_ = op.UpdateProgress("mirror_disk", "Mirroring volume", current*100/total, current, speed)
+		})
+		if err != nil {
+			return err
+		}
+
+		// The VM now uses the new volume so leave it in place whatever happens next.
+		revert.Success()
+
+		// Release the mount taken when the disk device was started and remove the original volume.
+		_, err = pool.UnmountCustomVolume(projectName, vol.Name, op)
+		if err != nil && !errors.Is(err, storageDrivers.ErrInUse) {
+			return fmt.Errorf("Failed unmounting original volume: %w", err)
+		}
+
+		err = pool.DeleteCustomVolume(projectName, vol.Name, op)
+		if err != nil {
+			return fmt.Errorf("Failed deleting original volume: %w", err)
+		}
+
+		// Record the new device config in the instance backup file.
+		inst, err := instance.LoadByProjectAndName(s, inst.Project().Name, inst.Name())
+		if err != nil {
+			return err
+		}
+
+		return inst.UpdateBackupFile()
+	}
+
+	volumeURL := entity.StorageVolumeURL(projectName, vol.Location, vol.Pool, vol.Type, vol.Name)
+	resources := map[entity.Type][]api.URL{
+		entity.TypeStorageVolume: {*volumeURL},
+		entity.TypeInstance:      {*entity.InstanceURL(inst.Project().Name, inst.Name())},
+	}
+
+	args := operations.OperationArgs{
+		ProjectName: projectName,
+		EntityURL:   volumeURL,
+		Type:        operationtype.VolumeMove,
+		Class:       operations.OperationClassTask,
+		RunHook:     run,
+		Resources:   resources,
+	}
+
+	op, err := operations.ScheduleUserOperationFromRequest(s, r, args)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
 // swagger:operation GET /1.0/storage-pools/{poolName}/volumes/{type}/{volumeName} storage storage_pool_volume_type_get
 //
 //	Get the storage volume
@@ -2145,6 +2329,8 @@ func storagePoolVolumeGet(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
+	dbVolume.Config = storagePools.VolumeConfigWithoutSecrets(dbVolume.Config)
+
 	etag := []any{details.volumeName, dbVolume.Type, dbVolume.Config}
 
 	return response.SyncResponseETag(true, dbVolume.StorageVolume, etag)
@@ -2231,7 +2417,7 @@ func storagePoolVolumePut(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Validate the ETag
-	etag := []any{details.volumeName, dbVolume.Type, dbVolume.Config}
+	etag := []any{details.volumeName, dbVolume.Type, storagePools.VolumeConfigWithoutSecrets(dbVolume.Config)}
 
 	err = util.EtagCheck(r, etag)
 	if err != nil {
@@ -2244,6 +2430,13 @@ func storagePoolVolumePut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	if req.Config != nil {
+		err = storagePools.VolumeConfigKeepSecrets(req.Config, dbVolume.Config)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
 	run := func(ctx context.Context, op *operations.Operation) error {
 		switch details.volumeType {
 		case cluster.StoragePoolVolumeTypeCustom:
@@ -2405,7 +2598,7 @@ func storagePoolVolumePatch(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Validate the ETag.
-	etag := []any{details.volumeName, dbVolume.Type, dbVolume.Config}
+	etag := []any{details.volumeName, dbVolume.Type, storagePools.VolumeConfigWithoutSecrets(dbVolume.Config)}
 
 	err = util.EtagCheck(r, etag)
 	if err != nil {
@@ -2422,6 +2615,11 @@ func storagePoolVolumePatch(d *Daemon, r *http.Request) response.Response {
 		req.Config = map[string]string{}
 	}
 
+	err = storagePools.VolumeConfigKeepSecrets(req.Config, dbVolume.Config)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Merge current config with requested changes.
 	for k, v := range dbVolume.Config {
 		_, ok := req.Config[k]
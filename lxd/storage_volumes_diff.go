@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/auth"
+	"github.com/canonical/lxd/lxd/db/operationtype"
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/lxd/response"
+	storagePools "github.com/canonical/lxd/lxd/storage"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/version"
+)
+
+var storagePoolVolumeTypeDiffCmd = APIEndpoint{
+	Path:        "storage-pools/{poolName}/volumes/{type}/{volumeName}/diff",
+	MetricsType: entity.TypeStoragePool,
+
+	Post: APIEndpointAction{Handler: storagePoolVolumeTypeDiffPost, AccessHandler: storagePoolVolumeTypeAccessHandler(entity.TypeStorageVolume, auth.EntitlementCanView)},
+}
+
+// swagger:operation POST /1.0/storage-pools/{poolName}/volumes/{type}/{volumeName}/diff storage storage_pool_volume_type_diff_post
+//
+//	Compare a storage volume with a snapshot
+//
+//	Lists the paths that were added, modified or deleted in a custom or container file system volume
+//	between one of its snapshots and either a later snapshot or the current state of the volume.
+//	The comparison runs as a background operation that can be canceled. Once it succeeded, the
+//	changed paths are available in the `diffs` field of the operation metadata, as a list of
+//	StorageVolumeDiff.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: query
+//	    name: target
+//	    description: Cluster member name
+//	    type: string
+//	    example: lxd01
+//	  - in: body
+//	    name: diff
+//	    description: Snapshots to compare
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/StorageVolumeDiffPost"
+//	responses:
+//	  "202":
+//	    $ref: "#/responses/Operation"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func storagePoolVolumeTypeDiffPost(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	details, err := request.GetContextValue[storageVolumeDetails](r.Context(), ctxStorageVolumeDetails)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	requestProjectName := request.ProjectParam(r)
+	effectiveProjectName, err := request.GetContextValue[string](r.Context(), request.CtxEffectiveProjectName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Forward if needed.
+	target := request.QueryParam(r, "target")
+	resp := forwardedResponseToNode(r.Context(), s, target)
+	if resp != nil {
+		return resp
+	}
+
+	resp = forwardedResponseIfVolumeIsRemote(r.Context(), s)
+	if resp != nil {
+		return resp
+	}
+
+	req := api.StorageVolumeDiffPost{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.From == "" {
+		return response.BadRequest(errors.New("Missing snapshot to compare from"))
+	}
+
+	run := func(ctx context.Context, op *operations.Operation) error {
+		diffs, err := details.pool.DiffVolume(ctx, effectiveProjectName, storagePools.VolumeDBTypeToType(details.volumeType), details.volumeName, req.From, req.To, op)
+		if err != nil {
+			return err
+		}
+
+		return op.UpdateMetadata(map[string]any{"diffs": diffs})
+	}
+
+	volumeURL := api.NewURL().Path(version.APIVersion, "storage-pools", details.pool.Name(), "volumes", details.volumeTypeName, details.volumeName).Project(effectiveProjectName)
+
+	args := operations.OperationArgs{
+		ProjectName: requestProjectName,
+		Type:        operationtype.VolumeDiff,
+		Class:       operations.OperationClassTask,
+		RunHook:     run,
+		EntityURL:   volumeURL,
+		Resources: map[entity.Type][]api.URL{
+			entity.TypeStorageVolume: {*volumeURL},
+		},
+	}
+
+	op, err := operations.ScheduleUserOperationFromRequest(s, r, args)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
@@ -0,0 +1,625 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/lxd/auth"
+	"github.com/canonical/lxd/lxd/db"
+	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/db/operationtype"
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/state"
+	storagePools "github.com/canonical/lxd/lxd/storage"
+	storageDrivers "github.com/canonical/lxd/lxd/storage/drivers"
+	"github.com/canonical/lxd/lxd/task"
+	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/version"
+)
+
+var storagePoolVolumeTypeReplicationCmd = APIEndpoint{
+	Path:        "storage-pools/{poolName}/volumes/{type}/{volumeName}/replication",
+	MetricsType: entity.TypeStoragePool,
+
+	Get:  APIEndpointAction{Handler: storagePoolVolumeTypeReplicationGet, AccessHandler: storagePoolVolumeTypeAccessHandler(entity.TypeStorageVolume, auth.EntitlementCanView)},
+	Post: APIEndpointAction{Handler: storagePoolVolumeTypeReplicationPost, AccessHandler: storagePoolVolumeTypeAccessHandler(entity.TypeStorageVolume, auth.EntitlementCanEdit)},
+}
+
+// storageVolumeReplicationRunning tracks the IDs of the custom volumes whose replica is being synchronized.
+var storageVolumeReplicationRunning = sync.Map{}
+
+// swagger:operation GET /1.0/storage-pools/{poolName}/volumes/{type}/{volumeName}/replication storage storage_pool_volume_type_replication_get
+//
+//	Get the storage volume replication state
+//
+//	Gets the role of the custom storage volume in a replication, the volume at the other end and the replication lag.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: query
+//	    name: target
+//	    description: Cluster member name
+//	    type: string
+//	    example: lxd01
+//	responses:
+//	  "200":
+//	    description: Storage volume replication state
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/StorageVolumeReplication"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func storagePoolVolumeTypeReplicationGet(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	details, err := request.GetContextValue[storageVolumeDetails](r.Context(), ctxStorageVolumeDetails)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Check that the storage volume type is valid.
+	if details.volumeType != dbCluster.StoragePoolVolumeTypeCustom {
+		return response.BadRequest(fmt.Errorf("Invalid storage volume type %q", details.volumeTypeName))
+	}
+
+	effectiveProjectName, err := request.GetContextValue[string](r.Context(), request.CtxEffectiveProjectName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Forward if needed.
+	target := request.QueryParam(r, "target")
+	resp := forwardedResponseToNode(r.Context(), s, target)
+	if resp != nil {
+		return resp
+	}
+
+	resp = forwardedResponseIfVolumeIsRemote(r.Context(), s)
+	if resp != nil {
+		return resp
+	}
+
+	dbVolume, err := storagePools.VolumeDBGet(details.pool, effectiveProjectName, details.volumeName, storageDrivers.VolumeTypeCustom)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, storageVolumeReplicationState(details.volumeName, dbVolume.Config))
+}
+
+// swagger:operation POST /1.0/storage-pools/{poolName}/volumes/{type}/{volumeName}/replication storage storage_pool_volume_type_replication_post
+//
+//	Run a storage volume replication action
+//
+//	Runs an action against the replication of a custom storage volume.
+//	The `sync` action of a source volume creates or refreshes its replica in the background.
+//	The `promote` action of a replica detaches it from its source so it can be used in place of the source volume.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: query
+//	    name: target
+//	    description: Cluster member name
+//	    type: string
+//	    example: lxd01
+//	  - in: body
+//	    name: action
+//	    description: Action to run
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/StorageVolumeReplicationPost"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "202":
+//	    $ref: "#/responses/Operation"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func storagePoolVolumeTypeReplicationPost(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	details, err := request.GetContextValue[storageVolumeDetails](r.Context(), ctxStorageVolumeDetails)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Check that the storage volume type is valid.
+	if details.volumeType != dbCluster.StoragePoolVolumeTypeCustom {
+		return response.BadRequest(fmt.Errorf("Invalid storage volume type %q", details.volumeTypeName))
+	}
+
+	requestProjectName := request.ProjectParam(r)
+	effectiveProjectName, err := request.GetContextValue[string](r.Context(), request.CtxEffectiveProjectName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Forward if needed.
+	target := request.QueryParam(r, "target")
+	resp := forwardedResponseToNode(r.Context(), s, target)
+	if resp != nil {
+		return resp
+	}
+
+	resp = forwardedResponseIfVolumeIsRemote(r.Context(), s)
+	if resp != nil {
+		return resp
+	}
+
+	req := api.StorageVolumeReplicationPost{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	dbVolume, err := storagePools.VolumeDBGet(details.pool, effectiveProjectName, details.volumeName, storageDrivers.VolumeTypeCustom)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	replication := storageVolumeReplicationState(details.volumeName, dbVolume.Config)
+
+	switch req.Action {
+	case "sync":
+		if replication.Role != api.StorageVolumeReplicationRoleSource {
+			return response.BadRequest(fmt.Errorf("Storage volume %q isn't a replication source", details.volumeName))
+		}
+
+		run := func(ctx context.Context, op *operations.Operation) error {
+			return storageVolumeReplicate(s, effectiveProjectName, details.pool, details.volumeName, op)
+		}
+
+		volumeURL := api.NewURL().Path(version.APIVersion, "storage-pools", details.pool.Name(), "volumes", details.volumeTypeName, details.volumeName).Project(effectiveProjectName)
+
+		args := operations.OperationArgs{
+			ProjectName: requestProjectName,
+			Type:        operationtype.VolumeReplicate,
+			Class:       operations.OperationClassTask,
+			RunHook:     run,
+			EntityURL:   volumeURL,
+			Resources: map[entity.Type][]api.URL{
+				entity.TypeStorageVolume: {*volumeURL},
+			},
+		}
+
+		op, err := operations.ScheduleUserOperationFromRequest(s, r, args)
+		if err != nil {
+			return response.InternalError(err)
+		}
+
+		return operations.OperationResponse(op)
+
+	case "promote":
+		if replication.Role != api.StorageVolumeReplicationRoleReplica {
+			return response.BadRequest(fmt.Errorf("Storage volume %q isn't a replica", details.volumeName))
+		}
+
+		// Removing the source marker stops further synchronizations from overwriting the volume.
+		newConfig := maps.Clone(dbVolume.Config)
+		delete(newConfig, "volatile.replication.source")
+		delete(newConfig, "volatile.replication.last_sync")
+
+		err = details.pool.UpdateCustomVolume(effectiveProjectName, details.volumeName, dbVolume.Description, newConfig, nil)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	return response.BadRequest(fmt.Errorf("Unknown action %q", req.Action))
+}
+
+// storageVolumeReplicaName returns the name of the replica of a custom volume.
+func storageVolumeReplicaName(volName string, config map[string]string) string {
+	if config["replication.target_volume"] != "" {
+		return config["replication.target_volume"]
+	}
+
+	return volName
+}
+
+// storageVolumeReplicationState returns the replication state of a custom volume from its config.
+func storageVolumeReplicationState(volName string, config map[string]string) *api.StorageVolumeReplication {
+	replication := &api.StorageVolumeReplication{Lag: -1}
+
+	if config["replication.target_pool"] != "" {
+		replication.Role = api.StorageVolumeReplicationRoleSource
+		replication.Remote = config["replication.target_remote"]
+		replication.Pool = config["replication.target_pool"]
+		replication.Volume = storageVolumeReplicaName(volName, config)
+		replication.Schedule = config["replication.schedule"]
+	} else if config["volatile.replication.source"] != "" {
+		var source string
+
+		replication.Role = api.StorageVolumeReplicationRoleReplica
+		replication.Remote, source = replicationSourceParse(config["volatile.replication.source"])
+		replication.Pool, replication.Volume, _ = strings.Cut(source, "/")
+	} else {
+		return replication
+	}
+
+	replication.LastSync, replication.Lag = replicationLastSync(config)
+
+	return replication
+}
+
+// storageVolumeReplicaConfig returns the config of the replica of a custom volume with the given config.
+// The replica doesn't replicate any further and records where it comes from.
+func storageVolumeReplicaConfig(config map[string]string, source string) map[string]string {
+	replicaConfig := make(map[string]string, len(config))
+	for key, value := range config {
+		if replicationIsConfigKey(key) {
+			continue
+		}
+
+		replicaConfig[key] = value
+	}
+
+	replicaConfig["volatile.replication.source"] = source
+
+	return replicaConfig
+}
+
+// storageVolumeReplicate creates or refreshes the replica of a custom volume, including its snapshots.
+// An existing volume is only refreshed if it is a replica of the source volume, so promoted replicas are never
+// overwritten.
+func storageVolumeReplicate(s *state.State, projectName string, pool storagePools.Pool, volName string, op *operations.Operation) error {
+	dbVolume, err := storagePools.VolumeDBGet(pool, projectName, volName, storageDrivers.VolumeTypeCustom)
+	if err != nil {
+		return err
+	}
+
+	targetPoolName := dbVolume.Config["replication.target_pool"]
+	if targetPoolName == "" {
+		return api.StatusErrorf(http.StatusBadRequest, "Storage volume %q isn't a replication source", volName)
+	}
+
+	remote := dbVolume.Config["replication.target_remote"] != ""
+	targetVolName := storageVolumeReplicaName(volName, dbVolume.Config)
+	if !remote && targetPoolName == pool.Name() && targetVolName == volName {
+		return api.StatusErrorf(http.StatusBadRequest, "Storage volume %q cannot be replicated onto itself", volName)
+	}
+
+	_, loaded := storageVolumeReplicationRunning.LoadOrStore(dbVolume.ID, struct{}{})
+	if loaded {
+		return api.StatusErrorf(http.StatusConflict, "Replication of storage volume %q is already running", volName)
+	}
+
+	defer storageVolumeReplicationRunning.Delete(dbVolume.ID)
+
+	source := replicationSource(s, remote, pool.Name()+"/"+volName)
+	lastSync := time.Now().UTC().Format(time.RFC3339)
+
+	if remote {
+		err = storageVolumeReplicateRemote(s, projectName, pool, dbVolume, targetPoolName, targetVolName, source, lastSync, op)
+	} else {
+		err = storageVolumeReplicateLocal(s, projectName, pool, dbVolume, targetPoolName, targetVolName, source, lastSync, op)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Record the synchronization time on the source volume so the lag can be reported.
+	err = storageVolumeReplicationRecordSync(s, projectName, pool.ID(), volName, lastSync)
+	if err != nil {
+		return fmt.Errorf("Failed recording replication time of storage volume %q: %w", volName, err)
+	}
+
+	return nil
+}
+
+// storageVolumeReplicateLocal creates or refreshes the replica of a custom volume in a pool of this server or cluster.
+func storageVolumeReplicateLocal(s *state.State, projectName string, pool storagePools.Pool, dbVolume *db.StorageVolume, targetPoolName string, targetVolName string, source string, lastSync string, op *operations.Operation) error {
+	targetPool, err := storagePools.LoadByName(s, targetPoolName)
+	if err != nil {
+		return fmt.Errorf("Failed loading replication target pool %q: %w", targetPoolName, err)
+	}
+
+	// Remote volumes can be replicated from any member, so their replica must be reachable from all of them.
+	if pool.Driver().Info().Remote && !targetPool.Driver().Info().Remote {
+		return api.StatusErrorf(http.StatusBadRequest, "Storage volume %q on remote pool %q can only be replicated to a remote pool", dbVolume.Name, pool.Name())
+	}
+
+	targetDBVolume, err := storagePools.VolumeDBGet(targetPool, projectName, targetVolName, storageDrivers.VolumeTypeCustom)
+	if err != nil && !response.IsNotFoundError(err) {
+		return err
+	}
+
+	if targetDBVolume == nil {
+		err = targetPool.CreateCustomVolumeFromCopy(projectName, projectName, targetVolName, dbVolume.Description, storageVolumeReplicaConfig(dbVolume.Config, source), pool.Name(), dbVolume.Name, true, op)
+	} else {
+		if targetDBVolume.Config["volatile.replication.source"] != source {
+			return api.StatusErrorf(http.StatusConflict, "Storage volume %q in pool %q isn't a replica of storage volume %q", targetVolName, targetPoolName, dbVolume.Name)
+		}
+
+		err = targetPool.RefreshCustomVolume(projectName, projectName, targetVolName, "", nil, pool.Name(), dbVolume.Name, true, op)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Failed synchronizing replica %q in pool %q: %w", targetVolName, targetPoolName, err)
+	}
+
+	// Record the synchronization time on the replica so the lag can also be reported from its side.
+	err = storageVolumeReplicationRecordSync(s, projectName, targetPool.ID(), targetVolName, lastSync)
+	if err != nil {
+		return fmt.Errorf("Failed recording replication time of storage volume %q: %w", targetVolName, err)
+	}
+
+	return nil
+}
+
+// storageVolumeReplicateRemote creates or refreshes the replica of a custom volume on a remote server by pushing the
+// volume with the migration protocol.
+func storageVolumeReplicateRemote(s *state.State, projectName string, pool storagePools.Pool, dbVolume *db.StorageVolume, targetPoolName string, targetVolName string, source string, lastSync string, op *operations.Operation) error {
+	address := dbVolume.Config["replication.target_remote"]
+
+	client, certPEM, err := replicationRemoteConnect(context.TODO(), s, dbVolume.Config)
+	if err != nil {
+		return err
+	}
+
+	client = client.UseProject(projectName)
+
+	targetVolume, _, err := client.GetStoragePoolVolume(targetPoolName, dbCluster.StoragePoolVolumeTypeNameCustom, targetVolName)
+	if err != nil && !api.StatusErrorCheck(err, http.StatusNotFound) {
+		return fmt.Errorf("Failed getting replica %q in pool %q of remote %q: %w", targetVolName, targetPoolName, address, err)
+	}
+
+	if targetVolume != nil && targetVolume.Config["volatile.replication.source"] != source {
+		return api.StatusErrorf(http.StatusConflict, "Storage volume %q in pool %q of remote %q isn't a replica of storage volume %q", targetVolName, targetPoolName, address, dbVolume.Name)
+	}
+
+	req := api.StorageVolumesPost{
+		Name:        targetVolName,
+		Type:        dbCluster.StoragePoolVolumeTypeNameCustom,
+		ContentType: dbVolume.ContentType,
+		StorageVolumePut: api.StorageVolumePut{
+			Description: dbVolume.Description,
+			Config:      storageVolumeReplicaConfig(dbVolume.Config, source),
+		},
+		Source: api.StorageVolumeSource{
+			Type:    api.SourceTypeMigration,
+			Mode:    "push",
+			Refresh: targetVolume != nil,
+		},
+	}
+
+	targetOp, err := client.CreateStoragePoolVolume(targetPoolName, req)
+	if err != nil {
+		return fmt.Errorf("Failed creating replica %q in pool %q of remote %q: %w", targetVolName, targetPoolName, address, err)
+	}
+
+	opURL, secrets := replicationPushTarget(address, targetOp)
+	ws, err := newStorageMigrationSource(false, &api.StorageVolumePostTarget{Certificate: certPEM, Operation: opURL, Websockets: secrets})
+	if err != nil {
+		_ = targetOp.Cancel()
+		return err
+	}
+
+	err = ws.DoStorage(s, projectName, pool.Name(), dbVolume.Name, op)
+	if err != nil {
+		_ = targetOp.Cancel()
+		return fmt.Errorf("Failed synchronizing replica %q in pool %q of remote %q: %w", targetVolName, targetPoolName, address, err)
+	}
+
+	err = targetOp.Wait()
+	if err != nil {
+		return fmt.Errorf("Failed synchronizing replica %q in pool %q of remote %q: %w", targetVolName, targetPoolName, address, err)
+	}
+
+	// Record the synchronization time on the replica so the lag can also be reported from its side.
+	targetVolume, etag, err := client.GetStoragePoolVolume(targetPoolName, dbCluster.StoragePoolVolumeTypeNameCustom, targetVolName)
+	if err != nil {
+		return err
+	}
+
+	put := targetVolume.Writable()
+	put.Config["volatile.replication.last_sync"] = lastSync
+
+	updateOp, err := client.UpdateStoragePoolVolume(targetPoolName, dbCluster.StoragePoolVolumeTypeNameCustom, targetVolName, put, etag)
+	if err != nil {
+		return fmt.Errorf("Failed recording replication time of storage volume %q on remote %q: %w", targetVolName, address, err)
+	}
+
+	return updateOp.Wait()
+}
+
+// storageVolumeReplicationRecordSync records the time of the last synchronization in the config of a custom volume.
+func storageVolumeReplicationRecordSync(s *state.State, projectName string, poolID int64, volName string, lastSync string) error {
+	return s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		vol, err := tx.GetStoragePoolVolume(ctx, poolID, projectName, dbCluster.StoragePoolVolumeTypeCustom, volName, true)
+		if err != nil {
+			return err
+		}
+
+		vol.Config["volatile.replication.last_sync"] = lastSync
+
+		return tx.UpdateStoragePoolVolume(ctx, projectName, volName, dbCluster.StoragePoolVolumeTypeCustom, poolID, vol.Description, vol.Config)
+	})
+}
+
+func autoReplicateCustomVolumesTask(stateFunc func() *state.State) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		s := stateFunc()
+
+		var volumes, remoteVolumes []db.StorageVolumeArgs
+		var memberCount int
+		var onlineMemberIDs []int64
+
+		err := s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+			allVolumes, err := tx.GetStoragePoolVolumesWithType(ctx, dbCluster.StoragePoolVolumeTypeCustom, true)
+			if err != nil {
+				return fmt.Errorf("Failed getting volumes for auto custom volume replication task: %w", err)
+			}
+
+			for _, v := range allVolumes {
+				if v.Config["replication.target_pool"] == "" {
+					continue
+				}
+
+				schedule := v.Config["replication.schedule"]
+				if schedule == "" || !snapshotIsScheduledNow(schedule, v.ID) {
+					continue
+				}
+
+				if v.NodeID < 0 {
+					// Keep a separate list of remote volumes in order to select a member to
+					// perform the replication later.
+					remoteVolumes = append(remoteVolumes, v)
+				} else {
+					volumes = append(volumes, v) // Always include local volumes.
+				}
+			}
+
+			if len(remoteVolumes) > 0 {
+				members, err := tx.GetNodes(ctx)
+				if err != nil {
+					return fmt.Errorf("Failed getting cluster members: %w", err)
+				}
+
+				memberCount = len(members)
+
+				for _, member := range members {
+					if !member.IsOffline(s.GlobalConfig.OfflineThreshold()) {
+						onlineMemberIDs = append(onlineMemberIDs, member.ID)
+					}
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			logger.Error("Failed getting custom volume info", logger.Ctx{"err": err})
+			return
+		}
+
+		// Remote volumes are replicated from a stable random online member so only one member refreshes
+		// each replica.
+		if memberCount > 1 && len(onlineMemberIDs) <= 0 {
+			logger.Error("Skipping remote volumes for auto custom volume replication task due to no online members")
+		} else {
+			localMemberID := s.DB.Cluster.GetNodeID()
+			for _, v := range remoteVolumes {
+				if memberCount > 1 {
+					selectedMemberID, err := util.GetStableRandomInt64FromList(v.ID, onlineMemberIDs)
+					if err != nil {
+						logger.Error("Failed scheduling remote auto custom volume replication task", logger.Ctx{"volName": v.Name, "project": v.ProjectName, "pool": v.PoolName, "err": err})
+						continue
+					}
+
+					if localMemberID != selectedMemberID {
+						continue
+					}
+				}
+
+				volumes = append(volumes, v)
+			}
+		}
+
+		if len(volumes) == 0 {
+			return
+		}
+
+		opRun := func(ctx context.Context, op *operations.Operation) error {
+			for _, v := range volumes {
+				err := ctx.Err()
+				if err != nil {
+					return err // Stop if context is cancelled.
+				}
+
+				pool, err := storagePools.LoadByName(s, v.PoolName)
+				if err != nil {
+					logger.Error("Failed loading pool for scheduled custom volume replication", logger.Ctx{"volName": v.Name, "project": v.ProjectName, "pool": v.PoolName, "err": err})
+					continue
+				}
+
+				// A failed replication doesn't prevent the other volumes from being replicated.
+				err = storageVolumeReplicate(s, v.ProjectName, pool, v.Name, op)
+				if err != nil {
+					logger.Error("Failed scheduled custom volume replication", logger.Ctx{"volName": v.Name, "project": v.ProjectName, "pool": v.PoolName, "err": err})
+				}
+			}
+
+			return nil
+		}
+
+		args := operations.OperationArgs{
+			Type:    operationtype.VolumeReplicationsScheduled,
+			Class:   operations.OperationClassTask,
+			RunHook: opRun,
+		}
+
+		logger.Info("Replicating scheduled custom volumes")
+		op, err := operations.ScheduleServerOperation(s, args)
+		if err != nil {
+			logger.Error("Failed creating scheduled custom volume replication operation", logger.Ctx{"err": err})
+			return
+		}
+
+		err = op.Wait(ctx)
+		if err != nil {
+			logger.Error("Failed scheduled custom volume replications", logger.Ctx{"err": err})
+			return
+		}
+
+		logger.Info("Done replicating scheduled custom volumes")
+	}
+
+	return f, task.Every(time.Minute, task.SkipFirst)
+}
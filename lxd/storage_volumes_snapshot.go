@@ -442,7 +442,7 @@ func storagePoolVolumeSnapshotsTypeGet(d *Daemon, r *http.Request) response.Resp
 			vol.UsedBy = project.FilterUsedBy(r.Context(), s.Authorizer, volumeUsedBy)
 
 			snap := &api.StorageVolumeSnapshot{}
-			snap.Config = vol.Config
+			snap.Config = storagePools.VolumeConfigWithoutSecrets(vol.Config)
 			snap.Description = vol.Description
 			snap.Name = vol.Name
 			snap.CreatedAt = vol.CreatedAt
@@ -691,7 +691,7 @@ func storagePoolVolumeSnapshotTypeGet(d *Daemon, r *http.Request) response.Respo
 	}
 
 	snapshot := &api.StorageVolumeSnapshot{}
-	snapshot.Config = dbVolume.Config
+	snapshot.Config = storagePools.VolumeConfigWithoutSecrets(dbVolume.Config)
 	snapshot.Description = dbVolume.Description
 	snapshot.Name = snapshotName
 	snapshot.ExpiresAt = &expiry
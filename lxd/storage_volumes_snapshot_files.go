@@ -0,0 +1,536 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/lxd/lxd/auth"
+	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/db/operationtype"
+	"github.com/canonical/lxd/lxd/idmap"
+	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/lxd/project"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/state"
+	storagePools "github.com/canonical/lxd/lxd/storage"
+	storageDrivers "github.com/canonical/lxd/lxd/storage/drivers"
+	"github.com/canonical/lxd/lxd/storage/filesystem"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/revert"
+	"github.com/canonical/lxd/shared/version"
+)
+
+var storagePoolVolumeSnapshotTypeFilesCmd = APIEndpoint{
+	Path:        "storage-pools/{poolName}/volumes/{type}/{volumeName}/snapshots/{snapshotName}/files",
+	MetricsType: entity.TypeStoragePool,
+
+	Get:  APIEndpointAction{Handler: storagePoolVolumeSnapshotTypeFilesGet, AccessHandler: storagePoolVolumeTypeAccessHandler(entity.TypeStorageVolumeSnapshot, auth.EntitlementCanView)},
+	Post: APIEndpointAction{Handler: storagePoolVolumeSnapshotTypeFilesPost, AccessHandler: storagePoolVolumeTypeAccessHandler(entity.TypeStorageVolume, auth.EntitlementCanEdit)},
+}
+
+// storageVolumeFilesRoot is the root directory of the files of a mounted storage volume or snapshot.
+type storageVolumeFilesRoot struct {
+	// path is the directory holding the files.
+	path string
+
+	// idmap is the idmap the ownership of the files is shifted with on disk, nil if it isn't shifted.
+	idmap *idmap.IdmapSet
+
+	// unmount unmounts the volume or snapshot.
+	unmount func()
+}
+
+// storageVolumeFilesMount mounts a container, virtual machine or custom file system volume, or one of their
+// snapshots if volName is a snapshot name, and returns the root directory of its files.
+func storageVolumeFilesMount(s *state.State, pool storagePools.Pool, projectName string, volType dbCluster.StoragePoolVolumeType, volName string, op *operations.Operation) (*storageVolumeFilesRoot, error) {
+	switch volType {
+	case dbCluster.StoragePoolVolumeTypeCustom:
+		dbVolume, err := storagePools.VolumeDBGet(pool, projectName, volName, storageDrivers.VolumeTypeCustom)
+		if err != nil {
+			return nil, err
+		}
+
+		if dbVolume.ContentType != dbCluster.StoragePoolVolumeContentTypeNameFS {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Files can only be accessed on storage volumes of content type %q", dbCluster.StoragePoolVolumeContentTypeNameFS)
+		}
+
+		root := &storageVolumeFilesRoot{
+			path: storageDrivers.GetVolumeMountPath(pool.Name(), storageDrivers.VolumeTypeCustom, project.StorageVolume(projectName, volName)),
+		}
+
+		if shared.IsSnapshot(volName) {
+			_, err = pool.MountCustomVolumeSnapshot(projectName, volName, op)
+			root.unmount = func() { _, _ = pool.UnmountCustomVolumeSnapshot(projectName, volName, op) }
+		} else {
+			_, err = pool.MountCustomVolume(projectName, volName, op)
+			root.unmount = func() { _, _ = pool.UnmountCustomVolume(projectName, volName, op) }
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return root, nil
+	case dbCluster.StoragePoolVolumeTypeContainer:
+		inst, err := instance.LoadByProjectAndName(s, projectName, volName)
+		if err != nil {
+			return nil, err
+		}
+
+		root := &storageVolumeFilesRoot{path: inst.RootfsPath()}
+
+		root.idmap, err = inst.(instance.Container).DiskIdmap()
+		if err != nil {
+			return nil, fmt.Errorf("Failed getting disk idmap: %w", err)
+		}
+
+		if inst.IsSnapshot() {
+			_, err = pool.MountInstanceSnapshot(inst, op)
+			root.unmount = func() { _ = pool.UnmountInstanceSnapshot(inst, op) }
+		} else {
+			_, err = pool.MountInstance(inst, op)
+			root.unmount = func() { _ = pool.UnmountInstance(inst, op) }
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return root, nil
+	case dbCluster.StoragePoolVolumeTypeVM:
+		inst, err := instance.LoadByProjectAndName(s, projectName, volName)
+		if err != nil {
+			return nil, err
+		}
+
+		// The guest file system in the block volume is never mounted on the host as its content isn't trusted.
+		// Only the files of the filesystem volume of the virtual machine, holding its configuration and
+		// agent files, can be accessed. They are mounted read-only for snapshots.
+		root := &storageVolumeFilesRoot{path: inst.Path()}
+
+		if inst.IsSnapshot() {
+			_, err = pool.MountInstanceSnapshot(inst, op)
+			root.unmount = func() { _ = pool.UnmountInstanceSnapshot(inst, op) }
+		} else {
+			_, err = pool.MountInstance(inst, op)
+			root.unmount = func() { _ = pool.UnmountInstance(inst, op) }
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return root, nil
+	}
+
+	return nil, api.StatusErrorf(http.StatusBadRequest, "Files of storage volumes of type %q cannot be accessed", volType.String())
+}
+
+// storageVolumeFilesPath returns a path relative to the root directory of a storage volume.
+func storageVolumeFilesPath(path string) string {
+	path = strings.TrimPrefix(filepath.Clean("/"+path), "/")
+	if path == "" {
+		return "."
+	}
+
+	return path
+}
+
+// swagger:operation GET /1.0/storage-pools/{poolName}/volumes/{type}/{volumeName}/snapshots/{snapshotName}/files storage storage_pool_volumes_type_snapshot_files_get
+//
+//	Get a file from a storage volume snapshot
+//
+//	Gets the file content from a snapshot of a container, virtual machine or custom file system volume.
+//	For virtual machines, the files are those of their configuration volume, not of the guest file system.
+//	If it's a directory, a json list of files will be returned instead.
+//
+//	---
+//	produces:
+//	  - application/json
+//	  - application/octet-stream
+//	parameters:
+//	  - in: query
+//	    name: path
+//	    description: Path to the file
+//	    type: string
+//	    example: /etc/hosts
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: query
+//	    name: target
+//	    description: Cluster member name
+//	    type: string
+//	    example: lxd01
+//	responses:
+//	  "200":
+//	     description: Raw file or directory listing
+//	     headers:
+//	       X-LXD-uid:
+//	         description: File owner UID
+//	         schema:
+//	           type: integer
+//	       X-LXD-gid:
+//	         description: File owner GID
+//	         schema:
+//	           type: integer
+//	       X-LXD-mode:
+//	         description: Mode mask
+//	         schema:
+//	           type: integer
+//	       X-LXD-modified:
+//	         description: Last modified date
+//	         schema:
+//	           type: string
+//	       X-LXD-type:
+//	         description: Type of file (file, symlink or directory)
+//	         schema:
+//	           type: string
+//	     content:
+//	       application/octet-stream:
+//	         schema:
+//	           type: string
+//	           example: some-text
+//	       application/json:
+//	         schema:
+//	           type: array
+//	           items:
+//	             type: string
+//	           example: |-
+//	             [
+//	               "etc",
+//	               "home"
+//	             ]
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func storagePoolVolumeSnapshotTypeFilesGet(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	details, err := request.GetContextValue[storageVolumeDetails](r.Context(), ctxStorageVolumeDetails)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	snapshotName, err := url.PathUnescape(mux.Vars(r)["snapshotName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	effectiveProjectName, err := request.GetContextValue[string](r.Context(), request.CtxEffectiveProjectName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	path := r.FormValue("path")
+	if path == "" {
+		return response.BadRequest(errors.New("Missing path argument"))
+	}
+
+	// Forward if needed.
+	target := request.QueryParam(r, "target")
+	resp := forwardedResponseToNode(r.Context(), s, target)
+	if resp != nil {
+		return resp
+	}
+
+	resp = forwardedResponseIfVolumeIsRemote(r.Context(), s)
+	if resp != nil {
+		return resp
+	}
+
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	snapRoot, err := storageVolumeFilesMount(s, details.pool, effectiveProjectName, details.volumeType, details.volumeName+"/"+snapshotName, nil)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	reverter.Add(snapRoot.unmount)
+
+	root, err := os.OpenRoot(snapRoot.path)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	defer func() { _ = root.Close() }()
+
+	relPath := storageVolumeFilesPath(path)
+	stat, err := root.Lstat(relPath)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed accessing %q in storage volume snapshot %q: %w", path, snapshotName, err))
+	}
+
+	fileType := "file"
+	if stat.IsDir() {
+		fileType = "directory"
+	} else if stat.Mode()&os.ModeSymlink == os.ModeSymlink {
+		fileType = "symlink"
+	} else if !stat.Mode().IsRegular() {
+		return response.BadRequest(fmt.Errorf("Cannot retrieve %q as it isn't a file, symlink or directory", path))
+	}
+
+	// Report the ownership as seen from inside of the instance.
+	var uid, gid int64
+	sys, ok := stat.Sys().(*syscall.Stat_t)
+	if ok {
+		uid, gid = int64(sys.Uid), int64(sys.Gid)
+		if snapRoot.idmap != nil {
+			nsUID, nsGID := snapRoot.idmap.ShiftFromNs(uid, gid)
+			if nsUID >= 0 && nsGID >= 0 {
+				uid, gid = nsUID, nsGID
+			}
+		}
+	}
+
+	headers := map[string]string{
+		"X-LXD-uid":      strconv.FormatInt(uid, 10),
+		"X-LXD-gid":      strconv.FormatInt(gid, 10),
+		"X-LXD-mode":     fmt.Sprintf("%04o", stat.Mode().Perm()),
+		"X-LXD-modified": stat.ModTime().UTC().String(),
+		"X-LXD-type":     fileType,
+	}
+
+	switch fileType {
+	case "file":
+		file, err := root.Open(relPath)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		reverter.Add(func() { _ = file.Close() })
+
+		// The snapshot stays mounted until the file has been sent.
+		cleanup := reverter.Clone()
+		reverter.Success()
+
+		files := []response.FileResponseEntry{{
+			Identifier:   filepath.Base(relPath),
+			Filename:     filepath.Base(relPath),
+			File:         file,
+			FileSize:     stat.Size(),
+			FileModified: stat.ModTime(),
+			Cleanup:      cleanup.Fail,
+		}}
+
+		return response.FileResponse(files, headers)
+	case "symlink":
+		// Symlinks aren't resolved as their targets are relative to the root of the snapshot.
+		target, err := root.Readlink(relPath)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		files := []response.FileResponseEntry{{
+			Identifier:   filepath.Base(relPath),
+			Filename:     filepath.Base(relPath),
+			File:         strings.NewReader(target),
+			FileSize:     int64(len(target)),
+			FileModified: stat.ModTime(),
+		}}
+
+		return response.FileResponse(files, headers)
+	}
+
+	dir, err := root.Open(relPath)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	dirEnts, err := dir.Readdirnames(-1)
+	_ = dir.Close()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseHeaders(true, dirEnts, headers)
+}
+
+// swagger:operation POST /1.0/storage-pools/{poolName}/volumes/{type}/{volumeName}/snapshots/{snapshotName}/files storage storage_pool_volumes_type_snapshot_files_post
+//
+//	Restore files from a storage volume snapshot
+//
+//	Copies the given files and directories from a snapshot of a container or custom file system volume back
+//	into the volume, replacing their current version.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: query
+//	    name: target
+//	    description: Cluster member name
+//	    type: string
+//	    example: lxd01
+//	  - in: body
+//	    name: files
+//	    description: Files to restore
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/StorageVolumeSnapshotFilesPost"
+//	responses:
+//	  "202":
+//	    $ref: "#/responses/Operation"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func storagePoolVolumeSnapshotTypeFilesPost(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	details, err := request.GetContextValue[storageVolumeDetails](r.Context(), ctxStorageVolumeDetails)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	snapshotName, err := url.PathUnescape(mux.Vars(r)["snapshotName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	requestProjectName := request.ProjectParam(r)
+	effectiveProjectName, err := request.GetContextValue[string](r.Context(), request.CtxEffectiveProjectName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Forward if needed.
+	target := request.QueryParam(r, "target")
+	resp := forwardedResponseToNode(r.Context(), s, target)
+	if resp != nil {
+		return resp
+	}
+
+	resp = forwardedResponseIfVolumeIsRemote(r.Context(), s)
+	if resp != nil {
+		return resp
+	}
+
+	req := api.StorageVolumeSnapshotFilesPost{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if len(req.Paths) == 0 {
+		return response.BadRequest(errors.New("No paths to restore"))
+	}
+
+	if details.volumeType == dbCluster.StoragePoolVolumeTypeVM {
+		return response.BadRequest(errors.New("Files of virtual machine volumes cannot be restored"))
+	}
+
+	run := func(ctx context.Context, op *operations.Operation) error {
+		return storageVolumeRestoreFiles(s, details.pool, effectiveProjectName, details.volumeType, details.volumeName, snapshotName, req.Paths, op)
+	}
+
+	snapshotURL := api.NewURL().Path(version.APIVersion, "storage-pools", details.pool.Name(), "volumes", details.volumeTypeName, details.volumeName, "snapshots", snapshotName).Project(effectiveProjectName)
+
+	args := operations.OperationArgs{
+		ProjectName: requestProjectName,
+		Type:        operationtype.VolumeSnapshotFilesRestore,
+		Class:       operations.OperationClassTask,
+		RunHook:     run,
+		EntityURL:   snapshotURL,
+		Resources: map[entity.Type][]api.URL{
+			entity.TypeStorageVolumeSnapshot: {*snapshotURL},
+		},
+	}
+
+	op, err := operations.ScheduleUserOperationFromRequest(s, r, args)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// storageVolumeRestoreFiles copies the given paths from a volume snapshot back into the volume.
+func storageVolumeRestoreFiles(s *state.State, pool storagePools.Pool, projectName string, volType dbCluster.StoragePoolVolumeType, volName string, snapshotName string, paths []string, op *operations.Operation) error {
+	snapRoot, err := storageVolumeFilesMount(s, pool, projectName, volType, volName+"/"+snapshotName, op)
+	if err != nil {
+		return err
+	}
+
+	defer snapRoot.unmount()
+
+	volRoot, err := storageVolumeFilesMount(s, pool, projectName, volType, volName, op)
+	if err != nil {
+		return err
+	}
+
+	defer volRoot.unmount()
+
+	src, err := os.OpenRoot(snapRoot.path)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = src.Close() }()
+
+	dst, err := os.OpenRoot(volRoot.path)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = dst.Close() }()
+
+	// Keep the ownership seen from inside of the instance in case its idmap changed since the snapshot.
+	shift := func(uid int64, gid int64) (int64, int64) {
+		if snapRoot.idmap != nil {
+			uid, gid = snapRoot.idmap.ShiftFromNs(uid, gid)
+		}
+
+		if volRoot.idmap != nil {
+			uid, gid = volRoot.idmap.ShiftIntoNs(uid, gid)
+		}
+
+		return uid, gid
+	}
+
+	for _, path := range paths {
+		err = filesystem.CopyFromRoot(src, dst, storageVolumeFilesPath(path), shift)
+		if err != nil {
+			return fmt.Errorf("Failed restoring %q from storage volume snapshot %q: %w", path, snapshotName, err)
+		}
+
+		logger.Info("Restored file from storage volume snapshot", logger.Ctx{"project": projectName, "pool": pool.Name(), "volName": volName, "snapshot": snapshotName, "path": path})
+	}
+
+	return nil
+}
@@ -12,6 +12,7 @@ const (
 	EventTypeLogging   = "logging"
 	EventTypeOperation = "operation"
 	EventTypeOVN       = "ovn"
+	EventTypeSecurity  = "security"
 )
 
 // Event represents an event entry (over websocket)
@@ -118,6 +119,23 @@ func (event *Event) ToLogging() (EventLogRecord, error) {
 			},
 		}
 
+		return record, nil
+	case EventTypeSecurity:
+		e := &EventSecurity{}
+		err := json.Unmarshal(event.Metadata, &e)
+		if err != nil {
+			return EventLogRecord{}, err
+		}
+
+		record := EventLogRecord{
+			Time: event.Timestamp,
+			Lvl:  "warning",
+			Msg:  "Mechanism: " + e.Mechanism + ", Operation: " + e.Operation + ", Source: " + e.Source,
+			Ctx: []any{
+				"Message", e.Message,
+			},
+		}
+
 		return record, nil
 	}
 
@@ -139,6 +157,40 @@ type EventLogging struct {
 	Context map[string]string `yaml:"context" json:"context"`
 }
 
+// EventSecurity represents a security type event entry.
+// It reports an operation of an instance that its security profiles would have denied if they were enforced.
+//
+// API extension: instance_security_profile_complain.
+type EventSecurity struct {
+	// Security mechanism that reported the operation (seccomp or apparmor)
+	// Example: apparmor
+	Mechanism string `yaml:"mechanism" json:"mechanism"`
+
+	// Operation that would have been denied
+	// Example: mount
+	Operation string `yaml:"operation" json:"operation"`
+
+	// Kernel audit message
+	// Example: apparmor="ALLOWED" operation="mount" ...
+	Message string `yaml:"message" json:"message"`
+
+	// Number of identical operations not sent as events since the previous event
+	// Example: 0
+	Suppressed int `yaml:"suppressed" json:"suppressed"`
+
+	// Instance URL
+	// Example: /1.0/instances/c1
+	Source string `yaml:"source" json:"source"`
+
+	// Instance name
+	// Example: c1
+	Name string `yaml:"name" json:"name"`
+
+	// Instance project
+	// Example: default
+	Project string `yaml:"project" json:"project"`
+}
+
 // EventLifecycle represets a lifecycle type event entry
 //
 // API extension: event_lifecycle.
@@ -65,6 +65,7 @@ const (
 	EventLifecycleInstanceSnapshotUpdated           = "instance-snapshot-updated"
 	EventLifecycleInstanceStarted                   = "instance-started"
 	EventLifecycleInstanceStopped                   = "instance-stopped"
+	EventLifecycleInstanceSyscallIntercepted        = "instance-syscall-intercepted"
 	EventLifecycleInstanceUpdated                   = "instance-updated"
 	EventLifecycleNetworkACLCreated                 = "network-acl-created"
 	EventLifecycleNetworkACLDeleted                 = "network-acl-deleted"
@@ -100,6 +101,7 @@ const (
 	EventLifecycleProjectUpdated                    = "project-updated"
 	EventLifecycleStoragePoolCreated                = "storage-pool-created"
 	EventLifecycleStoragePoolDeleted                = "storage-pool-deleted"
+	EventLifecycleStoragePoolNearlyFull             = "storage-pool-nearly-full"
 	EventLifecycleStoragePoolUpdated                = "storage-pool-updated"
 	EventLifecycleStorageBucketCreated              = "storage-bucket-created"
 	EventLifecycleStorageBucketUpdated              = "storage-bucket-updated"
@@ -113,6 +115,7 @@ const (
 	EventLifecycleStorageVolumeBackupRenamed        = "storage-volume-backup-renamed"
 	EventLifecycleStorageVolumeBackupRetrieved      = "storage-volume-backup-retrieved"
 	EventLifecycleStorageVolumeDeleted              = "storage-volume-deleted"
+	EventLifecycleStorageVolumeNearlyFull           = "storage-volume-nearly-full"
 	EventLifecycleStorageVolumeRenamed              = "storage-volume-renamed"
 	EventLifecycleStorageVolumeRestored             = "storage-volume-restored"
 	EventLifecycleStorageVolumeSnapshotCreated      = "storage-volume-snapshot-created"
@@ -0,0 +1,56 @@
+package api
+
+// InstanceCheckpointsPost represents the fields available for a new LXD instance checkpoint.
+//
+// swagger:model
+//
+// API extension: instance_checkpoints.
+type InstanceCheckpointsPost struct {
+	// Checkpoint name
+	// Example: checkpoint0
+	Name string `json:"name" yaml:"name"`
+
+	// List of disk devices to track (defaults to all writable disks)
+	// Example: ["root", "data"]
+	Disks []string `json:"disks" yaml:"disks"`
+}
+
+// InstanceCheckpoint represents a LXD instance checkpoint.
+//
+// swagger:model
+//
+// API extension: instance_checkpoints.
+type InstanceCheckpoint struct {
+	// Checkpoint name
+	// Example: checkpoint0
+	Name string `json:"name" yaml:"name"`
+
+	// Disks tracked by the checkpoint, keyed by device name
+	Disks map[string]InstanceCheckpointDisk `json:"disks" yaml:"disks"`
+}
+
+// InstanceCheckpointDisk represents the changes tracked by a checkpoint on a disk.
+//
+// swagger:model
+//
+// API extension: instance_checkpoints.
+type InstanceCheckpointDisk struct {
+	// Number of bytes changed since the checkpoint was created
+	// Example: 1048576
+	DirtyBytes int64 `json:"dirty_bytes" yaml:"dirty_bytes"`
+
+	// Size in bytes of the blocks tracked by the checkpoint
+	// Example: 65536
+	Granularity int64 `json:"granularity" yaml:"granularity"`
+}
+
+// InstanceCheckpointExportPost represents the fields available to export the changes tracked by a checkpoint.
+//
+// swagger:model
+//
+// API extension: instance_checkpoints.
+type InstanceCheckpointExportPost struct {
+	// Disk device to export
+	// Example: root
+	Disk string `json:"disk" yaml:"disk"`
+}
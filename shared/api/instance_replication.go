@@ -0,0 +1,52 @@
+package api
+
+import (
+	"time"
+)
+
+// InstanceReplication represents the replication state of an instance
+//
+// swagger:model
+//
+// API extension: instance_replication.
+type InstanceReplication struct {
+	// Role of the instance (source or replica), empty if the instance isn't replicated
+	// Example: source
+	Role string `json:"role" yaml:"role"`
+
+	// Remote server at the other end of the replication, empty if it is this server or cluster
+	// (address of the target server for a source instance, certificate fingerprint of the source server for a replica)
+	// Example: https://10.0.0.2:8443
+	Remote string `json:"remote" yaml:"remote"`
+
+	// Storage pool of the replica
+	// Example: backup
+	Pool string `json:"pool" yaml:"pool"`
+
+	// Name of the instance at the other end of the replication
+	// Example: c1-replica
+	Instance string `json:"instance" yaml:"instance"`
+
+	// Replication schedule of the source instance
+	// Example: @hourly
+	Schedule string `json:"schedule" yaml:"schedule"`
+
+	// Time of the last successful synchronization
+	// Example: 2026-10-18T12:00:00Z
+	LastSync time.Time `json:"last_sync" yaml:"last_sync"`
+
+	// Number of seconds since the last successful synchronization (-1 if never synchronized)
+	// Example: 120
+	Lag int64 `json:"lag" yaml:"lag"`
+}
+
+// InstanceReplicationPost represents an action to run against the replication of an instance
+//
+// swagger:model
+//
+// API extension: instance_replication.
+type InstanceReplicationPost struct {
+	// Action to run (sync on a source instance, promote on a replica)
+	// Example: sync
+	Action string `json:"action" yaml:"action"`
+}
@@ -56,6 +56,11 @@ type InstanceState struct {
 
 	// CPU usage information
 	CPU InstanceStateCPU `json:"cpu" yaml:"cpu"`
+
+	// Pressure stall information (only available on cgroup2 hosts and guests)
+	//
+	// API extension: instances_state_pressure
+	Pressure *InstanceStatePressure `json:"pressure,omitempty" yaml:"pressure,omitempty"`
 }
 
 // InstanceStateDisk represents the disk information section of a LXD instance's state.
@@ -86,6 +91,58 @@ type InstanceStateCPU struct {
 	Usage int64 `json:"usage" yaml:"usage"`
 }
 
+// InstanceStatePressure represents the pressure stall information section of a LXD instance's state.
+//
+// swagger:model
+//
+// API extension: instances_state_pressure.
+type InstanceStatePressure struct {
+	// CPU pressure
+	CPU *InstanceStatePressureResource `json:"cpu,omitempty" yaml:"cpu,omitempty"`
+
+	// Memory pressure
+	Memory *InstanceStatePressureResource `json:"memory,omitempty" yaml:"memory,omitempty"`
+
+	// IO pressure
+	IO *InstanceStatePressureResource `json:"io,omitempty" yaml:"io,omitempty"`
+}
+
+// InstanceStatePressureResource represents the pressure stall information of a single resource.
+//
+// swagger:model
+//
+// API extension: instances_state_pressure.
+type InstanceStatePressureResource struct {
+	// Time during which at least some tasks were stalled on the resource
+	Some InstanceStatePressureValues `json:"some" yaml:"some"`
+
+	// Time during which all non-idle tasks were stalled on the resource
+	Full InstanceStatePressureValues `json:"full" yaml:"full"`
+}
+
+// InstanceStatePressureValues represents the stall averages and total stall time of a resource.
+//
+// swagger:model
+//
+// API extension: instances_state_pressure.
+type InstanceStatePressureValues struct {
+	// Percentage of time stalled over the last 10 seconds
+	// Example: 0.42
+	Avg10 float64 `json:"avg10" yaml:"avg10"`
+
+	// Percentage of time stalled over the last 60 seconds
+	// Example: 0.21
+	Avg60 float64 `json:"avg60" yaml:"avg60"`
+
+	// Percentage of time stalled over the last 300 seconds
+	// Example: 0.05
+	Avg300 float64 `json:"avg300" yaml:"avg300"`
+
+	// Total stall time in microseconds
+	// Example: 1829044
+	Total uint64 `json:"total" yaml:"total"`
+}
+
 // InstanceStateMemory represents the memory information section of a LXD instance's state.
 //
 // swagger:model
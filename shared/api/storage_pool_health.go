@@ -0,0 +1,54 @@
+package api
+
+// StoragePoolHealthStatusHealthy storage pool or check is healthy.
+const StoragePoolHealthStatusHealthy = "Healthy"
+
+// StoragePoolHealthStatusDegraded storage pool or check is degraded.
+const StoragePoolHealthStatusDegraded = "Degraded"
+
+// StoragePoolHealthStatusUnknown storage pool or check status could not be determined.
+const StoragePoolHealthStatusUnknown = "Unknown"
+
+// StoragePoolHealth represents the health of a storage pool on a cluster member
+//
+// swagger:model
+//
+// API extension: storage_pool_health.
+type StoragePoolHealth struct {
+	// Overall health status (Healthy, Degraded or Unknown)
+	// Example: Healthy
+	Status string `json:"status" yaml:"status"`
+
+	// Results of the individual driver checks
+	Checks []StoragePoolHealthCheck `json:"checks" yaml:"checks"`
+}
+
+// StoragePoolHealthCheck represents the result of a single storage pool health check
+//
+// swagger:model
+//
+// API extension: storage_pool_health.
+type StoragePoolHealthCheck struct {
+	// Name of the check
+	// Example: pool-state
+	Name string `json:"name" yaml:"name"`
+
+	// Status of the check (Healthy, Degraded or Unknown)
+	// Example: Healthy
+	Status string `json:"status" yaml:"status"`
+
+	// Details about the check result
+	// Example: ONLINE
+	Message string `json:"message" yaml:"message"`
+}
+
+// StoragePoolHealthPost represents an action to run against the storage pool health
+//
+// swagger:model
+//
+// API extension: storage_pool_health.
+type StoragePoolHealthPost struct {
+	// Action to run (scrub)
+	// Example: scrub
+	Action string `json:"action" yaml:"action"`
+}
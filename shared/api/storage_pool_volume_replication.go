@@ -0,0 +1,58 @@
+package api
+
+import (
+	"time"
+)
+
+// StorageVolumeReplicationRoleSource is the role of a custom volume that is replicated to another pool.
+const StorageVolumeReplicationRoleSource = "source"
+
+// StorageVolumeReplicationRoleReplica is the role of a custom volume that is kept in sync with a source volume.
+const StorageVolumeReplicationRoleReplica = "replica"
+
+// StorageVolumeReplication represents the replication state of a custom storage volume
+//
+// swagger:model
+//
+// API extension: storage_volume_replication.
+type StorageVolumeReplication struct {
+	// Role of the volume (source or replica), empty if the volume isn't replicated
+	// Example: source
+	Role string `json:"role" yaml:"role"`
+
+	// Remote server at the other end of the replication, empty if it is this server or cluster
+	// (address of the target server for a source volume, certificate fingerprint of the source server for a replica)
+	// Example: https://10.0.0.2:8443
+	Remote string `json:"remote" yaml:"remote"`
+
+	// Storage pool of the volume at the other end of the replication
+	// Example: backup
+	Pool string `json:"pool" yaml:"pool"`
+
+	// Name of the volume at the other end of the replication
+	// Example: data
+	Volume string `json:"volume" yaml:"volume"`
+
+	// Replication schedule of the source volume
+	// Example: @hourly
+	Schedule string `json:"schedule" yaml:"schedule"`
+
+	// Time of the last successful synchronization
+	// Example: 2026-10-18T12:00:00Z
+	LastSync time.Time `json:"last_sync" yaml:"last_sync"`
+
+	// Number of seconds since the last successful synchronization (-1 if never synchronized)
+	// Example: 120
+	Lag int64 `json:"lag" yaml:"lag"`
+}
+
+// StorageVolumeReplicationPost represents an action to run against the replication of a custom storage volume
+//
+// swagger:model
+//
+// API extension: storage_volume_replication.
+type StorageVolumeReplicationPost struct {
+	// Action to run (sync on a source volume, promote on a replica)
+	// Example: sync
+	Action string `json:"action" yaml:"action"`
+}
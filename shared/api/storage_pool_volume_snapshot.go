@@ -113,3 +113,53 @@ func (storageVolumeSnapshot *StorageVolumeSnapshot) SetWritable(put StorageVolum
 	storageVolumeSnapshot.Description = put.Description
 	storageVolumeSnapshot.ExpiresAt = put.ExpiresAt
 }
+
+// StorageVolumeSnapshotFilesPost represents the files to restore from a LXD storage volume snapshot
+//
+// swagger:model
+//
+// API extension: storage_volume_snapshot_files.
+type StorageVolumeSnapshotFilesPost struct {
+	// Paths of the files and directories to copy from the snapshot back into the volume
+	// Example: ["/etc/hosts", "/var/lib/app"]
+	Paths []string `json:"paths" yaml:"paths"`
+}
+
+// StorageVolumeDiffChangeAdded indicates that a path was added to a storage volume.
+const StorageVolumeDiffChangeAdded = "added"
+
+// StorageVolumeDiffChangeModified indicates that the content or metadata of a path of a storage volume was modified.
+const StorageVolumeDiffChangeModified = "modified"
+
+// StorageVolumeDiffChangeDeleted indicates that a path was deleted from a storage volume.
+const StorageVolumeDiffChangeDeleted = "deleted"
+
+// StorageVolumeDiffPost represents the snapshots to compare a LXD storage volume between
+//
+// swagger:model
+//
+// API extension: storage_volume_diff.
+type StorageVolumeDiffPost struct {
+	// Name of the snapshot to compare from
+	// Example: snap0
+	From string `json:"from" yaml:"from"`
+
+	// Name of the snapshot to compare to (the current state of the volume if empty)
+	// Example: snap1
+	To string `json:"to" yaml:"to"`
+}
+
+// StorageVolumeDiff represents a path that changed between two states of a LXD storage volume
+//
+// swagger:model
+//
+// API extension: storage_volume_diff.
+type StorageVolumeDiff struct {
+	// Path relative to the root of the volume
+	// Example: /etc/hosts
+	Path string `json:"path" yaml:"path"`
+
+	// Kind of change (added, modified or deleted)
+	// Example: modified
+	Change string `json:"change" yaml:"change"`
+}
@@ -0,0 +1,71 @@
+// Package psi parses the pressure stall information (PSI) reported by the Linux kernel.
+package psi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Stats represents the pressure stall information of a resource.
+type Stats struct {
+	Some Values
+	Full Values
+}
+
+// Values represents the stall averages (in percent) and total stall time (in microseconds).
+type Values struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	Total  uint64
+}
+
+// Parse parses the content of a cgroup2 or /proc/pressure PSI file.
+func Parse(content string) (*Stats, error) {
+	stats := &Stats{}
+
+	for line := range strings.SplitSeq(strings.TrimSpace(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var values *Values
+
+		switch fields[0] {
+		case "some":
+			values = &stats.Some
+		case "full":
+			values = &stats.Full
+		default:
+			return nil, fmt.Errorf("Unknown pressure line %q", line)
+		}
+
+		for _, field := range fields[1:] {
+			key, value, found := strings.Cut(field, "=")
+			if !found {
+				return nil, fmt.Errorf("Failed extracting pressure %q (from %q)", field, line)
+			}
+
+			var err error
+
+			switch key {
+			case "avg10":
+				values.Avg10, err = strconv.ParseFloat(value, 64)
+			case "avg60":
+				values.Avg60, err = strconv.ParseFloat(value, 64)
+			case "avg300":
+				values.Avg300, err = strconv.ParseFloat(value, 64)
+			case "total":
+				values.Total, err = strconv.ParseUint(value, 10, 64)
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("Failed parsing pressure %q %q (from %q): %w", key, value, line, err)
+			}
+		}
+	}
+
+	return stats, nil
+}
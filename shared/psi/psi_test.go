@@ -0,0 +1,30 @@
+package psi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	stats, err := Parse("some avg10=1.50 avg60=0.75 avg300=0.10 total=123456\nfull avg10=0.50 avg60=0.25 avg300=0.00 total=6543\n")
+	require.NoError(t, err)
+	assert.Equal(t, Values{Avg10: 1.5, Avg60: 0.75, Avg300: 0.1, Total: 123456}, stats.Some)
+	assert.Equal(t, Values{Avg10: 0.5, Avg60: 0.25, Avg300: 0, Total: 6543}, stats.Full)
+
+	// Older kernels don't report the "full" line for CPU.
+	stats, err = Parse("some avg10=0.00 avg60=0.00 avg300=0.00 total=42")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), stats.Some.Total)
+	assert.Equal(t, Values{}, stats.Full)
+
+	_, err = Parse("partial avg10=0.00")
+	assert.Error(t, err)
+
+	_, err = Parse("some avg10")
+	assert.Error(t, err)
+
+	_, err = Parse("some total=abc")
+	assert.Error(t, err)
+}
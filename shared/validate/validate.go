@@ -677,6 +677,15 @@ func IsX509Certificate(value string) error {
 	return err
 }
 
+// IsCertificateFingerprint checks if the value is a full SHA-256 certificate fingerprint.
+func IsCertificateFingerprint(value string) error {
+	if len(value) != 64 || strings.Trim(value, "0123456789abcdef") != "" {
+		return errors.New("Invalid certificate fingerprint, expected 64 lowercase hexadecimal characters")
+	}
+
+	return nil
+}
+
 // IsAbsFilePath checks if value is an absolute file path.
 func IsAbsFilePath(value string) error {
 	if !filepath.IsAbs(value) {
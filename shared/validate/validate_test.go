@@ -964,3 +964,26 @@ func TestIsHTTPSURL(t *testing.T) {
 		})
 	}
 }
+
+func TestIsCertificateFingerprint(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"valid fingerprint", "5c2eafe93ef5d2bd5a2ae8f4fa22a7a1fdd4f9bc7b0f71a3e0c7e7f3f1d0b2c4", false},
+		{"uppercase rejected", "5C2EAFE93EF5D2BD5A2AE8F4FA22A7A1FDD4F9BC7B0F71A3E0C7E7F3F1D0B2C4", true},
+		{"short fingerprint rejected", "5c2eafe93ef5", true},
+		{"non hexadecimal rejected", "zc2eafe93ef5d2bd5a2ae8f4fa22a7a1fdd4f9bc7b0f71a3e0c7e7f3f1d0b2c4", true},
+		{"empty fingerprint rejected", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.IsCertificateFingerprint(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("IsCertificateFingerprint(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -482,6 +482,33 @@ var APIExtensions = []string{
 	"storage_ceph_use_rbd_defaults",
 	"bulk_operations",
 	"ovn_dynamic_northbound_connection",
+	"instance_memory_hotplug",
+	"instance_memory_balloon_auto",
+	"instance_vm_numa_nodes",
+	"instance_checkpoints",
+	"instance_live_storage_move",
+	"container_syscall_intercept_audit",
+	"instance_security_profile_complain",
+	"disk_network_share_sources",
+	"disk_overlay",
+	"proxy_multiple_connect",
+	"instances_state_pressure",
+	"project_resource_pools",
+	"disk_io_limits_burst",
+	"instance_usb_redirection",
+	"devices_serial",
+	"storage_volume_encryption",
+	"storage_dir_reflink",
+	"storage_pool_health",
+	"storage_volume_replication",
+	"storage_usage_warnings",
+	"storage_volume_snapshot_files",
+	"storage_volume_diff",
+	"storage_driver_nfs",
+	"storage_driver_lvmcluster",
+	"storage_volume_inode_quota",
+	"projects_limits_disk_io",
+	"instance_replication",
 }
 
 // APIExtensionsCount returns the number of available API extensions.
@@ -78,8 +78,11 @@ readonly test_group_instance=(
     "exec"
     "exec_exit_code"
     "lxd_benchmark_basic"
+    "vm_checkpoints"
     "vm_empty"
+    "vm_live_storage_move"
     "vm_pcie_bus"
+    "vm_usbredir"
 )
 
 readonly test_group_image=(
@@ -225,6 +228,7 @@ readonly test_group_standalone_storage=(
     "projects_storage"
     "projects_backups"
     "projects_limits"
+    "projects_resource_pools"
     "projects_restrictions"
     "projects_images_volume"
     "projects_backups_volume"
@@ -235,11 +239,16 @@ readonly test_group_standalone_storage=(
     "snapshot_schedule"
     "snapshot_volume_db_recovery"
     "snapshot_fail"
+    "snapshot_files"
     "snapshot_multi_volume"
     "storage_volume_recover"
     "storage_volume_recover_by_container"
     "storage"
     "storage_volume_snapshots"
+    "storage_volume_diff"
+    "storage_volume_replication"
+    "instance_replication"
+    "replication_remote"
     "storage_local_volume_handling"
     "storage_profiles"
     "storage_volume_attach"
@@ -248,6 +257,9 @@ readonly test_group_standalone_storage=(
     "storage_driver_ceph"
     "storage_driver_cephfs"
     "storage_driver_dir"
+    "storage_driver_lvm"
+    "storage_driver_lvmcluster"
+    "storage_driver_nfs"
     "storage_driver_zfs"
     "storage_driver_pure"
     "storage_buckets"
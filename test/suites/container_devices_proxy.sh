@@ -7,6 +7,7 @@ test_container_devices_proxy() {
 
   container_devices_proxy_validation
   container_devices_proxy_tcp
+  container_devices_proxy_tcp_multiple
   container_devices_proxy_tcp_unix
   container_devices_proxy_tcp_udp
   container_devices_proxy_udp
@@ -56,6 +57,15 @@ container_devices_proxy_validation() {
     false
   fi
 
+  # Check multiple connect addresses must be interchangeable and aren't supported in NAT mode.
+  ! lxc config device add proxyTester proxyDev proxy "listen=tcp:127.0.0.1:$HOST_TCP_PORT" connect=tcp:127.0.0.1:4321,udp:127.0.0.1:4321 || false
+  ! lxc config device add proxyTester proxyDev proxy "listen=tcp:127.0.0.1:$HOST_TCP_PORT" connect=tcp:127.0.0.1:4321,tcp:127.0.0.2:4321-4322 || false
+  ! lxc config device add proxyTester proxyDev proxy "listen=unix:/tmp/proxy.sock" connect=unix:/tmp/a.sock,unix:/tmp/b.sock || false
+  ! lxc config device add proxyTester proxyDev proxy "listen=tcp:127.0.0.1:$HOST_TCP_PORT" connect=tcp:0.0.0.0:4321,tcp:0.0.0.0:4322 nat=true || false
+
+  # Check the PROXY protocol version requires the PROXY protocol.
+  ! lxc config device add proxyTester proxyDev proxy "listen=tcp:127.0.0.1:$HOST_TCP_PORT" connect=tcp:127.0.0.1:4321 proxy_protocol.version=2 || false
+
   # Check that old invalid config doesn't prevent device being stopped and removed cleanly.
   lxc config device add proxyTester proxyDev proxy "listen=tcp:127.0.0.1:$HOST_TCP_PORT" connect=tcp:127.0.0.1:4321 bind=host
   lxd sql global "UPDATE instances_devices_config SET value='tcp:localhost:4321' WHERE value='tcp:127.0.0.1:4321';"
@@ -290,6 +300,43 @@ container_devices_proxy_tcp() {
   lxc network delete lxdt$$
 }
 
+container_devices_proxy_tcp_multiple() {
+  echo "====> Testing tcp proxying to multiple targets"
+
+  # Setup
+  lxc launch testimage proxyTester
+  PID="$(lxc query /1.0/instances/proxyTester/state | jq .pid)"
+
+  nsenter -n -U -t "${PID}" -- socat tcp4-listen:4321,fork,reuseaddr system:"echo target1" &
+  NSENTER_PID1=$!
+  nsenter -n -U -t "${PID}" -- socat tcp4-listen:4322,fork,reuseaddr system:"echo target2" &
+  NSENTER_PID2=$!
+  lxc config device add proxyTester proxyDev proxy "listen=tcp:127.0.0.1:$HOST_TCP_PORT" connect=tcp:127.0.0.1:4321,tcp:127.0.0.1:4322 bind=host
+  sleep 0.1
+
+  # Check the connections are distributed between the targets.
+  ECHO1="$(socat -u tcp:127.0.0.1:"${HOST_TCP_PORT}" -)"
+  ECHO2="$(socat -u tcp:127.0.0.1:"${HOST_TCP_PORT}" -)"
+  if [ "${ECHO1}" = "${ECHO2}" ]; then
+    cat "${LXD_DIR}/logs/proxyTester/proxy.proxyDev.log"
+    echo "Proxy device did not distribute the connections between the targets"
+    false
+  fi
+
+  # Check the connections go to the remaining target when one is down.
+  kill "${NSENTER_PID1}" 2>/dev/null || true
+  wait "${NSENTER_PID1}" 2>/dev/null || true
+
+  for _ in 1 2 3; do
+    [ "$(socat -u tcp:127.0.0.1:"${HOST_TCP_PORT}" -)" = "target2" ]
+  done
+
+  # Cleanup
+  kill "${NSENTER_PID2}" 2>/dev/null || true
+  wait "${NSENTER_PID2}" 2>/dev/null || true
+  lxc delete -f proxyTester
+}
+
 container_devices_proxy_unix() {
   echo "====> Testing unix proxying"
 
@@ -27,6 +27,12 @@ test_metrics() {
   lxc query "/1.0/metrics" | grep -F 'name="c1"'
   lxc query "/1.0/metrics?project=default" | grep -F 'name="c1"'
 
+  if [ -e /sys/fs/cgroup/cgroup.controllers ] && [ -e /proc/pressure/cpu ]; then
+    echo "==> c1 pressure stall information should be reported on cgroup2 hosts"
+    lxc query "/1.0/metrics" | grep -F 'lxd_pressure_stall_seconds_total{kind="some",name="c1",project="default",resource="memory",type="container"}'
+    [ "$(lxc query /1.0/instances/c1/state | jq -r '.pressure.io.some.total')" != "null" ]
+  fi
+
   echo "==> c2 metrics should not be shown as the container is stopped"
   ! lxc query "/1.0/metrics" | grep -F 'name="c2"' || false
   ! lxc query "/1.0/metrics?project=default" | grep -F 'name="c2"' || false
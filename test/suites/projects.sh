@@ -780,6 +780,38 @@ test_projects_limits() {
   # not possible.
   ! lxc project set p1 limits.processes 125 || false
 
+  # Can't set the project's disk I/O limits because the root disks have no
+  # I/O limits defined.
+  ! lxc project set p1 limits.disk.iops 1000 || false
+  ! lxc project set p1 limits.disk.bandwidth 100MB || false
+
+  # Set an IOPS limit on the root disk of the default profile.
+  lxc profile device set default root limits.max=200iops
+
+  # Can't set the project's IOPS limit if it's below the current total.
+  ! lxc project set p1 limits.disk.iops 300 || false
+  lxc project set p1 limits.disk.iops 400
+
+  # Changing the instance I/O limits above the aggregate project's limit is
+  # not possible.
+  ! lxc config device override c2 root limits.max=300iops || false
+  lxc config device override c2 root limits.max=100iops
+
+  # Disks can limit their I/O in a single direction.
+  lxc config device unset c2 root limits.max
+  lxc config device set c2 root limits.read=100iops
+
+  # Byte/s limits can only be used if the project has a bandwidth limit.
+  ! lxc profile device set default root limits.max=10MB || false
+  lxc project set p1 limits.disk.bandwidth 20MB
+  lxc profile device set default root limits.max=10MB
+  ! lxc config device set c2 root limits.max=20MB || false
+
+  lxc config device remove c2 root
+  lxc project unset p1 limits.disk.iops
+  lxc project unset p1 limits.disk.bandwidth
+  lxc profile device unset default root limits.max
+
   # Set a cpu limit on the default profile and on the instance, with c2
   # using CPU pinning.
   lxc profile set default limits.cpu=2
@@ -1347,6 +1379,55 @@ VIRTUAL-MACHINES,UNLIMITED,0"
   lxc project delete test-usage
 }
 
+test_projects_resource_pools() {
+  ensure_import_testimage
+
+  lxc project create rp -c features.images=false
+  lxc profile show default --project default | lxc profile edit default --project rp
+
+  # Validation.
+  ! lxc project set rp resource_pools.web.limits.memory=foo || false
+  ! lxc project set rp resource_pools.web.limits.cpu.allowance=foo || false
+  ! lxc project set rp resource_pools.web.limits.foo=1 || false
+  ! lxc project set rp resource_pools.w@b.limits.memory=1GiB || false
+  lxc project set rp resource_pools.web.limits.memory=256MiB resource_pools.web.limits.cpu.allowance=50ms/100ms
+
+  lxc init testimage c1 --project rp -c limits.resource_pool=web -c limits.memory=128MiB
+  lxc init testimage c2 --project rp -c limits.resource_pool=web
+  lxc init testimage c3 --project rp -c limits.resource_pool=batch
+  ! lxc start c3 --project rp || false
+  lxc delete c3 --project rp
+
+  # Check the accounting.
+  lxc project info rp --format csv | grep -xF "INSTANCES (RESOURCE_POOL.WEB),UNLIMITED,2"
+  lxc project info rp --format csv | grep -xF "MEMORY (RESOURCE_POOL.WEB),256.00MiB,128.00MiB"
+
+  # A resource pool cannot be removed while in use.
+  lxc project unset rp resource_pools.web.limits.cpu.allowance
+  ! lxc project unset rp resource_pools.web.limits.memory || false
+
+  if [ -e /sys/fs/cgroup/cgroup.controllers ]; then
+    local cgroup_base
+    cgroup_base="/sys/fs/cgroup$(sed -n 's|^0::\(.*\)|\1|p' /proc/1/cgroup | sed 's|/init.scope$||')"
+    cgroup_base="${cgroup_base%/}"
+
+    lxc start c1 c2 --project rp
+    [ "$(cat "${cgroup_base}/lxd.pool.rp_web/memory.max")" = "268435456" ]
+    [ -d "${cgroup_base}/lxd.pool.rp_web/lxc.payload.rp_c1" ]
+    [ -d "${cgroup_base}/lxd.pool.rp_web/lxc.payload.rp_c2" ]
+
+    # Limit changes apply to the running members.
+    lxc project set rp resource_pools.web.limits.memory=512MiB
+    [ "$(cat "${cgroup_base}/lxd.pool.rp_web/memory.max")" = "536870912" ]
+
+    lxc stop -f c1 c2 --project rp
+  fi
+
+  lxc delete c1 c2 --project rp
+  lxc project unset rp resource_pools.web.limits.memory
+  lxc project delete rp
+}
+
 test_projects_yaml() {
   lxc project create test-project-yaml <<EOF
 config:
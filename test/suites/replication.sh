@@ -0,0 +1,162 @@
+test_storage_volume_replication() {
+  local pool
+  pool="lxdtest-$(basename "${LXD_DIR}")"
+
+  lxc storage create replicas dir
+
+  lxc storage volume create "${pool}" data
+  lxc storage volume snapshot "${pool}" data snap0
+
+  # Only replication sources can be synchronized.
+  ! lxc storage volume replication sync "${pool}" data || false
+  lxc storage volume replication show "${pool}" data | grep -xF "role: \"\""
+
+  # The first synchronization creates the replica with the snapshots of the volume.
+  lxc storage volume set "${pool}" data replication.target_pool=replicas user.foo=bar
+  lxc storage volume replication show "${pool}" data | grep -xF "role: source"
+  lxc storage volume replication show "${pool}" data | grep -xF "lag: -1"
+  lxc storage volume replication sync "${pool}" data
+  [ "$(lxc storage volume get replicas data volatile.replication.source)" = "${pool}/data" ]
+  [ "$(lxc storage volume get replicas data user.foo)" = "bar" ]
+  [ -z "$(lxc storage volume get replicas data replication.target_pool)" ]
+  lxc query /1.0/storage-pools/replicas/volumes/custom/data/snapshots | jq --exit-status 'length == 1'
+  lxc storage volume replication show replicas data | grep -xF "role: replica"
+  lxc storage volume replication show replicas data | grep -xF "pool: ${pool}"
+  [ "$(lxc storage volume replication show "${pool}" data | awk '/^lag:/ {print $2}')" -ge 0 ]
+
+  # Following synchronizations refresh the replica, including deleted snapshots.
+  lxc storage volume snapshot "${pool}" data snap1
+  lxc storage volume delete "${pool}" data/snap0
+  lxc storage volume replication sync "${pool}" data
+  lxc query /1.0/storage-pools/replicas/volumes/custom/data/snapshots | jq --exit-status 'length == 1 and (.[0] | endswith("/snap1"))'
+
+  # Volumes that aren't replicas of the source volume are never overwritten.
+  lxc storage volume create replicas other
+  lxc storage volume set "${pool}" data replication.target_volume=other
+  ! lxc storage volume replication sync "${pool}" data || false
+  [ -z "$(lxc storage volume get replicas other volatile.replication.source)" ]
+  lxc storage volume unset "${pool}" data replication.target_volume
+
+  # A promoted replica is detached from its source volume.
+  ! lxc storage volume replication promote "${pool}" data || false
+  lxc storage volume replication promote replicas data
+  [ -z "$(lxc storage volume get replicas data volatile.replication.source)" ]
+  lxc storage volume replication show replicas data | grep -xF "role: \"\""
+  ! lxc storage volume replication sync "${pool}" data || false
+
+  lxc storage volume delete replicas other
+  lxc storage volume delete replicas data
+  lxc storage volume delete "${pool}" data
+  lxc storage delete replicas
+}
+
+test_instance_replication() {
+  local uuid
+
+  ensure_import_testimage
+
+  lxc storage create replicas dir
+
+  lxc init testimage c1 -d "${SMALL_ROOT_DISK}" -c limits.memory=256MiB
+  lxc snapshot c1 snap0
+
+  # Replication options can't be set on profiles and replicas need their own name on the same server.
+  ! lxc profile set default replication.target_pool=replicas || false
+  lxc config set c1 replication.target_pool=replicas
+  ! lxc config replication sync c1 || false
+  lxc config set c1 replication.target_instance=c1-replica
+  lxc config replication show c1 | grep -xF "role: source"
+
+  # The first synchronization creates the replica with its root disk in the target pool.
+  lxc config replication sync c1
+  [ "$(lxc config get c1-replica volatile.replication.source)" = "c1" ]
+  [ "$(lxc config get c1-replica limits.memory)" = "256MiB" ]
+  [ "$(lxc config get c1-replica boot.autostart)" = "false" ]
+  [ -z "$(lxc config get c1-replica replication.target_pool)" ]
+  [ "$(lxc config device get c1-replica root pool)" = "replicas" ]
+  [ "$(lxc list -f csv -c S c1-replica)" = "1" ]
+  lxc config replication show c1-replica | grep -xF "role: replica"
+  lxc config replication show c1-replica | grep -xF "instance: c1"
+  [ -n "$(lxc config get c1 volatile.replication.last_sync)" ]
+
+  # Following synchronizations refresh the replica and keep its identity.
+  uuid="$(lxc config get c1-replica volatile.uuid)"
+  lxc snapshot c1 snap1
+  lxc config set c1 limits.memory=512MiB
+  lxc config replication sync c1
+  [ "$(lxc list -f csv -c S c1-replica)" = "2" ]
+  [ "$(lxc config get c1-replica limits.memory)" = "512MiB" ]
+  [ "$(lxc config get c1-replica volatile.uuid)" = "${uuid}" ]
+
+  # Running replicas and instances that aren't replicas of the source instance are never overwritten.
+  lxc start c1-replica
+  ! lxc config replication sync c1 || false
+  lxc stop -f c1-replica
+
+  lxc init testimage c2 -d "${SMALL_ROOT_DISK}"
+  lxc config set c1 replication.target_instance=c2
+  ! lxc config replication sync c1 || false
+  [ -z "$(lxc config get c2 volatile.replication.source)" ]
+  lxc config set c1 replication.target_instance=c1-replica
+
+  # A promoted replica is detached from its source instance.
+  ! lxc config replication promote c1 || false
+  lxc config replication promote c1-replica
+  [ -z "$(lxc config get c1-replica volatile.replication.source)" ]
+  ! lxc config replication sync c1 || false
+
+  lxc delete -f c1 c1-replica c2
+  lxc storage delete replicas
+}
+
+test_replication_remote() {
+  local LXD2_DIR LXD2_ADDR pool pool2 fingerprint
+  pool="lxdtest-$(basename "${LXD_DIR}")"
+
+  ensure_import_testimage
+
+  LXD2_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  spawn_lxd "${LXD2_DIR}" true
+  LXD2_ADDR=$(< "${LXD2_DIR}/lxd.addr")
+  pool2="lxdtest-$(basename "${LXD2_DIR}")"
+  fingerprint="$(LXD_DIR=${LXD2_DIR} lxc query /1.0 | jq -r .environment.certificate_fingerprint)"
+
+  lxc storage volume create "${pool}" data
+  lxc storage volume snapshot "${pool}" data snap0
+  lxc storage volume set "${pool}" data replication.target_pool="${pool2}" replication.target_remote="https://${LXD2_ADDR}"
+
+  # The remote certificate must be pinned and the remote must trust this server.
+  ! lxc storage volume replication sync "${pool}" data || false
+  lxc storage volume set "${pool}" data replication.target_remote_fingerprint="$(printf '%064d' 0)"
+  ! lxc storage volume replication sync "${pool}" data || false
+  lxc storage volume set "${pool}" data replication.target_remote_fingerprint="${fingerprint}"
+  ! lxc storage volume replication sync "${pool}" data || false
+  LXD_DIR=${LXD2_DIR} lxc config trust add "${LXD_DIR}/server.crt"
+
+  # Volumes are replicated to the remote and refreshed.
+  lxc storage volume replication sync "${pool}" data
+  LXD_DIR=${LXD2_DIR} lxc storage volume get "${pool2}" data volatile.replication.source | grep -E "^[0-9a-f]{64}:${pool}/data$"
+  lxc storage volume snapshot "${pool}" data snap1
+  lxc storage volume replication sync "${pool}" data
+  LXD_DIR=${LXD2_DIR} lxc query "/1.0/storage-pools/${pool2}/volumes/custom/data/snapshots" | jq --exit-status 'length == 2'
+  lxc storage volume replication show "${pool}" data | grep -xF "remote: https://${LXD2_ADDR}"
+  LXD_DIR=${LXD2_DIR} lxc storage volume replication show "${pool2}" data | grep -xF "volume: data"
+
+  # Instances are replicated to the remote with the same name.
+  lxc init testimage c1 -d "${SMALL_ROOT_DISK}"
+  lxc snapshot c1
+  lxc config set c1 replication.target_pool="${pool2}" replication.target_remote="https://${LXD2_ADDR}" replication.target_remote_fingerprint="${fingerprint}"
+  lxc config replication sync c1
+  lxc config replication sync c1
+  [ "$(LXD_DIR=${LXD2_DIR} lxc list -f csv -c S c1)" = "1" ]
+  [ "$(LXD_DIR=${LXD2_DIR} lxc config device get c1 root pool)" = "${pool2}" ]
+  LXD_DIR=${LXD2_DIR} lxc config replication show c1 | grep -xF "role: replica"
+
+  # Promoted replicas on the remote are never overwritten.
+  LXD_DIR=${LXD2_DIR} lxc config replication promote c1
+  ! lxc config replication sync c1 || false
+
+  lxc delete -f c1
+  lxc storage volume delete "${pool}" data
+  kill_lxd "$LXD2_DIR"
+}
@@ -520,6 +520,34 @@ test_snapshot_fail() {
   lxc delete --force c1
 }
 
+test_snapshot_files() {
+  ensure_import_testimage
+
+  echo "==> Files are pulled from an instance snapshot with --snapshot"
+  lxc launch testimage c1
+  lxc exec c1 -- sh -c "echo snap0 > /root/file"
+  lxc snapshot c1 snap0
+  lxc exec c1 -- sh -c "echo current > /root/file"
+  [ "$(lxc file pull c1/root/file - --snapshot snap0)" = "snap0" ]
+  [ "$(lxc file pull c1/root/file -)" = "current" ]
+  ! lxc file pull c1/root/file - --snapshot snap1 || false
+
+  echo "==> Paths starting with the name of a snapshot are paths of the instance"
+  lxc exec c1 -- mkdir -p /snap0/root
+  lxc exec c1 -- sh -c "echo instance > /snap0/root/file"
+  [ "$(lxc file pull c1/snap0/root/file -)" = "instance" ]
+  lxc delete -f c1
+
+  if [ "${LXD_VM_TESTS}" = "1" ]; then
+    echo "==> Files of virtual machine snapshots are those of their configuration volume"
+    lxc init --vm --empty v1 -c limits.memory=128MiB -d "${SMALL_ROOT_DISK}"
+    lxc snapshot v1 snap0
+    lxc file pull v1/backup.yaml - --snapshot snap0 | grep -xF "  name: v1"
+    ! lxc storage volume restore "lxdtest-$(basename "${LXD_DIR}")" virtual-machine/v1 snap0 --path backup.yaml || false
+    lxc delete -f v1
+  fi
+}
+
 test_snapshot_multi_volume() {
   ensure_import_testimage
 
@@ -8,6 +8,8 @@ test_storage_driver_dir() {
   fi
 
   do_dir_on_empty_fs
+  do_dir_reflink
+  do_dir_encryption
 
   if uname -r | grep -- -kvm$; then
     echo "==> SKIP: the -kvm kernel flavor is does not support XFS quotas (CONFIG_XFS_QUOTA is not set)"
@@ -34,6 +36,7 @@ do_dir_on_empty_fs() {
 
   # Create storage pool in the root path of the mounted filesystem where lost+found subdirectory exists.
   lxc storage create s1 dir source="${mount_point}"
+  [ "$(lxc storage get s1 volatile.reflink)" = "false" ]
   lxc storage delete s1
 
   # Create storage pool in the non-root path of the mounted filesystem where lost+found subdirectory exists.
@@ -50,6 +53,139 @@ do_dir_on_empty_fs() {
   deconfigure_loop_device "${tmp_file}" "${tmp_device}"
 }
 
+do_dir_encryption() {
+  if ! command -v cryptsetup >/dev/null; then
+    echo "==> SKIP: Skipping dir encryption tests as cryptsetup is missing"
+    return
+  fi
+
+  local pool pool_path
+  pool="lxdtest-$(basename "${LXD_DIR}")"
+  pool_path="${LXD_DIR}/storage-pools/${pool}"
+
+  echo "==> Only block volumes can be encrypted"
+  ! lxc storage volume create "${pool}" vol1 block.encryption=luks2 || false
+  ! lxc storage volume create "${pool}" vol1 --type=block block.encryption=luks1 || false
+  lxc storage volume create "${pool}" vol1 --type=block block.encryption=luks2 size=32MiB
+  ! lxc storage volume show "${pool}" vol1 | grep -F volatile.encryption.key || false
+  ! lxc storage volume set "${pool}" vol1 volatile.encryption.key=foo || false
+  ! lxc storage volume unset "${pool}" vol1 block.encryption || false
+
+  echo "==> The block file holds the LUKS2 header on top of the volume size"
+  cryptsetup isLuks "${pool_path}/custom/default_vol1/root.img"
+  [ "$(stat -c %s "${pool_path}/custom/default_vol1/root.img")" = "$((48 * 1024 * 1024))" ]
+
+  echo "==> Pool defaults only apply to block volumes"
+  lxc storage set "${pool}" volume.block.encryption=luks2
+  lxc storage volume create "${pool}" vol2
+  lxc storage volume create "${pool}" vol3 --type=block size=32MiB
+  [ "$(lxc storage volume get "${pool}" vol2 block.encryption)" = "" ]
+  [ "$(lxc storage volume get "${pool}" vol3 block.encryption)" = "luks2" ]
+  cryptsetup isLuks "${pool_path}/custom/default_vol3/root.img"
+  lxc storage unset "${pool}" volume.block.encryption
+
+  echo "==> Snapshots, copies and resized volumes stay encrypted"
+  lxc storage volume snapshot "${pool}" vol1 snap0
+  cryptsetup isLuks "${pool_path}/custom-snapshots/default_vol1/snap0/root.img"
+  lxc storage volume set "${pool}" vol1 size=64MiB
+  [ "$(stat -c %s "${pool_path}/custom/default_vol1/root.img")" = "$((80 * 1024 * 1024))" ]
+  cryptsetup isLuks "${pool_path}/custom/default_vol1/root.img"
+  ! lxc storage volume set "${pool}" vol1 size=32MiB || false
+  lxc storage volume copy "${pool}/vol1" "${pool}/vol1-copy"
+  cryptsetup isLuks "${pool_path}/custom/default_vol1-copy/root.img"
+  lxc storage volume restore "${pool}" vol1 snap0
+  cryptsetup isLuks "${pool_path}/custom/default_vol1/root.img"
+
+  lxc storage volume delete "${pool}" vol1-copy
+  lxc storage volume delete "${pool}" vol1
+  lxc storage volume delete "${pool}" vol2
+  lxc storage volume delete "${pool}" vol3
+
+  # No decrypted device is left behind.
+  ! ls /dev/mapper/lxd-luks-* || false
+}
+
+do_dir_reflink() {
+  if ! command -v filefrag >/dev/null; then
+    echo "==> SKIP: Skipping dir reflink tests as filefrag is missing"
+    return
+  fi
+
+  echo "==> Create and mount a small XFS filesystem with reflinks."
+
+  # XFS filesystem must be larger than 300MB.
+  configure_loop_device tmp_file tmp_device 300M
+  # shellcheck disable=SC2154
+  if ! mkfs.xfs -m reflink=1 "${tmp_device}"; then
+    echo "==> SKIP: Skipping dir reflink tests as XFS reflinks are not supported"
+    deconfigure_loop_device "${tmp_file}" "${tmp_device}"
+    return
+  fi
+
+  mount_point="$(mktemp -d -p "${TEST_DIR}" mountpoint.XXX)"
+  mount "${tmp_device}" "${mount_point}"
+
+  # Check that the file shares its extents with another file.
+  shared_extents() {
+    filefrag -v "${1}" | grep -qw shared
+  }
+
+  echo "==> Reflink support is detected and can't be changed."
+  lxc storage create reflink_pool dir source="${mount_point}"
+  [ "$(lxc storage get reflink_pool volatile.reflink)" = "true" ]
+  ! lxc storage set reflink_pool volatile.reflink=false || false
+  ! lxc storage unset reflink_pool volatile.reflink || false
+  [ "$(lxc storage get reflink_pool volatile.reflink)" = "true" ]
+
+  echo "==> Images are stored as volumes and instances are cloned from them."
+  ensure_import_testimage
+  lxc launch testimage c1 -s reflink_pool
+  fingerprint="$(lxc config get c1 volatile.base_image)"
+  [ -d "${mount_point}/images/${fingerprint}" ]
+  shared_extents "${mount_point}/containers/c1/rootfs/bin/busybox"
+
+  echo "==> Instance snapshots and copies are cloned."
+  lxc snapshot c1 snap0
+  shared_extents "${mount_point}/containers-snapshots/c1/snap0/rootfs/bin/busybox"
+  lxc copy c1 c2
+  shared_extents "${mount_point}/containers/c2/rootfs/bin/busybox"
+  shared_extents "${mount_point}/containers-snapshots/c2/snap0/rootfs/bin/busybox"
+  lxc restore c1 snap0
+  lxc start c2
+  lxc delete -f c1 c2
+
+  echo "==> Custom volume copies keep their snapshots and are cloned."
+  lxc storage volume create reflink_pool vol1
+  dd if=/dev/urandom of="${mount_point}/custom/default_vol1/file" bs=1M count=1
+  lxc storage volume snapshot reflink_pool vol1 snap0
+  shared_extents "${mount_point}/custom-snapshots/default_vol1/snap0/file"
+  lxc storage volume copy reflink_pool/vol1 reflink_pool/vol2
+  shared_extents "${mount_point}/custom/default_vol2/file"
+  shared_extents "${mount_point}/custom-snapshots/default_vol2/snap0/file"
+  cmp "${mount_point}/custom/default_vol1/file" "${mount_point}/custom/default_vol2/file"
+
+  echo "==> Block volume snapshots are restored from clones."
+  lxc storage volume create reflink_pool vol3 --type=block size=16MiB
+  dd if=/dev/urandom of="${mount_point}/custom/default_vol3/root.img" bs=1M count=1 conv=notrunc
+  sum="$(sha256sum "${mount_point}/custom/default_vol3/root.img" | cut -d' ' -f1)"
+  lxc storage volume snapshot reflink_pool vol3 snap0
+  shared_extents "${mount_point}/custom-snapshots/default_vol3/snap0/root.img"
+  dd if=/dev/zero of="${mount_point}/custom/default_vol3/root.img" bs=1M count=1 conv=notrunc
+  lxc storage volume restore reflink_pool vol3 snap0
+  [ "$(sha256sum "${mount_point}/custom/default_vol3/root.img" | cut -d' ' -f1)" = "${sum}" ]
+  shared_extents "${mount_point}/custom/default_vol3/root.img"
+
+  lxc storage volume delete reflink_pool vol1
+  lxc storage volume delete reflink_pool vol2
+  lxc storage volume delete reflink_pool vol3
+  lxc storage delete reflink_pool
+
+  echo "==> Cleanup the loopback file."
+  umount "${mount_point}"
+  rmdir "${mount_point}"
+  deconfigure_loop_device "${tmp_file}" "${tmp_device}"
+}
+
 do_dir_xfs_project_quotas() {
   echo "==> Create and mount a small XFS filesystem with project quotas."
 
@@ -92,6 +228,22 @@ do_dir_xfs_project_quotas() {
      return 1
   fi
 
+  echo "==> Limit the number of inodes of a custom volume."
+  ! lxc storage volume create xfs_pool vol1 --type=block size.inodes=100 || false
+  lxc storage volume create xfs_pool vol1 size.inodes=100
+  vol_project_id=$(lsattr -d -p "${mount_point}/custom/default_vol1" | awk '{print $1}')
+  project_inode_quota=$(xfs_quota -x -c 'report -i -n' "${mount_point}" | awk -v id="#${vol_project_id}" '$1 == id {print $4}')
+  [ "${project_inode_quota}" = "100" ]
+
+  lxc storage volume set xfs_pool vol1 size.inodes=200
+  project_inode_quota=$(xfs_quota -x -c 'report -i -n' "${mount_point}" | awk -v id="#${vol_project_id}" '$1 == id {print $4}')
+  [ "${project_inode_quota}" = "200" ]
+
+  lxc storage volume attach xfs_pool vol1 foo /mnt
+  ! lxc exec foo -- sh -c 'for i in $(seq 300); do touch /mnt/file$i || exit 1; done' || false
+  lxc storage volume detach xfs_pool vol1 foo
+  lxc storage volume delete xfs_pool vol1
+
   echo "==> Delete the container."
   lxc delete foo --force
 
@@ -0,0 +1,163 @@
+test_storage_driver_lvm() {
+  local lxd_backend
+
+  lxd_backend=$(storage_backend "${LXD_DIR}")
+  if [ "${lxd_backend}" != "lvm" ]; then
+    export TEST_UNMET_REQUIREMENT="lvm specific test, not for ${lxd_backend}"
+    return
+  fi
+
+  do_lvm_encryption
+  do_lvm_thinpool_autoextend
+}
+
+do_lvm_encryption() {
+  if ! command -v cryptsetup >/dev/null; then
+    echo "==> SKIP: Skipping LVM encryption tests as cryptsetup is missing"
+    return
+  fi
+
+  local pool pool2
+  pool="lxdtest-$(basename "${LXD_DIR}")"
+  pool2="lxdtest-$(basename "${LXD_DIR}")-encrypted"
+
+  # Check that the volume is opened through its LUKS2 decrypted device.
+  luks_opened() {
+    cryptsetup status "lxd-luks-${1}-${2}" | grep -q "type: *LUKS2"
+  }
+
+  ensure_import_testimage
+
+  echo "==> Create encrypted custom volumes"
+  lxc storage volume create "${pool}" vol1 block.encryption=luks2 size=32MiB
+  lxc storage volume create "${pool}" vol2 --type=block block.encryption=luks2 size=32MiB
+  ! lxc storage volume create "${pool}" vol3 block.encryption=luks1 || false
+
+  echo "==> The key is never returned and can't be changed"
+  ! lxc storage volume show "${pool}" vol1 | grep -F volatile.encryption.key || false
+  ! lxc query "/1.0/storage-pools/${pool}/volumes?recursion=1" | grep -F volatile.encryption.key || false
+  lxc storage volume set "${pool}" vol1 user.foo=bar
+  ! lxc storage volume set "${pool}" vol1 volatile.encryption.key=foo || false
+  ! lxc storage volume unset "${pool}" vol1 block.encryption || false
+
+  echo "==> The data is kept across activations"
+  lxc launch testimage c1 -d "${SMALL_ROOT_DISK}"
+  lxc storage volume attach "${pool}" vol1 c1 /mnt
+  luks_opened "${pool}" custom_default_vol1
+  lxc exec c1 -- sh -c "echo encrypted > /mnt/file"
+  lxc restart -f c1
+  [ "$(lxc exec c1 -- cat /mnt/file)" = "encrypted" ]
+  lxc storage volume detach "${pool}" vol1 c1
+
+  echo "==> Copies are encrypted and keep the data"
+  lxc storage volume copy "${pool}/vol1" "${pool}/vol1-copy"
+  lxc storage create "${pool2}" lvm size=1GiB volume.block.encryption=luks2
+  lxc storage volume copy "${pool}/vol1" "${pool2}/vol1"
+
+  echo "==> Backups restore encrypted volumes"
+  lxc storage volume export "${pool}" vol1 "${TEST_DIR}/vol1.tar.gz"
+  lxc storage volume import "${pool}" "${TEST_DIR}/vol1.tar.gz" vol1-import
+  rm "${TEST_DIR}/vol1.tar.gz"
+
+  lxc storage volume attach "${pool}" vol1-copy c1 /mnt/copy
+  lxc storage volume attach "${pool2}" vol1 c1 /mnt/pool2
+  lxc storage volume attach "${pool}" vol1-import c1 /mnt/import
+  [ "$(lxc exec c1 -- cat /mnt/copy/file)" = "encrypted" ]
+  [ "$(lxc exec c1 -- cat /mnt/pool2/file)" = "encrypted" ]
+  [ "$(lxc exec c1 -- cat /mnt/import/file)" = "encrypted" ]
+  luks_opened "${pool}" custom_default_vol1--copy
+  luks_opened "${pool2}" custom_default_vol1
+  luks_opened "${pool}" custom_default_vol1--import
+  lxc delete -f c1
+
+  echo "==> Instance volumes use the encryption of the pool"
+  lxc launch testimage c2 -s "${pool2}"
+  luks_opened "${pool2}" containers_c2
+  lxc delete -f c2
+
+  lxc storage volume delete "${pool}" vol1
+  lxc storage volume delete "${pool}" vol1-copy
+  lxc storage volume delete "${pool}" vol1-import
+  lxc storage volume delete "${pool}" vol2
+  lxc storage volume delete "${pool2}" vol1
+  lxc storage delete "${pool2}"
+
+  # No decrypted device is left behind.
+  ! ls /dev/mapper/lxd-luks-* || false
+}
+
+do_lvm_thinpool_autoextend() {
+  local loop_file loop_device vg pool thinpool_size monitor_pid
+  vg="lxdtest-$(basename "${LXD_DIR}")-autoextend"
+  pool="${vg}"
+
+  # Thin pool usage in percent.
+  thinpool_usage() {
+    lvs --noheadings -o data_percent "${vg}/thinpool" | awk '{print int($1)}'
+  }
+
+  # Number of warnings of a type with a given status.
+  warning_count() {
+    lxc query "/1.0/warnings?recursion=1" | jq --raw-output --arg type "${1}" --arg status "${2}" '[.[] | select(.type == $type and .status == $status)] | length'
+  }
+
+  ensure_import_testimage
+
+  echo "==> Create a volume group with free space around a small thin pool"
+  configure_loop_device loop_file loop_device 512M
+  vgcreate "${vg}" "${loop_device}"
+  lvcreate --type thin-pool --size 64M --name thinpool "${vg}"
+  thinpool_size="$(lvs --noheadings --units b --nosuffix -o lv_size "${vg}/thinpool" | xargs)"
+
+  lxc storage create "${pool}" lvm source="${vg}" lvm.thinpool_name=thinpool
+  ! lxc storage set "${pool}" lvm.thinpool_autoextend_threshold=100 || false
+  ! lxc storage set "${pool}" lvm.use_thinpool=false lvm.thinpool_autoextend_threshold=50 || false
+
+  echo "==> Fill the instance volume above its warning threshold"
+  lxc launch testimage c1 -s "${pool}" -d "${SMALL_ROOT_DISK}"
+  lxc storage volume set "${pool}" container/c1 warning_threshold=60
+  lxc exec c1 -- dd if=/dev/zero of=/root/fill bs=1M count=24
+  lxc exec c1 -- sync
+
+  echo "==> The usage check raises a warning but doesn't extend the thin pool without threshold"
+  lxc monitor --type=lifecycle --format json > "${TEST_DIR}/usage-events.jsonl" &
+  monitor_pid=$!
+  sleep 0.1
+  lxc query -X POST /internal/testing/storage-usage-task
+  [ "$(warning_count "Storage volume nearly full" new)" = "1" ]
+  lxc query "/1.0/warnings?recursion=1" | jq --exit-status '[.[] | select(.type == "Storage volume nearly full")][0].last_message | startswith("Volume \"c1\" of type \"container\"")'
+
+  echo "==> A lifecycle event is only emitted when the usage crosses the threshold"
+  lxc query -X POST /internal/testing/storage-usage-task
+  kill_go_proc "${monitor_pid}" || true
+  [ "$(jq --slurp '[.[] | select(.metadata.action == "storage-volume-nearly-full")] | length' "${TEST_DIR}/usage-events.jsonl")" = "1" ]
+  jq --exit-status --slurp '[.[] | select(.metadata.action == "storage-volume-nearly-full")][0].metadata.source == "/1.0/storage-pools/'"${pool}"'/volumes/container/c1"' "${TEST_DIR}/usage-events.jsonl"
+  rm "${TEST_DIR}/usage-events.jsonl"
+  [ "$(lvs --noheadings --units b --nosuffix -o lv_size "${vg}/thinpool" | xargs)" = "${thinpool_size}" ]
+
+  echo "==> The thin pool is extended once its usage reaches the threshold"
+  lxc storage set "${pool}" lvm.thinpool_autoextend_threshold="$(( $(thinpool_usage) - 1 ))" lvm.thinpool_autoextend_percent=50
+  lxc query -X POST /internal/testing/storage-usage-task
+  [ "$(lvs --noheadings --units b --nosuffix -o lv_size "${vg}/thinpool" | xargs)" -gt "${thinpool_size}" ]
+  [ "$(warning_count "Storage pool extension failed" new)" = "0" ]
+
+  echo "==> The warnings are resolved once the usage is back below the thresholds"
+  lxc exec c1 -- rm /root/fill
+  lxc exec c1 -- sync
+  lxc storage set "${pool}" lvm.thinpool_autoextend_threshold=99
+  lxc query -X POST /internal/testing/storage-usage-task
+  [ "$(warning_count "Storage volume nearly full" new)" = "0" ]
+  [ "$(warning_count "Storage volume nearly full" resolved)" = "1" ]
+
+  lxc delete -f c1
+  lxc storage delete "${pool}"
+  lxc warning delete --all
+
+  # The volume group is only left behind if LXD didn't remove it with the pool.
+  if vgs "${vg}" >/dev/null 2>&1; then
+    vgremove -f "${vg}"
+  fi
+
+  pvremove -f "${loop_device}" || true
+  deconfigure_loop_device "${loop_file}" "${loop_device}"
+}
@@ -0,0 +1,122 @@
+test_storage_driver_lvmcluster() {
+  if ! lvmlockctl --info >/dev/null 2>&1; then
+    export TEST_UNMET_REQUIREMENT="Requires a running 'lvmlockd'"
+    return
+  fi
+
+  local tested=0
+
+  if command -v targetcli >/dev/null && command -v iscsiadm >/dev/null; then
+    do_lvmcluster_iscsi
+    tested=1
+  fi
+
+  if command -v nvme >/dev/null && modprobe nvmet-tcp 2>/dev/null && modprobe nvme-tcp 2>/dev/null; then
+    do_lvmcluster_nvme
+    tested=1
+  fi
+
+  if [ "${tested}" = "0" ]; then
+    export TEST_UNMET_REQUIREMENT="Requires 'targetcli' and 'iscsiadm' or 'nvme' and the 'nvmet-tcp' kernel module"
+  fi
+}
+
+do_lvmcluster_iscsi() {
+  local target_qn="iqn.2024-01.io.lxd.test:lvmcluster"
+  local device="/dev/disk/by-path/ip-127.0.0.1:3260-iscsi-${target_qn}-lun-0"
+  local backing_file
+  backing_file="$(mktemp -p "${TEST_DIR}" lvmcluster.XXX)"
+  truncate -s 2GiB "${backing_file}"
+
+  echo "==> Export a file backed LUN from a local iSCSI target."
+  targetcli /backstores/fileio create name=lvmcluster file_or_dev="${backing_file}"
+  targetcli /iscsi create "${target_qn}"
+  targetcli "/iscsi/${target_qn}/tpg1/luns" create /backstores/fileio/lvmcluster
+  targetcli "/iscsi/${target_qn}/tpg1" set attribute authentication=0 demo_mode_write_protect=0 generate_node_acls=1 cache_dynamic_acls=1
+
+  do_lvmcluster_pool lvmcluster-iscsi iscsi "${target_qn}" "${device}"
+  ! iscsiadm -m session 2>/dev/null | grep -F "${target_qn}" || false
+
+  targetcli /iscsi delete "${target_qn}"
+  targetcli /backstores/fileio delete lvmcluster
+  rm "${backing_file}"
+}
+
+do_lvmcluster_nvme() {
+  local target_qn="nqn.2024-01.io.lxd.test:lvmcluster"
+  local uuid="6d1e6bbc-3e7a-4b36-9c49-5c6e0e4d2f10"
+  local device="/dev/disk/by-id/nvme-uuid.${uuid}"
+  local nvmet="/sys/kernel/config/nvmet"
+  local backing_file loop_device
+  configure_loop_device backing_file loop_device 2GiB
+
+  echo "==> Export a loop device backed namespace from a local NVMe/TCP target."
+  mkdir "${nvmet}/subsystems/${target_qn}"
+  echo 1 > "${nvmet}/subsystems/${target_qn}/attr_allow_any_host"
+  mkdir "${nvmet}/subsystems/${target_qn}/namespaces/1"
+  echo "${loop_device}" > "${nvmet}/subsystems/${target_qn}/namespaces/1/device_path"
+  echo "${uuid}" > "${nvmet}/subsystems/${target_qn}/namespaces/1/device_uuid"
+  echo 1 > "${nvmet}/subsystems/${target_qn}/namespaces/1/enable"
+  mkdir "${nvmet}/ports/1"
+  echo tcp > "${nvmet}/ports/1/addr_trtype"
+  echo ipv4 > "${nvmet}/ports/1/addr_adrfam"
+  echo 127.0.0.1 > "${nvmet}/ports/1/addr_traddr"
+  echo 4420 > "${nvmet}/ports/1/addr_trsvcid"
+  ln -s "${nvmet}/subsystems/${target_qn}" "${nvmet}/ports/1/subsystems/${target_qn}"
+
+  do_lvmcluster_pool lvmcluster-nvme nvme "${target_qn}" "${device}"
+  ! nvme list-subsys 2>/dev/null | grep -F "${target_qn}" || false
+
+  rm "${nvmet}/ports/1/subsystems/${target_qn}"
+  rmdir "${nvmet}/ports/1"
+  echo 0 > "${nvmet}/subsystems/${target_qn}/namespaces/1/enable"
+  rmdir "${nvmet}/subsystems/${target_qn}/namespaces/1"
+  rmdir "${nvmet}/subsystems/${target_qn}"
+  deconfigure_loop_device "${backing_file}" "${loop_device}"
+}
+
+# do_lvmcluster_pool runs the lvmcluster pool checks against a shared LUN reached through the given connector.
+do_lvmcluster_pool() {
+  local pool="${1}"
+  local connector="${2}"
+  local target_qn="${3}"
+  local device="${4}"
+
+  # Connector settings are validated.
+  ! lxc storage create "${pool}" lvmcluster source="${device}" lvm.connector="${connector}" || false
+  ! lxc storage create "${pool}" lvmcluster source="${device}" lvm.connector=foo lvm.connector.target=127.0.0.1 lvm.connector.target_qn="${target_qn}" || false
+
+  # Thin pools and sizes are refused.
+  ! lxc storage create "${pool}" lvmcluster source="${device}" lvm.use_thinpool=true lvm.connector="${connector}" lvm.connector.target=127.0.0.1 lvm.connector.target_qn="${target_qn}" || false
+  ! lxc storage create "${pool}" lvmcluster source="${device}" size=1GiB lvm.connector="${connector}" lvm.connector.target=127.0.0.1 lvm.connector.target_qn="${target_qn}" || false
+
+  echo "==> Create a shared volume group on the LUN."
+  lxc storage create "${pool}" lvmcluster source="${device}" lvm.connector="${connector}" lvm.connector.target=127.0.0.1 lvm.connector.target_qn="${target_qn}"
+  [ "$(lxc storage get "${pool}" source)" = "${pool}" ]
+  [ "$(vgs --noheadings -o vg_shared "${pool}" | xargs)" = "shared" ]
+  vgs --noheadings -o vg_tags "${pool}" | grep -wF lxd_pool
+  ! lxc storage set "${pool}" lvm.connector.target_qn="${target_qn}-other" || false
+  lxc storage info "${pool}"
+
+  echo "==> Logical volumes are only active while in use."
+  lxc storage volume create "${pool}" vol1 size=64MiB
+  [ -z "$(lvs --noheadings -o lv_active "${pool}/custom_default_vol1" | xargs)" ]
+  lxc storage volume snapshot "${pool}" vol1 snap0
+  lxc storage volume restore "${pool}" vol1 snap0
+  lxc storage volume delete "${pool}" vol1
+
+  lxc storage volume create "${pool}" vol1 --type=block size=64MiB
+  lxc storage volume set "${pool}" vol1 size=128MiB
+  [ "$(lvs --noheadings --units b --nosuffix -o lv_size "${pool}/custom_default_vol1.block" | xargs)" = "134217728" ]
+  lxc storage volume delete "${pool}" vol1
+
+  echo "==> Containers are stored on the shared volume group."
+  ensure_import_testimage
+  lxc init testimage c1 --storage "${pool}"
+  lvs "${pool}/containers_c1"
+  lxc snapshot c1
+  lxc delete c1
+
+  echo "==> Deleting the pool keeps the volume group and disconnects from the target."
+  lxc storage delete "${pool}"
+}
@@ -0,0 +1,54 @@
+test_storage_driver_nfs() {
+  if ! command -v exportfs >/dev/null || [ ! -e /proc/fs/nfsd/versions ]; then
+    export TEST_UNMET_REQUIREMENT="Requires a running kernel NFS server and 'exportfs'"
+    return
+  fi
+
+  echo "==> Export an empty directory from the local kernel NFS server."
+  local export_path
+  export_path="$(mktemp -d -p "${TEST_DIR}" nfs.XXX)"
+  exportfs -o rw,sync,no_subtree_check,no_root_squash,fsid="$(shuf -i 1000-65000 -n 1)" "127.0.0.1:${export_path}"
+
+  # Missing or invalid export settings.
+  ! lxc storage create nfs nfs nfs.path="${export_path}" || false
+  ! lxc storage create nfs nfs nfs.host=127.0.0.1 nfs.path=relative/path || false
+
+  # Non-empty exports are refused.
+  touch "${export_path}/file"
+  ! lxc storage create nfs nfs nfs.host=127.0.0.1 nfs.path="${export_path}" || false
+  rm "${export_path}/file"
+
+  lxc storage create nfs nfs nfs.host=127.0.0.1 nfs.path="${export_path}"
+  lxc storage info nfs
+  ! lxc storage set nfs nfs.path=/other || false
+
+  echo "==> Filesystem volumes are directories on the export."
+  lxc storage volume create nfs vol1
+  [ -d "${export_path}/custom/default_vol1" ]
+  lxc storage volume snapshot nfs vol1 snap0
+  lxc storage volume rename nfs vol1 vol2
+  lxc storage volume copy nfs/vol2 nfs/vol1 --volume-only
+  lxc storage volume restore nfs vol2 snap0
+  lxc storage volume delete nfs vol1
+  lxc storage volume delete nfs vol2
+
+  echo "==> Block volumes are raw files on the export."
+  lxc storage volume create nfs vol1 --type=block size=16MiB
+  [ "$(stat -c %s "${export_path}/custom/default_vol1/root.img")" = "16777216" ]
+  lxc storage volume set nfs vol1 size=32MiB
+  [ "$(stat -c %s "${export_path}/custom/default_vol1/root.img")" = "33554432" ]
+  lxc storage volume delete nfs vol1
+
+  echo "==> Containers are stored on the export."
+  ensure_import_testimage
+  lxc init testimage c1 --storage nfs
+  [ -d "${export_path}/containers/c1/rootfs" ]
+  lxc snapshot c1
+  lxc delete c1
+
+  lxc storage delete nfs
+  [ -z "$(ls -A "${export_path}")" ]
+
+  exportfs -u "127.0.0.1:${export_path}"
+  rmdir "${export_path}"
+}
@@ -15,6 +15,154 @@ test_storage_driver_zfs() {
   do_zfs_delegate
   do_zfs_rebase
   do_recursive_copy_snapshot_cleanup
+  do_zfs_encryption
+  do_zfs_usage_warnings
+  do_zfs_inode_quota
+}
+
+do_zfs_encryption() {
+  if ! command -v cryptsetup >/dev/null; then
+    echo "==> SKIP: Skipping ZFS encryption tests as cryptsetup is missing"
+    return
+  fi
+
+  local pool
+  pool="lxdtest-$(basename "${LXD_DIR}")"
+
+  # Check that the volume is opened through its LUKS2 decrypted device.
+  luks_opened() {
+    cryptsetup status "lxd-luks-${pool}-${pool}-${1}" | grep -q "type: *LUKS2"
+  }
+
+  ensure_import_testimage
+
+  echo "==> Only ZFS volumes can be encrypted"
+  ! lxc storage volume create "${pool}" vol1 block.encryption=luks2 size=32MiB || false
+  lxc storage volume create "${pool}" vol1 block.encryption=luks2 zfs.block_mode=true size=32MiB
+  lxc storage volume create "${pool}" vol2 --type=block block.encryption=luks2 size=32MiB
+  ! lxc storage volume show "${pool}" vol1 | grep -F volatile.encryption.key || false
+
+  echo "==> The data is kept across activations and in snapshots"
+  lxc launch testimage c1 -d "${SMALL_ROOT_DISK}"
+  lxc storage volume attach "${pool}" vol1 c1 /mnt
+  luks_opened custom-default_vol1
+  lxc exec c1 -- sh -c "echo encrypted > /mnt/file"
+  lxc restart -f c1
+  [ "$(lxc exec c1 -- cat /mnt/file)" = "encrypted" ]
+  lxc storage volume detach "${pool}" vol1 c1
+  lxc storage volume snapshot "${pool}" vol1 snap0
+  lxc storage volume set "${pool}" vol1 size=64MiB
+
+  echo "==> Copies of volumes and snapshots are encrypted and keep the data"
+  lxc storage volume copy "${pool}/vol1" "${pool}/vol1-copy"
+  lxc storage volume copy "${pool}/vol1/snap0" "${pool}/vol1-snap"
+  lxc storage volume attach "${pool}" vol1-copy c1 /mnt/copy
+  lxc storage volume attach "${pool}" vol1-snap c1 /mnt/snap
+  [ "$(lxc exec c1 -- cat /mnt/copy/file)" = "encrypted" ]
+  [ "$(lxc exec c1 -- cat /mnt/snap/file)" = "encrypted" ]
+  luks_opened custom-default_vol1-copy
+  luks_opened custom-default_vol1-snap
+  lxc delete -f c1
+
+  lxc storage volume delete "${pool}" vol1-copy
+  lxc storage volume delete "${pool}" vol1-snap
+  lxc storage volume delete "${pool}" vol1
+  lxc storage volume delete "${pool}" vol2
+
+  # No decrypted device is left behind.
+  ! ls /dev/mapper/lxd-luks-* || false
+}
+
+do_zfs_usage_warnings() {
+  local pool monitor_pid
+  pool="lxdtest-$(basename "${LXD_DIR}")-usage"
+
+  # Number of warnings of a type with a given status.
+  warning_count() {
+    lxc query "/1.0/warnings?recursion=1" | jq --raw-output --arg type "${1}" --arg status "${2}" '[.[] | select(.type == $type and .status == $status)] | length'
+  }
+
+  ensure_import_testimage
+
+  lxc storage create "${pool}" zfs size=1GiB
+  ! lxc storage set "${pool}" warning_threshold=101 || false
+
+  echo "==> Fill an unsized volume and the pool above their thresholds"
+  lxc storage volume create "${pool}" vol1
+  lxc launch testimage c1 -d "${SMALL_ROOT_DISK}"
+  lxc storage volume attach "${pool}" vol1 c1 /mnt
+  lxc exec c1 -- dd if=/dev/urandom of=/mnt/fill bs=1M count=200
+  lxc exec c1 -- sync
+  lxc storage set "${pool}" warning_threshold=10 volume.warning_threshold=10
+
+  lxc monitor --type=lifecycle --format json > "${TEST_DIR}/usage-events.jsonl" &
+  monitor_pid=$!
+  sleep 0.1
+  lxc query -X POST /internal/testing/storage-usage-task
+  lxc query -X POST /internal/testing/storage-usage-task
+  kill_go_proc "${monitor_pid}" || true
+
+  [ "$(warning_count "Storage pool nearly full" new)" = "1" ]
+  [ "$(warning_count "Storage volume nearly full" new)" = "1" ]
+  [ "$(jq --slurp '[.[] | select(.metadata.action == "storage-pool-nearly-full")] | length' "${TEST_DIR}/usage-events.jsonl")" = "1" ]
+  [ "$(jq --slurp '[.[] | select(.metadata.action == "storage-volume-nearly-full")] | length' "${TEST_DIR}/usage-events.jsonl")" = "1" ]
+  rm "${TEST_DIR}/usage-events.jsonl"
+
+  echo "==> The warnings are resolved once the usage is back below the thresholds"
+  lxc storage set "${pool}" warning_threshold=90 volume.warning_threshold=90
+  lxc query -X POST /internal/testing/storage-usage-task
+  [ "$(warning_count "Storage pool nearly full" new)" = "0" ]
+  [ "$(warning_count "Storage volume nearly full" new)" = "0" ]
+
+  lxc delete -f c1
+  lxc storage volume delete "${pool}" vol1
+  lxc storage delete "${pool}"
+  lxc warning delete --all
+}
+
+do_zfs_inode_quota() {
+  local pool loop_file loop_device
+  pool="lxdtest-$(basename "${LXD_DIR}")-inodes"
+
+  ensure_import_testimage
+
+  lxc storage create "${pool}" zfs size=1GiB
+
+  echo "==> The inode limit of a custom volume is a project object quota"
+  ! lxc storage volume create "${pool}" vol1 zfs.block_mode=true size.inodes=100 || false
+  lxc storage volume create "${pool}" vol1 size.inodes=100
+  [ "$(zfs get -H -o value projectobjquota@1 "${pool}/custom/default_vol1")" = "100" ]
+
+  lxc storage volume set "${pool}" vol1 size.inodes=200
+  [ "$(zfs get -H -o value projectobjquota@1 "${pool}/custom/default_vol1")" = "200" ]
+
+  lxc launch testimage c1 -s "${pool}"
+  lxc storage volume attach "${pool}" vol1 c1 /mnt
+  ! lxc exec c1 -- sh -c 'for i in $(seq 300); do touch /mnt/file$i || exit 1; done' || false
+  lxc storage volume detach "${pool}" vol1 c1
+
+  lxc storage volume unset "${pool}" vol1 size.inodes
+  [ "$(zfs get -H -o value projectobjquota@1 "${pool}/custom/default_vol1")" = "none" ]
+  lxc storage volume delete "${pool}" vol1
+
+  echo "==> The files of instances cloned from an image count against their inode limit"
+  lxc storage set "${pool}" volume.size.inodes=10000
+  lxc init testimage c2 -s "${pool}"
+  [ "$(zfs get -H -o value projectobjquota@1 "${pool}/containers/c2")" = "10000" ]
+  [ "$(zfs get -H -p -o value projectobjused@1 "${pool}/containers/c2")" -gt 100 ]
+  lxc delete -f c1 c2
+  lxc storage delete "${pool}"
+
+  echo "==> Inode limits require the project_quota feature of the pool"
+  configure_loop_device loop_file loop_device 128M
+  zpool create -f -m none -o feature@project_quota=disabled "${pool}-noproject" "${loop_device}"
+  lxc storage create "${pool}-noproject" zfs source="${pool}-noproject"
+  [[ "$(CLIENT_DEBUG="" SHELL_TRACING="" lxc storage volume create "${pool}-noproject" vol1 size.inodes=100 2>&1)" == *'requires the "project_quota" feature to be enabled on ZFS pool'* ]]
+  ! lxc storage volume show "${pool}-noproject" vol1 || false
+  lxc storage volume create "${pool}-noproject" vol1
+  lxc storage volume delete "${pool}-noproject" vol1
+  lxc storage delete "${pool}-noproject"
+  deconfigure_loop_device "${loop_file}" "${loop_device}"
 }
 
 do_zfs_delegate() {
@@ -353,3 +353,44 @@ EOF
   LXD_DIR="${LXD_DIR}"
   kill_lxd "${LXD_STORAGE_DIR}"
 }
+
+test_storage_volume_diff() {
+  ensure_import_testimage
+
+  local pool
+  pool="lxdtest-$(basename "${LXD_DIR}")"
+
+  lxc storage volume create "${pool}" vol1
+  lxc launch testimage c1
+  lxc storage volume attach "${pool}" vol1 c1 /mnt
+  lxc exec c1 -- sh -c "echo foo > /mnt/modified && echo foo > /mnt/deleted && mkdir /mnt/dir"
+  lxc storage volume snapshot "${pool}" vol1 snap0
+
+  echo "==> The changes since a snapshot are listed"
+  lxc exec c1 -- sh -c "echo bar > /mnt/modified && rm /mnt/deleted && echo foo > /mnt/dir/added"
+  lxc exec c1 -- sync
+  lxc storage volume diff "${pool}" vol1 snap0 --format csv | grep -xF "modified,/modified"
+  lxc storage volume diff "${pool}" vol1 snap0 --format csv | grep -xF "deleted,/deleted"
+  lxc storage volume diff "${pool}" vol1 snap0 --format csv | grep -xF "added,/dir/added"
+
+  echo "==> Two snapshots are compared"
+  lxc storage volume snapshot "${pool}" vol1 snap1
+  lxc exec c1 -- rm /mnt/modified
+  lxc storage volume diff "${pool}" vol1 snap0 snap1 --format csv | grep -xF "modified,/modified"
+  ! lxc storage volume diff "${pool}" vol1 snap0 snap1 --format csv | grep -F "deleted,/modified" || false
+  lxc storage volume diff "${pool}" vol1 snap1 --format csv | grep -xF "deleted,/modified"
+
+  echo "==> The comparison runs as an operation returning the changes in its metadata"
+  lxc query --wait -X POST -d '{"from": "snap1"}' "/1.0/storage-pools/${pool}/volumes/custom/vol1/diff" | jq -r '.metadata.diffs[] | .change + "," + .path' | grep -xF "deleted,/modified"
+  ! lxc query --wait -X POST -d '{"from": ""}' "/1.0/storage-pools/${pool}/volumes/custom/vol1/diff" || false
+  ! lxc storage volume diff "${pool}" vol1 snap2 || false
+
+  echo "==> The changes of a container are relative to its root file system"
+  lxc snapshot c1 snap0
+  lxc exec c1 -- touch /root/added
+  lxc exec c1 -- sync
+  lxc storage volume diff "${pool}" container/c1 snap0 --format csv | grep -xF "added,/root/added"
+
+  lxc delete -f c1
+  lxc storage volume delete "${pool}" vol1
+}
@@ -31,6 +31,9 @@ test_vm_empty() {
   ! lxc launch --vm --empty v1 -c limits.memory=0 -d "${SMALL_ROOT_DISK}" || false
   ! lxc launch --vm --empty v1 -c limits.memory=0% -d "${SMALL_ROOT_DISK}" || false
 
+  echo "==> Memory hotplug limits must be fixed sizes"
+  ! lxc init --vm --empty v1 -c limits.memory=128MiB -c limits.memory.hotplug_max=50% -d "${SMALL_ROOT_DISK}" || false
+
   echo "==> Percentage memory limits"
   lxc launch --vm --empty v1 -c limits.memory=1% -d "${SMALL_ROOT_DISK}"
 
@@ -63,6 +66,126 @@ test_vm_empty() {
   [ ! -d "${LXD_DIR}/logs/v1" ]
 }
 
+test_vm_checkpoints() {
+  if [ "${LXD_TMPFS:-0}" = "1" ] && ! runsMinimumKernel 6.6; then
+    export TEST_UNMET_REQUIREMENT="QEMU requires direct-io support which requires a kernel >= 6.6 for tmpfs support (LXD_TMPFS=${LXD_TMPFS})"
+    return 0
+  fi
+
+  pool="$(lxc profile device get default root pool)"
+
+  echo "==> Checkpoints require checkpoints.enabled"
+  lxc launch --vm --empty v1 -c limits.memory=128MiB -d "${SMALL_ROOT_DISK}"
+  ! lxc query --request POST /1.0/instances/v1/checkpoints --data '{"name": "c0"}' || false
+  ! lxc config set v1 checkpoints.enabled=true || false
+  lxc stop -f v1
+  lxc config set v1 checkpoints.enabled=true
+  lxc storage volume create "${pool}" v1block --type=block size=1MiB
+  lxc config device add v1 v1block disk source=v1block pool="${pool}"
+  lxc start v1
+
+  echo "==> Create checkpoints"
+  lxc query --request POST /1.0/instances/v1/checkpoints --data '{"name": "c0"}'
+  ! lxc query --request POST /1.0/instances/v1/checkpoints --data '{"name": "c0"}' || false
+  lxc query --request POST /1.0/instances/v1/checkpoints --data '{"name": "c1", "disks": ["root"]}'
+  ! lxc query --request POST /1.0/instances/v1/checkpoints --data '{"name": "c2", "disks": ["missing"]}' || false
+  [ "$(lxc query /1.0/instances/v1/checkpoints/c0 | jq -r '.disks | keys | join(",")')" = "root,v1block" ]
+  [ "$(lxc query /1.0/instances/v1/checkpoints/c1 | jq -r '.disks | keys | join(",")')" = "root" ]
+
+  echo "==> Checkpoints are kept across restarts"
+  lxc restart -f v1
+  [ "$(lxc query '/1.0/instances/v1/checkpoints?recursion=1' | jq -r 'map(.name) | join(",")')" = "c0,c1" ]
+
+  echo "==> Delete checkpoints"
+  lxc query --request DELETE /1.0/instances/v1/checkpoints/c1
+  ! lxc query --request DELETE /1.0/instances/v1/checkpoints/c1 || false
+  ! lxc query /1.0/instances/v1/checkpoints/c1 || false
+
+  echo "==> Detaching a disk discards its checkpoints"
+  lxc config device remove v1 v1block
+  [ "$(lxc query /1.0/instances/v1/checkpoints/c0 | jq -r '.disks | keys | join(",")')" = "root" ]
+
+  echo "==> Disabling checkpoints discards them"
+  lxc stop -f v1
+  lxc config set v1 checkpoints.enabled=false
+  lxc start v1
+  ! lxc query /1.0/instances/v1/checkpoints/c0 || false
+  lxc stop -f v1
+  lxc config set v1 checkpoints.enabled=true
+  lxc start v1
+  [ "$(lxc query '/1.0/instances/v1/checkpoints?recursion=1' | jq -r 'length')" = "0" ]
+
+  lxc delete -f v1
+  lxc storage volume delete "${pool}" v1block
+}
+
+test_vm_live_storage_move() {
+  if [ "${LXD_TMPFS:-0}" = "1" ] && ! runsMinimumKernel 6.6; then
+    export TEST_UNMET_REQUIREMENT="QEMU requires direct-io support which requires a kernel >= 6.6 for tmpfs support (LXD_TMPFS=${LXD_TMPFS})"
+    return 0
+  fi
+
+  pool="$(lxc profile device get default root pool)"
+  pool2="lxdtest-$(basename "${LXD_DIR}")-move"
+  lxc storage create "${pool2}" dir
+
+  echo "==> Move the root disk of a running VM"
+  lxc launch --vm --empty v1 -c limits.memory=128MiB -d "${SMALL_ROOT_DISK}"
+  lxc move v1 --storage "${pool2}"
+  [ "$(lxc list -f csv -c s v1)" = "RUNNING" ]
+  [ "$(lxc config device get v1 root pool)" = "${pool2}" ]
+  [ "$(lxc config get v1 volatile.storage_move.source_pool)" = "${pool}" ]
+
+  echo "==> Another move requires the previous one to be completed"
+  ! lxc move v1 --storage "${pool}" || false
+
+  echo "==> The source volume is removed once the VM stops"
+  lxc stop -f v1
+  [ "$(lxc config get v1 volatile.storage_move.source_pool || echo fail)" = "" ]
+  ! lxc storage volume show "${pool}" virtual-machine/v1 || false
+  lxc storage volume show "${pool2}" virtual-machine/v1
+
+  echo "==> Running VMs with snapshots require migration.stateful"
+  lxc start v1
+  lxc snapshot v1
+  ! lxc move v1 --storage "${pool}" || false
+  [ "$(lxc list -f csv -c s v1)" = "RUNNING" ]
+  [ "$(lxc config device get v1 root pool)" = "${pool2}" ]
+
+  lxc delete -f v1
+  lxc storage delete "${pool2}"
+}
+
+test_vm_usbredir() {
+  if [ "${LXD_TMPFS:-0}" = "1" ] && ! runsMinimumKernel 6.6; then
+    export TEST_UNMET_REQUIREMENT="QEMU requires direct-io support which requires a kernel >= 6.6 for tmpfs support (LXD_TMPFS=${LXD_TMPFS})"
+    return 0
+  fi
+
+  usbredir() {
+    curl --silent --unix-socket "${LXD_DIR}/unix.socket" --max-time 2 --output /dev/null --write-out '%{http_code}' -H "Upgrade: ${2:-usbredir}" -H "Connection: Upgrade" "lxd/1.0/instances/v1/usbredir?name=${1}" || true
+  }
+
+  echo "==> Invalid local devices are rejected by the client"
+  ! lxc usb attach v1 || false
+  ! lxc usb attach v1 --local 1050 || false
+  ! lxc usb attach v1 --local 1050:04070 || false
+
+  lxc init --vm --empty v1 -c limits.memory=128MiB -d "${SMALL_ROOT_DISK}"
+
+  echo "==> Redirection requires a running VM, a valid name and the usbredir upgrade"
+  [ "$(usbredir dev0)" = "400" ]
+  lxc start v1
+  [ "$(usbredir dev0 sftp)" = "400" ]
+  [ "$(usbredir "invalid/name")" = "400" ]
+
+  echo "==> Redirected devices are unplugged once the connection is closed"
+  [ "$(usbredir dev0)" = "101" ]
+  [ "$(usbredir dev0)" = "101" ]
+
+  lxc delete -f v1
+}
+
 test_vm_pcie_bus() {
   echo "==> Device PCIe bus numbers"
   pool=$(lxc profile device get default root pool)